@@ -0,0 +1,224 @@
+package stablehlo
+
+import (
+	"fmt"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/internal/utils"
+	"github.com/gomlx/stablehlo/shapeinference"
+	"github.com/gomlx/stablehlo/types"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// GraphNode is the minimal view of a node of the computation graph of another framework (e.g. GoMLX), used by
+// Function.ConvertGraph to lower the graph to StableHLO, without depending on the types of this package.
+//
+// Implementations must be comparable (e.g. pointers), since the nodes are used as map keys.
+type GraphNode interface {
+	// OpName is the StableHLO name of the operation, e.g. "stablehlo.add" -- the "stablehlo." prefix can be omitted.
+	OpName() string
+
+	// Inputs are the operands of the operation.
+	Inputs() []GraphOutput
+
+	// Attributes of the operation, with their values in StableHLO syntax, rendered verbatim.
+	// E.g.: "comparison_direction" -> "#stablehlo<comparison_direction LT>".
+	Attributes() map[string]string
+
+	// OutputShapes of the operation, usually only one.
+	OutputShapes() []shapes.Shape
+}
+
+// GraphOutput refers to one of the outputs of a GraphNode.
+type GraphOutput struct {
+	Node GraphNode
+
+	// Output is the index of the output of the node, 0 for the nodes with only one output.
+	Output int
+}
+
+// ConvertGraph adds to the function the operations of the graph nodes needed to compute the given outputs, in
+// topological order, and returns their values. See GraphNode.
+//
+// The parameters map the nodes that are the inputs of the graph to values of the function (e.g. created with
+// Function.NamedInput), and their shapes must match. Nodes without inputs that are not parameters (e.g.
+// "stablehlo.constant" or "stablehlo.iota") are converted as any other.
+//
+// The operations known to this package are added as regular statements, and the other ones (e.g. from other
+// dialects) as raw statements (see Function.RawStatement). The attributes are taken as given. The output shapes
+// of the element-wise operations (e.g. add, compare, select) are checked with shape inference, and the other ones are
+// taken as given. Operations with regions (e.g. reduce, while, sort) are not supported, and they return an error:
+// the Builder API must be used for them.
+//
+// Example, lowering a graph whose only parameter is the node xNode:
+//
+//	x, err := fn.NamedInput("x", shapes.Make(dtypes.Float32, 3))
+//	...
+//	outputs, err := fn.ConvertGraph([]GraphOutput{{Node: root}}, map[GraphNode]*Value{xNode: x})
+func (fn *Function) ConvertGraph(outputs []GraphOutput, parameters map[GraphNode]*Value) (values []*Value, err error) {
+	defer fn.multiOpErrorHandler(&err, &values, len(outputs))()
+	if fn.Returned {
+		return nil, errors.Errorf("cannot convert graph after returning, in function %q", fn.Name)
+	}
+	for node, value := range parameters {
+		if value == nil {
+			return nil, errors.Errorf("cannot convert graph in function %q, because the value of parameter %s is nil",
+				fn.Name, node.OpName())
+		}
+		if value.fn != fn {
+			return nil, errors.Errorf("cannot convert graph in function %q, because the value of parameter %s is from function %q",
+				fn.Name, node.OpName(), value.fn.Name)
+		}
+		if nodeShapes := node.OutputShapes(); len(nodeShapes) > 0 && (len(nodeShapes) != 1 || !nodeShapes[0].Equal(value.shape)) {
+			return nil, errors.Errorf("cannot convert graph in function %q, because parameter %s has shapes %v, but its value has shape %s",
+				fn.Name, node.OpName(), nodeShapes, value.shape)
+		}
+	}
+
+	converted := make(map[GraphNode][]*Value, len(parameters))
+	for node, value := range parameters {
+		converted[node] = []*Value{value}
+	}
+	visiting := utils.MakeSet[GraphNode]()
+	var convert func(node GraphNode) error
+	convert = func(node GraphNode) error {
+		if _, found := converted[node]; found {
+			return nil
+		}
+		if visiting.Has(node) {
+			return errors.Errorf("graph has a cycle through node %s", node.OpName())
+		}
+		visiting.Insert(node)
+		inputs := node.Inputs()
+		operands := make([]*Value, len(inputs))
+		for i, input := range inputs {
+			if input.Node == nil {
+				return errors.Errorf("input #%d of node %s is nil", i, node.OpName())
+			}
+			if err := convert(input.Node); err != nil {
+				return err
+			}
+			inputValues := converted[input.Node]
+			if input.Output < 0 || input.Output >= len(inputValues) {
+				return errors.Errorf("input #%d of node %s refers to output #%d of node %s, which has %d outputs",
+					i, node.OpName(), input.Output, input.Node.OpName(), len(inputValues))
+			}
+			operands[i] = inputValues[input.Output]
+		}
+		nodeValues, err := fn.addGraphNode(node, operands)
+		if err != nil {
+			return err
+		}
+		delete(visiting, node)
+		converted[node] = nodeValues
+		return nil
+	}
+
+	values = make([]*Value, len(outputs))
+	for i, output := range outputs {
+		if output.Node == nil {
+			return nil, errors.Errorf("output #%d is nil", i)
+		}
+		if err := convert(output.Node); err != nil {
+			return nil, errors.WithMessagef(err, "converting graph output #%d", i)
+		}
+		nodeValues := converted[output.Node]
+		if output.Output < 0 || output.Output >= len(nodeValues) {
+			return nil, errors.Errorf("output #%d refers to output #%d of node %s, which has %d outputs",
+				i, output.Output, output.Node.OpName(), len(nodeValues))
+		}
+		values[i] = nodeValues[output.Output]
+	}
+	return values, nil
+}
+
+// addGraphNode adds the operation of the node, with the given operands, to the function.
+func (fn *Function) addGraphNode(node GraphNode, operands []*Value) ([]*Value, error) {
+	opName := node.OpName()
+	if !strings.Contains(opName, ".") {
+		opName = "stablehlo." + opName
+	}
+	outputShapes := node.OutputShapes()
+	if len(outputShapes) == 0 {
+		return nil, errors.Errorf("node %s has no output shapes", opName)
+	}
+	if opName == "stablehlo.return" || opName == "func.return" {
+		return nil, errors.Errorf("node %s can't be converted, use Function.Return with the converted outputs instead", opName)
+	}
+	if regionOpNames.Has(opName) {
+		return nil, errors.Errorf("node %s can't be converted, because it requires regions: use the Builder API instead", opName)
+	}
+	attributes := node.Attributes()
+	if op, found := importOpTypes[opName]; found {
+		if inferred, ok, err := inferElementWiseShape(op, operands); err != nil {
+			return nil, errors.WithMessagef(err, "node %s", opName)
+		} else if ok && (len(outputShapes) != 1 || !outputShapes[0].Equal(inferred)) {
+			return nil, errors.Errorf("node %s has output shapes %v, but its inferred shape is %s", opName, outputShapes, inferred)
+		}
+		stmt := fn.addMultiOp(op, slices.Clone(outputShapes), operands)
+		if len(attributes) > 0 {
+			stmt.Attributes = make(map[string]any, len(attributes))
+			for key, value := range attributes {
+				stmt.Attributes[key] = literalStr(value)
+			}
+		}
+		return stmt.Outputs, nil
+	}
+
+	// Unknown operation: converted to a raw statement.
+	placeholders := make([]string, len(operands))
+	for i := range operands {
+		placeholders[i] = fmt.Sprintf("$%d", i)
+	}
+	snippet := fmt.Sprintf("%q(%s)", opName, strings.Join(placeholders, ", "))
+	if len(attributes) > 0 {
+		parts := make([]string, 0, len(attributes))
+		for _, key := range slices.Sorted(maps.Keys(attributes)) {
+			parts = append(parts, fmt.Sprintf("%s = %s", key, attributes[key]))
+		}
+		snippet += " { " + strings.Join(parts, ", ") + " }"
+	}
+	return fn.RawStatement(snippet, operands, outputShapes...)
+}
+
+// regionOpNames are the operations that require regions (closures), which a GraphNode can't describe.
+var regionOpNames = utils.SetWith(
+	"stablehlo.reduce", "stablehlo.reduce_window", "stablehlo.reduce_scatter", "stablehlo.all_reduce",
+	"stablehlo.scatter", "stablehlo.select_and_scatter", "stablehlo.sort", "stablehlo.map",
+	"stablehlo.while", "stablehlo.case", "stablehlo.if",
+)
+
+// inferElementWiseShape returns the output shape of the element-wise operations, whose shape only depends on the
+// shapes of their operands. It returns false for the other operations.
+func inferElementWiseShape(op optypes.OpType, operands []*Value) (output shapes.Shape, ok bool, err error) {
+	operandShapes := make([]shapes.Shape, len(operands))
+	for i, operand := range operands {
+		operandShapes[i] = operand.shape
+	}
+	switch {
+	case len(operands) == 1 && shapeinference.StandardUnaryOperations.Has(op):
+		output, err = shapeinference.UnaryOp(op, operandShapes[0])
+	case len(operands) == 2 && shapeinference.StandardBinaryOperations.Has(op):
+		output, err = shapeinference.BinaryOp(op, operandShapes[0], operandShapes[1])
+	case len(operands) == 2 && op == optypes.Compare:
+		// The direction doesn't change the shape.
+		output, err = shapeinference.Compare(operandShapes[0], operandShapes[1], types.CompareEQ, types.CompareAuto)
+	case len(operands) == 3 && op == optypes.Select:
+		output, err = shapeinference.Select(operandShapes[0], operandShapes[1], operandShapes[2])
+	case len(operands) == 3 && op == optypes.Clamp:
+		output, err = shapeinference.Clamp(operandShapes[0], operandShapes[1], operandShapes[2])
+	case len(operands) == 2 && op == optypes.Complex:
+		output, err = shapeinference.Complex(operandShapes[0], operandShapes[1])
+	case len(operands) == 1 && (op == optypes.Real || op == optypes.Imag):
+		output, err = shapeinference.RealOrImag(operandShapes[0])
+	case len(operands) == 1 && op == optypes.IsFinite:
+		output, err = shapeinference.IsFinite(operandShapes[0])
+	default:
+		return shapes.Invalid(), false, nil
+	}
+	return output, err == nil, err
+}
@@ -0,0 +1,99 @@
+package stablehlo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+// testGraphNode implements GraphNode, as a framework would do with its own graph nodes.
+type testGraphNode struct {
+	op         string
+	inputs     []GraphOutput
+	attributes map[string]string
+	shapes     []shapes.Shape
+}
+
+func (n *testGraphNode) OpName() string                { return n.op }
+func (n *testGraphNode) Inputs() []GraphOutput         { return n.inputs }
+func (n *testGraphNode) Attributes() map[string]string { return n.attributes }
+func (n *testGraphNode) OutputShapes() []shapes.Shape  { return n.shapes }
+
+func TestConvertGraph(t *testing.T) {
+	f32 := shapes.Make(dtypes.Float32, 3)
+	xNode := &testGraphNode{op: "parameter", shapes: []shapes.Shape{f32}}
+	constNode := &testGraphNode{op: "constant", shapes: []shapes.Shape{f32},
+		attributes: map[string]string{"value": "dense<1.0> : tensor<3xf32>"}}
+	addNode := &testGraphNode{op: "stablehlo.add", shapes: []shapes.Shape{f32},
+		inputs: []GraphOutput{{Node: xNode}, {Node: constNode}}}
+	compareNode := &testGraphNode{op: "compare", shapes: []shapes.Shape{shapes.Make(dtypes.Bool, 3)},
+		inputs:     []GraphOutput{{Node: addNode}, {Node: xNode}},
+		attributes: map[string]string{"comparison_direction": "#stablehlo<comparison_direction GT>"}}
+	customNode := &testGraphNode{op: "mydialect.scale", shapes: []shapes.Shape{f32, f32},
+		inputs:     []GraphOutput{{Node: addNode}},
+		attributes: map[string]string{"factor": "2.0 : f32", "axis": "0 : i64"}}
+
+	t.Run("Convert", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", f32))
+		outputs := must(fn.ConvertGraph(
+			[]GraphOutput{{Node: compareNode}, {Node: customNode, Output: 1}},
+			map[GraphNode]*Value{xNode: x}))
+		must0(fn.Return(outputs...))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestConvertGraph_Convert {
+  func.func @main(%x: tensor<3xf32>) -> (tensor<3xi1>, tensor<3xf32>) {
+    %0 = "stablehlo.constant"() { value = dense<1.0> : tensor<3xf32> } : () -> tensor<3xf32>
+    %1 = "stablehlo.add"(%x, %0) : (tensor<3xf32>, tensor<3xf32>) -> tensor<3xf32>
+    %2 = "stablehlo.compare"(%1, %x) { comparison_direction = #stablehlo<comparison_direction GT> } : (tensor<3xf32>, tensor<3xf32>) -> tensor<3xi1>
+    %3, %4 = "mydialect.scale"(%1) { axis = 0 : i64, factor = 2.0 : f32 } : (tensor<3xf32>) -> (tensor<3xf32>, tensor<3xf32>)
+    "stablehlo.return"(%2, %4) : (tensor<3xi1>, tensor<3xf32>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+		requireRoundTrip(t, builder)
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		fn := New(t.Name()).Main()
+		x := must(fn.NamedInput("x", f32))
+		cycleNode := &testGraphNode{op: "negate", shapes: []shapes.Shape{f32}}
+		cycleNode.inputs = []GraphOutput{{Node: &testGraphNode{op: "abs", shapes: []shapes.Shape{f32},
+			inputs: []GraphOutput{{Node: cycleNode}}}}}
+		for _, tc := range []struct {
+			name       string
+			outputs    []GraphOutput
+			parameters map[GraphNode]*Value
+			want       string
+		}{
+			{"cycle", []GraphOutput{{Node: cycleNode}}, nil, "cycle"},
+			{"output index", []GraphOutput{{Node: addNode, Output: 1}}, map[GraphNode]*Value{xNode: x}, "which has 1 outputs"},
+			{"parameter shape", []GraphOutput{{Node: addNode}},
+				map[GraphNode]*Value{xNode: must(fn.NamedInput("y", shapes.Make(dtypes.Float32, 2)))}, "parameter"},
+			{"return", []GraphOutput{{Node: &testGraphNode{op: "return", shapes: []shapes.Shape{f32},
+				inputs: []GraphOutput{{Node: xNode}}}}}, map[GraphNode]*Value{xNode: x}, "Function.Return"},
+			{"no shapes", []GraphOutput{{Node: &testGraphNode{op: "negate",
+				inputs: []GraphOutput{{Node: xNode}}}}}, map[GraphNode]*Value{xNode: x}, "no output shapes"},
+			{"region", []GraphOutput{{Node: &testGraphNode{op: "reduce", shapes: []shapes.Shape{shapes.Make(dtypes.Float32)},
+				inputs: []GraphOutput{{Node: xNode}, {Node: constNode}}}}}, map[GraphNode]*Value{xNode: x}, "requires regions"},
+			{"inferred shape", []GraphOutput{{Node: &testGraphNode{op: "add", shapes: []shapes.Shape{shapes.Make(dtypes.Float32, 4)},
+				inputs: []GraphOutput{{Node: xNode}, {Node: xNode}}}}}, map[GraphNode]*Value{xNode: x}, "inferred shape"},
+			{"invalid operands", []GraphOutput{{Node: &testGraphNode{op: "and", shapes: []shapes.Shape{f32},
+				inputs: []GraphOutput{{Node: xNode}, {Node: xNode}}}}}, map[GraphNode]*Value{xNode: x}, "node stablehlo.and"},
+			{"nil parameter", []GraphOutput{{Node: addNode}}, map[GraphNode]*Value{xNode: nil}, "is nil"},
+		} {
+			if _, err := fn.ConvertGraph(tc.outputs, tc.parameters); err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.want, err)
+			}
+		}
+	})
+}
@@ -0,0 +1,69 @@
+package stablehlo
+
+import (
+	"slices"
+
+	"github.com/pkg/errors"
+)
+
+// AliasingOutputAttribute is the input attribute used to mark an input buffer as aliased (donated) to an output.
+// Its value is the index of the output (int32).
+const AliasingOutputAttribute = "tf.aliasing_output"
+
+// inputOutputAlias is a pending aliasing of an input to an output value, resolved at Function.Return.
+type inputOutputAlias struct {
+	input, output *Value
+}
+
+// AliasInputToOutput marks the input buffer of the function to be reused (aliased) for the given output value,
+// which must later be returned by Function.Return -- the index of the output is only resolved then.
+//
+// This saves memory for values updated "in-place", like model parameters updated by an optimizer:
+// the PJRT client must then donate the corresponding input buffer when executing the program.
+//
+// The input must be an input of fn (not a closure), and the output must have the same shape.
+// It is rendered as the input attribute "tf.aliasing_output = <output index> : i32".
+func (fn *Function) AliasInputToOutput(input, output *Value) error {
+	if fn.Parent != nil {
+		return errors.Errorf("AliasInputToOutput: cannot alias inputs of closure %q", fn.Name)
+	}
+	if fn.Returned {
+		return errors.Errorf("AliasInputToOutput: Function.Return already called for %q", fn.Name)
+	}
+	if slices.Index(fn.Inputs, input) == -1 {
+		return errors.Errorf("AliasInputToOutput: value %s is not an input of function %q", input, fn.Name)
+	}
+	if output.fn != fn {
+		return errors.Errorf("AliasInputToOutput: output value %s is not from function %q", output, fn.Name)
+	}
+	if !input.shape.Equal(output.shape) {
+		return errors.Errorf("AliasInputToOutput: input %s and output %s must have the same shape, got %s and %s",
+			input, output, input.shape, output.shape)
+	}
+	for _, alias := range fn.aliases {
+		if alias.input == input {
+			return errors.Errorf("AliasInputToOutput: input %s is already aliased to %s", input, alias.output)
+		}
+		if alias.output == output {
+			return errors.Errorf("AliasInputToOutput: output %s is already aliased to input %s", output, alias.input)
+		}
+	}
+	fn.aliases = append(fn.aliases, inputOutputAlias{input: input, output: output})
+	return nil
+}
+
+// resolveAliases sets the aliasing attribute of the inputs given the returned values.
+func (fn *Function) resolveAliases(returnedValues []*Value) error {
+	for _, alias := range fn.aliases {
+		outputIdx := slices.Index(returnedValues, alias.output)
+		if outputIdx == -1 {
+			return errors.Errorf("input %s was aliased to value %s, but it is not returned by function %q",
+				alias.input, alias.output, fn.Name)
+		}
+		if alias.input.Attributes == nil {
+			alias.input.Attributes = make(map[string]any)
+		}
+		alias.input.Attributes[AliasingOutputAttribute] = int32(outputIdx)
+	}
+	return nil
+}
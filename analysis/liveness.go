@@ -0,0 +1,174 @@
+// Package analysis implements analyses of StableHLO programs built with github.com/gomlx/stablehlo,
+// to help planning their execution.
+package analysis
+
+import (
+	"slices"
+
+	"github.com/gomlx/stablehlo"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// StatementLiveness holds the values live during the execution of a statement.
+type StatementLiveness struct {
+	Statement *stablehlo.Statement
+
+	// Live values while executing the statement: its inputs, its outputs and the values
+	// defined earlier that are still used by later statements.
+	Live []*stablehlo.Value
+
+	// Memory used by the live values, in bytes.
+	Memory uintptr
+}
+
+// DonationCandidate is an input of the function, not returned itself and not already aliased, paired with a returned
+// value of the same shape that is not an input, so the buffer of the input could be donated to it,
+// see stablehlo.Function.AliasInputToOutput.
+//
+// Each returned value is paired with at most one input. Whether the input is still used after the output is computed
+// is not checked: in that case the compiler has to keep a copy of the input, and the donation may not save memory.
+type DonationCandidate struct {
+	Input, Output *stablehlo.Value
+}
+
+// LivenessReport is the result of Liveness.
+type LivenessReport struct {
+	// Statements holds the liveness of each statement of the function, in order.
+	Statements []StatementLiveness
+
+	// PeakMemory is the maximum memory used by live values, and PeakStatement the index of the
+	// statement where it happens.
+	PeakMemory    uintptr
+	PeakStatement int
+
+	// DonationCandidates lists inputs that could be aliased to outputs of the same shape, because they are
+	// not returned themselves. Inputs already aliased are not included.
+	DonationCandidates []DonationCandidate
+}
+
+// Liveness computes the live values during each statement of the function, the peak memory used,
+// and the inputs that could be donated to outputs.
+//
+// The function must have been returned (see stablehlo.Function.Return).
+// Values from the function used inside closures (e.g. the reduction function of a Reduce) are considered
+// used by the statement that takes the closure.
+// The memory doesn't account for temporary buffers used internally by the operations, or any fusion done
+// by the compiler, so it is only an estimate of the memory required to execute the function.
+func Liveness(fn *stablehlo.Function) (*LivenessReport, error) {
+	if !fn.Returned {
+		return nil, errors.Errorf("Liveness requires function %q to be returned", fn.Name)
+	}
+
+	// lastUse holds the index of the last statement that uses each value of the function, computed in a single
+	// backward pass: the first use found is the last one.
+	// Values used in closures but defined outside the function (captured from a parent) are not included.
+	defined := make(map[*stablehlo.Value]bool, len(fn.Inputs)+len(fn.Statements))
+	for _, input := range fn.Inputs {
+		defined[input] = true
+	}
+	for _, stmt := range fn.Statements {
+		for _, output := range stmt.Outputs {
+			defined[output] = true
+		}
+	}
+	lastUse := make(map[*stablehlo.Value]int, len(defined))
+	for idx := len(fn.Statements) - 1; idx >= 0; idx-- {
+		for _, value := range statementUses(fn.Statements[idx]) {
+			if _, found := lastUse[value]; !found && defined[value] {
+				lastUse[value] = idx
+			}
+		}
+	}
+
+	// Forward pass: live holds the values defined so far (in order of definition) that are still used by the
+	// current or later statements, and liveMemory their memory.
+	report := &LivenessReport{Statements: make([]StatementLiveness, len(fn.Statements))}
+	var live []*stablehlo.Value
+	var liveMemory uintptr
+	addIfUsed := func(value *stablehlo.Value, idx int) {
+		if last, found := lastUse[value]; found && last > idx {
+			live = append(live, value)
+			liveMemory += valueMemory(value)
+		}
+	}
+	for _, input := range fn.Inputs {
+		addIfUsed(input, -1)
+	}
+	for idx, stmt := range fn.Statements {
+		// The outputs of the statement are live while executing it, even if they are not used.
+		stmtLive := slices.Concat(live, stmt.Outputs)
+		memory := liveMemory
+		for _, output := range stmt.Outputs {
+			memory += valueMemory(output)
+		}
+		report.Statements[idx] = StatementLiveness{Statement: stmt, Live: stmtLive, Memory: memory}
+		if memory > report.PeakMemory {
+			report.PeakMemory = memory
+			report.PeakStatement = idx
+		}
+
+		// Drop the values last used by this statement, and add its outputs used later.
+		live = slices.DeleteFunc(live, func(value *stablehlo.Value) bool {
+			if lastUse[value] == idx {
+				liveMemory -= valueMemory(value)
+				return true
+			}
+			return false
+		})
+		for _, output := range stmt.Outputs {
+			addIfUsed(output, idx)
+		}
+	}
+
+	// Donation candidates: inputs not returned and not already aliased, matched to returned values
+	// of the same shape that are not inputs themselves.
+	returned := fn.Statements[len(fn.Statements)-1].Inputs
+	isInput := make(map[*stablehlo.Value]bool, len(fn.Inputs))
+	for _, input := range fn.Inputs {
+		isInput[input] = true
+	}
+	var matched []*stablehlo.Value
+	for _, input := range fn.Inputs {
+		if _, aliased := input.Attributes[stablehlo.AliasingOutputAttribute]; aliased || slices.Contains(returned, input) {
+			continue
+		}
+		for _, output := range returned {
+			if isInput[output] || slices.Contains(matched, output) || !input.Shape().Equal(output.Shape()) {
+				continue
+			}
+			matched = append(matched, output)
+			report.DonationCandidates = append(report.DonationCandidates, DonationCandidate{Input: input, Output: output})
+			break
+		}
+	}
+	return report, nil
+}
+
+// statementUses returns the values used by the statement, including the values used by its closures.
+func statementUses(stmt *stablehlo.Statement) []*stablehlo.Value {
+	uses := slices.Clone(stmt.Inputs)
+	for _, closure := range stmt.FunctionParameters {
+		for _, closureStmt := range closure.Statements {
+			uses = append(uses, statementUses(closureStmt)...)
+		}
+	}
+	return uses
+}
+
+// valueMemory returns the memory used by the value, in bytes.
+func valueMemory(value *stablehlo.Value) uintptr {
+	return shapeMemory(value.Shape())
+}
+
+// shapeMemory returns the memory used by the shape, including all elements of tuples.
+func shapeMemory(shape shapes.Shape) uintptr {
+	if !shape.IsTuple() {
+		return shape.Memory()
+	}
+	var memory uintptr
+	for _, element := range shape.TupleShapes {
+		memory += shapeMemory(element)
+	}
+	return memory
+}
@@ -0,0 +1,114 @@
+package analysis
+
+import (
+	"testing"
+	"time"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func must[T any](value T, err error) T {
+	if err != nil {
+		panic(err)
+	}
+	return value
+}
+
+func TestLiveness(t *testing.T) {
+	builder := stablehlo.New(t.Name())
+	fn := builder.Main()
+	shape := shapes.Make(dtypes.Float32, 10) // 40 bytes.
+	x := must(fn.NamedInput("x", shape))
+	y := must(fn.NamedInput("y", shape))
+	a := must(stablehlo.Add(x, y))          // #0: x, y, a live.
+	b := must(stablehlo.Multiply(a, a))     // #1: y, a, b live -- x is no longer used.
+	c := must(stablehlo.Subtract(b, y))     // #2: y, b, c live.
+	if err := fn.Return(c, y); err != nil { // #3: y, c live.
+		t.Fatalf("failed to return: %v", err)
+	}
+
+	report := must(Liveness(fn))
+	wantLive := []int{3, 3, 3, 2}
+	for idx, stmt := range report.Statements {
+		if len(stmt.Live) != wantLive[idx] {
+			t.Errorf("statement #%d: expected %d live values, got %v", idx, wantLive[idx], stmt.Live)
+		}
+		if stmt.Memory != uintptr(40*wantLive[idx]) {
+			t.Errorf("statement #%d: expected %d bytes live, got %d", idx, 40*wantLive[idx], stmt.Memory)
+		}
+	}
+	if report.PeakMemory != 120 || report.PeakStatement != 0 {
+		t.Errorf("expected peak memory of 120 bytes at statement #0, got %d bytes at #%d",
+			report.PeakMemory, report.PeakStatement)
+	}
+	if len(report.DonationCandidates) != 1 || report.DonationCandidates[0].Input != x ||
+		report.DonationCandidates[0].Output != c {
+		t.Errorf("expected x to be a donation candidate for c, got %+v", report.DonationCandidates)
+	}
+
+	notReturned := builder.NewFunction("notReturned")
+	if _, err := Liveness(notReturned); err == nil {
+		t.Error("expected error for function not returned, got nil")
+	}
+}
+
+func TestLivenessAfterRematerialize(t *testing.T) {
+	builder := stablehlo.New(t.Name())
+	fn := builder.Main()
+	shape := shapes.Make(dtypes.Float32, 10) // 40 bytes.
+	x := must(fn.NamedInput("x", shape))
+	ones := must(stablehlo.BroadcastInDim(must(fn.ConstantFromScalar(float32(1))), shape, nil))
+	a := must(stablehlo.Add(x, ones))
+	b := must(stablehlo.Concatenate(0, a, a)) // Peak: a, b (80 bytes) and ones, if it's kept alive.
+	c := must(stablehlo.Slice(b, []int{5}, []int{15}, nil))
+	if err := fn.Return(must(stablehlo.Subtract(c, ones))); err != nil {
+		t.Fatalf("failed to return: %v", err)
+	}
+
+	before := must(Liveness(fn)).PeakMemory
+	if numCopies := must(fn.Rematerialize(2)); numCopies != 1 {
+		t.Fatalf("expected ones to be rematerialized once, got %d copies", numCopies)
+	}
+	after := must(Liveness(fn)).PeakMemory
+	if after >= before {
+		t.Errorf("expected Rematerialize to lower the peak memory, got %d bytes before and %d after", before, after)
+	}
+}
+
+// chainOfAdds builds a function with numOps Add operations chained one after the other, all using the input x.
+func chainOfAdds(numOps int) *stablehlo.Function {
+	fn := stablehlo.New("chain").Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 16)))
+	y := x
+	for range numOps {
+		y = must(stablehlo.Add(y, x))
+	}
+	if err := fn.Return(y); err != nil {
+		panic(err)
+	}
+	return fn
+}
+
+// BenchmarkLivenessScaling checks that Liveness scales linearly with the number of statements: the time per
+// statement of a 10x larger function must not grow by more than a small factor.
+func BenchmarkLivenessScaling(b *testing.B) {
+	const smallNumOps, largeNumOps = 10_000, 100_000
+	smallFn, largeFn := chainOfAdds(smallNumOps), chainOfAdds(largeNumOps)
+	var small, large time.Duration
+	for range b.N {
+		start := time.Now()
+		_ = must(Liveness(smallFn))
+		small += time.Since(start)
+		start = time.Now()
+		_ = must(Liveness(largeFn))
+		large += time.Since(start)
+	}
+	ratio := (float64(large) / largeNumOps) / (float64(small) / smallNumOps)
+	b.ReportMetric(ratio, "scaling")
+	if ratio > 3 {
+		b.Fatalf("Liveness is super-linear: the time per statement grew %.1fx from %d to %d statements",
+			ratio, smallNumOps, largeNumOps)
+	}
+}
@@ -0,0 +1,220 @@
+package stablehlo
+
+import (
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/shapeinference"
+	"github.com/gomlx/stablehlo/types"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// TieBreak selects which index ArgMax and ArgMin return when the extreme value appears more than once along the
+// reduced axis.
+//
+// Frameworks differ here (NumPy, JAX and PyTorch return the lowest index, some kernels the highest), and a silent
+// mismatch is hard to debug, so it is always given explicitly.
+type TieBreak int
+
+const (
+	// TiesToLowestIndex returns the first index with the extreme value, as NumPy's and JAX's argmax.
+	TiesToLowestIndex TieBreak = iota
+
+	// TiesToHighestIndex returns the last index with the extreme value.
+	TiesToHighestIndex
+)
+
+// String implements fmt.Stringer.
+func (t TieBreak) String() string {
+	switch t {
+	case TiesToLowestIndex:
+		return "TiesToLowestIndex"
+	case TiesToHighestIndex:
+		return "TiesToHighestIndex"
+	default:
+		return "InvalidTieBreak"
+	}
+}
+
+// ArgMax returns the indices of the maximum values of x along the given axis, with the given integer outputDType,
+// which must be able to hold all the indices of the axis. The output has the shape of x without the axis.
+//
+// NaNs are considered larger than any other value, so the index of a NaN is returned if there is one. When the
+// maximum appears more than once, tieBreak selects which of the indices is returned.
+//
+// It is implemented with a MultiReduce of the values and their indices, whose comparator closure implements the
+// tie-breaking.
+func ArgMax(x *Value, axis int, outputDType dtypes.DType, tieBreak TieBreak) (*Value, error) {
+	return argMinMax(x, axis, outputDType, tieBreak, true)
+}
+
+// ArgMin returns the indices of the minimum values of x along the given axis, with the given integer outputDType,
+// which must be able to hold all the indices of the axis. The output has the shape of x without the axis.
+//
+// NaNs are considered smaller than any other value, so the index of a NaN is returned if there is one. When the
+// minimum appears more than once, tieBreak selects which of the indices is returned.
+//
+// It is implemented with a MultiReduce of the values and their indices, whose comparator closure implements the
+// tie-breaking.
+func ArgMin(x *Value, axis int, outputDType dtypes.DType, tieBreak TieBreak) (*Value, error) {
+	return argMinMax(x, axis, outputDType, tieBreak, false)
+}
+
+// argMinMax implements ArgMax (if isMax) and ArgMin.
+func argMinMax(x *Value, axis int, outputDType dtypes.DType, tieBreak TieBreak, isMax bool) (output *Value, err error) {
+	name := "ArgMin"
+	if isMax {
+		name = "ArgMax"
+	}
+	fn := x.fn
+	defer fn.opErrorHandler(&err, &output)()
+	if fn.Returned {
+		return nil, errors.Errorf("cannot add operation %s after returning, in function %q", name, fn.Name)
+	}
+	if tieBreak != TiesToLowestIndex && tieBreak != TiesToHighestIndex {
+		return nil, errors.Errorf("%s: invalid tie-breaking mode %d", name, tieBreak)
+	}
+	adjustedAxis, err := shapeinference.AdjustAxisToRank(axis, x.shape.Rank())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "%s", name)
+	}
+	if _, err = shapeinference.ArgMinMax(x.shape, adjustedAxis, outputDType); err != nil {
+		return nil, err
+	}
+	// The indices are generated in outputDType, so it must hold all the indices of the axis.
+	_, maxIndex, _ := integerRange(outputDType)
+	if dim := x.shape.Dimensions[adjustedAxis]; dim > 0 && uint64(dim-1) > maxIndex {
+		return nil, errors.Errorf("%s: output dtype %s can't hold the indices of axis %d with dimension %d",
+			name, outputDType, adjustedAxis, dim)
+	}
+	dtype := x.shape.DType
+
+	// Initial values: a value that any element (other than an equal one) replaces, and an index that loses all ties.
+	var initValue, initIndex *Value
+	switch {
+	case dtype.IsFloat():
+		format, err := fn.floatFormatFor(dtype, name)
+		if err != nil {
+			return nil, err
+		}
+		if _, hasInf := format.infinityBits(isMax); hasInf {
+			initValue, err = fn.ConstantInf(dtype, isMax)
+		} else if isMax {
+			// Dtypes without infinity: the tie-breaking makes the extreme finite values work as well.
+			initValue, err = fn.ConstantMinFinite(dtype)
+		} else {
+			initValue, err = fn.ConstantMaxFinite(dtype)
+		}
+		if err != nil {
+			return nil, err
+		}
+	case isMax:
+		initValue, err = fn.scalarConstantOfDType(dtype, dtype.LowestValue())
+	default:
+		initValue, err = fn.scalarConstantOfDType(dtype, dtype.HighestValue())
+	}
+	if err != nil {
+		return nil, err
+	}
+	if tieBreak == TiesToLowestIndex {
+		initIndex, err = fn.scalarConstantOfDType(outputDType, outputDType.HighestValue())
+	} else {
+		initIndex, err = fn.scalarConstantOfDType(outputDType, 0)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	comparator, err := fn.argMinMaxClosure(dtype, outputDType, tieBreak, isMax)
+	if err != nil {
+		return nil, err
+	}
+	indices, err := fn.Iota(shapes.Make(outputDType, x.shape.Dimensions...), adjustedAxis)
+	if err != nil {
+		return nil, err
+	}
+	outputs, err := MultiReduce([]*Value{x, indices}, []*Value{initValue, initIndex}, comparator, adjustedAxis)
+	if err != nil {
+		return nil, err
+	}
+	return outputs[1], nil
+}
+
+// argMinMaxClosure returns the comparator closure of ArgMax (if isMax) and ArgMin: it takes
+// (lhsValue, lhsIndex, rhsValue, rhsIndex) and returns the (value, index) pair that wins.
+//
+// The pairs are totally ordered -- by value, NaN being the extreme, and then by index according to the tieBreak --,
+// so the reduction is associative and commutative, as required by Reduce.
+func (fn *Function) argMinMaxClosure(dtype, indexDType dtypes.DType, tieBreak TieBreak, isMax bool) (*Function, error) {
+	closure := fn.Closure()
+	if err := closure.DeclareOutputs(shapes.Make(dtype), shapes.Make(indexDType)); err != nil {
+		return nil, err
+	}
+	var inputs [4]*Value
+	for i, input := range []struct {
+		name  string
+		dtype dtypes.DType
+	}{{"lhs", dtype}, {"lhsIndex", indexDType}, {"rhs", dtype}, {"rhsIndex", indexDType}} {
+		var err error
+		inputs[i], err = closure.NamedInput(input.name, shapes.Make(input.dtype))
+		if err != nil {
+			return nil, err
+		}
+	}
+	lhs, lhsIndex, rhs, rhsIndex := inputs[0], inputs[1], inputs[2], inputs[3]
+
+	direction := types.CompareLT
+	if isMax {
+		direction = types.CompareGT
+	}
+	wins, err := CompareAuto(lhs, rhs, direction)
+	if err != nil {
+		return nil, err
+	}
+	equal, err := CompareAuto(lhs, rhs, types.CompareEQ)
+	if err != nil {
+		return nil, err
+	}
+	if dtype.IsFloat() {
+		// NaN wins over any other value, and ties with NaN.
+		lhsIsNaN, err := CompareAuto(lhs, lhs, types.CompareNE)
+		if err != nil {
+			return nil, err
+		}
+		rhsIsNaN, err := CompareAuto(rhs, rhs, types.CompareNE)
+		if err != nil {
+			return nil, err
+		}
+		wins, err = closure.Expr(rhsIsNaN).Not().And(lhsIsNaN).Or(wins).Value()
+		if err != nil {
+			return nil, err
+		}
+		equal, err = closure.Expr(lhsIsNaN).And(rhsIsNaN).Or(equal).Value()
+		if err != nil {
+			return nil, err
+		}
+	}
+	indexDirection := types.CompareLT
+	if tieBreak == TiesToHighestIndex {
+		indexDirection = types.CompareGT
+	}
+	winsTie, err := CompareAuto(lhsIndex, rhsIndex, indexDirection)
+	if err != nil {
+		return nil, err
+	}
+	pickLhs, err := closure.Expr(equal).And(winsTie).Or(wins).Value()
+	if err != nil {
+		return nil, err
+	}
+	value, err := Select(pickLhs, lhs, rhs)
+	if err != nil {
+		return nil, err
+	}
+	index, err := Select(pickLhs, lhsIndex, rhsIndex)
+	if err != nil {
+		return nil, err
+	}
+	if err = closure.Return(value, index); err != nil {
+		return nil, err
+	}
+	return closure, nil
+}
@@ -0,0 +1,85 @@
+package stablehlo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestArgMinMax(t *testing.T) {
+	builder := New(t.Name())
+	fn := builder.Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2, 3)))
+	if _, err := ArgMax(x, 2, dtypes.Int32, TiesToLowestIndex); err == nil {
+		t.Error("expected error for invalid axis")
+	}
+	if _, err := ArgMax(x, 1, dtypes.Float32, TiesToLowestIndex); err == nil {
+		t.Error("expected error for float output dtype")
+	}
+	if _, err := ArgMin(x, 1, dtypes.Int32, TieBreak(2)); err == nil {
+		t.Error("expected error for invalid tie-breaking mode")
+	}
+	long := must(New("long").Main().NamedInput("long", shapes.Make(dtypes.Float32, 300)))
+	if _, err := ArgMax(long, 0, dtypes.Int8, TiesToLowestIndex); err == nil {
+		t.Error("expected error for output dtype that can't hold the indices of the axis")
+	}
+	if _, err := ArgMin(long, 0, dtypes.Uint8, TiesToHighestIndex); err == nil {
+		t.Error("expected error for output dtype that can't hold the indices of the axis")
+	}
+	argMax := must(ArgMax(x, -1, dtypes.Int32, TiesToLowestIndex))
+	if want := shapes.Make(dtypes.Int32, 2); !argMax.Shape().Equal(want) {
+		t.Fatalf("ArgMax shape is %s, wanted %s", argMax.Shape(), want)
+	}
+	must0(fn.Return(argMax))
+	program := string(must(builder.Build()))
+	fmt.Printf("%s program:\n%s", t.Name(), program)
+	want := `module @TestArgMinMax {
+  func.func @main(%x: tensor<2x3xf32>) -> tensor<2xi32> {
+    %0 = "stablehlo.constant"() { value = dense<0xff800000> : tensor<f32> } : () -> tensor<f32>
+    %1 = "stablehlo.constant"() { value = dense<2147483647> : tensor<i32> } : () -> tensor<i32>
+    %16 = "stablehlo.iota"() { iota_dimension = 1 : i64 } : () -> tensor<2x3xi32>
+    %17, %18 = "stablehlo.reduce"(%x, %16, %0, %1) ({
+      ^reductionFn(%lhs: tensor<f32>, %lhsIndex: tensor<i32>, %rhs: tensor<f32>, %rhsIndex: tensor<i32>) :
+          %2 = "stablehlo.compare"(%lhs, %rhs) {
+            compare_type = #stablehlo<comparison_type FLOAT>,
+            comparison_direction = #stablehlo<comparison_direction GT>
+          } : (tensor<f32>, tensor<f32>) -> tensor<i1>
+          %3 = "stablehlo.compare"(%lhs, %rhs) {
+            compare_type = #stablehlo<comparison_type FLOAT>,
+            comparison_direction = #stablehlo<comparison_direction EQ>
+          } : (tensor<f32>, tensor<f32>) -> tensor<i1>
+          %4 = "stablehlo.compare"(%lhs, %lhs) {
+            compare_type = #stablehlo<comparison_type FLOAT>,
+            comparison_direction = #stablehlo<comparison_direction NE>
+          } : (tensor<f32>, tensor<f32>) -> tensor<i1>
+          %5 = "stablehlo.compare"(%rhs, %rhs) {
+            compare_type = #stablehlo<comparison_type FLOAT>,
+            comparison_direction = #stablehlo<comparison_direction NE>
+          } : (tensor<f32>, tensor<f32>) -> tensor<i1>
+          %6 = "stablehlo.not"(%5) : (tensor<i1>) -> tensor<i1>
+          %7 = "stablehlo.and"(%6, %4) : (tensor<i1>, tensor<i1>) -> tensor<i1>
+          %8 = "stablehlo.or"(%7, %2) : (tensor<i1>, tensor<i1>) -> tensor<i1>
+          %9 = "stablehlo.and"(%4, %5) : (tensor<i1>, tensor<i1>) -> tensor<i1>
+          %10 = "stablehlo.or"(%9, %3) : (tensor<i1>, tensor<i1>) -> tensor<i1>
+          %11 = "stablehlo.compare"(%lhsIndex, %rhsIndex) {
+            compare_type = #stablehlo<comparison_type SIGNED>,
+            comparison_direction = #stablehlo<comparison_direction LT>
+          } : (tensor<i32>, tensor<i32>) -> tensor<i1>
+          %12 = "stablehlo.and"(%10, %11) : (tensor<i1>, tensor<i1>) -> tensor<i1>
+          %13 = "stablehlo.or"(%12, %8) : (tensor<i1>, tensor<i1>) -> tensor<i1>
+          %14 = "stablehlo.select"(%13, %lhs, %rhs) : (tensor<i1>, tensor<f32>, tensor<f32>) -> tensor<f32>
+          %15 = "stablehlo.select"(%13, %lhsIndex, %rhsIndex) : (tensor<i1>, tensor<i32>, tensor<i32>) -> tensor<i32>
+          "stablehlo.return"(%14, %15) : (tensor<f32>, tensor<i32>) -> ()
+    }) { dimensions = array<i64: 1> } : (tensor<2x3xf32>, tensor<2x3xi32>, tensor<f32>, tensor<i32>) -> (tensor<2xf32>, tensor<2xi32>)
+    "stablehlo.return"(%18) : (tensor<2xi32>) -> ()
+  }
+}
+`
+	if program != want {
+		fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+		t.Fatal("programs don't match")
+	}
+	requireRoundTrip(t, builder)
+}
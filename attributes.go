@@ -0,0 +1,293 @@
+package stablehlo
+
+import (
+	"maps"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/shapeinference"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// Attribute returns the value of the named attribute of the statement, and whether it is set.
+//
+// Attributes created by this package are often stored as their StableHLO text, use Statement.AttributeString
+// to get the rendered value, or Statement.AttributeInts for integer arrays (e.g. the Slice "start_indices").
+func (s *Statement) Attribute(name string) (value any, found bool) {
+	value, found = s.Attributes[name]
+	return
+}
+
+// AttributeNames returns the sorted names of the attributes set in the statement.
+func (s *Statement) AttributeNames() []string {
+	return slices.Sorted(maps.Keys(s.Attributes))
+}
+
+// AttributeString returns the StableHLO text of the named attribute, as it is written in the program
+// (e.g. "array<i64: 0, 1>" or "true"), and whether it is set.
+func (s *Statement) AttributeString(name string) (string, bool) {
+	value, found := s.Attributes[name]
+	if !found {
+		return "", false
+	}
+	return literalToStableHLO(value), true
+}
+
+// AttributeInts returns the value of an integer attribute -- an `array<i64: ...>` or a scalar integer, which is
+// returned as a slice with one element.
+func (s *Statement) AttributeInts(name string) ([]int, error) {
+	value, found := s.Attributes[name]
+	if !found {
+		return nil, errors.Errorf("%s has no attribute %q", s.OpName(), name)
+	}
+	ints, err := attributeToInts(value)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "attribute %q of %s", name, s.OpName())
+	}
+	return ints, nil
+}
+
+// SetAttribute sets (or changes) an attribute of the statement after it has been created, e.g. to flip
+// "indices_are_sorted" or change the padding of a Pad in a transformation pass. A nil value removes the attribute.
+//
+// The value can be a bool, a string, a Go number, a []int (converted to an `array<i64: ...>`), a []bool
+// (converted to an `array<i1: ...>`), a []float32 or []float64 (converted to an `array<f32: ...>` or
+// `array<f64: ...>`), or one of the attribute values read from another statement.
+//
+// For operations whose output shapes depend on their attributes (Transpose, Slice, Pad, BroadcastInDim, Reverse,
+// Concatenate, Iota and Reduce), the output shapes are inferred again: an invalid attribute, or a change of the
+// shape of an output that is already used, returns an error and leaves the statement unchanged. Outputs that are not
+// used yet get their new shapes. For other operations the attributes are not validated.
+//
+// Use Statement.SetAttributes to change attributes that are only valid together (e.g. the Slice start and limit).
+func (s *Statement) SetAttribute(name string, value any) error {
+	return s.SetAttributes(map[string]any{name: value})
+}
+
+// SetAttributes sets (or changes) several attributes of the statement at once, and validates them together.
+// See Statement.SetAttribute.
+func (s *Statement) SetAttributes(attributes map[string]any) error {
+	if s.OpType == optypes.RawSnippet || s.OpType == optypes.FuncReturn {
+		return errors.Errorf("cannot set attributes of %s statements", s.OpType)
+	}
+	names := slices.Sorted(maps.Keys(attributes))
+	normalized := make(map[string]any, len(attributes))
+	for _, name := range names {
+		if name == "" {
+			return errors.New("SetAttributes requires non-empty attribute names")
+		}
+		switch v := attributes[name].(type) {
+		case []int:
+			normalized[name] = intSliceToArrayI64StableHLO(v)
+		case []bool:
+			normalized[name] = boolSliceToArrayI1StableHLO(v)
+		case []float32:
+			normalized[name] = float32SliceToArrayF32StableHLO(v)
+		case []float64:
+			normalized[name] = float64SliceToArrayF64StableHLO(v)
+		case nil, string, bool, float32, float64, int, int8, int16, int32, int64, uint8, uint16, uint32, uint64,
+			hasToStableHLO:
+			normalized[name] = v
+		default:
+			return errors.Errorf("attribute %q of %s: unsupported value type %T", name, s.OpName(), v)
+		}
+	}
+
+	previous := maps.Clone(s.Attributes)
+	if s.Attributes == nil {
+		s.Attributes = make(map[string]any)
+	}
+	for name, value := range normalized {
+		if value == nil {
+			delete(s.Attributes, name)
+		} else {
+			s.Attributes[name] = value
+		}
+	}
+	if err := s.revalidateShapes(); err != nil {
+		s.Attributes = previous
+		return errors.WithMessagef(err, "setting attributes %q of %s", names, s.OpName())
+	}
+	return nil
+}
+
+// revalidateShapes infers the output shapes of the statement from its attributes again, and updates the shapes of
+// the outputs not yet used. It fails if the attributes are invalid or the shape of a used output would change.
+func (s *Statement) revalidateShapes() error {
+	infer, found := attributeShapeInference[s.OpType]
+	if !found {
+		return nil
+	}
+	outputShapes, err := infer(s)
+	if err != nil {
+		return err
+	}
+	if len(outputShapes) != len(s.Outputs) {
+		return errors.Errorf("attributes yield %d outputs, but the statement has %d", len(outputShapes), len(s.Outputs))
+	}
+	for i, output := range s.Outputs {
+		if !output.shape.Equal(outputShapes[i]) && output.NumUses() > 0 {
+			return errors.Errorf("it would change the shape of output #%d from %s to %s, but it is already used",
+				i, output.shape, outputShapes[i])
+		}
+	}
+	for i, output := range s.Outputs {
+		output.shape = outputShapes[i]
+	}
+	return nil
+}
+
+// attributeShapeInference infers the output shapes of the statements whose shapes depend on their attributes,
+// see Statement.SetAttribute.
+var attributeShapeInference = map[optypes.OpType]func(s *Statement) ([]shapes.Shape, error){
+	optypes.Transpose: func(s *Statement) ([]shapes.Shape, error) {
+		permutation, err := s.AttributeInts("permutation")
+		if err != nil {
+			return nil, err
+		}
+		output, err := shapeinference.Transpose(s.Inputs[0].shape, permutation)
+		return []shapes.Shape{output}, err
+	},
+	optypes.Slice: func(s *Statement) ([]shapes.Shape, error) {
+		params, err := s.attributesInts("start_indices", "limit_indices", "strides")
+		if err != nil {
+			return nil, err
+		}
+		output, err := shapeinference.Slice(s.Inputs[0].shape, params[0], params[1], params[2])
+		return []shapes.Shape{output}, err
+	},
+	optypes.Pad: func(s *Statement) ([]shapes.Shape, error) {
+		params, err := s.attributesInts("edge_padding_low", "edge_padding_high", "interior_padding")
+		if err != nil {
+			return nil, err
+		}
+		output, err := shapeinference.Pad(s.Inputs[0].shape, s.Inputs[1].shape, params[0], params[1], params[2])
+		return []shapes.Shape{output}, err
+	},
+	optypes.BroadcastInDim: func(s *Statement) ([]shapes.Shape, error) {
+		axesMapping, err := s.AttributeInts("broadcast_dimensions")
+		if err != nil {
+			return nil, err
+		}
+		output := s.Outputs[0].shape
+		return []shapes.Shape{output}, shapeinference.BroadcastInDim(s.Inputs[0].shape, output, axesMapping)
+	},
+	optypes.Reverse: func(s *Statement) ([]shapes.Shape, error) {
+		axes, err := s.AttributeInts("dimensions")
+		if err != nil {
+			return nil, err
+		}
+		if err := checkAttributeAxes(axes, s.Inputs[0].shape.Rank()); err != nil {
+			return nil, err
+		}
+		return []shapes.Shape{s.Inputs[0].shape}, nil
+	},
+	optypes.Concatenate: func(s *Statement) ([]shapes.Shape, error) {
+		axis, err := s.AttributeInts("dimension")
+		if err != nil {
+			return nil, err
+		}
+		if len(axis) != 1 {
+			return nil, errors.Errorf("dimension must be a single axis, got %v", axis)
+		}
+		if err := checkAttributeAxes(axis, s.Inputs[0].shape.Rank()); err != nil {
+			return nil, err
+		}
+		output, err := shapeinference.Concatenate(valuesToShapes(s.Inputs), axis[0])
+		return []shapes.Shape{output}, err
+	},
+	optypes.Iota: func(s *Statement) ([]shapes.Shape, error) {
+		axis, err := s.AttributeInts("iota_dimension")
+		if err != nil {
+			return nil, err
+		}
+		output := s.Outputs[0].shape
+		if len(axis) != 1 {
+			return nil, errors.Errorf("iota_dimension must be a single axis, got %v", axis)
+		}
+		return []shapes.Shape{output}, checkAttributeAxes(axis, output.Rank())
+	},
+	optypes.Reduce: func(s *Statement) ([]shapes.Shape, error) {
+		axes, err := s.AttributeInts("dimensions")
+		if err != nil {
+			return nil, err
+		}
+		if err := checkAttributeAxes(axes, s.Inputs[0].shape.Rank()); err != nil {
+			return nil, err
+		}
+		numInputs := len(s.Inputs) / 2
+		reductionFn := s.FunctionParameters[0]
+		return shapeinference.Reduce(valuesToShapes(s.Inputs[:numInputs]), valuesToShapes(s.Inputs[numInputs:]),
+			valuesToShapes(reductionFn.Inputs), valuesToShapes(reductionFn.Outputs), axes)
+	},
+}
+
+// attributesInts returns the values of the given integer attributes, see Statement.AttributeInts.
+func (s *Statement) attributesInts(names ...string) ([][]int, error) {
+	values := make([][]int, len(names))
+	for i, name := range names {
+		var err error
+		if values[i], err = s.AttributeInts(name); err != nil {
+			return nil, err
+		}
+	}
+	return values, nil
+}
+
+// checkAttributeAxes checks that the axes stored in an attribute are in the range [0, rank).
+// Contrary to the ops arguments, negative axes are not valid in the StableHLO attributes.
+func checkAttributeAxes(axes []int, rank int) error {
+	for _, axis := range axes {
+		if axis < 0 || axis >= rank {
+			return errors.Errorf("invalid axis %d for rank %d", axis, rank)
+		}
+	}
+	return nil
+}
+
+// attributeToInts converts an attribute value with integers (an `array<i64: ...>` or a scalar integer) to a slice.
+func attributeToInts(value any) ([]int, error) {
+	switch v := value.(type) {
+	case int:
+		return []int{v}, nil
+	case int32:
+		return []int{int(v)}, nil
+	case int64:
+		return []int{int(v)}, nil
+	case literalStr:
+		text := strings.TrimSpace(string(v))
+		if scalar, _, found := strings.Cut(text, ":"); found && !strings.HasPrefix(text, "array<") {
+			// Scalar with its type, e.g. "1 : i64".
+			text = strings.TrimSpace(scalar)
+			n, err := strconv.Atoi(text)
+			if err != nil {
+				return nil, errors.Errorf("invalid integer attribute %q", v)
+			}
+			return []int{n}, nil
+		}
+		body, isArray := strings.CutPrefix(text, "array<i64")
+		body, isClosed := strings.CutSuffix(body, ">")
+		if !isArray || !isClosed {
+			return nil, errors.Errorf("attribute %q is not an array<i64: ...>", v)
+		}
+		body = strings.TrimSpace(strings.TrimPrefix(strings.TrimSpace(body), ":"))
+		if body == "" {
+			return []int{}, nil
+		}
+		parts := strings.Split(body, ",")
+		ints := make([]int, len(parts))
+		for i, part := range parts {
+			n, err := strconv.Atoi(strings.TrimSpace(part))
+			if err != nil {
+				return nil, errors.Errorf("invalid element %q in attribute %q", part, v)
+			}
+			ints[i] = n
+		}
+		return ints, nil
+	default:
+		return nil, errors.Errorf("attribute value of type %T is not an integer array", value)
+	}
+}
@@ -0,0 +1,68 @@
+package stablehlo
+
+import (
+	"slices"
+
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/pkg/errors"
+)
+
+// OptimizationBarrier returns the operands unchanged, but it ensures that the operations producing them are all
+// executed before any operation that uses its outputs: the compiler won't move computations across it.
+//
+// See Function.OrderingGroup to keep groups of statements from being reordered.
+func OptimizationBarrier(operands ...*Value) (outputs []*Value, err error) {
+	op := optypes.OptimizationBarrier
+	if len(operands) == 0 {
+		return nil, errors.Errorf("%s requires at least one operand", op)
+	}
+	fn := operands[0].fn
+	defer fn.multiOpErrorHandler(&err, &outputs, len(operands))()
+	if fn.Returned {
+		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
+			op, fn.Name)
+	}
+	for i, operand := range operands {
+		if operand.fn != fn {
+			return nil, errors.Errorf("cannot add operation %s to function %q, because operand #%d is from different function (%q and %q)",
+				op, fn.Name, i, operand.fn.Name, fn.Name)
+		}
+	}
+	stmt := fn.addMultiOp(op, valuesToShapes(operands), slices.Clone(operands))
+	return stmt.Outputs, nil
+}
+
+// OrderingGroup adds the statements created by body as a group that the compiler won't reorder with the
+// statements created before or after it -- e.g. to keep the phases of a program (forward, backward, optimizer
+// update) apart.
+//
+// The inputs of the group (the values created before it, used by body) are passed through an OptimizationBarrier
+// and given to body, and the values returned by body are passed through another OptimizationBarrier and returned:
+// the statements after the group must use the returned values (and not the ones returned by body).
+// If there are no inputs, only the outputs barrier is added.
+func (fn *Function) OrderingGroup(inputs []*Value, body func(inputs []*Value) ([]*Value, error)) (outputs []*Value, err error) {
+	if len(inputs) > 0 {
+		inputs, err = OptimizationBarrier(inputs...)
+		if err != nil {
+			return nil, errors.WithMessage(err, "OrderingGroup inputs")
+		}
+	}
+	groupOutputs, err := body(inputs)
+	if err != nil {
+		return nil, err
+	}
+	if len(groupOutputs) == 0 {
+		return nil, errors.New("OrderingGroup body must return at least one value")
+	}
+	for i, output := range groupOutputs {
+		if output.fn != fn {
+			return nil, errors.Errorf("OrderingGroup body returned value #%d from a different function (%q and %q)",
+				i, output.fn.Name, fn.Name)
+		}
+	}
+	outputs, err = OptimizationBarrier(groupOutputs...)
+	if err != nil {
+		return nil, errors.WithMessage(err, "OrderingGroup outputs")
+	}
+	return outputs, nil
+}
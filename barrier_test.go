@@ -0,0 +1,63 @@
+package stablehlo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+func TestOrderingGroup(t *testing.T) {
+	t.Run("Rendering", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		outputs := must(fn.OrderingGroup([]*Value{x}, func(inputs []*Value) ([]*Value, error) {
+			y, err := Multiply(inputs[0], inputs[0])
+			if err != nil {
+				return nil, err
+			}
+			return []*Value{y, inputs[0]}, nil
+		}))
+		must0(fn.Return(must(Add(outputs[0], outputs[1]))))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestOrderingGroup_Rendering {
+  func.func @main(%x: tensor<3xf32>) -> tensor<3xf32> {
+    %0 = "stablehlo.optimization_barrier"(%x) : (tensor<3xf32>) -> tensor<3xf32>
+    %1 = "stablehlo.multiply"(%0, %0) : (tensor<3xf32>, tensor<3xf32>) -> tensor<3xf32>
+    %2, %3 = "stablehlo.optimization_barrier"(%1, %0) : (tensor<3xf32>, tensor<3xf32>) -> (tensor<3xf32>, tensor<3xf32>)
+    %4 = "stablehlo.add"(%2, %3) : (tensor<3xf32>, tensor<3xf32>) -> tensor<3xf32>
+    "stablehlo.return"(%4) : (tensor<3xf32>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		if _, err := OptimizationBarrier(); err == nil {
+			t.Error("expected error for OptimizationBarrier without operands")
+		}
+		if _, err := fn.OrderingGroup(nil, func([]*Value) ([]*Value, error) { return nil, nil }); err == nil {
+			t.Error("expected error for OrderingGroup body without outputs")
+		}
+		bodyErr := errors.New("body failed")
+		if _, err := fn.OrderingGroup([]*Value{x}, func([]*Value) ([]*Value, error) { return nil, bodyErr }); !errors.Is(err, bodyErr) {
+			t.Errorf("expected the body error, got %v", err)
+		}
+		other := New("other").Main()
+		y := must(other.NamedInput("y", shapes.Make(dtypes.Float32, 3)))
+		if _, err := fn.OrderingGroup(nil, func([]*Value) ([]*Value, error) { return []*Value{y}, nil }); err == nil {
+			t.Error("expected error for OrderingGroup body returning a value of another function")
+		}
+	})
+}
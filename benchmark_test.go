@@ -0,0 +1,183 @@
+package stablehlo
+
+import (
+	"fmt"
+	"io"
+	"testing"
+	"time"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+// Benchmarks of the builder hot path: adding operations (addOp), encoding attributes and rendering the program (Build).
+//
+// Run them with:
+//
+//	go test -run='^$' -bench=. -benchmem .
+//
+// Besides the usual ns/op (per full graph), they report "ns/graph-op": the time per operation in the graph.
+
+// benchmarkGraphSizes are the number of operations in the graphs benchmarked.
+var benchmarkGraphSizes = []int{10_000, 100_000, 1_000_000}
+
+// benchmarkAddChain builds a graph with numOps Add operations, chained one after the other.
+func benchmarkAddChain(numOps int) *Builder {
+	return benchmarkAddChainIn(New("benchmark"), numOps)
+}
+
+// benchmarkAddChainIn is like benchmarkAddChain, but it builds the graph in the given (configured) Builder.
+func benchmarkAddChainIn(b *Builder, numOps int) *Builder {
+	fn := b.Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 16)))
+	y := x
+	for range numOps {
+		y = must(Add(y, x))
+	}
+	if err := fn.Return(y); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// benchmarkSliceChain builds a graph with numOps Slice operations, all with starts, limits and strides attributes.
+func benchmarkSliceChain(numOps int) *Builder {
+	b := New("benchmark")
+	fn := b.Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 4, 8, 16)))
+	outputs := make([]*Value, 0, numOps)
+	for i := range numOps {
+		outputs = append(outputs, must(Slice(x, []int{0, i % 8, 0}, []int{4, 8, 16}, []int{1, 1, 1 + i%3})))
+	}
+	// Only return the last one, the others are dead code, but they are still rendered.
+	if err := fn.Return(outputs[len(outputs)-1]); err != nil {
+		panic(err)
+	}
+	return b
+}
+
+// reportPerGraphOp reports the time per operation in the graph.
+func reportPerGraphOp(b *testing.B, numOps int) {
+	b.ReportMetric(float64(b.Elapsed().Nanoseconds())/float64(b.N)/float64(numOps), "ns/graph-op")
+}
+
+func BenchmarkAddOp(b *testing.B) {
+	for _, numOps := range benchmarkGraphSizes {
+		b.Run(fmt.Sprintf("ops=%d", numOps), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				_ = benchmarkAddChain(numOps)
+			}
+			reportPerGraphOp(b, numOps)
+		})
+	}
+}
+
+func BenchmarkAddOpWithShapeCache(b *testing.B) {
+	for _, numOps := range benchmarkGraphSizes {
+		b.Run(fmt.Sprintf("ops=%d", numOps), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				_ = benchmarkAddChainIn(New("benchmark").WithShapeInferenceCache(true), numOps)
+			}
+			reportPerGraphOp(b, numOps)
+		})
+	}
+}
+
+// BenchmarkShapeInference measures the shape inference of Add and Reduce (as called while adding the
+// operations), with and without the shape inference cache.
+func BenchmarkShapeInference(b *testing.B) {
+	shape := shapes.Make(dtypes.Float32, 16, 32, 64)
+	scalar := shapes.Make(dtypes.Float32)
+	for _, cached := range []bool{false, true} {
+		builder := New("benchmark").WithShapeInferenceCache(cached)
+		b.Run(fmt.Sprintf("op=Add/cache=%v", cached), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				_ = must(builder.inferBinaryOp(optypes.Add, shape, shape))
+			}
+		})
+		b.Run(fmt.Sprintf("op=Reduce/cache=%v", cached), func(b *testing.B) {
+			b.ReportAllocs()
+			inputs, initialValues := []shapes.Shape{shape}, []shapes.Shape{scalar}
+			reductionInputs, reductionOutputs := []shapes.Shape{scalar, scalar}, []shapes.Shape{scalar}
+			axes := []int{1, 2}
+			for range b.N {
+				_ = must(builder.inferReduce(inputs, initialValues, reductionInputs, reductionOutputs, axes))
+			}
+		})
+	}
+}
+
+func BenchmarkAttributeEncoding(b *testing.B) {
+	for _, numOps := range benchmarkGraphSizes {
+		b.Run(fmt.Sprintf("ops=%d", numOps), func(b *testing.B) {
+			builder := benchmarkSliceChain(numOps)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				if err := builder.Write(io.Discard); err != nil {
+					b.Fatalf("failed to render program: %+v", err)
+				}
+			}
+			reportPerGraphOp(b, numOps)
+		})
+	}
+}
+
+func BenchmarkBuild(b *testing.B) {
+	for _, numOps := range benchmarkGraphSizes {
+		b.Run(fmt.Sprintf("ops=%d", numOps), func(b *testing.B) {
+			builder := benchmarkAddChain(numOps)
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				if _, err := builder.Build(); err != nil {
+					b.Fatalf("failed to build program: %+v", err)
+				}
+			}
+			reportPerGraphOp(b, numOps)
+		})
+	}
+}
+
+func BenchmarkBuildInto(b *testing.B) {
+	for _, numOps := range benchmarkGraphSizes {
+		b.Run(fmt.Sprintf("ops=%d", numOps), func(b *testing.B) {
+			builder := benchmarkAddChain(numOps)
+			var buf []byte
+			b.ReportAllocs()
+			b.ResetTimer()
+			for range b.N {
+				var err error
+				if buf, err = builder.BuildInto(buf); err != nil {
+					b.Fatalf("failed to build program: %+v", err)
+				}
+			}
+			reportPerGraphOp(b, numOps)
+		})
+	}
+}
+
+// BenchmarkAddOpScaling checks that adding operations scales linearly with the size of the graph: the time per
+// operation of a 10x larger graph (where the input x has 10x more uses) must not grow by more than a small factor.
+func BenchmarkAddOpScaling(b *testing.B) {
+	const smallNumOps, largeNumOps = 10_000, 100_000
+	var small, large time.Duration
+	for range b.N {
+		start := time.Now()
+		_ = benchmarkAddChain(smallNumOps)
+		small += time.Since(start)
+		start = time.Now()
+		_ = benchmarkAddChain(largeNumOps)
+		large += time.Since(start)
+	}
+	ratio := (float64(large) / largeNumOps) / (float64(small) / smallNumOps)
+	b.ReportMetric(ratio, "scaling")
+	if ratio > 3 {
+		b.Fatalf("adding operations is super-linear: the time per operation grew %.1fx from %d to %d operations",
+			ratio, smallNumOps, largeNumOps)
+	}
+}
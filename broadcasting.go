@@ -0,0 +1,96 @@
+package stablehlo
+
+import (
+	"slices"
+
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// EnsureRank reshapes x to the given rank by prepending axes of dimension 1 (NumPy-style), e.g. a [3] value
+// becomes [1, 1, 3] for rank 3. It returns x itself if it already has the rank.
+//
+// It returns an error if x has a higher rank or a dynamic shape.
+func EnsureRank(x *Value, rank int) (output *Value, err error) {
+	fn := x.fn
+	defer fn.opErrorHandler(&err, &output)()
+	if x.shape.Rank() == rank {
+		return x, nil
+	}
+	if x.shape.Rank() > rank {
+		return nil, errors.Errorf("EnsureRank(%d): operand with shape %s has a higher rank", rank, x.shape)
+	}
+	if x.shape.IsDynamic() {
+		return nil, errors.Errorf("EnsureRank(%d) requires a static shape, got %s", rank, x.shape)
+	}
+	dimensions := make([]int, rank-x.shape.Rank(), rank)
+	for i := range dimensions {
+		dimensions[i] = 1
+	}
+	dimensions = append(dimensions, x.shape.Dimensions...)
+	return Reshape(x, shapes.Make(x.shape.DType, dimensions...))
+}
+
+// AutoExpand broadcasts the operands to a common shape using the NumPy broadcasting rules, so they can be used
+// with the binary operations, which require operands of the same shape: the lower rank operands are expanded
+// with leading axes, and axes of dimension 1 are broadcast to the dimension of the other operands.
+//
+// E.g. operands of shapes [3], [2, 1] and [] are broadcast to [2, 3]. Operands that already have the common
+// shape are returned as is. The dtypes are not changed.
+//
+// It returns an error if the dimensions of an axis are not compatible (different and not 1), or if any of the
+// operands has a dynamic shape.
+func AutoExpand(operands ...*Value) (outputs []*Value, err error) {
+	if len(operands) == 0 {
+		return nil, errors.New("AutoExpand requires at least one operand")
+	}
+	fn := operands[0].fn
+	defer fn.multiOpErrorHandler(&err, &outputs, len(operands))()
+	rank := 0
+	for i, operand := range operands {
+		if operand.fn != fn {
+			return nil, errors.Errorf("AutoExpand: operand #%d is not part of function %q", i, fn.Name)
+		}
+		if operand.shape.IsDynamic() {
+			return nil, errors.Errorf("AutoExpand requires static shapes, got %s for operand #%d", operand.shape, i)
+		}
+		rank = max(rank, operand.shape.Rank())
+	}
+
+	// Common dimensions, aligned to the right.
+	dimensions := make([]int, rank)
+	for i := range dimensions {
+		dimensions[i] = 1
+	}
+	for _, operand := range operands {
+		offset := rank - operand.shape.Rank()
+		for axis, dim := range operand.shape.Dimensions {
+			switch target := dimensions[offset+axis]; {
+			case dim == target || dim == 1:
+			case target == 1:
+				dimensions[offset+axis] = dim
+			default:
+				return nil, errors.Errorf("AutoExpand: operand shapes %s are not compatible on axis %d (of the "+
+					"broadcast rank %d)", valuesToShapes(operands), offset+axis, rank)
+			}
+		}
+	}
+
+	outputs = make([]*Value, len(operands))
+	for i, operand := range operands {
+		if slices.Equal(operand.shape.Dimensions, dimensions) {
+			outputs[i] = operand
+			continue
+		}
+		offset := rank - operand.shape.Rank()
+		axesMapping := make([]int, operand.shape.Rank())
+		for axis := range axesMapping {
+			axesMapping[axis] = offset + axis
+		}
+		outputs[i], err = BroadcastInDim(operand, shapes.Make(operand.shape.DType, dimensions...), axesMapping)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "AutoExpand: broadcasting operand #%d", i)
+		}
+	}
+	return outputs, nil
+}
@@ -0,0 +1,73 @@
+package stablehlo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestAutoExpand(t *testing.T) {
+	t.Run("Rendering", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		y := must(fn.NamedInput("y", shapes.Make(dtypes.Float32, 2, 1)))
+		s := must(fn.NamedInput("s", shapes.Make(dtypes.Float32)))
+		expanded := must(AutoExpand(x, y, s))
+		sum := must(Add(must(Add(expanded[0], expanded[1])), expanded[2]))
+		must0(fn.Return(sum, must(EnsureRank(x, 3))))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestAutoExpand_Rendering {
+  func.func @main(%x: tensor<3xf32>, %y: tensor<2x1xf32>, %s: tensor<f32>) -> (tensor<2x3xf32>, tensor<1x1x3xf32>) {
+    %0 = "stablehlo.broadcast_in_dim"(%x) { broadcast_dimensions = array<i64: 1> } : (tensor<3xf32>) -> tensor<2x3xf32>
+    %1 = "stablehlo.broadcast_in_dim"(%y) { broadcast_dimensions = array<i64: 0, 1> } : (tensor<2x1xf32>) -> tensor<2x3xf32>
+    %2 = "stablehlo.broadcast_in_dim"(%s) { broadcast_dimensions = array<i64> } : (tensor<f32>) -> tensor<2x3xf32>
+    %3 = "stablehlo.add"(%0, %1) : (tensor<2x3xf32>, tensor<2x3xf32>) -> tensor<2x3xf32>
+    %4 = "stablehlo.add"(%3, %2) : (tensor<2x3xf32>, tensor<2x3xf32>) -> tensor<2x3xf32>
+    %5 = "stablehlo.reshape"(%x) : (tensor<3xf32>) -> tensor<1x1x3xf32>
+    "stablehlo.return"(%4, %5) : (tensor<2x3xf32>, tensor<1x1x3xf32>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+	})
+
+	t.Run("Shapes", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.Input(shapes.Make(dtypes.Int32, 4, 1, 3)))
+		y := must(fn.Input(shapes.Make(dtypes.Int32, 5, 1)))
+		expanded := must(AutoExpand(x, y))
+		for i, value := range expanded {
+			if want := shapes.Make(dtypes.Int32, 4, 5, 3); !value.Shape().Equal(want) {
+				t.Errorf("operand #%d expanded to %s, wanted %s", i, value.Shape(), want)
+			}
+		}
+		same := must(AutoExpand(x, x))
+		if same[0] != x || same[1] != x {
+			t.Error("AutoExpand should return operands already with the common shape as is")
+		}
+		if got := must(EnsureRank(x, 3)); got != x {
+			t.Error("EnsureRank should return x as is if it already has the rank")
+		}
+		if _, err := AutoExpand(x, must(fn.Input(shapes.Make(dtypes.Int32, 2, 4)))); err == nil {
+			t.Error("expected error for incompatible dimensions")
+		}
+		if _, err := AutoExpand(); err == nil {
+			t.Error("expected error for no operands")
+		}
+		if _, err := EnsureRank(x, 2); err == nil {
+			t.Error("expected error for EnsureRank to a lower rank")
+		}
+		dynamic := must(fn.Input(shapes.Make(dtypes.Int32, shapes.DynamicDim).WithBounds(8)))
+		if _, err := EnsureRank(dynamic, 2); err == nil {
+			t.Error("expected error for EnsureRank of a dynamic shape")
+		}
+	})
+}
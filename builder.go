@@ -34,8 +34,38 @@ type Builder struct {
 	numPartitions int
 
 	// nextChannelID is the next ID to be assigned in channel handles.
-	// It is just a Unique ID.
+	// It is just a Unique ID, starting at 1 since 0 means no channel.
 	nextChannelID int
+
+	// constantsAsInputsMinSize is the minimum size of constants converted to inputs, see WithLargeConstantsAsInputs.
+	constantsAsInputsMinSize int
+
+	// targetFeatures of the backend, see WithTargetFeatures.
+	targetFeatures *TargetFeatures
+
+	// targetVersion of StableHLO, see WithTargetVersion.
+	targetVersion *Version
+
+	// generatorMetadata rendered before the module, see WithGeneratorMetadata.
+	generatorMetadata *GeneratorMetadata
+
+	// genericForm renders the module and functions in the MLIR generic form, see WithGenericForm.
+	genericForm bool
+
+	// passthroughAttributes of the module, rendered verbatim, see SetPassthroughAttribute.
+	passthroughAttributes map[string]string
+
+	// canonicalNames renders the values with canonical names, see WithCanonicalNames.
+	canonicalNames bool
+
+	// lastBuildStats are the memory statistics of the last build, see LastBuildStats.
+	lastBuildStats BuildStats
+
+	// shapeCache memoizes the shape inference of some operations, see WithShapeInferenceCache.
+	shapeCache *shapeInferenceCache
+
+	// numFunctions and numStatements created so far, used to assign their IDs, see Function.ID and Statement.ID.
+	numFunctions, numStatements int
 }
 
 // New creates a new Builder object holding a computation graph in construction.
@@ -52,7 +82,8 @@ type Builder struct {
 // See github.com/gomlx/gopjrt for a Go API to PJRT.
 func New(name string) *Builder {
 	return &Builder{
-		name: name,
+		name:          name,
+		nextChannelID: 1,
 	}
 }
 
@@ -75,8 +106,10 @@ type elementWriter interface {
 //
 // See Function.
 func (b *Builder) NewFunction(name string, inputs ...*Value) *Function {
+	b.numFunctions++
 	fn := &Function{
 		Builder: b,
+		id:      b.numFunctions,
 		Name:    name,
 		Inputs:  inputs,
 		values:  slices.Clone(inputs),
@@ -110,6 +143,7 @@ func (b *Builder) getModuleAttributes() []string {
 	if b.numPartitions > 0 {
 		attributes = append(attributes, fmt.Sprintf(" stablehlo.num_partitions = %d", b.numPartitions))
 	}
+	attributes = append(attributes, passthroughAttributesList(b.passthroughAttributes)...)
 	return attributes
 }
 
@@ -120,6 +154,19 @@ func (b *Builder) getModuleAttributes() []string {
 //
 // See Builder.Build to check and output the program.
 func (b *Builder) Write(writer io.Writer) error {
+	if b.canonicalNames {
+		var buf bytes.Buffer
+		if err := b.write(&buf); err != nil {
+			return err
+		}
+		_, err := writer.Write(CanonicalProgram(buf.Bytes()))
+		return err
+	}
+	return b.write(writer)
+}
+
+// write implements Write, without the canonical names.
+func (b *Builder) write(writer io.Writer) error {
 	var err error
 	w := func(format string, args ...any) {
 		if err != nil {
@@ -136,7 +183,16 @@ func (b *Builder) Write(writer io.Writer) error {
 		err = e.Write(writer, indentation)
 	}
 
-	// Write module header
+	// Write generator metadata and module header
+	if b.generatorMetadata != nil {
+		err = b.generatorMetadata.write(writer)
+	}
+	if b.genericForm {
+		if err != nil {
+			return err
+		}
+		return b.writeGenericModule(writer)
+	}
 	w("module @%s", NormalizeIdentifier(b.name))
 	attrs := b.getModuleAttributes()
 	if len(attrs) > 0 {
@@ -179,29 +235,120 @@ func (b *Builder) Write(writer io.Writer) error {
 	return err
 }
 
+// Verify checks the region isolation of the program: the statements of each function, and recursively of the
+// closures (regions) of its statements, only use values of their own function as operands -- a closure can't
+// reference the values of its parent function -- and each closure is used by a statement of its parent function.
+//
+// The operations check their operands when they are created, but the statements can be changed afterward (e.g.
+// by transformations), so the error reports the precise location of the offending operand.
+//
+// It's called by Build.
+func (b *Builder) Verify() error {
+	for _, fn := range b.functions {
+		if fn.Parent != nil {
+			continue
+		}
+		if err := fn.verifyRegions(func() string { return fmt.Sprintf("function %q", fn.Name) }); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
 // Build checks the validity and builds the StableHLO program.
 //
 // If you want the output of an incomplete program (without the checking), use Builder.Write instead.
+//
+// See BuildInto to reuse the memory of a previous build.
 func (b *Builder) Build() ([]byte, error) {
+	return b.BuildInto(nil)
+}
+
+// BuildInto is like Build, but the program is written into buf (from its start, overwriting its contents),
+// reusing its capacity: a larger buffer is allocated only if the program doesn't fit.
+// It returns the buffer with the program, which can be passed again to BuildInto once the program is no longer
+// needed.
+//
+// It's useful for serving systems that rebuild similar programs frequently, to reduce the garbage collection churn.
+// See LastBuildStats to check whether the buffers are being reused.
+//
+// Example:
+//
+//	var buf []byte
+//	for ... {
+//		buf, err = builder.BuildInto(buf)
+//		...
+//	}
+func (b *Builder) BuildInto(buf []byte) ([]byte, error) {
+	if err := b.check(); err != nil {
+		return nil, err
+	}
+	providedCapacity := cap(buf)
+	writer := bytes.NewBuffer(buf[:0])
+	if err := b.Write(writer); err != nil {
+		return nil, err
+	}
+	program := writer.Bytes()
+	b.lastBuildStats = BuildStats{
+		Size:             len(program),
+		ProvidedCapacity: providedCapacity,
+		Capacity:         cap(program),
+		Reused:           providedCapacity > 0 && len(program) <= providedCapacity,
+	}
+	return program, nil
+}
+
+// BuildStats reports the memory used by a build of the program, see Builder.LastBuildStats.
+type BuildStats struct {
+	// Size of the program, in bytes.
+	Size int
+
+	// ProvidedCapacity is the capacity of the buffer given to BuildInto (0 for Build), and Capacity is the capacity
+	// of the returned buffer.
+	ProvidedCapacity, Capacity int
+
+	// Reused is true if the program was written in the provided buffer, without allocating a new one.
+	Reused bool
+}
+
+// LastBuildStats returns the memory statistics of the last successful Build or BuildInto.
+//
+// If Reused is false with BuildInto, the provided buffer was too small: consider keeping the returned buffer,
+// or allocating one with a larger capacity.
+func (b *Builder) LastBuildStats() BuildStats {
+	return b.lastBuildStats
+}
+
+// check checks the validity of the program, before building it.
+func (b *Builder) check() error {
 	hasMain := false
 	for _, fn := range b.functions {
+		if err := fn.Err(); err != nil {
+			return errors.WithMessagef(err, "function %q", fn.findRootFn().Name)
+		}
 		if fn.Name == "main" {
 			hasMain = true
 		}
+		if !fn.Returned {
+			return errors.Errorf("function %q was not returned, see Function.Return", fn.Name)
+		}
 		if len(fn.Statements) == 0 {
-			return nil, fmt.Errorf("function %q has no statements", fn.Name)
+			return fmt.Errorf("function %q has no statements", fn.Name)
+		}
+		if err := fn.checkTargetFeatures(); err != nil {
+			return err
+		}
+		if err := fn.checkShapeSizes(); err != nil {
+			return err
+		}
+		if err := fn.checkMandatoryAttributes(); err != nil {
+			return err
 		}
 	}
 	if !hasMain {
-		return nil, errors.New("program must have a main function")
+		return errors.New("program must have a main function")
 	}
-
-	var buf bytes.Buffer
-	err := b.Write(&buf)
-	if err != nil {
-		return nil, err
-	}
-	return buf.Bytes(), nil
+	return b.Verify()
 }
 
 // getChannelHandle generates the channel_handle attribute string.
@@ -251,6 +398,16 @@ func (b *Builder) WithNumPartitions(n int) *Builder {
 	return b
 }
 
+// NumReplicas returns the number of replicas set with WithNumReplicas (or WithShardy), or 0 if not set.
+func (b *Builder) NumReplicas() int {
+	return b.numReplicas
+}
+
+// NumPartitions returns the number of partitions set with WithNumPartitions (or WithShardy), or 0 if not set.
+func (b *Builder) NumPartitions() int {
+	return b.numPartitions
+}
+
 // WithShardy enables distributed computation across the devices selected by the given meshes.
 //
 // This is the recommended way to do distributed (across devices) computation, and given the inputs
@@ -0,0 +1,74 @@
+package stablehlo
+
+import (
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// Call adds a call (func.call) to another function of the program, and returns the callee outputs.
+//
+// The callee must be a top-level function (not a closure) of the same Builder, and it must already be
+// returned (see Function.Return), so its outputs are known. The args must match the callee inputs shapes.
+//
+// See also Builder.InlineCalls to inline the called functions into the call sites.
+func (fn *Function) Call(callee *Function, args ...*Value) (outputs []*Value, err error) {
+	if callee != nil {
+		defer fn.multiOpErrorHandler(&err, &outputs, len(callee.Outputs))()
+	}
+	op := optypes.FuncCall
+	if fn.Returned {
+		return nil, errors.Errorf("cannot add operation %s after returning, in function %q", op, fn.Name)
+	}
+	if callee == nil {
+		return nil, errors.Errorf("%s requires a callee function, got nil", op)
+	}
+	if callee.Builder != fn.Builder {
+		return nil, errors.Errorf("%s: callee function %q is from a different Builder", op, callee.Name)
+	}
+	if callee.Parent != nil {
+		return nil, errors.Errorf("%s: callee function %q is a closure, only top-level functions can be called", op, callee.Name)
+	}
+	if callee == fn.findRootFn() {
+		return nil, errors.Errorf("%s: function %q cannot call itself", op, callee.Name)
+	}
+	if !callee.Returned {
+		return nil, errors.Errorf("%s: callee function %q must be returned (Function.Return) before being called", op, callee.Name)
+	}
+	if len(args) != len(callee.Inputs) {
+		return nil, errors.Errorf("%s: callee function %q takes %d arguments, got %d",
+			op, callee.Name, len(callee.Inputs), len(args))
+	}
+	for i, arg := range args {
+		if arg.fn != fn {
+			return nil, errors.Errorf("cannot add operation %s to function %q, because the argument #%d is not part of the function",
+				op, fn.Name, i)
+		}
+		if !arg.shape.Equal(callee.Inputs[i].shape) {
+			return nil, errors.Errorf("%s: argument #%d for function %q has shape %s, but the function expects %s",
+				op, i, callee.Name, arg.shape, callee.Inputs[i].shape)
+		}
+	}
+	outputShapes := make([]shapes.Shape, len(callee.Outputs))
+	for i, output := range callee.Outputs {
+		outputShapes[i] = output.shape
+	}
+	stmt := fn.addMultiOp(op, outputShapes, args)
+	stmt.Attributes = map[string]any{"callee": literalStrF("@%s", callee.Name)}
+	return stmt.Outputs, nil
+}
+
+// calleeOf returns the function called by a FuncCall statement, or nil if it is not found.
+func (s *Statement) calleeOf() *Function {
+	callee, ok := s.Attributes["callee"].(literalStr)
+	if !ok || len(callee) < 2 {
+		return nil
+	}
+	name := string(callee[1:])
+	for _, fn := range s.Builder.functions {
+		if fn.Parent == nil && fn.Name == name {
+			return fn
+		}
+	}
+	return nil
+}
@@ -0,0 +1,122 @@
+package stablehlo
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// WithCanonicalNames enables (or disables) the rendering of the program with canonical value names: in each
+// function, the values (including the inputs) are renamed %0, %1, ... in the order they first appear.
+//
+// Together with the attributes rendered in sorted order, it makes the rendered program depend only on its
+// structure, not on the names given to the inputs or on the order values were created. It's useful to cache or
+// compare programs generated by different code paths.
+func (b *Builder) WithCanonicalNames(enabled bool) *Builder {
+	b.canonicalNames = enabled
+	return b
+}
+
+// CanonicalProgram returns the program with canonical value names: in each function, the values are renamed
+// %0, %1, ... in the order they first appear. See Builder.WithCanonicalNames.
+//
+// Symbols (e.g. "@main"), string literals and comments are not changed.
+func CanonicalProgram(program []byte) []byte {
+	var sb strings.Builder
+	sb.Grow(len(program))
+	var names map[string]string
+	for i, line := range strings.SplitAfter(string(program), "\n") {
+		trimmed := strings.TrimSpace(line)
+		if i == 0 || strings.HasPrefix(trimmed, "func.func ") || strings.HasPrefix(trimmed, `"func.func"`) {
+			// Value names are scoped to the functions.
+			names = make(map[string]string)
+		}
+		canonicalizeLine(&sb, line, names)
+	}
+	return []byte(sb.String())
+}
+
+// canonicalizeLine writes the line to sb, renaming the values according to names, and adding new names as they
+// first appear.
+func canonicalizeLine(sb *strings.Builder, line string, names map[string]string) {
+	inString := false
+	for i := 0; i < len(line); i++ {
+		c := line[i]
+		if inString {
+			sb.WriteByte(c)
+			if c == '\\' && i+1 < len(line) {
+				i++
+				sb.WriteByte(line[i])
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		switch {
+		case c == '"':
+			inString = true
+		case c == '/' && strings.HasPrefix(line[i:], "//"):
+			sb.WriteString(line[i:])
+			return
+		case c == '%':
+			end := i + 1
+			for end < len(line) && isValueNameChar(line[end]) {
+				end++
+			}
+			if end > i+1 {
+				name := line[i+1 : end]
+				canonical, found := names[name]
+				if !found {
+					canonical = strconv.Itoa(len(names))
+					names[name] = canonical
+				}
+				sb.WriteByte('%')
+				sb.WriteString(canonical)
+				i = end - 1
+				continue
+			}
+		}
+		sb.WriteByte(c)
+	}
+}
+
+// isValueNameChar returns whether c can be part of the name of an MLIR value (after the "%").
+func isValueNameChar(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || (c >= '0' && c <= '9') ||
+		c == '_' || c == '$' || c == '.' || c == '-'
+}
+
+// EqualPrograms returns nil if the programs are equal modulo the renaming of their values (see CanonicalProgram)
+// and their comments (e.g. the generator metadata), or an error describing the first line that differs.
+func EqualPrograms(a, b []byte) error {
+	linesA := canonicalLines(a)
+	linesB := canonicalLines(b)
+	for i := range max(len(linesA), len(linesB)) {
+		var lineA, lineB string
+		if i < len(linesA) {
+			lineA = linesA[i]
+		}
+		if i < len(linesB) {
+			lineB = linesB[i]
+		}
+		if lineA != lineB {
+			return errors.Errorf("programs differ at line %d (ignoring comments), after renaming values:\n  %s\n  %s",
+				i+1, lineA, lineB)
+		}
+	}
+	return nil
+}
+
+// canonicalLines returns the lines of the canonical program, without comment lines and trailing spaces.
+func canonicalLines(program []byte) []string {
+	var lines []string
+	for _, line := range strings.Split(string(CanonicalProgram(program)), "\n") {
+		line = strings.TrimRight(line, " \t\r")
+		if line == "" || strings.HasPrefix(strings.TrimSpace(line), "//") {
+			continue
+		}
+		lines = append(lines, line)
+	}
+	return lines
+}
@@ -0,0 +1,81 @@
+package stablehlo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+// requireEqualPrograms checks that the programs are equal modulo the renaming of their values, see EqualPrograms.
+func requireEqualPrograms(t *testing.T, want, got []byte) {
+	t.Helper()
+	if err := EqualPrograms(want, got); err != nil {
+		t.Fatalf("%v\nwant:\n%s\ngot:\n%s", err, want, got)
+	}
+}
+
+func TestCanonicalNames(t *testing.T) {
+	buildProgram := func(name string, inputNames []string, canonical bool) *Builder {
+		builder := New(name).WithCanonicalNames(canonical)
+		fn := builder.Main()
+		var inputs []*Value
+		for _, inputName := range inputNames {
+			var input *Value
+			if inputName == "" {
+				input = must(fn.Input(shapes.Make(dtypes.Float32, 3)))
+			} else {
+				input = must(fn.NamedInput(inputName, shapes.Make(dtypes.Float32, 3)))
+			}
+			inputs = append(inputs, input)
+		}
+		sum := must(Add(inputs[0], inputs[1]))
+		custom := must(fn.CustomCall("handler %x", []shapes.Shape{sum.Shape()}, sum).Done())
+		must0(fn.Return(must(Multiply(custom[0], inputs[0]))))
+		return builder
+	}
+
+	t.Run("Rendering", func(t *testing.T) {
+		builder := buildProgram(t.Name(), []string{"x", "y"}, true)
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestCanonicalNames_Rendering {
+  func.func @main(%0: tensor<3xf32>, %1: tensor<3xf32>) -> tensor<3xf32> {
+    %2 = "stablehlo.add"(%0, %1) : (tensor<3xf32>, tensor<3xf32>) -> tensor<3xf32>
+    %3 = "stablehlo.custom_call"(%2) { call_target_name = "handler %x" } : (tensor<3xf32>) -> tensor<3xf32>
+    %4 = "stablehlo.multiply"(%3, %0) : (tensor<3xf32>, tensor<3xf32>) -> tensor<3xf32>
+    "stablehlo.return"(%4) : (tensor<3xf32>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+		requireRoundTrip(t, builder)
+	})
+
+	t.Run("EqualPrograms", func(t *testing.T) {
+		named := must(buildProgram("program", []string{"x", "y"}, false).Build())
+		unnamed := must(buildProgram("program", []string{"", ""}, false).WithGeneratorMetadata(GeneratorMetadata{Name: "test"}).Build())
+		if string(named) == string(unnamed) {
+			t.Fatal("programs with different input names should render differently")
+		}
+		requireEqualPrograms(t, named, unnamed)
+		requireEqualPrograms(t, named, must(buildProgram("program", []string{"a", "b"}, true).Build()))
+
+		swapped := must(buildProgram("program", []string{"y", "x"}, false).Build())
+		requireEqualPrograms(t, named, swapped)
+
+		other := New("program")
+		fn := other.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		must0(fn.Return(must(Add(x, x))))
+		err := EqualPrograms(named, must(other.Build()))
+		if err == nil || !strings.Contains(err.Error(), "programs differ at line 2") {
+			t.Errorf("expected programs to differ at line 2, got %v", err)
+		}
+	})
+}
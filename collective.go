@@ -2,6 +2,7 @@ package stablehlo
 
 import (
 	"fmt"
+	"slices"
 	"strings"
 
 	"github.com/gomlx/stablehlo/internal/optypes"
@@ -10,6 +11,34 @@ import (
 	"github.com/pkg/errors"
 )
 
+// collectiveConfig returns the optional configuration given to the collective operation op, or nil if none was given,
+// in which case no channel handle is rendered: the default for non-SPMD programs.
+//
+// UseGlobalDeviceIDs is only accepted if supportsGlobalDeviceIDs, and it requires a channel handle with a positive id.
+func collectiveConfig(op optypes.OpType, config []*types.CollectiveConfig, supportsGlobalDeviceIDs bool) (
+	*types.CollectiveConfig, error) {
+	if len(config) > 1 {
+		return nil, errors.Errorf("only one config can be provided, got %d", len(config))
+	}
+	if len(config) == 0 || config[0] == nil {
+		return nil, nil
+	}
+	cfg := config[0]
+	if cfg.ChannelID != nil && *cfg.ChannelID < 0 {
+		return nil, errors.Errorf("%s: invalid negative ChannelID %d", op, *cfg.ChannelID)
+	}
+	if cfg.UseGlobalDeviceIDs {
+		if !supportsGlobalDeviceIDs {
+			return nil, errors.Errorf("%s doesn't support UseGlobalDeviceIDs", op)
+		}
+		if cfg.ChannelID != nil && *cfg.ChannelID == 0 {
+			return nil, errors.Errorf("%s: UseGlobalDeviceIDs requires a channel handle with a positive id, "+
+				"but ChannelID is 0 (which means no channel)", op)
+		}
+	}
+	return cfg, nil
+}
+
 // formatReplicaGroups converts a 2D Go slice into the StableHLO dense tensor literal format.
 // Example: [[0, 1], [2, 3]] -> "dense<[[0, 1], [2, 3]]> : tensor<2x2xi64>"
 func formatReplicaGroups(groups [][]int) literalStr {
@@ -53,9 +82,10 @@ func formatReplicaGroups(groups [][]int) literalStr {
 //
 // Consider using Builder.WithShardy for distributed computation instead: other forms of distributed
 // (collective) computation across devices are not tested and may not work.
-func CollectiveBroadcast(operand *Value, replicaGroups [][]int, config ...*types.CollectiveConfig) (*Value, error) {
+func CollectiveBroadcast(operand *Value, replicaGroups [][]int, config ...*types.CollectiveConfig) (output *Value, err error) {
 	op := optypes.CollectiveBroadcast
 	fn := operand.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -66,15 +96,13 @@ func CollectiveBroadcast(operand *Value, replicaGroups [][]int, config ...*types
 		return nil, err
 	}
 
-	var cfg *types.CollectiveConfig
-	if len(config) > 1 {
-		return nil, errors.Errorf("only one config can be provided, got %d", len(config))
-	} else if len(config) == 1 {
-		cfg = config[0]
+	cfg, err := collectiveConfig(op, config, false)
+	if err != nil {
+		return nil, err
 	}
 
-	if cfg != nil && (cfg.UseGlobalDeviceIDs || cfg.ChannelType == types.CrossPartition) {
-		return nil, errors.Errorf("UseGlobalDeviceIDs or CrossPartition type is not supported for CollectiveBroadcast")
+	if cfg != nil && cfg.ChannelType == types.CrossPartition {
+		return nil, errors.Errorf("CrossPartition type is not supported for %s", op)
 	}
 
 	stmt := fn.addOp(op, outputShape, operand)
@@ -90,10 +118,12 @@ func CollectiveBroadcast(operand *Value, replicaGroups [][]int, config ...*types
 // AllReduce performs a distributed reduce operation across replicas.
 // It is a distributed version of Reduce.
 //
-//   - operands: The tensors from the *local* replica to be reduced.
-//   - replicaGroups: A 2D array defining the communicating device groups, e.g., `[[0, 1, 2, 3]]`.
+//   - operands: The tensors from the *local* replica to be reduced. Multiple operands (of the same dtype, but
+//     possibly different shapes) are reduced in one collective operation (the variadic form of
+//     stablehlo.all_reduce), and one output is returned per operand.
 //   - computation: A closure function that defines the reduction operation (e.g., SUM). It must
-//     take two scalar inputs for each operand's dtype and return one scalar output of the same dtype.
+//     take two scalar inputs of the operands' dtype and return one scalar output of the same dtype.
+//     The same computation is used for all operands.
 //   - replicaGroups: A 2D array defining the communicating device groups. For standard data
 //     parallelism, this is typically a single group with all the replica numbers --
 //     notice it's not the device numbers by the replica numbers (there is an indirection).
@@ -104,12 +134,13 @@ func CollectiveBroadcast(operand *Value, replicaGroups [][]int, config ...*types
 // Consider using Builder.WithShardy for distributed computation instead: other forms of distributed
 // (collective) computation across devices are not tested and may not work.
 func AllReduce(operands []*Value, replicaGroups [][]int, computation *Function, config ...*types.CollectiveConfig) (
-	[]*Value, error) {
+	outputs []*Value, err error) {
 	op := optypes.AllReduce
 	if len(operands) == 0 {
 		return nil, errors.Errorf("AllReduce requires at least one operand")
 	}
 	fn := operands[0].fn
+	defer fn.multiOpErrorHandler(&err, &outputs, len(operands))()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -130,20 +161,18 @@ func AllReduce(operands []*Value, replicaGroups [][]int, computation *Function,
 	outputShapes, err := shapeinference.AllReduce(
 		valuesToShapes(operands),
 		valuesToShapes(computation.Inputs),
-		valuesToShapes(computation.Outputs),
+		computation.OutputShapes(),
 		replicaGroups)
 	if err != nil {
 		return nil, err
 	}
 
-	var cfg *types.CollectiveConfig
-	if len(config) > 1 {
-		return nil, errors.Errorf("only one config can be provided, got %d", len(config))
-	} else if len(config) == 1 {
-		cfg = config[0]
+	cfg, err := collectiveConfig(op, config, true)
+	if err != nil {
+		return nil, err
 	}
 
-	stmt := fn.addMultiOp(op, outputShapes, operands)
+	stmt := fn.addMultiOp(op, outputShapes, slices.Clone(operands))
 	stmt.Attributes = map[string]any{
 		"replica_groups": formatReplicaGroups(replicaGroups),
 	}
@@ -166,9 +195,10 @@ func AllReduce(operands []*Value, replicaGroups [][]int, computation *Function,
 //
 // Consider using Builder.WithShardy for distributed computation instead: other forms of distributed
 // (collective) computation across devices are not tested and may not work.
-func AllGather(operand *Value, replicaGroups [][]int, allGatherDim int, config ...*types.CollectiveConfig) (*Value, error) {
+func AllGather(operand *Value, replicaGroups [][]int, allGatherDim int, config ...*types.CollectiveConfig) (output *Value, err error) {
 	op := optypes.AllGather
 	fn := operand.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q", op, fn.Name)
 	}
@@ -178,11 +208,9 @@ func AllGather(operand *Value, replicaGroups [][]int, allGatherDim int, config .
 		return nil, err
 	}
 
-	var cfg *types.CollectiveConfig
-	if len(config) > 1 {
-		return nil, errors.Errorf("only one config can be provided, got %d", len(config))
-	} else if len(config) == 1 {
-		cfg = config[0]
+	cfg, err := collectiveConfig(op, config, true)
+	if err != nil {
+		return nil, err
 	}
 
 	stmt := fn.addOp(op, outputShape, operand)
@@ -211,9 +239,10 @@ func AllGather(operand *Value, replicaGroups [][]int, allGatherDim int, config .
 //
 // Consider using Builder.WithShardy for distributed computation instead: other forms of distributed
 // (collective) computation across devices are not tested and may not work.
-func AllToAll(operand *Value, replicaGroups [][]int, splitDimension, concatDimension, splitCount int, config ...*types.CollectiveConfig) (*Value, error) {
+func AllToAll(operand *Value, replicaGroups [][]int, splitDimension, concatDimension, splitCount int, config ...*types.CollectiveConfig) (output *Value, err error) {
 	op := optypes.AllToAll
 	fn := operand.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q", op, fn.Name)
 	}
@@ -223,11 +252,9 @@ func AllToAll(operand *Value, replicaGroups [][]int, splitDimension, concatDimen
 		return nil, err
 	}
 
-	var cfg *types.CollectiveConfig
-	if len(config) > 1 {
-		return nil, errors.Errorf("only one config can be provided, got %d", len(config))
-	} else if len(config) == 1 {
-		cfg = config[0]
+	cfg, err := collectiveConfig(op, config, false)
+	if err != nil {
+		return nil, err
 	}
 
 	stmt := fn.addOp(op, outputShape, operand)
@@ -240,9 +267,6 @@ func AllToAll(operand *Value, replicaGroups [][]int, splitDimension, concatDimen
 	if cfg != nil {
 		stmt.Attributes["channel_handle"] = fn.Builder.getChannelHandle(cfg)
 	}
-	if cfg != nil && cfg.UseGlobalDeviceIDs {
-		stmt.Attributes["use_global_device_ids"] = true
-	}
 	return stmt.Outputs[0], nil
 }
 
@@ -274,9 +298,10 @@ func formatSourceTargetPairs(pairs [][2]int) literalStr {
 //
 // Consider using Builder.WithShardy for distributed computation instead: other forms of distributed
 // (collective) computation across devices are not tested and may not work.
-func CollectivePermute(operand *Value, sourceTargetPairs [][2]int, config ...*types.CollectiveConfig) (*Value, error) {
+func CollectivePermute(operand *Value, sourceTargetPairs [][2]int, config ...*types.CollectiveConfig) (output *Value, err error) {
 	op := optypes.CollectivePermute
 	fn := operand.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q", op, fn.Name)
 	}
@@ -286,11 +311,9 @@ func CollectivePermute(operand *Value, sourceTargetPairs [][2]int, config ...*ty
 		return nil, err
 	}
 
-	var cfg *types.CollectiveConfig
-	if len(config) > 1 {
-		return nil, errors.Errorf("only one config can be provided, got %d", len(config))
-	} else if len(config) == 1 {
-		cfg = config[0]
+	cfg, err := collectiveConfig(op, config, false)
+	if err != nil {
+		return nil, err
 	}
 
 	stmt := fn.addOp(op, outputShape, operand)
@@ -300,8 +323,5 @@ func CollectivePermute(operand *Value, sourceTargetPairs [][2]int, config ...*ty
 	if cfg != nil {
 		stmt.Attributes["channel_handle"] = fn.Builder.getChannelHandle(cfg)
 	}
-	if cfg != nil && cfg.UseGlobalDeviceIDs {
-		stmt.Attributes["use_global_device_ids"] = true
-	}
 	return stmt.Outputs[0], nil
 }
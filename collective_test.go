@@ -0,0 +1,70 @@
+package stablehlo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestCollectiveConfig(t *testing.T) {
+	builder := New(t.Name()).WithNumReplicas(2)
+	fn := builder.Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2)))
+	replicaGroups := [][]int{{0, 1}}
+
+	// Without a config no channel handle is rendered, with a config the automatic ids start at 1.
+	noChannel := must(AllGather(x, replicaGroups, 0))
+	withChannel := must(AllGather(x, replicaGroups, 0, &types.CollectiveConfig{UseGlobalDeviceIDs: true}))
+	channelID := 7
+	manualChannel := must(CollectiveBroadcast(x, replicaGroups, &types.CollectiveConfig{ChannelID: &channelID}))
+	must0(fn.Return(noChannel, withChannel, manualChannel))
+	program := string(must(builder.Build()))
+	fmt.Printf("%s program:\n%s", t.Name(), program)
+	want := `module @TestCollectiveConfig attributes {stablehlo.num_replicas = 2} {
+  func.func @main(%x: tensor<2xf32>) -> (tensor<4xf32>, tensor<4xf32>, tensor<2xf32>) {
+    %0 = "stablehlo.all_gather"(%x) {
+      all_gather_dim = 0 : i64,
+      replica_groups = dense<[[0, 1]]> : tensor<1x2xi64>
+    } : (tensor<2xf32>) -> tensor<4xf32>
+    %1 = "stablehlo.all_gather"(%x) {
+      all_gather_dim = 0 : i64,
+      channel_handle = #stablehlo.channel_handle<handle = 1, type = 0>,
+      replica_groups = dense<[[0, 1]]> : tensor<1x2xi64>,
+      use_global_device_ids = true
+    } : (tensor<2xf32>) -> tensor<4xf32>
+    %2 = "stablehlo.collective_broadcast"(%x) {
+      channel_handle = #stablehlo.channel_handle<handle = 7, type = 0>,
+      replica_groups = dense<[[0, 1]]> : tensor<1x2xi64>
+    } : (tensor<2xf32>) -> tensor<2xf32>
+    "stablehlo.return"(%0, %1, %2) : (tensor<4xf32>, tensor<4xf32>, tensor<2xf32>) -> ()
+  }
+}
+`
+	if program != want {
+		fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+		t.Fatal("programs don't match")
+	}
+
+	// Invalid configurations.
+	fn = New(t.Name()).Main()
+	x = must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2)))
+	zero, negative := 0, -1
+	if _, err := AllGather(x, replicaGroups, 0, &types.CollectiveConfig{ChannelID: &zero, UseGlobalDeviceIDs: true}); err == nil {
+		t.Error("expected error for UseGlobalDeviceIDs without a channel")
+	}
+	if _, err := AllGather(x, replicaGroups, 0, &types.CollectiveConfig{ChannelID: &negative}); err == nil {
+		t.Error("expected error for negative ChannelID")
+	}
+	if _, err := AllToAll(x, replicaGroups, 0, 0, 2, &types.CollectiveConfig{UseGlobalDeviceIDs: true}); err == nil {
+		t.Error("expected error for AllToAll with UseGlobalDeviceIDs")
+	}
+	if _, err := CollectivePermute(x, [][2]int{{0, 1}}, &types.CollectiveConfig{UseGlobalDeviceIDs: true}); err == nil {
+		t.Error("expected error for CollectivePermute with UseGlobalDeviceIDs")
+	}
+	if _, err := AllGather(x, replicaGroups, 0, &types.CollectiveConfig{}, &types.CollectiveConfig{}); err == nil {
+		t.Error("expected error for more than one config")
+	}
+}
@@ -0,0 +1,90 @@
+package stablehlo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"fmt"
+	"io"
+
+	"github.com/pkg/errors"
+)
+
+// compressedProgramComment is the comment in the gzip header of the programs generated by
+// Builder.BuildCompressed, identifying their content.
+const compressedProgramComment = "StableHLO text program"
+
+// CompressionStats reports the sizes of a program compressed by Builder.BuildCompressed.
+type CompressionStats struct {
+	// RawSize is the size in bytes of the StableHLO text program.
+	RawSize int
+
+	// CompressedSize is the size in bytes of the compressed program, including its header.
+	CompressedSize int
+}
+
+// Ratio returns the compression ratio, RawSize / CompressedSize.
+func (s CompressionStats) Ratio() float64 {
+	if s.CompressedSize == 0 {
+		return 0
+	}
+	return float64(s.RawSize) / float64(s.CompressedSize)
+}
+
+// String implements fmt.Stringer.
+func (s CompressionStats) String() string {
+	return fmt.Sprintf("%d bytes compressed to %d bytes (%.1fx)", s.RawSize, s.CompressedSize, s.Ratio())
+}
+
+// BuildCompressed is like Build, but it returns the StableHLO text program compressed with gzip, along with the
+// raw and compressed sizes -- textual programs with large constants easily reach hundreds of megabytes, and they
+// compress very well, which makes them much easier to store and move around.
+//
+// The output is a standard gzip stream (it can be decompressed with gunzip), whose header records the name of the
+// program (the Builder name with the ".mlir" extension) and a comment identifying the content. It's deterministic:
+// the modification time is not set.
+//
+// See DecompressProgram to recover the program.
+func (b *Builder) BuildCompressed() ([]byte, CompressionStats, error) {
+	program, err := b.Build()
+	if err != nil {
+		return nil, CompressionStats{}, err
+	}
+	var buf bytes.Buffer
+	writer, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+	if err != nil {
+		return nil, CompressionStats{}, errors.Wrap(err, "BuildCompressed")
+	}
+	writer.Header = gzip.Header{
+		Name:    NormalizeIdentifier(b.name) + ".mlir",
+		Comment: compressedProgramComment,
+		OS:      255, // Unknown.
+	}
+	if _, err = writer.Write(program); err != nil {
+		return nil, CompressionStats{}, errors.Wrap(err, "BuildCompressed")
+	}
+	if err = writer.Close(); err != nil {
+		return nil, CompressionStats{}, errors.Wrap(err, "BuildCompressed")
+	}
+	stats := CompressionStats{RawSize: len(program), CompressedSize: buf.Len()}
+	return buf.Bytes(), stats, nil
+}
+
+// DecompressProgram returns the StableHLO text program compressed by Builder.BuildCompressed.
+//
+// It fails if compressed is not a gzip stream generated by Builder.BuildCompressed.
+func DecompressProgram(compressed []byte) ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(compressed))
+	if err != nil {
+		return nil, errors.Wrap(err, "DecompressProgram")
+	}
+	defer func() { _ = reader.Close() }()
+	if reader.Comment != compressedProgramComment {
+		return nil, errors.Errorf("DecompressProgram: not a compressed StableHLO program (gzip header comment %q)",
+			reader.Comment)
+	}
+	program, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, errors.Wrap(err, "DecompressProgram")
+	}
+	return program, nil
+}
@@ -0,0 +1,55 @@
+package stablehlo
+
+import (
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestBuildCompressed(t *testing.T) {
+	b := New(t.Name())
+	fn := b.Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 64)))
+	c := must(fn.ConstantFromFlatAndDimensions(make([]float32, 64), 64))
+	must0(fn.Return(must(Add(x, c))))
+	program := must(b.Build())
+
+	compressed, stats, err := b.BuildCompressed()
+	if err != nil {
+		t.Fatalf("BuildCompressed failed: %+v", err)
+	}
+	if stats.RawSize != len(program) || stats.CompressedSize != len(compressed) {
+		t.Errorf("unexpected stats %+v: program has %d bytes, compressed %d bytes", stats, len(program), len(compressed))
+	}
+	if stats.Ratio() <= 1 {
+		t.Errorf("expected the program to compress, got %s", stats)
+	}
+	again, _, err := b.BuildCompressed()
+	if err != nil || !bytes.Equal(again, compressed) {
+		t.Errorf("expected BuildCompressed to be deterministic (err=%v)", err)
+	}
+
+	reader := must(gzip.NewReader(bytes.NewReader(compressed)))
+	if reader.Name != "TestBuildCompressed.mlir" {
+		t.Errorf("unexpected gzip header name %q", reader.Name)
+	}
+	decompressed := must(DecompressProgram(compressed))
+	if !bytes.Equal(decompressed, program) {
+		t.Errorf("decompressed program doesn't match:\n%s", decompressed)
+	}
+
+	// A gzip stream not generated by BuildCompressed.
+	var buf bytes.Buffer
+	writer := gzip.NewWriter(&buf)
+	_, _ = writer.Write(program)
+	must0(writer.Close())
+	if _, err := DecompressProgram(buf.Bytes()); err == nil {
+		t.Error("expected DecompressProgram to fail for a gzip stream without the program header")
+	}
+	if _, err := DecompressProgram(program); err == nil {
+		t.Error("expected DecompressProgram to fail for uncompressed input")
+	}
+}
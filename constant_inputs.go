@@ -0,0 +1,146 @@
+package stablehlo
+
+import (
+	"fmt"
+	"reflect"
+	"slices"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// ConstantInput describes a constant that is fed as an input of the function, instead of being rendered as a
+// literal in the program -- which is infeasible for large values, like multi-GB model weights.
+//
+// See Function.ExternalConstant, Function.ConstantAsInput and Builder.WithLargeConstantsAsInputs.
+type ConstantInput struct {
+	// Name of the constant, also used as the name of the input.
+	// It is the name given when creating the constant, converted with ConvertToValidName.
+	Name string
+
+	// OriginalName is the name given when creating the constant, before ConvertToValidName.
+	// Executors use it to match the constant with the weights loaded from files, whose names usually
+	// contain separators like "." or "/" (e.g.: "encoder.layer.0.weight").
+	OriginalName string
+
+	// Shape of the constant.
+	Shape shapes.Shape
+
+	// InputIndex is the position of the input in Function.Inputs, that is, the position of the parameter
+	// when executing the program.
+	InputIndex int
+
+	// Flat holds a copy of the values of the constant in a flat slice (e.g.: []float32), if they were given when
+	// building.
+	// It is nil for external constants (see Function.ExternalConstant), whose values must be provided during
+	// execution (e.g.: loaded from a file).
+	Flat any
+}
+
+// WithLargeConstantsAsInputs configures the builder to automatically convert the constants created with
+// Function.ConstantFromFlatAndDimensions with minSize or more elements to inputs of the function,
+// see Function.ConstantAsInput.
+//
+// It only applies to top-level functions (not closures), since closures can't have extra inputs.
+// A minSize <= 0 disables it (the default).
+func (b *Builder) WithLargeConstantsAsInputs(minSize int) *Builder {
+	b.constantsAsInputsMinSize = minSize
+	return b
+}
+
+// ExternalConstant creates an input of the function that represents a constant whose values are not known
+// when building the program: e.g., model weights to be loaded (or mmap'ed) from a file during execution.
+//
+// The constant is registered in Function.ConstantInputs (with a nil Flat value), so the executor can match
+// it by name: the input is named with ConvertToValidName(name), but the original name is kept in
+// ConstantInput.OriginalName.
+//
+// It can only be used in top-level functions (not in closures).
+func (fn *Function) ExternalConstant(name string, shape shapes.Shape) (output *Value, err error) {
+	defer fn.opErrorHandler(&err, &output)()
+	return fn.addConstantInput(name, shape, nil)
+}
+
+// ConstantAsInput is like ConstantFromFlatAndDimensions, but instead of rendering the values as a literal in
+// the program, it creates an input of the function with the given name.
+// The values are registered in Function.ConstantInputs, and must be fed as the corresponding parameter
+// during execution.
+//
+// It can only be used in top-level functions (not in closures).
+func (fn *Function) ConstantAsInput(name string, flat any, dimensions ...int) (output *Value, err error) {
+	defer fn.opErrorHandler(&err, &output)()
+	flatV := reflect.ValueOf(flat)
+	if flatV.Kind() != reflect.Slice {
+		return nil, errors.Errorf("ConstantAsInput(%q): expected a flat slice of values, got %T", name, flat)
+	}
+	dtype := dtypes.FromGoType(flatV.Type().Elem())
+	if dtype == dtypes.INVALID {
+		return nil, errors.Errorf("ConstantAsInput(%q): unsupported constant flat values type %T -- expected a slice of a basic data type",
+			name, flat)
+	}
+	shape := shapes.Make(dtype, dimensions...)
+	if shape.Size() != flatV.Len() {
+		return nil, errors.Errorf("ConstantAsInput(%q): flat values size %d doesn't match shape size %d (%s)",
+			name, flatV.Len(), shape.Size(), shape)
+	}
+	return fn.addConstantInput(name, shape, flat)
+}
+
+// ConstantInputs returns the constants fed as inputs of the function, in the order they were created.
+//
+// See ConstantInput.
+func (fn *Function) ConstantInputs() []ConstantInput {
+	return fn.constantInputs
+}
+
+// addConstantInput creates the input and registers the constant.
+func (fn *Function) addConstantInput(name string, shape shapes.Shape, flat any) (*Value, error) {
+	if fn.Parent != nil {
+		return nil, errors.Errorf("cannot create constant input %q in closure %q: only top-level functions can have constant inputs",
+			name, fn.Name)
+	}
+	if fn.Returned {
+		return nil, errors.Errorf("Function.Return already called for %q", fn.Name)
+	}
+	if !shape.Ok() {
+		return nil, errors.Errorf("invalid shape %s for constant input %q", shape, name)
+	}
+	value, err := fn.NamedInput(name, shape)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "creating constant input %q", name)
+	}
+	if flat != nil {
+		// Copy the values, so later changes to the caller's slice don't change the constant.
+		flatV := reflect.ValueOf(flat)
+		flatCopy := reflect.MakeSlice(flatV.Type(), flatV.Len(), flatV.Len())
+		reflect.Copy(flatCopy, flatV)
+		flat = flatCopy.Interface()
+	}
+	fn.constantInputs = append(fn.constantInputs, ConstantInput{
+		Name:         value.name,
+		OriginalName: name,
+		Shape:        shape,
+		InputIndex:   len(fn.Inputs) - 1,
+		Flat:         flat,
+	})
+	return value, nil
+}
+
+// largeConstantAsInput returns whether a constant with the given shape should be converted to an input,
+// see Builder.WithLargeConstantsAsInputs.
+func (fn *Function) largeConstantAsInput(shape shapes.Shape) bool {
+	minSize := fn.Builder.constantsAsInputsMinSize
+	return minSize > 0 && fn.Parent == nil && shape.Size() >= minSize
+}
+
+// nextConstantInputName returns a default name for a constant converted to an input, skipping the names already
+// used by other inputs (e.g. created with NamedInput).
+func (fn *Function) nextConstantInputName() string {
+	for i := len(fn.constantInputs); ; i++ {
+		name := fmt.Sprintf("constant%d", i)
+		if !slices.ContainsFunc(fn.Inputs, func(input *Value) bool { return input.name == name }) {
+			return name
+		}
+	}
+}
@@ -0,0 +1,71 @@
+package stablehlo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestConstantInputs(t *testing.T) {
+	builder := New(t.Name()).WithLargeConstantsAsInputs(4)
+	fn := builder.Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2, 2)))
+	weights := must(fn.ExternalConstant("weights", shapes.Make(dtypes.Float32, 2, 2)))
+	bias := must(fn.ConstantFromFlatAndDimensions([]float32{1, 2, 3, 4}, 2, 2))
+	small := must(fn.ConstantFromFlatAndDimensions([]float32{0.5, 0.5}, 2))
+	small = must(BroadcastInDim(small, x.Shape(), []int{1}))
+	y := must(Multiply(x, weights))
+	y = must(Add(y, bias))
+	y = must(Multiply(y, small))
+	if err := fn.Return(y); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	program := string(must(builder.Build()))
+	fmt.Printf("%s program:\n%s", t.Name(), program)
+	want := `module @TestConstantInputs {
+  func.func @main(%x: tensor<2x2xf32>, %weights: tensor<2x2xf32>, %constant1: tensor<2x2xf32>) -> tensor<2x2xf32> {
+    %0 = "stablehlo.constant"() { value = dense<[0.5, 0.5]> : tensor<2xf32> } : () -> tensor<2xf32>
+    %1 = "stablehlo.broadcast_in_dim"(%0) { broadcast_dimensions = array<i64: 1> } : (tensor<2xf32>) -> tensor<2x2xf32>
+    %2 = "stablehlo.multiply"(%x, %weights) : (tensor<2x2xf32>, tensor<2x2xf32>) -> tensor<2x2xf32>
+    %3 = "stablehlo.add"(%2, %constant1) : (tensor<2x2xf32>, tensor<2x2xf32>) -> tensor<2x2xf32>
+    %4 = "stablehlo.multiply"(%3, %1) : (tensor<2x2xf32>, tensor<2x2xf32>) -> tensor<2x2xf32>
+    "stablehlo.return"(%4) : (tensor<2x2xf32>) -> ()
+  }
+}
+`
+	if program != want {
+		fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+		t.Fatal("programs don't match")
+	}
+
+	constants := fn.ConstantInputs()
+	if len(constants) != 2 {
+		t.Fatalf("expected 2 constant inputs, got %d", len(constants))
+	}
+	if constants[0].Name != "weights" || constants[0].InputIndex != 1 || constants[0].Flat != nil {
+		t.Errorf("unexpected external constant %+v", constants[0])
+	}
+	if constants[1].Name != "constant1" || constants[1].InputIndex != 2 || constants[1].Flat == nil {
+		t.Errorf("unexpected lifted constant %+v", constants[1])
+	}
+}
+
+func TestConstantInputsNames(t *testing.T) {
+	builder := New(t.Name()).WithLargeConstantsAsInputs(4)
+	fn := builder.Main()
+	x := must(fn.NamedInput("constant0", shapes.Make(dtypes.Float32, 4)))
+	flat := []float32{1, 2, 3, 4}
+	c := must(fn.ConstantFromFlatAndDimensions(flat, 4))
+	flat[0] = 100
+	must0(fn.Return(must(Add(x, c))))
+
+	constants := fn.ConstantInputs()
+	if len(constants) != 1 || constants[0].Name != "constant1" {
+		t.Fatalf("expected the lifted constant to skip the name of the input constant0, got %+v", constants)
+	}
+	if got := constants[0].Flat.([]float32); got[0] != 1 {
+		t.Errorf("expected the constant values to be copied, got %v after changing the caller's slice", got)
+	}
+}
@@ -0,0 +1,264 @@
+package stablehlo
+
+import (
+	"fmt"
+	"math"
+	"reflect"
+	"slices"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/gopjrt/dtypes/bfloat16"
+	"github.com/gomlx/stablehlo/internal/utils"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+	"github.com/x448/float16"
+)
+
+// ZerosLike returns a value with the same shape and dtype as x, filled with zeros (false for booleans).
+//
+// It is rendered as a scalar constant broadcast to the shape of x, not as a dense literal.
+func ZerosLike(x *Value) (*Value, error) {
+	return FullLike(x, 0)
+}
+
+// OnesLike returns a value with the same shape and dtype as x, filled with ones (true for booleans).
+//
+// It is rendered as a scalar constant broadcast to the shape of x, not as a dense literal.
+func OnesLike(x *Value) (*Value, error) {
+	return FullLike(x, 1)
+}
+
+// FullLike returns a value with the same shape and dtype as x, filled with the given scalar value, which is
+// converted to the dtype of x: integers saturate to the range of the dtype, and floats are rounded (see
+// Function.ConstantFromScalarOfDType for the conversion rules).
+//
+// Like ConstantZeros, it returns an error for the non-positive values of the unsigned f8E8M0FNU, which has no zero
+// (nor negative values), instead of converting them to NaN.
+//
+// It is rendered as a scalar constant broadcast to the shape of x, not as a dense literal.
+func FullLike(x *Value, scalar any) (output *Value, err error) {
+	fn := x.fn
+	defer fn.opErrorHandler(&err, &output)()
+	if fn.Returned {
+		return nil, errors.Errorf("Function.Return already called for %q", fn.Name)
+	}
+	if x.shape.DType == dtypes.F8E8M0FNU && isNonPositiveScalar(scalar) {
+		return nil, errors.Errorf("FullLike(%s): dtype %s has no zero or negative representation, got %v",
+			x.shape, x.shape.DType, scalar)
+	}
+	c, err := fn.scalarConstantOfDType(x.shape.DType, scalar)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "FullLike(%s)", x.shape)
+	}
+	if x.shape.IsScalar() {
+		return c, nil
+	}
+	return BroadcastInDim(c, x.shape, nil)
+}
+
+// ConstantZeros returns a constant of the given shape filled with zeros (false for booleans).
+//
+// It supports every dtype with a zero, including complex, the f8 floats and the sub-byte integers used for
+// quantization (dtypes.S4, dtypes.U4, etc.). It returns an error for dtypes without a zero, like f8E8M0FNU.
+//
+// Like ZerosLike, it is rendered as a scalar constant broadcast to the shape, not as a dense literal.
+func (fn *Function) ConstantZeros(shape shapes.Shape) (output *Value, err error) {
+	defer fn.opErrorHandler(&err, &output)()
+	return fn.constantFilled("ConstantZeros", shape, false)
+}
+
+// ConstantOnes returns a constant of the given shape filled with ones (true for booleans).
+//
+// It supports the same dtypes as ConstantZeros, and also f8E8M0FNU.
+//
+// Like OnesLike, it is rendered as a scalar constant broadcast to the shape, not as a dense literal.
+func (fn *Function) ConstantOnes(shape shapes.Shape) (output *Value, err error) {
+	defer fn.opErrorHandler(&err, &output)()
+	return fn.constantFilled("ConstantOnes", shape, true)
+}
+
+// constantFilled implements ConstantZeros (one=false) and ConstantOnes (one=true).
+func (fn *Function) constantFilled(caller string, shape shapes.Shape, one bool) (*Value, error) {
+	if fn.Returned {
+		return nil, errors.Errorf("%s: Function.Return already called for %q", caller, fn.Name)
+	}
+	if shape.IsDynamic() {
+		return nil, errors.Errorf("%s: requires a static shape, got %s", caller, shape)
+	}
+	if shape.DType == dtypes.F8E8M0FNU && !one {
+		return nil, errors.Errorf("%s: dtype %s has no zero representation", caller, shape.DType)
+	}
+	value := 0.0
+	if one {
+		value = 1
+	}
+	c, err := fn.scalarConstantOfDType(shape.DType, value)
+	if err != nil {
+		return nil, errors.WithMessage(err, caller)
+	}
+	if shape.IsScalar() {
+		return c, nil
+	}
+	return BroadcastInDim(c, shape, nil)
+}
+
+// WithScalar applies the binary operation op to x and the Go scalar value (a bool or a number), converted to the
+// dtype of x and broadcast to its shape (see FullLike).
+//
+// Example:
+//
+//	y, err := WithScalar(Multiply, x, 2.0)
+//
+// The generated <Op>Scalar variants of the standard binary operations (e.g. AddScalar) are shortcuts to it.
+func WithScalar(op func(lhs, rhs *Value) (*Value, error), x *Value, scalar any) (output *Value, err error) {
+	fn := x.fn
+	defer fn.opErrorHandler(&err, &output)()
+	rhs, err := FullLike(x, scalar)
+	if err != nil {
+		return nil, err
+	}
+	return op(x, rhs)
+}
+
+// isNonPositiveScalar returns whether the Go scalar is a number (or bool) <= 0 (or false). NaN is not.
+func isNonPositiveScalar(value any) bool {
+	valueV := reflect.ValueOf(value)
+	switch {
+	case !valueV.IsValid():
+		return false
+	case valueV.Kind() == reflect.Bool:
+		return !valueV.Bool()
+	case valueV.CanInt():
+		return valueV.Int() <= 0
+	case valueV.CanUint():
+		return valueV.Uint() == 0
+	case valueV.CanFloat():
+		return valueV.Float() <= 0
+	default:
+		return false
+	}
+}
+
+// nativeScalarDTypes are the dtypes whose Go type a numeric scalar can be converted to with reflect.
+var nativeScalarDTypes = []dtypes.DType{
+	dtypes.Int8, dtypes.Int16, dtypes.Int32, dtypes.Int64,
+	dtypes.Uint8, dtypes.Uint16, dtypes.Uint32, dtypes.Uint64,
+	dtypes.Float32, dtypes.Float64,
+}
+
+// scalarConstantOfDType creates a scalar constant of the given dtype from a Go scalar (bool or number) value.
+//
+// It's the conversion used by all the constants created for a given dtype (FullLike, WithScalar, ConstantZeros,
+// Function.ConstantFromScalarOfDType, etc.), with the rules:
+//
+//   - Floats: the value is rounded to the nearest representable value (ties to even). Values too large become
+//     infinities, or NaN for the dtypes without infinities (e.g. F8E4M3FN). For the unsigned F8E8M0FNU,
+//     non-positive values become NaN. Dtypes without a Go type (the f8 variants) are rendered with their bits.
+//   - Integers: the value is truncated towards zero and saturated to the range of the dtype. NaN becomes 0.
+//   - Bool: true if the value is not 0.
+//   - Complex: a real value becomes the real part. Complex values are only accepted for complex dtypes.
+func (fn *Function) scalarConstantOfDType(dtype dtypes.DType, value any) (*Value, error) {
+	if dtypes.FromAny(value) == dtype {
+		return fn.ConstantFromScalar(value)
+	}
+	valueV := reflect.ValueOf(value)
+	if !valueV.IsValid() {
+		return nil, errors.Errorf("unsupported scalar value type %T, expected a bool or a number", value)
+	}
+	var f64 float64
+	switch valueV.Kind() {
+	case reflect.Bool:
+		if valueV.Bool() {
+			f64 = 1
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		f64 = float64(valueV.Int())
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		f64 = float64(valueV.Uint())
+	case reflect.Float32, reflect.Float64:
+		f64 = valueV.Float()
+	case reflect.Complex64, reflect.Complex128:
+		if !dtype.IsComplex() {
+			return nil, errors.Errorf("cannot convert complex value %v to dtype %s", value, dtype)
+		}
+		if dtype == dtypes.Complex64 {
+			return fn.ConstantFromScalar(complex64(valueV.Complex()))
+		}
+		return fn.ConstantFromScalar(valueV.Complex())
+	default:
+		return nil, errors.Errorf("unsupported scalar value type %T, expected a bool or a number", value)
+	}
+
+	switch dtype {
+	case dtypes.Bool:
+		return fn.ConstantFromScalar(f64 != 0)
+	case dtypes.Float64:
+		return fn.ConstantFromScalar(f64)
+	case dtypes.Float32:
+		return fn.ConstantFromScalar(float32(f64))
+	case dtypes.Float16:
+		return fn.ConstantFromScalar(float16.Frombits(uint16(floatFormats[dtype].bitsFromFloat64(f64))))
+	case dtypes.BFloat16:
+		return fn.ConstantFromScalar(bfloat16.FromBits(uint16(floatFormats[dtype].bitsFromFloat64(f64))))
+	case dtypes.Complex64:
+		return fn.ConstantFromScalar(complex(float32(f64), 0))
+	case dtypes.Complex128:
+		return fn.ConstantFromScalar(complex(f64, 0))
+	}
+	if format, found := floatFormats[dtype]; found {
+		return fn.constantFromBits(dtype, format, format.bitsFromFloat64(f64)), nil
+	}
+	minValue, maxValue, found := integerRange(dtype)
+	if !found {
+		return nil, errors.Errorf("dtype %s is not supported for scalar constants", dtype)
+	}
+
+	// Saturate in the integer domain when possible, to preserve the precision of large integers.
+	var saturated any
+	switch kind := valueV.Kind(); {
+	case kind >= reflect.Int && kind <= reflect.Int64:
+		v := valueV.Int()
+		switch {
+		case v < minValue:
+			saturated = minValue
+		case v >= 0 && uint64(v) > maxValue:
+			saturated = maxValue
+		default:
+			saturated = v
+		}
+	case kind >= reflect.Uint && kind <= reflect.Uintptr:
+		saturated = min(valueV.Uint(), maxValue)
+	default:
+		switch truncated := math.Trunc(f64); {
+		case math.IsNaN(f64):
+			saturated = int64(0)
+		case truncated <= float64(minValue):
+			saturated = minValue
+		case truncated >= float64(maxValue):
+			saturated = maxValue
+		case truncated < 0:
+			saturated = int64(truncated)
+		default:
+			saturated = uint64(truncated)
+		}
+	}
+	if slices.Contains(nativeScalarDTypes, dtype) {
+		return fn.ConstantFromScalar(reflect.ValueOf(saturated).Convert(dtype.GoType()).Interface())
+	}
+	// The sub-byte integers have no Go type: render the integer literal directly.
+	return fn.constantFromLiteral(shapes.Make(dtype), fmt.Sprint(saturated)), nil
+}
+
+// integerRange returns the range of values of the integer dtype, including the sub-byte ones (S4, U4, ...),
+// or false if dtype is not an integer.
+func integerRange(dtype dtypes.DType) (minValue int64, maxValue uint64, found bool) {
+	switch {
+	case dtype.IsUnsigned() || dtype == dtypes.U4 || dtype == dtypes.U2:
+		return 0, math.MaxUint64 >> (64 - utils.DTypeBitWidth(dtype)), true
+	case dtype.IsInt() || dtype == dtypes.S4 || dtype == dtypes.S2:
+		bitWidth := utils.DTypeBitWidth(dtype)
+		return math.MinInt64 >> (64 - bitWidth), math.MaxInt64 >> (64 - bitWidth), true
+	default:
+		return 0, 0, false
+	}
+}
@@ -0,0 +1,228 @@
+package stablehlo
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestFullLike(t *testing.T) {
+	t.Run("Rendering", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2, 3)))
+		b := must(fn.NamedInput("b", shapes.Make(dtypes.Bool)))
+		must0(fn.Return(must(ZerosLike(x)), must(FullLike(x, 2)), must(OnesLike(b))))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestFullLike_Rendering {
+  func.func @main(%x: tensor<2x3xf32>, %b: tensor<i1>) -> (tensor<2x3xf32>, tensor<2x3xf32>, tensor<i1>) {
+    %0 = "stablehlo.constant"() { value = dense<0.0> : tensor<f32> } : () -> tensor<f32>
+    %1 = "stablehlo.broadcast_in_dim"(%0) { broadcast_dimensions = array<i64> } : (tensor<f32>) -> tensor<2x3xf32>
+    %2 = "stablehlo.constant"() { value = dense<2.0> : tensor<f32> } : () -> tensor<f32>
+    %3 = "stablehlo.broadcast_in_dim"(%2) { broadcast_dimensions = array<i64> } : (tensor<f32>) -> tensor<2x3xf32>
+    %4 = "stablehlo.constant"() { value = dense<true> : tensor<i1> } : () -> tensor<i1>
+    "stablehlo.return"(%1, %3, %4) : (tensor<2x3xf32>, tensor<2x3xf32>, tensor<i1>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+	})
+
+	t.Run("DTypes", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		for _, dtype := range []dtypes.DType{dtypes.Int8, dtypes.Uint64, dtypes.Float64, dtypes.Float16, dtypes.BFloat16,
+			dtypes.Complex128, dtypes.F8E5M2, dtypes.Bool} {
+			x := must(fn.Input(shapes.Make(dtype, 4)))
+			for _, scalar := range []any{0, uint8(1), float32(1.5), true} {
+				y := must(FullLike(x, scalar))
+				if !y.Shape().Equal(x.Shape()) {
+					t.Errorf("FullLike(%s, %v) returned shape %s", x.Shape(), scalar, y.Shape())
+				}
+			}
+		}
+		x := must(fn.Input(shapes.Make(dtypes.Float32, 4)))
+		if _, err := FullLike(x, "1"); err == nil {
+			t.Error("expected error for a string scalar, got nil")
+		}
+		if _, err := FullLike(x, 1i); err == nil {
+			t.Error("expected error for a complex scalar with a float value, got nil")
+		}
+		e8m0 := must(fn.Input(shapes.Make(dtypes.F8E8M0FNU, 4)))
+		for _, scalar := range []any{0, -2.0, uint8(0), false} {
+			if _, err := FullLike(e8m0, scalar); err == nil {
+				t.Errorf("expected error for FullLike(%s, %v) with no zero representation, got nil", e8m0.shape, scalar)
+			}
+		}
+		if _, err := ZerosLike(e8m0); err == nil {
+			t.Error("expected error for ZerosLike of a dtype with no zero representation, got nil")
+		}
+		must(FullLike(e8m0, 0.5))
+	})
+
+	t.Run("Conversion", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		i32 := must(fn.NamedInput("i32", shapes.Make(dtypes.Int32)))
+		u8 := must(fn.NamedInput("u8", shapes.Make(dtypes.Uint8)))
+		f8 := must(fn.NamedInput("f8", shapes.Make(dtypes.F8E4M3FN)))
+		must0(fn.Return(must(AddScalar(i32, 1e10)), must(AddScalar(u8, -1)), must(FullLike(u8, uint64(1000))),
+			must(FullLike(f8, 1.5))))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestFullLike_Conversion {
+  func.func @main(%i32: tensor<i32>, %u8: tensor<ui8>, %f8: tensor<f8E4M3FN>) -> (tensor<i32>, tensor<ui8>, tensor<ui8>, tensor<f8E4M3FN>) {
+    %0 = "stablehlo.constant"() { value = dense<2147483647> : tensor<i32> } : () -> tensor<i32>
+    %1 = "stablehlo.add"(%i32, %0) : (tensor<i32>, tensor<i32>) -> tensor<i32>
+    %2 = "stablehlo.constant"() { value = dense<0> : tensor<ui8> } : () -> tensor<ui8>
+    %3 = "stablehlo.add"(%u8, %2) : (tensor<ui8>, tensor<ui8>) -> tensor<ui8>
+    %4 = "stablehlo.constant"() { value = dense<255> : tensor<ui8> } : () -> tensor<ui8>
+    %5 = "stablehlo.constant"() { value = dense<0x3c> : tensor<f8E4M3FN> } : () -> tensor<f8E4M3FN>
+    "stablehlo.return"(%1, %3, %4, %5) : (tensor<i32>, tensor<ui8>, tensor<ui8>, tensor<f8E4M3FN>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+	})
+}
+
+func TestConstantZerosOnes(t *testing.T) {
+	t.Run("Rendering", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		must0(fn.Return(
+			must(fn.ConstantZeros(shapes.Make(dtypes.Bool, 2))),
+			must(fn.ConstantOnes(shapes.Make(dtypes.Bool))),
+			must(fn.ConstantOnes(shapes.Make(dtypes.Complex64, 2))),
+			must(fn.ConstantZeros(shapes.Make(dtypes.S4, 3))),
+			must(fn.ConstantOnes(shapes.Make(dtypes.U2))),
+			must(fn.ConstantOnes(shapes.Make(dtypes.F8E4M3FN, 2))),
+			must(fn.ConstantOnes(shapes.Make(dtypes.F8E8M0FNU))),
+			must(fn.ConstantZeros(shapes.Make(dtypes.Int32))),
+		))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestConstantZerosOnes_Rendering {
+  func.func @main() -> (tensor<2xi1>, tensor<i1>, tensor<2xcomplex<f32>>, tensor<3xi4>, tensor<ui2>, tensor<2xf8E4M3FN>, tensor<f8E8M0FNU>, tensor<i32>) {
+    %0 = "stablehlo.constant"() { value = dense<false> : tensor<i1> } : () -> tensor<i1>
+    %1 = "stablehlo.broadcast_in_dim"(%0) { broadcast_dimensions = array<i64> } : (tensor<i1>) -> tensor<2xi1>
+    %2 = "stablehlo.constant"() { value = dense<true> : tensor<i1> } : () -> tensor<i1>
+    %3 = "stablehlo.constant"() { value = dense<(1.0, 0.0)> : tensor<complex<f32>> } : () -> tensor<complex<f32>>
+    %4 = "stablehlo.broadcast_in_dim"(%3) { broadcast_dimensions = array<i64> } : (tensor<complex<f32>>) -> tensor<2xcomplex<f32>>
+    %5 = "stablehlo.constant"() { value = dense<0> : tensor<i4> } : () -> tensor<i4>
+    %6 = "stablehlo.broadcast_in_dim"(%5) { broadcast_dimensions = array<i64> } : (tensor<i4>) -> tensor<3xi4>
+    %7 = "stablehlo.constant"() { value = dense<1> : tensor<ui2> } : () -> tensor<ui2>
+    %8 = "stablehlo.constant"() { value = dense<0x38> : tensor<f8E4M3FN> } : () -> tensor<f8E4M3FN>
+    %9 = "stablehlo.broadcast_in_dim"(%8) { broadcast_dimensions = array<i64> } : (tensor<f8E4M3FN>) -> tensor<2xf8E4M3FN>
+    %10 = "stablehlo.constant"() { value = dense<0x7f> : tensor<f8E8M0FNU> } : () -> tensor<f8E8M0FNU>
+    %11 = "stablehlo.constant"() { value = dense<0> : tensor<i32> } : () -> tensor<i32>
+    "stablehlo.return"(%1, %2, %4, %6, %7, %9, %10, %11) : (tensor<2xi1>, tensor<i1>, tensor<2xcomplex<f32>>, tensor<3xi4>, tensor<ui2>, tensor<2xf8E4M3FN>, tensor<f8E8M0FNU>, tensor<i32>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		for _, shape := range []shapes.Shape{
+			shapes.Make(dtypes.F8E8M0FNU, 2), // No zero.
+			shapes.Make(dtypes.TOKEN),
+			shapes.Make(dtypes.F4E2M1FN),
+			shapes.Make(dtypes.Float32, shapes.DynamicDim),
+		} {
+			if _, err := fn.ConstantZeros(shape); err == nil {
+				t.Errorf("expected error for ConstantZeros(%s), got nil", shape)
+			}
+		}
+		if _, err := fn.ConstantOnes(shapes.Make(dtypes.InvalidDType)); err == nil {
+			t.Error("expected error for ConstantOnes with an invalid dtype, got nil")
+		}
+	})
+}
+
+func TestConstantFromScalarOfDType(t *testing.T) {
+	builder := New(t.Name())
+	fn := builder.Main()
+	var outputs []*Value
+	for _, tc := range []struct {
+		dtype dtypes.DType
+		value float64
+	}{
+		{dtypes.Float32, 0.5},
+		{dtypes.BFloat16, 1.5},
+		{dtypes.Float16, -2},
+		{dtypes.F8E4M3FN, 1e6}, // Saturates to NaN, f8E4M3FN has no infinities.
+		{dtypes.Int8, 300},     // Saturates to the max value.
+		{dtypes.Uint16, -3},    // Saturates to 0.
+		{dtypes.Int64, -2.7},   // Truncated.
+		{dtypes.S4, -100},      // Saturates to the min value.
+		{dtypes.U4, 9.9},       // Truncated.
+		{dtypes.Int32, math.NaN()},
+		{dtypes.Bool, 0.1},
+		{dtypes.Complex128, 3},
+	} {
+		c := must(fn.ConstantFromScalarOfDType(tc.dtype, tc.value))
+		if !c.Shape().Equal(shapes.Make(tc.dtype)) {
+			t.Errorf("ConstantFromScalarOfDType(%s, %g) returned shape %s", tc.dtype, tc.value, c.Shape())
+		}
+		outputs = append(outputs, c)
+	}
+	must0(fn.Return(outputs...))
+	program := string(must(builder.Build()))
+	fmt.Printf("%s program:\n%s", t.Name(), program)
+	want := `module @TestConstantFromScalarOfDType {
+  func.func @main() -> (tensor<f32>, tensor<bf16>, tensor<f16>, tensor<f8E4M3FN>, tensor<i8>, tensor<ui16>, tensor<i64>, tensor<i4>, tensor<ui4>, tensor<i32>, tensor<i1>, tensor<complex<f64>>) {
+    %0 = "stablehlo.constant"() { value = dense<0.5> : tensor<f32> } : () -> tensor<f32>
+    %1 = "stablehlo.constant"() { value = dense<1.5> : tensor<bf16> } : () -> tensor<bf16>
+    %2 = "stablehlo.constant"() { value = dense<-2.0> : tensor<f16> } : () -> tensor<f16>
+    %3 = "stablehlo.constant"() { value = dense<0x7f> : tensor<f8E4M3FN> } : () -> tensor<f8E4M3FN>
+    %4 = "stablehlo.constant"() { value = dense<127> : tensor<i8> } : () -> tensor<i8>
+    %5 = "stablehlo.constant"() { value = dense<0> : tensor<ui16> } : () -> tensor<ui16>
+    %6 = "stablehlo.constant"() { value = dense<-2> : tensor<i64> } : () -> tensor<i64>
+    %7 = "stablehlo.constant"() { value = dense<-8> : tensor<i4> } : () -> tensor<i4>
+    %8 = "stablehlo.constant"() { value = dense<9> : tensor<ui4> } : () -> tensor<ui4>
+    %9 = "stablehlo.constant"() { value = dense<0> : tensor<i32> } : () -> tensor<i32>
+    %10 = "stablehlo.constant"() { value = dense<true> : tensor<i1> } : () -> tensor<i1>
+    %11 = "stablehlo.constant"() { value = dense<(3.0, 0.0)> : tensor<complex<f64>> } : () -> tensor<complex<f64>>
+    "stablehlo.return"(%0, %1, %2, %3, %4, %5, %6, %7, %8, %9, %10, %11) : (tensor<f32>, tensor<bf16>, tensor<f16>, tensor<f8E4M3FN>, tensor<i8>, tensor<ui16>, tensor<i64>, tensor<i4>, tensor<ui4>, tensor<i32>, tensor<i1>, tensor<complex<f64>>) -> ()
+  }
+}
+`
+	if program != want {
+		fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+		t.Fatal("programs don't match")
+	}
+
+	// FullLike (and so WithScalar) uses the same conversion.
+	fn = New(t.Name() + "_full_like").Main()
+	for _, dtype := range []dtypes.DType{dtypes.Uint8, dtypes.Int32, dtypes.S4, dtypes.F8E5M2, dtypes.BFloat16} {
+		for _, value := range []float64{-1, 1e10, 2.5, math.NaN()} {
+			x := must(fn.Input(shapes.Make(dtype)))
+			got := must(FullLike(x, value)).Producer().Attributes["value"]
+			want := must(fn.ConstantFromScalarOfDType(dtype, value)).Producer().Attributes["value"]
+			if fmt.Sprint(got) != fmt.Sprint(want) {
+				t.Errorf("FullLike(%s, %g) = %v, ConstantFromScalarOfDType = %v", dtype, value, got, want)
+			}
+		}
+	}
+
+	fn = New(t.Name() + "_errors").Main()
+	if _, err := fn.ConstantFromScalarOfDType(dtypes.TOKEN, 1); err == nil {
+		t.Error("expected error for ConstantFromScalarOfDType(TOKEN), got nil")
+	}
+}
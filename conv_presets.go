@@ -0,0 +1,147 @@
+package stablehlo
+
+import (
+	"slices"
+
+	"github.com/gomlx/stablehlo/types"
+	"github.com/pkg/errors"
+)
+
+// ConvLayout selects one of the standard axes layouts of the Conv1D, DepthwiseConv2D and Conv3D convolution presets.
+//
+// The layout is shared by the input, the kernel and the output, using the usual letters: N for the batch axis,
+// C for the channels, I and O for the kernel input and output channels, and W, H, D for the spatial axes.
+type ConvLayout int
+
+const (
+	// ChannelsLast is the NWC (1D), NHWC (2D) or NDHWC (3D) layout for input and output, with the kernel laid out
+	// as WIO, HWIO or DHWIO. It's the default of TensorFlow and JAX.
+	ChannelsLast ConvLayout = iota
+
+	// ChannelsFirst is the NCW (1D), NCHW (2D) or NCDHW (3D) layout for input and output, with the kernel laid out
+	// as OIW, OIHW or OIDHW. It's the default of PyTorch.
+	ChannelsFirst
+)
+
+// String implements fmt.Stringer.
+func (l ConvLayout) String() string {
+	switch l {
+	case ChannelsLast:
+		return "ChannelsLast"
+	case ChannelsFirst:
+		return "ChannelsFirst"
+	default:
+		return "InvalidConvLayout"
+	}
+}
+
+// AxesConfig returns the convolution axes configuration of the layout for the given number of spatial axes,
+// to be used with ConvolutionWith.
+func (l ConvLayout) AxesConfig(numSpatialAxes int) (types.ConvolveAxesConfig, error) {
+	var axes types.ConvolveAxesConfig
+	if numSpatialAxes < 1 {
+		return axes, errors.Errorf("ConvLayout.AxesConfig: invalid number of spatial axes %d", numSpatialAxes)
+	}
+	// spatialAxes returns the numSpatialAxes consecutive axes starting at first.
+	spatialAxes := func(first int) []int {
+		spatial := make([]int, numSpatialAxes)
+		for i := range spatial {
+			spatial[i] = first + i
+		}
+		return spatial
+	}
+	switch l {
+	case ChannelsLast:
+		axes.InputBatch, axes.InputChannels, axes.InputSpatial = 0, numSpatialAxes+1, spatialAxes(1)
+		axes.KernelInputChannels, axes.KernelOutputChannels, axes.KernelSpatial = numSpatialAxes, numSpatialAxes+1, spatialAxes(0)
+	case ChannelsFirst:
+		axes.InputBatch, axes.InputChannels, axes.InputSpatial = 0, 1, spatialAxes(2)
+		axes.KernelInputChannels, axes.KernelOutputChannels, axes.KernelSpatial = 1, 0, spatialAxes(2)
+	default:
+		return axes, errors.Errorf("ConvLayout.AxesConfig: invalid layout %d", l)
+	}
+	axes.OutputBatch, axes.OutputChannels, axes.OutputSpatial = axes.InputBatch, axes.InputChannels, slices.Clone(axes.InputSpatial)
+	return axes, nil
+}
+
+// Conv1D convolves input with kernel over one spatial axis, with the given layout: NWC input and output with
+// a WIO kernel for ChannelsLast, or NCW input and output with an OIW kernel for ChannelsFirst.
+//
+// The window configures the strides, paddings and dilations (see NewWindow), and it can be left empty for the
+// defaults. It uses the default precision, see ConvolutionWith for full control of the convolution.
+func Conv1D(input, kernel *Value, layout ConvLayout, window Window) (*Value, error) {
+	return convWithLayout("Conv1D", input, kernel, layout, 1, window, 1)
+}
+
+// Conv3D convolves input with kernel over three spatial axes, with the given layout: NDHWC input and output with
+// a DHWIO kernel for ChannelsLast, or NCDHW input and output with an OIDHW kernel for ChannelsFirst.
+//
+// The window configures the strides, paddings and dilations (see NewWindow), and it can be left empty for the
+// defaults. It uses the default precision, see ConvolutionWith for full control of the convolution.
+func Conv3D(input, kernel *Value, layout ConvLayout, window Window) (*Value, error) {
+	return convWithLayout("Conv3D", input, kernel, layout, 3, window, 1)
+}
+
+// DepthwiseConv2D convolves each channel of input with its own filters over two spatial axes, with the given
+// layout: NHWC input and output with an HW1O kernel for ChannelsLast, or NCHW input and output with an O1HW kernel
+// for ChannelsFirst.
+//
+// The kernel input channels axis must have dimension 1, and its output channels dimension must be a multiple of the
+// input channels (the "channel multiplier"): output channel k is computed from the input channel k / multiplier.
+// This is the layout of PyTorch's depthwise weights, and of TensorFlow's [H, W, C, M] weights reshaped to
+// [H, W, 1, C*M].
+//
+// It's a Convolution with channelGroupCount set to the number of input channels. Any other kernel layout would
+// either fail or silently compute a different grouped convolution, so it is validated here.
+//
+// The window configures the strides, paddings and dilations (see NewWindow), and it can be left empty for the
+// defaults.
+func DepthwiseConv2D(input, kernel *Value, layout ConvLayout, window Window) (output *Value, err error) {
+	fn := input.fn
+	defer fn.opErrorHandler(&err, &output)()
+	axes, err := layout.AxesConfig(2)
+	if err != nil {
+		return nil, errors.WithMessage(err, "DepthwiseConv2D")
+	}
+	if input.shape.Rank() != 4 || kernel.shape.Rank() != 4 {
+		return nil, errors.Errorf("DepthwiseConv2D: input and kernel must have rank 4 with the %s layout, got input %s and kernel %s",
+			layout, input.shape, kernel.shape)
+	}
+	inputChannels := input.shape.Dimensions[axes.InputChannels]
+	kernelInputChannels := kernel.shape.Dimensions[axes.KernelInputChannels]
+	kernelOutputChannels := kernel.shape.Dimensions[axes.KernelOutputChannels]
+	if kernelInputChannels != 1 {
+		return nil, errors.Errorf("DepthwiseConv2D: kernel %s must have dimension 1 on its input channels axis %d "+
+			"with the %s layout, got %d", kernel.shape, axes.KernelInputChannels, layout, kernelInputChannels)
+	}
+	if inputChannels == 0 || kernelOutputChannels%inputChannels != 0 {
+		return nil, errors.Errorf("DepthwiseConv2D: kernel %s output channels (%d) must be a multiple of the input %s "+
+			"channels (%d) with the %s layout", kernel.shape, kernelOutputChannels, input.shape, inputChannels, layout)
+	}
+	return convWithLayout("DepthwiseConv2D", input, kernel, layout, 2, window, inputChannels)
+}
+
+// convWithLayout implements the convolution presets: it checks the ranks of the operands and calls
+// ConvolutionWith with the axes of the layout.
+func convWithLayout(name string, input, kernel *Value, layout ConvLayout, numSpatialAxes int, window Window,
+	channelGroupCount int) (output *Value, err error) {
+	fn := input.fn
+	defer fn.opErrorHandler(&err, &output)()
+	if fn.Returned {
+		return nil, errors.Errorf("cannot add operation %s after returning, in function %q", name, fn.Name)
+	}
+	axes, err := layout.AxesConfig(numSpatialAxes)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "%s", name)
+	}
+	if input.shape.Rank() != numSpatialAxes+2 || kernel.shape.Rank() != numSpatialAxes+2 {
+		return nil, errors.Errorf("%s: input and kernel must have rank %d with the %s layout, got input %s and kernel %s",
+			name, numSpatialAxes+2, layout, input.shape, kernel.shape)
+	}
+	output, err = ConvolutionWith(input, kernel, window, axes, channelGroupCount, 1,
+		types.DotGeneralPrecisionDefault, types.DotGeneralPrecisionDefault)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "%s", name)
+	}
+	return output, nil
+}
@@ -0,0 +1,121 @@
+package stablehlo
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestConvPresets(t *testing.T) {
+	t.Run("AxesConfig", func(t *testing.T) {
+		axes := must(ChannelsLast.AxesConfig(3))
+		if axes.InputBatch != 0 || axes.InputChannels != 4 || !slices.Equal(axes.InputSpatial, []int{1, 2, 3}) ||
+			axes.KernelInputChannels != 3 || axes.KernelOutputChannels != 4 || !slices.Equal(axes.KernelSpatial, []int{0, 1, 2}) ||
+			axes.OutputChannels != 4 || !slices.Equal(axes.OutputSpatial, []int{1, 2, 3}) {
+			t.Errorf("unexpected NDHWC axes configuration: %+v", axes)
+		}
+		axes = must(ChannelsFirst.AxesConfig(1))
+		if axes.InputBatch != 0 || axes.InputChannels != 1 || !slices.Equal(axes.InputSpatial, []int{2}) ||
+			axes.KernelInputChannels != 1 || axes.KernelOutputChannels != 0 || !slices.Equal(axes.KernelSpatial, []int{2}) ||
+			axes.OutputChannels != 1 || !slices.Equal(axes.OutputSpatial, []int{2}) {
+			t.Errorf("unexpected NCW axes configuration: %+v", axes)
+		}
+		if _, err := ConvLayout(7).AxesConfig(2); err == nil {
+			t.Error("expected error for invalid layout, got nil")
+		}
+	})
+
+	t.Run("Conv1D and Conv3D", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x1 := must(fn.NamedInput("x1", shapes.Make(dtypes.Float32, 2, 8, 3)))
+		k1 := must(fn.NamedInput("k1", shapes.Make(dtypes.Float32, 3, 3, 4)))
+		x3 := must(fn.NamedInput("x3", shapes.Make(dtypes.Float32, 2, 3, 5, 6, 7)))
+		k3 := must(fn.NamedInput("k3", shapes.Make(dtypes.Float32, 4, 3, 2, 2, 2)))
+		if _, err := Conv1D(x1, k1, ChannelsLast, Window{Strides: []int{2, 2}}); err == nil {
+			t.Error("expected error for window with the wrong number of axes, got nil")
+		}
+		if _, err := Conv3D(x1, k1, ChannelsLast, Window{}); err == nil {
+			t.Error("expected error for operands with the wrong rank, got nil")
+		}
+		y1 := must(Conv1D(x1, k1, ChannelsLast, must(NewWindow().Strides(2).Paddings([2]int{1, 1}).Done())))
+		if want := shapes.Make(dtypes.Float32, 2, 4, 4); !y1.Shape().Equal(want) {
+			t.Errorf("Conv1D: got shape %s, wanted %s", y1.Shape(), want)
+		}
+		y3 := must(Conv3D(x3, k3, ChannelsFirst, Window{}))
+		if want := shapes.Make(dtypes.Float32, 2, 4, 4, 5, 6); !y3.Shape().Equal(want) {
+			t.Errorf("Conv3D: got shape %s, wanted %s", y3.Shape(), want)
+		}
+		must0(fn.Return(y1, y3))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestConvPresets_Conv1D_and_Conv3D {
+  func.func @main(%x1: tensor<2x8x3xf32>, %k1: tensor<3x3x4xf32>, %x3: tensor<2x3x5x6x7xf32>, %k3: tensor<4x3x2x2x2xf32>) -> (tensor<2x4x4xf32>, tensor<2x4x4x5x6xf32>) {
+    %0 = "stablehlo.convolution"(%x1, %k1) {
+      batch_group_count = 1 : i64,
+      dimension_numbers = #stablehlo.conv<[b, 0, f]x[0, i, o]->[b, 0, f]>,
+      feature_group_count = 1 : i64,
+      lhs_dilation = array<i64: 1>,
+      padding = dense<[[1, 1]]> : tensor<1x2xi64>,
+      precision_config = [#stablehlo<precision DEFAULT>, #stablehlo<precision DEFAULT>],
+      rhs_dilation = array<i64: 1>,
+      window_reversal = array<i1: false>,
+      window_strides = array<i64: 2>
+    } : (tensor<2x8x3xf32>, tensor<3x3x4xf32>) -> tensor<2x4x4xf32>
+    %1 = "stablehlo.convolution"(%x3, %k3) {
+      batch_group_count = 1 : i64,
+      dimension_numbers = #stablehlo.conv<[b, f, 0, 1, 2]x[o, i, 0, 1, 2]->[b, f, 0, 1, 2]>,
+      feature_group_count = 1 : i64,
+      lhs_dilation = array<i64: 1, 1, 1>,
+      padding = dense<[[0, 0], [0, 0], [0, 0]]> : tensor<3x2xi64>,
+      precision_config = [#stablehlo<precision DEFAULT>, #stablehlo<precision DEFAULT>],
+      rhs_dilation = array<i64: 1, 1, 1>,
+      window_reversal = array<i1: false, false, false>,
+      window_strides = array<i64: 1, 1, 1>
+    } : (tensor<2x3x5x6x7xf32>, tensor<4x3x2x2x2xf32>) -> tensor<2x4x4x5x6xf32>
+    "stablehlo.return"(%0, %1) : (tensor<2x4x4xf32>, tensor<2x4x4x5x6xf32>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+	})
+
+	t.Run("DepthwiseConv2D", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2, 8, 8, 3)))
+		k := must(fn.NamedInput("k", shapes.Make(dtypes.Float32, 3, 3, 1, 6)))
+		for i, kernelDims := range [][]int{
+			{3, 3, 3, 6}, // Kernel input channels must be 1.
+			{3, 3, 1, 4}, // Kernel output channels must be a multiple of the input channels.
+			{3, 3, 6},    // Wrong rank.
+		} {
+			badKernel := must(fn.NamedInput(fmt.Sprintf("bad%d", i), shapes.Make(dtypes.Float32, kernelDims...)))
+			if _, err := DepthwiseConv2D(x, badKernel, ChannelsLast, Window{}); err == nil {
+				t.Errorf("expected error for kernel dimensions %v, got nil", kernelDims)
+			}
+		}
+		y := must(DepthwiseConv2D(x, k, ChannelsLast, must(NewWindow().Strides(2, 2).Done())))
+		if want := shapes.Make(dtypes.Float32, 2, 3, 3, 6); !y.Shape().Equal(want) {
+			t.Errorf("DepthwiseConv2D: got shape %s, wanted %s", y.Shape(), want)
+		}
+		must0(fn.Return(y))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		for _, want := range []string{
+			"dimension_numbers = #stablehlo.conv<[b, 0, 1, f]x[0, 1, i, o]->[b, 0, 1, f]>",
+			"feature_group_count = 3 : i64",
+		} {
+			if !strings.Contains(program, want) {
+				t.Errorf("program missing %q", want)
+			}
+		}
+	})
+}
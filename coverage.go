@@ -0,0 +1,68 @@
+package stablehlo
+
+import (
+	_ "embed"
+	"encoding/json"
+	"slices"
+	"strings"
+)
+
+// opCoverageJSON is generated by ./internal/cmd/ops_generator, see go:generate in stablehlo.go.
+//
+//go:embed op_coverage.json
+var opCoverageJSON []byte
+
+// OpCoverageStatus is the implementation status of an operation of the StableHLO specification.
+type OpCoverageStatus string
+
+const (
+	// OpImplemented operations can be added to programs with this package.
+	OpImplemented OpCoverageStatus = "implemented"
+
+	// OpShapeInferenceOnly operations have shape inference (see package shapeinference), but can't be added to
+	// programs yet.
+	OpShapeInferenceOnly OpCoverageStatus = "shape_inference"
+
+	// OpMissing operations are not supported. Consider Function.RawStatement as a workaround.
+	OpMissing OpCoverageStatus = "missing"
+)
+
+// OpCoverage describes the support of one operation of the StableHLO specification.
+type OpCoverage struct {
+	// Name of the operation, e.g. "stablehlo.add".
+	Name string `json:"name"`
+
+	// Status of the implementation.
+	Status OpCoverageStatus `json:"status"`
+
+	// OpType is the name of the corresponding internal operation type, if one is defined. Usually it's also the name
+	// of the function that creates the operation.
+	OpType string `json:"op_type,omitempty"`
+}
+
+// OpsCoverage returns the coverage of the operations of the StableHLO specification (https://openxla.org/stablehlo/spec)
+// by this package, sorted by name. Deprecated operations are not listed.
+//
+// It's generated from the source code, and the same information is available in the op_coverage.json file, for
+// tools that don't use Go.
+func OpsCoverage() []OpCoverage {
+	var coverage []OpCoverage
+	if err := json.Unmarshal(opCoverageJSON, &coverage); err != nil {
+		panic(err) // The embedded file is generated, it should never be invalid.
+	}
+	return coverage
+}
+
+// OpCoverageOf returns the coverage of the StableHLO operation with the given name ("stablehlo.<op>" or just "<op>").
+// It returns false if the operation is not in the StableHLO specification.
+func OpCoverageOf(name string) (OpCoverage, bool) {
+	if !strings.Contains(name, ".") {
+		name = "stablehlo." + name
+	}
+	coverage := OpsCoverage()
+	idx := slices.IndexFunc(coverage, func(op OpCoverage) bool { return op.Name == name })
+	if idx < 0 {
+		return OpCoverage{}, false
+	}
+	return coverage[idx], true
+}
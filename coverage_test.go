@@ -0,0 +1,69 @@
+package stablehlo
+
+import (
+	"encoding/json"
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/stablehlo/internal/opscoverage"
+)
+
+func TestOpsCoverage(t *testing.T) {
+	coverage := OpsCoverage()
+	if len(coverage) < 100 {
+		t.Fatalf("expected at least 100 operations in the coverage report, got %d", len(coverage))
+	}
+	if !slices.IsSortedFunc(coverage, func(a, b OpCoverage) int { return strings.Compare(a.Name, b.Name) }) {
+		t.Error("coverage report is not sorted by name")
+	}
+	for _, op := range coverage {
+		switch op.Status {
+		case OpImplemented:
+			if op.OpType == "" {
+				t.Errorf("implemented operation %s has no OpType", op.Name)
+			}
+		case OpShapeInferenceOnly, OpMissing:
+		default:
+			t.Errorf("operation %s has invalid status %q", op.Name, op.Status)
+		}
+	}
+
+	for name, want := range map[string]OpCoverageStatus{
+		"stablehlo.add":                  OpImplemented,
+		"dot_general":                    OpImplemented,
+		"stablehlo.optimization_barrier": OpImplemented,
+		"stablehlo.after_all":            OpMissing,
+		"stablehlo.dynamic_iota":         OpImplemented,
+	} {
+		op, found := OpCoverageOf(name)
+		if !found || op.Status != want {
+			t.Errorf("OpCoverageOf(%q) = %+v, %v, want status %q", name, op, found, want)
+		}
+	}
+	if _, found := OpCoverageOf("stablehlo.not_an_op"); found {
+		t.Error("OpCoverageOf should not find operations not in the specification")
+	}
+}
+
+// TestOpsCoverageUpToDate checks that the embedded op_coverage.json matches the current code: if it fails, run
+// `go generate .` to regenerate it.
+func TestOpsCoverageUpToDate(t *testing.T) {
+	generated, err := opscoverage.Generate(".")
+	if err != nil {
+		t.Fatalf("failed to generate the coverage: %+v", err)
+	}
+	var want []OpCoverage
+	if err := json.Unmarshal(generated, &want); err != nil {
+		t.Fatalf("failed to parse the generated coverage: %+v", err)
+	}
+	if got := OpsCoverage(); !reflect.DeepEqual(got, want) {
+		for i := range min(len(got), len(want)) {
+			if got[i] != want[i] {
+				t.Errorf("op_coverage.json has %+v, but the code has %+v", got[i], want[i])
+			}
+		}
+		t.Fatal("op_coverage.json is out-of-date, run `go generate .` to regenerate it")
+	}
+}
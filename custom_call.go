@@ -0,0 +1,402 @@
+package stablehlo
+
+import (
+	"fmt"
+	"maps"
+	"reflect"
+	"slices"
+	"strings"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/internal/utils"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// CustomCallAPIVersion is the version of the API used by the custom call target, see CustomCallBuilder.APIVersion.
+type CustomCallAPIVersion int
+
+const (
+	// CustomCallAPIVersionOriginal is the original (legacy) custom call API, with an opaque string backend_config.
+	CustomCallAPIVersionOriginal CustomCallAPIVersion = 1
+
+	// CustomCallAPIVersionStatusReturning is the legacy custom call API where the target can return a status.
+	CustomCallAPIVersionStatusReturning CustomCallAPIVersion = 2
+
+	// CustomCallAPIVersionStatusReturningUnified is the legacy custom call API, with a unified signature across backends.
+	CustomCallAPIVersionStatusReturningUnified CustomCallAPIVersion = 3
+
+	// CustomCallAPIVersionTypedFFI is the typed FFI API (used by PJRT plugins' FFI handlers), whose backend_config
+	// is a dictionary of typed attributes. See BackendConfig.
+	CustomCallAPIVersionTypedFFI CustomCallAPIVersion = 4
+)
+
+// CustomCallBuilder is a builder for CustomCall operations. See Function.CustomCall for more details.
+type CustomCallBuilder struct {
+	fn             *Function
+	callTargetName string
+	inputs         []*Value
+	outputShapes   []shapes.Shape
+
+	apiVersion          CustomCallAPIVersion
+	hasSideEffect       bool
+	backendConfig       *BackendConfig
+	backendConfigString *string
+
+	// outputOperandAliases are the pairs (output index, operand index) given to AliasOutputToOperand.
+	outputOperandAliases [][2]int
+}
+
+// CustomCall creates a call to the custom (backend specific) function registered as callTargetName, for instance
+// a PJRT plugin FFI handler.
+//
+// The outputShapes must be given, since they can't be inferred: the custom call will have one output per shape.
+//
+// Because there are optional parameters, this function returns a CustomCallBuilder that can
+// be further configured. Call CustomCallBuilder.Done to get the outputs of the operation.
+//
+// Example of a call to an FFI handler with typed attributes:
+//
+//	outputs, err := fn.CustomCall("my_ffi_handler", []shapes.Shape{x.Shape()}, x).
+//		BackendConfig(NewBackendConfig().Float32("alpha", 0.5).Int64s("axes", 0, 1)).
+//		Done()
+func (fn *Function) CustomCall(callTargetName string, outputShapes []shapes.Shape, inputs ...*Value) *CustomCallBuilder {
+	return &CustomCallBuilder{
+		fn:             fn,
+		callTargetName: callTargetName,
+		inputs:         inputs,
+		outputShapes:   outputShapes,
+	}
+}
+
+// APIVersion sets the version of the custom call API used by the target.
+//
+// If not set, it defaults to CustomCallAPIVersionTypedFFI if a typed BackendConfig is given, otherwise it is
+// omitted (and StableHLO assumes CustomCallAPIVersionOriginal).
+func (b *CustomCallBuilder) APIVersion(version CustomCallAPIVersion) *CustomCallBuilder {
+	b.apiVersion = version
+	return b
+}
+
+// HasSideEffect marks the custom call as having side effects, so it is not removed or reordered by the compiler,
+// even if its outputs are not used.
+func (b *CustomCallBuilder) HasSideEffect() *CustomCallBuilder {
+	b.hasSideEffect = true
+	return b
+}
+
+// BackendConfig sets the typed attributes passed to the custom call target, as used by the typed FFI API.
+//
+// It is mutually exclusive with BackendConfigString.
+func (b *CustomCallBuilder) BackendConfig(config *BackendConfig) *CustomCallBuilder {
+	b.backendConfig = config
+	return b
+}
+
+// BackendConfigString sets an opaque string passed to the custom call target, as used by the legacy APIs.
+//
+// It is mutually exclusive with BackendConfig.
+func (b *CustomCallBuilder) BackendConfigString(config string) *CustomCallBuilder {
+	b.backendConfigString = &config
+	return b
+}
+
+// AliasOutputToOperand declares that the output #outputIdx of the custom call reuses the buffer of the operand
+// #operandIdx, for in-place kernels (e.g. a fused optimizer update), rendered in the output_operand_aliases attribute.
+//
+// The output and the operand must have the same shape, and each output and each operand can only be aliased once.
+// The operand buffer must not be used after the custom call.
+func (b *CustomCallBuilder) AliasOutputToOperand(outputIdx, operandIdx int) *CustomCallBuilder {
+	b.outputOperandAliases = append(b.outputOperandAliases, [2]int{outputIdx, operandIdx})
+	return b
+}
+
+// Done indicates the end of the CustomCallBuilder configuration.
+// It checks the validity of the parameters and returns the outputs of the custom call.
+func (b *CustomCallBuilder) Done() (outputs []*Value, err error) {
+	op := optypes.CustomCall
+	fn := b.fn
+	defer fn.multiOpErrorHandler(&err, &outputs, len(b.outputShapes))()
+	if fn.Returned {
+		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
+			op, fn.Name)
+	}
+	if b.callTargetName == "" {
+		return nil, errors.Errorf("%s requires a call target name", op)
+	}
+	for i, input := range b.inputs {
+		if input.fn != fn {
+			return nil, errors.Errorf("cannot add operation %s to function %q, because the input #%d is not part of the function",
+				op, fn.Name, i)
+		}
+	}
+	for i, shape := range b.outputShapes {
+		if !shape.Ok() || shape.IsTuple() {
+			return nil, errors.Errorf("%s: invalid output shape #%d: %s", op, i, shape)
+		}
+	}
+	if b.backendConfig != nil && b.backendConfigString != nil {
+		return nil, errors.Errorf("%s: BackendConfig and BackendConfigString are mutually exclusive", op)
+	}
+	apiVersion := b.apiVersion
+	if b.backendConfig != nil {
+		if err := b.backendConfig.err; err != nil {
+			return nil, errors.WithMessagef(err, "%s: invalid backend config", op)
+		}
+		if apiVersion == 0 {
+			apiVersion = CustomCallAPIVersionTypedFFI
+		} else if apiVersion != CustomCallAPIVersionTypedFFI {
+			return nil, errors.Errorf("%s: a typed BackendConfig requires the typed FFI API version (%d), got %d",
+				op, CustomCallAPIVersionTypedFFI, apiVersion)
+		}
+	}
+	if b.backendConfigString != nil && apiVersion == CustomCallAPIVersionTypedFFI {
+		return nil, errors.Errorf("%s: the typed FFI API version (%d) requires a typed BackendConfig, not a string",
+			op, CustomCallAPIVersionTypedFFI)
+	}
+	aliases, err := b.outputOperandAliasesAttribute()
+	if err != nil {
+		return nil, err
+	}
+
+	stmt := fn.addMultiOp(op, b.outputShapes, b.inputs)
+	stmt.Attributes = map[string]any{
+		"call_target_name": b.callTargetName,
+	}
+	if apiVersion != 0 {
+		stmt.Attributes["api_version"] = int32(apiVersion)
+	}
+	if b.hasSideEffect {
+		stmt.Attributes["has_side_effect"] = true
+	}
+	if b.backendConfig != nil {
+		stmt.Attributes["backend_config"] = b.backendConfig
+	} else if b.backendConfigString != nil {
+		stmt.Attributes["backend_config"] = *b.backendConfigString
+	}
+	if aliases != "" {
+		stmt.Attributes["output_operand_aliases"] = aliases
+	}
+	return stmt.Outputs, nil
+}
+
+// outputOperandAliasesAttribute validates the aliases set with AliasOutputToOperand, and returns the
+// output_operand_aliases attribute value, ordered by output index, or "" if there are no aliases.
+func (b *CustomCallBuilder) outputOperandAliasesAttribute() (literalStr, error) {
+	op := optypes.CustomCall
+	if len(b.outputOperandAliases) == 0 {
+		return "", nil
+	}
+	aliases := slices.Clone(b.outputOperandAliases)
+	slices.SortStableFunc(aliases, func(lhs, rhs [2]int) int { return lhs[0] - rhs[0] })
+	aliasedOperands := make(map[int]int)
+	var parts []string
+	for i, alias := range aliases {
+		outputIdx, operandIdx := alias[0], alias[1]
+		if i > 0 && aliases[i-1][0] == outputIdx {
+			return "", errors.Errorf("%s: output #%d aliased more than once", op, outputIdx)
+		}
+		if outputIdx < 0 || outputIdx >= len(b.outputShapes) {
+			return "", errors.Errorf("%s: aliased output #%d out of range, there are %d outputs",
+				op, outputIdx, len(b.outputShapes))
+		}
+		if operandIdx < 0 || operandIdx >= len(b.inputs) {
+			return "", errors.Errorf("%s: output #%d aliased to operand #%d out of range, there are %d operands",
+				op, outputIdx, operandIdx, len(b.inputs))
+		}
+		if otherOutputIdx, found := aliasedOperands[operandIdx]; found {
+			return "", errors.Errorf("%s: operand #%d aliased to both outputs #%d and #%d",
+				op, operandIdx, otherOutputIdx, outputIdx)
+		}
+		aliasedOperands[operandIdx] = outputIdx
+		if operandShape := b.inputs[operandIdx].shape; !operandShape.Equal(b.outputShapes[outputIdx]) {
+			return "", errors.Errorf("%s: output #%d with shape %s can't alias operand #%d with shape %s",
+				op, outputIdx, b.outputShapes[outputIdx], operandIdx, operandShape)
+		}
+		// With multiple outputs, the outputs are the elements of a tuple.
+		outputTupleIndices := ""
+		if len(b.outputShapes) > 1 {
+			outputTupleIndices = fmt.Sprintf("%d", outputIdx)
+		}
+		parts = append(parts, fmt.Sprintf(
+			"#stablehlo.output_operand_alias<output_tuple_indices = [%s], operand_index = %d, operand_tuple_indices = []>",
+			outputTupleIndices, operandIdx))
+	}
+	return literalStr("[" + strings.Join(parts, ", ") + "]"), nil
+}
+
+// BackendConfig is a builder of the typed attributes dictionary passed as backend_config to custom calls using the
+// typed FFI API (see Function.CustomCall).
+//
+// Each method sets one attribute and returns the BackendConfig itself, so calls can be chained.
+// The attributes are rendered sorted by name, and the types are preserved: e.g. Int32("n", 3) is rendered as
+// `n = 3 : i32`, which is what the FFI handler binding the attribute as int32_t expects.
+//
+// Errors (e.g.: an unsupported array type) are reported when the custom call is created.
+type BackendConfig struct {
+	attributes map[string]string
+	err        error
+}
+
+// NewBackendConfig creates an empty BackendConfig.
+func NewBackendConfig() *BackendConfig {
+	return &BackendConfig{attributes: make(map[string]string)}
+}
+
+// set the rendered value of an attribute, checking the name.
+func (c *BackendConfig) set(name, value string) *BackendConfig {
+	if c.err == nil && !isValidAttributeName(name) {
+		c.err = errors.Errorf("invalid backend config attribute name %q", name)
+	}
+	c.attributes[name] = value
+	return c
+}
+
+// isValidAttributeName returns whether the name can be used as a (bare) attribute name in a dictionary.
+func isValidAttributeName(name string) bool {
+	if name == "" || (name[0] >= '0' && name[0] <= '9') {
+		return false
+	}
+	for _, c := range name {
+		if !((c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') || c == '_' || c == '.' || c == '$') {
+			return false
+		}
+	}
+	return true
+}
+
+// Bool sets a boolean attribute.
+func (c *BackendConfig) Bool(name string, value bool) *BackendConfig {
+	return c.set(name, literalToStableHLO(value))
+}
+
+// Int32 sets an int32 attribute.
+func (c *BackendConfig) Int32(name string, value int32) *BackendConfig {
+	return c.set(name, literalToStableHLO(value))
+}
+
+// Int64 sets an int64 attribute.
+func (c *BackendConfig) Int64(name string, value int64) *BackendConfig {
+	return c.set(name, literalToStableHLO(value))
+}
+
+// Float32 sets a float32 attribute.
+func (c *BackendConfig) Float32(name string, value float32) *BackendConfig {
+	return c.set(name, literalToStableHLO(value))
+}
+
+// Float64 sets a float64 attribute.
+func (c *BackendConfig) Float64(name string, value float64) *BackendConfig {
+	return c.set(name, literalToStableHLO(value))
+}
+
+// Scalar sets an attribute of any of the Go numeric types (int8 to uint64, float32, float64) or bool.
+// The attribute type is given by the Go type of the value: e.g. uint8(1) is rendered as `1 : ui8`.
+func (c *BackendConfig) Scalar(name string, value any) *BackendConfig {
+	switch value.(type) {
+	case bool, float32, float64, int, int8, int16, int32, int64, uint8, uint16, uint32, uint64:
+		return c.set(name, literalToStableHLO(value))
+	default:
+		if c.err == nil {
+			c.err = errors.Errorf("backend config attribute %q: unsupported scalar type %T", name, value)
+		}
+		return c
+	}
+}
+
+// String sets a string attribute.
+func (c *BackendConfig) String(name, value string) *BackendConfig {
+	return c.set(name, literalToStableHLO(value))
+}
+
+// DType sets an attribute with a data type, e.g. `dtype = f32`.
+func (c *BackendConfig) DType(name string, dtype dtypes.DType) *BackendConfig {
+	return c.set(name, utils.DTypeToStableHLO(dtype))
+}
+
+// Dict sets a nested dictionary attribute.
+func (c *BackendConfig) Dict(name string, dict *BackendConfig) *BackendConfig {
+	if dict == nil {
+		if c.err == nil {
+			c.err = errors.Errorf("backend config attribute %q: Dict requires a non-nil BackendConfig", name)
+		}
+		return c
+	}
+	if c.err == nil && dict.err != nil {
+		c.err = errors.WithMessagef(dict.err, "backend config attribute %q", name)
+	}
+	return c.set(name, dict.ToStableHLO())
+}
+
+// Int32s sets a dense array of int32 attribute, e.g. `array<i32: 1, 2>`.
+func (c *BackendConfig) Int32s(name string, values ...int32) *BackendConfig {
+	return c.Array(name, values)
+}
+
+// Int64s sets a dense array of int64 attribute, e.g. `array<i64: 1, 2>`.
+func (c *BackendConfig) Int64s(name string, values ...int64) *BackendConfig {
+	return c.Array(name, values)
+}
+
+// Float32s sets a dense array of float32 attribute, e.g. `array<f32: 1.0, 2.0>`.
+func (c *BackendConfig) Float32s(name string, values ...float32) *BackendConfig {
+	return c.Array(name, values)
+}
+
+// Float64s sets a dense array of float64 attribute, e.g. `array<f64: 1.0, 2.0>`.
+func (c *BackendConfig) Float64s(name string, values ...float64) *BackendConfig {
+	return c.Array(name, values)
+}
+
+// Array sets a dense array attribute from a slice of any of the Go numeric types (int8 to uint64, float32, float64)
+// or bool. The element type is given by the Go type of the slice: e.g. []uint8{1, 2} is rendered as
+// `array<ui8: 1, 2>`.
+func (c *BackendConfig) Array(name string, values any) *BackendConfig {
+	rValues := reflect.ValueOf(values)
+	if rValues.Kind() != reflect.Slice {
+		if c.err == nil {
+			c.err = errors.Errorf("backend config attribute %q: Array requires a slice, got %T", name, values)
+		}
+		return c
+	}
+	switch rValues.Type().Elem().Kind() {
+	case reflect.Bool, reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Float32, reflect.Float64:
+	default:
+		if c.err == nil {
+			c.err = errors.Errorf("backend config attribute %q: unsupported array type %T", name, values)
+		}
+		return c
+	}
+	elementStr := utils.DTypeToStableHLO(dtypes.FromGoType(rValues.Type().Elem()))
+	var sb strings.Builder
+	sb.WriteString("array<")
+	sb.WriteString(elementStr)
+	for i := range rValues.Len() {
+		if i == 0 {
+			sb.WriteString(": ")
+		} else {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(podToStableHLO(rValues.Index(i).Interface()))
+	}
+	sb.WriteString(">")
+	return c.set(name, sb.String())
+}
+
+// Raw sets an attribute with a value already rendered in StableHLO format, for attribute types not covered
+// by the other methods.
+func (c *BackendConfig) Raw(name, value string) *BackendConfig {
+	return c.set(name, value)
+}
+
+// ToStableHLO returns the dictionary of attributes in StableHLO format, e.g. `{alpha = 0.5 : f32, n = 3 : i32}`.
+func (c *BackendConfig) ToStableHLO() string {
+	names := slices.Sorted(maps.Keys(c.attributes))
+	parts := make([]string, len(names))
+	for i, name := range names {
+		parts[i] = fmt.Sprintf("%s = %s", name, c.attributes[name])
+	}
+	return "{" + strings.Join(parts, ", ") + "}"
+}
@@ -0,0 +1,189 @@
+package stablehlo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestBackendConfig(t *testing.T) {
+	nested := NewBackendConfig().String("mode", "fast").Bool("enabled", true)
+	config := NewBackendConfig().
+		Int32("n", 3).
+		Int64("big", 1<<40).
+		Float32("alpha", 0.5).
+		Float64("beta", 1e-8).
+		Scalar("flags", uint8(7)).
+		DType("dtype", dtypes.BFloat16).
+		Int64s("axes", 0, 1).
+		Float32s("weights", 1, 2.5).
+		Array("mask", []bool{true, false}).
+		Int32s("empty").
+		Dict("options", nested)
+	if config.err != nil {
+		t.Fatalf("unexpected error: %+v", config.err)
+	}
+	want := `{alpha = 0.5 : f32, axes = array<i64: 0, 1>, beta = 1.0e-08 : f64, big = 1099511627776 : i64, ` +
+		`dtype = bf16, empty = array<i32>, flags = 7 : ui8, mask = array<i1: true, false>, n = 3 : i32, ` +
+		`options = {enabled = true, mode = "fast"}, weights = array<f32: 1.0, 2.5>}`
+	if got := config.ToStableHLO(); got != want {
+		t.Errorf("BackendConfig.ToStableHLO():\nwant %s\n got %s", want, got)
+	}
+
+	// Errors.
+	if NewBackendConfig().Array("x", []string{"a"}).err == nil {
+		t.Errorf("expected error for an array of strings")
+	}
+	if NewBackendConfig().Array("x", 1).err == nil {
+		t.Errorf("expected error for an array that is not a slice")
+	}
+	if NewBackendConfig().Scalar("x", "a").err == nil {
+		t.Errorf("expected error for a string passed as scalar")
+	}
+	if NewBackendConfig().Int32("not valid", 1).err == nil {
+		t.Errorf("expected error for invalid attribute name")
+	}
+	if NewBackendConfig().Dict("x", NewBackendConfig().Scalar("y", "a")).err == nil {
+		t.Errorf("expected error from nested dictionary")
+	}
+	if NewBackendConfig().Dict("x", nil).err == nil {
+		t.Errorf("expected error for a nil nested dictionary")
+	}
+}
+
+func TestCustomCall(t *testing.T) {
+	t.Run("TypedFFI", func(t *testing.T) {
+		b := New(t.Name())
+		fn := b.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		outputs, err := fn.CustomCall("my_handler", []shapes.Shape{x.Shape(), shapes.Make(dtypes.Int32)}, x).
+			BackendConfig(NewBackendConfig().Float32("alpha", 2)).
+			HasSideEffect().
+			Done()
+		if err != nil {
+			t.Fatalf("CustomCall failed: %+v", err)
+		}
+		if len(outputs) != 2 {
+			t.Fatalf("expected 2 outputs, got %d", len(outputs))
+		}
+		if err := fn.Return(outputs...); err != nil {
+			t.Fatalf("Return failed: %+v", err)
+		}
+		program := string(must(b.Build()))
+		want := `%0, %1 = "stablehlo.custom_call"(%x) {
+      api_version = 4 : i32,
+      backend_config = {alpha = 2.0 : f32},
+      call_target_name = "my_handler",
+      has_side_effect = true
+    } : (tensor<3xf32>) -> (tensor<3xf32>, tensor<i32>)`
+		if !strings.Contains(program, want) {
+			t.Errorf("program should contain:\n%s\ngot:\n%s", want, program)
+		}
+	})
+
+	t.Run("Legacy", func(t *testing.T) {
+		b := New(t.Name())
+		fn := b.Main()
+		outputs := must(fn.CustomCall("legacy", []shapes.Shape{shapes.Make(dtypes.Float32)}).
+			BackendConfigString("opaque").
+			APIVersion(CustomCallAPIVersionStatusReturning).
+			Done())
+		if err := fn.Return(outputs...); err != nil {
+			t.Fatalf("Return failed: %+v", err)
+		}
+		program := string(must(b.Build()))
+		want := `"stablehlo.custom_call"() {
+      api_version = 2 : i32,
+      backend_config = "opaque",
+      call_target_name = "legacy"
+    } : () -> tensor<f32>`
+		if !strings.Contains(program, want) {
+			t.Errorf("program should contain:\n%s\ngot:\n%s", want, program)
+		}
+	})
+
+	t.Run("OutputOperandAliases", func(t *testing.T) {
+		b := New(t.Name())
+		fn := b.Main()
+		params := must(fn.NamedInput("params", shapes.Make(dtypes.Float32, 3)))
+		grads := must(fn.NamedInput("grads", shapes.Make(dtypes.Float32, 3)))
+		state := must(fn.NamedInput("state", shapes.Make(dtypes.Float32, 3)))
+		outputs := must(fn.CustomCall("fused_sgd", []shapes.Shape{params.Shape(), state.Shape()}, params, grads, state).
+			AliasOutputToOperand(1, 2).
+			AliasOutputToOperand(0, 0).
+			Done())
+		must0(fn.Return(outputs...))
+		program := string(must(b.Build()))
+		want := `%0, %1 = "stablehlo.custom_call"(%params, %grads, %state) {
+      call_target_name = "fused_sgd",
+      output_operand_aliases = [` +
+			`#stablehlo.output_operand_alias<output_tuple_indices = [0], operand_index = 0, operand_tuple_indices = []>, ` +
+			`#stablehlo.output_operand_alias<output_tuple_indices = [1], operand_index = 2, operand_tuple_indices = []>]
+    } : (tensor<3xf32>, tensor<3xf32>, tensor<3xf32>) -> (tensor<3xf32>, tensor<3xf32>)`
+		if !strings.Contains(program, want) {
+			t.Errorf("program should contain:\n%s\ngot:\n%s", want, program)
+		}
+
+		// Single output: the output is not a tuple.
+		b = New(t.Name())
+		fn = b.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		outputs = must(fn.CustomCall("inplace", []shapes.Shape{x.Shape()}, x).AliasOutputToOperand(0, 0).Done())
+		must0(fn.Return(outputs...))
+		program = string(must(b.Build()))
+		want = `output_operand_aliases = [` +
+			`#stablehlo.output_operand_alias<output_tuple_indices = [], operand_index = 0, operand_tuple_indices = []>]`
+		if !strings.Contains(program, want) {
+			t.Errorf("program should contain:\n%s\ngot:\n%s", want, program)
+		}
+
+		// Errors.
+		fn = New(t.Name()).Main()
+		x = must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		y := must(fn.NamedInput("y", shapes.Make(dtypes.Int32, 3)))
+		outShapes := []shapes.Shape{x.Shape(), x.Shape()}
+		for name, builder := range map[string]*CustomCallBuilder{
+			"output out of range":    fn.CustomCall("f", outShapes, x, y).AliasOutputToOperand(2, 0),
+			"operand out of range":   fn.CustomCall("f", outShapes, x, y).AliasOutputToOperand(0, 2),
+			"dtype mismatch":         fn.CustomCall("f", outShapes, x, y).AliasOutputToOperand(0, 1),
+			"output aliased twice":   fn.CustomCall("f", outShapes, x, y).AliasOutputToOperand(0, 0).AliasOutputToOperand(0, 0),
+			"operand aliased twice":  fn.CustomCall("f", outShapes, x, y).AliasOutputToOperand(0, 0).AliasOutputToOperand(1, 0),
+			"negative operand index": fn.CustomCall("f", outShapes, x, y).AliasOutputToOperand(0, -2),
+		} {
+			if _, err := builder.Done(); err == nil {
+				t.Errorf("expected error for %s", name)
+			}
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		fn := New(t.Name()).Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		outShapes := []shapes.Shape{x.Shape()}
+		if _, err := fn.CustomCall("", outShapes, x).Done(); err == nil {
+			t.Errorf("expected error for empty call target name")
+		}
+		if _, err := fn.CustomCall("f", []shapes.Shape{shapes.Invalid()}, x).Done(); err == nil {
+			t.Errorf("expected error for invalid output shape")
+		}
+		if _, err := fn.CustomCall("f", outShapes, x).
+			BackendConfig(NewBackendConfig()).BackendConfigString("x").Done(); err == nil {
+			t.Errorf("expected error for both typed and string backend configs")
+		}
+		if _, err := fn.CustomCall("f", outShapes, x).
+			BackendConfig(NewBackendConfig()).APIVersion(CustomCallAPIVersionOriginal).Done(); err == nil {
+			t.Errorf("expected error for typed backend config with legacy API version")
+		}
+		if _, err := fn.CustomCall("f", outShapes, x).
+			BackendConfig(NewBackendConfig().Array("x", []string{"a"})).Done(); err == nil {
+			t.Errorf("expected error for invalid backend config")
+		}
+		other := New(t.Name()).Main()
+		y := must(other.NamedInput("y", shapes.Make(dtypes.Float32, 3)))
+		if _, err := fn.CustomCall("f", outShapes, y).Done(); err == nil {
+			t.Errorf("expected error for input from another function")
+		}
+	})
+}
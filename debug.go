@@ -0,0 +1,44 @@
+package stablehlo
+
+import (
+	"github.com/pkg/errors"
+)
+
+// debugOutput is a value registered with DebugPrint.
+type debugOutput struct {
+	tag   string
+	value *Value
+}
+
+// DebugPrint registers the value to be printed, with the given tag, when the program is executed.
+// It returns the value itself, so it can be used inline: x, err = DebugPrint(x, "x").
+//
+// The PJRT C API doesn't expose outfeed or host callbacks, so instead the values registered are appended as
+// extra outputs of the function (after the values given to Function.Return), and the github.com/gomlx/stablehlo/exec
+// package strips them from the outputs and prints them after each execution.
+// If executing the program directly with PJRT, the extra outputs are listed by Function.DebugTags.
+//
+// It can only be used in the main function (or other top-level functions), not in closures,
+// and it must be called before Function.Return.
+func DebugPrint(value *Value, tag string) (output *Value, err error) {
+	fn := value.fn
+	defer fn.opErrorHandler(&err, &output)()
+	if fn.Returned {
+		return nil, errors.Errorf("cannot DebugPrint(%q) after returning, in function %q", tag, fn.Name)
+	}
+	if fn.Parent != nil {
+		return nil, errors.Errorf("DebugPrint(%q) is not supported in closures, but used in function %q", tag, fn.Name)
+	}
+	fn.debugOutputs = append(fn.debugOutputs, debugOutput{tag: tag, value: value})
+	return value, nil
+}
+
+// DebugTags returns the tags of the values registered with DebugPrint, in the order they are appended to
+// the outputs of the function.
+func (fn *Function) DebugTags() []string {
+	tags := make([]string, len(fn.debugOutputs))
+	for i, debug := range fn.debugOutputs {
+		tags[i] = debug.tag
+	}
+	return tags
+}
@@ -0,0 +1,39 @@
+package stablehlo
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestDebugPrint(t *testing.T) {
+	builder := New(t.Name())
+	fn := builder.Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+	y := must(DebugPrint(must(Exponential(x)), "exp(x)"))
+	y = must(Add(y, x))
+	if err := fn.Return(y); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(fn.Outputs) != 2 {
+		t.Fatalf("expected 2 outputs (1 debug), got %d", len(fn.Outputs))
+	}
+	if want := []string{"exp(x)"}; !slices.Equal(fn.DebugTags(), want) {
+		t.Errorf("expected debug tags %v, got %v", want, fn.DebugTags())
+	}
+	program := string(must(builder.Build()))
+	fmt.Printf("%s program:\n%s", t.Name(), program)
+	if !strings.Contains(program, `"stablehlo.return"(%1, %0)`) {
+		t.Errorf("expected exp(x) to be returned as an extra output")
+	}
+
+	closure := fn.Closure()
+	c := must(closure.NamedInput("c", shapes.Make(dtypes.Float32)))
+	if _, err := DebugPrint(c, "c"); err == nil {
+		t.Error("expected error using DebugPrint in a closure, got nil")
+	}
+}
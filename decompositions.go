@@ -0,0 +1,123 @@
+package stablehlo
+
+import (
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/pkg/errors"
+)
+
+// The decompositions of the unary operations, used when the target backend doesn't support them natively (see
+// TargetFeatures), are the functions named "decompose<OpName>" below: ./internal/cmd/ops_generator registers them
+// in gen_decompositions.go.
+
+// constantsLike returns the values as constants with the shape of x, which must have a float dtype.
+func constantsLike(op optypes.OpType, x *Value, values ...float64) ([]*Value, error) {
+	if !x.shape.DType.IsFloat() {
+		return nil, errors.Errorf("decomposition of %s only supports float dtypes, got %s", op, x.shape)
+	}
+	constants := make([]*Value, len(values))
+	for i, value := range values {
+		var err error
+		constants[i], err = x.fn.broadcastScalar(value, x.shape)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return constants, nil
+}
+
+// decomposeCbrt returns sign(x) * |x|^(1/3).
+func decomposeCbrt(x *Value) (*Value, error) {
+	c, err := constantsLike(optypes.Cbrt, x, 1.0/3.0)
+	if err != nil {
+		return nil, err
+	}
+	sign, err := Sign(x)
+	if err != nil {
+		return nil, err
+	}
+	return x.fn.Expr(x).Abs().Power(c[0]).Mul(sign).Value()
+}
+
+// decomposeErf uses the approximation 7.1.26 from Abramowitz and Stegun (maximum error of 1.5e-7):
+//
+//	erf(x) = sign(x) * (1 - (a1*t + a2*t^2 + a3*t^3 + a4*t^4 + a5*t^5) * exp(-x^2)), with t = 1 / (1 + p*|x|)
+func decomposeErf(x *Value) (*Value, error) {
+	c, err := constantsLike(optypes.Erf, x,
+		1, 0.3275911, 0.254829592, -0.284496736, 1.421413741, -1.453152027, 1.061405429)
+	if err != nil {
+		return nil, err
+	}
+	one, p, a := c[0], c[1], c[2:]
+	fn := x.fn
+	t, err := fn.Expr(x).Abs().Mul(p).Add(one).Apply(func(v *Value) (*Value, error) { return Divide(one, v) }).Value()
+	if err != nil {
+		return nil, err
+	}
+	// Horner's method: poly = t * (a1 + t * (a2 + t * (a3 + t * (a4 + t * a5)))).
+	poly := fn.Expr(a[4])
+	for i := 3; i >= 0; i-- {
+		poly = poly.Mul(t).Add(a[i])
+	}
+	expNegX2, err := fn.Expr(x).Mul(x).Negate().Exponential().Value()
+	if err != nil {
+		return nil, err
+	}
+	sign, err := Sign(x)
+	if err != nil {
+		return nil, err
+	}
+	return poly.Mul(t).Mul(expNegX2).Apply(func(v *Value) (*Value, error) { return Subtract(one, v) }).Mul(sign).Value()
+}
+
+// decomposeExponentialMinusOne returns exp(x) - 1, less accurate than the native operation for x close to 0.
+func decomposeExponentialMinusOne(x *Value) (*Value, error) {
+	c, err := constantsLike(optypes.ExponentialMinusOne, x, 1)
+	if err != nil {
+		return nil, err
+	}
+	return x.fn.Expr(x).Exponential().Sub(c[0]).Value()
+}
+
+// decomposeLogPlusOne returns log(1 + x), less accurate than the native operation for x close to 0.
+func decomposeLogPlusOne(x *Value) (*Value, error) {
+	c, err := constantsLike(optypes.LogPlusOne, x, 1)
+	if err != nil {
+		return nil, err
+	}
+	return x.fn.Expr(x).Add(c[0]).Log().Value()
+}
+
+// decomposeLogistic returns 1 / (1 + exp(-x)).
+func decomposeLogistic(x *Value) (*Value, error) {
+	c, err := constantsLike(optypes.Logistic, x, 1)
+	if err != nil {
+		return nil, err
+	}
+	one := c[0]
+	return x.fn.Expr(x).Negate().Exponential().Add(one).Apply(func(v *Value) (*Value, error) { return Divide(one, v) }).Value()
+}
+
+// decomposeRoundNearestAfz returns sign(x) * floor(|x| + 0.5), that is, rounding half away from zero.
+func decomposeRoundNearestAfz(x *Value) (*Value, error) {
+	c, err := constantsLike(optypes.RoundNearestAfz, x, 0.5)
+	if err != nil {
+		return nil, err
+	}
+	sign, err := Sign(x)
+	if err != nil {
+		return nil, err
+	}
+	return x.fn.Expr(x).Abs().Add(c[0]).Floor().Mul(sign).Value()
+}
+
+// decomposeTan returns sin(x) / cos(x).
+func decomposeTan(x *Value) (*Value, error) {
+	if _, err := constantsLike(optypes.Tan, x); err != nil {
+		return nil, err
+	}
+	cos, err := Cosine(x)
+	if err != nil {
+		return nil, err
+	}
+	return x.fn.Expr(x).Sine().Div(cos).Value()
+}
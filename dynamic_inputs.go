@@ -0,0 +1,62 @@
+package stablehlo
+
+import (
+	"maps"
+
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// IsDynamicAttribute is the input attribute that marks inputs with dynamic axes (shapes.DynamicDim).
+// It is set automatically (to true) for inputs with dynamic shapes, and it must not be set to true for
+// static inputs.
+const IsDynamicAttribute = "mhlo.is_dynamic"
+
+// validateInputShape checks the dynamic axes and bounds of an input shape, and the consistency of the
+// IsDynamicAttribute attribute, if set. It returns a copy of the attributes (so the caller's map is not modified),
+// with IsDynamicAttribute set for dynamic shapes.
+func validateInputShape(shape shapes.Shape, attributes map[string]any) (map[string]any, error) {
+	if !shape.Ok() {
+		return nil, errors.Errorf("invalid input shape %s", shape)
+	}
+	if err := shape.CheckSize(); err != nil {
+		return nil, errors.WithMessage(err, "invalid input shape")
+	}
+	if len(shape.Bounds) > 0 {
+		if len(shape.Bounds) != shape.Rank() {
+			return nil, errors.Errorf("input shape %s has %d bounds, but it must have one per axis (%d), see Shape.WithBounds",
+				shape, len(shape.Bounds), shape.Rank())
+		}
+		for axis, bound := range shape.Bounds {
+			if bound == shapes.DynamicDim {
+				continue
+			}
+			if shape.Dimensions[axis] != shapes.DynamicDim {
+				return nil, errors.Errorf("input shape %s has a bound (%d) for the static axis %d", shape, bound, axis)
+			}
+			if bound <= 0 {
+				return nil, errors.Errorf("input shape %s has an invalid bound (%d) for axis %d, it must be positive",
+					shape, bound, axis)
+			}
+		}
+	}
+	isDynamic := shape.IsDynamic()
+	if value, found := attributes[IsDynamicAttribute]; found {
+		flag, ok := value.(bool)
+		if !ok {
+			return nil, errors.Errorf("input attribute %q must be a bool, got %T", IsDynamicAttribute, value)
+		}
+		if flag != isDynamic {
+			return nil, errors.Errorf("input attribute %q is set to %v, but the input shape %s is %s",
+				IsDynamicAttribute, flag, shape, map[bool]string{true: "dynamic", false: "static"}[isDynamic])
+		}
+	}
+	attributes = maps.Clone(attributes)
+	if isDynamic {
+		if attributes == nil {
+			attributes = make(map[string]any)
+		}
+		attributes[IsDynamicAttribute] = true
+	}
+	return attributes, nil
+}
@@ -0,0 +1,79 @@
+package stablehlo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestDynamicInputs(t *testing.T) {
+	t.Run("attribute emitted", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		shape := shapes.Make(dtypes.Float32, shapes.DynamicDim, 3).WithBounds(8, shapes.DynamicDim)
+		x := must(fn.NamedInput("x", shape))
+		static := must(fn.NamedInput("static", shapes.Make(dtypes.Float32, 3)))
+		if err := fn.Return(x, static); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `%x: tensor<?x3xf32, #stablehlo.bounds<8, ?>> { mhlo.is_dynamic = true }, %static: tensor<3xf32>)`
+		if !strings.Contains(program, want) {
+			t.Errorf("expected program to contain %q", want)
+		}
+	})
+
+	t.Run("bounds carried", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, shapes.DynamicDim, 3).WithBounds(8, shapes.DynamicDim)))
+		transposed := must(Transpose(x, 1, 0))
+		sliced := must(DynamicSlice(x, []*Value{must(fn.ConstantFromScalar(int32(0))), must(fn.ConstantFromScalar(int32(0)))}, []int{2, 3}))
+		must0(fn.Return(transposed, sliced))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `-> (tensor<3x?xf32, #stablehlo.bounds<?, 8>>, tensor<2x3xf32>)`
+		if !strings.Contains(program, want) {
+			t.Errorf("expected program to contain %q", want)
+		}
+	})
+
+	t.Run("caller attributes not modified", func(t *testing.T) {
+		fn := New(t.Name()).Main()
+		attributes := map[string]any{"mhlo.layout_mode": literalStr(`"default"`)}
+		shape := shapes.Make(dtypes.Float32, shapes.DynamicDim).WithBounds(8)
+		x := must(fn.NamedInputWithAttributes("x", shape, attributes))
+		if len(attributes) != 1 {
+			t.Errorf("expected the caller's attributes to be left unchanged, got %v", attributes)
+		}
+		if x.Attributes[IsDynamicAttribute] != true {
+			t.Errorf("expected the input to have the attribute %q set, got %v", IsDynamicAttribute, x.Attributes)
+		}
+	})
+
+	t.Run("invalid", func(t *testing.T) {
+		fn := New(t.Name()).Main()
+		badBounds := shapes.Make(dtypes.Float32, 4, shapes.DynamicDim)
+		badBounds.Bounds = []int{8, shapes.DynamicDim}
+		if _, err := fn.NamedInput("bad_bounds", badBounds); err == nil {
+			t.Error("expected error for bound on a static axis, got nil")
+		}
+		badRank := shapes.Make(dtypes.Float32, shapes.DynamicDim, 3)
+		badRank.Bounds = []int{8}
+		if _, err := fn.NamedInput("bad_rank", badRank); err == nil {
+			t.Error("expected error for bounds with the wrong rank, got nil")
+		}
+		if _, err := fn.NamedInputWithAttributes("static", shapes.Make(dtypes.Float32, 3),
+			map[string]any{IsDynamicAttribute: true}); err == nil {
+			t.Errorf("expected error for %s set on a static input, got nil", IsDynamicAttribute)
+		}
+		if _, err := fn.NamedInputWithAttributes("dynamic", shapes.Make(dtypes.Float32, shapes.DynamicDim),
+			map[string]any{IsDynamicAttribute: false}); err == nil {
+			t.Errorf("expected error for %s set to false on a dynamic input, got nil", IsDynamicAttribute)
+		}
+	})
+}
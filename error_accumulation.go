@@ -0,0 +1,132 @@
+package stablehlo
+
+import (
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+// WithErrorAccumulation enables the error-accumulating mode for the function (and its closures): instead of
+// returning an error, the op constructors record the first error in the function and return a "poisoned" *Value
+// (see Value.IsPoisoned) and a nil error.
+//
+// Once an error is recorded, any further operation in the function (or its closures) is ignored, and it
+// returns poisoned values as well.
+// The first error is returned by Function.Err, Function.Return and Builder.Build, so it only needs to be
+// checked once at the end.
+//
+// Errors that cannot be associated with a function -- e.g., calling Concatenate without any operands -- are
+// still returned directly.
+//
+// Example:
+//
+//	fn := b.Main().WithErrorAccumulation()
+//	x, _ := fn.Input(shape)
+//	y, _ := Tanh(x)
+//	z, _ := Add(x, y)
+//	if err := fn.Return(z); err != nil { ... }
+//
+// It returns the function itself, so it can be chained.
+func (fn *Function) WithErrorAccumulation() *Function {
+	fn.findRootFn().accumulateErrors = true
+	return fn
+}
+
+// Err returns the first error recorded in the function (or any of its closures), when in error-accumulating mode.
+//
+// It always returns nil if the function is not in error-accumulating mode. See Function.WithErrorAccumulation.
+func (fn *Function) Err() error {
+	return fn.findRootFn().err
+}
+
+// newPoisonedValue returns a poisoned value, returned by operations in error-accumulating mode after an error.
+func (fn *Function) newPoisonedValue() *Value {
+	return &Value{
+		fn:       fn,
+		name:     "poisoned",
+		shape:    shapes.Invalid(),
+		poisoned: true,
+	}
+}
+
+// poisonedValuePanic is the value of the panic raised when a statement is created with a poisoned value as input,
+// see Statement.registerUses. It's recovered by the op error handlers, since the function is already invalid.
+type poisonedValuePanic struct{}
+
+// panicIfPoisoned panics with poisonedValuePanic if the value is poisoned.
+func (v *Value) panicIfPoisoned() {
+	if v != nil && v.poisoned {
+		panic(poisonedValuePanic{})
+	}
+}
+
+// recoverPoisonedValuePanic recovers a poisonedValuePanic, and re-panics any other panic: those are bugs.
+// It must be called directly by a deferred function.
+func recoverPoisonedValuePanic(r any) {
+	if _, ok := r.(poisonedValuePanic); r != nil && !ok {
+		panic(r)
+	}
+}
+
+// opErrorHandler should be deferred at the start of op constructors with pointers to their named results.
+// It's a no-op if the function is not in error-accumulating mode.
+//
+// It returns a function that, at the end of the op construction, records the error in the function and replaces
+// the outputs by poisoned values. If the function already had an error when the op construction started,
+// the op is ignored: the statements it may have created are dropped, and poisoned values are returned.
+//
+// Usage:
+//
+//	defer fn.opErrorHandler(&err, &output)()
+func (fn *Function) opErrorHandler(err *error, outputs ...**Value) func() {
+	rootFn := fn.findRootFn()
+	if !rootFn.accumulateErrors {
+		return func() {}
+	}
+	hadError := rootFn.err != nil
+	numStatements := len(fn.Statements)
+	return func() {
+		if hadError {
+			// Operating on poisoned values is ignored: the function is already invalid.
+			recoverPoisonedValuePanic(recover())
+			fn.truncateStatements(numStatements)
+		} else if *err != nil {
+			rootFn.err = *err
+		} else {
+			return
+		}
+		*err = nil
+		for _, output := range outputs {
+			*output = fn.newPoisonedValue()
+		}
+	}
+}
+
+// multiOpErrorHandler is like opErrorHandler, but for ops that return a slice of values.
+// On error, outputs is set to numOutputs poisoned values.
+//
+// Usage:
+//
+//	defer fn.multiOpErrorHandler(&err, &outputs, len(inputs))()
+func (fn *Function) multiOpErrorHandler(err *error, outputs *[]*Value, numOutputs int) func() {
+	rootFn := fn.findRootFn()
+	if !rootFn.accumulateErrors {
+		return func() {}
+	}
+	hadError := rootFn.err != nil
+	numStatements := len(fn.Statements)
+	return func() {
+		if hadError {
+			// Operating on poisoned values is ignored: the function is already invalid.
+			recoverPoisonedValuePanic(recover())
+			fn.truncateStatements(numStatements)
+		} else if *err != nil {
+			rootFn.err = *err
+		} else {
+			return
+		}
+		*err = nil
+		*outputs = make([]*Value, numOutputs)
+		for i := range *outputs {
+			(*outputs)[i] = fn.newPoisonedValue()
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package stablehlo
+
+import (
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestErrorAccumulation(t *testing.T) {
+	t.Run("no errors", func(t *testing.T) {
+		b := New(t.Name())
+		fn := b.Main().WithErrorAccumulation()
+		x, _ := fn.NamedInput("x", shapes.Make(dtypes.Float32, 3))
+		y, _ := Tanh(x)
+		z, _ := Add(x, y)
+		if err := fn.Return(z); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, err := b.Build(); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+	})
+
+	t.Run("first error", func(t *testing.T) {
+		b := New(t.Name())
+		fn := b.Main().WithErrorAccumulation()
+		x, _ := fn.NamedInput("x", shapes.Make(dtypes.Float32, 3))
+		y, _ := fn.NamedInput("y", shapes.Make(dtypes.Float32, 4))
+		sum, err := Add(x, y)
+		if err != nil {
+			t.Fatalf("expected error to be accumulated, got %v", err)
+		}
+		if !sum.IsPoisoned() {
+			t.Fatalf("expected poisoned value, got %s", sum)
+		}
+		firstErr := fn.Err()
+		if firstErr == nil {
+			t.Fatal("expected an error to be recorded, got nil")
+		}
+		numStatements := len(fn.Statements)
+
+		// Operations on poisoned values are ignored.
+		sliced, err := Slice(sum, []int{0}, []int{2}, []int{1})
+		if err != nil || !sliced.IsPoisoned() {
+			t.Fatalf("expected poisoned value and no error, got %v, %v", sliced, err)
+		}
+		// Operations after an error are ignored, even if valid.
+		neg, err := Negate(x)
+		if err != nil || !neg.IsPoisoned() {
+			t.Fatalf("expected poisoned value and no error, got %v, %v", neg, err)
+		}
+		if len(fn.Statements) != numStatements {
+			t.Errorf("expected no new statements after an error, got %d new ones", len(fn.Statements)-numStatements)
+		}
+		if fn.Err() != firstErr {
+			t.Errorf("expected first error %v to be kept, got %v", firstErr, fn.Err())
+		}
+		if err := fn.Return(neg); err != firstErr {
+			t.Errorf("expected Return to return the first error %v, got %v", firstErr, err)
+		}
+		if _, err := b.Build(); err == nil {
+			t.Error("expected Build to fail, got nil error")
+		}
+	})
+
+	t.Run("closure", func(t *testing.T) {
+		b := New(t.Name())
+		fn := b.Main().WithErrorAccumulation()
+		x, _ := fn.NamedInput("x", shapes.Make(dtypes.Float32, 3))
+		closure := fn.Closure()
+		lhs, _ := closure.Input(shapes.Make(dtypes.Float32))
+		rhs, _ := closure.Input(shapes.Make(dtypes.Int32))
+		_, _ = Add(lhs, rhs)
+		if fn.Err() == nil {
+			t.Fatal("expected error in closure to be recorded in the parent function, got nil")
+		}
+		if err := fn.Return(x); err == nil {
+			t.Error("expected Return to return the closure error, got nil")
+		}
+	})
+	t.Run("bugs are not recovered", func(t *testing.T) {
+		fn := New(t.Name()).Main().WithErrorAccumulation()
+		x, _ := fn.NamedInput("x", shapes.Make(dtypes.Float32, 3))
+		y, _ := fn.NamedInput("y", shapes.Make(dtypes.Float32, 4))
+		_, _ = Add(x, y)
+		if fn.Err() == nil {
+			t.Fatal("expected an error to be recorded, got nil")
+		}
+		defer func() {
+			if r := recover(); r != "bug" {
+				t.Errorf("expected the panic %q to be re-raised, got %v", "bug", r)
+			}
+		}()
+		_, _ = fn.ConvertGraph([]GraphOutput{{Node: &panickingGraphNode{}}}, nil)
+		t.Error("expected ConvertGraph to panic")
+	})
+}
+
+// panickingGraphNode is a GraphNode with a bug: it panics when its inputs are requested.
+type panickingGraphNode struct{ testGraphNode }
+
+func (n *panickingGraphNode) Inputs() []GraphOutput { panic("bug") }
@@ -0,0 +1,345 @@
+// Package exec provides a small helper to compile and execute the StableHLO programs built with
+// github.com/gomlx/stablehlo using PJRT (github.com/gomlx/gopjrt), including feeding the constants
+// converted to inputs (see stablehlo.Function.ConstantInputs) -- e.g., model weights loaded from files --, and
+// printing the values registered with stablehlo.DebugPrint.
+package exec
+
+import (
+	"cmp"
+	"fmt"
+	"io"
+	"os"
+	"slices"
+
+	"github.com/gomlx/gopjrt/pjrt"
+	"github.com/gomlx/stablehlo"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// Executable is a compiled StableHLO program, with the buffers of its constant inputs.
+//
+// Constant inputs created with values (stablehlo.Function.ConstantAsInput, or large constants
+// converted with stablehlo.Builder.WithLargeConstantsAsInputs) are uploaded on Compile.
+// External constants (stablehlo.Function.ExternalConstant) must be bound with BindWeights before
+// calling Execute.
+type Executable struct {
+	client *pjrt.Client
+	loaded *pjrt.LoadedExecutable
+
+	// name of the main function, numInputs its number of inputs (including the constant inputs) and
+	// numDevices the number of devices the program is executed on.
+	name                  string
+	numInputs, numDevices int
+
+	// constantInputs and debugTags of the main function, see stablehlo.Function.ConstantInputs and
+	// stablehlo.Function.DebugTags.
+	constantInputs []stablehlo.ConstantInput
+	debugTags      []string
+
+	// inputShapes of the non-constant inputs of the main function, see UploadInput. Only known for executables
+	// created with Compile.
+	inputShapes []shapes.Shape
+
+	// constants holds the buffers of the constant inputs, indexed by their input index.
+	constants map[int]*pjrt.Buffer
+
+	// debugWriter is where the values of stablehlo.DebugPrint are printed.
+	debugWriter io.Writer
+}
+
+// Compile builds the program of the given main function and compiles it with the client.
+//
+// It also uploads the values of the constant inputs of main that have them.
+//
+// Programs configured with multiple replicas or partitions (see stablehlo.Builder.WithNumReplicas and
+// stablehlo.Builder.WithShardy) are compiled to be executed on those devices. See CompileWithOptions to configure
+// the compilation.
+func Compile(client *pjrt.Client, main *stablehlo.Function) (*Executable, error) {
+	return CompileWithOptions(client, main, &CompileOptions{})
+}
+
+// CompileWithOptions is like Compile, but it configures the compilation with the given options: the number of
+// replicas and partitions, the device assignment and XLA debug options (e.g. CompileOptions.DisableFusion).
+//
+// Programs with constant inputs (see stablehlo.Function.ConstantInputs) or DebugPrint values can only be executed
+// on one device.
+//
+// The XLA debug options are passed to PJRT by temporarily setting the pjrt.EnvXlaDebugOptions environment variable
+// of the process (merged into its current value), with os.Setenv. So when they are used, CompileWithOptions is not
+// safe to run concurrently with other PJRT compilations (including Compile of other clients) or with other goroutines
+// reading the environment.
+func CompileWithOptions(client *pjrt.Client, main *stablehlo.Function, options *CompileOptions) (*Executable, error) {
+	if err := options.Validate(); err != nil {
+		return nil, err
+	}
+	builder := main.Builder
+	numReplicas := max(cmp.Or(options.NumReplicas, builder.NumReplicas()), 1)
+	numPartitions := max(cmp.Or(options.NumPartitions, builder.NumPartitions()), 1)
+	useShardy := len(builder.Meshes()) > 0
+	if useShardy && numReplicas > 1 {
+		return nil, errors.Errorf("program %q uses Shardy meshes with %d replicas, but Shardy requires a single replica",
+			main.Name, numReplicas)
+	}
+	if !useShardy && numPartitions > 1 {
+		return nil, errors.Errorf("program %q has %d partitions but no Shardy meshes: only data parallelism "+
+			"(replicas) is supported without Shardy", main.Name, numPartitions)
+	}
+	numDevices := numReplicas * numPartitions
+	if len(options.DeviceAssignment) > 0 && len(options.DeviceAssignment) != numDevices {
+		return nil, errors.Errorf("program %q has %d devices assigned, but it requires %d (replicas x partitions)",
+			main.Name, len(options.DeviceAssignment), numDevices)
+	}
+	if numDevices > 1 && len(main.ConstantInputs()) > 0 {
+		return nil, errors.Errorf("program %q has constant inputs, which are not supported when executing on %d devices",
+			main.Name, numDevices)
+	}
+	if numDevices > 1 && len(main.DebugTags()) > 0 {
+		return nil, errors.Errorf("program %q has DebugPrint values, which are not supported when executing on %d devices",
+			main.Name, numDevices)
+	}
+
+	program, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	compileConfig, err := newCompileConfig(client, program, options)
+	if err != nil {
+		return nil, err
+	}
+	if useShardy {
+		compileConfig = compileConfig.WithShardy(numDevices)
+	} else if numDevices > 1 {
+		compileConfig = compileConfig.WithSPMD(numDevices)
+	}
+	if len(options.DeviceAssignment) > 0 {
+		compileConfig = compileConfig.WithDeviceAssignment(options.DeviceAssignment)
+	}
+	loaded, err := compileConfig.Done()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to compile program")
+	}
+	e := &Executable{
+		client:         client,
+		loaded:         loaded,
+		name:           main.Name,
+		numInputs:      len(main.Inputs),
+		numDevices:     numDevices,
+		constantInputs: main.ConstantInputs(),
+		debugTags:      main.DebugTags(),
+		inputShapes:    make([]shapes.Shape, 0, len(main.Inputs)),
+		constants:      make(map[int]*pjrt.Buffer),
+		debugWriter:    os.Stderr,
+	}
+	for idx, input := range main.Inputs {
+		if !slices.ContainsFunc(e.constantInputs, func(c stablehlo.ConstantInput) bool { return c.InputIndex == idx }) {
+			e.inputShapes = append(e.inputShapes, input.Shape())
+		}
+	}
+	for _, constant := range e.constantInputs {
+		if constant.Flat == nil {
+			continue
+		}
+		buffer, err := client.BufferFromHost().FromFlatDataWithDimensions(constant.Flat, constant.Shape.Dimensions).Done()
+		if err != nil {
+			_ = e.Destroy()
+			return nil, errors.WithMessagef(err, "failed to upload constant input %q", constant.Name)
+		}
+		e.constants[constant.InputIndex] = buffer
+	}
+	return e, nil
+}
+
+// BindWeights uploads the weights matching (by name) the external constant inputs of the program.
+//
+// Weights are matched by the name given to stablehlo.Function.ExternalConstant (see
+// stablehlo.ConstantInput.OriginalName), or else by the name converted with stablehlo.ConvertToValidName -- in
+// which case it is an error if more than one weight converts to the same name.
+//
+// It returns an error if a weight doesn't match the shape of the corresponding constant. Weights that don't
+// correspond to any external constant are ignored, and external constants not found in weights are left
+// unbound -- they can be bound by a later call, for instance from another file. Use UnboundConstants to list
+// them, or BindAllWeights to bind all of them at once.
+func (e *Executable) BindWeights(weights Weights) error {
+	matched, err := matchWeights(e.constantInputs, weights)
+	if err != nil {
+		return err
+	}
+	for _, constant := range e.constantInputs {
+		tensor, found := matched[constant.InputIndex]
+		if !found {
+			continue
+		}
+		if tensor.DType != constant.Shape.DType || !slices.Equal(tensor.Dimensions, constant.Shape.Dimensions) {
+			return errors.Errorf("weight %q has dtype %s and dimensions %v, but the program expects %s",
+				constant.OriginalName, tensor.DType, tensor.Dimensions, constant.Shape)
+		}
+		buffer, err := e.client.BufferFromHost().FromRawData(tensor.Data, tensor.DType, tensor.Dimensions).Done()
+		if err != nil {
+			return errors.WithMessagef(err, "failed to upload weight %q", constant.OriginalName)
+		}
+		if previous := e.constants[constant.InputIndex]; previous != nil {
+			_ = previous.Destroy()
+		}
+		e.constants[constant.InputIndex] = buffer
+	}
+	return nil
+}
+
+// BindAllWeights is like BindWeights, but it returns an error, without uploading any weight, if some
+// external constant of the program that is not yet bound is not found in weights -- e.g. because of a typo
+// in its name.
+func (e *Executable) BindAllWeights(weights Weights) error {
+	matched, err := matchWeights(e.constantInputs, weights)
+	if err != nil {
+		return err
+	}
+	var missing []string
+	for _, constant := range e.constantInputs {
+		if _, found := matched[constant.InputIndex]; !found && e.constants[constant.InputIndex] == nil {
+			missing = append(missing, constant.OriginalName)
+		}
+	}
+	if len(missing) > 0 {
+		return errors.Errorf("weights not found for the external constants %q", missing)
+	}
+	return e.BindWeights(weights)
+}
+
+// UnboundConstants returns the names (as given to stablehlo.Function.ExternalConstant) of the external
+// constants that were not bound yet, see BindWeights.
+func (e *Executable) UnboundConstants() []string {
+	var names []string
+	for _, constant := range e.constantInputs {
+		if e.constants[constant.InputIndex] == nil {
+			names = append(names, constant.OriginalName)
+		}
+	}
+	return names
+}
+
+// matchWeights returns the weights matching the external constants, indexed by their input index.
+// See BindWeights for the matching rules.
+func matchWeights(constants []stablehlo.ConstantInput, weights Weights) (map[int]*Tensor, error) {
+	matched := make(map[int]*Tensor)
+	var validKeys map[string][]string
+	for _, constant := range constants {
+		if constant.Flat != nil {
+			continue
+		}
+		if tensor, found := weights[constant.OriginalName]; found {
+			matched[constant.InputIndex] = tensor
+			continue
+		}
+		if validKeys == nil {
+			validKeys = make(map[string][]string, len(weights))
+			for key := range weights {
+				validKey := stablehlo.ConvertToValidName(key)
+				validKeys[validKey] = append(validKeys[validKey], key)
+			}
+		}
+		keys := validKeys[constant.Name]
+		switch len(keys) {
+		case 0:
+			continue
+		case 1:
+			matched[constant.InputIndex] = weights[keys[0]]
+		default:
+			slices.Sort(keys)
+			return nil, errors.Errorf("weights %q all match the external constant %q", keys, constant.OriginalName)
+		}
+	}
+	return matched, nil
+}
+
+// Execute the program with the given inputs: they must be the non-constant inputs of the main function, in order.
+// The constant inputs are fed automatically.
+//
+// For programs executed on multiple devices (see CompileLaunchConfig), the inputs of each device are given one
+// device after the other, in the order of the device assignment, and so are the returned outputs.
+//
+// The inputs are not donated, and the caller owns (and must destroy) the returned buffers.
+func (e *Executable) Execute(inputs ...*pjrt.Buffer) ([]*pjrt.Buffer, error) {
+	numConstants := len(e.constantInputs)
+	numDeviceInputs := e.numInputs - numConstants
+	if len(inputs) != e.numDevices*numDeviceInputs {
+		if e.numDevices > 1 {
+			return nil, errors.Errorf("program %q takes %d inputs per device, for %d devices, but %d were given",
+				e.name, numDeviceInputs, e.numDevices, len(inputs))
+		}
+		return nil, errors.Errorf("program %q takes %d inputs (besides its %d constant inputs), but %d were given",
+			e.name, numDeviceInputs, numConstants, len(inputs))
+	}
+	allInputs := inputs
+	if numConstants > 0 {
+		// Constant inputs are only supported for single device programs, see NewLaunchConfig.
+		allInputs = make([]*pjrt.Buffer, 0, e.numInputs)
+		for idx := range e.numInputs {
+			constantIdx := slices.IndexFunc(e.constantInputs, func(c stablehlo.ConstantInput) bool { return c.InputIndex == idx })
+			if constantIdx != -1 {
+				buffer := e.constants[idx]
+				if buffer == nil {
+					return nil, errors.Errorf("constant input %q was not bound, see BindWeights",
+						e.constantInputs[constantIdx].OriginalName)
+				}
+				allInputs = append(allInputs, buffer)
+				continue
+			}
+			allInputs = append(allInputs, inputs[0])
+			inputs = inputs[1:]
+		}
+	}
+	outputs, err := e.loaded.Execute(allInputs...).DonateNone().Done()
+	if err != nil {
+		return nil, err
+	}
+	return e.printDebugOutputs(outputs)
+}
+
+// WithDebugWriter sets where the values registered with stablehlo.DebugPrint are printed. It defaults to os.Stderr.
+func (e *Executable) WithDebugWriter(w io.Writer) *Executable {
+	e.debugWriter = w
+	return e
+}
+
+// printDebugOutputs prints and destroys the trailing outputs registered with stablehlo.DebugPrint, and returns
+// the remaining outputs.
+func (e *Executable) printDebugOutputs(outputs []*pjrt.Buffer) ([]*pjrt.Buffer, error) {
+	tags := e.debugTags
+	numOutputs := len(outputs) - len(tags)
+	var firstErr error
+	for i, buffer := range outputs[numOutputs:] {
+		flat, dimensions, err := buffer.ToFlatDataAndDimensions()
+		if err == nil {
+			_, err = fmt.Fprintf(e.debugWriter, "DebugPrint(%s): dimensions=%v, values=%v\n", tags[i], dimensions, flat)
+		}
+		if destroyErr := buffer.Destroy(); err == nil {
+			err = destroyErr
+		}
+		if err != nil && firstErr == nil {
+			firstErr = errors.WithMessagef(err, "failed to print debug value %q", tags[i])
+		}
+	}
+	if firstErr != nil {
+		for _, buffer := range outputs[:numOutputs] {
+			_ = buffer.Destroy()
+		}
+		return nil, firstErr
+	}
+	return outputs[:numOutputs], nil
+}
+
+// Destroy frees the compiled program and the constant buffers.
+func (e *Executable) Destroy() error {
+	var firstErr error
+	for _, buffer := range e.constants {
+		if err := buffer.Destroy(); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+	e.constants = nil
+	if err := e.loaded.Destroy(); err != nil && firstErr == nil {
+		firstErr = err
+	}
+	return firstErr
+}
@@ -0,0 +1,61 @@
+package exec
+
+import (
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestMatchWeights(t *testing.T) {
+	fn := stablehlo.New(t.Name()).Main()
+	shape := shapes.Make(dtypes.Float32, 2)
+	if _, err := fn.ExternalConstant("encoder.layer.0.weight", shape); err != nil {
+		t.Fatalf("ExternalConstant failed: %+v", err)
+	}
+	if _, err := fn.ExternalConstant("encoder/layer.0/bias", shape); err != nil {
+		t.Fatalf("ExternalConstant failed: %+v", err)
+	}
+	if _, err := fn.ConstantAsInput("values", []float32{1, 2}, 2); err != nil {
+		t.Fatalf("ConstantAsInput failed: %+v", err)
+	}
+	constants := fn.ConstantInputs()
+	if got := constants[0].OriginalName; got != "encoder.layer.0.weight" {
+		t.Errorf("expected the original name to be kept, got %q", got)
+	}
+
+	// Dotted names match by their original name, and names differing only by separators match by their
+	// converted name.
+	weight, bias := &Tensor{DType: dtypes.Float32}, &Tensor{DType: dtypes.Float32}
+	matched, err := matchWeights(constants, Weights{
+		"encoder.layer.0.weight": weight,
+		"encoder.layer.0.bias":   bias,
+		"values":                 &Tensor{},
+		"unused":                 &Tensor{},
+	})
+	if err != nil {
+		t.Fatalf("matchWeights failed: %+v", err)
+	}
+	if len(matched) != 2 || matched[0] != weight || matched[1] != bias {
+		t.Errorf("unexpected matched weights: %v", matched)
+	}
+
+	// Missing weights are not matched.
+	matched, err = matchWeights(constants, Weights{"encoder.layer.0.wieght": weight})
+	if err != nil {
+		t.Fatalf("matchWeights failed: %+v", err)
+	}
+	if len(matched) != 0 {
+		t.Errorf("expected no matched weights, got %v", matched)
+	}
+
+	// Weights colliding after converting their names are rejected.
+	if _, err = matchWeights(constants, Weights{
+		"encoder.layer.0.weight": weight,
+		"encoder.layer.0.bias":   bias,
+		"encoder-layer-0-bias":   bias,
+	}); err == nil {
+		t.Error("expected error for weights colliding after converting their names, got nil")
+	}
+}
@@ -0,0 +1,316 @@
+package exec
+
+import (
+	"math"
+	"reflect"
+	"slices"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/gopjrt/dtypes/bfloat16"
+	"github.com/gomlx/gopjrt/pjrt"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+	"github.com/x448/float16"
+)
+
+// HostLayout is the order of the elements of the flat Go slices transferred to (Upload) and from (Download) the
+// devices.
+type HostLayout int
+
+const (
+	// RowMajor layout (also known as "C order"): the last axis varies the fastest. It's the layout of the PJRT
+	// buffers and of the stablehlo constants.
+	RowMajor HostLayout = iota
+
+	// ColumnMajor layout (also known as "Fortran order"): the first axis varies the fastest, as used by
+	// BLAS/LAPACK libraries.
+	ColumnMajor
+)
+
+// String implements fmt.Stringer.
+func (l HostLayout) String() string {
+	switch l {
+	case RowMajor:
+		return "RowMajor"
+	case ColumnMajor:
+		return "ColumnMajor"
+	default:
+		return "InvalidHostLayout"
+	}
+}
+
+// Upload converts the flat Go slice to the dtype of the shape, and the order of its elements from the given layout
+// to row-major, and uploads it to the default device of the client.
+//
+// The flat slice must have exactly the number of elements of the shape, which must be static. Integers can be
+// converted to other integer types (it fails if a value overflows) or to floats, and floats to other float types
+// (including float16 and bfloat16) -- e.g. float64 Go data can be fed to a float32 program. Other conversions
+// (floats to integers, bool or complex to other types) return an error.
+func Upload(client *pjrt.Client, flat any, shape shapes.Shape, layout HostLayout) (*pjrt.Buffer, error) {
+	if shape.IsTuple() || shape.IsDynamic() {
+		return nil, errors.Errorf("Upload requires a static non-tuple shape, got %s", shape)
+	}
+	deviceFlat, err := hostToDevice(flat, shape, layout)
+	if err != nil {
+		return nil, err
+	}
+	buffer, err := client.BufferFromHost().FromFlatDataWithDimensions(deviceFlat, shape.Dimensions).Done()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to upload data with shape %s", shape)
+	}
+	return buffer, nil
+}
+
+// UploadInput is like Upload, using the shape of the non-constant input #inputIdx of the main function -- that is,
+// the index of the input in Execute.
+//
+// The input shapes are only known for executables created with Compile.
+func (e *Executable) UploadInput(inputIdx int, flat any, layout HostLayout) (*pjrt.Buffer, error) {
+	if e.inputShapes == nil {
+		return nil, errors.Errorf("the input shapes of program %q are not known (compiled from a LaunchConfig), "+
+			"use Upload with the shape instead", e.name)
+	}
+	if inputIdx < 0 || inputIdx >= len(e.inputShapes) {
+		return nil, errors.Errorf("invalid input index %d, program %q takes %d inputs (besides its constant inputs)",
+			inputIdx, e.name, len(e.inputShapes))
+	}
+	buffer, err := Upload(e.client, flat, e.inputShapes[inputIdx], layout)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "input #%d of program %q", inputIdx, e.name)
+	}
+	return buffer, nil
+}
+
+// Download copies the contents of the buffer to the flat Go slice dst, converting the elements from the dtype of
+// the buffer to the type of the elements of dst (with the same rules as Upload), and their order from row-major to
+// the given layout.
+//
+// The dst slice must have exactly the number of elements of the buffer.
+func Download(buffer *pjrt.Buffer, dst any, layout HostLayout) error {
+	dstValue := reflect.ValueOf(dst)
+	if dstValue.Kind() != reflect.Slice {
+		return errors.Errorf("Download requires a slice as destination, got %T", dst)
+	}
+	flat, dimensions, err := buffer.ToFlatDataAndDimensions()
+	if err != nil {
+		return errors.WithMessagef(err, "failed to download buffer")
+	}
+	return deviceToHost(flat, dimensions, dstValue, layout)
+}
+
+// hostToDevice converts the flat Go slice to the flat slice of the dtype of the shape, in row-major order.
+func hostToDevice(flat any, shape shapes.Shape, layout HostLayout) (any, error) {
+	src := reflect.ValueOf(flat)
+	if src.Kind() != reflect.Slice {
+		return nil, errors.Errorf("flat data must be a slice, got %T", flat)
+	}
+	if shape.IsDynamic() {
+		return nil, errors.Errorf("flat data requires a static shape, got %s", shape)
+	}
+	if src.Len() != shape.Size() {
+		return nil, errors.Errorf("flat data has %d elements, but shape %s requires %d", src.Len(), shape, shape.Size())
+	}
+	if !slices.Contains(hostDTypes, shape.DType) {
+		return nil, errors.Errorf("dtype %s is not supported for host transfers", shape.DType)
+	}
+	converted, err := convertFlat(src, shape.DType.GoType())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "converting flat data to shape %s", shape)
+	}
+	converted, err = transposeLayout(converted, shape.Dimensions, layout, RowMajor)
+	if err != nil {
+		return nil, err
+	}
+	return converted.Interface(), nil
+}
+
+// deviceToHost copies the row-major flat data downloaded from a device with the given dimensions to dst,
+// converting its elements and layout.
+func deviceToHost(flat any, dimensions []int, dst reflect.Value, layout HostLayout) error {
+	src := reflect.ValueOf(flat)
+	if src.Len() != dst.Len() {
+		return errors.Errorf("destination has %d elements, but the buffer with dimensions %v has %d",
+			dst.Len(), dimensions, src.Len())
+	}
+	converted, err := convertFlat(src, dst.Type().Elem())
+	if err != nil {
+		return errors.WithMessagef(err, "converting buffer with dimensions %v to %s", dimensions, dst.Type())
+	}
+	converted, err = transposeLayout(converted, dimensions, RowMajor, layout)
+	if err != nil {
+		return err
+	}
+	reflect.Copy(dst, converted)
+	return nil
+}
+
+// hostDTypes are the dtypes supported by Upload: the ones with a Go type.
+var hostDTypes = []dtypes.DType{dtypes.Bool, dtypes.Int8, dtypes.Int16, dtypes.Int32, dtypes.Int64,
+	dtypes.Uint8, dtypes.Uint16, dtypes.Uint32, dtypes.Uint64, dtypes.Float16, dtypes.BFloat16, dtypes.Float32,
+	dtypes.Float64, dtypes.Complex64, dtypes.Complex128}
+
+var (
+	float16Type  = reflect.TypeOf(float16.Float16(0))
+	bfloat16Type = reflect.TypeOf(bfloat16.BFloat16(0))
+)
+
+// elementKind groups the Go types of the elements of the flat slices by the conversions supported between them.
+type elementKind int
+
+const (
+	invalidKind elementKind = iota
+	boolKind
+	intKind
+	uintKind
+	floatKind
+	complexKind
+)
+
+// kindOf returns the elementKind of the Go type t.
+func kindOf(t reflect.Type) elementKind {
+	if t == float16Type || t == bfloat16Type {
+		return floatKind
+	}
+	switch t.Kind() {
+	case reflect.Bool:
+		return boolKind
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return intKind
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return uintKind
+	case reflect.Float32, reflect.Float64:
+		return floatKind
+	case reflect.Complex64, reflect.Complex128:
+		return complexKind
+	default:
+		return invalidKind
+	}
+}
+
+// convertFlat converts the elements of the src slice to the Go type dstType, see Upload for the conversions
+// supported. It returns src itself if it already has the dstType elements.
+func convertFlat(src reflect.Value, dstType reflect.Type) (reflect.Value, error) {
+	srcType := src.Type().Elem()
+	if srcType == dstType {
+		return src, nil
+	}
+	srcKind, dstKind := kindOf(srcType), kindOf(dstType)
+	isInteger := func(kind elementKind) bool { return kind == intKind || kind == uintKind }
+	switch {
+	case srcKind == invalidKind || dstKind == invalidKind:
+		return reflect.Value{}, errors.Errorf("cannot convert %s to %s: unsupported element type", srcType, dstType)
+	case srcKind == floatKind && isInteger(dstKind):
+		return reflect.Value{}, errors.Errorf("cannot convert %s to %s: floats are not converted to integers, "+
+			"convert them explicitly", srcType, dstType)
+	case (srcKind == boolKind || dstKind == boolKind || srcKind == complexKind || dstKind == complexKind) &&
+		srcKind != dstKind:
+		return reflect.Value{}, errors.Errorf("cannot convert %s to %s", srcType, dstType)
+	}
+
+	dst := reflect.MakeSlice(reflect.SliceOf(dstType), src.Len(), src.Len())
+	for i := range src.Len() {
+		srcElem, dstElem := src.Index(i), dst.Index(i)
+		switch {
+		case isInteger(srcKind) && isInteger(dstKind):
+			if integerOverflows(srcElem, srcKind, dstElem, dstKind) {
+				return reflect.Value{}, errors.Errorf("cannot convert %s to %s: element #%d (%v) overflows",
+					srcType, dstType, i, srcElem)
+			}
+			dstElem.Set(srcElem.Convert(dstType))
+		case dstKind == floatKind:
+			setFloat(dstElem, getFloat(srcElem, srcKind))
+		default:
+			// Bool to bool or complex to complex of different Go types.
+			dstElem.Set(srcElem.Convert(dstType))
+		}
+	}
+	return dst, nil
+}
+
+// integerOverflows returns whether the integer value src doesn't fit in the type of the integer dst.
+func integerOverflows(src reflect.Value, srcKind elementKind, dst reflect.Value, dstKind elementKind) bool {
+	if srcKind == intKind {
+		x := src.Int()
+		if dstKind == intKind {
+			return dst.OverflowInt(x)
+		}
+		return x < 0 || dst.OverflowUint(uint64(x))
+	}
+	x := src.Uint()
+	if dstKind == uintKind {
+		return dst.OverflowUint(x)
+	}
+	return x > math.MaxInt64 || dst.OverflowInt(int64(x))
+}
+
+// getFloat returns the value of the integer or float element as a float64.
+func getFloat(elem reflect.Value, kind elementKind) float64 {
+	switch {
+	case elem.Type() == float16Type:
+		return float64(elem.Interface().(float16.Float16).Float32())
+	case elem.Type() == bfloat16Type:
+		return float64(elem.Interface().(bfloat16.BFloat16).Float32())
+	case kind == intKind:
+		return float64(elem.Int())
+	case kind == uintKind:
+		return float64(elem.Uint())
+	default:
+		return elem.Float()
+	}
+}
+
+// setFloat sets the float element to x, rounding it to the precision of the element type.
+func setFloat(elem reflect.Value, x float64) {
+	switch elem.Type() {
+	case float16Type:
+		elem.Set(reflect.ValueOf(float16.Fromfloat32(float32(x))))
+	case bfloat16Type:
+		elem.Set(reflect.ValueOf(bfloat16.FromFloat64(x)))
+	default:
+		elem.SetFloat(x)
+	}
+}
+
+// transposeLayout returns the flat slice with the given dimensions reordered from one layout to the other.
+// It returns flat itself if no reordering is needed.
+func transposeLayout(flat reflect.Value, dimensions []int, from, to HostLayout) (reflect.Value, error) {
+	for _, layout := range []HostLayout{from, to} {
+		if layout != RowMajor && layout != ColumnMajor {
+			return reflect.Value{}, errors.Errorf("invalid host layout %d", layout)
+		}
+	}
+	if from == to || len(dimensions) < 2 {
+		return flat, nil
+	}
+
+	// Column-major strides of each axis.
+	rank := len(dimensions)
+	strides := make([]int, rank)
+	stride := 1
+	for axis, dim := range dimensions {
+		strides[axis] = stride
+		stride *= dim
+	}
+
+	// Loop over the elements in row-major order, keeping track of the column-major index.
+	result := reflect.MakeSlice(flat.Type(), flat.Len(), flat.Len())
+	indices := make([]int, rank)
+	columnMajorIdx := 0
+	for rowMajorIdx := range flat.Len() {
+		if from == RowMajor {
+			result.Index(columnMajorIdx).Set(flat.Index(rowMajorIdx))
+		} else {
+			result.Index(rowMajorIdx).Set(flat.Index(columnMajorIdx))
+		}
+		for axis := rank - 1; axis >= 0; axis-- {
+			indices[axis]++
+			columnMajorIdx += strides[axis]
+			if indices[axis] < dimensions[axis] {
+				break
+			}
+			columnMajorIdx -= indices[axis] * strides[axis]
+			indices[axis] = 0
+		}
+	}
+	return result, nil
+}
@@ -0,0 +1,131 @@
+package exec
+
+import (
+	"reflect"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/gopjrt/dtypes/bfloat16"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/x448/float16"
+)
+
+func TestHostTransfers(t *testing.T) {
+	t.Run("dtype conversion", func(t *testing.T) {
+		flat, err := hostToDevice([]float64{1.5, -2, 3}, shapes.Make(dtypes.Float32, 3), RowMajor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := flat, []float32{1.5, -2, 3}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %#v, want %#v", got, want)
+		}
+		flat, err = hostToDevice([]int{1, 2}, shapes.Make(dtypes.Float16, 2), RowMajor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := flat, []float16.Float16{float16.Fromfloat32(1), float16.Fromfloat32(2)}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %#v, want %#v", got, want)
+		}
+		flat, err = hostToDevice([]uint8{0, 255}, shapes.Make(dtypes.Int32, 2), RowMajor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := flat, []int32{0, 255}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %#v, want %#v", got, want)
+		}
+		// Same type: no copy.
+		data := []float32{1, 2}
+		flat, err = hostToDevice(data, shapes.Make(dtypes.Float32, 2), RowMajor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if &flat.([]float32)[0] != &data[0] {
+			t.Error("expected the data not to be copied if it already has the dtype of the shape")
+		}
+
+		dst := make([]float64, 2)
+		if err = deviceToHost([]bfloat16.BFloat16{bfloat16.FromFloat32(0.5), bfloat16.FromFloat32(4)}, []int{2},
+			reflect.ValueOf(dst), RowMajor); err != nil {
+			t.Fatal(err)
+		}
+		if want := []float64{0.5, 4}; !slices.Equal(dst, want) {
+			t.Errorf("got %v, want %v", dst, want)
+		}
+	})
+
+	t.Run("layout", func(t *testing.T) {
+		// Column-major [[0, 1, 2], [3, 4, 5]].
+		columnMajor := []int32{0, 3, 1, 4, 2, 5}
+		flat, err := hostToDevice(columnMajor, shapes.Make(dtypes.Int32, 2, 3), ColumnMajor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got, want := flat, []int32{0, 1, 2, 3, 4, 5}; !reflect.DeepEqual(got, want) {
+			t.Errorf("got %v, want %v", got, want)
+		}
+		dst := make([]int64, 6)
+		if err = deviceToHost(flat, []int{2, 3}, reflect.ValueOf(dst), ColumnMajor); err != nil {
+			t.Fatal(err)
+		}
+		if want := []int64{0, 3, 1, 4, 2, 5}; !slices.Equal(dst, want) {
+			t.Errorf("got %v, want %v", dst, want)
+		}
+
+		// Rank-3 round trip.
+		dims := []int{2, 3, 4}
+		rowMajor := make([]int, 24)
+		for i := range rowMajor {
+			rowMajor[i] = i
+		}
+		transposed, err := transposeLayout(reflect.ValueOf(rowMajor), dims, RowMajor, ColumnMajor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		// Element [1, 2, 3] is at row-major index 1*12+2*4+3 = 23 and column-major index 1+2*2+3*6 = 23;
+		// element [1, 0, 0] is at row-major index 12 and column-major index 1.
+		if got := transposed.Index(1).Interface(); got != 12 {
+			t.Errorf("column-major element #1 is %v, wanted 12", got)
+		}
+		back, err := transposeLayout(transposed, dims, ColumnMajor, RowMajor)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if got := back.Interface().([]int); !slices.Equal(got, rowMajor) {
+			t.Errorf("round trip got %v, want %v", got, rowMajor)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		for _, tc := range []struct {
+			name  string
+			flat  any
+			shape shapes.Shape
+			want  string
+		}{
+			{"not a slice", 1.0, shapes.Make(dtypes.Float32), "must be a slice"},
+			{"size", []float32{1, 2}, shapes.Make(dtypes.Float32, 3), "has 2 elements"},
+			{"dynamic", []float32{1, 2}, shapes.Make(dtypes.Float32, shapes.DynamicDim).WithBounds(2), "static shape"},
+			{"float to int", []float64{1.5}, shapes.Make(dtypes.Int32, 1), "floats are not converted to integers"},
+			{"bool to float", []bool{true}, shapes.Make(dtypes.Float32, 1), "cannot convert bool to float32"},
+			{"overflow", []int64{1 << 40}, shapes.Make(dtypes.Int32, 1), "overflows"},
+			{"negative to unsigned", []int8{-1}, shapes.Make(dtypes.Uint8, 1), "overflows"},
+			{"string", []string{"a"}, shapes.Make(dtypes.Float32, 1), "unsupported element type"},
+			{"f8", []float32{1}, shapes.Make(dtypes.F8E4M3FN, 1), "not supported for host transfers"},
+		} {
+			_, err := hostToDevice(tc.flat, tc.shape, RowMajor)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.want, err)
+			}
+		}
+		_, err := hostToDevice([]float32{1, 2}, shapes.Make(dtypes.Float32, 2), HostLayout(7))
+		if err == nil || !strings.Contains(err.Error(), "invalid host layout") {
+			t.Errorf("expected invalid host layout error, got %v", err)
+		}
+		err = deviceToHost([]float32{1, 2}, []int{2}, reflect.ValueOf(make([]float32, 3)), RowMajor)
+		if err == nil || !strings.Contains(err.Error(), "destination has 3 elements") {
+			t.Errorf("expected destination size error, got %v", err)
+		}
+	})
+}
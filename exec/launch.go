@@ -0,0 +1,249 @@
+package exec
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"slices"
+
+	"github.com/gomlx/gopjrt/pjrt"
+	"github.com/gomlx/stablehlo"
+	"github.com/gomlx/stablehlo/types/shardy"
+	"github.com/pkg/errors"
+)
+
+// LaunchConfig bundles a built program with what is needed to compile and execute it across devices: the number
+// of replicas and partitions, the device assignment and the Shardy metadata (meshes and the shardings of the
+// inputs and outputs).
+//
+// It is serialized as JSON (see Marshal and Save), so a program can be built once and shipped as a single artifact
+// to each host of a multi-host deployment, where it is restored (UnmarshalLaunchConfig or LoadLaunchConfig) and
+// compiled with CompileLaunchConfig.
+type LaunchConfig struct {
+	// Name of the main function of the program.
+	Name string `json:"name"`
+
+	// Program is the StableHLO program, as returned by stablehlo.Builder.Build.
+	Program string `json:"program"`
+
+	// NumReplicas and NumPartitions of the program, see stablehlo.Builder.WithNumReplicas and
+	// stablehlo.Builder.WithNumPartitions. Zero means not set (equivalent to 1).
+	NumReplicas   int `json:"num_replicas,omitempty"`
+	NumPartitions int `json:"num_partitions,omitempty"`
+
+	// DeviceAssignment lists the devices to execute the program on, in the order their inputs are fed
+	// (see Executable.Execute). If empty, PJRT's default assignment is used.
+	DeviceAssignment []int `json:"device_assignment,omitempty"`
+
+	// Meshes configured with stablehlo.Builder.WithShardy. If not empty, the program is compiled with Shardy.
+	Meshes []MeshConfig `json:"meshes,omitempty"`
+
+	// InputShardings and OutputShardings hold the "sdy.sharding" attribute of each input and output of the
+	// main function (e.g. `#sdy.sharding<@mesh, [{"data"}, {}]>`), or "" if not sharded (replicated).
+	InputShardings  []string `json:"input_shardings"`
+	OutputShardings []string `json:"output_shardings"`
+}
+
+// MeshConfig describes a shardy.DeviceMesh in a LaunchConfig.
+type MeshConfig struct {
+	Name                    string   `json:"name"`
+	AxesNames               []string `json:"axes_names"`
+	AxesSizes               []int    `json:"axes_sizes"`
+	LogicalDeviceAssignment []int    `json:"logical_device_assignment,omitempty"`
+}
+
+// DeviceMesh recreates the shardy.DeviceMesh described by the MeshConfig.
+func (m MeshConfig) DeviceMesh() (*shardy.DeviceMesh, error) {
+	mesh, err := shardy.NewDeviceMesh(m.Name, m.AxesSizes, m.AxesNames)
+	if err != nil {
+		return nil, err
+	}
+	if len(m.LogicalDeviceAssignment) > 0 {
+		if err = mesh.SetLogicalDeviceAssignment(m.LogicalDeviceAssignment...); err != nil {
+			return nil, errors.WithMessagef(err, "mesh %q", m.Name)
+		}
+	}
+	return mesh, nil
+}
+
+// NewLaunchConfig builds the program of the given main function and bundles it with its configuration.
+//
+// The deviceAssignment is optional (nil uses PJRT's default assignment), otherwise it must list one device per
+// replica and partition of the program.
+//
+// Programs with constant inputs (see stablehlo.Function.ConstantInputs) or values registered with
+// stablehlo.DebugPrint are not supported, since those are not part of the serialized program.
+func NewLaunchConfig(main *stablehlo.Function, deviceAssignment []int) (*LaunchConfig, error) {
+	if len(main.ConstantInputs()) > 0 {
+		return nil, errors.Errorf("program %q has constant inputs, which are not supported by LaunchConfig", main.Name)
+	}
+	if len(main.DebugTags()) > 0 {
+		return nil, errors.Errorf("program %q has DebugPrint values, which are not supported by LaunchConfig", main.Name)
+	}
+	if !main.Returned {
+		return nil, errors.Errorf("program %q has not returned yet, see Function.Return", main.Name)
+	}
+	builder := main.Builder
+	program, err := builder.Build()
+	if err != nil {
+		return nil, err
+	}
+	c := &LaunchConfig{
+		Name:             main.Name,
+		Program:          string(program),
+		NumReplicas:      builder.NumReplicas(),
+		NumPartitions:    builder.NumPartitions(),
+		DeviceAssignment: slices.Clone(deviceAssignment),
+		InputShardings:   valuesShardings(main.Inputs),
+		OutputShardings:  valuesShardings(main.Outputs),
+	}
+	for _, mesh := range builder.Meshes() {
+		c.Meshes = append(c.Meshes, MeshConfig{
+			Name:                    mesh.Name(),
+			AxesNames:               slices.Clone(mesh.AxesNames()),
+			AxesSizes:               slices.Clone(mesh.AxesSizes()),
+			LogicalDeviceAssignment: slices.Clone(mesh.LogicalDeviceAssignment()),
+		})
+	}
+	if err = c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// valuesShardings returns the "sdy.sharding" attribute of each value, or "" if not set.
+func valuesShardings(values []*stablehlo.Value) []string {
+	shardings := make([]string, len(values))
+	for i, value := range values {
+		if sharding, found := value.Attributes["sdy.sharding"]; found {
+			shardings[i] = fmt.Sprint(sharding)
+		}
+	}
+	return shardings
+}
+
+// NumDevices returns the number of devices the program is executed on: the number of replicas times the number
+// of partitions.
+func (c *LaunchConfig) NumDevices() int {
+	return max(c.NumReplicas, 1) * max(c.NumPartitions, 1)
+}
+
+// Validate checks the consistency of the LaunchConfig.
+func (c *LaunchConfig) Validate() error {
+	if c.Program == "" {
+		return errors.Errorf("launch configuration of %q has no program", c.Name)
+	}
+	if len(c.Meshes) > 0 && c.NumReplicas > 1 {
+		return errors.Errorf("launch configuration of %q uses Shardy meshes with %d replicas, but Shardy "+
+			"requires a single replica", c.Name, c.NumReplicas)
+	}
+	if len(c.Meshes) == 0 && c.NumPartitions > 1 {
+		return errors.Errorf("launch configuration of %q has %d partitions but no Shardy meshes: only data "+
+			"parallelism (replicas) is supported without Shardy", c.Name, c.NumPartitions)
+	}
+	if len(c.DeviceAssignment) > 0 {
+		if len(c.DeviceAssignment) != c.NumDevices() {
+			return errors.Errorf("launch configuration of %q has %d devices assigned, but it requires %d "+
+				"(replicas x partitions)", c.Name, len(c.DeviceAssignment), c.NumDevices())
+		}
+		for i, device := range c.DeviceAssignment {
+			if device < 0 || slices.Index(c.DeviceAssignment, device) != i {
+				return errors.Errorf("launch configuration of %q has invalid device assignment %v: devices must be "+
+					"non-negative and unique", c.Name, c.DeviceAssignment)
+			}
+		}
+	}
+	for i, meshConfig := range c.Meshes {
+		if slices.IndexFunc(c.Meshes, func(m MeshConfig) bool { return m.Name == meshConfig.Name }) != i {
+			return errors.Errorf("launch configuration of %q has duplicate mesh name %q", c.Name, meshConfig.Name)
+		}
+		mesh, err := meshConfig.DeviceMesh()
+		if err != nil {
+			return errors.WithMessagef(err, "launch configuration of %q", c.Name)
+		}
+		if mesh.NumDevices() > c.NumDevices() {
+			return errors.Errorf("launch configuration of %q has mesh %q with %d devices, but the program is "+
+				"executed on %d devices", c.Name, mesh.Name(), mesh.NumDevices(), c.NumDevices())
+		}
+	}
+	return nil
+}
+
+// Marshal serializes the LaunchConfig as JSON.
+func (c *LaunchConfig) Marshal() ([]byte, error) {
+	data, err := json.MarshalIndent(c, "", "  ")
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to serialize launch configuration of %q", c.Name)
+	}
+	return data, nil
+}
+
+// Save the serialized LaunchConfig to the file in path.
+func (c *LaunchConfig) Save(path string) error {
+	data, err := c.Marshal()
+	if err != nil {
+		return err
+	}
+	if err = os.WriteFile(path, data, 0644); err != nil {
+		return errors.Wrapf(err, "failed to write launch configuration to %q", path)
+	}
+	return nil
+}
+
+// UnmarshalLaunchConfig deserializes and validates a LaunchConfig serialized with LaunchConfig.Marshal.
+func UnmarshalLaunchConfig(data []byte) (*LaunchConfig, error) {
+	c := &LaunchConfig{}
+	if err := json.Unmarshal(data, c); err != nil {
+		return nil, errors.Wrap(err, "failed to parse launch configuration")
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	return c, nil
+}
+
+// LoadLaunchConfig reads a LaunchConfig saved with LaunchConfig.Save.
+func LoadLaunchConfig(path string) (*LaunchConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read launch configuration from %q", path)
+	}
+	c, err := UnmarshalLaunchConfig(data)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "launch configuration file %q", path)
+	}
+	return c, nil
+}
+
+// CompileLaunchConfig compiles the program of the LaunchConfig with the client, using Shardy if the program has
+// meshes, and its device assignment.
+//
+// The returned Executable takes the inputs of all devices (see Executable.Execute).
+func CompileLaunchConfig(client *pjrt.Client, c *LaunchConfig) (*Executable, error) {
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+	numDevices := c.NumDevices()
+	compileConfig := client.Compile().WithStableHLO([]byte(c.Program))
+	if len(c.Meshes) > 0 {
+		compileConfig = compileConfig.WithShardy(numDevices)
+	} else if numDevices > 1 {
+		compileConfig = compileConfig.WithSPMD(numDevices)
+	}
+	if len(c.DeviceAssignment) > 0 {
+		compileConfig = compileConfig.WithDeviceAssignment(c.DeviceAssignment)
+	}
+	loaded, err := compileConfig.Done()
+	if err != nil {
+		return nil, errors.WithMessagef(err, "failed to compile program %q", c.Name)
+	}
+	return &Executable{
+		client:      client,
+		loaded:      loaded,
+		name:        c.Name,
+		numInputs:   len(c.InputShardings),
+		numDevices:  numDevices,
+		constants:   make(map[int]*pjrt.Buffer),
+		debugWriter: os.Stderr,
+	}, nil
+}
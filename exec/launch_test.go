@@ -0,0 +1,87 @@
+package exec
+
+import (
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/gomlx/stablehlo/types/shardy"
+)
+
+func TestLaunchConfig(t *testing.T) {
+	mesh, err := shardy.NewDeviceMesh("mesh", []int{2}, []string{"data"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = mesh.SetLogicalDeviceAssignment(1, 0); err != nil {
+		t.Fatal(err)
+	}
+	builder := stablehlo.New(t.Name()).WithShardy(mesh)
+	main := builder.Main()
+	x, err := main.NamedInputWithSharding("x", shapes.Make(dtypes.Float32, 2, 3),
+		builder.NewShardingSpec().AddShardedAxis("data"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := main.NamedInput("y", shapes.Make(dtypes.Float32, 2, 3))
+	if err != nil {
+		t.Fatal(err)
+	}
+	sum, err := stablehlo.Add(x, y)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = main.Return(sum); err != nil {
+		t.Fatal(err)
+	}
+
+	config, err := NewLaunchConfig(main, []int{3, 2})
+	if err != nil {
+		t.Fatalf("NewLaunchConfig failed: %+v", err)
+	}
+	if config.NumReplicas != 1 || config.NumPartitions != 2 || config.NumDevices() != 2 {
+		t.Errorf("unexpected replicas/partitions: %d/%d", config.NumReplicas, config.NumPartitions)
+	}
+	wantInputShardings := []string{`#sdy.sharding<@mesh, [{"data"}, {}]>`, ""}
+	if !reflect.DeepEqual(config.InputShardings, wantInputShardings) {
+		t.Errorf("InputShardings = %q, want %q", config.InputShardings, wantInputShardings)
+	}
+	if !reflect.DeepEqual(config.OutputShardings, []string{""}) {
+		t.Errorf("OutputShardings = %q, want a single unsharded output", config.OutputShardings)
+	}
+
+	// Round-trip through a file.
+	path := filepath.Join(t.TempDir(), "launch.json")
+	if err = config.Save(path); err != nil {
+		t.Fatalf("Save failed: %+v", err)
+	}
+	loaded, err := LoadLaunchConfig(path)
+	if err != nil {
+		t.Fatalf("LoadLaunchConfig failed: %+v", err)
+	}
+	if !reflect.DeepEqual(loaded, config) {
+		t.Errorf("loaded launch configuration differs:\n%+v\nwant:\n%+v", loaded, config)
+	}
+	loadedMesh, err := loaded.Meshes[0].DeviceMesh()
+	if err != nil {
+		t.Fatalf("DeviceMesh failed: %+v", err)
+	}
+	if loadedMesh.String() != mesh.String() ||
+		!reflect.DeepEqual(loadedMesh.LogicalDeviceAssignment(), mesh.LogicalDeviceAssignment()) {
+		t.Errorf("loaded mesh %s differs from %s", loadedMesh, mesh)
+	}
+
+	// Invalid configurations.
+	if _, err = NewLaunchConfig(main, []int{0, 1, 2}); err == nil {
+		t.Error("expected error for device assignment of the wrong size")
+	}
+	if _, err = NewLaunchConfig(main, []int{1, 1}); err == nil {
+		t.Error("expected error for repeated devices in the device assignment")
+	}
+	if _, err = UnmarshalLaunchConfig([]byte(`{"name": "main", "num_partitions": 2}`)); err == nil {
+		t.Error("expected error for launch configuration without a program")
+	}
+}
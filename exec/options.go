@@ -0,0 +1,163 @@
+package exec
+
+import (
+	"fmt"
+	"os"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/gomlx/gopjrt/pjrt"
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/prototext"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoregistry"
+)
+
+// CompileOptions holds the XLA compilation options used by CompileWithOptions, as typed fields, instead of
+// configuring them with environment variables.
+//
+// The zero value compiles the program as Compile does.
+//
+// There is no option for XLA's automatic SPMD partitioning (ExecutableBuildOptions.use_auto_spmd_partitioning):
+// gopjrt doesn't expose it in its pjrt.CompileConfig. Use Shardy meshes (stablehlo.Builder.WithShardy) instead.
+type CompileOptions struct {
+	// NumReplicas and NumPartitions the program is compiled for. Zero means the values configured in the
+	// builder of the program (stablehlo.Builder.WithNumReplicas and stablehlo.Builder.WithNumPartitions).
+	//
+	// Programs with Shardy meshes (stablehlo.Builder.WithShardy) are partitioned with Shardy, and they must have
+	// a single replica. Otherwise, only replicas (data parallelism) are supported.
+	NumReplicas, NumPartitions int
+
+	// DeviceAssignment lists the devices to execute the program on, one per replica and partition.
+	// If empty, PJRT's default assignment is used.
+	DeviceAssignment []int
+
+	// DisableFusion disables XLA's fusion passes, so each operation is executed by its own kernel.
+	// It's useful to debug numerical differences, and it's usually much slower.
+	DisableFusion bool
+
+	// DisableHLOPasses lists the names of other XLA HLO passes to disable (DebugOptions.xla_disable_hlo_passes).
+	DisableHLOPasses []string
+
+	// DumpTo is a directory where XLA dumps the program at the different stages of the compilation
+	// (DebugOptions.xla_dump_to), in text format.
+	DumpTo string
+
+	// DebugOptions holds other XLA DebugOptions, in the protobuf text format, e.g. "xla_cpu_enable_fast_math: true".
+	DebugOptions string
+}
+
+// fusionPasses are the names of XLA's fusion passes, disabled by CompileOptions.DisableFusion.
+var fusionPasses = []string{"fusion", "priority-fusion", "multi_output_fusion"}
+
+// Validate checks the consistency of the options.
+func (o *CompileOptions) Validate() error {
+	if o.NumReplicas < 0 || o.NumPartitions < 0 {
+		return errors.Errorf("compile options have invalid number of replicas (%d) or partitions (%d)",
+			o.NumReplicas, o.NumPartitions)
+	}
+	for i, device := range o.DeviceAssignment {
+		if device < 0 || slices.Index(o.DeviceAssignment, device) != i {
+			return errors.Errorf("compile options have invalid device assignment %v: devices must be "+
+				"non-negative and unique", o.DeviceAssignment)
+		}
+	}
+	for _, pass := range o.DisableHLOPasses {
+		if pass == "" || strings.ContainsAny(pass, "\"\\\n") {
+			return errors.Errorf("compile options have invalid HLO pass name %q", pass)
+		}
+	}
+	return nil
+}
+
+// xlaDebugOptions returns the XLA DebugOptions of the options, in the protobuf text format.
+//
+// The typed fields (DisableFusion, DisableHLOPasses and DumpTo) are merged into CompileOptions.DebugOptions:
+// they override the singular fields also set there (e.g. xla_dump_to), and the repeated fields are concatenated.
+func (o *CompileOptions) xlaDebugOptions() (string, error) {
+	var parts []string
+	passes := slices.Clone(o.DisableHLOPasses)
+	if o.DisableFusion {
+		passes = append(passes, fusionPasses...)
+	}
+	for _, pass := range passes {
+		parts = append(parts, fmt.Sprintf("xla_disable_hlo_passes: %q", pass))
+	}
+	if o.DumpTo != "" {
+		parts = append(parts, "xla_dump_to: "+strconv.Quote(o.DumpTo), "xla_dump_hlo_as_text: true")
+	}
+	return mergeXLADebugOptions(o.DebugOptions, strings.Join(parts, " "))
+}
+
+// xlaDebugOptionsName is the full name of XLA's DebugOptions protobuf, registered by gopjrt.
+const xlaDebugOptionsName = "xla.DebugOptions"
+
+// mergeXLADebugOptions parses the XLA DebugOptions given in the protobuf text format and merges them in order,
+// with proto.Merge: singular fields set by later options override the earlier ones, repeated fields are concatenated.
+// It returns the merged DebugOptions in the protobuf text format, or an empty string if none are set.
+//
+// gopjrt doesn't export the DebugOptions Go type, so its descriptor is taken from the global protobuf registry.
+func mergeXLADebugOptions(debugOptions ...string) (string, error) {
+	messageType, err := protoregistry.GlobalTypes.FindMessageByName(xlaDebugOptionsName)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to find the XLA DebugOptions protobuf %q", xlaDebugOptionsName)
+	}
+	merged := messageType.New().Interface()
+	for _, text := range debugOptions {
+		if text == "" {
+			continue
+		}
+		parsed := messageType.New().Interface()
+		if err := prototext.Unmarshal([]byte(text), parsed); err != nil {
+			return "", errors.Wrapf(err, "invalid XLA DebugOptions %q", text)
+		}
+		proto.Merge(merged, parsed)
+	}
+	text, err := prototext.Marshal(merged)
+	if err != nil {
+		return "", errors.Wrapf(err, "failed to convert the XLA DebugOptions to text")
+	}
+	return string(text), nil
+}
+
+// xlaDebugOptionsMu serializes the changes to the pjrt.EnvXlaDebugOptions environment variable.
+var xlaDebugOptionsMu sync.Mutex
+
+// newCompileConfig creates the compilation configuration of the program with the XLA DebugOptions of the options.
+//
+// PJRT only takes the DebugOptions from the pjrt.EnvXlaDebugOptions environment variable, read when the compilation
+// configuration is created: so it's set only during the call, to the DebugOptions of the options merged into its
+// current value, if any (the options override the singular fields set in the environment).
+// os.Setenv changes the environment of the whole process: the lock only serializes the calls of this package, it
+// doesn't protect other PJRT compilations or other readers of the environment running concurrently.
+//
+// TODO: pass the DebugOptions directly, without changing the environment, once pjrt.CompileConfig accepts them.
+func newCompileConfig(client *pjrt.Client, program []byte, options *CompileOptions) (*pjrt.CompileConfig, error) {
+	debugOptions, err := options.xlaDebugOptions()
+	if err != nil {
+		return nil, err
+	}
+	if debugOptions == "" {
+		return client.Compile().WithStableHLO(program), nil
+	}
+	xlaDebugOptionsMu.Lock()
+	defer xlaDebugOptionsMu.Unlock()
+	previous, wasSet := os.LookupEnv(pjrt.EnvXlaDebugOptions)
+	if wasSet && previous != "" {
+		debugOptions, err = mergeXLADebugOptions(previous, debugOptions)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "failed to merge $%s with the compile options", pjrt.EnvXlaDebugOptions)
+		}
+	}
+	_ = os.Setenv(pjrt.EnvXlaDebugOptions, debugOptions)
+	defer func() {
+		if wasSet {
+			_ = os.Setenv(pjrt.EnvXlaDebugOptions, previous)
+		} else {
+			_ = os.Unsetenv(pjrt.EnvXlaDebugOptions)
+		}
+	}()
+	return client.Compile().WithStableHLO(program), nil
+}
@@ -0,0 +1,129 @@
+package exec
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/gomlx/stablehlo/types/shardy"
+)
+
+func TestCompileOptions(t *testing.T) {
+	options := &CompileOptions{
+		DisableFusion:    true,
+		DisableHLOPasses: []string{"algsimp"},
+		DumpTo:           "/tmp/xla dump",
+		DebugOptions:     "xla_cpu_enable_fast_math: true",
+	}
+	if err := options.Validate(); err != nil {
+		t.Fatalf("Validate failed: %+v", err)
+	}
+	// equalDebugOptions checks that the XLA DebugOptions in text format are equal, ignoring the formatting.
+	equalDebugOptions := func(got, want string) bool {
+		t.Helper()
+		wantNormalized, err := mergeXLADebugOptions(want)
+		if err != nil {
+			t.Fatalf("invalid DebugOptions %q: %+v", want, err)
+		}
+		return strings.Join(strings.Fields(got), " ") == strings.Join(strings.Fields(wantNormalized), " ")
+	}
+	want := `xla_disable_hlo_passes: "algsimp" xla_disable_hlo_passes: "fusion" ` +
+		`xla_disable_hlo_passes: "priority-fusion" xla_disable_hlo_passes: "multi_output_fusion" ` +
+		`xla_dump_to: "/tmp/xla dump" xla_dump_hlo_as_text: true xla_cpu_enable_fast_math: true`
+	if got, err := options.xlaDebugOptions(); err != nil || !equalDebugOptions(got, want) {
+		t.Errorf("xlaDebugOptions() = %q, %v, want %q", got, err, want)
+	}
+	if got, err := (&CompileOptions{NumReplicas: 2}).xlaDebugOptions(); err != nil || got != "" {
+		t.Errorf("xlaDebugOptions() without debug options = %q, %v, want empty", got, err)
+	}
+
+	// The typed fields override the singular fields of DebugOptions, and of the environment.
+	options = &CompileOptions{DumpTo: "/tmp/b", DebugOptions: `xla_dump_to: "/tmp/a" xla_disable_hlo_passes: "cse"`}
+	got, err := options.xlaDebugOptions()
+	want = `xla_dump_to: "/tmp/b" xla_dump_hlo_as_text: true xla_disable_hlo_passes: "cse"`
+	if err != nil || !equalDebugOptions(got, want) {
+		t.Errorf("xlaDebugOptions() = %q, %v, want %q", got, err, want)
+	}
+	got, err = mergeXLADebugOptions(`xla_dump_to: "/tmp/env" xla_cpu_enable_fast_math: true`, got)
+	want = `xla_dump_to: "/tmp/b" xla_dump_hlo_as_text: true xla_disable_hlo_passes: "cse" ` +
+		`xla_cpu_enable_fast_math: true`
+	if err != nil || !equalDebugOptions(got, want) {
+		t.Errorf("mergeXLADebugOptions() = %q, %v, want %q", got, err, want)
+	}
+	if _, err := (&CompileOptions{DebugOptions: "xla_unknown_option: 1"}).xlaDebugOptions(); err == nil {
+		t.Errorf("xlaDebugOptions() with an unknown DebugOptions field should have failed")
+	}
+
+	for _, invalid := range []*CompileOptions{
+		{NumReplicas: -1},
+		{DeviceAssignment: []int{0, 0}},
+		{DisableHLOPasses: []string{`bad"name`}},
+	} {
+		if err := invalid.Validate(); err == nil {
+			t.Errorf("Validate(%+v) should have failed", invalid)
+		}
+	}
+}
+
+func TestCompileWithOptionsErrors(t *testing.T) {
+	// newProgram returns the main function of a returned program, with the builder configured by configure.
+	// If withConstant is true, a constant input is added to the program.
+	newProgram := func(name string, configure func(b *stablehlo.Builder), withConstant bool) *stablehlo.Function {
+		builder := stablehlo.New(name)
+		configure(builder)
+		main := builder.Main()
+		x, err := main.NamedInput("x", shapes.Make(dtypes.Float32, 2))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if withConstant {
+			if x, err = main.ConstantAsInput("c", []float32{1, 2}, 2); err != nil {
+				t.Fatal(err)
+			}
+		}
+		if err = main.Return(x); err != nil {
+			t.Fatal(err)
+		}
+		return main
+	}
+	mesh, err := shardy.NewDeviceMesh("mesh", []int{2}, []string{"data"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	withConstant := newProgram("constant", func(b *stablehlo.Builder) {}, true)
+	withDebugPrint := stablehlo.New("debug").Main()
+	x, err := withDebugPrint.NamedInput("x", shapes.Make(dtypes.Float32, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if x, err = stablehlo.DebugPrint(x, "x"); err != nil {
+		t.Fatal(err)
+	}
+	if err = withDebugPrint.Return(x); err != nil {
+		t.Fatal(err)
+	}
+
+	// The errors are found before the client is used.
+	for _, tc := range []struct {
+		name    string
+		main    *stablehlo.Function
+		options *CompileOptions
+		want    string
+	}{
+		{"shardy replicas", newProgram("shardy", func(b *stablehlo.Builder) { b.WithShardy(mesh) }, false),
+			&CompileOptions{NumReplicas: 2}, "single replica"},
+		{"partitions", newProgram("partitions", func(b *stablehlo.Builder) {}, false),
+			&CompileOptions{NumPartitions: 2}, "no Shardy meshes"},
+		{"device assignment", newProgram("replicas", func(b *stablehlo.Builder) { b.WithNumReplicas(2) }, false),
+			&CompileOptions{DeviceAssignment: []int{0}}, "requires 2"},
+		{"constant inputs", withConstant, &CompileOptions{NumReplicas: 2}, "constant inputs"},
+		{"debug print", withDebugPrint, &CompileOptions{NumReplicas: 2}, "DebugPrint"},
+		{"invalid", withConstant, &CompileOptions{NumPartitions: -1}, "invalid number"},
+	} {
+		if _, err := CompileWithOptions(nil, tc.main, tc.options); err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("%s: expected error containing %q, got %v", tc.name, tc.want, err)
+		}
+	}
+}
@@ -0,0 +1,233 @@
+package exec
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/pkg/errors"
+)
+
+// Tensor holds the raw (little-endian, row-major) data of a weight loaded from a file.
+type Tensor struct {
+	DType      dtypes.DType
+	Dimensions []int
+	Data       []byte
+}
+
+// Weights maps weight names to their values, see LoadWeights.
+type Weights map[string]*Tensor
+
+// LoadWeights loads the weights from a safetensors (".safetensors") or numpy (".npz") file, based on the
+// file extension.
+func LoadWeights(path string) (Weights, error) {
+	switch strings.ToLower(filepath.Ext(path)) {
+	case ".safetensors":
+		return LoadSafetensors(path)
+	case ".npz":
+		return LoadNpz(path)
+	default:
+		return nil, errors.Errorf("unknown weights file format for %q: expected a .safetensors or .npz file", path)
+	}
+}
+
+// safetensorsDTypes maps the safetensors dtype names to dtypes.
+var safetensorsDTypes = map[string]dtypes.DType{
+	"BOOL":    dtypes.Bool,
+	"U8":      dtypes.Uint8,
+	"I8":      dtypes.Int8,
+	"U16":     dtypes.Uint16,
+	"I16":     dtypes.Int16,
+	"U32":     dtypes.Uint32,
+	"I32":     dtypes.Int32,
+	"U64":     dtypes.Uint64,
+	"I64":     dtypes.Int64,
+	"F16":     dtypes.Float16,
+	"BF16":    dtypes.BFloat16,
+	"F32":     dtypes.Float32,
+	"F64":     dtypes.Float64,
+	"F8_E5M2": dtypes.F8E5M2,
+	"F8_E4M3": dtypes.F8E4M3FN,
+}
+
+// LoadSafetensors loads all the weights of a safetensors file (https://github.com/huggingface/safetensors).
+func LoadSafetensors(path string) (Weights, error) {
+	contents, err := os.ReadFile(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read safetensors file")
+	}
+	weights, err := parseSafetensors(contents)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "in safetensors file %q", path)
+	}
+	return weights, nil
+}
+
+// parseSafetensors parses the contents of a safetensors file.
+// The returned tensors data point to contents.
+func parseSafetensors(contents []byte) (Weights, error) {
+	if len(contents) < 8 {
+		return nil, errors.New("file too short for a safetensors header")
+	}
+	headerSize := binary.LittleEndian.Uint64(contents[:8])
+	if headerSize > uint64(len(contents)-8) {
+		return nil, errors.Errorf("invalid header size %d for file with %d bytes", headerSize, len(contents))
+	}
+	var header map[string]json.RawMessage
+	if err := json.Unmarshal(contents[8:8+headerSize], &header); err != nil {
+		return nil, errors.Wrapf(err, "failed to parse safetensors header")
+	}
+	data := contents[8+headerSize:]
+	weights := make(Weights, len(header))
+	for name, rawInfo := range header {
+		if name == "__metadata__" {
+			continue
+		}
+		var info struct {
+			DType       string `json:"dtype"`
+			Shape       []int  `json:"shape"`
+			DataOffsets [2]int `json:"data_offsets"`
+		}
+		if err := json.Unmarshal(rawInfo, &info); err != nil {
+			return nil, errors.Wrapf(err, "failed to parse header of tensor %q", name)
+		}
+		dtype, found := safetensorsDTypes[info.DType]
+		if !found {
+			return nil, errors.Errorf("tensor %q has unsupported dtype %q", name, info.DType)
+		}
+		begin, end := info.DataOffsets[0], info.DataOffsets[1]
+		if begin < 0 || end < begin || end > len(data) {
+			return nil, errors.Errorf("tensor %q has invalid data offsets [%d, %d]", name, begin, end)
+		}
+		tensor := &Tensor{DType: dtype, Dimensions: info.Shape, Data: data[begin:end]}
+		if err := tensor.checkSize(); err != nil {
+			return nil, errors.WithMessagef(err, "tensor %q", name)
+		}
+		weights[name] = tensor
+	}
+	return weights, nil
+}
+
+// numpyDTypes maps the numpy (little-endian or byte-sized) type descriptions to dtypes.
+var numpyDTypes = map[string]dtypes.DType{
+	"|b1":  dtypes.Bool,
+	"|u1":  dtypes.Uint8,
+	"|i1":  dtypes.Int8,
+	"<u2":  dtypes.Uint16,
+	"<i2":  dtypes.Int16,
+	"<u4":  dtypes.Uint32,
+	"<i4":  dtypes.Int32,
+	"<u8":  dtypes.Uint64,
+	"<i8":  dtypes.Int64,
+	"<f2":  dtypes.Float16,
+	"<f4":  dtypes.Float32,
+	"<f8":  dtypes.Float64,
+	"<c8":  dtypes.Complex64,
+	"<c16": dtypes.Complex128,
+}
+
+var (
+	npyDescrRegex        = regexp.MustCompile(`'descr':\s*'([^']*)'`)
+	npyFortranOrderRegex = regexp.MustCompile(`'fortran_order':\s*(True|False)`)
+	npyShapeRegex        = regexp.MustCompile(`'shape':\s*\(([^)]*)\)`)
+)
+
+// LoadNpz loads all the arrays of a numpy .npz file (as saved by numpy.savez or numpy.savez_compressed).
+// The weights are named after the arrays (the file names in the archive, without the ".npy" extension).
+func LoadNpz(path string) (Weights, error) {
+	archive, err := zip.OpenReader(path)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to open npz file %q", path)
+	}
+	defer func() { _ = archive.Close() }()
+	weights := make(Weights, len(archive.File))
+	for _, file := range archive.File {
+		name := strings.TrimSuffix(file.Name, ".npy")
+		reader, err := file.Open()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to open array %q in npz file %q", name, path)
+		}
+		contents, err := io.ReadAll(reader)
+		_ = reader.Close()
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to read array %q in npz file %q", name, path)
+		}
+		weights[name], err = parseNpy(contents)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "array %q in npz file %q", name, path)
+		}
+	}
+	return weights, nil
+}
+
+// parseNpy parses the contents of a numpy .npy file (https://numpy.org/doc/stable/reference/generated/numpy.lib.format.html).
+func parseNpy(contents []byte) (*Tensor, error) {
+	const magic = "\x93NUMPY"
+	if len(contents) < 10 || !bytes.HasPrefix(contents, []byte(magic)) {
+		return nil, errors.New("invalid npy magic string")
+	}
+	major := contents[6]
+	var headerLen, headerStart int
+	switch major {
+	case 1:
+		headerLen, headerStart = int(binary.LittleEndian.Uint16(contents[8:10])), 10
+	case 2, 3:
+		if len(contents) < 12 {
+			return nil, errors.New("npy file too short")
+		}
+		headerLen, headerStart = int(binary.LittleEndian.Uint32(contents[8:12])), 12
+	default:
+		return nil, errors.Errorf("unsupported npy format version %d", major)
+	}
+	if headerStart+headerLen > len(contents) {
+		return nil, errors.Errorf("invalid npy header length %d", headerLen)
+	}
+	header := string(contents[headerStart : headerStart+headerLen])
+	descr := npyDescrRegex.FindStringSubmatch(header)
+	fortranOrder := npyFortranOrderRegex.FindStringSubmatch(header)
+	shape := npyShapeRegex.FindStringSubmatch(header)
+	if descr == nil || fortranOrder == nil || shape == nil {
+		return nil, errors.Errorf("invalid npy header %q", header)
+	}
+	if fortranOrder[1] == "True" {
+		return nil, errors.New("arrays in Fortran (column-major) order are not supported")
+	}
+	dtype, found := numpyDTypes[descr[1]]
+	if !found {
+		return nil, errors.Errorf("unsupported numpy dtype %q (only little-endian numeric types are supported)", descr[1])
+	}
+	var dimensions []int
+	for _, dimStr := range strings.Split(shape[1], ",") {
+		dimStr = strings.TrimSpace(dimStr)
+		if dimStr == "" {
+			continue
+		}
+		dim, err := strconv.Atoi(dimStr)
+		if err != nil {
+			return nil, errors.Wrapf(err, "invalid npy shape %q", shape[1])
+		}
+		dimensions = append(dimensions, dim)
+	}
+	tensor := &Tensor{DType: dtype, Dimensions: dimensions, Data: contents[headerStart+headerLen:]}
+	if err := tensor.checkSize(); err != nil {
+		return nil, err
+	}
+	return tensor, nil
+}
+
+// checkSize checks that the data size matches the dtype and dimensions.
+func (t *Tensor) checkSize() error {
+	if want := t.DType.SizeForDimensions(t.Dimensions...); len(t.Data) != want {
+		return errors.Errorf("data has %d bytes, but dtype %s with dimensions %v requires %d bytes",
+			len(t.Data), t.DType, t.Dimensions, want)
+	}
+	return nil
+}
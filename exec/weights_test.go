@@ -0,0 +1,117 @@
+package exec
+
+import (
+	"archive/zip"
+	"encoding/binary"
+	"math"
+	"os"
+	"path/filepath"
+	"slices"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+)
+
+// float32Bytes returns the little-endian bytes of the values.
+func float32Bytes(values ...float32) []byte {
+	data := make([]byte, 0, 4*len(values))
+	for _, v := range values {
+		data = binary.LittleEndian.AppendUint32(data, math.Float32bits(v))
+	}
+	return data
+}
+
+func TestLoadSafetensors(t *testing.T) {
+	header := `{"__metadata__":{"format":"pt"},"w":{"dtype":"F32","shape":[2,2],"data_offsets":[0,16]},"b":{"dtype":"I32","shape":[1],"data_offsets":[16,20]}}`
+	contents := binary.LittleEndian.AppendUint64(nil, uint64(len(header)))
+	contents = append(contents, header...)
+	contents = append(contents, float32Bytes(1, 2, 3, 4)...)
+	contents = binary.LittleEndian.AppendUint32(contents, 7)
+	path := filepath.Join(t.TempDir(), "model.safetensors")
+	if err := os.WriteFile(path, contents, 0600); err != nil {
+		t.Fatal(err)
+	}
+	weights, err := LoadWeights(path)
+	if err != nil {
+		t.Fatalf("LoadWeights failed: %+v", err)
+	}
+	if len(weights) != 2 {
+		t.Fatalf("expected 2 weights, got %d", len(weights))
+	}
+	w := weights["w"]
+	if w.DType != dtypes.Float32 || !slices.Equal(w.Dimensions, []int{2, 2}) || !slices.Equal(w.Data, float32Bytes(1, 2, 3, 4)) {
+		t.Errorf("unexpected weight w: %+v", w)
+	}
+	b := weights["b"]
+	if b.DType != dtypes.Int32 || !slices.Equal(b.Dimensions, []int{1}) || binary.LittleEndian.Uint32(b.Data) != 7 {
+		t.Errorf("unexpected weight b: %+v", b)
+	}
+
+	// Invalid data offsets.
+	header = `{"w":{"dtype":"F32","shape":[2,2],"data_offsets":[0,12]}}`
+	contents = binary.LittleEndian.AppendUint64(nil, uint64(len(header)))
+	contents = append(contents, header...)
+	contents = append(contents, float32Bytes(1, 2, 3, 4)...)
+	if _, err := parseSafetensors(contents); err == nil {
+		t.Error("expected error for data size not matching the shape, got nil")
+	}
+}
+
+// npyBytes returns the contents of a version 1.0 .npy file with the given header dictionary and data.
+func npyBytes(header string, data []byte) []byte {
+	contents := []byte("\x93NUMPY\x01\x00")
+	contents = binary.LittleEndian.AppendUint16(contents, uint16(len(header)))
+	contents = append(contents, header...)
+	return append(contents, data...)
+}
+
+func TestLoadNpz(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "model.npz")
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	archive := zip.NewWriter(f)
+	arrays := map[string][]byte{
+		"w.npy":      npyBytes("{'descr': '<f4', 'fortran_order': False, 'shape': (2, 2), }\n", float32Bytes(1, 2, 3, 4)),
+		"scalar.npy": npyBytes("{'descr': '<f4', 'fortran_order': False, 'shape': (), }\n", float32Bytes(5)),
+	}
+	for name, contents := range arrays {
+		writer, err := archive.Create(name)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if _, err = writer.Write(contents); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err = archive.Close(); err != nil {
+		t.Fatal(err)
+	}
+	if err = f.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	weights, err := LoadWeights(path)
+	if err != nil {
+		t.Fatalf("LoadWeights failed: %+v", err)
+	}
+	w := weights["w"]
+	if w == nil || w.DType != dtypes.Float32 || !slices.Equal(w.Dimensions, []int{2, 2}) || !slices.Equal(w.Data, float32Bytes(1, 2, 3, 4)) {
+		t.Errorf("unexpected weight w: %+v", w)
+	}
+	scalar := weights["scalar"]
+	if scalar == nil || len(scalar.Dimensions) != 0 || !slices.Equal(scalar.Data, float32Bytes(5)) {
+		t.Errorf("unexpected weight scalar: %+v", scalar)
+	}
+
+	for _, header := range []string{
+		"{'descr': '>f4', 'fortran_order': False, 'shape': (1,), }\n",
+		"{'descr': '<f4', 'fortran_order': True, 'shape': (1,), }\n",
+		"{'descr': '<f4', 'fortran_order': False, 'shape': (2,), }\n",
+	} {
+		if _, err := parseNpy(npyBytes(header, float32Bytes(1))); err == nil {
+			t.Errorf("expected error for npy header %q, got nil", header)
+		}
+	}
+}
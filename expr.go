@@ -0,0 +1,134 @@
+package stablehlo
+
+import (
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// Expr is a small expression builder that chains operations on a Value, accumulating the first error
+// encountered, which is returned only once at the end by Expr.Value.
+//
+// Example:
+//
+//	y, err := fn.Expr(x).Mul(w).Add(b).Tanh().Value()
+//
+// The standard unary and binary operations are available as methods. For the binary operations, the
+// expression is used as the left-hand side operand. Use Expr.Apply for any other operation.
+//
+// Each method returns a new Expr, so an Expr can be used as a common sub-expression of multiple chains.
+type Expr struct {
+	fn    *Function
+	value *Value
+	err   error
+}
+
+// Expr starts a chain of operations on x. See Expr for details.
+func (fn *Function) Expr(x *Value) *Expr {
+	e := &Expr{fn: fn, value: x}
+	if x == nil {
+		e.err = errors.Errorf("Function.Expr() in function %q: nil value given", fn.Name)
+	} else if x.fn != fn {
+		e.err = errors.Errorf("Function.Expr() in function %q: value %s is from a different function (%q)",
+			fn.Name, x.name, x.fn.Name)
+	}
+	return e
+}
+
+// Value returns the resulting value of the chain of operations, or the first error encountered.
+func (e *Expr) Value() (*Value, error) {
+	if e.err != nil {
+		return nil, e.err
+	}
+	return e.value, nil
+}
+
+// Err returns the first error encountered in the chain of operations, or nil if there were no errors.
+func (e *Expr) Err() error {
+	return e.err
+}
+
+// Shape returns the shape of the current value of the expression, or an invalid shape if there was an error.
+func (e *Expr) Shape() shapes.Shape {
+	if e.err != nil {
+		return shapes.Invalid()
+	}
+	return e.value.shape
+}
+
+// Apply chains an arbitrary operation that takes the current value of the expression and returns a new one.
+//
+// Example:
+//
+//	y, err := fn.Expr(x).Apply(func(v *Value) (*Value, error) { return Reduce(v, zero, sumFn, 0) }).Value()
+func (e *Expr) Apply(opFn func(operand *Value) (*Value, error)) *Expr {
+	if e.err != nil {
+		return e
+	}
+	value, err := opFn(e.value)
+	if err != nil {
+		return &Expr{fn: e.fn, err: err}
+	}
+	return &Expr{fn: e.fn, value: value}
+}
+
+// unaryOp chains a unary operation.
+func (e *Expr) unaryOp(opFn func(operand *Value) (*Value, error)) *Expr {
+	return e.Apply(opFn)
+}
+
+// binaryOp chains a binary operation, with the expression as the left-hand side.
+func (e *Expr) binaryOp(opFn func(lhs, rhs *Value) (*Value, error), rhs *Value) *Expr {
+	if e.err != nil {
+		return e
+	}
+	if rhs == nil {
+		return &Expr{fn: e.fn, err: errors.Errorf("Expr in function %q: nil right-hand side operand", e.fn.Name)}
+	}
+	return e.Apply(func(lhs *Value) (*Value, error) { return opFn(lhs, rhs) })
+}
+
+// Mul is an alias to Expr.Multiply.
+func (e *Expr) Mul(rhs *Value) *Expr {
+	return e.Multiply(rhs)
+}
+
+// Sub is an alias to Expr.Subtract.
+func (e *Expr) Sub(rhs *Value) *Expr {
+	return e.Subtract(rhs)
+}
+
+// Div is an alias to Expr.Divide.
+func (e *Expr) Div(rhs *Value) *Expr {
+	return e.Divide(rhs)
+}
+
+// MulScalar is an alias to Expr.MultiplyScalar.
+func (e *Expr) MulScalar(rhs any) *Expr {
+	return e.MultiplyScalar(rhs)
+}
+
+// SubScalar is an alias to Expr.SubtractScalar.
+func (e *Expr) SubScalar(rhs any) *Expr {
+	return e.SubtractScalar(rhs)
+}
+
+// DivScalar is an alias to Expr.DivideScalar.
+func (e *Expr) DivScalar(rhs any) *Expr {
+	return e.DivideScalar(rhs)
+}
+
+// Convert chains a Convert operation to the given dtype.
+func (e *Expr) Convert(dtype dtypes.DType) *Expr {
+	return e.Apply(func(x *Value) (*Value, error) { return Convert(x, dtype) })
+}
+
+// Reshape chains a Reshape operation to the given dimensions, keeping the dtype.
+func (e *Expr) Reshape(dimensions ...int) *Expr {
+	return e.Apply(func(x *Value) (*Value, error) { return Reshape(x, shapes.Make(x.shape.DType, dimensions...)) })
+}
+
+// Transpose chains a Transpose operation with the given permutation.
+func (e *Expr) Transpose(permutation ...int) *Expr {
+	return e.Apply(func(x *Value) (*Value, error) { return Transpose(x, permutation...) })
+}
@@ -0,0 +1,95 @@
+package stablehlo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestExpr(t *testing.T) {
+	t.Run("chain", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		shape := shapes.Make(dtypes.Float32, 3)
+		x := must(fn.NamedInput("x", shape))
+		y := must(fn.NamedInput("y", shape))
+		z := must(fn.NamedInput("z", shape))
+		result, err := fn.Expr(x).Mul(y).Add(z).Tanh().Value()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if err := fn.Return(result); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestExpr_chain {
+  func.func @main(%x: tensor<3xf32>, %y: tensor<3xf32>, %z: tensor<3xf32>) -> tensor<3xf32> {
+    %0 = "stablehlo.multiply"(%x, %y) : (tensor<3xf32>, tensor<3xf32>) -> tensor<3xf32>
+    %1 = "stablehlo.add"(%0, %z) : (tensor<3xf32>, tensor<3xf32>) -> tensor<3xf32>
+    %2 = "stablehlo.tanh"(%1) : (tensor<3xf32>) -> tensor<3xf32>
+    "stablehlo.return"(%2) : (tensor<3xf32>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+	})
+
+	t.Run("scalars", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Int32, 2)))
+		result, err := fn.Expr(x).MulScalar(3).SubtractScalar(1.0).Value()
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if err := fn.Return(result); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestExpr_scalars {
+  func.func @main(%x: tensor<2xi32>) -> tensor<2xi32> {
+    %0 = "stablehlo.constant"() { value = dense<3> : tensor<i32> } : () -> tensor<i32>
+    %1 = "stablehlo.broadcast_in_dim"(%0) { broadcast_dimensions = array<i64> } : (tensor<i32>) -> tensor<2xi32>
+    %2 = "stablehlo.multiply"(%x, %1) : (tensor<2xi32>, tensor<2xi32>) -> tensor<2xi32>
+    %3 = "stablehlo.constant"() { value = dense<1> : tensor<i32> } : () -> tensor<i32>
+    %4 = "stablehlo.broadcast_in_dim"(%3) { broadcast_dimensions = array<i64> } : (tensor<i32>) -> tensor<2xi32>
+    %5 = "stablehlo.subtract"(%2, %4) : (tensor<2xi32>, tensor<2xi32>) -> tensor<2xi32>
+    "stablehlo.return"(%5) : (tensor<2xi32>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+		if _, err := AndScalar(x, "true"); err == nil {
+			t.Error("expected error for a string scalar, got nil")
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		y := must(fn.NamedInput("y", shapes.Make(dtypes.Float32, 4)))
+		e := fn.Expr(x).Mul(y)
+		if e.Err() == nil {
+			t.Fatal("expected error for mismatched shapes, got nil")
+		}
+		// Further operations are not added, and the first error is kept.
+		numStatements := len(fn.Statements)
+		if _, err := e.Tanh().Add(x).Value(); err != e.Err() {
+			t.Errorf("expected the first error %v, got %v", e.Err(), err)
+		}
+		if len(fn.Statements) != numStatements {
+			t.Errorf("expected no new statements after an error, got %d new ones", len(fn.Statements)-numStatements)
+		}
+	})
+}
@@ -0,0 +1,180 @@
+package stablehlo
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/internal/utils"
+	"github.com/pkg/errors"
+)
+
+// TargetFeatures describes the capabilities of the backend the program is built for.
+//
+// Operations not supported natively by the backend are replaced by a decomposition into other operations,
+// if one is available (see DecomposableOps), and otherwise Builder.Build returns an error.
+type TargetFeatures struct {
+	// Name of the target backend, used in error messages.
+	Name string
+
+	// UnsupportedOps lists the StableHLO operations not supported natively by the backend, by their
+	// StableHLO name (e.g. "stablehlo.tan" or "chlo.erf").
+	UnsupportedOps []string
+
+	// UnsupportedDTypes lists the dtypes not supported by the backend at all: Builder.Build returns an error if
+	// they are used by any function input or operation.
+	UnsupportedDTypes []dtypes.DType
+
+	// UnsupportedOpDTypes lists the dtypes not supported by specific operations, by their StableHLO name:
+	// Builder.Build returns an error if the operation has an input or output of one of the dtypes.
+	UnsupportedOpDTypes map[string][]dtypes.DType
+}
+
+// CPUTargetFeatures returns the profile of the XLA CPU backend, which supports all the operations and dtypes of
+// this package.
+func CPUTargetFeatures() TargetFeatures {
+	return TargetFeatures{Name: "cpu"}
+}
+
+// CUDATargetFeatures returns a conservative profile of the XLA CUDA backend: it rejects the FNUZ float8 variants,
+// used by AMD and Graphcore hardware.
+//
+// Notice Float64 is supported, but very slow on most consumer GPUs: add it to UnsupportedDTypes to catch its
+// accidental use.
+func CUDATargetFeatures() TargetFeatures {
+	return TargetFeatures{
+		Name:              "cuda",
+		UnsupportedDTypes: []dtypes.DType{dtypes.F8E4M3FNUZ, dtypes.F8E5M2FNUZ, dtypes.F8E4M3B11FNUZ},
+	}
+}
+
+// TPUTargetFeatures returns a conservative profile of TPU backends: it rejects Float64 and the complex dtypes,
+// which are not supported by the TPU hardware, and the FNUZ float8 variants other than F8E4M3B11FNUZ.
+func TPUTargetFeatures() TargetFeatures {
+	return TargetFeatures{
+		Name: "tpu",
+		UnsupportedDTypes: []dtypes.DType{dtypes.Float64, dtypes.Complex64, dtypes.Complex128,
+			dtypes.F8E4M3FNUZ, dtypes.F8E5M2FNUZ},
+	}
+}
+
+// WithTargetFeatures configures the capabilities of the backend the program is built for -- e.g. one of the
+// profiles CPUTargetFeatures, CUDATargetFeatures or TPUTargetFeatures.
+//
+// It must be set before the operations are created, since unsupported operations are decomposed when created.
+// The unsupported operations and dtypes left are reported by Builder.Build, instead of failing later when the
+// program is compiled by PJRT.
+func (b *Builder) WithTargetFeatures(features TargetFeatures) *Builder {
+	b.targetFeatures = &features
+	return b
+}
+
+// DecomposableOps returns the StableHLO names of the operations (e.g. "stablehlo.tan" or "chlo.erf") that have a
+// decomposition into other operations, used when the target backend doesn't support them natively.
+// See TargetFeatures.
+func DecomposableOps() []string {
+	names := make([]string, 0, len(decomposableOps))
+	for _, op := range decomposableOps {
+		names = append(names, op.ToStableHLO())
+	}
+	slices.Sort(names)
+	return names
+}
+
+// isSupported returns whether the target backend supports the operation natively.
+func (b *Builder) isSupported(op optypes.OpType) bool {
+	return b.targetFeatures == nil || !slices.Contains(b.targetFeatures.UnsupportedOps, op.ToStableHLO())
+}
+
+// decompositionFor returns the decomposition of the operation if it is not supported by the target backend,
+// or nil if it is supported (or it can't be decomposed).
+func (b *Builder) decompositionFor(op optypes.OpType) func(operand *Value) (*Value, error) {
+	if b.isSupported(op) {
+		return nil
+	}
+	return decomposition(op)
+}
+
+// checkTargetFeatures returns an error if any of the statements of the function uses an operation or dtype not
+// supported by the target backend.
+func (fn *Function) checkTargetFeatures() error {
+	b := fn.Builder
+	if b.targetFeatures == nil {
+		return nil
+	}
+	unsupportedDTypes := b.targetFeatures.UnsupportedDTypes
+	for _, input := range fn.Inputs {
+		if slices.Contains(unsupportedDTypes, input.shape.DType) {
+			return errors.Errorf("dtype %s, used by input %s of function %q, is not supported by the target %q",
+				input.shape.DType, input, fn.Name, b.targetFeatures.Name)
+		}
+	}
+	for _, stmt := range fn.Statements {
+		opName := stmt.OpType.ToStableHLO()
+		if !b.isSupported(stmt.OpType) {
+			return errors.Errorf("operation %s, used in function %q, is not supported by the target %q and has no decomposition",
+				opName, fn.Name, b.targetFeatures.Name)
+		}
+		unsupportedOpDTypes := b.targetFeatures.UnsupportedOpDTypes[opName]
+		for _, value := range slices.Concat(stmt.Inputs, stmt.Outputs) {
+			dtype := value.shape.DType
+			if slices.Contains(unsupportedDTypes, dtype) {
+				return errors.Errorf("dtype %s, used by operation %s in function %q, is not supported by the target %q",
+					dtype, opName, fn.Name, b.targetFeatures.Name)
+			}
+			if slices.Contains(unsupportedOpDTypes, dtype) {
+				return errors.Errorf("operation %s, used in function %q, doesn't support dtype %s in the target %q",
+					opName, fn.Name, dtype, b.targetFeatures.Name)
+			}
+		}
+		for _, closure := range stmt.FunctionParameters {
+			if err := closure.checkTargetFeatures(); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// FeatureSet lists the features used by a program, see Builder.Features.
+type FeatureSet struct {
+	// Ops lists the StableHLO names of the operations used (e.g. "stablehlo.add"), sorted.
+	// Statements created with Function.RawStatement are listed as "raw_snippet".
+	Ops []string
+
+	// DTypes lists the dtypes of the values used (inputs and outputs of the operations), sorted.
+	DTypes []dtypes.DType
+
+	// Attributes lists the names of the operations attributes used (e.g. "comparison_direction"), sorted.
+	Attributes []string
+}
+
+// Features returns the set of operations, dtypes and attributes used by the program (including closures),
+// so deployment tooling can check its compatibility with serving backends.
+//
+// It can be called at any time, usually after Builder.Build.
+func (b *Builder) Features() FeatureSet {
+	ops := utils.MakeSet[string]()
+	dtypesUsed := utils.MakeSet[dtypes.DType]()
+	attributes := utils.MakeSet[string]()
+	for _, fn := range b.functions {
+		for _, input := range fn.Inputs {
+			dtypesUsed.Insert(input.shape.DType)
+		}
+		for _, stmt := range fn.Statements {
+			ops.Insert(stmt.OpType.ToStableHLO())
+			for _, value := range slices.Concat(stmt.Inputs, stmt.Outputs) {
+				dtypesUsed.Insert(value.shape.DType)
+			}
+			for name := range stmt.Attributes {
+				attributes.Insert(name)
+			}
+		}
+	}
+	return FeatureSet{
+		Ops:        slices.Sorted(maps.Keys(ops)),
+		DTypes:     slices.Sorted(maps.Keys(dtypesUsed)),
+		Attributes: slices.Sorted(maps.Keys(attributes)),
+	}
+}
@@ -0,0 +1,149 @@
+package stablehlo
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestTargetFeatures(t *testing.T) {
+	if !slices.Contains(DecomposableOps(), "chlo.erf") {
+		t.Errorf("expected stablehlo.erf in DecomposableOps, got %v", DecomposableOps())
+	}
+
+	t.Run("decompositions", func(t *testing.T) {
+		builder := New(t.Name()).WithTargetFeatures(TargetFeatures{
+			Name:           "test",
+			UnsupportedOps: []string{"chlo.erf", "stablehlo.round_nearest_afz"},
+		})
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		y := must(Erf(x))
+		y = must(RoundNearestAfz(y))
+		y = must(Tanh(y))
+		if err := fn.Return(y); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		for _, unwanted := range []string{"chlo.erf", "stablehlo.round_nearest_afz"} {
+			if strings.Contains(program, unwanted) {
+				t.Errorf("program should not use %q", unwanted)
+			}
+		}
+		for _, wanted := range []string{"stablehlo.floor", "stablehlo.exponential", "stablehlo.tanh"} {
+			if !strings.Contains(program, wanted) {
+				t.Errorf("program should use %q", wanted)
+			}
+		}
+	})
+
+	t.Run("decompositions with accuracy", func(t *testing.T) {
+		builder := New(t.Name()).WithTargetFeatures(TargetFeatures{
+			Name:           "test",
+			UnsupportedOps: []string{"stablehlo.tan"},
+		})
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		if _, err := TanWithAccuracy(x, types.ResultAccuracy{Mode: types.ResultAccuracyHighest}); err == nil {
+			t.Error("expected error setting the result accuracy of a decomposed operation, got nil")
+		}
+		y := must(TanWithAccuracy(x, types.ResultAccuracy{}))
+		if err := fn.Return(y); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		program := string(must(builder.Build()))
+		if strings.Contains(program, "stablehlo.tan") || strings.Contains(program, "result_accuracy") {
+			t.Errorf("program should use the decomposition of stablehlo.tan, without result_accuracy:\n%s", program)
+		}
+	})
+
+	t.Run("unsupported", func(t *testing.T) {
+		builder := New(t.Name()).WithTargetFeatures(TargetFeatures{
+			Name:           "test",
+			UnsupportedOps: []string{"stablehlo.tanh"},
+		})
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		if err := fn.Return(must(Tanh(x))); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if _, err := builder.Build(); err == nil {
+			t.Fatal("expected error building program with unsupported stablehlo.tanh, got nil")
+		}
+	})
+}
+
+func TestFeatures(t *testing.T) {
+	builder := New(t.Name())
+	fn := builder.Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+	y := must(fn.NamedInput("y", shapes.Make(dtypes.Float32, 3)))
+	isLess := must(Compare(x, y, types.CompareLT, types.CompareFloat))
+	if err := fn.Return(must(Select(isLess, must(Add(x, y)), y))); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	features := builder.Features()
+	if want := []string{"stablehlo.add", "stablehlo.compare", "stablehlo.return", "stablehlo.select"}; !slices.Equal(features.Ops, want) {
+		t.Errorf("expected ops %v, got %v", want, features.Ops)
+	}
+	if want := []dtypes.DType{dtypes.Bool, dtypes.Float32}; !slices.Equal(features.DTypes, want) {
+		t.Errorf("expected dtypes %v, got %v", want, features.DTypes)
+	}
+	if want := []string{"compare_type", "comparison_direction"}; !slices.Equal(features.Attributes, want) {
+		t.Errorf("expected attributes %v, got %v", want, features.Attributes)
+	}
+}
+
+func TestTargetProfiles(t *testing.T) {
+	newProgram := func(features TargetFeatures, dtype dtypes.DType) *Builder {
+		builder := New(t.Name()).WithTargetFeatures(features)
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		y := must(Convert(x, dtype))
+		sum := must(Reduce(y, must(fn.scalarConstantOfDType(dtype, 0)),
+			must(fn.NewReductionClosure(ReductionAdd, dtype)), 0))
+		must0(fn.Return(must(Convert(sum, dtypes.Float32))))
+		return builder
+	}
+	for _, tc := range []struct {
+		features TargetFeatures
+		dtype    dtypes.DType
+		wantErr  string
+	}{
+		{CPUTargetFeatures(), dtypes.Float64, ""},
+		{CUDATargetFeatures(), dtypes.Float64, ""},
+		{TPUTargetFeatures(), dtypes.Float64, `dtype Float64, used by operation stablehlo.convert in function "main", is not supported by the target "tpu"`},
+		{TPUTargetFeatures(), dtypes.Complex64, "not supported by the target \"tpu\""},
+		{TPUTargetFeatures(), dtypes.BFloat16, ""},
+		{TargetFeatures{Name: "test", UnsupportedDTypes: []dtypes.DType{dtypes.Float16}}, dtypes.Float16,
+			"not supported by the target \"test\""},
+		{TargetFeatures{
+			Name:                "test",
+			UnsupportedOpDTypes: map[string][]dtypes.DType{"stablehlo.add": {dtypes.Int32}},
+		}, dtypes.Int32, `operation stablehlo.add, used in function "closure0", doesn't support dtype Int32 in the target "test"`},
+	} {
+		_, err := newProgram(tc.features, tc.dtype).Build()
+		if tc.wantErr == "" {
+			if err != nil {
+				t.Errorf("%s with %s: unexpected error: %v", tc.features.Name, tc.dtype, err)
+			}
+		} else if err == nil || !strings.Contains(err.Error(), tc.wantErr) {
+			t.Errorf("%s with %s: expected error containing %q, got %v", tc.features.Name, tc.dtype, tc.wantErr, err)
+		}
+	}
+
+	// Unsupported dtypes of the function inputs.
+	builder := New(t.Name()).WithTargetFeatures(TPUTargetFeatures())
+	fn := builder.Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float64)))
+	must0(fn.Return(x))
+	if _, err := builder.Build(); err == nil || !strings.Contains(err.Error(), "used by input %x") {
+		t.Errorf("expected error for Float64 input, got %v", err)
+	}
+}
@@ -20,6 +20,9 @@ import (
 type Function struct {
 	Builder *Builder
 
+	// id is the unique ID of the function in the Builder, see Function.ID.
+	id int
+
 	// Name of the function. It should not include the "@" prefix.
 	Name string
 
@@ -49,6 +52,38 @@ type Function struct {
 
 	// Returned indicates if the function has a return statement, so it can no longer be changed.
 	Returned bool
+
+	// accumulateErrors indicates the error-accumulating mode, see WithErrorAccumulation.
+	// It is only set in the root function.
+	accumulateErrors bool
+
+	// err is the first error recorded in error-accumulating mode.
+	// It is only set in the root function.
+	err error
+
+	// aliases of inputs to outputs, resolved at Return. See AliasInputToOutput.
+	aliases []inputOutputAlias
+
+	// constantInputs are the constants fed as inputs, see ConstantInputs.
+	constantInputs []ConstantInput
+
+	// debugOutputs are the values appended to the outputs, see DebugPrint.
+	debugOutputs []debugOutput
+
+	// reductionClosures caches the closures created by NewReductionClosure, by operation and dtypes.
+	reductionClosures map[string]*Function
+
+	// private visibility of the function, see SetPrivate.
+	private bool
+
+	// passthroughAttributes of the function, rendered verbatim, see SetPassthroughAttribute.
+	passthroughAttributes map[string]string
+
+	// declaredOutputs are the output shapes checked on Return, see DeclareOutputs.
+	declaredOutputs []shapes.Shape
+
+	// inputsDeclared is set for closures created by ClosureWithSignature, which take no further inputs.
+	inputsDeclared bool
 }
 
 // findRootFn returns the root function of a function tree.
@@ -146,13 +181,22 @@ func (fn *Function) NamedInputWithAttributes(name string, shape shapes.Shape,
 //
 // Names are used in the StableHLO code and may be helpful for debugging, but otherwise have no impact.
 func (fn *Function) NamedInputWithShardingAndAttributes(name string, shape shapes.Shape,
-	shardingSpec *shardy.ShardingSpec, attributes map[string]any) (*Value, error) {
+	shardingSpec *shardy.ShardingSpec, attributes map[string]any) (output *Value, err error) {
+	defer fn.opErrorHandler(&err, &output)()
+	attributes, err = validateInputShape(shape, attributes)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "input %q", name)
+	}
 	value := &Value{
 		fn:         fn,
 		name:       ConvertToValidName(name),
 		shape:      shape,
 		Attributes: attributes,
 	}
+	if fn.inputsDeclared {
+		return nil, errors.Errorf("cannot add input %q to function %q, its inputs were declared with ClosureWithSignature",
+			value.name, fn.Name)
+	}
 	for i, input := range fn.Inputs {
 		if input.name == value.name {
 			return nil, errors.Errorf("duplicate input name %q with input #%d", value.name, i)
@@ -163,13 +207,8 @@ func (fn *Function) NamedInputWithShardingAndAttributes(name string, shape shape
 			value.Attributes = make(map[string]any)
 		}
 		value.Attributes["sdy.sharding"] = literalStr(shardingSpec.ToValueAttribute(value.shape))
-		if slices.Index(fn.Builder.meshes, shardingSpec.Mesh) == -1 {
-			meshesNames := make([]string, len(fn.Builder.meshes))
-			for _, mesh := range fn.Builder.meshes {
-				meshesNames = append(meshesNames, mesh.Name())
-			}
-			return nil, errors.Errorf("sharding spec meshe %q doesn't match any of the stablehlo.Builder meshes (%s)",
-				shardingSpec.Mesh, strings.Join(meshesNames, ", "))
+		if err := fn.Builder.checkShardingSpecMesh(shardingSpec); err != nil {
+			return nil, err
 		}
 		if err := shardingSpec.ValidateShape(shape); err != nil {
 			return nil, err
@@ -179,8 +218,51 @@ func (fn *Function) NamedInputWithShardingAndAttributes(name string, shape shape
 	return value, nil
 }
 
+// MultiInput creates one new input parameter per shape, with default unique names, like Input.
+//
+// It cuts the boilerplate of functions with hundreds of inputs, typical of flattened model weights.
+// If any input fails, none is added.
+func (fn *Function) MultiInput(inputShapes []shapes.Shape) (inputs []*Value, err error) {
+	return fn.addInputs(nil, inputShapes)
+}
+
+// NamedInputs creates one new input parameter per name and shape, like NamedInput.
+// The names must be unique, and there must be one per shape.
+//
+// If any input fails, none is added.
+func (fn *Function) NamedInputs(names []string, inputShapes []shapes.Shape) (inputs []*Value, err error) {
+	if len(names) != len(inputShapes) {
+		defer fn.multiOpErrorHandler(&err, &inputs, len(inputShapes))()
+		return nil, errors.Errorf("NamedInputs requires one name per shape, got %d names and %d shapes",
+			len(names), len(inputShapes))
+	}
+	return fn.addInputs(names, inputShapes)
+}
+
+// addInputs implements MultiInput (names == nil) and NamedInputs.
+func (fn *Function) addInputs(names []string, inputShapes []shapes.Shape) ([]*Value, error) {
+	rootFn := fn.findRootFn()
+	numInputs, nextArgID := len(fn.Inputs), rootFn.nextArgID
+	inputs := make([]*Value, len(inputShapes))
+	for i, shape := range inputShapes {
+		var err error
+		if names == nil {
+			inputs[i], err = fn.Input(shape)
+		} else {
+			inputs[i], err = fn.NamedInput(names[i], shape)
+		}
+		if err != nil {
+			fn.Inputs = fn.Inputs[:numInputs]
+			rootFn.nextArgID = nextArgID
+			return nil, errors.WithMessagef(err, "input #%d", i)
+		}
+	}
+	return inputs, nil
+}
+
 // ConstantFromScalar creates a new constant statement and returns the resulting value.
-func (fn *Function) ConstantFromScalar(value any) (*Value, error) {
+func (fn *Function) ConstantFromScalar(value any) (output *Value, err error) {
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("Function.Return already called for %q", fn.Name)
 	}
@@ -197,6 +279,7 @@ func (fn *Function) ConstantFromScalar(value any) (*Value, error) {
 	}
 	c := &Statement{
 		Builder:  fn.Builder,
+		id:       fn.Builder.newStatementID(),
 		Function: fn,
 		OpType:   optypes.Constant,
 		Attributes: map[string]any{
@@ -208,8 +291,36 @@ func (fn *Function) ConstantFromScalar(value any) (*Value, error) {
 	return c.Outputs[0], nil
 }
 
+// ConstantFromScalarOfDType creates a new scalar constant statement of the given dtype, with the value converted
+// to it, and returns the resulting value. Unlike ConstantFromScalar, the dtype is not inferred from the Go type of
+// the value, so it supports the dtypes without a Go type (the f8 floats and the sub-byte integers).
+//
+// The conversion rules, shared with FullLike, WithScalar, ConstantZeros and ConstantOnes, are:
+//
+//   - Floats: the value is rounded to the nearest representable value (ties to even). Values too large become
+//     infinities, or NaN for the dtypes without infinities (e.g. F8E4M3FN). For the unsigned F8E8M0FNU,
+//     non-positive values become NaN.
+//   - Integers: the value is truncated towards zero and saturated to the range of the dtype. NaN becomes 0.
+//   - Bool: true if the value is not 0.
+//   - Complex: the value is the real part, the imaginary part is 0.
+func (fn *Function) ConstantFromScalarOfDType(dtype dtypes.DType, value float64) (output *Value, err error) {
+	defer fn.opErrorHandler(&err, &output)()
+	if fn.Returned {
+		return nil, errors.Errorf("Function.Return already called for %q", fn.Name)
+	}
+	output, err = fn.scalarConstantOfDType(dtype, value)
+	if err != nil {
+		return nil, errors.WithMessage(err, "ConstantFromScalarOfDType")
+	}
+	return output, nil
+}
+
 // ConstantFromFlatAndDimensions creates a new constant statement from a flat slice with the raw values and the dimensions of the shape.
-func (fn *Function) ConstantFromFlatAndDimensions(flat any, dimensions ...int) (*Value, error) {
+//
+// If the builder is configured with Builder.WithLargeConstantsAsInputs, large constants are converted to
+// inputs of the function instead (see Function.ConstantAsInput).
+func (fn *Function) ConstantFromFlatAndDimensions(flat any, dimensions ...int) (output *Value, err error) {
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("Function.Return already called for %q", fn.Name)
 	}
@@ -219,17 +330,23 @@ func (fn *Function) ConstantFromFlatAndDimensions(flat any, dimensions ...int) (
 		return nil, errors.Errorf("unsupported constant flat values type %T -- expected a slice of a basic data type", flat)
 	}
 	shape := shapes.Make(dtype, dimensions...)
+	if err := shape.CheckSize(); err != nil {
+		return nil, err
+	}
 	if shape.Size() != flatV.Len() {
 		return nil, errors.Errorf("flat values size %d doesn't match shape size %d (%s)", flatV.Len(), shape.Size(), shape)
 	}
+	if fn.largeConstantAsInput(shape) {
+		return fn.ConstantAsInput(fn.nextConstantInputName(), flat, dimensions...)
+	}
 	c := &Statement{
 		Builder:    fn.Builder,
+		id:         fn.Builder.newStatementID(),
 		Function:   fn,
 		OpType:     optypes.Constant,
 		Attributes: make(map[string]any, 1),
 		Outputs:    []*Value{fn.newValue(shape)},
 	}
-	var err error
 	if shape.IsScalar() {
 		c.Attributes["value"], err = newTensorLiteralFromFlatAndDimensions(flatV.Index(0).Interface())
 	} else {
@@ -242,6 +359,88 @@ func (fn *Function) ConstantFromFlatAndDimensions(flat any, dimensions ...int) (
 	return c.Outputs[0], nil
 }
 
+// ConstantFromTensor creates a constant from a strided view of data, e.g. a sub-matrix of a larger matrix, or the
+// backing slice of a gonum matrix (with its stride) or of another tensor library, without requiring the caller to
+// materialize a contiguous flat slice.
+//
+// The element at indices (i_0, ..., i_{rank-1}) is data[i_0*strides[0] + ... + i_{rank-1}*strides[rank-1]]: the
+// strides are given in number of elements (not bytes), there must be one per axis, and they must be non-negative
+// (a zero stride repeats the same elements along the axis). If strides is nil, data is read contiguously in row-major
+// order, as in ConstantFromFlatAndDimensions, except that data can have extra elements at the end.
+// To start the view at some offset, pass the sub-slice data[offset:].
+//
+// It returns an error if the view reaches beyond the end of data.
+func (fn *Function) ConstantFromTensor(data any, dims, strides []int) (output *Value, err error) {
+	defer fn.opErrorHandler(&err, &output)()
+	dataV := reflect.ValueOf(data)
+	if dataV.Kind() != reflect.Slice {
+		return nil, errors.Errorf("ConstantFromTensor requires a slice as data, got %T", data)
+	}
+	dtype := dtypes.FromGoType(dataV.Type().Elem())
+	if dtype == dtypes.INVALID {
+		return nil, errors.Errorf("unsupported constant data type %T -- expected a slice of a basic data type", data)
+	}
+	for axis, dim := range dims {
+		if dim < 0 {
+			return nil, errors.Errorf("ConstantFromTensor: invalid negative dimension %d for axis %d", dim, axis)
+		}
+	}
+	shape := shapes.Make(dtype, dims...)
+	if err := shape.CheckSize(); err != nil {
+		return nil, err
+	}
+	if strides == nil {
+		strides = make([]int, len(dims))
+		stride := 1
+		for axis := len(dims) - 1; axis >= 0; axis-- {
+			strides[axis] = stride
+			stride *= dims[axis]
+		}
+	}
+	if len(strides) != len(dims) {
+		return nil, errors.Errorf("ConstantFromTensor: got %d strides for %d dimensions", len(strides), len(dims))
+	}
+
+	// Check that the last element of the view is within data, taking care of overflows.
+	size := shape.Size()
+	if size > 0 && dataV.Len() == 0 {
+		return nil, errors.Errorf("ConstantFromTensor: view with dimensions %v has %d elements, but data is empty",
+			dims, size)
+	}
+	if size > 0 {
+		// lastIdx is always within data, so dataV.Len()-1-lastIdx below is never negative.
+		lastIdx := 0
+		for axis, stride := range strides {
+			if stride < 0 {
+				return nil, errors.Errorf("ConstantFromTensor: invalid negative stride %d for axis %d", stride, axis)
+			}
+			if stride > 0 && dims[axis]-1 > (dataV.Len()-1-lastIdx)/stride {
+				return nil, errors.Errorf("ConstantFromTensor: view with dimensions %v and strides %v reaches beyond "+
+					"the end of data with %d elements", dims, strides, dataV.Len())
+			}
+			lastIdx += (dims[axis] - 1) * stride
+		}
+	}
+
+	// Copy the view in row-major order.
+	flat := reflect.MakeSlice(dataV.Type(), size, size)
+	indices := make([]int, len(dims))
+	dataIdx := 0
+	for flatIdx := range size {
+		flat.Index(flatIdx).Set(dataV.Index(dataIdx))
+		for axis := len(dims) - 1; axis >= 0; axis-- {
+			indices[axis]++
+			dataIdx += strides[axis]
+			if indices[axis] < dims[axis] {
+				break
+			}
+			dataIdx -= indices[axis] * strides[axis]
+			indices[axis] = 0
+		}
+	}
+	return fn.ConstantFromFlatAndDimensions(flat.Interface(), dims...)
+}
+
 // Return adds a return statement to the function with the given return values.
 // There must be at least one return value.
 //
@@ -287,7 +486,12 @@ func (fn *Function) ReturnWithShardingAndAttributes(values []*Value, shardingSpe
 }
 
 // ReturnWithAttributes adds a return statement to the function with the given return values and attributes.
+//
+// In error-accumulating mode (see WithErrorAccumulation), it returns the first error recorded, if any.
 func (fn *Function) ReturnWithAttributes(values []*Value, attributes []map[string]any) error {
+	if err := fn.Err(); err != nil {
+		return err
+	}
 	if fn.Returned {
 		return errors.Errorf("Function.Return already called for %q", fn.Name)
 	}
@@ -299,6 +503,21 @@ func (fn *Function) ReturnWithAttributes(values []*Value, attributes []map[strin
 			"if attributes is defined (!=nil) Function.ReturnWithAttributes requires the same number of "+
 				"values and attributes, got %d and %d", len(values), len(attributes))
 	}
+	if err := fn.checkDeclaredOutputs(values); err != nil {
+		return err
+	}
+	if err := fn.resolveAliases(values); err != nil {
+		return err
+	}
+	if len(fn.debugOutputs) > 0 {
+		values, attributes = slices.Clone(values), slices.Clone(attributes)
+		for _, debug := range fn.debugOutputs {
+			values = append(values, debug.value)
+			if len(attributes) > 0 {
+				attributes = append(attributes, nil)
+			}
+		}
+	}
 	fn.Returned = true
 	outputValues := make([]*Value, len(values))
 	for i, value := range values {
@@ -317,28 +536,126 @@ func (fn *Function) ReturnWithAttributes(values []*Value, attributes []map[strin
 	fn.Outputs = outputValues
 	stmt := &Statement{
 		Builder:  fn.Builder,
+		id:       fn.Builder.newStatementID(),
 		Function: fn,
 		OpType:   optypes.FuncReturn,
 		Inputs:   values,
 	}
+	stmt.registerUses()
 	fn.Statements = append(fn.Statements, stmt)
 	return nil
 }
 
+// DeclareOutputs declares the shapes of the outputs of the function, checked when it returns: Return fails if the
+// values returned don't match them, instead of the error surfacing later, where the function is used (e.g. a
+// closure passed to an operation).
+//
+// It must be called before Return.
+func (fn *Function) DeclareOutputs(outputShapes ...shapes.Shape) error {
+	if fn.Returned {
+		return errors.Errorf("cannot declare the outputs of function %q after returning", fn.Name)
+	}
+	if len(outputShapes) == 0 {
+		return errors.Errorf("DeclareOutputs requires at least one output shape, in function %q", fn.Name)
+	}
+	for i, shape := range outputShapes {
+		if !shape.Ok() || shape.IsTuple() {
+			return errors.Errorf("DeclareOutputs got invalid shape %s for output #%d, in function %q", shape, i, fn.Name)
+		}
+	}
+	fn.declaredOutputs = slices.Clone(outputShapes)
+	return nil
+}
+
+// OutputShapes returns the shapes of the outputs of the function: the shapes of the values returned if it has
+// already returned, or otherwise the shapes declared with DeclareOutputs (nil if they were not declared).
+//
+// The operations taking closures (Reduce, While, Scatter, etc.) use it to validate them, so a closure whose outputs
+// are declared can be used before its body is written, see ClosureWithSignature.
+func (fn *Function) OutputShapes() []shapes.Shape {
+	if fn.Returned {
+		return valuesToShapes(fn.Outputs)
+	}
+	return slices.Clone(fn.declaredOutputs)
+}
+
+// checkDeclaredOutputs checks the values returned against the outputs declared with DeclareOutputs, if any.
+func (fn *Function) checkDeclaredOutputs(values []*Value) error {
+	if fn.declaredOutputs == nil {
+		return nil
+	}
+	if len(values) != len(fn.declaredOutputs) {
+		return errors.Errorf("function %q was declared with %d outputs, but %d values were returned",
+			fn.Name, len(fn.declaredOutputs), len(values))
+	}
+	for i, value := range values {
+		if !value.shape.Equal(fn.declaredOutputs[i]) {
+			return errors.Errorf("function %q was declared with output #%d of shape %s, but a value of shape %s was returned",
+				fn.Name, i, fn.declaredOutputs[i], value.shape)
+		}
+	}
+	return nil
+}
+
 // Iota creates a constant of the given shape with increasing numbers (starting from 0)
 // on the given axis. So Iota([2,2], 1) returns [[0 1][0 1]], while Iota([2,2], 0)
 // returns [[0 0][1 1]].
-func (fn *Function) Iota(shape shapes.Shape, axis int) (*Value, error) {
+func (fn *Function) Iota(shape shapes.Shape, axis int) (output *Value, err error) {
+	defer fn.opErrorHandler(&err, &output)()
 	op := optypes.Iota
 	adjustedAxis, err := shapeinference.AdjustAxisToRank(axis, shape.Rank())
 	if err != nil {
 		return nil, errors.WithMessagef(err, "Iota axis is invalid for shape %s", shape)
 	}
+	if err := shape.CheckSize(); err != nil {
+		return nil, err
+	}
 	stmt := fn.addOp(op, shape)
 	stmt.Attributes = map[string]any{"iota_dimension": int64(adjustedAxis)}
 	return stmt.Outputs[0], nil
 }
 
+// DynamicIota is like Iota, but the dimensions of the output are given at runtime by outputShape, a 1D integer
+// value with one element per axis, for bounded dynamic pipelines.
+//
+// The shape gives the dtype and the upper-bounds of the output: its dynamic axes (shapes.DynamicDim) must have a
+// bound set (see shapes.Shape.WithBounds), and its static axes are fixed. E.g., for a sequence of up to 512
+// elements: shapes.Make(dtypes.Int32, shapes.DynamicDim).WithBounds(512).
+//
+// If outputShape is a constant, its values are checked against the shape. Otherwise, they must be within the
+// bounds at runtime.
+func (fn *Function) DynamicIota(outputShape *Value, shape shapes.Shape, axis int) (output *Value, err error) {
+	defer fn.opErrorHandler(&err, &output)()
+	op := optypes.DynamicIota
+	if fn.Returned {
+		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
+			op, fn.Name)
+	}
+	if outputShape.fn != fn {
+		return nil, errors.Errorf("cannot add operation %s to function %q, because outputShape is from a different function (%q)",
+			op, fn.Name, outputShape.fn.Name)
+	}
+	adjustedAxis, err := shapeinference.AdjustAxisToRank(axis, shape.Rank())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "DynamicIota axis is invalid for shape %s", shape)
+	}
+	outputShapeInferred, err := shapeinference.DynamicIota(outputShape.shape, shape, adjustedAxis)
+	if err != nil {
+		return nil, err
+	}
+	if dims, ok := constantInts(outputShape); ok {
+		for ii, dim := range dims {
+			if bound := shape.Bound(ii); dim < 0 || dim > bound || (shape.Dimensions[ii] != shapes.DynamicDim && dim != bound) {
+				return nil, errors.Errorf("DynamicIota: outputShape %v is not compatible with shape %s on axis %d",
+					dims, shape, ii)
+			}
+		}
+	}
+	stmt := fn.addOp(op, outputShapeInferred, outputShape)
+	stmt.Attributes = map[string]any{"iota_dimension": int64(adjustedAxis)}
+	return stmt.Outputs[0], nil
+}
+
 // Closure creates an unnamed closure function that can be used as an argument to operations like
 // Reduce, ReduceWindow, ScatterAndUpdate, etc.
 //
@@ -357,8 +674,35 @@ func (fn *Function) Closure() *Function {
 	return closureFn
 }
 
+// ClosureWithSignature creates a closure (see Closure) with the given input shapes and declared output shapes (see
+// DeclareOutputs), and returns it along with its inputs.
+//
+// Since its signature is known, it can be passed to the operations that consume it (Reduce, While, Scatter, etc.)
+// before its body is written: they validate it against its signature, enabling top-down construction of programs.
+// The body is then written with the returned inputs, and checked against the declared outputs on Return.
+// No further inputs can be added to the closure, and it must be returned before the program is built.
+func (fn *Function) ClosureWithSignature(inputShapes []shapes.Shape, outputShapes ...shapes.Shape) (*Function, []*Value, error) {
+	closure := fn.Closure()
+	if err := closure.DeclareOutputs(outputShapes...); err != nil {
+		return nil, nil, err
+	}
+	inputs := make([]*Value, len(inputShapes))
+	for i, shape := range inputShapes {
+		input, err := closure.Input(shape)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "ClosureWithSignature input #%d", i)
+		}
+		inputs[i] = input
+	}
+	closure.inputsDeclared = true
+	return closure, inputs, nil
+}
+
 // Write the function as StableHLO code, with the given indentation.
 func (fn *Function) Write(writer io.Writer, indentation string) error {
+	if fn.Builder.genericForm && fn.Parent == nil {
+		return fn.writeGeneric(writer, indentation)
+	}
 	// Create the formatting w() and we() internal functions to facilitate handling error while generating the statement code.
 	var err error
 	w := func(format string, args ...any) {
@@ -383,7 +727,11 @@ func (fn *Function) Write(writer io.Writer, indentation string) error {
 	normalFunction := fn.Parent == nil
 	isClosure := fn.Parent != nil
 	if normalFunction {
-		w("%sfunc.func @%s(", indentation, fn.Name)
+		w("%sfunc.func ", indentation)
+		if fn.private {
+			w("private ")
+		}
+		w("@%s(", fn.Name)
 	} else if isClosure {
 		w("(")
 	}
@@ -408,12 +756,15 @@ func (fn *Function) Write(writer io.Writer, indentation string) error {
 			if i > 0 {
 				w(", ")
 			}
-			w(output.shape.ToStableHLO())
+			w("%s", output.shape.ToStableHLO())
 			writeAttributes(writer, indentation, output.Attributes, w)
 		}
 		if encloseOutputInParenthesis {
 			w(")")
 		}
+		if attrs := passthroughAttributesList(fn.passthroughAttributes); len(attrs) > 0 {
+			w(" attributes {%s}", strings.Join(attrs, ", "))
+		}
 		w(" {\n")
 	}
 
@@ -427,3 +778,76 @@ func (fn *Function) Write(writer io.Writer, indentation string) error {
 	}
 	return err
 }
+
+// verifyRegions implements Builder.Verify for the function, and recursively for the closures of its statements.
+// The location describes the function in the error messages, e.g. `function "main", statement #3 (stablehlo.reduce),
+// region #0 "body"`: it's only called to build an error, so verifying valid programs doesn't format any strings.
+func (fn *Function) verifyRegions(location func() string) error {
+	stmtLocation := func(stmtIdx int, stmt *Statement) string {
+		return fmt.Sprintf("%s, statement #%d (%s)", location(), stmtIdx, stmt.OpName())
+	}
+	for stmtIdx, stmt := range fn.Statements {
+		if stmt.Function != fn {
+			return errors.Errorf("%s: the statement belongs to another function", stmtLocation(stmtIdx, stmt))
+		}
+		for i, input := range stmt.Inputs {
+			if input.fn == fn {
+				continue
+			}
+			if input.fn == nil {
+				return errors.Errorf("%s: operand #%d (%s) doesn't belong to any function",
+					stmtLocation(stmtIdx, stmt), i, input)
+			}
+			return errors.Errorf("%s: operand #%d (%s) is a value of function %q, operands must be values of the "+
+				"same function (closures can't reference values of their parent)",
+				stmtLocation(stmtIdx, stmt), i, input, input.fn.Name)
+		}
+		for i, closure := range stmt.FunctionParameters {
+			regionLocation := func() string {
+				if i < len(stmt.FunctionParametersNames) {
+					return fmt.Sprintf("%s, region #%d %q", stmtLocation(stmtIdx, stmt), i, stmt.FunctionParametersNames[i])
+				}
+				return fmt.Sprintf("%s, region #%d", stmtLocation(stmtIdx, stmt), i)
+			}
+			if closure.Parent != fn {
+				return errors.Errorf("%s: closure %q is not a closure of function %q", regionLocation(), closure.Name, fn.Name)
+			}
+			if err := closure.verifyRegions(regionLocation); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// checkMandatoryAttributes returns an error if any statement of the function is missing one of the mandatory
+// attributes of its operation (see optypes.OpType.MandatoryAttributes), which would render an invalid program.
+func (fn *Function) checkMandatoryAttributes() error {
+	for _, stmt := range fn.Statements {
+		for _, name := range stmt.OpType.MandatoryAttributes() {
+			if _, found := stmt.Attributes[name]; found {
+				continue
+			}
+			if len(stmt.Outputs) > 0 {
+				return errors.Errorf("%s (output %s), in function %q, is missing the mandatory attribute %q",
+					stmt.OpName(), stmt.Outputs[0], fn.Name, name)
+			}
+			return errors.Errorf("%s, in function %q, is missing the mandatory attribute %q",
+				stmt.OpName(), fn.Name, name)
+		}
+	}
+	return nil
+}
+
+// checkShapeSizes returns an error if any value of the function has a shape too large for the platform,
+// see shapes.Shape.CheckSize.
+func (fn *Function) checkShapeSizes() error {
+	for _, stmt := range fn.Statements {
+		for _, output := range stmt.Outputs {
+			if err := output.shape.CheckSize(); err != nil {
+				return errors.WithMessagef(err, "output %s of %s, in function %q", output, stmt.OpName(), fn.Name)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,234 @@
+package stablehlo
+
+import (
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+// GatherToSlices returns a rule for Function.Rewrite that replaces the Gather operations whose start indices are
+// constants by static Slice operations, concatenated (and reshaped and transposed as needed) into the Gather output.
+//
+// Backends compile the static slices faster, and execute them with less overhead, which is useful for instance for
+// embedding lookups with constant indices.
+//
+// Only Gather operations with at most maxSlices slices (one per start index) are rewritten, since each slice
+// becomes a separate operation. Gather operations with batching axes are not rewritten.
+//
+// Example:
+//
+//	numRewrites, err := fn.Rewrite(GatherToSlices(16))
+func GatherToSlices(maxSlices int) *RewriteRule {
+	return &RewriteRule{
+		Name: "gather_to_slices",
+		Op:   optypes.Gather.ToStableHLO(),
+		Predicate: func(match *Statement) bool {
+			_, _, ok := gatherConstantStarts(match, maxSlices)
+			return ok
+		},
+		Replace: func(fn *Function, match *Statement) ([]*Value, error) {
+			config, starts, _ := gatherConstantStarts(match, maxSlices)
+			operand, indices := match.Inputs[0], match.Inputs[1]
+			dtype := operand.shape.DType
+			var offsetDims []int
+			for axis, size := range config.sliceSizes {
+				if !slices.Contains(config.collapsedSliceAxes, axis) {
+					offsetDims = append(offsetDims, size)
+				}
+			}
+			pieces := make([]*Value, len(starts))
+			for i, start := range starts {
+				limits := make([]int, len(start))
+				for axis := range start {
+					limits[axis] = start[axis] + config.sliceSizes[axis]
+				}
+				piece, err := Slice(operand, start, limits, nil)
+				if err != nil {
+					return nil, err
+				}
+				if shape := shapes.Make(dtype, slices.Concat([]int{1}, offsetDims)...); !piece.shape.Equal(shape) {
+					piece, err = Reshape(piece, shape)
+					if err != nil {
+						return nil, err
+					}
+				}
+				pieces[i] = piece
+			}
+			output, err := Concatenate(0, pieces...)
+			if err != nil {
+				return nil, err
+			}
+
+			// Reshape to the batch axes (the start indices axes other than the index vector axis) followed by the
+			// offset axes, and transpose the offset axes to their position in the output.
+			batchDims := slices.Clone(indices.shape.Dimensions)
+			if config.indexVectorAxis < len(batchDims) {
+				batchDims = slices.Delete(batchDims, config.indexVectorAxis, config.indexVectorAxis+1)
+			}
+			if shape := shapes.Make(dtype, slices.Concat(batchDims, offsetDims)...); !output.shape.Equal(shape) {
+				output, err = Reshape(output, shape)
+				if err != nil {
+					return nil, err
+				}
+			}
+			permutation := make([]int, len(batchDims)+len(offsetDims))
+			nextBatchAxis := 0
+			for axis := range permutation {
+				if k := slices.Index(config.offsetOutputAxes, axis); k >= 0 {
+					permutation[axis] = len(batchDims) + k
+				} else {
+					permutation[axis] = nextBatchAxis
+					nextBatchAxis++
+				}
+			}
+			for axis, source := range permutation {
+				if axis != source {
+					output, err = Transpose(output, permutation...)
+					break
+				}
+			}
+			if err != nil {
+				return nil, err
+			}
+			return []*Value{output}, nil
+		},
+	}
+}
+
+// gatherConfig holds the parameters of a Gather statement, parsed from its attributes.
+type gatherConfig struct {
+	indexVectorAxis                                                 int
+	offsetOutputAxes, collapsedSliceAxes, startIndexMap, sliceSizes []int
+	hasBatchingAxes                                                 bool
+}
+
+var (
+	gatherListAttributeRegexp = regexp.MustCompile(`(\w+) = \[([^]]*)]`)
+	gatherIndexVectorRegexp   = regexp.MustCompile(`index_vector_dim = (\d+)`)
+)
+
+// parseGatherConfig parses the attributes of a Gather statement, as generated by Gather. It returns false if they
+// can't be parsed.
+func parseGatherConfig(stmt *Statement) (config gatherConfig, ok bool) {
+	dimensionNumbers, ok1 := stmt.Attributes["dimension_numbers"].(literalStr)
+	sliceSizes, ok2 := stmt.Attributes["slice_sizes"].(literalStr)
+	if !ok1 || !ok2 {
+		return config, false
+	}
+	lists := make(map[string][]int)
+	for _, match := range gatherListAttributeRegexp.FindAllStringSubmatch(string(dimensionNumbers), -1) {
+		if lists[match[1]], ok = parseIntList(match[2]); !ok {
+			return config, false
+		}
+	}
+	indexVectorMatch := gatherIndexVectorRegexp.FindStringSubmatch(string(dimensionNumbers))
+	if indexVectorMatch == nil {
+		return config, false
+	}
+	config.indexVectorAxis, _ = strconv.Atoi(indexVectorMatch[1])
+	config.offsetOutputAxes = lists["offset_dims"]
+	config.collapsedSliceAxes = lists["collapsed_slice_dims"]
+	config.startIndexMap = lists["start_index_map"]
+	config.hasBatchingAxes = len(lists["operand_batching_dims"]) > 0 || len(lists["start_indices_batching_dims"]) > 0
+	sizes, found := strings.CutPrefix(string(sliceSizes), "array<i64")
+	if !found {
+		return config, false
+	}
+	sizes = strings.TrimPrefix(strings.TrimSuffix(sizes, ">"), ":")
+	config.sliceSizes, ok = parseIntList(sizes)
+	return config, ok
+}
+
+// parseIntList parses a comma-separated list of integers, possibly empty.
+func parseIntList(list string) ([]int, bool) {
+	var ints []int
+	for part := range strings.SplitSeq(list, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		value, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, false
+		}
+		ints = append(ints, value)
+	}
+	return ints, true
+}
+
+// gatherConstantStarts returns the configuration of a Gather statement whose start indices are a constant, and the
+// operand start of each of its slices, clamped as in the specification, in the row-major order of the batch axes
+// of the start indices.
+//
+// It returns false if the statement can't be converted to at most maxSlices static slices.
+func gatherConstantStarts(stmt *Statement, maxSlices int) (config gatherConfig, starts [][]int, ok bool) {
+	if len(stmt.Inputs) != 2 {
+		return config, nil, false
+	}
+	operand, indices := stmt.Inputs[0], stmt.Inputs[1]
+	if operand.shape.IsDynamic() || indices.shape.IsDynamic() {
+		return config, nil, false
+	}
+	config, ok = parseGatherConfig(stmt)
+	if !ok || config.hasBatchingAxes || len(config.sliceSizes) != operand.shape.Rank() {
+		return config, nil, false
+	}
+	flat, ok := constantInts(indices)
+	if !ok {
+		return config, nil, false
+	}
+	indicesDims := indices.shape.Dimensions
+	indexVectorSize := 1
+	if config.indexVectorAxis < len(indicesDims) {
+		indexVectorSize = indicesDims[config.indexVectorAxis]
+	}
+	if indexVectorSize != len(config.startIndexMap) {
+		return config, nil, false
+	}
+	numSlices := indices.shape.Size() / max(indexVectorSize, 1)
+	if numSlices == 0 || numSlices > maxSlices || len(flat) != indices.shape.Size() {
+		return config, nil, false
+	}
+
+	// Row-major strides of the start indices, and the position of the current slice in them.
+	strides := make([]int, len(indicesDims))
+	stride := 1
+	for axis := len(indicesDims) - 1; axis >= 0; axis-- {
+		strides[axis] = stride
+		stride *= indicesDims[axis]
+	}
+	position := make([]int, len(indicesDims))
+	starts = make([][]int, numSlices)
+	for i := range starts {
+		start := make([]int, operand.shape.Rank())
+		for k, operandAxis := range config.startIndexMap {
+			flatIdx := 0
+			for axis, idx := range position {
+				if axis == config.indexVectorAxis {
+					idx = k
+				}
+				flatIdx += idx * strides[axis]
+			}
+			maxStart := operand.shape.Dimensions[operandAxis] - config.sliceSizes[operandAxis]
+			start[operandAxis] = min(max(flat[flatIdx], 0), maxStart)
+		}
+		starts[i] = start
+
+		// Next position, skipping the index vector axis.
+		for axis := len(position) - 1; axis >= 0; axis-- {
+			if axis == config.indexVectorAxis {
+				continue
+			}
+			position[axis]++
+			if position[axis] < indicesDims[axis] {
+				break
+			}
+			position[axis] = 0
+		}
+	}
+	return config, starts, true
+}
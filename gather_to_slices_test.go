@@ -0,0 +1,83 @@
+package stablehlo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestGatherToSlices(t *testing.T) {
+	// gatherRows gathers rows of x with the given indices (of shape [numIndices, 1]); the offset (row) axis of the
+	// output is offsetOutputAxis.
+	gatherRows := func(x, indices *Value, offsetOutputAxis int) *Value {
+		return must(Gather(x, indices, 1, []int{offsetOutputAxis}, []int{0}, nil, nil, []int{0},
+			[]int{1, x.Shape().Dimensions[1]}, false))
+	}
+
+	t.Run("rewrite", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3, 5)))
+		indices := must(fn.ConstantFromFlatAndDimensions([]int32{1, 7}, 2, 1)) // 7 is clamped to 2.
+		y0 := gatherRows(x, indices, 1)
+		y1 := gatherRows(x, indices, 0) // Output with the offset axis first: [5, 2].
+		must0(fn.Return(y0, y1))
+		if numRewrites := must(fn.Rewrite(GatherToSlices(2))); numRewrites != 2 {
+			t.Errorf("expected 2 rewrites, got %d", numRewrites)
+		}
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestGatherToSlices_rewrite {
+  func.func @main(%x: tensor<3x5xf32>) -> (tensor<2x5xf32>, tensor<5x2xf32>) {
+    %3 = "stablehlo.slice"(%x) {
+      limit_indices = array<i64: 2, 5>,
+      start_indices = array<i64: 1, 0>,
+      strides = array<i64: 1, 1>
+    } : (tensor<3x5xf32>) -> tensor<1x5xf32>
+    %4 = "stablehlo.slice"(%x) {
+      limit_indices = array<i64: 3, 5>,
+      start_indices = array<i64: 2, 0>,
+      strides = array<i64: 1, 1>
+    } : (tensor<3x5xf32>) -> tensor<1x5xf32>
+    %5 = "stablehlo.concatenate"(%3, %4) { dimension = 0 : i64 } : (tensor<1x5xf32>, tensor<1x5xf32>) -> tensor<2x5xf32>
+    %6 = "stablehlo.slice"(%x) {
+      limit_indices = array<i64: 2, 5>,
+      start_indices = array<i64: 1, 0>,
+      strides = array<i64: 1, 1>
+    } : (tensor<3x5xf32>) -> tensor<1x5xf32>
+    %7 = "stablehlo.slice"(%x) {
+      limit_indices = array<i64: 3, 5>,
+      start_indices = array<i64: 2, 0>,
+      strides = array<i64: 1, 1>
+    } : (tensor<3x5xf32>) -> tensor<1x5xf32>
+    %8 = "stablehlo.concatenate"(%6, %7) { dimension = 0 : i64 } : (tensor<1x5xf32>, tensor<1x5xf32>) -> tensor<2x5xf32>
+    %9 = "stablehlo.transpose"(%8) { permutation = array<i64: 1, 0> } : (tensor<2x5xf32>) -> tensor<5x2xf32>
+    "stablehlo.return"(%5, %9) : (tensor<2x5xf32>, tensor<5x2xf32>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+	})
+
+	t.Run("not rewritten", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3, 5)))
+		dynamicIndices := must(fn.NamedInput("indices", shapes.Make(dtypes.Int32, 2, 1)))
+		tooManyIndices := must(fn.ConstantFromFlatAndDimensions([]int32{0, 1, 2}, 3, 1))
+		must0(fn.Return(gatherRows(x, dynamicIndices, 1), gatherRows(x, tooManyIndices, 1)))
+		if numRewrites := must(fn.Rewrite(GatherToSlices(2))); numRewrites != 0 {
+			t.Errorf("expected no rewrites, got %d", numRewrites)
+		}
+		program := string(must(builder.Build()))
+		if strings.Count(program, `"stablehlo.gather"`) != 2 {
+			t.Errorf("expected the gather operations to be kept:\n%s", program)
+		}
+	})
+}
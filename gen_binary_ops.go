@@ -12,86 +12,176 @@ func Add(lhs, rhs *Value) (*Value, error) {
 	return fn.binaryOp(optypes.Add, lhs, rhs)
 }
 
+// AddScalar implements the corresponding standard binary operation, with the Go scalar rhs converted to the
+// dtype of lhs and broadcast to its shape. See WithScalar.
+func AddScalar(lhs *Value, rhs any) (*Value, error) {
+	return WithScalar(Add, lhs, rhs)
+}
+
 // And implements the corresponding standard binary operation.
 func And(lhs, rhs *Value) (*Value, error) {
 	fn := lhs.fn
 	return fn.binaryOp(optypes.And, lhs, rhs)
 }
 
+// AndScalar implements the corresponding standard binary operation, with the Go scalar rhs converted to the
+// dtype of lhs and broadcast to its shape. See WithScalar.
+func AndScalar(lhs *Value, rhs any) (*Value, error) {
+	return WithScalar(And, lhs, rhs)
+}
+
 // Atan2 implements the corresponding standard binary operation.
 func Atan2(lhs, rhs *Value) (*Value, error) {
 	fn := lhs.fn
 	return fn.binaryOp(optypes.Atan2, lhs, rhs)
 }
 
+// Atan2Scalar implements the corresponding standard binary operation, with the Go scalar rhs converted to the
+// dtype of lhs and broadcast to its shape. See WithScalar.
+func Atan2Scalar(lhs *Value, rhs any) (*Value, error) {
+	return WithScalar(Atan2, lhs, rhs)
+}
+
 // Divide implements the corresponding standard binary operation.
 func Divide(lhs, rhs *Value) (*Value, error) {
 	fn := lhs.fn
 	return fn.binaryOp(optypes.Divide, lhs, rhs)
 }
 
+// DivideScalar implements the corresponding standard binary operation, with the Go scalar rhs converted to the
+// dtype of lhs and broadcast to its shape. See WithScalar.
+func DivideScalar(lhs *Value, rhs any) (*Value, error) {
+	return WithScalar(Divide, lhs, rhs)
+}
+
 // Maximum implements the corresponding standard binary operation.
 func Maximum(lhs, rhs *Value) (*Value, error) {
 	fn := lhs.fn
 	return fn.binaryOp(optypes.Maximum, lhs, rhs)
 }
 
+// MaximumScalar implements the corresponding standard binary operation, with the Go scalar rhs converted to the
+// dtype of lhs and broadcast to its shape. See WithScalar.
+func MaximumScalar(lhs *Value, rhs any) (*Value, error) {
+	return WithScalar(Maximum, lhs, rhs)
+}
+
 // Minimum implements the corresponding standard binary operation.
 func Minimum(lhs, rhs *Value) (*Value, error) {
 	fn := lhs.fn
 	return fn.binaryOp(optypes.Minimum, lhs, rhs)
 }
 
+// MinimumScalar implements the corresponding standard binary operation, with the Go scalar rhs converted to the
+// dtype of lhs and broadcast to its shape. See WithScalar.
+func MinimumScalar(lhs *Value, rhs any) (*Value, error) {
+	return WithScalar(Minimum, lhs, rhs)
+}
+
 // Multiply implements the corresponding standard binary operation.
 func Multiply(lhs, rhs *Value) (*Value, error) {
 	fn := lhs.fn
 	return fn.binaryOp(optypes.Multiply, lhs, rhs)
 }
 
+// MultiplyScalar implements the corresponding standard binary operation, with the Go scalar rhs converted to the
+// dtype of lhs and broadcast to its shape. See WithScalar.
+func MultiplyScalar(lhs *Value, rhs any) (*Value, error) {
+	return WithScalar(Multiply, lhs, rhs)
+}
+
 // Or implements the corresponding standard binary operation.
 func Or(lhs, rhs *Value) (*Value, error) {
 	fn := lhs.fn
 	return fn.binaryOp(optypes.Or, lhs, rhs)
 }
 
+// OrScalar implements the corresponding standard binary operation, with the Go scalar rhs converted to the
+// dtype of lhs and broadcast to its shape. See WithScalar.
+func OrScalar(lhs *Value, rhs any) (*Value, error) {
+	return WithScalar(Or, lhs, rhs)
+}
+
 // Power implements the corresponding standard binary operation.
 func Power(lhs, rhs *Value) (*Value, error) {
 	fn := lhs.fn
 	return fn.binaryOp(optypes.Power, lhs, rhs)
 }
 
+// PowerScalar implements the corresponding standard binary operation, with the Go scalar rhs converted to the
+// dtype of lhs and broadcast to its shape. See WithScalar.
+func PowerScalar(lhs *Value, rhs any) (*Value, error) {
+	return WithScalar(Power, lhs, rhs)
+}
+
 // Remainder implements the corresponding standard binary operation.
 func Remainder(lhs, rhs *Value) (*Value, error) {
 	fn := lhs.fn
 	return fn.binaryOp(optypes.Remainder, lhs, rhs)
 }
 
+// RemainderScalar implements the corresponding standard binary operation, with the Go scalar rhs converted to the
+// dtype of lhs and broadcast to its shape. See WithScalar.
+func RemainderScalar(lhs *Value, rhs any) (*Value, error) {
+	return WithScalar(Remainder, lhs, rhs)
+}
+
 // ShiftLeft implements the corresponding standard binary operation.
 func ShiftLeft(lhs, rhs *Value) (*Value, error) {
 	fn := lhs.fn
 	return fn.binaryOp(optypes.ShiftLeft, lhs, rhs)
 }
 
+// ShiftLeftScalar implements the corresponding standard binary operation, with the Go scalar rhs converted to the
+// dtype of lhs and broadcast to its shape. See WithScalar.
+func ShiftLeftScalar(lhs *Value, rhs any) (*Value, error) {
+	return WithScalar(ShiftLeft, lhs, rhs)
+}
+
 // ShiftRightArithmetic implements the corresponding standard binary operation.
 func ShiftRightArithmetic(lhs, rhs *Value) (*Value, error) {
 	fn := lhs.fn
 	return fn.binaryOp(optypes.ShiftRightArithmetic, lhs, rhs)
 }
 
+// ShiftRightArithmeticScalar implements the corresponding standard binary operation, with the Go scalar rhs converted to the
+// dtype of lhs and broadcast to its shape. See WithScalar.
+func ShiftRightArithmeticScalar(lhs *Value, rhs any) (*Value, error) {
+	return WithScalar(ShiftRightArithmetic, lhs, rhs)
+}
+
 // ShiftRightLogical implements the corresponding standard binary operation.
 func ShiftRightLogical(lhs, rhs *Value) (*Value, error) {
 	fn := lhs.fn
 	return fn.binaryOp(optypes.ShiftRightLogical, lhs, rhs)
 }
 
+// ShiftRightLogicalScalar implements the corresponding standard binary operation, with the Go scalar rhs converted to the
+// dtype of lhs and broadcast to its shape. See WithScalar.
+func ShiftRightLogicalScalar(lhs *Value, rhs any) (*Value, error) {
+	return WithScalar(ShiftRightLogical, lhs, rhs)
+}
+
 // Subtract implements the corresponding standard binary operation.
 func Subtract(lhs, rhs *Value) (*Value, error) {
 	fn := lhs.fn
 	return fn.binaryOp(optypes.Subtract, lhs, rhs)
 }
 
+// SubtractScalar implements the corresponding standard binary operation, with the Go scalar rhs converted to the
+// dtype of lhs and broadcast to its shape. See WithScalar.
+func SubtractScalar(lhs *Value, rhs any) (*Value, error) {
+	return WithScalar(Subtract, lhs, rhs)
+}
+
 // Xor implements the corresponding standard binary operation.
 func Xor(lhs, rhs *Value) (*Value, error) {
 	fn := lhs.fn
 	return fn.binaryOp(optypes.Xor, lhs, rhs)
 }
+
+// XorScalar implements the corresponding standard binary operation, with the Go scalar rhs converted to the
+// dtype of lhs and broadcast to its shape. See WithScalar.
+func XorScalar(lhs *Value, rhs any) (*Value, error) {
+	return WithScalar(Xor, lhs, rhs)
+}
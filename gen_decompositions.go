@@ -0,0 +1,43 @@
+/***** File generated by ./internal/cmd/ops_generator. Don't edit it directly. *****/
+
+package stablehlo
+
+import (
+	"github.com/gomlx/stablehlo/internal/optypes"
+)
+
+// decomposableOps lists the operations that have a decomposition, see decomposition.
+var decomposableOps = []optypes.OpType{
+	optypes.Cbrt,
+	optypes.Erf,
+	optypes.ExponentialMinusOne,
+	optypes.LogPlusOne,
+	optypes.Logistic,
+	optypes.RoundNearestAfz,
+	optypes.Tan,
+}
+
+// decomposition returns the decomposition of the unary operation into other operations, used when the
+// target backend doesn't support it natively (see TargetFeatures), or nil if there is none.
+//
+// The decompositions are the functions named "decompose<OpName>" in decompositions.go.
+func decomposition(op optypes.OpType) func(operand *Value) (*Value, error) {
+	switch op {
+	case optypes.Cbrt:
+		return decomposeCbrt
+	case optypes.Erf:
+		return decomposeErf
+	case optypes.ExponentialMinusOne:
+		return decomposeExponentialMinusOne
+	case optypes.LogPlusOne:
+		return decomposeLogPlusOne
+	case optypes.Logistic:
+		return decomposeLogistic
+	case optypes.RoundNearestAfz:
+		return decomposeRoundNearestAfz
+	case optypes.Tan:
+		return decomposeTan
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,283 @@
+/***** File generated by ./internal/cmd/ops_generator. Don't edit it directly. *****/
+
+package stablehlo
+
+// Add chains the corresponding standard binary operation, with the expression as the left-hand side operand.
+func (e *Expr) Add(rhs *Value) *Expr {
+	return e.binaryOp(Add, rhs)
+}
+
+// AddScalar chains the corresponding standard binary operation with a Go scalar as the right-hand side
+// operand, converted to the dtype of the expression. See WithScalar.
+func (e *Expr) AddScalar(rhs any) *Expr {
+	return e.Apply(func(lhs *Value) (*Value, error) { return AddScalar(lhs, rhs) })
+}
+
+// And chains the corresponding standard binary operation, with the expression as the left-hand side operand.
+func (e *Expr) And(rhs *Value) *Expr {
+	return e.binaryOp(And, rhs)
+}
+
+// AndScalar chains the corresponding standard binary operation with a Go scalar as the right-hand side
+// operand, converted to the dtype of the expression. See WithScalar.
+func (e *Expr) AndScalar(rhs any) *Expr {
+	return e.Apply(func(lhs *Value) (*Value, error) { return AndScalar(lhs, rhs) })
+}
+
+// Atan2 chains the corresponding standard binary operation, with the expression as the left-hand side operand.
+func (e *Expr) Atan2(rhs *Value) *Expr {
+	return e.binaryOp(Atan2, rhs)
+}
+
+// Atan2Scalar chains the corresponding standard binary operation with a Go scalar as the right-hand side
+// operand, converted to the dtype of the expression. See WithScalar.
+func (e *Expr) Atan2Scalar(rhs any) *Expr {
+	return e.Apply(func(lhs *Value) (*Value, error) { return Atan2Scalar(lhs, rhs) })
+}
+
+// Divide chains the corresponding standard binary operation, with the expression as the left-hand side operand.
+func (e *Expr) Divide(rhs *Value) *Expr {
+	return e.binaryOp(Divide, rhs)
+}
+
+// DivideScalar chains the corresponding standard binary operation with a Go scalar as the right-hand side
+// operand, converted to the dtype of the expression. See WithScalar.
+func (e *Expr) DivideScalar(rhs any) *Expr {
+	return e.Apply(func(lhs *Value) (*Value, error) { return DivideScalar(lhs, rhs) })
+}
+
+// Maximum chains the corresponding standard binary operation, with the expression as the left-hand side operand.
+func (e *Expr) Maximum(rhs *Value) *Expr {
+	return e.binaryOp(Maximum, rhs)
+}
+
+// MaximumScalar chains the corresponding standard binary operation with a Go scalar as the right-hand side
+// operand, converted to the dtype of the expression. See WithScalar.
+func (e *Expr) MaximumScalar(rhs any) *Expr {
+	return e.Apply(func(lhs *Value) (*Value, error) { return MaximumScalar(lhs, rhs) })
+}
+
+// Minimum chains the corresponding standard binary operation, with the expression as the left-hand side operand.
+func (e *Expr) Minimum(rhs *Value) *Expr {
+	return e.binaryOp(Minimum, rhs)
+}
+
+// MinimumScalar chains the corresponding standard binary operation with a Go scalar as the right-hand side
+// operand, converted to the dtype of the expression. See WithScalar.
+func (e *Expr) MinimumScalar(rhs any) *Expr {
+	return e.Apply(func(lhs *Value) (*Value, error) { return MinimumScalar(lhs, rhs) })
+}
+
+// Multiply chains the corresponding standard binary operation, with the expression as the left-hand side operand.
+func (e *Expr) Multiply(rhs *Value) *Expr {
+	return e.binaryOp(Multiply, rhs)
+}
+
+// MultiplyScalar chains the corresponding standard binary operation with a Go scalar as the right-hand side
+// operand, converted to the dtype of the expression. See WithScalar.
+func (e *Expr) MultiplyScalar(rhs any) *Expr {
+	return e.Apply(func(lhs *Value) (*Value, error) { return MultiplyScalar(lhs, rhs) })
+}
+
+// Or chains the corresponding standard binary operation, with the expression as the left-hand side operand.
+func (e *Expr) Or(rhs *Value) *Expr {
+	return e.binaryOp(Or, rhs)
+}
+
+// OrScalar chains the corresponding standard binary operation with a Go scalar as the right-hand side
+// operand, converted to the dtype of the expression. See WithScalar.
+func (e *Expr) OrScalar(rhs any) *Expr {
+	return e.Apply(func(lhs *Value) (*Value, error) { return OrScalar(lhs, rhs) })
+}
+
+// Power chains the corresponding standard binary operation, with the expression as the left-hand side operand.
+func (e *Expr) Power(rhs *Value) *Expr {
+	return e.binaryOp(Power, rhs)
+}
+
+// PowerScalar chains the corresponding standard binary operation with a Go scalar as the right-hand side
+// operand, converted to the dtype of the expression. See WithScalar.
+func (e *Expr) PowerScalar(rhs any) *Expr {
+	return e.Apply(func(lhs *Value) (*Value, error) { return PowerScalar(lhs, rhs) })
+}
+
+// Remainder chains the corresponding standard binary operation, with the expression as the left-hand side operand.
+func (e *Expr) Remainder(rhs *Value) *Expr {
+	return e.binaryOp(Remainder, rhs)
+}
+
+// RemainderScalar chains the corresponding standard binary operation with a Go scalar as the right-hand side
+// operand, converted to the dtype of the expression. See WithScalar.
+func (e *Expr) RemainderScalar(rhs any) *Expr {
+	return e.Apply(func(lhs *Value) (*Value, error) { return RemainderScalar(lhs, rhs) })
+}
+
+// ShiftLeft chains the corresponding standard binary operation, with the expression as the left-hand side operand.
+func (e *Expr) ShiftLeft(rhs *Value) *Expr {
+	return e.binaryOp(ShiftLeft, rhs)
+}
+
+// ShiftLeftScalar chains the corresponding standard binary operation with a Go scalar as the right-hand side
+// operand, converted to the dtype of the expression. See WithScalar.
+func (e *Expr) ShiftLeftScalar(rhs any) *Expr {
+	return e.Apply(func(lhs *Value) (*Value, error) { return ShiftLeftScalar(lhs, rhs) })
+}
+
+// ShiftRightArithmetic chains the corresponding standard binary operation, with the expression as the left-hand side operand.
+func (e *Expr) ShiftRightArithmetic(rhs *Value) *Expr {
+	return e.binaryOp(ShiftRightArithmetic, rhs)
+}
+
+// ShiftRightArithmeticScalar chains the corresponding standard binary operation with a Go scalar as the right-hand side
+// operand, converted to the dtype of the expression. See WithScalar.
+func (e *Expr) ShiftRightArithmeticScalar(rhs any) *Expr {
+	return e.Apply(func(lhs *Value) (*Value, error) { return ShiftRightArithmeticScalar(lhs, rhs) })
+}
+
+// ShiftRightLogical chains the corresponding standard binary operation, with the expression as the left-hand side operand.
+func (e *Expr) ShiftRightLogical(rhs *Value) *Expr {
+	return e.binaryOp(ShiftRightLogical, rhs)
+}
+
+// ShiftRightLogicalScalar chains the corresponding standard binary operation with a Go scalar as the right-hand side
+// operand, converted to the dtype of the expression. See WithScalar.
+func (e *Expr) ShiftRightLogicalScalar(rhs any) *Expr {
+	return e.Apply(func(lhs *Value) (*Value, error) { return ShiftRightLogicalScalar(lhs, rhs) })
+}
+
+// Subtract chains the corresponding standard binary operation, with the expression as the left-hand side operand.
+func (e *Expr) Subtract(rhs *Value) *Expr {
+	return e.binaryOp(Subtract, rhs)
+}
+
+// SubtractScalar chains the corresponding standard binary operation with a Go scalar as the right-hand side
+// operand, converted to the dtype of the expression. See WithScalar.
+func (e *Expr) SubtractScalar(rhs any) *Expr {
+	return e.Apply(func(lhs *Value) (*Value, error) { return SubtractScalar(lhs, rhs) })
+}
+
+// Xor chains the corresponding standard binary operation, with the expression as the left-hand side operand.
+func (e *Expr) Xor(rhs *Value) *Expr {
+	return e.binaryOp(Xor, rhs)
+}
+
+// XorScalar chains the corresponding standard binary operation with a Go scalar as the right-hand side
+// operand, converted to the dtype of the expression. See WithScalar.
+func (e *Expr) XorScalar(rhs any) *Expr {
+	return e.Apply(func(lhs *Value) (*Value, error) { return XorScalar(lhs, rhs) })
+}
+
+// Abs chains the corresponding standard unary operation.
+func (e *Expr) Abs() *Expr {
+	return e.unaryOp(Abs)
+}
+
+// Cbrt chains the corresponding standard unary operation.
+func (e *Expr) Cbrt() *Expr {
+	return e.unaryOp(Cbrt)
+}
+
+// Ceil chains the corresponding standard unary operation.
+func (e *Expr) Ceil() *Expr {
+	return e.unaryOp(Ceil)
+}
+
+// Cosine chains the corresponding standard unary operation.
+func (e *Expr) Cosine() *Expr {
+	return e.unaryOp(Cosine)
+}
+
+// CountLeadingZeros chains the corresponding standard unary operation.
+func (e *Expr) CountLeadingZeros() *Expr {
+	return e.unaryOp(CountLeadingZeros)
+}
+
+// Erf chains the corresponding standard unary operation.
+func (e *Expr) Erf() *Expr {
+	return e.unaryOp(Erf)
+}
+
+// Exponential chains the corresponding standard unary operation.
+func (e *Expr) Exponential() *Expr {
+	return e.unaryOp(Exponential)
+}
+
+// ExponentialMinusOne chains the corresponding standard unary operation.
+func (e *Expr) ExponentialMinusOne() *Expr {
+	return e.unaryOp(ExponentialMinusOne)
+}
+
+// Floor chains the corresponding standard unary operation.
+func (e *Expr) Floor() *Expr {
+	return e.unaryOp(Floor)
+}
+
+// Log chains the corresponding standard unary operation.
+func (e *Expr) Log() *Expr {
+	return e.unaryOp(Log)
+}
+
+// LogPlusOne chains the corresponding standard unary operation.
+func (e *Expr) LogPlusOne() *Expr {
+	return e.unaryOp(LogPlusOne)
+}
+
+// Logistic chains the corresponding standard unary operation.
+func (e *Expr) Logistic() *Expr {
+	return e.unaryOp(Logistic)
+}
+
+// Negate chains the corresponding standard unary operation.
+func (e *Expr) Negate() *Expr {
+	return e.unaryOp(Negate)
+}
+
+// Not chains the corresponding standard unary operation.
+func (e *Expr) Not() *Expr {
+	return e.unaryOp(Not)
+}
+
+// Popcnt chains the corresponding standard unary operation.
+func (e *Expr) Popcnt() *Expr {
+	return e.unaryOp(Popcnt)
+}
+
+// RoundNearestAfz chains the corresponding standard unary operation.
+func (e *Expr) RoundNearestAfz() *Expr {
+	return e.unaryOp(RoundNearestAfz)
+}
+
+// RoundNearestEven chains the corresponding standard unary operation.
+func (e *Expr) RoundNearestEven() *Expr {
+	return e.unaryOp(RoundNearestEven)
+}
+
+// Rsqrt chains the corresponding standard unary operation.
+func (e *Expr) Rsqrt() *Expr {
+	return e.unaryOp(Rsqrt)
+}
+
+// Sign chains the corresponding standard unary operation.
+func (e *Expr) Sign() *Expr {
+	return e.unaryOp(Sign)
+}
+
+// Sine chains the corresponding standard unary operation.
+func (e *Expr) Sine() *Expr {
+	return e.unaryOp(Sine)
+}
+
+// Sqrt chains the corresponding standard unary operation.
+func (e *Expr) Sqrt() *Expr {
+	return e.unaryOp(Sqrt)
+}
+
+// Tan chains the corresponding standard unary operation.
+func (e *Expr) Tan() *Expr {
+	return e.unaryOp(Tan)
+}
+
+// Tanh chains the corresponding standard unary operation.
+func (e *Expr) Tanh() *Expr {
+	return e.unaryOp(Tanh)
+}
@@ -4,6 +4,7 @@ package stablehlo
 
 import (
 	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/types"
 )
 
 // Abs implements the corresponding standard unary operation.
@@ -13,11 +14,20 @@ func Abs(operand *Value) (*Value, error) {
 }
 
 // Cbrt implements the corresponding standard unary operation.
+//
+// If the target backend doesn't support it natively (see TargetFeatures), it is replaced by a decomposition
+// into other operations.
 func Cbrt(operand *Value) (*Value, error) {
 	fn := operand.fn
 	return fn.unaryOp(optypes.Cbrt, operand)
 }
 
+// CbrtWithAccuracy implements the corresponding standard unary operation, with the given result accuracy.
+func CbrtWithAccuracy(operand *Value, accuracy types.ResultAccuracy) (*Value, error) {
+	fn := operand.fn
+	return fn.unaryOpWithAccuracy(optypes.Cbrt, operand, accuracy)
+}
+
 // Ceil implements the corresponding standard unary operation.
 func Ceil(operand *Value) (*Value, error) {
 	fn := operand.fn
@@ -30,6 +40,12 @@ func Cosine(operand *Value) (*Value, error) {
 	return fn.unaryOp(optypes.Cosine, operand)
 }
 
+// CosineWithAccuracy implements the corresponding standard unary operation, with the given result accuracy.
+func CosineWithAccuracy(operand *Value, accuracy types.ResultAccuracy) (*Value, error) {
+	fn := operand.fn
+	return fn.unaryOpWithAccuracy(optypes.Cosine, operand, accuracy)
+}
+
 // CountLeadingZeros implements the corresponding standard unary operation.
 func CountLeadingZeros(operand *Value) (*Value, error) {
 	fn := operand.fn
@@ -37,6 +53,9 @@ func CountLeadingZeros(operand *Value) (*Value, error) {
 }
 
 // Erf implements the corresponding standard unary operation.
+//
+// If the target backend doesn't support it natively (see TargetFeatures), it is replaced by a decomposition
+// into other operations.
 func Erf(operand *Value) (*Value, error) {
 	fn := operand.fn
 	return fn.unaryOp(optypes.Erf, operand)
@@ -48,12 +67,27 @@ func Exponential(operand *Value) (*Value, error) {
 	return fn.unaryOp(optypes.Exponential, operand)
 }
 
+// ExponentialWithAccuracy implements the corresponding standard unary operation, with the given result accuracy.
+func ExponentialWithAccuracy(operand *Value, accuracy types.ResultAccuracy) (*Value, error) {
+	fn := operand.fn
+	return fn.unaryOpWithAccuracy(optypes.Exponential, operand, accuracy)
+}
+
 // ExponentialMinusOne implements the corresponding standard unary operation.
+//
+// If the target backend doesn't support it natively (see TargetFeatures), it is replaced by a decomposition
+// into other operations.
 func ExponentialMinusOne(operand *Value) (*Value, error) {
 	fn := operand.fn
 	return fn.unaryOp(optypes.ExponentialMinusOne, operand)
 }
 
+// ExponentialMinusOneWithAccuracy implements the corresponding standard unary operation, with the given result accuracy.
+func ExponentialMinusOneWithAccuracy(operand *Value, accuracy types.ResultAccuracy) (*Value, error) {
+	fn := operand.fn
+	return fn.unaryOpWithAccuracy(optypes.ExponentialMinusOne, operand, accuracy)
+}
+
 // Floor implements the corresponding standard unary operation.
 func Floor(operand *Value) (*Value, error) {
 	fn := operand.fn
@@ -66,18 +100,42 @@ func Log(operand *Value) (*Value, error) {
 	return fn.unaryOp(optypes.Log, operand)
 }
 
+// LogWithAccuracy implements the corresponding standard unary operation, with the given result accuracy.
+func LogWithAccuracy(operand *Value, accuracy types.ResultAccuracy) (*Value, error) {
+	fn := operand.fn
+	return fn.unaryOpWithAccuracy(optypes.Log, operand, accuracy)
+}
+
 // LogPlusOne implements the corresponding standard unary operation.
+//
+// If the target backend doesn't support it natively (see TargetFeatures), it is replaced by a decomposition
+// into other operations.
 func LogPlusOne(operand *Value) (*Value, error) {
 	fn := operand.fn
 	return fn.unaryOp(optypes.LogPlusOne, operand)
 }
 
+// LogPlusOneWithAccuracy implements the corresponding standard unary operation, with the given result accuracy.
+func LogPlusOneWithAccuracy(operand *Value, accuracy types.ResultAccuracy) (*Value, error) {
+	fn := operand.fn
+	return fn.unaryOpWithAccuracy(optypes.LogPlusOne, operand, accuracy)
+}
+
 // Logistic implements the corresponding standard unary operation.
+//
+// If the target backend doesn't support it natively (see TargetFeatures), it is replaced by a decomposition
+// into other operations.
 func Logistic(operand *Value) (*Value, error) {
 	fn := operand.fn
 	return fn.unaryOp(optypes.Logistic, operand)
 }
 
+// LogisticWithAccuracy implements the corresponding standard unary operation, with the given result accuracy.
+func LogisticWithAccuracy(operand *Value, accuracy types.ResultAccuracy) (*Value, error) {
+	fn := operand.fn
+	return fn.unaryOpWithAccuracy(optypes.Logistic, operand, accuracy)
+}
+
 // Negate implements the corresponding standard unary operation.
 func Negate(operand *Value) (*Value, error) {
 	fn := operand.fn
@@ -97,6 +155,9 @@ func Popcnt(operand *Value) (*Value, error) {
 }
 
 // RoundNearestAfz implements the corresponding standard unary operation.
+//
+// If the target backend doesn't support it natively (see TargetFeatures), it is replaced by a decomposition
+// into other operations.
 func RoundNearestAfz(operand *Value) (*Value, error) {
 	fn := operand.fn
 	return fn.unaryOp(optypes.RoundNearestAfz, operand)
@@ -114,6 +175,12 @@ func Rsqrt(operand *Value) (*Value, error) {
 	return fn.unaryOp(optypes.Rsqrt, operand)
 }
 
+// RsqrtWithAccuracy implements the corresponding standard unary operation, with the given result accuracy.
+func RsqrtWithAccuracy(operand *Value, accuracy types.ResultAccuracy) (*Value, error) {
+	fn := operand.fn
+	return fn.unaryOpWithAccuracy(optypes.Rsqrt, operand, accuracy)
+}
+
 // Sign implements the corresponding standard unary operation.
 func Sign(operand *Value) (*Value, error) {
 	fn := operand.fn
@@ -126,20 +193,47 @@ func Sine(operand *Value) (*Value, error) {
 	return fn.unaryOp(optypes.Sine, operand)
 }
 
+// SineWithAccuracy implements the corresponding standard unary operation, with the given result accuracy.
+func SineWithAccuracy(operand *Value, accuracy types.ResultAccuracy) (*Value, error) {
+	fn := operand.fn
+	return fn.unaryOpWithAccuracy(optypes.Sine, operand, accuracy)
+}
+
 // Sqrt implements the corresponding standard unary operation.
 func Sqrt(operand *Value) (*Value, error) {
 	fn := operand.fn
 	return fn.unaryOp(optypes.Sqrt, operand)
 }
 
+// SqrtWithAccuracy implements the corresponding standard unary operation, with the given result accuracy.
+func SqrtWithAccuracy(operand *Value, accuracy types.ResultAccuracy) (*Value, error) {
+	fn := operand.fn
+	return fn.unaryOpWithAccuracy(optypes.Sqrt, operand, accuracy)
+}
+
 // Tan implements the corresponding standard unary operation.
+//
+// If the target backend doesn't support it natively (see TargetFeatures), it is replaced by a decomposition
+// into other operations.
 func Tan(operand *Value) (*Value, error) {
 	fn := operand.fn
 	return fn.unaryOp(optypes.Tan, operand)
 }
 
+// TanWithAccuracy implements the corresponding standard unary operation, with the given result accuracy.
+func TanWithAccuracy(operand *Value, accuracy types.ResultAccuracy) (*Value, error) {
+	fn := operand.fn
+	return fn.unaryOpWithAccuracy(optypes.Tan, operand, accuracy)
+}
+
 // Tanh implements the corresponding standard unary operation.
 func Tanh(operand *Value) (*Value, error) {
 	fn := operand.fn
 	return fn.unaryOp(optypes.Tanh, operand)
 }
+
+// TanhWithAccuracy implements the corresponding standard unary operation, with the given result accuracy.
+func TanhWithAccuracy(operand *Value, accuracy types.ResultAccuracy) (*Value, error) {
+	fn := operand.fn
+	return fn.unaryOpWithAccuracy(optypes.Tanh, operand, accuracy)
+}
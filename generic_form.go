@@ -0,0 +1,183 @@
+package stablehlo
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+
+	"github.com/gomlx/stablehlo/internal/utils"
+	"github.com/pkg/errors"
+)
+
+// WithGenericForm configures the Builder to render the program in the MLIR generic form, where every operation,
+// including the module and the functions, is written as `"dialect.op"(operands) <{properties}> ({regions}) {attributes} : type`.
+//
+// E.g., instead of `func.func @main(%x: tensor<f32>) -> tensor<f32> {...}` it renders
+// `"func.func"() <{function_type = (tensor<f32>) -> tensor<f32>, sym_name = "main"}> ({^bb0(%x: tensor<f32>): ...}) : () -> ()`.
+//
+// The generic form is more verbose, but it doesn't depend on the custom assembly format of each dialect, so it is more
+// robust for downstream parsers and tooling pinned to different versions of MLIR.
+//
+// The operations in the functions are always rendered in the generic form, except raw snippets
+// (see Function.RawStatement), which are written as given.
+func (b *Builder) WithGenericForm(enabled bool) *Builder {
+	b.genericForm = enabled
+	return b
+}
+
+// writeGenericModule writes the "builtin.module" operation in the generic form.
+func (b *Builder) writeGenericModule(writer io.Writer) error {
+	var err error
+	w := func(format string, args ...any) {
+		if err != nil {
+			// No op if an error was encountered earlier
+			return
+		}
+		_, err = fmt.Fprintf(writer, format, args...)
+	}
+	we := func(e elementWriter, indentation string) {
+		if err != nil {
+			// No op if an error was encountered earlier
+			return
+		}
+		err = e.Write(writer, indentation)
+	}
+
+	w("\"builtin.module\"() <{sym_name = %q}> ({\n", NormalizeIdentifier(b.name))
+
+	// Shardy meshes are "sdy.mesh" symbol operations:
+	namesUsed := utils.MakeSet[string](len(b.meshes))
+	for _, mesh := range b.meshes {
+		if namesUsed.Has(mesh.Name()) {
+			return errors.Errorf("duplicate mesh name %q", mesh.Name())
+		}
+		namesUsed.Insert(mesh.Name())
+		w("%s\"sdy.mesh\"() <{mesh = %s, sym_name = %q}> : () -> ()\n",
+			IndentationStep, mesh.ToStableHLOAttribute(), mesh.Name())
+	}
+
+	// Write non-inline functions:
+	var count int
+	for _, fn := range b.functions {
+		if fn.Parent != nil {
+			continue
+		}
+		if count > 0 {
+			w("\n")
+		}
+		we(fn, IndentationStep)
+		w("\n")
+		count++
+	}
+	w("})")
+	attrs := b.getModuleAttributes()
+	if len(attrs) > 0 {
+		w(" {")
+		for i, attr := range attrs {
+			if i > 0 {
+				w(", ")
+			}
+			w("%s", strings.TrimSpace(attr))
+		}
+		w("}")
+	}
+	w(" : () -> ()\n")
+	return err
+}
+
+// writeGeneric writes the function as a "func.func" operation in the generic form.
+func (fn *Function) writeGeneric(writer io.Writer, indentation string) error {
+	var err error
+	w := func(format string, args ...any) {
+		if err != nil {
+			// No op if an error was encountered earlier
+			return
+		}
+		_, err = fmt.Fprintf(writer, format, args...)
+	}
+	we := func(e elementWriter, indentation string) {
+		if err != nil {
+			// No op if an error was encountered earlier
+			return
+		}
+		err = e.Write(writer, indentation)
+	}
+	nextIndent := indentation + IndentationStep
+
+	// Properties, in alphabetical order, as MLIR prints them:
+	w("%s\"func.func\"() <{", indentation)
+	if attrs := genericArgAttributes(fn.Inputs); attrs != "" {
+		w("arg_attrs = %s, ", attrs)
+	}
+	w("function_type = (")
+	for i, input := range fn.Inputs {
+		if i > 0 {
+			w(", ")
+		}
+		w("%s", input.shape.ToStableHLO())
+	}
+	w(") -> ")
+	if len(fn.Outputs) != 1 {
+		w("(")
+	}
+	for i, output := range fn.Outputs {
+		if i > 0 {
+			w(", ")
+		}
+		w("%s", output.shape.ToStableHLO())
+	}
+	if len(fn.Outputs) != 1 {
+		w(")")
+	}
+	if attrs := genericArgAttributes(fn.Outputs); attrs != "" {
+		w(", res_attrs = %s", attrs)
+	}
+	w(", sym_name = %q", fn.Name)
+	if fn.private {
+		w(", sym_visibility = \"private\"")
+	}
+	w("}> ({\n")
+
+	// Body: the entry block arguments are the function inputs.
+	if len(fn.Inputs) > 0 {
+		w("%s^bb0(", indentation)
+		for i, input := range fn.Inputs {
+			if i > 0 {
+				w(", ")
+			}
+			we(input, nextIndent)
+			w(": %s", input.shape.ToStableHLO())
+		}
+		w("):\n")
+	}
+	for _, stmt := range fn.Statements {
+		we(stmt, nextIndent)
+		w("\n")
+	}
+	w("%s})", indentation)
+	if attrs := passthroughAttributesList(fn.passthroughAttributes); len(attrs) > 0 {
+		w(" {%s}", strings.Join(attrs, ", "))
+	}
+	w(" : () -> ()")
+	return err
+}
+
+// genericArgAttributes renders the attributes of the function arguments (or results) as an array of dictionaries,
+// one per value. It returns "" if none of the values have attributes.
+func genericArgAttributes(values []*Value) string {
+	if !slices.ContainsFunc(values, func(v *Value) bool { return len(v.Attributes) > 0 }) {
+		return ""
+	}
+	parts := make([]string, len(values))
+	for i, value := range values {
+		keys := slices.Sorted(maps.Keys(value.Attributes))
+		attrs := make([]string, len(keys))
+		for j, key := range keys {
+			attrs[j] = fmt.Sprintf("%s = %s", key, literalToStableHLO(value.Attributes[key]))
+		}
+		parts[i] = "{" + strings.Join(attrs, ", ") + "}"
+	}
+	return "[" + strings.Join(parts, ", ") + "]"
+}
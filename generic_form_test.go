@@ -0,0 +1,58 @@
+package stablehlo
+
+import (
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/gomlx/stablehlo/types/shardy"
+)
+
+func TestGenericForm(t *testing.T) {
+	t.Run("Main", func(t *testing.T) {
+		builder := New(t.Name()).WithNumReplicas(2).WithGenericForm(true)
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		y := must(fn.NamedInput("y", shapes.Make(dtypes.Float32, 3)))
+		sum := must(Add(x, y))
+		if err := fn.Return(sum, x); err != nil {
+			t.Fatalf("Return failed: %+v", err)
+		}
+		program := string(must(builder.Build()))
+		want := `"builtin.module"() <{sym_name = "TestGenericForm_Main"}> ({
+  "func.func"() <{function_type = (tensor<3xf32>, tensor<3xf32>) -> (tensor<3xf32>, tensor<3xf32>), sym_name = "main"}> ({
+  ^bb0(%x: tensor<3xf32>, %y: tensor<3xf32>):
+    %0 = "stablehlo.add"(%x, %y) : (tensor<3xf32>, tensor<3xf32>) -> tensor<3xf32>
+    "stablehlo.return"(%0, %x) : (tensor<3xf32>, tensor<3xf32>) -> ()
+  }) : () -> ()
+}) {stablehlo.num_replicas = 2} : () -> ()
+`
+		if program != want {
+			t.Errorf("expected program:\n%s\ngot:\n%s", want, program)
+		}
+	})
+
+	t.Run("Shardy", func(t *testing.T) {
+		mesh := must(shardy.NewDeviceMesh("mesh", []int{2}, []string{"data"}))
+		builder := New(t.Name()).WithNumPartitions(2).WithShardy(mesh).WithGenericForm(true)
+		fn := builder.Main()
+		x := must(fn.NamedInputWithSharding("x", shapes.Make(dtypes.Float32, 4),
+			builder.NewShardingSpec().AddShardedAxis("data")))
+		if err := fn.Return(must(Negate(x))); err != nil {
+			t.Fatalf("Return failed: %+v", err)
+		}
+		program := string(must(builder.Build()))
+		want := `"builtin.module"() <{sym_name = "TestGenericForm_Shardy"}> ({
+  "sdy.mesh"() <{mesh = #sdy.mesh<["data"=2]>, sym_name = "mesh"}> : () -> ()
+  "func.func"() <{arg_attrs = [{sdy.sharding = #sdy.sharding<@mesh, [{"data"}]>}], function_type = (tensor<4xf32>) -> tensor<4xf32>, sym_name = "main"}> ({
+  ^bb0(%x: tensor<4xf32>):
+    %0 = "stablehlo.negate"(%x) : (tensor<4xf32>) -> tensor<4xf32>
+    "stablehlo.return"(%0) : (tensor<4xf32>) -> ()
+  }) : () -> ()
+}) {stablehlo.num_replicas = 1, stablehlo.num_partitions = 2} : () -> ()
+`
+		if program != want {
+			t.Errorf("expected program:\n%s\ngot:\n%s", want, program)
+		}
+	})
+}
@@ -8,6 +8,7 @@ require (
 	github.com/gomlx/gopjrt v0.10.0-rc0
 	github.com/pkg/errors v0.9.1
 	github.com/x448/float16 v0.8.4
+	google.golang.org/protobuf v1.36.10
 	k8s.io/klog/v2 v2.130.1
 )
 
@@ -18,7 +19,6 @@ require (
 	golang.org/x/mod v0.27.0 // indirect
 	golang.org/x/sync v0.16.0 // indirect
 	golang.org/x/tools v0.36.0 // indirect
-	google.golang.org/protobuf v1.36.10 // indirect
 )
 
 tool github.com/dmarkham/enumer
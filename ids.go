@@ -0,0 +1,77 @@
+package stablehlo
+
+// ID returns the unique ID of the function in its Builder: a positive integer assigned when the function (or
+// closure) is created, in creation order, and never reused.
+//
+// It's stable for the lifetime of the Builder, so external layers (e.g. autodiff or optimization passes) can key
+// their metadata off the functions without maps of pointers. See Builder.FunctionsByID.
+func (fn *Function) ID() int {
+	return fn.id
+}
+
+// ID returns the unique ID of the statement in its Builder: a positive integer assigned when the statement is
+// created, in creation order, and never reused -- statements rewritten or inlined get new IDs.
+//
+// It's stable for the lifetime of the Builder, so external layers (e.g. autodiff or optimization passes) can key
+// their metadata off the statements without maps of pointers. See Builder.StatementsByID.
+//
+// Statements created directly (not by the operations of this package) get an ID the first time it is requested.
+func (s *Statement) ID() int {
+	if s.id == 0 {
+		s.id = s.Function.Builder.newStatementID()
+	}
+	return s.id
+}
+
+// newStatementID returns a new unique statement ID.
+func (b *Builder) newStatementID() int {
+	b.numStatements++
+	return b.numStatements
+}
+
+// FunctionsByID returns the functions of the program -- the top-level functions and, recursively, the closures
+// used by their statements -- by their ID. See Function.ID.
+//
+// It can be called at any time, usually after Builder.Build, and it reflects the current state of the program:
+// closures no longer used (e.g. inlined or created for a failed operation) are not included.
+func (b *Builder) FunctionsByID() map[int]*Function {
+	functions := make(map[int]*Function)
+	b.walkFunctions(func(fn *Function) {
+		functions[fn.ID()] = fn
+	})
+	return functions
+}
+
+// StatementsByID returns the statements of all the functions of the program (see FunctionsByID) by their ID.
+// See Statement.ID.
+//
+// It can be called at any time, usually after Builder.Build, and it reflects the current state of the program:
+// statements removed (e.g. by Function.Rewrite or Builder.InlineCalls) are not included.
+func (b *Builder) StatementsByID() map[int]*Statement {
+	statements := make(map[int]*Statement)
+	b.walkFunctions(func(fn *Function) {
+		for _, stmt := range fn.Statements {
+			statements[stmt.ID()] = stmt
+		}
+	})
+	return statements
+}
+
+// walkFunctions calls visit for each top-level function of the program and, recursively, for the closures used by
+// their statements.
+func (b *Builder) walkFunctions(visit func(fn *Function)) {
+	var walk func(fn *Function)
+	walk = func(fn *Function) {
+		visit(fn)
+		for _, stmt := range fn.Statements {
+			for _, closure := range stmt.FunctionParameters {
+				walk(closure)
+			}
+		}
+	}
+	for _, fn := range b.functions {
+		if fn.Parent == nil {
+			walk(fn)
+		}
+	}
+}
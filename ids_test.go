@@ -0,0 +1,57 @@
+package stablehlo
+
+import (
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestIDs(t *testing.T) {
+	builder := New(t.Name())
+	fn := builder.Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2, 3)))
+	zero := must(fn.ConstantFromScalar(float32(0)))
+	sumFn := must(fn.NewReductionClosure(ReductionAdd, dtypes.Float32))
+	sum := must(Reduce(x, zero, sumFn, 1))
+	must0(fn.Return(sum))
+	_ = must(builder.Build())
+	unused := fn.Closure() // Never used by a statement.
+
+	functions := builder.FunctionsByID()
+	if len(functions) != 2 || functions[fn.ID()] != fn || functions[sumFn.ID()] != sumFn {
+		t.Errorf("unexpected FunctionsByID: %v", functions)
+	}
+	if _, found := functions[unused.ID()]; found {
+		t.Error("unused closure should not be listed in FunctionsByID")
+	}
+	if fn.ID() >= sumFn.ID() {
+		t.Errorf("function IDs should follow the creation order, got %d for main and %d for the closure",
+			fn.ID(), sumFn.ID())
+	}
+
+	statements := builder.StatementsByID()
+	// Constant, reduce and return in main, add and return in the closure.
+	if len(statements) != 5 {
+		t.Fatalf("expected 5 statements, got %d", len(statements))
+	}
+	seen := make(map[int]bool)
+	for _, f := range []*Function{fn, sumFn} {
+		for _, stmt := range f.Statements {
+			if stmt.ID() <= 0 || seen[stmt.ID()] {
+				t.Errorf("invalid or repeated statement ID %d", stmt.ID())
+			}
+			seen[stmt.ID()] = true
+			if statements[stmt.ID()] != stmt {
+				t.Errorf("StatementsByID()[%d] is not the statement %s", stmt.ID(), stmt.OpType)
+			}
+		}
+	}
+
+	// Statements created directly get an ID on demand.
+	stmt := &Statement{Function: fn, OpType: optypes.Constant}
+	if id := stmt.ID(); id <= 0 || seen[id] || stmt.ID() != id {
+		t.Errorf("unexpected ID %d for statement created directly", id)
+	}
+}
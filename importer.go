@@ -0,0 +1,1140 @@
+package stablehlo
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/gomlx/stablehlo/types/shardy"
+	"github.com/pkg/errors"
+)
+
+// Import parses a StableHLO program in MLIR text format into a new Builder, so it can be inspected,
+// edited (e.g. with Function.Rewrite) and re-emitted with Builder.Build.
+//
+// The module and functions can be either in the pretty form (`module @name {...}`, `func.func @main(...)`),
+// as rendered by Builder.Build, or in the generic form (see Builder.WithGenericForm). The operations inside the
+// functions must be in the generic form (`%0 = "stablehlo.add"(%x, %y) : (...) -> ...`), which is what this package
+// renders and what MLIR tools output with the `--mlir-print-op-generic` flag.
+// Programs dumped by XLA tools as HLO protos must be first converted to StableHLO with those tools.
+//
+// The attributes of the operations are kept verbatim. Operations unknown to this package (e.g. from other dialects)
+// are imported as raw statements (see Function.RawStatement), as long as they don't have regions.
+// The operand types in the signatures of the operations, and the result types of the functions, are checked against
+// the values: a mismatch is an error. The output types of the operations are taken from their signatures.
+//
+// Module attributes other than the number of replicas and partitions, function attributes and location information
+// are not imported.
+func Import(program []byte) (*Builder, error) {
+	imp := &importer{text: string(program)}
+	imp.maxTmpID, imp.maxArgID = -1, -1
+	for _, match := range importTmpIDRegex.FindAllStringSubmatch(imp.text, -1) {
+		if id, err := strconv.Atoi(match[1]); err == nil {
+			imp.maxTmpID = max(imp.maxTmpID, id)
+		}
+	}
+	for _, match := range importArgIDRegex.FindAllStringSubmatch(imp.text, -1) {
+		if id, err := strconv.Atoi(match[1]); err == nil {
+			imp.maxArgID = max(imp.maxArgID, id)
+		}
+	}
+	if err := imp.parseModule(); err != nil {
+		return nil, errors.WithMessagef(err, "Import failed at line %d", imp.line())
+	}
+	return imp.b, nil
+}
+
+var (
+	importTmpIDRegex = regexp.MustCompile(`%(\d+)\b`)
+	importArgIDRegex = regexp.MustCompile(`%arg(\d+)\b`)
+)
+
+// importer holds the state of Import.
+type importer struct {
+	text string
+	pos  int
+	b    *Builder
+
+	// maxTmpID and maxArgID are the largest numeric names ("%<n>" and "%arg<n>") used in the program,
+	// so new values created after the import don't clash with the imported ones.
+	maxTmpID, maxArgID int
+}
+
+// importScope maps the names in the program to the values of a function.
+type importScope map[string]*Value
+
+// line returns the line number of the current position, for error messages.
+func (imp *importer) line() int {
+	return strings.Count(imp.text[:imp.pos], "\n") + 1
+}
+
+// skipSpace skips white spaces and comments.
+func (imp *importer) skipSpace() {
+	for imp.pos < len(imp.text) {
+		c := imp.text[imp.pos]
+		if c == ' ' || c == '\t' || c == '\n' || c == '\r' {
+			imp.pos++
+		} else if strings.HasPrefix(imp.text[imp.pos:], "//") {
+			next := strings.IndexByte(imp.text[imp.pos:], '\n')
+			if next == -1 {
+				imp.pos = len(imp.text)
+			} else {
+				imp.pos += next + 1
+			}
+		} else {
+			return
+		}
+	}
+}
+
+// peek returns whether the text at the current position (after spaces) starts with str.
+func (imp *importer) peek(str string) bool {
+	imp.skipSpace()
+	return strings.HasPrefix(imp.text[imp.pos:], str)
+}
+
+// consume str if the text at the current position (after spaces) starts with it.
+func (imp *importer) consume(str string) bool {
+	if imp.peek(str) {
+		imp.pos += len(str)
+		return true
+	}
+	return false
+}
+
+// expect consumes str, or returns an error.
+func (imp *importer) expect(str string) error {
+	if !imp.consume(str) {
+		return errors.Errorf("expected %q, got %q", str, imp.context())
+	}
+	return nil
+}
+
+// context returns a short piece of the text at the current position, for error messages.
+func (imp *importer) context() string {
+	end := min(imp.pos+40, len(imp.text))
+	if newLine := strings.IndexByte(imp.text[imp.pos:end], '\n'); newLine != -1 {
+		end = imp.pos + newLine
+	}
+	return imp.text[imp.pos:end]
+}
+
+// isIdentifierChar returns whether c can be part of MLIR identifiers (symbol, value and attribute names).
+func isIdentifierChar(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z') ||
+		c == '_' || c == '.' || c == '$' || c == '-'
+}
+
+// parseIdentifier parses a bare identifier, or a quoted string (returned unquoted).
+func (imp *importer) parseIdentifier() (string, error) {
+	imp.skipSpace()
+	if imp.peek(`"`) {
+		quoted, err := imp.scanString()
+		if err != nil {
+			return "", err
+		}
+		return strconv.Unquote(quoted)
+	}
+	start := imp.pos
+	for imp.pos < len(imp.text) && isIdentifierChar(imp.text[imp.pos]) {
+		imp.pos++
+	}
+	if start == imp.pos {
+		return "", errors.Errorf("expected identifier, got %q", imp.context())
+	}
+	return imp.text[start:imp.pos], nil
+}
+
+// scanString returns the quoted string at the current position, including the quotes.
+func (imp *importer) scanString() (string, error) {
+	imp.skipSpace()
+	start := imp.pos
+	if imp.pos >= len(imp.text) || imp.text[imp.pos] != '"' {
+		return "", errors.Errorf("expected string, got %q", imp.context())
+	}
+	for imp.pos++; imp.pos < len(imp.text); imp.pos++ {
+		switch imp.text[imp.pos] {
+		case '\\':
+			imp.pos++
+		case '"':
+			imp.pos++
+			return imp.text[start:imp.pos], nil
+		}
+	}
+	return "", errors.New("unterminated string")
+}
+
+// scanValue returns the text of an attribute value (or a type), up to the first top-level ',' or closing delimiter.
+// It keeps track of nested delimiters and strings, and of the "->" arrows of function types.
+func (imp *importer) scanValue() (string, error) {
+	imp.skipSpace()
+	start := imp.pos
+	var stack []byte
+	closing := map[byte]byte{'(': ')', '[': ']', '{': '}', '<': '>'}
+	for imp.pos < len(imp.text) {
+		c := imp.text[imp.pos]
+		switch {
+		case c == '"':
+			if _, err := imp.scanString(); err != nil {
+				return "", err
+			}
+			continue
+		case c == '-' && strings.HasPrefix(imp.text[imp.pos:], "->"):
+			imp.pos += 2
+			continue
+		case closing[c] != 0:
+			stack = append(stack, closing[c])
+		case c == ')' || c == ']' || c == '}' || c == '>':
+			if len(stack) == 0 {
+				return strings.TrimSpace(imp.text[start:imp.pos]), nil
+			}
+			if stack[len(stack)-1] != c {
+				return "", errors.Errorf("unbalanced %q in %q", c, imp.text[start:imp.pos+1])
+			}
+			stack = stack[:len(stack)-1]
+		case c == ',' && len(stack) == 0:
+			return strings.TrimSpace(imp.text[start:imp.pos]), nil
+		}
+		imp.pos++
+	}
+	return "", errors.Errorf("unterminated value %q", imp.text[start:])
+}
+
+// scanGroup returns the text of the group starting with the opening delimiter at the current position,
+// up to its matching closing delimiter, inclusive.
+func (imp *importer) scanGroup(opening string) (string, error) {
+	if !imp.peek(opening) {
+		return "", errors.Errorf("expected %q, got %q", opening, imp.context())
+	}
+	start := imp.pos
+	imp.pos++
+	for {
+		if _, err := imp.scanValue(); err != nil {
+			return "", err
+		}
+		if !imp.consume(",") {
+			break
+		}
+	}
+	closing := map[string]byte{"(": ')', "[": ']', "{": '}', "<": '>'}[opening]
+	if imp.pos >= len(imp.text) || imp.text[imp.pos] != closing {
+		return "", errors.Errorf("unterminated %q", imp.text[start:imp.pos])
+	}
+	imp.pos++
+	return imp.text[start:imp.pos], nil
+}
+
+// parseDict parses an attributes dictionary "{key = value, ...}", keeping the values verbatim.
+// Keys without values (unit attributes) get the value "unit".
+func (imp *importer) parseDict() (map[string]string, error) {
+	if err := imp.expect("{"); err != nil {
+		return nil, err
+	}
+	dict := make(map[string]string)
+	for !imp.consume("}") {
+		if len(dict) > 0 {
+			if err := imp.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		key, err := imp.parseIdentifier()
+		if err != nil {
+			return nil, err
+		}
+		if !imp.consume("=") {
+			dict[key] = "unit"
+			continue
+		}
+		value, err := imp.scanValue()
+		if err != nil {
+			return nil, errors.WithMessagef(err, "attribute %q", key)
+		}
+		dict[key] = value
+	}
+	return dict, nil
+}
+
+// dictToAttributes converts a parsed dictionary to the attributes of a statement or value.
+func dictToAttributes(dict map[string]string) map[string]any {
+	if len(dict) == 0 {
+		return nil
+	}
+	attributes := make(map[string]any, len(dict))
+	for key, value := range dict {
+		switch value {
+		case "true", "false":
+			// Some attributes (e.g. mhlo.is_dynamic) are checked as bool.
+			attributes[key] = value == "true"
+		default:
+			attributes[key] = literalStr(value)
+		}
+	}
+	return attributes
+}
+
+// parseType parses a tensor or tuple type.
+func (imp *importer) parseType() (shapes.Shape, error) {
+	imp.skipSpace()
+	start := imp.pos
+	for imp.pos < len(imp.text) && (isIdentifierChar(imp.text[imp.pos]) || imp.text[imp.pos] == '!') {
+		imp.pos++
+	}
+	if imp.peek("<") {
+		depth := 0
+		for ; imp.pos < len(imp.text); imp.pos++ {
+			if imp.text[imp.pos] == '<' {
+				depth++
+			} else if imp.text[imp.pos] == '>' {
+				depth--
+				if depth == 0 {
+					imp.pos++
+					break
+				}
+			}
+		}
+	}
+	typeStr := imp.text[start:imp.pos]
+	shape, err := shapes.FromStableHLO(typeStr)
+	if err != nil {
+		imp.pos = start
+		return shapes.Invalid(), err
+	}
+	imp.skipLocation()
+	return shape, nil
+}
+
+// parseTypeList parses a list of types, either a single type or a parenthesized list "(t1, t2)".
+func (imp *importer) parseTypeList() ([]shapes.Shape, error) {
+	if !imp.consume("(") {
+		shape, err := imp.parseType()
+		if err != nil {
+			return nil, err
+		}
+		return []shapes.Shape{shape}, nil
+	}
+	var types []shapes.Shape
+	for !imp.consume(")") {
+		if len(types) > 0 {
+			if err := imp.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		shape, err := imp.parseType()
+		if err != nil {
+			return nil, err
+		}
+		types = append(types, shape)
+	}
+	return types, nil
+}
+
+// parseFunctionType parses "(inputs types) -> outputs types".
+func (imp *importer) parseFunctionType() (inputs, outputs []shapes.Shape, err error) {
+	inputs, err = imp.parseTypeList()
+	if err != nil {
+		return
+	}
+	if err = imp.expect("->"); err != nil {
+		return
+	}
+	outputs, err = imp.parseTypeList()
+	return
+}
+
+// skipLocation skips an optional location, "loc(...)".
+func (imp *importer) skipLocation() {
+	if !imp.peek("loc(") {
+		return
+	}
+	imp.pos += len("loc(")
+	_, _ = imp.scanValue()
+	_ = imp.expect(")")
+}
+
+// parseModule parses the whole program.
+func (imp *importer) parseModule() error {
+	imp.skipAliases()
+	if imp.consume("module") {
+		name := "module"
+		if imp.consume("@") {
+			var err error
+			if name, err = imp.parseIdentifier(); err != nil {
+				return err
+			}
+		}
+		imp.b = New(name)
+		if imp.consume("attributes") {
+			dict, err := imp.parseDict()
+			if err != nil {
+				return err
+			}
+			if err := imp.setModuleAttributes(dict); err != nil {
+				return err
+			}
+		}
+		if err := imp.expect("{"); err != nil {
+			return err
+		}
+		if err := imp.parseModuleBody(); err != nil {
+			return err
+		}
+		if err := imp.expect("}"); err != nil {
+			return err
+		}
+
+	} else if imp.consume(`"builtin.module"`) {
+		if err := imp.expect("()"); err != nil {
+			return err
+		}
+		properties := make(map[string]string)
+		if imp.consume("<") {
+			var err error
+			if properties, err = imp.parseDict(); err != nil {
+				return err
+			}
+			if err = imp.expect(">"); err != nil {
+				return err
+			}
+		}
+		name := "module"
+		if symName, found := properties["sym_name"]; found {
+			var err error
+			if name, err = strconv.Unquote(symName); err != nil {
+				return errors.Errorf("invalid module name %s", symName)
+			}
+		}
+		imp.b = New(name)
+		if err := imp.expect("({"); err != nil {
+			return err
+		}
+		if err := imp.parseModuleBody(); err != nil {
+			return err
+		}
+		if err := imp.expect("})"); err != nil {
+			return err
+		}
+		if imp.peek("{") {
+			dict, err := imp.parseDict()
+			if err != nil {
+				return err
+			}
+			if err := imp.setModuleAttributes(dict); err != nil {
+				return err
+			}
+		}
+		if err := imp.expect(":"); err != nil {
+			return err
+		}
+		if _, _, err := imp.parseFunctionType(); err != nil {
+			return err
+		}
+
+	} else {
+		// Functions without a module.
+		imp.b = New("module")
+		if err := imp.parseModuleBody(); err != nil {
+			return err
+		}
+	}
+	imp.skipLocation()
+	imp.skipAliases()
+	if imp.pos < len(imp.text) {
+		return errors.Errorf("unexpected text after the module: %q", imp.context())
+	}
+	return nil
+}
+
+// skipAliases skips the location aliases (e.g. `#loc1 = loc("file.py":10:0)`) defined before or after the module.
+func (imp *importer) skipAliases() {
+	for imp.peek("#") {
+		start := imp.pos
+		imp.pos++
+		_, _ = imp.parseIdentifier()
+		if !imp.consume("=") || !imp.peek("loc(") {
+			imp.pos = start
+			return
+		}
+		imp.skipLocation()
+	}
+}
+
+// setModuleAttributes sets the number of replicas and partitions from the module attributes.
+func (imp *importer) setModuleAttributes(dict map[string]string) error {
+	for key, setter := range map[string]func(int) *Builder{
+		"stablehlo.num_replicas":   imp.b.WithNumReplicas,
+		"stablehlo.num_partitions": imp.b.WithNumPartitions,
+	} {
+		value, found := dict[key]
+		if !found {
+			continue
+		}
+		value, _, _ = strings.Cut(value, ":")
+		n, err := strconv.Atoi(strings.TrimSpace(value))
+		if err != nil {
+			return errors.Errorf("invalid module attribute %s = %s", key, dict[key])
+		}
+		setter(n)
+	}
+	return nil
+}
+
+// parseModuleBody parses the meshes and functions of the module, until a "}" or the end of the text.
+func (imp *importer) parseModuleBody() error {
+	for {
+		imp.skipSpace()
+		switch {
+		case imp.pos >= len(imp.text) || imp.peek("}"):
+			return nil
+		case imp.consume("sdy.mesh"):
+			if err := imp.expect("@"); err != nil {
+				return err
+			}
+			name, err := imp.parseIdentifier()
+			if err != nil {
+				return err
+			}
+			if err := imp.expect("="); err != nil {
+				return err
+			}
+			body, err := imp.scanGroup("<")
+			if err != nil {
+				return errors.WithMessagef(err, "mesh %q", name)
+			}
+			if err := imp.addMesh(name, body); err != nil {
+				return err
+			}
+		case imp.consume(`"sdy.mesh"`):
+			if err := imp.expect("()"); err != nil {
+				return err
+			}
+			if err := imp.expect("<"); err != nil {
+				return err
+			}
+			properties, err := imp.parseDict()
+			if err != nil {
+				return err
+			}
+			if err := imp.expect(">"); err != nil {
+				return err
+			}
+			if err := imp.expect(":"); err != nil {
+				return err
+			}
+			if _, _, err := imp.parseFunctionType(); err != nil {
+				return err
+			}
+			name, err := strconv.Unquote(properties["sym_name"])
+			if err != nil {
+				return errors.Errorf("invalid mesh name %q", properties["sym_name"])
+			}
+			body, found := strings.CutPrefix(properties["mesh"], "#sdy.mesh")
+			if !found {
+				return errors.Errorf("invalid mesh %q", properties["mesh"])
+			}
+			if err := imp.addMesh(name, body); err != nil {
+				return err
+			}
+		case imp.consume("func.func"):
+			if err := imp.parseFunction(); err != nil {
+				return err
+			}
+		case imp.consume(`"func.func"`):
+			if err := imp.parseGenericFunction(); err != nil {
+				return err
+			}
+		default:
+			return errors.Errorf("expected a function or a mesh in the module, got %q", imp.context())
+		}
+		imp.skipLocation()
+	}
+}
+
+var (
+	importMeshAxisRegex      = regexp.MustCompile(`"([^"]*)"\s*=\s*(\d+)`)
+	importMeshDeviceIDsRegex = regexp.MustCompile(`device_ids\s*=\s*\[([^\]]*)]`)
+)
+
+// addMesh adds a Shardy mesh to the builder, given its body, e.g. `<["data"=4], device_ids=[3, 2, 1, 0]>`.
+func (imp *importer) addMesh(name, body string) error {
+	axesPart, _, _ := strings.Cut(body, "]")
+	var axesNames []string
+	var axesSizes []int
+	for _, match := range importMeshAxisRegex.FindAllStringSubmatch(axesPart, -1) {
+		size, _ := strconv.Atoi(match[2])
+		axesNames = append(axesNames, match[1])
+		axesSizes = append(axesSizes, size)
+	}
+	mesh, err := shardy.NewDeviceMesh(name, axesSizes, axesNames)
+	if err != nil {
+		return errors.WithMessagef(err, "mesh %q", name)
+	}
+	if match := importMeshDeviceIDsRegex.FindStringSubmatch(body); match != nil {
+		var ids []int
+		for _, idStr := range strings.Split(match[1], ",") {
+			id, err := strconv.Atoi(strings.TrimSpace(idStr))
+			if err != nil {
+				return errors.Errorf("invalid device id %q in mesh %q", idStr, name)
+			}
+			ids = append(ids, id)
+		}
+		if err := mesh.SetLogicalDeviceAssignment(ids...); err != nil {
+			return errors.WithMessagef(err, "mesh %q", name)
+		}
+	}
+	imp.b.meshes = append(imp.b.meshes, mesh)
+	return nil
+}
+
+// newFunction creates a new top-level function in the builder.
+func (imp *importer) newFunction(name string) *Function {
+	fn := imp.b.NewFunction(name)
+	fn.nextTmpID = imp.maxTmpID + 1
+	fn.nextArgID = imp.maxArgID + 1
+	return fn
+}
+
+// addInput adds an input to the function with the given name (including the "%" prefix).
+func (imp *importer) addInput(fn *Function, scope importScope, name string, shape shapes.Shape,
+	attributes map[string]string) error {
+	input, err := fn.NamedInputWithAttributes(strings.TrimPrefix(name, "%"), shape, dictToAttributes(attributes))
+	if err != nil {
+		return err
+	}
+	scope[name] = input
+	return nil
+}
+
+// parseFunction parses a function in the pretty form, after the "func.func" keyword.
+func (imp *importer) parseFunction() error {
+	private := false
+	for _, visibility := range []string{"public", "private", "nested"} {
+		if imp.consume(visibility + " ") {
+			private = visibility == "private"
+			break
+		}
+	}
+	if err := imp.expect("@"); err != nil {
+		return err
+	}
+	name, err := imp.parseIdentifier()
+	if err != nil {
+		return err
+	}
+	fn := imp.newFunction(name)
+	fn.private = private
+	scope := make(importScope)
+	if err := imp.expect("("); err != nil {
+		return err
+	}
+	for !imp.consume(")") {
+		if len(fn.Inputs) > 0 {
+			if err := imp.expect(","); err != nil {
+				return err
+			}
+		}
+		imp.skipSpace()
+		argName, err := imp.parseValueName()
+		if err != nil {
+			return err
+		}
+		if err := imp.expect(":"); err != nil {
+			return err
+		}
+		shape, err := imp.parseType()
+		if err != nil {
+			return err
+		}
+		var attributes map[string]string
+		if imp.peek("{") {
+			if attributes, err = imp.parseDict(); err != nil {
+				return err
+			}
+		}
+		imp.skipLocation()
+		if err := imp.addInput(fn, scope, argName, shape, attributes); err != nil {
+			return errors.WithMessagef(err, "function %q", name)
+		}
+	}
+
+	// Outputs shapes and attributes: the shapes are declared, so they are checked when the function returns.
+	var outputsAttributes []map[string]string
+	var outputShapes []shapes.Shape
+	if imp.consume("->") {
+		parenthesized := imp.consume("(")
+		for !parenthesized || !imp.consume(")") {
+			if parenthesized && len(outputsAttributes) > 0 {
+				if err := imp.expect(","); err != nil {
+					return err
+				}
+			}
+			shape, err := imp.parseType()
+			if err != nil {
+				return err
+			}
+			outputShapes = append(outputShapes, shape)
+			// Attributes are only allowed for parenthesized outputs, otherwise the "{" starts the body.
+			var attributes map[string]string
+			if parenthesized && imp.peek("{") {
+				if attributes, err = imp.parseDict(); err != nil {
+					return err
+				}
+			}
+			outputsAttributes = append(outputsAttributes, attributes)
+			if !parenthesized {
+				break
+			}
+		}
+	}
+	if len(outputShapes) > 0 {
+		if err := fn.DeclareOutputs(outputShapes...); err != nil {
+			return err
+		}
+	}
+	if imp.consume("attributes") {
+		if fn.passthroughAttributes, err = imp.parseDict(); err != nil {
+			return err
+		}
+	}
+	if err := imp.expect("{"); err != nil {
+		return err
+	}
+	if err := imp.parseStatements(fn, scope, outputsAttributes); err != nil {
+		return errors.WithMessagef(err, "function %q", name)
+	}
+	return imp.expect("}")
+}
+
+// parseGenericFunction parses a function in the generic form, after the `"func.func"` operation name.
+func (imp *importer) parseGenericFunction() error {
+	if err := imp.expect("()"); err != nil {
+		return err
+	}
+	if err := imp.expect("<"); err != nil {
+		return err
+	}
+	properties, err := imp.parseDict()
+	if err != nil {
+		return err
+	}
+	if err := imp.expect(">"); err != nil {
+		return err
+	}
+	name, err := strconv.Unquote(properties["sym_name"])
+	if err != nil {
+		return errors.Errorf("invalid function name %q", properties["sym_name"])
+	}
+	argsAttributes, err := parseDictArray(properties["arg_attrs"])
+	if err != nil {
+		return errors.WithMessagef(err, "function %q arg_attrs", name)
+	}
+	outputsAttributes, err := parseDictArray(properties["res_attrs"])
+	if err != nil {
+		return errors.WithMessagef(err, "function %q res_attrs", name)
+	}
+
+	typeImporter := &importer{text: properties["function_type"]}
+	inputShapes, outputShapes, err := typeImporter.parseFunctionType()
+	if err != nil {
+		return errors.WithMessagef(err, "function %q function_type", name)
+	}
+
+	fn := imp.newFunction(name)
+	fn.private = properties["sym_visibility"] == `"private"`
+	if len(outputShapes) > 0 {
+		if err := fn.DeclareOutputs(outputShapes...); err != nil {
+			return err
+		}
+	}
+	scope := make(importScope)
+	if err := imp.expect("({"); err != nil {
+		return err
+	}
+	if imp.peek("^") {
+		_, args, err := imp.parseBlockHeader()
+		if err != nil {
+			return err
+		}
+		for i, arg := range args {
+			var attributes map[string]string
+			if i < len(argsAttributes) {
+				attributes = argsAttributes[i]
+			}
+			if err := imp.addInput(fn, scope, arg.name, arg.shape, attributes); err != nil {
+				return errors.WithMessagef(err, "function %q", name)
+			}
+		}
+	}
+	if err := checkImportedTypes(valuesToShapes(fn.Inputs), inputShapes); err != nil {
+		return errors.WithMessagef(err, "function %q inputs", name)
+	}
+	if err := imp.parseStatements(fn, scope, outputsAttributes); err != nil {
+		return errors.WithMessagef(err, "function %q", name)
+	}
+	if err := imp.expect("})"); err != nil {
+		return err
+	}
+	if imp.peek("{") {
+		if fn.passthroughAttributes, err = imp.parseDict(); err != nil {
+			return err
+		}
+	}
+	if err := imp.expect(":"); err != nil {
+		return err
+	}
+	_, _, err = imp.parseFunctionType()
+	return err
+}
+
+// checkImportedTypes checks that the shapes of the values match the types declared in the program.
+func checkImportedTypes(valuesShapes, declared []shapes.Shape) error {
+	if len(valuesShapes) != len(declared) {
+		return errors.Errorf("%d types declared for %d values", len(declared), len(valuesShapes))
+	}
+	for i, shape := range valuesShapes {
+		if !shape.Equal(declared[i]) {
+			return errors.Errorf("value #%d has shape %s, but %s was declared", i, shape, declared[i])
+		}
+	}
+	return nil
+}
+
+// parseDictArray parses an array of dictionaries, as used by the arg_attrs and res_attrs of functions.
+func parseDictArray(text string) ([]map[string]string, error) {
+	if text == "" {
+		return nil, nil
+	}
+	imp := &importer{text: text}
+	if err := imp.expect("["); err != nil {
+		return nil, err
+	}
+	var dicts []map[string]string
+	for !imp.consume("]") {
+		if len(dicts) > 0 {
+			if err := imp.expect(","); err != nil {
+				return nil, err
+			}
+		}
+		dict, err := imp.parseDict()
+		if err != nil {
+			return nil, err
+		}
+		dicts = append(dicts, dict)
+	}
+	return dicts, nil
+}
+
+// parseValueName parses a value name, including the "%" prefix.
+func (imp *importer) parseValueName() (string, error) {
+	if !imp.consume("%") {
+		return "", errors.Errorf("expected value name, got %q", imp.context())
+	}
+	start := imp.pos
+	for imp.pos < len(imp.text) && isIdentifierChar(imp.text[imp.pos]) {
+		imp.pos++
+	}
+	if start == imp.pos {
+		return "", errors.Errorf("expected value name, got %q", imp.context())
+	}
+	return "%" + imp.text[start:imp.pos], nil
+}
+
+// importBlockArg is an argument of a block.
+type importBlockArg struct {
+	name  string
+	shape shapes.Shape
+}
+
+// parseBlockHeader parses a block label with its arguments, e.g. "^bb0(%x: tensor<f32>):".
+func (imp *importer) parseBlockHeader() (label string, args []importBlockArg, err error) {
+	if err = imp.expect("^"); err != nil {
+		return
+	}
+	if label, err = imp.parseIdentifier(); err != nil {
+		return
+	}
+	if imp.consume("(") {
+		for !imp.consume(")") {
+			if len(args) > 0 {
+				if err = imp.expect(","); err != nil {
+					return
+				}
+			}
+			var arg importBlockArg
+			imp.skipSpace()
+			if arg.name, err = imp.parseValueName(); err != nil {
+				return
+			}
+			if err = imp.expect(":"); err != nil {
+				return
+			}
+			if arg.shape, err = imp.parseType(); err != nil {
+				return
+			}
+			args = append(args, arg)
+		}
+	}
+	err = imp.expect(":")
+	return
+}
+
+// importOpTypes maps the StableHLO names of the operations to their OpType.
+var importOpTypes = func() map[string]optypes.OpType {
+	opTypes := make(map[string]optypes.OpType)
+	for _, op := range optypes.OpTypeValues() {
+		switch op {
+		case optypes.Invalid, optypes.Last, optypes.RawSnippet, optypes.Identity, optypes.FuncReturn:
+			continue
+		}
+		opTypes[op.ToStableHLO()] = op
+	}
+	return opTypes
+}()
+
+// parseStatements parses the statements of a function (or closure) until its closing "}".
+// The outputsAttributes are used when the function returns.
+func (imp *importer) parseStatements(fn *Function, scope importScope, outputsAttributes []map[string]string) error {
+	for !imp.peek("}") {
+		if imp.pos >= len(imp.text) {
+			return errors.New("unexpected end of the program")
+		}
+		if err := imp.parseStatement(fn, scope, outputsAttributes); err != nil {
+			return errors.WithMessagef(err, "line %d", imp.line())
+		}
+	}
+	if !fn.Returned {
+		return errors.New("missing return statement")
+	}
+	return nil
+}
+
+// importResult is the name of the result of a statement. If count > 0, it is a "%name:count" group of results.
+type importResult struct {
+	name  string
+	count int
+}
+
+// parseStatement parses one operation in the generic form and adds it to fn.
+func (imp *importer) parseStatement(fn *Function, scope importScope, outputsAttributes []map[string]string) error {
+	// Results:
+	var results []importResult
+	if imp.peek("%") {
+		for {
+			name, err := imp.parseValueName()
+			if err != nil {
+				return err
+			}
+			result := importResult{name: name}
+			if imp.consume(":") {
+				countStr, err := imp.parseIdentifier()
+				if err != nil {
+					return err
+				}
+				if result.count, err = strconv.Atoi(countStr); err != nil {
+					return errors.Errorf("invalid number of results %q for %s", countStr, name)
+				}
+			}
+			results = append(results, result)
+			if !imp.consume(",") {
+				break
+			}
+		}
+		if err := imp.expect("="); err != nil {
+			return err
+		}
+	}
+
+	// Operation name and operands:
+	imp.skipSpace()
+	if !imp.peek(`"`) {
+		return errors.Errorf("only operations in the generic form (e.g. `\"stablehlo.add\"(%%x, %%y)`) are supported, got %q",
+			imp.context())
+	}
+	opName, err := imp.parseIdentifier()
+	if err != nil {
+		return err
+	}
+	if err := imp.expect("("); err != nil {
+		return err
+	}
+	var operands []*Value
+	for !imp.consume(")") {
+		if len(operands) > 0 {
+			if err := imp.expect(","); err != nil {
+				return err
+			}
+		}
+		imp.skipSpace()
+		name, err := imp.parseValueName()
+		if err != nil {
+			return err
+		}
+		if imp.consume("#") {
+			idx, err := imp.parseIdentifier()
+			if err != nil {
+				return err
+			}
+			name += "#" + idx
+		}
+		operand, found := scope[name]
+		if !found {
+			return errors.Errorf("%s: unknown value %s (values defined outside a closure can't be used in it)",
+				opName, name)
+		}
+		operands = append(operands, operand)
+	}
+	if imp.peek("[") {
+		return errors.Errorf("%s: operations with successors (branches) are not supported", opName)
+	}
+
+	// Properties, regions and attributes:
+	attributes := make(map[string]string)
+	var properties string
+	if imp.peek("<{") {
+		start := imp.pos
+		imp.pos++
+		dict, err := imp.parseDict()
+		if err != nil {
+			return err
+		}
+		if err := imp.expect(">"); err != nil {
+			return err
+		}
+		properties = imp.text[start:imp.pos]
+		maps.Copy(attributes, dict)
+	}
+	var closures []*Function
+	var closuresNames []string
+	if imp.consume("(") {
+		for !imp.consume(")") {
+			if len(closures) > 0 {
+				if err := imp.expect(","); err != nil {
+					return err
+				}
+			}
+			name, closure, err := imp.parseRegion(fn)
+			if err != nil {
+				return errors.WithMessagef(err, "region #%d of %s", len(closures), opName)
+			}
+			closures = append(closures, closure)
+			closuresNames = append(closuresNames, name)
+		}
+	}
+	var attributesText string
+	if imp.peek("{") {
+		start := imp.pos
+		dict, err := imp.parseDict()
+		if err != nil {
+			return err
+		}
+		attributesText = imp.text[start:imp.pos]
+		maps.Copy(attributes, dict)
+	}
+
+	// Signature:
+	if err := imp.expect(":"); err != nil {
+		return err
+	}
+	inputShapes, outputShapes, err := imp.parseFunctionType()
+	if err != nil {
+		return err
+	}
+	imp.skipLocation()
+	if err := checkImportedTypes(valuesToShapes(operands), inputShapes); err != nil {
+		return errors.WithMessagef(err, "%s operands", opName)
+	}
+
+	// Returns:
+	if opName == "stablehlo.return" || opName == "func.return" {
+		var returnAttributes []map[string]any
+		if slices.ContainsFunc(outputsAttributes, func(dict map[string]string) bool { return len(dict) > 0 }) {
+			returnAttributes = make([]map[string]any, len(operands))
+			for i := range min(len(operands), len(outputsAttributes)) {
+				returnAttributes[i] = dictToAttributes(outputsAttributes[i])
+			}
+		}
+		return fn.ReturnWithAttributes(operands, returnAttributes)
+	}
+
+	// Create statement:
+	var outputs []*Value
+	if op, found := importOpTypes[opName]; found {
+		stmt := fn.addMultiOp(op, outputShapes, operands)
+		stmt.Attributes = dictToAttributes(attributes)
+		for i, closure := range closures {
+			stmt.AddFunctionParameter(closuresNames[i], closure)
+		}
+		outputs = stmt.Outputs
+	} else {
+		if len(closures) > 0 {
+			return errors.Errorf("operation %s with regions is not supported", opName)
+		}
+		placeholders := make([]string, len(operands))
+		for i := range operands {
+			placeholders[i] = fmt.Sprintf("$%d", i)
+		}
+		snippet := fmt.Sprintf("%q(%s)", opName, strings.Join(placeholders, ", "))
+		if properties != "" {
+			snippet += " " + properties
+		}
+		if attributesText != "" {
+			snippet += " " + attributesText
+		}
+		if outputs, err = fn.RawStatement(snippet, operands, outputShapes...); err != nil {
+			return err
+		}
+	}
+
+	// Name the results:
+	numResults := 0
+	for _, result := range results {
+		numResults += max(result.count, 1)
+	}
+	if numResults != len(outputs) {
+		return errors.Errorf("%s has %d outputs, but %d results were named", opName, len(outputs), numResults)
+	}
+	idx := 0
+	for _, result := range results {
+		if result.count == 0 {
+			outputs[idx].name = ConvertToValidName(strings.TrimPrefix(result.name, "%"))
+			scope[result.name] = outputs[idx]
+			idx++
+			continue
+		}
+		for i := range result.count {
+			scope[fmt.Sprintf("%s#%d", result.name, i)] = outputs[idx]
+			idx++
+		}
+	}
+	return nil
+}
+
+// parseRegion parses a region of an operation, "{ ^label(args): statements }", into a closure of fn.
+func (imp *importer) parseRegion(fn *Function) (name string, closure *Function, err error) {
+	if err = imp.expect("{"); err != nil {
+		return
+	}
+	closure = fn.Closure()
+	name = closure.Name
+	scope := make(importScope)
+	if imp.peek("^") {
+		var args []importBlockArg
+		if name, args, err = imp.parseBlockHeader(); err != nil {
+			return
+		}
+		for _, arg := range args {
+			if err = imp.addInput(closure, scope, arg.name, arg.shape, nil); err != nil {
+				return
+			}
+		}
+	}
+	if err = imp.parseStatements(closure, scope, nil); err != nil {
+		return
+	}
+	err = imp.expect("}")
+	return
+}
@@ -0,0 +1,145 @@
+package stablehlo
+
+import (
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/gomlx/stablehlo/types/shardy"
+)
+
+// requireRoundTrip imports the program built by builder, and checks that it renders back to the same program.
+func requireRoundTrip(t *testing.T, builder *Builder) *Builder {
+	t.Helper()
+	program := must(builder.Build())
+	imported, err := Import(program)
+	if err != nil {
+		t.Fatalf("Import failed: %+v\nprogram:\n%s", err, program)
+	}
+	imported.genericForm = builder.genericForm
+	got := must(imported.Build())
+	if string(got) != string(program) {
+		t.Fatalf("imported program differs:\nwant:\n%s\ngot:\n%s", program, got)
+	}
+	return imported
+}
+
+func TestImport(t *testing.T) {
+	t.Run("Calls", func(t *testing.T) {
+		requireRoundTrip(t, buildCallsProgram(t))
+	})
+
+	t.Run("Features", func(t *testing.T) {
+		mesh := must(shardy.NewDeviceMesh("mesh", []int{2, 2}, []string{"data", "model"}))
+		must0(mesh.SetLogicalDeviceAssignment(3, 2, 1, 0))
+		builder := New(t.Name()).WithNumReplicas(1).WithNumPartitions(4).WithShardy(mesh)
+		fn := builder.Main()
+		x := must(fn.NamedInputWithSharding("x", shapes.Make(dtypes.Float32, 4, 2),
+			builder.NewShardingSpec().AddShardedAxis("data")))
+		y := must(fn.Input(shapes.Make(dtypes.Int32)))
+		sum := must(Add(x, x))
+		tOut := must(Transpose(sum, 1, 0))
+		c := must(fn.ConstantFromFlatAndDimensions([]float32{1, 2}, 2))
+		custom := must(fn.CustomCall("handler", []shapes.Shape{c.Shape(), y.Shape()}, c, y).
+			BackendConfig(NewBackendConfig().Float32("alpha", 0.5).String("name", "a, b }")).
+			Done())
+		raw := must(fn.RawStatement(`"mydialect.op"($0) { weird = "}>" }`, []*Value{custom[1]}, y.Shape()))
+		normal, mean, variance, err := BatchNormTraining(x, c, c, 1e-3, 1)
+		must0(err)
+		must0(fn.ReturnWithShardingAndAttributes(
+			[]*Value{tOut, custom[0], raw[0], normal, mean, variance},
+			[]*shardy.ShardingSpec{nil, nil, nil, builder.NewShardingSpec().AddShardedAxis("model"), nil, nil},
+			nil))
+		imported := requireRoundTrip(t, builder)
+
+		// New values created after importing must not clash with the imported ones.
+		if imported.numPartitions != 4 || len(imported.meshes) != 1 {
+			t.Errorf("imported builder lost module attributes or meshes")
+		}
+		main := imported.functions[0]
+		if main.nextTmpID <= 8 || main.nextArgID != 1 {
+			t.Errorf("imported function has nextTmpID=%d and nextArgID=%d", main.nextTmpID, main.nextArgID)
+		}
+	})
+
+	t.Run("GenericForm", func(t *testing.T) {
+		builder := buildCallsProgram(t).WithGenericForm(true)
+		requireRoundTrip(t, builder)
+	})
+
+	t.Run("Handwritten", func(t *testing.T) {
+		program := `#loc1 = loc("model.py":10:0)
+module @handwritten attributes {mhlo.frontend_attributes = {}, stablehlo.num_replicas = 2 : i64} {
+  // A comment.
+  func.func public @main(%arg0: tensor<2xf32> loc("x"), %b.1: tensor<2xf32>) -> (tensor<f32> {jax.result_info = "out"}) {
+    %0 = "stablehlo.constant"() <{value = dense<0.0> : tensor<f32>}> : () -> tensor<f32>
+    %r:2 = "stablehlo.reduce"(%arg0, %b.1, %0, %0) <{dimensions = array<i64: 0>}> ({
+    ^bb0(%p0: tensor<f32>, %p1: tensor<f32>, %p2: tensor<f32>, %p3: tensor<f32>):
+      %c = "stablehlo.add"(%p0, %p2) : (tensor<f32>, tensor<f32>) -> tensor<f32> loc(#loc1)
+      %d = "stablehlo.maximum"(%p1, %p3) : (tensor<f32>, tensor<f32>) -> tensor<f32>
+      "stablehlo.return"(%c, %d) : (tensor<f32>, tensor<f32>) -> ()
+    }) : (tensor<2xf32>, tensor<2xf32>, tensor<f32>, tensor<f32>) -> (tensor<f32>, tensor<f32>)
+    %5 = "stablehlo.add"(%r#0, %r#1) : (tensor<f32>, tensor<f32>) -> tensor<f32>
+    "func.return"(%5) : (tensor<f32>) -> ()
+  }
+}
+`
+		builder, err := Import([]byte(program))
+		if err != nil {
+			t.Fatalf("Import failed: %+v", err)
+		}
+		// Values created after the import must not clash with the imported names.
+		names := make(map[string]bool)
+		for _, fn := range builder.functions {
+			for _, value := range fn.values {
+				if names[value.name] {
+					t.Errorf("value name %s is used more than once", value)
+				}
+				names[value.name] = true
+				if id, err := strconv.Atoi(value.name); err == nil && id >= fn.findRootFn().nextTmpID {
+					t.Errorf("value %s is not below nextTmpID=%d", value, fn.findRootFn().nextTmpID)
+				}
+			}
+		}
+		got := string(must(builder.Build()))
+		for _, want := range []string{
+			`module @handwritten attributes {stablehlo.num_replicas = 2} {`,
+			`func.func @main(%arg0: tensor<2xf32>, %b_1: tensor<2xf32>) -> (tensor<f32> { jax.result_info = "out" }) {`,
+			`%9, %10 = "stablehlo.reduce"(%arg0, %b_1, %0, %0) ({`,
+			`^bb0(%p0: tensor<f32>, %p1: tensor<f32>, %p2: tensor<f32>, %p3: tensor<f32>) :`,
+			`%c = "stablehlo.add"(%p0, %p2) : (tensor<f32>, tensor<f32>) -> tensor<f32>`,
+			`"stablehlo.return"(%c, %d) : (tensor<f32>, tensor<f32>) -> ()`,
+			`}) { dimensions = array<i64: 0> } : (tensor<2xf32>, tensor<2xf32>, tensor<f32>, tensor<f32>) -> (tensor<f32>, tensor<f32>)`,
+			`%5 = "stablehlo.add"(%9, %10) : (tensor<f32>, tensor<f32>) -> tensor<f32>`,
+			`"stablehlo.return"(%5) : (tensor<f32>) -> ()`,
+		} {
+			if !strings.Contains(got, want) {
+				t.Errorf("imported program missing %q, got:\n%s", want, got)
+			}
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		for _, program := range []string{
+			`module @m { func.func @main(%x: tensor<f32>) -> tensor<f32> { %0 = stablehlo.negate %x : tensor<f32> } }`,
+			`module @m { func.func @main(%x: tensor<f32>) -> tensor<f32> { "stablehlo.return"(%y) : (tensor<f32>) -> () } }`,
+			`module @m { func.func @main(%x: tensor<f32>) -> tensor<f32> { } }`,
+			`module @m { func.func @main(%x: tensor<3xfoo>) -> tensor<f32> { "stablehlo.return"(%x) : (tensor<f32>) -> () } }`,
+			`module @m { func.func @main(%x: tensor<f32>) -> tensor<f32> { "stablehlo.return"(%x) : (tensor<f32>) -> () }`,
+			`module @m { foo }`,
+			// Operand types that don't match the operands.
+			`module @m { func.func @main(%x: tensor<f32>) -> tensor<f32> { %0 = "stablehlo.negate"(%x) : (tensor<f32>, tensor<f32>) -> tensor<f32> "stablehlo.return"(%0) : (tensor<f32>) -> () } }`,
+			`module @m { func.func @main(%x: tensor<f32>) -> tensor<f32> { %0 = "stablehlo.negate"(%x) : (tensor<3xf32>) -> tensor<f32> "stablehlo.return"(%0) : (tensor<f32>) -> () } }`,
+			// Returned values that don't match the declared results.
+			`module @m { func.func @main(%x: tensor<3xf32>) -> tensor<f32> { "stablehlo.return"(%x) : (tensor<3xf32>) -> () } }`,
+			`module @m { "func.func"() <{function_type = (tensor<3xf32>) -> tensor<f32>, sym_name = "main"}> ({ ^bb0(%x: tensor<3xf32>): "func.return"(%x) : (tensor<3xf32>) -> () }) : () -> () }`,
+			`module @m { "func.func"() <{function_type = (tensor<f32>) -> tensor<3xf32>, sym_name = "main"}> ({ ^bb0(%x: tensor<3xf32>): "func.return"(%x) : (tensor<3xf32>) -> () }) : () -> () }`,
+		} {
+			if _, err := Import([]byte(program)); err == nil {
+				t.Errorf("Import should have failed for %q", program)
+			}
+		}
+	})
+}
@@ -0,0 +1,211 @@
+package stablehlo
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/pkg/errors"
+)
+
+// InlineCalls inlines the functions called with Function.Call into their call sites, where it is deemed beneficial:
+// calls to functions with at most maxStatements statements (not counting the return), or to functions called only
+// once. If maxStatements is negative, all calls are inlined.
+//
+// Functions with collective operations that use channel handles are only inlined if called once, since
+// their channel ids must be unique.
+//
+// Inlining trades program size (and compile time) for the opportunity of the compiler to optimize across the call
+// boundary. Functions that are no longer called after inlining are removed from the program.
+//
+// All top-level functions must have been returned (see Function.Return). It returns the number of calls inlined.
+//
+// Outlining (the converse, extracting repeated subgraphs into functions) is not supported.
+func (b *Builder) InlineCalls(maxStatements int) (numInlined int, err error) {
+	for _, fn := range b.functions {
+		if fn.Parent == nil && !fn.Returned {
+			return 0, errors.Errorf("InlineCalls requires all functions to be returned, but %q is not", fn.Name)
+		}
+	}
+	calledBefore := b.countCalls()
+	for {
+		counts := b.countCalls()
+		inlined := false
+		for _, fn := range slices.Clone(b.functions) {
+			for idx := 0; idx < len(fn.Statements); idx++ {
+				stmt := fn.Statements[idx]
+				if stmt.OpType != optypes.FuncCall {
+					continue
+				}
+				callee := stmt.calleeOf()
+				if callee == nil {
+					return numInlined, errors.Errorf("InlineCalls: callee %v of function %q not found",
+						stmt.Attributes["callee"], fn.Name)
+				}
+				calledOnce := counts[callee] == 1
+				if !calledOnce && (callee.hasChannelHandles() ||
+					(maxStatements >= 0 && len(callee.Statements)-1 > maxStatements)) {
+					continue
+				}
+				numNew := fn.inlineCall(idx, callee)
+				idx += numNew - 1
+				numInlined++
+				inlined = true
+			}
+		}
+		if !inlined {
+			break
+		}
+	}
+
+	// Remove functions that are no longer called.
+	counts := b.countCalls()
+	b.functions = slices.DeleteFunc(b.functions, func(fn *Function) bool {
+		rootFn := fn.findRootFn()
+		return rootFn.Name != MainFunctionName && calledBefore[rootFn] > 0 && counts[rootFn] == 0
+	})
+	return numInlined, nil
+}
+
+// countCalls returns the number of FuncCall statements calling each function.
+func (b *Builder) countCalls() map[*Function]int {
+	counts := make(map[*Function]int)
+	for _, fn := range b.functions {
+		for _, stmt := range fn.Statements {
+			if stmt.OpType == optypes.FuncCall {
+				counts[stmt.calleeOf()]++
+			}
+		}
+	}
+	return counts
+}
+
+// hasChannelHandles returns whether any of the statements of the function, or of its closures, uses a channel handle.
+func (fn *Function) hasChannelHandles() bool {
+	for _, stmt := range fn.Statements {
+		if _, found := stmt.Attributes["channel_handle"]; found {
+			return true
+		}
+		for _, closure := range stmt.FunctionParameters {
+			if closure.hasChannelHandles() {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// inlineCall replaces the FuncCall statement at stmtIdx by the statements of the callee.
+// It returns the number of statements inserted.
+func (fn *Function) inlineCall(stmtIdx int, callee *Function) int {
+	call := fn.Statements[stmtIdx]
+	mapping := make(map[*Value]*Value, len(callee.values))
+	for i, input := range callee.Inputs {
+		mapping[input] = call.Inputs[i]
+	}
+	replacements := make(map[*Value]*Value, len(call.Outputs))
+	newStatements := make([]*Statement, 0, len(callee.Statements))
+	for _, stmt := range callee.Statements {
+		if stmt.OpType == optypes.FuncReturn {
+			for i, value := range stmt.Inputs {
+				replacements[call.Outputs[i]] = mapping[value]
+			}
+			continue
+		}
+		newStatements = append(newStatements, fn.cloneStatement(stmt, mapping))
+	}
+	call.unregisterUses()
+	fn.Statements = slices.Concat(fn.Statements[:stmtIdx], newStatements, fn.Statements[stmtIdx+1:])
+	fn.replaceUses(replacements)
+	return len(newStatements)
+}
+
+// cloneStatement clones a statement (from another function) into fn, using mapping to translate the input values.
+// The new output values are added to the mapping. Closures are cloned as well.
+func (fn *Function) cloneStatement(stmt *Statement, mapping map[*Value]*Value) *Statement {
+	newStmt := &Statement{
+		Builder:                 fn.Builder,
+		id:                      fn.Builder.newStatementID(),
+		Function:                fn,
+		OpType:                  stmt.OpType,
+		Inputs:                  make([]*Value, len(stmt.Inputs)),
+		Attributes:              maps.Clone(stmt.Attributes),
+		FunctionParametersNames: slices.Clone(stmt.FunctionParametersNames),
+		Outputs:                 make([]*Value, len(stmt.Outputs)),
+		rawSnippet:              stmt.rawSnippet,
+	}
+	for i, input := range stmt.Inputs {
+		newStmt.Inputs[i] = mapping[input]
+	}
+	newStmt.registerUses()
+	for _, closure := range stmt.FunctionParameters {
+		newStmt.FunctionParameters = append(newStmt.FunctionParameters, fn.cloneClosure(closure))
+	}
+	for i, output := range stmt.Outputs {
+		newOutput := fn.newValue(output.shape)
+		newOutput.Attributes = maps.Clone(output.Attributes)
+		newOutput.mergeTagsFrom(output)
+		newStmt.Outputs[i] = newOutput
+		mapping[output] = newOutput
+	}
+	return newStmt
+}
+
+// cloneClosure creates a new closure of fn with a copy of the given closure.
+func (fn *Function) cloneClosure(closure *Function) *Function {
+	newClosure := fn.Closure()
+	newClosure.nextArgID = closure.nextArgID
+	mapping := make(map[*Value]*Value, len(closure.values))
+	for _, input := range closure.Inputs {
+		newInput := &Value{
+			fn:         newClosure,
+			name:       input.name,
+			shape:      input.shape,
+			Attributes: maps.Clone(input.Attributes),
+		}
+		newInput.mergeTagsFrom(input)
+		newClosure.Inputs = append(newClosure.Inputs, newInput)
+		newClosure.values = append(newClosure.values, newInput)
+		mapping[input] = newInput
+	}
+	for _, stmt := range closure.Statements {
+		newClosure.Statements = append(newClosure.Statements, newClosure.cloneStatement(stmt, mapping))
+	}
+	if len(closure.Outputs) > 0 {
+		// The last statement is the return, with the new values returned.
+		returnStmt := newClosure.Statements[len(newClosure.Statements)-1]
+		for i, output := range closure.Outputs {
+			newClosure.Outputs = append(newClosure.Outputs, &Value{
+				fn:         newClosure,
+				name:       returnStmt.Inputs[i].name,
+				shape:      output.shape,
+				Attributes: maps.Clone(output.Attributes),
+			})
+		}
+	}
+	newClosure.Returned = closure.Returned
+	return newClosure
+}
+
+// replaceUses replaces the uses of the values in fn by their replacements.
+// If the function is returned, the names of its outputs are updated accordingly. The replacements get merged the
+// tags of the values they replace, see Value.SetTag.
+func (fn *Function) replaceUses(replacements map[*Value]*Value) {
+	for original, replacement := range replacements {
+		replacement.mergeTagsFrom(original)
+	}
+	for _, stmt := range fn.Statements {
+		for i, input := range stmt.Inputs {
+			if replacement, found := replacements[input]; found {
+				stmt.Inputs[i] = replacement
+				input.removeUse(stmt)
+				replacement.addUse(stmt)
+			}
+		}
+		if stmt.OpType == optypes.FuncReturn {
+			for i, input := range stmt.Inputs {
+				fn.Outputs[i].name = input.name
+			}
+		}
+	}
+}
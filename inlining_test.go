@@ -0,0 +1,131 @@
+package stablehlo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+// buildCallsProgram builds a program where main calls "square_plus_one" twice and "sum_all" once.
+func buildCallsProgram(t *testing.T) *Builder {
+	builder := New(t.Name())
+	f := builder.NewFunction("square_plus_one")
+	{
+		x := must(f.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		one := must(f.ConstantFromFlatAndDimensions([]float32{1, 1, 1}, 3))
+		must0(f.Return(must(Add(must(Multiply(x, x)), one))))
+	}
+	g := builder.NewFunction("sum_all")
+	{
+		x := must(g.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		sumFn := g.Closure()
+		lhs := must(sumFn.NamedInput("lhs", shapes.Make(dtypes.Float32)))
+		rhs := must(sumFn.NamedInput("rhs", shapes.Make(dtypes.Float32)))
+		must0(sumFn.Return(must(Add(lhs, rhs))))
+		zero := must(g.ConstantFromScalar(float32(0)))
+		must0(g.Return(must(Reduce(x, zero, sumFn, 0))))
+	}
+	fn := builder.Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+	y := must(fn.Call(f, x))[0]
+	y = must(fn.Call(f, y))[0]
+	total := must(fn.Call(g, y))[0]
+	must0(fn.Return(y, total))
+	return builder
+}
+
+func must0(err error) {
+	if err != nil {
+		panic(err)
+	}
+}
+
+func TestCall(t *testing.T) {
+	builder := buildCallsProgram(t)
+	program := string(must(builder.Build()))
+	fmt.Printf("%s program:\n%s", t.Name(), program)
+	for _, want := range []string{
+		`%0 = "func.call"(%x) { callee = @square_plus_one } : (tensor<3xf32>) -> tensor<3xf32>`,
+		`"func.call"(%1) { callee = @sum_all } : (tensor<3xf32>) -> tensor<f32>`,
+	} {
+		if !strings.Contains(program, want) {
+			t.Errorf("program missing %q", want)
+		}
+	}
+
+	// Errors.
+	b := New(t.Name())
+	f := b.NewFunction("f")
+	fx := must(f.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+	fn := b.Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2)))
+	if _, err := fn.Call(f, x); err == nil {
+		t.Error("expected error calling a function not yet returned")
+	}
+	must0(f.Return(fx))
+	if _, err := fn.Call(f, x); err == nil {
+		t.Error("expected error calling a function with the wrong shape")
+	}
+	if _, err := fn.Call(fn.Closure(), x); err == nil {
+		t.Error("expected error calling a closure")
+	}
+	if _, err := fn.Call(fn, x); err == nil {
+		t.Error("expected error for a function calling itself")
+	}
+}
+
+func TestInlineCalls(t *testing.T) {
+	t.Run("all", func(t *testing.T) {
+		builder := buildCallsProgram(t)
+		numInlined := must(builder.InlineCalls(-1))
+		if numInlined != 3 {
+			t.Errorf("expected 3 calls inlined, got %d", numInlined)
+		}
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		for _, unwanted := range []string{"func.call", "@square_plus_one", "@sum_all"} {
+			if strings.Contains(program, unwanted) {
+				t.Errorf("inlined program should not contain %q", unwanted)
+			}
+		}
+		if got := strings.Count(program, `"stablehlo.multiply"`); got != 2 {
+			t.Errorf("expected 2 multiply statements, got %d", got)
+		}
+		if !strings.Contains(program, `"stablehlo.reduce"`) || !strings.Contains(program, "^reductionFn") {
+			t.Errorf("expected the reduce and its closure to be inlined")
+		}
+		if len(builder.functions) != 2 {
+			t.Errorf("expected 2 functions left (main and the reduce closure), got %d", len(builder.functions))
+		}
+	})
+
+	t.Run("small", func(t *testing.T) {
+		// square_plus_one has 3 statements and is called twice: it is not inlined.
+		// sum_all is called once, so it is inlined.
+		builder := buildCallsProgram(t)
+		numInlined := must(builder.InlineCalls(2))
+		if numInlined != 1 {
+			t.Errorf("expected 1 call inlined, got %d", numInlined)
+		}
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		if got := strings.Count(program, `callee = @square_plus_one`); got != 2 {
+			t.Errorf("expected 2 calls to square_plus_one, got %d", got)
+		}
+		if strings.Contains(program, "@sum_all") {
+			t.Errorf("expected sum_all to be inlined and removed")
+		}
+	})
+
+	t.Run("not returned", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		_ = must(fn.NamedInput("x", shapes.Make(dtypes.Float32)))
+		if _, err := builder.InlineCalls(-1); err == nil {
+			t.Error("expected error inlining with functions not returned")
+		}
+	})
+}
@@ -0,0 +1,139 @@
+package stablehlo
+
+import (
+	"slices"
+
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/pkg/errors"
+)
+
+// InsertBefore creates statements in the function at the position of stmt, instead of appending them at the end:
+// the statements created by build (calling the ops on fn as usual) are moved to just before stmt.
+// It returns the inserted statements.
+//
+// The new statements can only use the values defined before stmt (or created by build), otherwise the order of
+// the statements would be invalid: in that case, or if build returns an error, the new statements are dropped
+// and an error is returned.
+//
+// It can be used after the function is returned, e.g. by transformation passes. See also SpliceStatements.
+//
+// Example, scaling the input of a statement:
+//
+//	var scaled *Value
+//	_, err := fn.InsertBefore(stmt, func() (err error) {
+//		scaled, err = MultiplyScalar(stmt.Inputs[0], 2)
+//		return err
+//	})
+func (fn *Function) InsertBefore(stmt *Statement, build func() error) ([]*Statement, error) {
+	idx := slices.Index(fn.Statements, stmt)
+	if idx < 0 {
+		return nil, errors.Errorf("InsertBefore: statement is not part of function %q", fn.Name)
+	}
+	return fn.insertAt(idx, build)
+}
+
+// InsertAfter is like InsertBefore, but the statements created by build are moved to just after stmt, so they can
+// use its outputs. stmt can't be the return statement of the function.
+func (fn *Function) InsertAfter(stmt *Statement, build func() error) ([]*Statement, error) {
+	idx := slices.Index(fn.Statements, stmt)
+	if idx < 0 {
+		return nil, errors.Errorf("InsertAfter: statement is not part of function %q", fn.Name)
+	}
+	if stmt.OpType == optypes.FuncReturn {
+		return nil, errors.Errorf("InsertAfter: can't insert statements after the return statement of function %q", fn.Name)
+	}
+	return fn.insertAt(idx+1, build)
+}
+
+// insertAt implements InsertBefore and InsertAfter: it moves the statements created by build to the position idx.
+func (fn *Function) insertAt(idx int, build func() error) (inserted []*Statement, err error) {
+	if err := fn.Err(); err != nil {
+		return nil, err
+	}
+	numStatements := len(fn.Statements)
+	defer func() {
+		if err != nil {
+			// Drop the statements created by build.
+			fn.truncateStatements(numStatements)
+		}
+	}()
+	returned := fn.Returned
+	fn.Returned = false
+	err = build()
+	fn.Returned = returned
+	if err == nil {
+		err = fn.Err()
+	}
+	if err != nil {
+		return nil, errors.WithMessage(err, "inserting statements")
+	}
+	if len(fn.Statements) < numStatements {
+		return nil, errors.Errorf("inserting statements: statements of function %q were removed while building them", fn.Name)
+	}
+	inserted = slices.Clone(fn.Statements[numStatements:])
+	for _, stmt := range inserted {
+		if stmt.OpType == optypes.FuncReturn {
+			return nil, errors.Errorf("inserting statements: can't insert a return statement in function %q", fn.Name)
+		}
+	}
+	reordered := slices.Concat(fn.Statements[:idx], inserted, fn.Statements[idx:numStatements])
+	if err = fn.checkStatementsOrder(reordered); err != nil {
+		return nil, errors.WithMessage(err, "inserting statements")
+	}
+	fn.Statements = reordered
+	return inserted, nil
+}
+
+// SpliceStatements moves the range of statements [from, to) of the function to the position dest, an index in the
+// current fn.Statements (outside the range, or equal to its end), keeping their relative order.
+//
+// The order of the statements must stay valid: the moved statements must be placed after the definition of their
+// operands, and before the uses of their outputs. Otherwise, an error is returned and nothing is changed.
+func (fn *Function) SpliceStatements(from, to, dest int) error {
+	numStatements := len(fn.Statements)
+	if from < 0 || to > numStatements || from > to {
+		return errors.Errorf("SpliceStatements: invalid range [%d, %d) for function %q with %d statements",
+			from, to, fn.Name, numStatements)
+	}
+	if dest < 0 || dest > numStatements || (dest > from && dest < to) {
+		return errors.Errorf("SpliceStatements: invalid destination %d for the range [%d, %d) in function %q with %d statements",
+			dest, from, to, fn.Name, numStatements)
+	}
+	moved := fn.Statements[from:to]
+	var reordered []*Statement
+	if dest <= from {
+		reordered = slices.Concat(fn.Statements[:dest], moved, fn.Statements[dest:from], fn.Statements[to:])
+	} else {
+		reordered = slices.Concat(fn.Statements[:from], fn.Statements[to:dest], moved, fn.Statements[dest:])
+	}
+	if err := fn.checkStatementsOrder(reordered); err != nil {
+		return errors.WithMessagef(err, "SpliceStatements([%d, %d) to %d)", from, to, dest)
+	}
+	fn.Statements = reordered
+	return nil
+}
+
+// checkStatementsOrder checks that, in the given order of the statements of fn, each operand is defined (as an input
+// of fn or as an output of a previous statement) before it is used, and that the return statement is the last one.
+func (fn *Function) checkStatementsOrder(statements []*Statement) error {
+	defined := make(map[*Value]bool, len(fn.Inputs)+len(statements))
+	for _, input := range fn.Inputs {
+		defined[input] = true
+	}
+	for idx, stmt := range statements {
+		if stmt.OpType == optypes.FuncReturn && idx != len(statements)-1 {
+			return errors.Errorf("the return statement of function %q must be the last one, but it would be statement #%d of %d",
+				fn.Name, idx, len(statements))
+		}
+		for i, input := range stmt.Inputs {
+			if !defined[input] {
+				return errors.Errorf("statement #%d (%s) of function %q would use operand #%d (%s) before it is defined",
+					idx, stmt.OpName(), fn.Name, i, input)
+			}
+		}
+		for _, output := range stmt.Outputs {
+			defined[output] = true
+		}
+	}
+	return nil
+}
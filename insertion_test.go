@@ -0,0 +1,141 @@
+package stablehlo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestStatementInsertion(t *testing.T) {
+	// buildProgram returns a returned main function computing tanh(x) + exp(x).
+	buildProgram := func(name string) (*Builder, *Function) {
+		builder := New(name)
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		must0(fn.Return(must(Add(must(Tanh(x)), must(Exponential(x))))))
+		return builder, fn
+	}
+
+	t.Run("Insert", func(t *testing.T) {
+		builder, fn := buildProgram(t.Name())
+		tanhStmt, addStmt := fn.Statements[0], fn.Statements[2]
+		x := fn.Inputs[0]
+
+		// Scale x before the addition.
+		var scaled *Value
+		inserted := must(fn.InsertBefore(addStmt, func() (err error) {
+			scaled, err = MultiplyScalar(x, 2)
+			return err
+		}))
+		if n := len(inserted); n == 0 || fn.Statements[2] != inserted[0] || fn.Statements[2+n] != addStmt ||
+			inserted[n-1].Outputs[0] != scaled {
+			t.Fatalf("InsertBefore inserted %d statements in the wrong position", n)
+		}
+		// Negate tanh(x) right after it's computed.
+		var negated *Value
+		must(fn.InsertAfter(tanhStmt, func() (err error) {
+			negated, err = Negate(tanhStmt.Outputs[0])
+			return err
+		}))
+		if fn.Statements[1].Outputs[0] != negated {
+			t.Fatalf("InsertAfter inserted the statement in the wrong position")
+		}
+
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestStatementInsertion_Insert {
+  func.func @main(%x: tensor<3xf32>) -> tensor<3xf32> {
+    %0 = "stablehlo.tanh"(%x) : (tensor<3xf32>) -> tensor<3xf32>
+    %6 = "stablehlo.negate"(%0) : (tensor<3xf32>) -> tensor<3xf32>
+    %1 = "stablehlo.exponential"(%x) : (tensor<3xf32>) -> tensor<3xf32>
+    %3 = "stablehlo.constant"() { value = dense<2.0> : tensor<f32> } : () -> tensor<f32>
+    %4 = "stablehlo.broadcast_in_dim"(%3) { broadcast_dimensions = array<i64> } : (tensor<f32>) -> tensor<3xf32>
+    %5 = "stablehlo.multiply"(%x, %4) : (tensor<3xf32>, tensor<3xf32>) -> tensor<3xf32>
+    %2 = "stablehlo.add"(%0, %1) : (tensor<3xf32>, tensor<3xf32>) -> tensor<3xf32>
+    "stablehlo.return"(%2) : (tensor<3xf32>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+	})
+
+	t.Run("Splice", func(t *testing.T) {
+		builder, fn := buildProgram(t.Name())
+		tanhStmt, expStmt := fn.Statements[0], fn.Statements[1]
+
+		// Move exp(x) before tanh(x).
+		must0(fn.SpliceStatements(1, 2, 0))
+		if fn.Statements[0] != expStmt || fn.Statements[1] != tanhStmt {
+			t.Fatalf("SpliceStatements didn't move the statement")
+		}
+		// And back, moving tanh(x) after exp(x) instead.
+		must0(fn.SpliceStatements(1, 2, 0))
+		if fn.Statements[0] != tanhStmt || fn.Statements[1] != expStmt {
+			t.Fatalf("SpliceStatements didn't move the statement back")
+		}
+		program := string(must(builder.Build()))
+		if !strings.Contains(program, `"stablehlo.tanh"`) {
+			t.Fatalf("unexpected program:\n%s", program)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		_, fn := buildProgram(t.Name())
+		tanhStmt, addStmt, returnStmt := fn.Statements[0], fn.Statements[2], fn.Statements[3]
+		statements := fn.Statements
+
+		// Statements using values defined after the insertion point.
+		if _, err := fn.InsertBefore(tanhStmt, func() error {
+			_, err := Negate(tanhStmt.Outputs[0])
+			return err
+		}); err == nil || !strings.Contains(err.Error(), "before it is defined") {
+			t.Errorf("expected error inserting a statement before its operand, got %v", err)
+		}
+		if _, err := fn.InsertAfter(returnStmt, func() error { return nil }); err == nil {
+			t.Error("expected error inserting after the return statement, got nil")
+		}
+		if _, err := fn.InsertBefore(addStmt, func() error {
+			_, err := Negate(fn.Inputs[0])
+			if err == nil {
+				err = fmt.Errorf("failed on purpose")
+			}
+			return err
+		}); err == nil || !strings.Contains(err.Error(), "failed on purpose") {
+			t.Errorf("expected error from build, got %v", err)
+		}
+		other := New("other").Main()
+		if _, err := other.InsertBefore(tanhStmt, func() error { return nil }); err == nil {
+			t.Error("expected error inserting before a statement of another function, got nil")
+		}
+
+		// Moving a statement after its uses, or the return statement.
+		if err := fn.SpliceStatements(0, 1, 3); err == nil || !strings.Contains(err.Error(), "before it is defined") {
+			t.Errorf("expected error moving a statement after its uses, got %v", err)
+		}
+		if err := fn.SpliceStatements(3, 4, 0); err == nil || !strings.Contains(err.Error(), "return statement") {
+			t.Errorf("expected error moving the return statement, got %v", err)
+		}
+		if err := fn.SpliceStatements(0, 2, 1); err == nil {
+			t.Error("expected error for a destination inside the range, got nil")
+		}
+
+		// The failed operations didn't change the function.
+		if len(fn.Statements) != len(statements) {
+			t.Fatalf("failed operations changed the number of statements from %d to %d", len(statements), len(fn.Statements))
+		}
+		for i, stmt := range statements {
+			if fn.Statements[i] != stmt {
+				t.Errorf("failed operations changed statement #%d", i)
+			}
+		}
+		if fn.Inputs[0].NumUses() != 2 {
+			t.Errorf("failed operations left %d uses of x, wanted 2", fn.Inputs[0].NumUses())
+		}
+	})
+}
@@ -34,6 +34,12 @@ func {{.Name}}(lhs, rhs *Value) (*Value, error) {
 	fn := lhs.fn
 	return fn.binaryOp(optypes.{{.Name}}, lhs, rhs)
 }
+
+// {{.Name}}Scalar implements the corresponding standard binary operation, with the Go scalar rhs converted to the
+// dtype of lhs and broadcast to its shape. See WithScalar.
+func {{.Name}}Scalar(lhs *Value, rhs any) (*Value, error) {
+	return WithScalar({{.Name}}, lhs, rhs)
+}
 {{- end}}
 `))
 )
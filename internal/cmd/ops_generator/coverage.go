@@ -0,0 +1,20 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path"
+
+	"github.com/gomlx/stablehlo/internal/opscoverage"
+)
+
+const (
+	coverageFile = "op_coverage.json"
+)
+
+// GenerateOpsCoverage writes the coverage of the StableHLO specification operations to a JSON file, embedded by
+// the stablehlo package. See opscoverage.Generate.
+func GenerateOpsCoverage() {
+	must(os.WriteFile(coverageFile, must1(opscoverage.Generate(".")), 0o644))
+	fmt.Printf("✅ Successfully generated %s\n", path.Join(must1(os.Getwd()), coverageFile))
+}
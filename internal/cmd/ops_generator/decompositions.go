@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+	"go/ast"
+	"os"
+	"os/exec"
+	"path"
+	"text/template"
+
+	"github.com/gomlx/stablehlo/internal/opscoverage"
+	"github.com/gomlx/stablehlo/internal/utils"
+	"github.com/gomlx/stablehlo/shapeinference"
+)
+
+const (
+	decompositionsFile = "gen_decompositions.go"
+
+	// decompositionPrefix is the prefix of the functions implementing the decomposition of an operation, followed
+	// by the operation name (e.g. decomposeTan).
+	decompositionPrefix = "decompose"
+)
+
+var (
+	decompositionsTemplate = template.Must(
+		template.
+			New(decompositionsFile).
+			Parse(
+				`/***** File generated by ./internal/cmd/ops_generator. Don't edit it directly. *****/
+
+package stablehlo
+
+import (
+	"github.com/gomlx/stablehlo/internal/optypes"
+)
+
+// decomposableOps lists the operations that have a decomposition, see decomposition.
+var decomposableOps = []optypes.OpType{
+{{- range .}}
+	optypes.{{.}},
+{{- end}}
+}
+
+// decomposition returns the decomposition of the unary operation into other operations, used when the
+// target backend doesn't support it natively (see TargetFeatures), or nil if there is none.
+//
+// The decompositions are the functions named "decompose<OpName>" in decompositions.go.
+func decomposition(op optypes.OpType) func(operand *Value) (*Value, error) {
+	switch op {
+{{- range .}}
+	case optypes.{{.}}:
+		return decompose{{.}}
+{{- end}}
+	default:
+		return nil
+	}
+}
+`))
+)
+
+// decomposableUnaryOps returns the names of the standard unary operations with a decomposition: those for which
+// the stablehlo package has a function named decompositionPrefix followed by the operation name.
+func decomposableUnaryOps() utils.Set[string] {
+	decompositions := utils.MakeSet[string]()
+	for _, file := range must1(opscoverage.ParseNonTestFiles(".")) {
+		for _, decl := range file.Decls {
+			if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Recv == nil {
+				decompositions.Insert(funcDecl.Name.Name)
+			}
+		}
+	}
+	ops := utils.MakeSet[string]()
+	for op := range shapeinference.StandardUnaryOperations {
+		if decompositions.Has(decompositionPrefix + op.String()) {
+			ops.Insert(op.String())
+		}
+	}
+	return ops
+}
+
+// GenerateDecompositions generates the registry of the decompositions of the unary operations, used by the
+// Builder for the operations not supported by the target backend, see decomposableUnaryOps.
+func GenerateDecompositions() {
+	data := utils.SortedKeys(decomposableUnaryOps())
+
+	fileName := decompositionsFile
+	f := must1(os.Create(fileName))
+	must(decompositionsTemplate.Execute(f, data))
+	must(f.Close())
+
+	cmd := exec.Command("gofmt", "-w", fileName)
+	must(cmd.Run())
+	fmt.Printf("✅ Successfully generated %s\n", path.Join(must1(os.Getwd()), fileName))
+}
@@ -0,0 +1,71 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"os/exec"
+	"path"
+	"text/template"
+
+	"github.com/gomlx/stablehlo/internal/utils"
+	"github.com/gomlx/stablehlo/shapeinference"
+)
+
+const (
+	exprOpsFile = "gen_expr_ops.go"
+)
+
+var (
+	exprOpsTemplate = template.Must(
+		template.
+			New(exprOpsFile).
+			Parse(
+				`/***** File generated by ./internal/cmd/ops_generator. Don't edit it directly. *****/
+
+package stablehlo
+
+{{- range .BinaryOps}}
+// {{.Name}} chains the corresponding standard binary operation, with the expression as the left-hand side operand.
+func (e *Expr) {{.Name}}(rhs *Value) *Expr {
+	return e.binaryOp({{.Name}}, rhs)
+}
+
+// {{.Name}}Scalar chains the corresponding standard binary operation with a Go scalar as the right-hand side
+// operand, converted to the dtype of the expression. See WithScalar.
+func (e *Expr) {{.Name}}Scalar(rhs any) *Expr {
+	return e.Apply(func(lhs *Value) (*Value, error) { return {{.Name}}Scalar(lhs, rhs) })
+}
+{{- end}}
+
+{{- range .UnaryOps}}
+// {{.Name}} chains the corresponding standard unary operation.
+func (e *Expr) {{.Name}}() *Expr {
+	return e.unaryOp({{.Name}})
+}
+{{- end}}
+`))
+)
+
+type ExprOps struct {
+	BinaryOps []BinaryOp
+	UnaryOps  []UnaryOp
+}
+
+func GenerateExprOps() {
+	var data ExprOps
+	for _, k := range utils.SortedKeys(shapeinference.StandardBinaryOperations) {
+		data.BinaryOps = append(data.BinaryOps, BinaryOp{Name: k.String()})
+	}
+	for _, k := range utils.SortedKeys(shapeinference.StandardUnaryOperations) {
+		data.UnaryOps = append(data.UnaryOps, UnaryOp{Name: k.String()})
+	}
+
+	fileName := exprOpsFile
+	f := must1(os.Create(fileName))
+	must(exprOpsTemplate.Execute(f, data))
+	must(f.Close())
+
+	cmd := exec.Command("gofmt", "-w", fileName)
+	must(cmd.Run())
+	fmt.Printf("✅ Successfully generated %s\n", path.Join(must1(os.Getwd()), fileName))
+}
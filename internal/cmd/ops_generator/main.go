@@ -5,6 +5,9 @@ import "log"
 func main() {
 	GenerateBinaryOps()
 	GenerateUnaryOps()
+	GenerateDecompositions()
+	GenerateExprOps()
+	GenerateOpsCoverage()
 }
 
 func must(err error) {
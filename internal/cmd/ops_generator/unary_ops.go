@@ -26,28 +26,49 @@ package stablehlo
 
 import (
 	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/types"
 )
 
 {{- range .}}
 // {{.Name}} implements the corresponding standard unary operation.
+{{- if .HasDecomposition}}
+//
+// If the target backend doesn't support it natively (see TargetFeatures), it is replaced by a decomposition
+// into other operations.
+{{- end}}
 func {{.Name}}(operand *Value) (*Value, error) {
 	fn := operand.fn
 	return fn.unaryOp(optypes.{{.Name}}, operand)
 }
+{{- if .HasResultAccuracy}}
+
+// {{.Name}}WithAccuracy implements the corresponding standard unary operation, with the given result accuracy.
+func {{.Name}}WithAccuracy(operand *Value, accuracy types.ResultAccuracy) (*Value, error) {
+	fn := operand.fn
+	return fn.unaryOpWithAccuracy(optypes.{{.Name}}, operand, accuracy)
+}
+{{- end}}
 {{- end}}
 `))
 )
 
 type UnaryOp struct {
-	Name string
+	Name              string
+	HasResultAccuracy bool
+	HasDecomposition  bool
 }
 
 func GenerateUnaryOps() {
 	unaryOps := shapeinference.StandardUnaryOperations
 	data := make([]UnaryOp, 0, len(unaryOps))
+	decomposable := decomposableUnaryOps()
 
 	for _, k := range utils.SortedKeys(unaryOps) {
-		data = append(data, UnaryOp{Name: k.String()})
+		data = append(data, UnaryOp{
+			Name:              k.String(),
+			HasResultAccuracy: shapeinference.ResultAccuracyOperations.Has(k),
+			HasDecomposition:  decomposable.Has(k.String()),
+		})
 	}
 
 	fileName := unaryOpsFile
@@ -0,0 +1,150 @@
+// Package opscoverage computes the coverage of the operations of the StableHLO specification by the stablehlo
+// package, from its source code. It's used by ops_generator to generate op_coverage.json, and by the tests to check
+// the file is up-to-date.
+package opscoverage
+
+import (
+	"bytes"
+	"encoding/json"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/pkg/errors"
+)
+
+// specOps lists the operations of the StableHLO specification (https://openxla.org/stablehlo/spec),
+// without the deprecated ones.
+var specOps = []string{
+	"abs", "add", "after_all", "all_gather", "all_reduce", "all_to_all", "and", "atan2",
+	"batch_norm_grad", "batch_norm_inference", "batch_norm_training", "bitcast_convert", "broadcast_in_dim",
+	"case", "cbrt", "ceil", "cholesky", "clamp", "collective_broadcast", "collective_permute", "compare",
+	"complex", "composite", "concatenate", "constant", "convert", "convolution", "cosine",
+	"count_leading_zeros", "custom_call", "divide", "dot_general", "dynamic_broadcast_in_dim", "dynamic_conv",
+	"dynamic_gather", "dynamic_iota", "dynamic_pad", "dynamic_reshape", "dynamic_slice",
+	"dynamic_update_slice", "exponential", "exponential_minus_one", "fft", "floor", "gather",
+	"get_dimension_size", "get_tuple_element", "if", "imag", "infeed", "iota", "is_finite", "log",
+	"log_plus_one", "logistic", "map", "maximum", "minimum", "multiply", "negate", "not",
+	"optimization_barrier", "or", "outfeed", "pad", "partition_id", "popcnt", "power", "real", "recv",
+	"reduce", "reduce_precision", "reduce_scatter", "reduce_window", "remainder", "replica_id", "reshape",
+	"reverse", "rng", "rng_bit_generator", "round_nearest_afz", "round_nearest_even", "rsqrt", "scatter",
+	"select", "select_and_scatter", "send", "shift_left", "shift_right_arithmetic", "shift_right_logical",
+	"sign", "sine", "slice", "sort", "sqrt", "subtract", "tan", "tanh", "transpose", "triangular_solve",
+	"tuple", "uniform_dequantize", "uniform_quantize", "while", "xor",
+}
+
+// OpCoverage mirrors stablehlo.OpCoverage, as written to the JSON file.
+type OpCoverage struct {
+	Name   string `json:"name"`
+	Status string `json:"status"`
+	OpType string `json:"op_type,omitempty"`
+}
+
+// Generate returns the coverage of the StableHLO specification operations, encoded as JSON, for the stablehlo
+// package in rootDir.
+//
+// An operation is "implemented" if its OpType is used by the (non-test) code of the stablehlo package,
+// "shape_inference" if only the shapeinference package has a function for it, and "missing" otherwise.
+func Generate(rootDir string) ([]byte, error) {
+	usedOpTypes, err := selectorsOf(rootDir, "optypes")
+	if err != nil {
+		return nil, err
+	}
+	shapeInferenceFuncs, err := exportedFuncsOf(path.Join(rootDir, "shapeinference"))
+	if err != nil {
+		return nil, err
+	}
+
+	opTypes := make(map[string]optypes.OpType)
+	for op := optypes.Invalid + 1; op < optypes.Last; op++ {
+		if name, found := strings.CutPrefix(op.ToStableHLO(), "stablehlo."); found {
+			opTypes[name] = op
+		}
+	}
+
+	coverage := make([]OpCoverage, 0, len(specOps))
+	for _, name := range specOps {
+		entry := OpCoverage{Name: "stablehlo." + name, Status: "missing"}
+		if op, found := opTypes[name]; found {
+			entry.OpType = op.String()
+			switch {
+			case usedOpTypes[op.String()]:
+				entry.Status = "implemented"
+			case shapeInferenceFuncs[op.String()]:
+				entry.Status = "shape_inference"
+			}
+		}
+		coverage = append(coverage, entry)
+	}
+
+	var buf bytes.Buffer
+	encoder := json.NewEncoder(&buf)
+	encoder.SetIndent("", "  ")
+	if err := encoder.Encode(coverage); err != nil {
+		return nil, errors.Wrap(err, "failed to encode the coverage")
+	}
+	return buf.Bytes(), nil
+}
+
+// ParseNonTestFiles parses the non-test Go files of the package in dir. It's also used by ops_generator.
+func ParseNonTestFiles(dir string) ([]*ast.File, error) {
+	fileSet := token.NewFileSet()
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		return nil, errors.Wrapf(err, "failed to read directory %q", dir)
+	}
+	var files []*ast.File
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasSuffix(name, ".go") || strings.HasSuffix(name, "_test.go") {
+			continue
+		}
+		file, err := parser.ParseFile(fileSet, path.Join(dir, name), nil, parser.SkipObjectResolution)
+		if err != nil {
+			return nil, errors.Wrapf(err, "failed to parse %q", name)
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
+
+// selectorsOf returns the names selected from the given package (e.g. "Add" in "optypes.Add") in the package in dir.
+func selectorsOf(dir, pkgName string) (map[string]bool, error) {
+	files, err := ParseNonTestFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	selected := make(map[string]bool)
+	for _, file := range files {
+		ast.Inspect(file, func(node ast.Node) bool {
+			if sel, ok := node.(*ast.SelectorExpr); ok {
+				if ident, ok := sel.X.(*ast.Ident); ok && ident.Name == pkgName {
+					selected[sel.Sel.Name] = true
+				}
+			}
+			return true
+		})
+	}
+	return selected, nil
+}
+
+// exportedFuncsOf returns the names of the exported functions (not methods) of the package in dir.
+func exportedFuncsOf(dir string) (map[string]bool, error) {
+	files, err := ParseNonTestFiles(dir)
+	if err != nil {
+		return nil, err
+	}
+	funcs := make(map[string]bool)
+	for _, file := range files {
+		for _, decl := range file.Decls {
+			if funcDecl, ok := decl.(*ast.FuncDecl); ok && funcDecl.Recv == nil && funcDecl.Name.IsExported() {
+				funcs[funcDecl.Name.Name] = true
+			}
+		}
+	}
+	return funcs, nil
+}
@@ -15,8 +15,11 @@ type OpType int
 const (
 	Invalid OpType = iota
 	FuncReturn
+	FuncCall
 	Constant
 	Identity
+	RawSnippet
+	ShardingConstraint
 
 	Abs
 	Add
@@ -49,6 +52,7 @@ const (
 	Fft
 	Floor
 	Gather
+	GetDimensionSize
 	Imag
 	IsFinite
 	Iota
@@ -70,6 +74,7 @@ const (
 	ReduceWindow
 	Reshape
 	Reverse
+	Rng
 	RNGBitGenerator
 	RoundNearestAfz
 	RoundNearestEven
@@ -77,6 +82,7 @@ const (
 	Scatter
 	Select
 	SelectAndScatter
+	SetDimensionSize
 	ShiftLeft
 	ShiftRightArithmetic
 	ShiftRightLogical
@@ -105,7 +111,6 @@ const (
 	DynamicIota
 	DynamicPad
 	DynamicReshape
-	GetDimensionSize
 	GetTupleElement
 	If
 	Infeed
@@ -130,11 +135,60 @@ var (
 	// stableHLOMappings maps OpType to the corresponding StableHLO name, when the default
 	// "snake case" doesn't work.
 	stableHLOMappings = map[OpType]string{
-		FuncReturn: "stablehlo.return",
-		Erf:        "chlo.erf",
-		AllReduce:  "stablehlo.all_reduce"}
+		FuncReturn:         "stablehlo.return",
+		FuncCall:           "func.call",
+		RawSnippet:         "raw_snippet",
+		ShardingConstraint: "sdy.sharding_constraint",
+		Erf:                "chlo.erf",
+		AllReduce:          "stablehlo.all_reduce"}
+
+	// mandatoryAttributes lists the attributes that must be set for each operation, see MandatoryAttributes.
+	mandatoryAttributes = map[OpType][]string{
+		AllGather:           {"all_gather_dim", "replica_groups"},
+		AllReduce:           {"replica_groups"},
+		AllToAll:            {"split_dimension", "concat_dimension", "split_count", "replica_groups"},
+		BatchNormGrad:       {"epsilon", "feature_index"},
+		BatchNormInference:  {"epsilon", "feature_index"},
+		BatchNormTraining:   {"epsilon", "feature_index"},
+		BroadcastInDim:      {"broadcast_dimensions"},
+		CollectiveBroadcast: {"replica_groups"},
+		CollectivePermute:   {"source_target_pairs"},
+		Compare:             {"comparison_direction"},
+		Concatenate:         {"dimension"},
+		Constant:            {"value"},
+		Convolution:         {"dimension_numbers", "feature_group_count", "batch_group_count"},
+		CustomCall:          {"call_target_name"},
+		DotGeneral:          {"dot_dimension_numbers"},
+		DynamicGather:       {"dimension_numbers"},
+		DynamicIota:         {"iota_dimension"},
+		DynamicSlice:        {"slice_sizes"},
+		Fft:                 {"fft_type", "fft_length"},
+		FuncCall:            {"callee"},
+		Gather:              {"dimension_numbers", "slice_sizes"},
+		GetDimensionSize:    {"dimension"},
+		Iota:                {"iota_dimension"},
+		Pad:                 {"edge_padding_low", "edge_padding_high", "interior_padding"},
+		Reduce:              {"dimensions"},
+		ReduceWindow:        {"window_dimensions"},
+		Reverse:             {"dimensions"},
+		Rng:                 {"rng_distribution"},
+		RNGBitGenerator:     {"rng_algorithm"},
+		Scatter:             {"scatter_dimension_numbers"},
+		SetDimensionSize:    {"dimension"},
+		ShardingConstraint:  {"sharding"},
+		Slice:               {"start_indices", "limit_indices", "strides"},
+		Transpose:           {"permutation"},
+	}
 )
 
+// MandatoryAttributes returns the names of the attributes that must be set for the operation to be valid
+// (e.g. "dimension_numbers" and "slice_sizes" for Gather), or nil if none is required.
+//
+// Optional attributes, with a default value in the StableHLO specification, are not listed.
+func (op OpType) MandatoryAttributes() []string {
+	return mandatoryAttributes[op]
+}
+
 // ToStableHLO returns the ToStableHLO name of the operation.
 func (op OpType) ToStableHLO() string {
 	name, ok := stableHLOMappings[op]
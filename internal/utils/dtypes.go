@@ -16,6 +16,22 @@ func DTypeToStableHLO(dtype dtypes.DType) string {
 		return "f16"
 	case dtypes.BFloat16:
 		return "bf16"
+	case dtypes.F8E5M2:
+		return "f8E5M2"
+	case dtypes.F8E4M3FN:
+		return "f8E4M3FN"
+	case dtypes.F8E4M3B11FNUZ:
+		return "f8E4M3B11FNUZ"
+	case dtypes.F8E5M2FNUZ:
+		return "f8E5M2FNUZ"
+	case dtypes.F8E4M3FNUZ:
+		return "f8E4M3FNUZ"
+	case dtypes.F8E4M3:
+		return "f8E4M3"
+	case dtypes.F8E3M4:
+		return "f8E3M4"
+	case dtypes.F8E8M0FNU:
+		return "f8E8M0FNU"
 	case dtypes.S64:
 		return "i64"
 	case dtypes.S32:
@@ -32,6 +48,14 @@ func DTypeToStableHLO(dtype dtypes.DType) string {
 		return "ui16"
 	case dtypes.U8:
 		return "ui8"
+	case dtypes.S4:
+		return "i4"
+	case dtypes.U4:
+		return "ui4"
+	case dtypes.S2:
+		return "i2"
+	case dtypes.U2:
+		return "ui2"
 	case dtypes.Bool:
 		return "i1"
 	case dtypes.Complex64:
@@ -42,3 +66,45 @@ func DTypeToStableHLO(dtype dtypes.DType) string {
 		return fmt.Sprintf("unknown_dtype<%s>", dtype.String())
 	}
 }
+
+// DTypeFromStableHLO returns the dtype for the given StableHLO element type name (e.g. "f32", "ui8",
+// "complex<f64>"). It's the reverse of DTypeToStableHLO, and it returns false if the name is not known.
+func DTypeFromStableHLO(name string) (dtypes.DType, bool) {
+	for _, dtype := range dtypes.DTypeValues() {
+		if dtype != dtypes.InvalidDType && DTypeToStableHLO(dtype) == name {
+			return dtype, true
+		}
+	}
+	return dtypes.InvalidDType, false
+}
+
+// DTypeBitWidth returns the bit width of the dtype as defined by StableHLO, which can be smaller than
+// the storage used by Go (dtype.Bits()): booleans (i1) have 1 bit, and the sub-byte integers 2 or 4 bits.
+// The 8-bit floats, which have no Go type, have 8 bits.
+func DTypeBitWidth(dtype dtypes.DType) int {
+	switch dtype {
+	case dtypes.Bool:
+		return 1
+	case dtypes.F8E5M2, dtypes.F8E4M3FN, dtypes.F8E4M3B11FNUZ, dtypes.F8E5M2FNUZ, dtypes.F8E4M3FNUZ,
+		dtypes.F8E4M3, dtypes.F8E3M4, dtypes.F8E8M0FNU:
+		return 8
+	case dtypes.S4, dtypes.U4:
+		return 4
+	case dtypes.S2, dtypes.U2:
+		return 2
+	default:
+		return dtype.Bits()
+	}
+}
+
+// DTypeIsFloat returns whether the dtype is a StableHLO floating-point type: besides the types of dtype.IsFloat()
+// (Float16, BFloat16, Float32 and Float64), it includes the 8-bit float types (F8E4M3FN, F8E5M2, ...).
+func DTypeIsFloat(dtype dtypes.DType) bool {
+	switch dtype {
+	case dtypes.F8E5M2, dtypes.F8E4M3FN, dtypes.F8E4M3B11FNUZ, dtypes.F8E5M2FNUZ, dtypes.F8E4M3FNUZ,
+		dtypes.F8E4M3, dtypes.F8E3M4, dtypes.F8E8M0FNU:
+		return true
+	default:
+		return dtype.IsFloat()
+	}
+}
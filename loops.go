@@ -0,0 +1,295 @@
+package stablehlo
+
+import (
+	"fmt"
+	"math"
+	"slices"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// ForI runs the body function for i in [lower, upper) -- like a `for i := lower; i < upper; i++` loop --, threading
+// the loop-carried state through the iterations, and returns the final state. It's built on While.
+//
+// The body must be a returned top-level function of the same Builder (see Builder.NewFunction), taking the counter
+// i (a scalar with the dtype of lower) followed by the state values, and returning the updated state values, with
+// the same shapes (and no other outputs). The lower and upper bounds must be integer scalars of the same dtype. If upper <= lower the body
+// is not called, and the initial state is returned.
+func (fn *Function) ForI(lower, upper *Value, body *Function, state ...*Value) (outputs []*Value, err error) {
+	defer fn.multiOpErrorHandler(&err, &outputs, len(state))()
+	if fn.Returned {
+		return nil, errors.Errorf("cannot add ForI after returning, in function %q", fn.Name)
+	}
+	if lower.fn != fn || upper.fn != fn {
+		return nil, errors.Errorf("ForI: lower and upper bounds are not part of function %q", fn.Name)
+	}
+	if !lower.shape.IsScalar() || !lower.shape.DType.IsInt() || !upper.shape.Equal(lower.shape) {
+		return nil, errors.Errorf("ForI requires lower and upper bounds to be integer scalars of the same dtype, got %s and %s",
+			lower.shape, upper.shape)
+	}
+	if err := checkLoopBody("ForI", body, fn,
+		slices.Concat([]shapes.Shape{lower.shape}, valuesToShapes(state)), valuesToShapes(state), false); err != nil {
+		return nil, err
+	}
+	if len(state) == 0 {
+		return nil, nil
+	}
+
+	// Loop state: counter, upper bound and the state values.
+	loopState := slices.Concat([]*Value{lower, upper}, state)
+	cond, condInputs, err := fn.newLoopClosure(loopState, shapes.Make(dtypes.Bool))
+	if err != nil {
+		return nil, err
+	}
+	notDone, err := CompareAuto(condInputs[0], condInputs[1], types.CompareLT)
+	if err != nil {
+		return nil, err
+	}
+	if err = cond.Return(notDone); err != nil {
+		return nil, err
+	}
+
+	loopBody, bodyInputs, err := fn.newLoopClosure(loopState, valuesToShapes(loopState)...)
+	if err != nil {
+		return nil, err
+	}
+	counter := bodyInputs[0]
+	bodyOutputs, err := loopBody.Call(body, slices.Concat([]*Value{counter}, bodyInputs[2:])...)
+	if err != nil {
+		return nil, err
+	}
+	nextCounter, err := AddScalar(counter, 1)
+	if err != nil {
+		return nil, err
+	}
+	if err = loopBody.Return(slices.Concat([]*Value{nextCounter, bodyInputs[1]}, bodyOutputs)...); err != nil {
+		return nil, err
+	}
+
+	finalState, err := While(cond, loopBody, loopState...)
+	if err != nil {
+		return nil, err
+	}
+	return finalState[2:], nil
+}
+
+// Scan loops over the leading axis of the xs values, calling the body function with the loop-carried values and the
+// slices of xs of each step, and stacks the per-step outputs -- like JAX's lax.scan. It's the standard building block
+// for RNNs and samplers. It's built on While.
+//
+// The body must be a returned top-level function of the same Builder (see Builder.NewFunction), taking
+// (carry_1, ..., carry_N, x_1[t], ..., x_M[t]) and returning (newCarry_1, ..., newCarry_N, y_1[t], ..., y_K[t]),
+// where the carry values have the shapes of carryInit, and x_i[t] the shapes of xs[i] without the leading axis.
+//
+// It returns the final carry values, and the per-step outputs ys, each stacked along a new leading axis, with one
+// entry per step. There must be at least one xs, and all must have the same (static) leading dimension. If it is 0,
+// the body is not called: carryInit is returned, and the ys are empty (with a leading dimension of 0).
+func (fn *Function) Scan(xs, carryInit []*Value, body *Function) (carry, ys []*Value, err error) {
+	outputs, err := fn.scanOutputs(xs, carryInit, body)
+	if err != nil {
+		return nil, nil, err
+	}
+	return outputs[:len(carryInit)], outputs[len(carryInit):], nil
+}
+
+// scanOutputs implements Scan, returning the final carry values followed by the stacked per-step outputs.
+func (fn *Function) scanOutputs(xs, carryInit []*Value, body *Function) (outputs []*Value, err error) {
+	if body != nil {
+		defer fn.multiOpErrorHandler(&err, &outputs, max(len(body.Outputs), len(carryInit)))()
+	}
+	if fn.Returned {
+		return nil, errors.Errorf("cannot add Scan after returning, in function %q", fn.Name)
+	}
+	if len(xs) == 0 {
+		return nil, errors.New("Scan requires at least one xs value to loop over")
+	}
+	stepShapes := make([]shapes.Shape, len(xs))
+	for i, x := range xs {
+		if x.fn != fn {
+			return nil, errors.Errorf("Scan: xs[%d] is not part of function %q", i, fn.Name)
+		}
+		if x.shape.Rank() == 0 || x.shape.IsDynamic() {
+			return nil, errors.Errorf("Scan requires xs with a static shape of rank >= 1, got %s for xs[%d]", x.shape, i)
+		}
+		if x.shape.Dimensions[0] != xs[0].shape.Dimensions[0] {
+			return nil, errors.Errorf("Scan requires all xs to have the same leading dimension, got %s for xs[0] and %s for xs[%d]",
+				xs[0].shape, x.shape, i)
+		}
+		stepShapes[i] = shapes.Make(x.shape.DType, x.shape.Dimensions[1:]...)
+	}
+	numSteps := xs[0].shape.Dimensions[0]
+	if numSteps > math.MaxInt32 {
+		// The step counter is an int32.
+		return nil, errors.Errorf("Scan: leading dimension %d of xs is larger than int32 indices", numSteps)
+	}
+	for i, value := range carryInit {
+		if value.fn != fn {
+			return nil, errors.Errorf("Scan: carryInit[%d] is not part of function %q", i, fn.Name)
+		}
+	}
+	if err := checkLoopBody("Scan", body, fn, slices.Concat(valuesToShapes(carryInit), stepShapes),
+		valuesToShapes(carryInit), true); err != nil {
+		return nil, err
+	}
+	numCarry := len(carryInit)
+	for i, output := range body.Outputs[numCarry:] {
+		if output.shape.IsDynamic() {
+			return nil, errors.Errorf("Scan requires static per-step outputs, got %s for output #%d of %q",
+				output.shape, numCarry+i, body.Name)
+		}
+	}
+	if numSteps == 0 {
+		// The body is never called (and slicing the empty xs would fail): the carry is returned as is, with empty ys.
+		outputs = slices.Clone(carryInit)
+		for _, output := range body.Outputs[numCarry:] {
+			empty, err := fn.ConstantZeros(shapes.Make(output.shape.DType, slices.Concat([]int{0}, output.shape.Dimensions)...))
+			if err != nil {
+				return nil, err
+			}
+			outputs = append(outputs, empty)
+		}
+		return outputs, nil
+	}
+
+	// Loop state: counter, carry values, xs and the stacked outputs.
+	counter, err := fn.ConstantFromScalar(int32(0))
+	if err != nil {
+		return nil, err
+	}
+	loopState := slices.Concat([]*Value{counter}, carryInit, xs)
+	firstXs, firstYs := 1+numCarry, 1+numCarry+len(xs)
+	for _, output := range body.Outputs[numCarry:] {
+		stacked, err := fn.ConstantZeros(shapes.Make(output.shape.DType, slices.Concat([]int{numSteps}, output.shape.Dimensions)...))
+		if err != nil {
+			return nil, err
+		}
+		loopState = append(loopState, stacked)
+	}
+
+	cond, condInputs, err := fn.newLoopClosure(loopState, shapes.Make(dtypes.Bool))
+	if err != nil {
+		return nil, err
+	}
+	limit, err := cond.ConstantFromScalar(int32(numSteps))
+	if err != nil {
+		return nil, err
+	}
+	notDone, err := Compare(condInputs[0], limit, types.CompareLT, types.CompareSigned)
+	if err != nil {
+		return nil, err
+	}
+	if err = cond.Return(notDone); err != nil {
+		return nil, err
+	}
+
+	loopBody, bodyInputs, err := fn.newLoopClosure(loopState, valuesToShapes(loopState)...)
+	if err != nil {
+		return nil, err
+	}
+	step := bodyInputs[0]
+	// startIndices returns the indices of the step in a value of the given rank: (step, 0, ..., 0).
+	startIndices := func(rank int) ([]*Value, error) {
+		indices := []*Value{step}
+		for range rank - 1 {
+			zero, err := loopBody.ConstantFromScalar(int32(0))
+			if err != nil {
+				return nil, err
+			}
+			indices = append(indices, zero)
+		}
+		return indices, nil
+	}
+	stepArgs := slices.Clone(bodyInputs[1:firstXs])
+	for i, x := range bodyInputs[firstXs:firstYs] {
+		indices, err := startIndices(x.shape.Rank())
+		if err != nil {
+			return nil, err
+		}
+		sliceSizes := slices.Clone(x.shape.Dimensions)
+		sliceSizes[0] = 1
+		slice, err := DynamicSlice(x, indices, sliceSizes)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Scan: slicing xs[%d]", i)
+		}
+		xt, err := Reshape(slice, stepShapes[i])
+		if err != nil {
+			return nil, err
+		}
+		stepArgs = append(stepArgs, xt)
+	}
+	stepOutputs, err := loopBody.Call(body, stepArgs...)
+	if err != nil {
+		return nil, err
+	}
+	nextStep, err := AddScalar(step, 1)
+	if err != nil {
+		return nil, err
+	}
+	nextState := slices.Concat([]*Value{nextStep}, stepOutputs[:numCarry], bodyInputs[firstXs:firstYs])
+	for i, y := range stepOutputs[numCarry:] {
+		stacked := bodyInputs[firstYs+i]
+		update, err := Reshape(y, shapes.Make(y.shape.DType, slices.Concat([]int{1}, y.shape.Dimensions)...))
+		if err != nil {
+			return nil, err
+		}
+		indices, err := startIndices(stacked.shape.Rank())
+		if err != nil {
+			return nil, err
+		}
+		updated, err := DynamicUpdateSlice(stacked, update, indices)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "Scan: stacking output #%d", numCarry+i)
+		}
+		nextState = append(nextState, updated)
+	}
+	if err = loopBody.Return(nextState...); err != nil {
+		return nil, err
+	}
+
+	finalState, err := While(cond, loopBody, loopState...)
+	if err != nil {
+		return nil, err
+	}
+	return slices.Concat(finalState[1:firstXs], finalState[firstYs:]), nil
+}
+
+// checkLoopBody checks that body is a returned top-level function of the same Builder as fn, with the given input
+// shapes, and the given output shapes -- or outputs starting with them, if extraOutputs is true.
+func checkLoopBody(name string, body, fn *Function, inputShapes, outputShapes []shapes.Shape, extraOutputs bool) error {
+	if body == nil || body.Parent != nil || body.Builder != fn.Builder || !body.Returned {
+		return errors.Errorf("%s requires a returned top-level body function of the same Builder", name)
+	}
+	if len(body.Inputs) != len(inputShapes) {
+		return errors.Errorf("%s: body function %q takes %d inputs, but %d are required", name, body.Name,
+			len(body.Inputs), len(inputShapes))
+	}
+	for i, shape := range inputShapes {
+		if !body.Inputs[i].shape.Equal(shape) {
+			return errors.Errorf("%s: body function %q input #%d has shape %s, but %s is required", name, body.Name,
+				i, body.Inputs[i].shape, shape)
+		}
+	}
+	if len(body.Outputs) < len(outputShapes) || (!extraOutputs && len(body.Outputs) != len(outputShapes)) {
+		required := fmt.Sprint(len(outputShapes))
+		if extraOutputs {
+			required = "at least " + required
+		}
+		return errors.Errorf("%s: body function %q returns %d outputs, but %s are required", name, body.Name,
+			len(body.Outputs), required)
+	}
+	for i, shape := range outputShapes {
+		if !body.Outputs[i].shape.Equal(shape) {
+			return errors.Errorf("%s: body function %q output #%d has shape %s, but %s is required", name, body.Name,
+				i, body.Outputs[i].shape, shape)
+		}
+	}
+	return nil
+}
+
+// newLoopClosure creates a closure of fn taking the given loop state as inputs, and declaring the given outputs.
+func (fn *Function) newLoopClosure(state []*Value, outputShapes ...shapes.Shape) (*Function, []*Value, error) {
+	return fn.ClosureWithSignature(valuesToShapes(state), outputShapes...)
+}
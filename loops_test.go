@@ -0,0 +1,189 @@
+package stablehlo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestForI(t *testing.T) {
+	builder := New(t.Name())
+	body := builder.NewFunction("body")
+	i := must(body.NamedInput("i", shapes.Make(dtypes.Int32)))
+	acc := must(body.NamedInput("acc", shapes.Make(dtypes.Float32)))
+	must0(body.Return(must(Add(acc, must(Convert(i, dtypes.Float32))))))
+
+	fn := builder.Main()
+	lower := must(fn.ConstantFromScalar(int32(0)))
+	upper := must(fn.NamedInput("n", shapes.Make(dtypes.Int32)))
+	init := must(fn.ConstantFromScalar(float32(0)))
+	if _, err := fn.ForI(lower, must(fn.ConstantFromScalar(int64(3))), body, init); err == nil {
+		t.Error("expected error for bounds with different dtypes")
+	}
+	if _, err := fn.ForI(lower, upper, body, must(fn.ConstantFromScalar(float64(0)))); err == nil {
+		t.Error("expected error for state not matching the body inputs")
+	}
+	if _, err := fn.ForI(lower, upper, body); err == nil {
+		t.Error("expected error for missing state")
+	}
+	{
+		// Body with an extra output, in another builder to keep the program below unchanged.
+		otherBuilder := New(t.Name())
+		extraBody := otherBuilder.NewFunction("body")
+		i := must(extraBody.NamedInput("i", shapes.Make(dtypes.Int32)))
+		acc := must(extraBody.NamedInput("acc", shapes.Make(dtypes.Float32)))
+		must0(extraBody.Return(acc, i))
+		otherFn := otherBuilder.Main()
+		otherLower := must(otherFn.ConstantFromScalar(int32(0)))
+		if _, err := otherFn.ForI(otherLower, otherLower, extraBody, must(otherFn.ConstantFromScalar(float32(0)))); err == nil {
+			t.Error("expected error for a body with extra outputs")
+		}
+	}
+	outputs := must(fn.ForI(lower, upper, body, init))
+	must0(fn.Return(outputs...))
+	program := string(must(builder.Build()))
+	fmt.Printf("%s program:\n%s", t.Name(), program)
+	want := `module @TestForI {
+  func.func @body(%i: tensor<i32>, %acc: tensor<f32>) -> tensor<f32> {
+    %0 = "stablehlo.convert"(%i) : (tensor<i32>) -> tensor<f32>
+    %1 = "stablehlo.add"(%acc, %0) : (tensor<f32>, tensor<f32>) -> tensor<f32>
+    "stablehlo.return"(%1) : (tensor<f32>) -> ()
+  }
+
+  func.func @main(%n: tensor<i32>) -> tensor<f32> {
+    %0 = "stablehlo.constant"() { value = dense<0> : tensor<i32> } : () -> tensor<i32>
+    %1 = "stablehlo.constant"() { value = dense<0.0> : tensor<f32> } : () -> tensor<f32>
+    %2 = "stablehlo.constant"() { value = dense<3> : tensor<i64> } : () -> tensor<i64>
+    %3 = "stablehlo.constant"() { value = dense<0.0> : tensor<f64> } : () -> tensor<f64>
+    %8, %9, %10 = "stablehlo.while"(%0, %n, %1) ({
+      ^cond(%arg0: tensor<i32>, %arg1: tensor<i32>, %arg2: tensor<f32>) :
+          %4 = "stablehlo.compare"(%arg0, %arg1) {
+            compare_type = #stablehlo<comparison_type SIGNED>,
+            comparison_direction = #stablehlo<comparison_direction LT>
+          } : (tensor<i32>, tensor<i32>) -> tensor<i1>
+          "stablehlo.return"(%4) : (tensor<i1>) -> ()
+    }, {
+      ^body(%arg3: tensor<i32>, %arg4: tensor<i32>, %arg5: tensor<f32>) :
+          %5 = "func.call"(%arg3, %arg5) { callee = @body } : (tensor<i32>, tensor<f32>) -> tensor<f32>
+          %6 = "stablehlo.constant"() { value = dense<1> : tensor<i32> } : () -> tensor<i32>
+          %7 = "stablehlo.add"(%arg3, %6) : (tensor<i32>, tensor<i32>) -> tensor<i32>
+          "stablehlo.return"(%7, %arg4, %5) : (tensor<i32>, tensor<i32>, tensor<f32>) -> ()
+    }) : (tensor<i32>, tensor<i32>, tensor<f32>) -> (tensor<i32>, tensor<i32>, tensor<f32>)
+    "stablehlo.return"(%10) : (tensor<f32>) -> ()
+  }
+}
+`
+	if program != want {
+		fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+		t.Fatal("programs don't match")
+	}
+	requireRoundTrip(t, builder)
+}
+
+func TestScan(t *testing.T) {
+	builder := New(t.Name())
+	body := builder.NewFunction("body")
+	carry := must(body.NamedInput("carry", shapes.Make(dtypes.Float32, 2)))
+	x := must(body.NamedInput("x", shapes.Make(dtypes.Float32, 2)))
+	sum := must(Add(carry, x))
+	must0(body.Return(sum, must(Multiply(sum, sum))))
+
+	fn := builder.Main()
+	xs := must(fn.NamedInput("xs", shapes.Make(dtypes.Float32, 5, 2)))
+	init := must(fn.NamedInput("init", shapes.Make(dtypes.Float32, 2)))
+	if _, _, err := fn.Scan(nil, []*Value{init}, body); err == nil {
+		t.Error("expected error for missing xs")
+	}
+	if _, _, err := fn.Scan([]*Value{xs, must(fn.ConstantFromFlatAndDimensions([]float32{1, 2}, 1, 2))},
+		[]*Value{init}, body); err == nil {
+		t.Error("expected error for xs with different leading dimensions")
+	}
+	if _, _, err := fn.Scan([]*Value{xs}, []*Value{xs}, body); err == nil {
+		t.Error("expected error for carry not matching the body inputs")
+	}
+	final, ys, err := fn.Scan([]*Value{xs}, []*Value{init}, body)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(final) != 1 || len(ys) != 1 || !ys[0].Shape().Equal(shapes.Make(dtypes.Float32, 5, 2)) {
+		t.Fatalf("unexpected Scan outputs: %d carry values and %d stacked outputs", len(final), len(ys))
+	}
+	must0(fn.Return(final[0], ys[0]))
+	program := string(must(builder.Build()))
+	fmt.Printf("%s program:\n%s", t.Name(), program)
+	want := `module @TestScan {
+  func.func @body(%carry: tensor<2xf32>, %x: tensor<2xf32>) -> (tensor<2xf32>, tensor<2xf32>) {
+    %0 = "stablehlo.add"(%carry, %x) : (tensor<2xf32>, tensor<2xf32>) -> tensor<2xf32>
+    %1 = "stablehlo.multiply"(%0, %0) : (tensor<2xf32>, tensor<2xf32>) -> tensor<2xf32>
+    "stablehlo.return"(%0, %1) : (tensor<2xf32>, tensor<2xf32>) -> ()
+  }
+
+  func.func @main(%xs: tensor<5x2xf32>, %init: tensor<2xf32>) -> (tensor<2xf32>, tensor<5x2xf32>) {
+    %0 = "stablehlo.constant"() { value = dense<[[1.0, 2.0]]> : tensor<1x2xf32> } : () -> tensor<1x2xf32>
+    %1 = "stablehlo.constant"() { value = dense<0> : tensor<i32> } : () -> tensor<i32>
+    %2 = "stablehlo.constant"() { value = dense<0.0> : tensor<f32> } : () -> tensor<f32>
+    %3 = "stablehlo.broadcast_in_dim"(%2) { broadcast_dimensions = array<i64> } : (tensor<f32>) -> tensor<5x2xf32>
+    %16, %17, %18, %19 = "stablehlo.while"(%1, %init, %xs, %3) ({
+      ^cond(%arg0: tensor<i32>, %arg1: tensor<2xf32>, %arg2: tensor<5x2xf32>, %arg3: tensor<5x2xf32>) :
+          %4 = "stablehlo.constant"() { value = dense<5> : tensor<i32> } : () -> tensor<i32>
+          %5 = "stablehlo.compare"(%arg0, %4) {
+            compare_type = #stablehlo<comparison_type SIGNED>,
+            comparison_direction = #stablehlo<comparison_direction LT>
+          } : (tensor<i32>, tensor<i32>) -> tensor<i1>
+          "stablehlo.return"(%5) : (tensor<i1>) -> ()
+    }, {
+      ^body(%arg4: tensor<i32>, %arg5: tensor<2xf32>, %arg6: tensor<5x2xf32>, %arg7: tensor<5x2xf32>) :
+          %6 = "stablehlo.constant"() { value = dense<0> : tensor<i32> } : () -> tensor<i32>
+          %7 = "stablehlo.dynamic_slice"(%arg6, %arg4, %6) { slice_sizes = array<i64: 1, 2> } : (tensor<5x2xf32>, tensor<i32>, tensor<i32>) -> tensor<1x2xf32>
+          %8 = "stablehlo.reshape"(%7) : (tensor<1x2xf32>) -> tensor<2xf32>
+          %9, %10 = "func.call"(%arg5, %8) { callee = @body } : (tensor<2xf32>, tensor<2xf32>) -> (tensor<2xf32>, tensor<2xf32>)
+          %11 = "stablehlo.constant"() { value = dense<1> : tensor<i32> } : () -> tensor<i32>
+          %12 = "stablehlo.add"(%arg4, %11) : (tensor<i32>, tensor<i32>) -> tensor<i32>
+          %13 = "stablehlo.reshape"(%10) : (tensor<2xf32>) -> tensor<1x2xf32>
+          %14 = "stablehlo.constant"() { value = dense<0> : tensor<i32> } : () -> tensor<i32>
+          %15 = "stablehlo.dynamic_update_slice"(%arg7, %13, %arg4, %14) : (tensor<5x2xf32>, tensor<1x2xf32>, tensor<i32>, tensor<i32>) -> tensor<5x2xf32>
+          "stablehlo.return"(%12, %9, %arg6, %15) : (tensor<i32>, tensor<2xf32>, tensor<5x2xf32>, tensor<5x2xf32>) -> ()
+    }) : (tensor<i32>, tensor<2xf32>, tensor<5x2xf32>, tensor<5x2xf32>) -> (tensor<i32>, tensor<2xf32>, tensor<5x2xf32>, tensor<5x2xf32>)
+    "stablehlo.return"(%17, %19) : (tensor<2xf32>, tensor<5x2xf32>) -> ()
+  }
+}
+`
+	if program != want {
+		fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+		t.Fatal("programs don't match")
+	}
+	requireRoundTrip(t, builder)
+}
+
+func TestScanEmpty(t *testing.T) {
+	builder := New(t.Name())
+	body := builder.NewFunction("body")
+	carry := must(body.NamedInput("carry", shapes.Make(dtypes.Float32, 2)))
+	x := must(body.NamedInput("x", shapes.Make(dtypes.Float32, 2)))
+	sum := must(Add(carry, x))
+	must0(body.Return(sum, must(Multiply(sum, sum))))
+
+	fn := builder.Main()
+	xs := must(fn.NamedInput("xs", shapes.Make(dtypes.Float32, 0, 2)))
+	init := must(fn.NamedInput("init", shapes.Make(dtypes.Float32, 2)))
+	final, ys, err := fn.Scan([]*Value{xs}, []*Value{init}, body)
+	if err != nil {
+		t.Fatalf("Scan with 0 steps failed: %+v", err)
+	}
+	if len(final) != 1 || final[0] != init {
+		t.Errorf("expected the carry to be returned as is for 0 steps, got %v", final)
+	}
+	if len(ys) != 1 || !ys[0].Shape().Equal(shapes.Make(dtypes.Float32, 0, 2)) {
+		t.Fatalf("expected one empty stacked output of shape (Float32)[0 2], got %v", ys)
+	}
+	must0(fn.Return(final[0], ys[0]))
+	program := string(must(builder.Build()))
+	fmt.Printf("%s program:\n%s", t.Name(), program)
+	if strings.Contains(program, "stablehlo.while") {
+		t.Error("expected no while loop for 0 steps")
+	}
+	requireRoundTrip(t, builder)
+}
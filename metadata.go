@@ -0,0 +1,65 @@
+package stablehlo
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"time"
+)
+
+// GeneratorMetadata describes the tool that generated the program, so deployed artifacts can be traced back
+// to it. See Builder.WithGeneratorMetadata.
+//
+// Empty fields are omitted.
+type GeneratorMetadata struct {
+	// Name and Version of the tool that generated the program.
+	Name, Version string
+
+	// GitHash of the source code of the tool.
+	GitHash string
+
+	// Timestamp of the generation of the program.
+	Timestamp time.Time
+
+	// Extra holds arbitrary key/value pairs, rendered in sorted order of the keys.
+	// Newlines in the keys and values are rendered as spaces.
+	Extra map[string]string
+}
+
+// WithGeneratorMetadata sets the metadata of the generator of the program, rendered as comments before the module.
+// The metadata is copied, so later changes to its Extra map don't affect the program.
+func (b *Builder) WithGeneratorMetadata(metadata GeneratorMetadata) *Builder {
+	metadata.Extra = maps.Clone(metadata.Extra)
+	b.generatorMetadata = &metadata
+	return b
+}
+
+// write the metadata as comments, one per line.
+func (m *GeneratorMetadata) write(w io.Writer) error {
+	var lines []string
+	// Newlines in the keys or values would end the comment.
+	sanitize := strings.NewReplacer("\n", " ", "\r", " ")
+	add := func(key, value string) {
+		if value == "" {
+			return
+		}
+		key, value = sanitize.Replace(key), sanitize.Replace(value)
+		lines = append(lines, fmt.Sprintf("// %s: %s\n", key, value))
+	}
+	add("generator", strings.TrimSpace(m.Name+" "+m.Version))
+	add("git_hash", m.GitHash)
+	if !m.Timestamp.IsZero() {
+		add("timestamp", m.Timestamp.UTC().Format(time.RFC3339))
+	}
+	for _, key := range slices.Sorted(maps.Keys(m.Extra)) {
+		add(key, m.Extra[key])
+	}
+	for _, line := range lines {
+		if _, err := io.WriteString(w, line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,40 @@
+package stablehlo
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestGeneratorMetadata(t *testing.T) {
+	extra := map[string]string{"model": "resnet\n50", "author": "me", "x\nmodule @injected {": "1"}
+	builder := New(t.Name()).WithGeneratorMetadata(GeneratorMetadata{
+		Name:      "mytool",
+		Version:   "v1.2.3",
+		GitHash:   "0123abcd",
+		Timestamp: time.Date(2025, 3, 4, 5, 6, 7, 0, time.UTC),
+		Extra:     extra,
+	})
+	// Changes after WithGeneratorMetadata don't affect the program.
+	extra["late"] = "value"
+	fn := builder.Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32)))
+	if err := fn.Return(x); err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	program := string(must(builder.Build()))
+	want := `// generator: mytool v1.2.3
+// git_hash: 0123abcd
+// timestamp: 2025-03-04T05:06:07Z
+// author: me
+// model: resnet 50
+// x module @injected {: 1
+module @TestGeneratorMetadata {
+`
+	if !strings.HasPrefix(program, want) {
+		t.Errorf("expected program to start with:\n%s\ngot:\n%s", want, program)
+	}
+}
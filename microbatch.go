@@ -0,0 +1,154 @@
+package stablehlo
+
+import (
+	"math"
+	"slices"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// AccumulateMicrobatches splits the batchInputs into numMicrobatches microbatches along their first axis, calls the
+// step function once for each microbatch in a While loop, and returns the sums of the step outputs over all
+// microbatches. It's the usual gradient accumulation used to train with batches larger than what fits in memory.
+//
+// The step function must be a returned top-level function of the same Builder (see Builder.NewFunction), taking
+// the sharedInputs (e.g. the model parameters) followed by one microbatch of each of the batchInputs, and returning
+// the values to accumulate (e.g. the gradients and the loss). Divide the results by numMicrobatches for the means.
+//
+// The first axis of the batch inputs must be divisible by numMicrobatches, and fit an int32.
+func (fn *Function) AccumulateMicrobatches(step *Function, numMicrobatches int, sharedInputs, batchInputs []*Value) (
+	outputs []*Value, err error) {
+	if step != nil {
+		defer fn.multiOpErrorHandler(&err, &outputs, len(step.Outputs))()
+	}
+	if fn.Returned {
+		return nil, errors.Errorf("cannot add AccumulateMicrobatches after returning, in function %q", fn.Name)
+	}
+	if step == nil || step.Parent != nil || step.Builder != fn.Builder || !step.Returned {
+		return nil, errors.New("AccumulateMicrobatches requires a returned top-level step function of the same Builder")
+	}
+	if len(step.Outputs) == 0 {
+		return nil, errors.Errorf("AccumulateMicrobatches: step function %q has no outputs to accumulate", step.Name)
+	}
+	if numMicrobatches < 1 {
+		return nil, errors.Errorf("AccumulateMicrobatches: numMicrobatches must be >= 1, got %d", numMicrobatches)
+	}
+	if len(step.Inputs) != len(sharedInputs)+len(batchInputs) {
+		return nil, errors.Errorf("AccumulateMicrobatches: step function %q takes %d inputs, but %d shared and %d batch inputs were given",
+			step.Name, len(step.Inputs), len(sharedInputs), len(batchInputs))
+	}
+	microbatchSizes := make([]int, len(batchInputs))
+	for i, batch := range batchInputs {
+		if batch.shape.Rank() == 0 || batch.shape.Dimensions[0]%numMicrobatches != 0 {
+			return nil, errors.Errorf("AccumulateMicrobatches: batch input #%d with shape %s can't be split into %d microbatches",
+				i, batch.shape, numMicrobatches)
+		}
+		if batch.shape.Dimensions[0] > math.MaxInt32 {
+			// The microbatch start indices are computed in int32.
+			return nil, errors.Errorf("AccumulateMicrobatches: batch input #%d with shape %s has a first axis larger than int32 indices (%d)",
+				i, batch.shape, math.MaxInt32)
+		}
+		microbatchSizes[i] = batch.shape.Dimensions[0] / numMicrobatches
+		want := batch.shape.Clone()
+		want.Dimensions[0] = microbatchSizes[i]
+		if stepInput := step.Inputs[len(sharedInputs)+i]; !stepInput.shape.Equal(want) {
+			return nil, errors.Errorf("AccumulateMicrobatches: step function input #%d has shape %s, but the microbatches of batch input #%d have shape %s",
+				len(sharedInputs)+i, stepInput.shape, i, want)
+		}
+	}
+
+	// Loop state: counter, shared inputs, batch inputs and accumulators.
+	counter, err := fn.ConstantFromScalar(int32(0))
+	if err != nil {
+		return nil, err
+	}
+	state := slices.Concat([]*Value{counter}, sharedInputs, batchInputs)
+	for _, output := range step.Outputs {
+		zeros, err := fn.ConstantZeros(output.shape)
+		if err != nil {
+			return nil, err
+		}
+		state = append(state, zeros)
+	}
+	numAccumulators := len(step.Outputs)
+	firstBatch, firstAccumulator := 1+len(sharedInputs), len(state)-numAccumulators
+
+	cond, condInputs, err := fn.newLoopClosure(state, shapes.Make(dtypes.Bool))
+	if err != nil {
+		return nil, err
+	}
+	limit, err := cond.ConstantFromScalar(int32(numMicrobatches))
+	if err != nil {
+		return nil, err
+	}
+	notDone, err := Compare(condInputs[0], limit, types.CompareLT, types.CompareSigned)
+	if err != nil {
+		return nil, err
+	}
+	if err = cond.Return(notDone); err != nil {
+		return nil, err
+	}
+
+	body, bodyInputs, err := fn.newLoopClosure(state, valuesToShapes(state)...)
+	if err != nil {
+		return nil, err
+	}
+	stepArgs := slices.Clone(bodyInputs[1:firstBatch])
+	for i, batch := range bodyInputs[firstBatch:firstAccumulator] {
+		size, err := body.ConstantFromScalar(int32(microbatchSizes[i]))
+		if err != nil {
+			return nil, err
+		}
+		start, err := Multiply(bodyInputs[0], size)
+		if err != nil {
+			return nil, err
+		}
+		startIndices := []*Value{start}
+		sliceSizes := slices.Clone(batch.shape.Dimensions)
+		sliceSizes[0] = microbatchSizes[i]
+		for range batch.shape.Rank() - 1 {
+			zero, err := body.ConstantFromScalar(int32(0))
+			if err != nil {
+				return nil, err
+			}
+			startIndices = append(startIndices, zero)
+		}
+		microbatch, err := DynamicSlice(batch, startIndices, sliceSizes)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "AccumulateMicrobatches: slicing batch input #%d", i)
+		}
+		stepArgs = append(stepArgs, microbatch)
+	}
+	stepOutputs, err := body.Call(step, stepArgs...)
+	if err != nil {
+		return nil, err
+	}
+	one, err := body.ConstantFromScalar(int32(1))
+	if err != nil {
+		return nil, err
+	}
+	nextCounter, err := Add(bodyInputs[0], one)
+	if err != nil {
+		return nil, err
+	}
+	nextState := slices.Concat([]*Value{nextCounter}, bodyInputs[1:firstAccumulator])
+	for i, output := range stepOutputs {
+		accumulated, err := Add(bodyInputs[firstAccumulator+i], output)
+		if err != nil {
+			return nil, err
+		}
+		nextState = append(nextState, accumulated)
+	}
+	if err = body.Return(nextState...); err != nil {
+		return nil, err
+	}
+
+	finalState, err := While(cond, body, state...)
+	if err != nil {
+		return nil, err
+	}
+	return finalState[firstAccumulator:], nil
+}
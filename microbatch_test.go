@@ -0,0 +1,94 @@
+package stablehlo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestAccumulateMicrobatches(t *testing.T) {
+	builder := New(t.Name())
+	step := builder.NewFunction("step")
+	w := must(step.NamedInput("w", shapes.Make(dtypes.Float32, 3)))
+	x := must(step.NamedInput("x", shapes.Make(dtypes.Float32, 2, 3)))
+	grad := must(Multiply(must(BroadcastInDim(w, x.Shape(), []int{1})), x))
+	must0(step.Return(grad))
+
+	fn := builder.Main()
+	params := must(fn.NamedInput("params", shapes.Make(dtypes.Float32, 3)))
+	batch := must(fn.NamedInput("batch", shapes.Make(dtypes.Float32, 8, 3)))
+	if _, err := fn.AccumulateMicrobatches(step, 3, []*Value{params}, []*Value{batch}); err == nil {
+		t.Error("expected error for batch not divisible by the number of microbatches")
+	}
+	if _, err := fn.AccumulateMicrobatches(step, 2, []*Value{params}, []*Value{batch}); err == nil {
+		t.Error("expected error for microbatches not matching the step inputs")
+	}
+	if _, err := fn.AccumulateMicrobatches(step, 4, nil, []*Value{batch}); err == nil {
+		t.Error("expected error for missing shared inputs")
+	}
+	sums := must(fn.AccumulateMicrobatches(step, 4, []*Value{params}, []*Value{batch}))
+	must0(fn.Return(sums...))
+	program := string(must(builder.Build()))
+	fmt.Printf("%s program:\n%s", t.Name(), program)
+	want := `module @TestAccumulateMicrobatches {
+  func.func @step(%w: tensor<3xf32>, %x: tensor<2x3xf32>) -> tensor<2x3xf32> {
+    %0 = "stablehlo.broadcast_in_dim"(%w) { broadcast_dimensions = array<i64: 1> } : (tensor<3xf32>) -> tensor<2x3xf32>
+    %1 = "stablehlo.multiply"(%0, %x) : (tensor<2x3xf32>, tensor<2x3xf32>) -> tensor<2x3xf32>
+    "stablehlo.return"(%1) : (tensor<2x3xf32>) -> ()
+  }
+
+  func.func @main(%params: tensor<3xf32>, %batch: tensor<8x3xf32>) -> tensor<2x3xf32> {
+    %0 = "stablehlo.constant"() { value = dense<0> : tensor<i32> } : () -> tensor<i32>
+    %1 = "stablehlo.constant"() { value = dense<0.0> : tensor<f32> } : () -> tensor<f32>
+    %2 = "stablehlo.broadcast_in_dim"(%1) { broadcast_dimensions = array<i64> } : (tensor<f32>) -> tensor<2x3xf32>
+    %13, %14, %15, %16 = "stablehlo.while"(%0, %params, %batch, %2) ({
+      ^cond(%arg0: tensor<i32>, %arg1: tensor<3xf32>, %arg2: tensor<8x3xf32>, %arg3: tensor<2x3xf32>) :
+          %3 = "stablehlo.constant"() { value = dense<4> : tensor<i32> } : () -> tensor<i32>
+          %4 = "stablehlo.compare"(%arg0, %3) {
+            compare_type = #stablehlo<comparison_type SIGNED>,
+            comparison_direction = #stablehlo<comparison_direction LT>
+          } : (tensor<i32>, tensor<i32>) -> tensor<i1>
+          "stablehlo.return"(%4) : (tensor<i1>) -> ()
+    }, {
+      ^body(%arg4: tensor<i32>, %arg5: tensor<3xf32>, %arg6: tensor<8x3xf32>, %arg7: tensor<2x3xf32>) :
+          %5 = "stablehlo.constant"() { value = dense<2> : tensor<i32> } : () -> tensor<i32>
+          %6 = "stablehlo.multiply"(%arg4, %5) : (tensor<i32>, tensor<i32>) -> tensor<i32>
+          %7 = "stablehlo.constant"() { value = dense<0> : tensor<i32> } : () -> tensor<i32>
+          %8 = "stablehlo.dynamic_slice"(%arg6, %6, %7) { slice_sizes = array<i64: 2, 3> } : (tensor<8x3xf32>, tensor<i32>, tensor<i32>) -> tensor<2x3xf32>
+          %9 = "func.call"(%arg5, %8) { callee = @step } : (tensor<3xf32>, tensor<2x3xf32>) -> tensor<2x3xf32>
+          %10 = "stablehlo.constant"() { value = dense<1> : tensor<i32> } : () -> tensor<i32>
+          %11 = "stablehlo.add"(%arg4, %10) : (tensor<i32>, tensor<i32>) -> tensor<i32>
+          %12 = "stablehlo.add"(%arg7, %9) : (tensor<2x3xf32>, tensor<2x3xf32>) -> tensor<2x3xf32>
+          "stablehlo.return"(%11, %arg5, %arg6, %12) : (tensor<i32>, tensor<3xf32>, tensor<8x3xf32>, tensor<2x3xf32>) -> ()
+    }) : (tensor<i32>, tensor<3xf32>, tensor<8x3xf32>, tensor<2x3xf32>) -> (tensor<i32>, tensor<3xf32>, tensor<8x3xf32>, tensor<2x3xf32>)
+    "stablehlo.return"(%16) : (tensor<2x3xf32>) -> ()
+  }
+}
+`
+	if program != want {
+		fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+		t.Fatal("programs don't match")
+	}
+	requireRoundTrip(t, builder)
+}
+
+func TestWhile(t *testing.T) {
+	fn := New(t.Name()).Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32)))
+	cond := fn.Closure()
+	must0(cond.Return(must(cond.NamedInput("x", shapes.Make(dtypes.Float32)))))
+	body := fn.Closure()
+	must0(body.Return(must(body.NamedInput("x", shapes.Make(dtypes.Float32)))))
+	if _, err := While(cond, body, x); err == nil {
+		t.Error("expected error for cond not returning a boolean")
+	}
+	if _, err := While(body, fn.Closure(), x); err == nil {
+		t.Error("expected error for closure not returned")
+	}
+	other := New("other").Main().Closure()
+	if _, err := While(other, body, x); err == nil {
+		t.Error("expected error for closure of another function")
+	}
+}
@@ -0,0 +1,266 @@
+package stablehlo
+
+import (
+	"maps"
+	"slices"
+
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// Minimize reduces a program to one with fewer statements and outputs, and smaller inputs, that still satisfies the
+// interesting predicate, using delta debugging.
+// It is used to create minimal reproducers of bugs: e.g. with a predicate that reports whether compiling the
+// program with PJRT fails.
+//
+// The program is parsed with Import, so it must be in the form described there, and its "main" function is the one
+// minimized: calls to other functions are inlined first (see Builder.InlineCalls).
+// The predicate must hold for the original program, otherwise an error is returned.
+//
+// The reduction is done by repeatedly (until no more progress is made):
+//
+//   - Removing outputs of the main function.
+//   - Replacing outputs by the values they are computed from (the operands of the statements computing them).
+//   - Cutting values computed in the program: they are fed as inputs (named "cut_<value name>") instead, and the
+//     statements only used to compute them are removed (see Function.ExtractSubgraph).
+//
+// And then, once no more statements can be removed, by repeatedly halving the leading axis (e.g. the batch axis) of
+// the inputs: first of all the inputs with the same leading dimension at once, and then of each input. The shapes
+// of the statements using them are inferred again, which is only supported for element-wise operations (e.g. add,
+// compare, select): inputs used by other operations (e.g. reshape, dot_general, reduce), whose attributes depend
+// on the shapes, are not shrunk.
+//
+// Each candidate is rendered with Builder.Build and only kept if the predicate still holds.
+func Minimize(program []byte, interesting func(program []byte) bool) ([]byte, error) {
+	b, err := Import(program)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Minimize failed to import the program")
+	}
+	if _, err = b.InlineCalls(-1); err != nil {
+		return nil, errors.WithMessage(err, "Minimize failed to inline function calls")
+	}
+	var main *Function
+	for _, fn := range b.functions {
+		if fn.Name == MainFunctionName && fn.Parent == nil {
+			main = fn
+			break
+		}
+	}
+	if main == nil {
+		return nil, errors.Errorf("Minimize: program has no %q function", MainFunctionName)
+	}
+	if !interesting(program) {
+		return nil, errors.New("Minimize: the original program doesn't satisfy the predicate")
+	}
+	minimized, err := main.Minimize(func(candidate *Function) bool {
+		candidateProgram, err := candidate.Builder.Build()
+		return err == nil && interesting(candidateProgram)
+	})
+	if err != nil {
+		return nil, err
+	}
+	return minimized.Builder.Build()
+}
+
+// Minimize returns the self-contained "main" Function with the fewest statements, in a new Builder, derived from fn
+// by removing statements and outputs and shrinking its inputs, for which the interesting predicate holds. See the package function Minimize for the reductions attempted.
+//
+// fn must have been returned, and it must not call other functions (see Builder.InlineCalls).
+// The predicate is not checked for fn itself: if it doesn't hold for any of the candidates, the returned function is
+// equivalent to fn.
+func (fn *Function) Minimize(interesting func(candidate *Function) bool) (*Function, error) {
+	if !fn.Returned {
+		return nil, errors.Errorf("Minimize requires function %q to be returned", fn.Name)
+	}
+	// The values returned are the inputs of the return statement (fn.Outputs are its copies).
+	returnStmt := fn.Statements[len(fn.Statements)-1]
+	outputs := slices.Clone(returnStmt.Inputs[:len(fn.Outputs)])
+	var cuts []*Value
+	best, err := fn.ExtractSubgraph(outputs)
+	if err != nil {
+		return nil, errors.WithMessage(err, "Minimize")
+	}
+
+	// try checks whether the given outputs and cuts yield a smaller interesting candidate, and keeps it if so.
+	try := func(candidateOutputs, candidateCuts []*Value) bool {
+		candidate, err := fn.ExtractSubgraph(candidateOutputs, candidateCuts...)
+		if err != nil {
+			return false
+		}
+		if len(candidate.Statements)+len(candidate.Outputs) >= len(best.Statements)+len(best.Outputs) {
+			// Not a reduction: e.g. the cut value is still computed for other uses.
+			return false
+		}
+		if !interesting(candidate) {
+			return false
+		}
+		outputs, cuts, best = candidateOutputs, candidateCuts, candidate
+		return true
+	}
+
+	for progress := true; progress; {
+		progress = false
+
+		// Remove outputs: in chunks halving in size, as in delta debugging.
+		for chunkSize := len(outputs) / 2; chunkSize >= 1; chunkSize /= 2 {
+			for start := 0; start < len(outputs) && len(outputs) > 1; {
+				end := min(start+chunkSize, len(outputs))
+				candidateOutputs := slices.Concat(outputs[:start], outputs[end:])
+				if len(candidateOutputs) > 0 && try(candidateOutputs, cuts) {
+					progress = true
+					continue // Same start, now pointing to the following chunk.
+				}
+				start = end
+			}
+		}
+
+		// Replace outputs by the values they are computed from.
+		producers := make(map[*Value]*Statement)
+		for _, stmt := range fn.Statements {
+			for _, output := range stmt.Outputs {
+				producers[output] = stmt
+			}
+		}
+		for i := 0; i < len(outputs); i++ {
+			stmt, found := producers[outputs[i]]
+			if !found {
+				continue
+			}
+			for _, input := range stmt.Inputs {
+				if slices.Contains(outputs, input) {
+					continue
+				}
+				candidateOutputs := slices.Clone(outputs)
+				candidateOutputs[i] = input
+				if try(candidateOutputs, cuts) {
+					progress = true
+					i-- // Try to move the new output further up.
+					break
+				}
+			}
+		}
+
+		// Cut values: starting from the last statements, since cutting those removes the most statements.
+		for idx := len(fn.Statements) - 2; idx >= 0; idx-- {
+			stmt := fn.Statements[idx]
+			if slices.ContainsFunc(stmt.Outputs, func(output *Value) bool { return slices.Contains(cuts, output) }) {
+				continue
+			}
+			if try(outputs, slices.Concat(cuts, stmt.Outputs)) {
+				progress = true
+			}
+		}
+	}
+	return best.minimizeInputShapes(interesting), nil
+}
+
+// minimizeInputShapes repeatedly halves the leading axis of the inputs of fn (a returned "main" function, as
+// created by ExtractSubgraph), as long as the interesting predicate holds. It returns the smallest candidate, or fn
+// itself if its inputs can't be shrunk.
+func (fn *Function) minimizeInputShapes(interesting func(candidate *Function) bool) *Function {
+	best := fn
+	// try checks whether halving the leading axis of the given inputs yields an interesting candidate, and keeps
+	// it if so.
+	try := func(inputIndices []int) bool {
+		newDims := make(map[int]int, len(inputIndices))
+		for _, idx := range inputIndices {
+			newDims[idx] = best.Inputs[idx].shape.Dimensions[0] / 2
+		}
+		candidate, err := best.withLeadingDimensions(newDims)
+		if err != nil || !interesting(candidate) {
+			return false
+		}
+		best = candidate
+		return true
+	}
+	for progress := true; progress; {
+		progress = false
+		// Inputs that can be shrunk, grouped by their leading dimension, since they are often used together.
+		groups := make(map[int][]int)
+		for idx, input := range best.Inputs {
+			if input.shape.Rank() > 0 && input.shape.Dimensions[0] > 1 {
+				groups[input.shape.Dimensions[0]] = append(groups[input.shape.Dimensions[0]], idx)
+			}
+		}
+		for _, dim := range slices.Sorted(maps.Keys(groups)) {
+			group := groups[dim]
+			if try(group) {
+				progress = true
+				continue
+			}
+			if len(group) == 1 {
+				continue
+			}
+			for _, idx := range group {
+				if try([]int{idx}) {
+					progress = true
+				}
+			}
+		}
+	}
+	return best
+}
+
+// withLeadingDimensions returns a copy of fn (a returned "main" function, as created by ExtractSubgraph), in a
+// new Builder, with the leading dimension of the inputs changed to newDims (indexed by input index).
+//
+// The output shapes of the statements are inferred again: it returns an error if a statement using a changed
+// shape is not an element-wise operation (see inferElementWiseShape).
+func (fn *Function) withLeadingDimensions(newDims map[int]int) (*Function, error) {
+	b := New(fn.Builder.name)
+	b.numReplicas = fn.Builder.numReplicas
+	b.numPartitions = fn.Builder.numPartitions
+	b.meshes = fn.Builder.meshes
+	candidate := b.Main()
+	candidate.nextArgID = fn.nextArgID
+	mapping := make(map[*Value]*Value, len(fn.values))
+	for idx, input := range fn.Inputs {
+		shape := input.shape
+		if dim, found := newDims[idx]; found {
+			shape = shape.Clone()
+			shape.Dimensions[0] = dim
+		}
+		newInput := &Value{
+			fn:         candidate,
+			name:       input.name,
+			shape:      shape,
+			Attributes: maps.Clone(input.Attributes),
+		}
+		newInput.mergeTagsFrom(input)
+		candidate.Inputs = append(candidate.Inputs, newInput)
+		candidate.values = append(candidate.values, newInput)
+		mapping[input] = newInput
+	}
+
+	returnStmt := fn.Statements[len(fn.Statements)-1]
+	for _, stmt := range fn.Statements[:len(fn.Statements)-1] {
+		changed := slices.ContainsFunc(stmt.Inputs, func(input *Value) bool {
+			return !mapping[input].shape.Equal(input.shape)
+		})
+		newStmt := candidate.cloneStatement(stmt, mapping)
+		candidate.Statements = append(candidate.Statements, newStmt)
+		if !changed {
+			continue
+		}
+		var output shapes.Shape
+		ok := len(newStmt.Outputs) == 1 && len(newStmt.FunctionParameters) == 0 && newStmt.rawSnippet == ""
+		if ok {
+			var err error
+			if output, ok, err = inferElementWiseShape(newStmt.OpType, newStmt.Inputs); err != nil {
+				return nil, err
+			}
+		}
+		if !ok {
+			return nil, errors.Errorf("cannot infer the shape of %s with different operand shapes", stmt.OpName())
+		}
+		newStmt.Outputs[0].shape = output
+	}
+	outputs := make([]*Value, len(fn.Outputs))
+	for i, output := range returnStmt.Inputs[:len(fn.Outputs)] {
+		outputs[i] = mapping[output]
+	}
+	if err := candidate.Return(outputs...); err != nil {
+		return nil, err
+	}
+	return candidate, nil
+}
@@ -0,0 +1,85 @@
+package stablehlo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestMinimize(t *testing.T) {
+	builder := New(t.Name())
+	fn := builder.Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+	y := must(fn.NamedInput("y", shapes.Make(dtypes.Float32, 3)))
+	a := must(Exponential(x))
+	bb := must(Add(a, y))
+	c := must(Cosine(bb))
+	d := must(Multiply(c, y))
+	e := must(Tanh(d))
+	f := must(Negate(x))
+	must0(fn.Return(e, f))
+	program := must(builder.Build())
+
+	// The "bug" reproduced is the presence of a cosine.
+	numCalls := 0
+	hasCosine := func(program []byte) bool {
+		numCalls++
+		return strings.Contains(string(program), "stablehlo.cosine")
+	}
+	minimized := string(must(Minimize(program, hasCosine)))
+	fmt.Printf("%s minimized program (%d predicate calls):\n%s", t.Name(), numCalls, minimized)
+	if !strings.Contains(minimized, "stablehlo.cosine") {
+		t.Fatal("minimized program no longer satisfies the predicate")
+	}
+	for _, unwanted := range []string{"stablehlo.exponential", "stablehlo.add", "stablehlo.multiply",
+		"stablehlo.tanh", "stablehlo.negate"} {
+		if strings.Contains(minimized, unwanted) {
+			t.Errorf("minimized program should not contain %q", unwanted)
+		}
+	}
+	if strings.Count(minimized, "\"stablehlo.") != 2 { // Cosine and return.
+		t.Errorf("minimized program should contain only the cosine statement")
+	}
+
+	// Shrinking the leading axis of the inputs used by element-wise operations.
+	builder = New(t.Name())
+	fn = builder.Main()
+	x = must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 8, 3)))
+	y = must(fn.NamedInput("y", shapes.Make(dtypes.Float32, 8, 3)))
+	w := must(fn.NamedInput("w", shapes.Make(dtypes.Float32, 6, 2)))
+	must0(fn.Return(must(Cosine(must(Add(x, y)))), must(Negate(w))))
+	program = must(builder.Build())
+	hasAddAndCosine := func(program []byte) bool {
+		return strings.Contains(string(program), "stablehlo.add") && hasCosine(program)
+	}
+	minimized = string(must(Minimize(program, hasAddAndCosine)))
+	fmt.Printf("%s minimized program with shrunk inputs:\n%s", t.Name(), minimized)
+	if !strings.Contains(minimized, "tensor<1x3xf32>") || strings.Contains(minimized, "8x3") {
+		t.Errorf("minimized program should have the inputs x and y shrunk to 1x3")
+	}
+	if strings.Contains(minimized, "stablehlo.negate") || strings.Contains(minimized, "6x2") {
+		t.Errorf("minimized program should not have the output negate(w)")
+	}
+
+	// Inputs used by operations whose attributes depend on the shapes are not shrunk.
+	fn = New(t.Name()).Main()
+	x = must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 8, 3)))
+	must0(fn.Return(must(Reshape(x, shapes.Make(dtypes.Float32, 24)))))
+	if _, err := fn.withLeadingDimensions(map[int]int{0: 4}); err == nil {
+		t.Error("expected error shrinking the input of a reshape")
+	}
+
+	// Errors.
+	if _, err := Minimize(program, func([]byte) bool { return false }); err == nil {
+		t.Error("expected error for a program that doesn't satisfy the predicate")
+	}
+	if _, err := Minimize([]byte("not a program"), hasCosine); err == nil {
+		t.Error("expected error for an invalid program")
+	}
+	if _, err := New("unreturned").Main().Minimize(func(*Function) bool { return true }); err == nil {
+		t.Error("expected error for a function not returned")
+	}
+}
@@ -0,0 +1,148 @@
+package stablehlo
+
+import (
+	"slices"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/shapeinference"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// LayerNorm normalizes x to zero mean and unit variance over the given axes, and applies the optional affine
+// transform:
+//
+//	output = (x - mean) * rsqrt(variance + epsilon) * scale + offset
+//
+// where the mean and the (biased) variance are computed over the axes, e.g. the last axis for the layer
+// normalization of transformers.
+//
+// The scale and offset can be nil. Otherwise, their dimensions are the dimensions of x on the axes (e.g. [embedDim]
+// for the last axis), and they are broadcast to the shape of x.
+//
+// It's composed of Reduce, Rsqrt and broadcasts, the standard pattern that XLA fuses. Float16 and BFloat16 values
+// are normalized in Float32, like most frameworks do, and the output is converted back to the dtype of x.
+func LayerNorm(x *Value, axes []int, scale, offset *Value, epsilon float64) (output *Value, err error) {
+	fn := x.fn
+	defer fn.opErrorHandler(&err, &output)()
+	if fn.Returned {
+		return nil, errors.Errorf("cannot add operation LayerNorm after returning, in function %q", fn.Name)
+	}
+	dtype := x.shape.DType
+	if !dtype.IsFloat() {
+		return nil, errors.Errorf("LayerNorm requires a float operand, got %s", x.shape)
+	}
+	if x.shape.IsDynamic() {
+		return nil, errors.Errorf("LayerNorm requires a static shape, got %s", x.shape)
+	}
+	if len(axes) == 0 {
+		return nil, errors.New("LayerNorm requires at least one axis to normalize")
+	}
+	if epsilon < 0 {
+		return nil, errors.Errorf("LayerNorm requires a non-negative epsilon, got %g", epsilon)
+	}
+	adjustedAxes := make([]int, len(axes))
+	for i, axis := range axes {
+		adjustedAxes[i], err = shapeinference.AdjustAxisToRank(axis, x.shape.Rank())
+		if err != nil {
+			return nil, errors.WithMessage(err, "LayerNorm")
+		}
+	}
+	slices.Sort(adjustedAxes)
+	if len(slices.Compact(slices.Clone(adjustedAxes))) != len(adjustedAxes) {
+		return nil, errors.Errorf("LayerNorm: duplicate axes %v", axes)
+	}
+	axesDims := make([]int, len(adjustedAxes))
+	count := 1
+	for i, axis := range adjustedAxes {
+		axesDims[i] = x.shape.Dimensions[axis]
+		count *= axesDims[i]
+	}
+	for _, param := range []struct {
+		name  string
+		value *Value
+	}{{"scale", scale}, {"offset", offset}} {
+		if param.value == nil {
+			continue
+		}
+		if param.value.fn != fn {
+			return nil, errors.Errorf("LayerNorm: %s is not part of function %q", param.name, fn.Name)
+		}
+		if want := shapes.Make(dtype, axesDims...); !param.value.shape.Equal(want) {
+			return nil, errors.Errorf("LayerNorm: %s must have shape %s (the dimensions of x %s on the axes %v), got %s",
+				param.name, want, x.shape, adjustedAxes, param.value.shape)
+		}
+	}
+
+	computeDType := dtype
+	if dtype == dtypes.Float16 || dtype == dtypes.BFloat16 {
+		computeDType = dtypes.Float32
+	}
+	// toCompute converts the value to the computation dtype.
+	toCompute := func(v *Value) (*Value, error) {
+		if v.shape.DType == computeDType {
+			return v, nil
+		}
+		return Convert(v, computeDType)
+	}
+	x, err = toCompute(x)
+	if err != nil {
+		return nil, err
+	}
+	zero, err := fn.scalarConstantOfDType(computeDType, 0)
+	if err != nil {
+		return nil, err
+	}
+	sumFn, err := fn.NewReductionClosure(ReductionAdd, computeDType)
+	if err != nil {
+		return nil, err
+	}
+	allAxes := make([]int, x.shape.Rank())
+	for axis := range allAxes {
+		allAxes[axis] = axis
+	}
+	// meanOverAxes returns the mean of v over the axes, which are kept with dimension 1.
+	meanOverAxes := func(v *Value) (*Value, error) {
+		sum, err := ReduceKeepDims(v, zero, sumFn, adjustedAxes...)
+		if err != nil {
+			return nil, err
+		}
+		return MultiplyScalar(sum, 1/float64(count))
+	}
+	// broadcastToX broadcasts a value with the axes kept with dimension 1 back to the shape of x.
+	broadcastToX := func(v *Value) (*Value, error) {
+		return BroadcastInDim(v, x.shape, allAxes)
+	}
+
+	centered, err := fn.Expr(x).Apply(meanOverAxes).Apply(broadcastToX).
+		Apply(func(mean *Value) (*Value, error) { return Subtract(x, mean) }).Value()
+	if err != nil {
+		return nil, err
+	}
+	output, err = fn.Expr(centered).Mul(centered).Apply(meanOverAxes).
+		Apply(func(variance *Value) (*Value, error) { return AddScalar(variance, epsilon) }).
+		Rsqrt().Apply(broadcastToX).Mul(centered).Value()
+	if err != nil {
+		return nil, err
+	}
+	if scale != nil {
+		output, err = fn.Expr(scale).Apply(toCompute).
+			Apply(func(v *Value) (*Value, error) { return BroadcastInDim(v, x.shape, adjustedAxes) }).
+			Mul(output).Value()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if offset != nil {
+		output, err = fn.Expr(offset).Apply(toCompute).
+			Apply(func(v *Value) (*Value, error) { return BroadcastInDim(v, x.shape, adjustedAxes) }).
+			Add(output).Value()
+		if err != nil {
+			return nil, err
+		}
+	}
+	if computeDType != dtype {
+		return Convert(output, dtype)
+	}
+	return output, nil
+}
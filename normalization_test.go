@@ -0,0 +1,100 @@
+package stablehlo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestLayerNorm(t *testing.T) {
+	t.Run("program", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.BFloat16, 2, 4)))
+		scale := must(fn.NamedInput("scale", shapes.Make(dtypes.BFloat16, 4)))
+		offset := must(fn.NamedInput("offset", shapes.Make(dtypes.BFloat16, 4)))
+		y := must(LayerNorm(x, []int{-1}, scale, offset, 1e-5))
+		if !y.Shape().Equal(x.Shape()) {
+			t.Errorf("LayerNorm: got shape %s, wanted %s", y.Shape(), x.Shape())
+		}
+		must0(fn.Return(y))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestLayerNorm_program {
+  func.func @main(%x: tensor<2x4xbf16>, %scale: tensor<4xbf16>, %offset: tensor<4xbf16>) -> tensor<2x4xbf16> {
+    %0 = "stablehlo.convert"(%x) : (tensor<2x4xbf16>) -> tensor<2x4xf32>
+    %1 = "stablehlo.constant"() { value = dense<0.0> : tensor<f32> } : () -> tensor<f32>
+    %3 = "stablehlo.reduce"(%0, %1) ({
+      ^reductionFn(%lhs: tensor<f32>, %rhs: tensor<f32>) :
+          %2 = "stablehlo.add"(%lhs, %rhs) : (tensor<f32>, tensor<f32>) -> tensor<f32>
+          "stablehlo.return"(%2) : (tensor<f32>) -> ()
+    }) { dimensions = array<i64: 1> } : (tensor<2x4xf32>, tensor<f32>) -> tensor<2xf32>
+    %4 = "stablehlo.reshape"(%3) : (tensor<2xf32>) -> tensor<2x1xf32>
+    %5 = "stablehlo.constant"() { value = dense<0.25> : tensor<f32> } : () -> tensor<f32>
+    %6 = "stablehlo.broadcast_in_dim"(%5) { broadcast_dimensions = array<i64> } : (tensor<f32>) -> tensor<2x1xf32>
+    %7 = "stablehlo.multiply"(%4, %6) : (tensor<2x1xf32>, tensor<2x1xf32>) -> tensor<2x1xf32>
+    %8 = "stablehlo.broadcast_in_dim"(%7) { broadcast_dimensions = array<i64: 0, 1> } : (tensor<2x1xf32>) -> tensor<2x4xf32>
+    %9 = "stablehlo.subtract"(%0, %8) : (tensor<2x4xf32>, tensor<2x4xf32>) -> tensor<2x4xf32>
+    %10 = "stablehlo.multiply"(%9, %9) : (tensor<2x4xf32>, tensor<2x4xf32>) -> tensor<2x4xf32>
+    %11 = "stablehlo.reduce"(%10, %1) ({
+      ^reductionFn(%lhs: tensor<f32>, %rhs: tensor<f32>) :
+          %2 = "stablehlo.add"(%lhs, %rhs) : (tensor<f32>, tensor<f32>) -> tensor<f32>
+          "stablehlo.return"(%2) : (tensor<f32>) -> ()
+    }) { dimensions = array<i64: 1> } : (tensor<2x4xf32>, tensor<f32>) -> tensor<2xf32>
+    %12 = "stablehlo.reshape"(%11) : (tensor<2xf32>) -> tensor<2x1xf32>
+    %13 = "stablehlo.constant"() { value = dense<0.25> : tensor<f32> } : () -> tensor<f32>
+    %14 = "stablehlo.broadcast_in_dim"(%13) { broadcast_dimensions = array<i64> } : (tensor<f32>) -> tensor<2x1xf32>
+    %15 = "stablehlo.multiply"(%12, %14) : (tensor<2x1xf32>, tensor<2x1xf32>) -> tensor<2x1xf32>
+    %16 = "stablehlo.constant"() { value = dense<9.999999747378752e-06> : tensor<f32> } : () -> tensor<f32>
+    %17 = "stablehlo.broadcast_in_dim"(%16) { broadcast_dimensions = array<i64> } : (tensor<f32>) -> tensor<2x1xf32>
+    %18 = "stablehlo.add"(%15, %17) : (tensor<2x1xf32>, tensor<2x1xf32>) -> tensor<2x1xf32>
+    %19 = "stablehlo.rsqrt"(%18) : (tensor<2x1xf32>) -> tensor<2x1xf32>
+    %20 = "stablehlo.broadcast_in_dim"(%19) { broadcast_dimensions = array<i64: 0, 1> } : (tensor<2x1xf32>) -> tensor<2x4xf32>
+    %21 = "stablehlo.multiply"(%20, %9) : (tensor<2x4xf32>, tensor<2x4xf32>) -> tensor<2x4xf32>
+    %22 = "stablehlo.convert"(%scale) : (tensor<4xbf16>) -> tensor<4xf32>
+    %23 = "stablehlo.broadcast_in_dim"(%22) { broadcast_dimensions = array<i64: 1> } : (tensor<4xf32>) -> tensor<2x4xf32>
+    %24 = "stablehlo.multiply"(%23, %21) : (tensor<2x4xf32>, tensor<2x4xf32>) -> tensor<2x4xf32>
+    %25 = "stablehlo.convert"(%offset) : (tensor<4xbf16>) -> tensor<4xf32>
+    %26 = "stablehlo.broadcast_in_dim"(%25) { broadcast_dimensions = array<i64: 1> } : (tensor<4xf32>) -> tensor<2x4xf32>
+    %27 = "stablehlo.add"(%26, %24) : (tensor<2x4xf32>, tensor<2x4xf32>) -> tensor<2x4xf32>
+    %28 = "stablehlo.convert"(%27) : (tensor<2x4xf32>) -> tensor<2x4xbf16>
+    "stablehlo.return"(%28) : (tensor<2x4xbf16>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2, 3, 4)))
+		xInt := must(fn.NamedInput("xInt", shapes.Make(dtypes.Int32, 2, 4)))
+		scale := must(fn.NamedInput("scale", shapes.Make(dtypes.Float32, 3, 4)))
+		for name, tc := range map[string]struct {
+			x       *Value
+			axes    []int
+			scale   *Value
+			epsilon float64
+		}{
+			"int operand":       {xInt, []int{1}, nil, 0},
+			"no axes":           {x, nil, nil, 0},
+			"invalid axis":      {x, []int{3}, nil, 0},
+			"duplicate axes":    {x, []int{2, -1}, nil, 0},
+			"negative epsilon":  {x, []int{2}, nil, -1},
+			"wrong scale shape": {x, []int{2}, scale, 0},
+		} {
+			if _, err := LayerNorm(tc.x, tc.axes, tc.scale, nil, tc.epsilon); err == nil {
+				t.Errorf("%s: expected error, got nil", name)
+			}
+		}
+		if _, err := LayerNorm(x, []int{1, 2}, scale, nil, 1e-6); err != nil {
+			t.Errorf("expected no error normalizing over 2 axes, got %+v", err)
+		}
+	})
+}
@@ -2,6 +2,7 @@ package stablehlo
 
 import (
 	"fmt"
+	"reflect"
 	"slices"
 	"strconv"
 	"strings"
@@ -18,11 +19,13 @@ import (
 func (fn *Function) addOp(opType optypes.OpType, outputShape shapes.Shape, inputs ...*Value) *Statement {
 	stmt := &Statement{
 		Builder:  fn.Builder,
+		id:       fn.Builder.newStatementID(),
 		Function: fn,
 		OpType:   opType,
 		Inputs:   inputs,
 		Outputs:  []*Value{fn.newValue(outputShape)},
 	}
+	stmt.registerUses()
 	fn.Statements = append(fn.Statements, stmt)
 	return stmt
 }
@@ -35,17 +38,20 @@ func (fn *Function) addMultiOp(opType optypes.OpType, outputShapes []shapes.Shap
 	}
 	stmt := &Statement{
 		Builder:  fn.Builder,
+		id:       fn.Builder.newStatementID(),
 		Function: fn,
 		OpType:   opType,
 		Inputs:   inputs,
 		Outputs:  outputs,
 	}
+	stmt.registerUses()
 	fn.Statements = append(fn.Statements, stmt)
 	return stmt
 }
 
 // binaryOp adds a new binary operation to the function.
-func (fn *Function) binaryOp(op optypes.OpType, lhs, rhs *Value) (*Value, error) {
+func (fn *Function) binaryOp(op optypes.OpType, lhs, rhs *Value) (output *Value, err error) {
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -54,7 +60,7 @@ func (fn *Function) binaryOp(op optypes.OpType, lhs, rhs *Value) (*Value, error)
 		return nil, errors.Errorf("cannot add operation %s to function %q, because the operands are not part of the function",
 			op, fn.Name)
 	}
-	outputShape, err := shapeinference.BinaryOp(op, lhs.shape, rhs.shape)
+	outputShape, err := fn.Builder.inferBinaryOp(op, lhs.shape, rhs.shape)
 	if err != nil {
 		return nil, err
 	}
@@ -62,7 +68,8 @@ func (fn *Function) binaryOp(op optypes.OpType, lhs, rhs *Value) (*Value, error)
 }
 
 // unaryOp adds a new unary operation to the function.
-func (fn *Function) unaryOp(op optypes.OpType, operand *Value) (*Value, error) {
+func (fn *Function) unaryOp(op optypes.OpType, operand *Value) (output *Value, err error) {
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -71,19 +78,60 @@ func (fn *Function) unaryOp(op optypes.OpType, operand *Value) (*Value, error) {
 		return nil, errors.Errorf("cannot add operation %s to function %q, because the operand is not part of the function",
 			op, fn.Name)
 	}
-	outputShape, err := shapeinference.UnaryOp(op, operand.shape)
+	outputShape, err := fn.Builder.inferUnaryOp(op, operand.shape)
 	if err != nil {
 		return nil, err
 	}
+	if decompose := fn.Builder.decompositionFor(op); decompose != nil {
+		return decompose(operand)
+	}
 	return fn.addOp(op, outputShape, operand).Outputs[0], nil
 }
 
+// unaryOpWithAccuracy is like unaryOp, but also sets the "result_accuracy" attribute.
+// The op must be in shapeinference.ResultAccuracyOperations.
+func (fn *Function) unaryOpWithAccuracy(op optypes.OpType, operand *Value, accuracy types.ResultAccuracy) (output *Value, err error) {
+	defer fn.opErrorHandler(&err, &output)()
+	if !shapeinference.ResultAccuracyOperations.Has(op) {
+		return nil, errors.Errorf("operation %s doesn't support the result_accuracy attribute", op)
+	}
+	if err = accuracy.Validate(); err != nil {
+		return nil, errors.WithMessagef(err, "in operation %s", op)
+	}
+	if fn.Builder.decompositionFor(op) != nil {
+		// The decomposition is made of other operations, which can't take the attribute.
+		if accuracy != (types.ResultAccuracy{}) {
+			return nil, errors.Errorf("the result_accuracy of %s can't be set, since it is not supported by the target %q and it is decomposed",
+				op, fn.Builder.targetFeatures.Name)
+		}
+		return fn.unaryOp(op, operand)
+	}
+	if !fn.Builder.supportsVersion(versionResultAccuracy) {
+		if accuracy != (types.ResultAccuracy{}) {
+			return nil, fn.Builder.requireVersion(fmt.Sprintf("the result_accuracy attribute of %s", op), versionResultAccuracy)
+		}
+		// The default accuracy is the same as not setting the attribute.
+		return fn.unaryOp(op, operand)
+	}
+	output, err = fn.unaryOp(op, operand)
+	if err != nil || output.IsPoisoned() {
+		return output, err
+	}
+	stmt := fn.Statements[len(fn.Statements)-1]
+	stmt.Attributes = map[string]any{
+		"result_accuracy": accuracy,
+	}
+	return output, nil
+}
+
 // Compare implements the corresponding standard binary operation.
 //
 // For boolean data types (dtypes.Bool) use the types.CompareUnsigned type.
-func Compare(lhs, rhs *Value, direction types.ComparisonDirection, compareType types.ComparisonType) (*Value, error) {
+// Use types.CompareAuto (or CompareAuto) for the comparison type matching the operands data type.
+func Compare(lhs, rhs *Value, direction types.ComparisonDirection, compareType types.ComparisonType) (output *Value, err error) {
 	op := optypes.Compare
 	fn := lhs.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -96,6 +144,10 @@ func Compare(lhs, rhs *Value, direction types.ComparisonDirection, compareType t
 	if err != nil {
 		return nil, err
 	}
+	compareType, err = shapeinference.ResolveComparisonType(lhs.shape.DType, compareType)
+	if err != nil {
+		return nil, err
+	}
 	stmt := fn.addOp(op, outputShape, lhs, rhs)
 	stmt.Attributes = map[string]any{
 		"compare_type":         compareType,
@@ -104,6 +156,12 @@ func Compare(lhs, rhs *Value, direction types.ComparisonDirection, compareType t
 	return stmt.Outputs[0], nil
 }
 
+// CompareAuto is like Compare with types.CompareAuto: the comparison type is inferred from the operands data type,
+// see types.ComparisonTypeForDType.
+func CompareAuto(lhs, rhs *Value, direction types.ComparisonDirection) (output *Value, err error) {
+	return Compare(lhs, rhs, direction, types.CompareAuto)
+}
+
 func valuesToShapes(values []*Value) []shapes.Shape {
 	s := make([]shapes.Shape, len(values))
 	for i, v := range values {
@@ -113,9 +171,10 @@ func valuesToShapes(values []*Value) []shapes.Shape {
 }
 
 // Complex returns the complex value by concatenating the real and imaginary parts element-wise.
-func Complex(real, imag *Value) (*Value, error) {
+func Complex(real, imag *Value) (output *Value, err error) {
 	op := optypes.Complex
 	fn := real.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -132,9 +191,10 @@ func Complex(real, imag *Value) (*Value, error) {
 }
 
 // Real returns the real part of the complex value.
-func Real(complex *Value) (*Value, error) {
+func Real(complex *Value) (output *Value, err error) {
 	op := optypes.Real
 	fn := complex.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -146,10 +206,11 @@ func Real(complex *Value) (*Value, error) {
 	return fn.addOp(op, outputShape, complex).Outputs[0], nil
 }
 
-// Imag returns the real part of the complex value.
-func Imag(complex *Value) (*Value, error) {
+// Imag returns the imaginary part of the complex value.
+func Imag(complex *Value) (output *Value, err error) {
 	op := optypes.Imag
 	fn := complex.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -161,12 +222,80 @@ func Imag(complex *Value) (*Value, error) {
 	return fn.addOp(op, outputShape, complex).Outputs[0], nil
 }
 
+// Conjugate returns the complex conjugate of x: the imaginary part is negated.
+//
+// For non-complex numeric values, the conjugate is the value itself, and x is returned unchanged.
+func Conjugate(x *Value) (output *Value, err error) {
+	fn := x.fn
+	defer fn.opErrorHandler(&err, &output)()
+	if !x.shape.DType.IsComplex() {
+		if x.shape.DType == dtypes.Bool {
+			return nil, errors.Errorf("Conjugate requires a numeric data type, got %s", x.shape)
+		}
+		return x, nil
+	}
+	real, err := Real(x)
+	if err != nil {
+		return nil, err
+	}
+	imag, err := Imag(x)
+	if err != nil {
+		return nil, err
+	}
+	imag, err = Negate(imag)
+	if err != nil {
+		return nil, err
+	}
+	return Complex(real, imag)
+}
+
+// ComplexFromPolar returns the complex value with the given magnitude and phase (in radians), element-wise:
+// magnitude * (cos(phase) + i*sin(phase)).
+//
+// The magnitude and phase must have the same shape, with a Float32 or Float64 data type, resulting respectively
+// in a Complex64 or Complex128 value.
+func ComplexFromPolar(magnitude, phase *Value) (output *Value, err error) {
+	fn := magnitude.fn
+	defer fn.opErrorHandler(&err, &output)()
+	if phase.fn != fn {
+		return nil, errors.Errorf("ComplexFromPolar: magnitude and phase are from different functions (%q and %q)",
+			fn.Name, phase.fn.Name)
+	}
+	for _, operand := range []*Value{magnitude, phase} {
+		if dtype := operand.shape.DType; dtype != dtypes.Float32 && dtype != dtypes.Float64 {
+			return nil, errors.Errorf("ComplexFromPolar requires Float32 or Float64 operands, got magnitude %s and phase %s",
+				magnitude.shape, phase.shape)
+		}
+	}
+	cos, err := Cosine(phase)
+	if err != nil {
+		return nil, err
+	}
+	sin, err := Sine(phase)
+	if err != nil {
+		return nil, err
+	}
+	real, err := Multiply(magnitude, cos)
+	if err != nil {
+		return nil, err
+	}
+	imag, err := Multiply(magnitude, sin)
+	if err != nil {
+		return nil, err
+	}
+	return Complex(real, imag)
+}
+
 // IsFinite tests whether each element of operand is finite, i.e., if it is not positive nor negative infinity, and it is not NaN.
 // It returns the same shape as the input, but with boolean values where each element is true if and only if
 // the corresponding input element is finite.
-func IsFinite(x *Value) (*Value, error) {
+//
+// The operand must have a floating-point dtype, including BFloat16 and the 8-bit float types (F8E4M3FN, F8E5M2, ...).
+// Complex numbers are not supported.
+func IsFinite(x *Value) (output *Value, err error) {
 	op := optypes.IsFinite
 	fn := x.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -185,9 +314,10 @@ func IsFinite(x *Value) (*Value, error) {
 // Clamp is not defined for booleans or complex numbers (the semantics would not be clear).
 //
 // Note: the order of the arguments in StableHLO is different from most ML libraries.
-func Clamp(min, x, max *Value) (*Value, error) {
+func Clamp(min, x, max *Value) (output *Value, err error) {
 	op := optypes.Clamp
 	fn := x.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -211,9 +341,10 @@ type DotGeneralBuilder struct {
 	rhs                              *Value
 	rhsContractingAxes, rhsBatchAxes []int
 
-	precision   [2]types.DotGeneralPrecisionType
-	outputDType dtypes.DType
-	algorithm   *types.DotGeneralAlgorithm
+	precision       [2]types.DotGeneralPrecisionType
+	outputDType     dtypes.DType
+	algorithm       *types.DotGeneralAlgorithm
+	algorithmPreset types.DotGeneralAlgorithmPreset
 }
 
 // DotGeneral takes as input lhs (left-hand-side) and rhs (right-hand-side) specifications
@@ -231,7 +362,9 @@ type DotGeneralBuilder struct {
 //
 // Because there are optional parameters, this function returns a DotGeneralBuilder that can
 // be further configured. Call DotGeneralBuilder.Done to get the final DotGeneral node.
-func Dot(lhs, rhs *Value) (*Value, error) {
+func Dot(lhs, rhs *Value) (output *Value, err error) {
+	fn := lhs.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if lhs.Shape().Rank() != 2 || rhs.Shape().Rank() != 2 {
 		return nil, errors.Errorf("Dot only supports rank-2 tensors, got %d and %d", lhs.Shape().Rank(), rhs.Shape().Rank())
 	}
@@ -300,14 +433,47 @@ func (b *DotGeneralBuilder) OutputDType(dtype dtypes.DType) *DotGeneralBuilder {
 // See details in types.DotGeneralAlgorithm.
 func (b *DotGeneralBuilder) Algorithm(algorithm *types.DotGeneralAlgorithm) *DotGeneralBuilder {
 	b.algorithm = algorithm
+	b.algorithmPreset = 0
+	return b
+}
+
+// AlgorithmPreset sets the algorithm to use for the dot-general operation to one of the algorithms known by XLA,
+// e.g. types.DotAlgorithmBF16_BF16_F32_X3. It replaces any previous call to Algorithm.
+//
+// For the ANY_F8 presets, the precision types are set to the dtypes of the operands, which must be 8-bit floats.
+//
+// See details in types.DotGeneralAlgorithmPreset.
+func (b *DotGeneralBuilder) AlgorithmPreset(preset types.DotGeneralAlgorithmPreset) *DotGeneralBuilder {
+	b.algorithm = nil
+	b.algorithmPreset = preset
 	return b
 }
 
+// presetAlgorithm returns the algorithm of the preset set with AlgorithmPreset, adjusted to the operands.
+func (b *DotGeneralBuilder) presetAlgorithm() (*types.DotGeneralAlgorithm, error) {
+	algorithm := b.algorithmPreset.Algorithm()
+	if algorithm == nil {
+		return nil, errors.Errorf("invalid dot algorithm preset %s", b.algorithmPreset)
+	}
+	if b.algorithmPreset.IsAnyF8() {
+		for _, operand := range []*Value{b.lhs, b.rhs} {
+			if format, found := floatFormats[operand.shape.DType]; !found || format.bits != 8 {
+				return nil, errors.Errorf("dot algorithm preset %s requires 8-bit float operands, got %s and %s",
+					b.algorithmPreset, b.lhs.shape.DType, b.rhs.shape.DType)
+			}
+		}
+		algorithm.LhsPrecisionType = types.FloatPrecisionType{DType: b.lhs.shape.DType}
+		algorithm.RhsPrecisionType = types.FloatPrecisionType{DType: b.rhs.shape.DType}
+	}
+	return algorithm, nil
+}
+
 // Done indicates the end of the DotGeneralBuilder configuration.
 // It checks the validity of the parameters and shapes and returns the final DotGeneral node.
-func (b *DotGeneralBuilder) Done() (*Value, error) {
+func (b *DotGeneralBuilder) Done() (output *Value, err error) {
 	op := optypes.DotGeneral
 	fn := b.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -323,6 +489,13 @@ func (b *DotGeneralBuilder) Done() (*Value, error) {
 	if err != nil {
 		return nil, err
 	}
+	algorithm := b.algorithm
+	if b.algorithmPreset != 0 {
+		algorithm, err = b.presetAlgorithm()
+		if err != nil {
+			return nil, err
+		}
+	}
 	stmt := b.fn.addOp(op, outputShape, b.lhs, b.rhs)
 	stmt.Attributes = map[string]any{
 		"dot_dimension_numbers": literalStrF(
@@ -339,7 +512,7 @@ func (b *DotGeneralBuilder) Done() (*Value, error) {
 	precisionConfig := fmt.Sprintf("[#stablehlo<precision %s>, #stablehlo<precision %s>]",
 		b.precision[0].ToStableHLO(), b.precision[1].ToStableHLO())
 	stmt.Attributes["precision_config"] = literalStr(precisionConfig)
-	if b.algorithm != nil {
+	if algorithm != nil {
 		stmt.Attributes["algorithm"] = literalStrF("#stablehlo.dot_algorithm<\n"+
 			"\tlhs_precision_type = %s,\n"+
 			"\trhs_precision_type = %s,\n"+
@@ -348,13 +521,13 @@ func (b *DotGeneralBuilder) Done() (*Value, error) {
 			"\trhs_component_count = %d,\n"+
 			"\tnum_primitive_operations = %d,\n"+
 			"\tallow_imprecise_accumulation = %v>",
-			b.algorithm.LhsPrecisionType.ToStableHLO(),
-			b.algorithm.RhsPrecisionType.ToStableHLO(),
-			b.algorithm.AccumulationType.ToStableHLO(),
-			b.algorithm.LhsComponentCount,
-			b.algorithm.RhsComponentCount,
-			b.algorithm.NumPrimitiveOperations,
-			b.algorithm.AllowImpreciseAccumulation)
+			algorithm.LhsPrecisionType.ToStableHLO(),
+			algorithm.RhsPrecisionType.ToStableHLO(),
+			algorithm.AccumulationType.ToStableHLO(),
+			algorithm.LhsComponentCount,
+			algorithm.RhsComponentCount,
+			algorithm.NumPrimitiveOperations,
+			algorithm.AllowImpreciseAccumulation)
 	}
 	return stmt.Outputs[0], nil
 }
@@ -363,9 +536,10 @@ func (b *DotGeneralBuilder) Done() (*Value, error) {
 // The total size of the new shape must match the original shape.
 //
 // This has no effect on the data, no transposition is performed.
-func Reshape(operand *Value, shape shapes.Shape) (*Value, error) {
+func Reshape(operand *Value, shape shapes.Shape) (output *Value, err error) {
 	op := optypes.Reshape
 	fn := operand.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -374,6 +548,10 @@ func Reshape(operand *Value, shape shapes.Shape) (*Value, error) {
 		return nil, errors.Errorf("Reshape() requires the operand and the shape to have the same data type, got operand=%s and shape=%s",
 			operand.shape, shape)
 	}
+	if operand.shape.IsDynamic() || shape.IsDynamic() {
+		return nil, errors.Errorf("Reshape() requires static shapes, got operand=%s and shape=%s",
+			operand.shape, shape)
+	}
 	if operand.shape.Size() != shape.Size() {
 		return nil, errors.Errorf("Reshape() requires the total size of the new shape to match the original shape, got operand=%s and shape=%s",
 			operand.shape, shape)
@@ -387,14 +565,15 @@ func Reshape(operand *Value, shape shapes.Shape) (*Value, error) {
 //
 // The axesMapping should have one value per operand axes. It maps the axes from the operand to
 // the corresponding value on the target shape.
-func BroadcastInDim(operand *Value, target shapes.Shape, axesMapping []int) (*Value, error) {
+func BroadcastInDim(operand *Value, target shapes.Shape, axesMapping []int) (output *Value, err error) {
 	op := optypes.BroadcastInDim
 	fn := operand.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
 	}
-	err := shapeinference.BroadcastInDim(operand.shape, target, axesMapping)
+	err = shapeinference.BroadcastInDim(operand.shape, target, axesMapping)
 	if err != nil {
 		return nil, err
 	}
@@ -469,9 +648,10 @@ func BroadcastInDim(operand *Value, target shapes.Shape, axesMapping []int) (*Va
 func Gather(operand, startIndices *Value, indexVectorAxis int,
 	offsetOutputAxes, collapsedSliceAxes, operandBatchingAxes,
 	startIndicesBatchingAxes, startIndexMap,
-	sliceSizes []int, indicesAreSorted bool) (*Value, error) {
+	sliceSizes []int, indicesAreSorted bool) (output *Value, err error) {
 	op := optypes.Gather
 	fn := operand.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -489,28 +669,98 @@ func Gather(operand, startIndices *Value, indexVectorAxis int,
 	if err != nil {
 		return nil, err
 	}
+	dimensionNumbers, err := gatherDimensionNumbers(fn, indexVectorAxis, offsetOutputAxes, collapsedSliceAxes,
+		operandBatchingAxes, startIndicesBatchingAxes, startIndexMap)
+	if err != nil {
+		return nil, err
+	}
 	stmt := fn.addOp(op, outputShape, operand, startIndices)
 	stmt.Attributes = map[string]any{
-		"dimension_numbers": literalStrF(
-			"#stablehlo.gather<\n"+
-				"\toffset_dims = %s,\n"+
-				"\tcollapsed_slice_dims = %s,\n"+
-				"\toperand_batching_dims = %s,\n"+
-				"\tstart_indices_batching_dims = %s,\n"+
-				"\tstart_index_map = %s,\n"+
-				"\tindex_vector_dim = %d>",
-			intSliceToStableHLO(offsetOutputAxes),
-			intSliceToStableHLO(collapsedSliceAxes),
-			intSliceToStableHLO(operandBatchingAxes),
-			intSliceToStableHLO(startIndicesBatchingAxes),
-			intSliceToStableHLO(startIndexMap),
-			indexVectorAxis),
+		"dimension_numbers":  dimensionNumbers,
 		"slice_sizes":        intSliceToArrayI64StableHLO(sliceSizes),
 		"indices_are_sorted": indicesAreSorted,
 	}
 	return stmt.Outputs[0], nil
 }
 
+// DynamicGather is like Gather, but the slice sizes are given by sliceSizes, a 1D integer tensor with one value per
+// operand axis, which may be only known at runtime (e.g. computed from the data).
+//
+// If sliceSizes is a constant, the output shape is static, like in Gather. Otherwise, the offset axes of the output
+// are dynamic (shapes.DynamicDim), bounded by the dimensions (or bounds) of the corresponding operand axes.
+//
+// See Gather for the description of the other parameters.
+func DynamicGather(operand, startIndices, sliceSizes *Value, indexVectorAxis int,
+	offsetOutputAxes, collapsedSliceAxes, operandBatchingAxes,
+	startIndicesBatchingAxes, startIndexMap []int, indicesAreSorted bool) (output *Value, err error) {
+	op := optypes.DynamicGather
+	fn := operand.fn
+	defer fn.opErrorHandler(&err, &output)()
+	if fn.Returned {
+		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
+			op, fn.Name)
+	}
+	if startIndices.fn != fn || sliceSizes.fn != fn {
+		return nil, errors.Errorf("cannot add operation %s to function %q, because startIndices or sliceSizes are from a different function",
+			op, fn.Name)
+	}
+
+	outputShape, err := shapeinference.DynamicGather(
+		operand.shape, startIndices.shape, sliceSizes.shape, indexVectorAxis,
+		offsetOutputAxes, collapsedSliceAxes, operandBatchingAxes,
+		startIndicesBatchingAxes, startIndexMap, indicesAreSorted)
+	if err != nil {
+		return nil, err
+	}
+	if constantSizes, ok := constantInts(sliceSizes); ok {
+		outputShape, err = shapeinference.Gather(
+			operand.shape, startIndices.shape, indexVectorAxis,
+			offsetOutputAxes, collapsedSliceAxes, operandBatchingAxes,
+			startIndicesBatchingAxes, startIndexMap,
+			constantSizes, indicesAreSorted)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "%s with constant sliceSizes %v", op, constantSizes)
+		}
+	}
+	dimensionNumbers, err := gatherDimensionNumbers(fn, indexVectorAxis, offsetOutputAxes, collapsedSliceAxes,
+		operandBatchingAxes, startIndicesBatchingAxes, startIndexMap)
+	if err != nil {
+		return nil, err
+	}
+	stmt := fn.addOp(op, outputShape, operand, startIndices, sliceSizes)
+	stmt.Attributes = map[string]any{
+		"dimension_numbers":  dimensionNumbers,
+		"indices_are_sorted": indicesAreSorted,
+	}
+	return stmt.Outputs[0], nil
+}
+
+// gatherDimensionNumbers returns the "dimension_numbers" attribute of Gather and DynamicGather.
+func gatherDimensionNumbers(fn *Function, indexVectorAxis int,
+	offsetOutputAxes, collapsedSliceAxes, operandBatchingAxes,
+	startIndicesBatchingAxes, startIndexMap []int) (literalStr, error) {
+	batchingDims := fmt.Sprintf("\toperand_batching_dims = %s,\n\tstart_indices_batching_dims = %s,\n",
+		intSliceToStableHLO(operandBatchingAxes), intSliceToStableHLO(startIndicesBatchingAxes))
+	if !fn.Builder.supportsVersion(versionBatchingDims) {
+		if len(operandBatchingAxes) > 0 || len(startIndicesBatchingAxes) > 0 {
+			return "", fn.Builder.requireVersion("Gather with batching axes", versionBatchingDims)
+		}
+		batchingDims = ""
+	}
+	return literalStrF(
+		"#stablehlo.gather<\n"+
+			"\toffset_dims = %s,\n"+
+			"\tcollapsed_slice_dims = %s,\n"+
+			"%s"+
+			"\tstart_index_map = %s,\n"+
+			"\tindex_vector_dim = %d>",
+		intSliceToStableHLO(offsetOutputAxes),
+		intSliceToStableHLO(collapsedSliceAxes),
+		batchingDims,
+		intSliceToStableHLO(startIndexMap),
+		indexVectorAxis), nil
+}
+
 // Slice extracts a subarray from the input array.
 // The subarray is of the same rank as the input and contains the values inside a bounding box within the input array
 // where the dimensions and indices of the bounding box are given as arguments to the slice operation.
@@ -520,9 +770,10 @@ func Gather(operand, startIndices *Value, indexVectorAxis int,
 //
 //	Slice(x={0, 1, 2, 3, 4}, starts={2}, limits={4}, strides=nil) -> {2, 3}
 //	Slice(x={0, 1, 2, 3, 4}, starts={2}, limits={5}, strides={2}) -> {2, 4}
-func Slice(x *Value, starts, limits, strides []int) (*Value, error) {
+func Slice(x *Value, starts, limits, strides []int) (output *Value, err error) {
 	op := optypes.Slice
 	fn := x.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -551,12 +802,13 @@ func Slice(x *Value, starts, limits, strides []int) (*Value, error) {
 // All axes that are not being concatenated must match dimensions, except on the axes being concatenated.
 // It doesn't work with scalars -- use ExpandAxes.
 // If there is only one operand, it is returned and this is a no-op.
-func Concatenate(axis int, operands ...*Value) (*Value, error) {
+func Concatenate(axis int, operands ...*Value) (output *Value, err error) {
 	op := optypes.Concatenate
 	if len(operands) == 0 {
 		return nil, errors.New("Concatenate requires at least one operand")
 	}
 	fn := operands[0].fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -594,9 +846,10 @@ func Concatenate(axis int, operands ...*Value) (*Value, error) {
 // The reduction function must be created with Builder.NewClosure, and it should take as input scalar
 // values be associative and commutative.
 //
-// The initialValue and x must have the same DType. This initial dtype must be promotable to the dtype accepted
-// by the reductions function. The result dtype is the same as the output of the reduction function.
-// So one could reduce-sum a 4bit quantized tensor directly into a Float32.
+// The dtypes of x and of initialValue must be promotable to the dtype accepted by the reduction function (see
+// dtypes.DType.IsPromotableTo), and they are converted to it if needed. The result dtype is the same as the output of
+// the reduction function. So one could reduce-sum a 4bit quantized tensor directly into a Float32, using a
+// Float32 zero or a 4bit zero as the initial value. See MultiReduce for the details of the dtype rules.
 //
 // See MultiReduce for a version that accepts multiple inputs and outputs.
 func Reduce(x, initialValue *Value, reductionFn *Function, axes ...int) (*Value, error) {
@@ -621,14 +874,22 @@ func Reduce(x, initialValue *Value, reductionFn *Function, axes ...int) (*Value,
 //
 // See Reduce for a version that accepts a single input.
 //
-// TODO: promotion of types doesn't seem to be working according to the spec in
-// https://openxla.org/stablehlo/spec#reduce.
-func MultiReduce(inputs, initialValues []*Value, reductionFn *Function, axes ...int) ([]*Value, error) {
+// The dtypes are propagated as follows, for each input i:
+//
+//   - The dtype of the reduction function lhs_i, rhs_i and out_i (they must be the same) is the dtype of outputs[i].
+//   - inputs[i] and initialValues[i] can have a different dtype than the reduction function, if it is promotable
+//     to it (see https://openxla.org/stablehlo/spec#reduce and dtypes.DType.IsPromotableTo) -- e.g. to accumulate
+//     float16 inputs in float32, with either a float16 or a float32 initial value. Otherwise, it returns an error.
+//   - Since not all backends support the implicit promotion, the inputs and initial values are explicitly converted
+//     (with Convert) to the dtype of the reduction function, so the generated reduce operation always has the same
+//     dtype for its inputs, initial values and outputs.
+func MultiReduce(inputs, initialValues []*Value, reductionFn *Function, axes ...int) (outputs []*Value, err error) {
 	op := optypes.Reduce
 	if len(inputs) == 0 {
 		return nil, errors.New("MultiReduce requires at least one operand")
 	}
 	fn := inputs[0].fn
+	defer fn.multiOpErrorHandler(&err, &outputs, len(inputs))()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -650,14 +911,42 @@ func MultiReduce(inputs, initialValues []*Value, reductionFn *Function, axes ...
 			op, fn.Name)
 	}
 
-	outputsShapes, err := shapeinference.Reduce(
-		valuesToShapes(inputs), valuesToShapes(initialValues),
-		valuesToShapes(reductionFn.Inputs), valuesToShapes(reductionFn.Outputs),
+	// Promotion of the inputs and initial values to the dtype of the reduction function.
+	promotedInputs, promotedInitialValues := valuesToShapes(inputs), valuesToShapes(initialValues)
+	if len(reductionFn.Inputs) == 2*len(inputs) && len(initialValues) == len(inputs) {
+		for i, input := range inputs {
+			dtype := reductionFn.Inputs[i].shape.DType
+			if input.shape.DType != dtype {
+				if !input.shape.DType.IsPromotableTo(dtype) {
+					return nil, errors.Errorf("%s: input #%d dtype %s is not promotable to the reduction function dtype %s",
+						op, i, input.shape.DType, dtype)
+				}
+				promotedInputs[i].DType = dtype
+			}
+			if initialDType := initialValues[i].shape.DType; initialDType != dtype {
+				if !initialDType.IsPromotableTo(dtype) {
+					return nil, errors.Errorf("%s: initial value #%d dtype %s is not promotable to the reduction function dtype %s (the input dtype is %s)",
+						op, i, initialDType, dtype, input.shape.DType)
+				}
+				promotedInitialValues[i].DType = dtype
+			}
+		}
+	}
+	outputsShapes, err := fn.Builder.inferReduce(
+		promotedInputs, promotedInitialValues,
+		valuesToShapes(reductionFn.Inputs), reductionFn.OutputShapes(),
 		axes)
 	if err != nil {
 		return nil, err
 	}
 	allInputs := append(slices.Clone(inputs), initialValues...)
+	for i, promoted := range slices.Concat(promotedInputs, promotedInitialValues) {
+		if allInputs[i].shape.DType != promoted.DType {
+			if allInputs[i], err = Convert(allInputs[i], promoted.DType); err != nil {
+				return nil, err
+			}
+		}
+	}
 	stmt := fn.addMultiOp(op, outputsShapes, allInputs)
 	stmt.Attributes = map[string]any{
 		"dimensions": intSliceToArrayI64StableHLO(axes),
@@ -666,13 +955,68 @@ func MultiReduce(inputs, initialValues []*Value, reductionFn *Function, axes ...
 	return stmt.Outputs, nil
 }
 
+// ReduceKeepDims is like Reduce, but the reduced axes are kept in the output with dimension 1, so the output has
+// the same rank as x -- convenient to broadcast the result back to the shape of x.
+//
+// See MultiReduceKeepDims for a version that accepts multiple inputs and outputs.
+func ReduceKeepDims(x, initialValue *Value, reductionFn *Function, axes ...int) (*Value, error) {
+	results, err := MultiReduceKeepDims([]*Value{x}, []*Value{initialValue}, reductionFn, axes...)
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// MultiReduceKeepDims is like MultiReduce, but the reduced axes are kept in the outputs with dimension 1, so the
+// outputs have the same rank as the inputs.
+func MultiReduceKeepDims(inputs, initialValues []*Value, reductionFn *Function, axes ...int) (outputs []*Value, err error) {
+	if len(inputs) == 0 {
+		return nil, errors.New("MultiReduceKeepDims requires at least one operand")
+	}
+	fn := inputs[0].fn
+	defer fn.multiOpErrorHandler(&err, &outputs, len(inputs))()
+	axes = slices.Clone(axes) // MultiReduce normalizes the axes in-place.
+	reduced, err := MultiReduce(inputs, initialValues, reductionFn, axes...)
+	if err != nil {
+		return nil, err
+	}
+	outputs = make([]*Value, len(reduced))
+	for i, output := range reduced {
+		keptDimsShape := inputs[0].shape.Clone()
+		keptDimsShape.DType = output.shape.DType
+		for _, axis := range axes {
+			keptDimsShape.Dimensions[axis] = 1
+			if keptDimsShape.HasBounds() {
+				keptDimsShape.Bounds[axis] = shapes.DynamicDim
+			}
+		}
+		outputs[i], err = Reshape(output, keptDimsShape)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return outputs, nil
+}
+
+// ReduceAll reduces x over all its axes, returning a scalar.
+//
+// See Reduce for details on the initialValue and reductionFn.
+func ReduceAll(x, initialValue *Value, reductionFn *Function) (*Value, error) {
+	axes := make([]int, x.shape.Rank())
+	for i := range axes {
+		axes[i] = i
+	}
+	return Reduce(x, initialValue, reductionFn, axes...)
+}
+
 // Select takes element-wise values from onTrue or onFalse depending on the value of the pred (must be boolean).
 //
 // The pred must be boolean and can be a scalar or have the same shape as isTrue and isFalse.
 // isTrue and isFalse must have the same shape and dtypes.
-func Select(pred, onTrue, onFalse *Value) (*Value, error) {
+func Select(pred, onTrue, onFalse *Value) (output *Value, err error) {
 	op := optypes.Select
 	fn := pred.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -702,10 +1046,15 @@ func Select(pred, onTrue, onFalse *Value) (*Value, error) {
 // If targetDType.Size() < x.DType().Size(), the returned shape will have an extra axis in the end, with dimension of
 // x.DType().Size() / targetDType.Size().
 //
+// The sizes above are the StableHLO bit widths, which for sub-byte dtypes (dtypes.S4, dtypes.U4, etc.) are
+// smaller than the storage used in Go.
+// Booleans (dtypes.Bool, or i1 in StableHLO) can't be bitcast to other dtypes: use Convert instead.
+//
 // E.g: Bitcast([1]uint32{0xdeadbeef}, dtypes.UInt16) -> [1][2]uint16{{0xbeef, 0xdead}} // Little-endian encoding.
-func BitcastConvert(operand *Value, targetDtype dtypes.DType) (*Value, error) {
+func BitcastConvert(operand *Value, targetDtype dtypes.DType) (output *Value, err error) {
 	op := optypes.BitcastConvert
 	fn := operand.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -723,9 +1072,10 @@ func BitcastConvert(operand *Value, targetDtype dtypes.DType) (*Value, error) {
 // There should be one value in permutation for each axis in x (len(permutation) == rank(x)).
 //
 // The output will have: output.Shape.Dimension[ii] = x.Shape.Dimension[permutations[i]].
-func Transpose(x *Value, permutation ...int) (*Value, error) {
+func Transpose(x *Value, permutation ...int) (output *Value, err error) {
 	op := optypes.Transpose
 	fn := x.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -742,18 +1092,19 @@ func Transpose(x *Value, permutation ...int) (*Value, error) {
 }
 
 // RNGBitGenerator generates the given shape filled with random bits.
-// It takes the current random number generator (RNG) state, see RngState or RngStateFromSeed.
+// It takes the current random number generator (RNG) state, see also RNGState to thread it through the program.
 //
 // It returns the new state of the RNG and the generated values (with random bits) with the given shape.
 //
 // The state shape depends on the algorithm:
 //
-// - types.RngDefault: PJRT implementation defined.
-// - types.RngThreeFry: 2xUint64
-// - types.RngPhilox: 2xUint64 or 3xUint64
+// - types.RNGDefault: PJRT implementation defined.
+// - types.RNGThreeFry: 2xUint64
+// - types.RNGPhilox: 2xUint64 or 3xUint64
 func RNGBitGenerator(state *Value, shape shapes.Shape, algorithm types.RNGBitGeneratorAlgorithm) (newState, values *Value, err error) {
 	op := optypes.RNGBitGenerator
 	fn := state.fn
+	defer fn.opErrorHandler(&err, &newState, &values)()
 	if fn.Returned {
 		return nil, nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -765,6 +1116,48 @@ func RNGBitGenerator(state *Value, shape shapes.Shape, algorithm types.RNGBitGen
 	return stmt.Outputs[0], stmt.Outputs[1], nil
 }
 
+// RNG returns random values with the given shape sampled from the distribution, using the implementation-defined
+// RNG of the backend. It is the legacy stablehlo.rng operation, for backends that don't support RNGBitGenerator.
+//
+// For the types.RNGUniform distribution, the values are sampled from the interval [a, b) -- the behavior is
+// implementation-defined if b <= a. For types.RNGNormal, a is the mean and b the standard deviation.
+//
+// The scalars a and b must have the dtype of the shape, which must be static. The dimensions of the shape are fed to
+// the operation as a constant.
+func RNG(a, b *Value, shape shapes.Shape, distribution types.RNGDistribution) (output *Value, err error) {
+	op := optypes.Rng
+	fn := a.fn
+	defer fn.opErrorHandler(&err, &output)()
+	if fn.Returned {
+		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
+			op, fn.Name)
+	}
+	if b.fn != fn {
+		return nil, errors.New("RNG operands a and b must be part of the same function")
+	}
+	if shape.IsTuple() || shape.IsDynamic() || shape.DType != a.shape.DType {
+		return nil, errors.Errorf("RNG requires a static shape with the dtype of a and b (%s), got %s",
+			a.shape.DType, shape)
+	}
+	outputShape, err := shapeinference.RNG(a.shape, b.shape, shape.Dimensions, distribution)
+	if err != nil {
+		return nil, err
+	}
+	dims := make([]int64, shape.Rank())
+	for axis, dim := range shape.Dimensions {
+		dims[axis] = int64(dim)
+	}
+	dimsValue, err := fn.ConstantFromFlatAndDimensions(dims, len(dims))
+	if err != nil {
+		return nil, err
+	}
+	stmt := fn.addOp(op, outputShape, a, b, dimsValue)
+	stmt.Attributes = map[string]any{
+		"rng_distribution": literalStr(distribution.ToStableHLO()),
+	}
+	return stmt.Outputs[0], nil
+}
+
 // Scatter returns the input updated with the values of update at the locations pointed by scatterIndices.
 // It allows axes to be used in powerful ways, but it's complex to get right.
 // Full details in https://openxla.org/stablehlo/spec#gather.
@@ -827,7 +1220,7 @@ func MultiScatter(inputs []*Value, scatterIndices *Value, updates []*Value,
 	inputBatchingAxes, scatterIndicesBatchingAxes []int,
 	indexedInputAxes []int, indexVectorAxis int,
 	indicesAreSorted, uniqueIndices bool,
-	updateComputationFn *Function) ([]*Value, error) {
+	updateComputationFn *Function) (outputs []*Value, err error) {
 	op := optypes.Scatter
 	if len(inputs) == 0 {
 		return nil, errors.New("MultiScatter requires at least one input")
@@ -837,6 +1230,7 @@ func MultiScatter(inputs []*Value, scatterIndices *Value, updates []*Value,
 			len(inputs), len(updates))
 	}
 	fn := inputs[0].fn
+	defer fn.multiOpErrorHandler(&err, &outputs, len(inputs))()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -870,26 +1264,32 @@ func MultiScatter(inputs []*Value, scatterIndices *Value, updates []*Value,
 		updateWindowAxes, insertedWindowAxes,
 		inputBatchingAxes, scatterIndicesBatchingAxes,
 		indexedInputAxes, indexVectorAxis,
-		updateComputationInputShapes, valuesToShapes(updateComputationFn.Outputs))
+		updateComputationInputShapes, updateComputationFn.OutputShapes())
 	if err != nil {
 		return nil, err
 	}
 	allInputs := append(slices.Clone(inputs), scatterIndices)
 	allInputs = append(allInputs, updates...)
+	batchingDims := fmt.Sprintf("\tinput_batching_dims = %s,\n\tscatter_indices_batching_dims = %s,\n",
+		intSliceToStableHLO(inputBatchingAxes), intSliceToStableHLO(scatterIndicesBatchingAxes))
+	if !fn.Builder.supportsVersion(versionBatchingDims) {
+		if len(inputBatchingAxes) > 0 || len(scatterIndicesBatchingAxes) > 0 {
+			return nil, fn.Builder.requireVersion("Scatter with batching axes", versionBatchingDims)
+		}
+		batchingDims = ""
+	}
 	stmt := fn.addMultiOp(op, outputShapes, allInputs)
 	stmt.Attributes = map[string]any{
 		"scatter_dimension_numbers": literalStrF(
 			"#stablehlo.scatter<\n"+
 				"\tupdate_window_dims = %s,\n"+
 				"\tinserted_window_dims = %s,\n"+
-				"\tinput_batching_dims = %s,\n"+
-				"\tscatter_indices_batching_dims = %s,\n"+
+				"%s"+
 				"\tscatter_dims_to_operand_dims = %s,\n"+
 				"\tindex_vector_dim = %d>",
 			intSliceToStableHLO(updateWindowAxes),
 			intSliceToStableHLO(insertedWindowAxes),
-			intSliceToStableHLO(inputBatchingAxes),
-			intSliceToStableHLO(scatterIndicesBatchingAxes),
+			batchingDims,
 			intSliceToStableHLO(indexedInputAxes),
 			indexVectorAxis),
 		"indices_are_sorted": indicesAreSorted,
@@ -907,9 +1307,10 @@ func MultiScatter(inputs []*Value, scatterIndices *Value, updates []*Value,
 //
 // Currently, it doesn't work for quantized to/from regular tensors. Use UniformQuantize and UniformDequantize
 // for that.
-func Convert(x *Value, dtype dtypes.DType) (*Value, error) {
+func Convert(x *Value, dtype dtypes.DType) (output *Value, err error) {
 	op := optypes.Convert
 	fn := x.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -942,9 +1343,10 @@ func Convert(x *Value, dtype dtypes.DType) (*Value, error) {
 //
 //	For each axis i in x:
 //	output.Dimensions[i] = paddingStart[i] + x.Dimensions[i] + max((x.Dimensions[i]-1), 0)*paddingInterior[i] + paddingEnd[i]
-func Pad(x, fill *Value, paddingStart, paddingEnd, paddingInterior []int) (*Value, error) {
+func Pad(x, fill *Value, paddingStart, paddingEnd, paddingInterior []int) (output *Value, err error) {
 	op := optypes.Pad
 	fn := x.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -974,6 +1376,74 @@ func Pad(x, fill *Value, paddingStart, paddingEnd, paddingInterior []int) (*Valu
 	return stmt.Outputs[0], nil
 }
 
+// DynamicPad is like Pad, but the paddings are given as 1D integer tensors (with one value per axis of x, and all
+// of the same dtype), which may be only known at runtime (e.g. computed from the data).
+//
+// If the paddings are constants, the output shape is static, like in Pad. Otherwise, all axes of the output are
+// dynamic (shapes.DynamicDim).
+//
+// See Pad for the description of the paddings.
+func DynamicPad(x, fill, paddingStart, paddingEnd, paddingInterior *Value) (output *Value, err error) {
+	op := optypes.DynamicPad
+	fn := x.fn
+	defer fn.opErrorHandler(&err, &output)()
+	if fn.Returned {
+		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
+			op, fn.Name)
+	}
+	for i, operand := range []*Value{fill, paddingStart, paddingEnd, paddingInterior} {
+		if operand.fn != fn {
+			return nil, errors.Errorf("cannot add operation %s to function %q, because operand #%d is from different function (%q and %q)",
+				op, fn.Name, i+1, operand.fn.Name, fn.Name)
+		}
+	}
+
+	outputShape, err := shapeinference.DynamicPad(x.shape, fill.shape, paddingStart.shape, paddingEnd.shape,
+		paddingInterior.shape)
+	if err != nil {
+		return nil, err
+	}
+	start, okStart := constantInts(paddingStart)
+	end, okEnd := constantInts(paddingEnd)
+	interior, okInterior := constantInts(paddingInterior)
+	if okStart && okEnd && okInterior {
+		outputShape, err = shapeinference.Pad(x.shape, fill.shape, start, end, interior)
+		if err != nil {
+			return nil, errors.WithMessagef(err, "%s with constant paddings", op)
+		}
+	}
+	stmt := fn.addOp(op, outputShape, x, fill, paddingStart, paddingEnd, paddingInterior)
+	return stmt.Outputs[0], nil
+}
+
+// constantInts returns the values of v if it is an integer constant, or false if it is not a constant.
+func constantInts(v *Value) ([]int, bool) {
+	producer := v.Producer()
+	if producer == nil || producer.OpType != optypes.Constant || !v.shape.DType.IsInt() {
+		return nil, false
+	}
+	literal, ok := producer.Attributes["value"].(tensorLiteral)
+	if !ok {
+		return nil, false
+	}
+	valueV := reflect.ValueOf(literal.value)
+	if valueV.Kind() != reflect.Slice {
+		valueV = reflect.ValueOf([]any{literal.value})
+	}
+	ints := make([]int, valueV.Len())
+	for i := range ints {
+		element := reflect.ValueOf(valueV.Index(i).Interface())
+		if element.CanInt() {
+			ints[i] = int(element.Int())
+		} else if element.CanUint() {
+			ints[i] = int(element.Uint())
+		} else {
+			return nil, false
+		}
+	}
+	return ints, true
+}
+
 // Convolution performs a convolution supporting strides, padding, dilations, feature grouping, and batch grouping.
 //
 // See description in https://openxla.org/stablehlo/spec#convolution
@@ -983,15 +1453,87 @@ func Pad(x, fill *Value, paddingStart, paddingEnd, paddingInterior []int) (*Valu
 //
 // Note: since the spec mentions that window_reversal will be removed, we didn't include it in the API.
 // If you need it, we can create an alternative API for Convolve with it.
+//
+// See ConvolutionWithAccumulation to control the dtype used for the accumulation.
 func Convolution(input, kernel *Value,
 	strides []int, paddings [][2]int, inputDilations, kernelDilations []int,
 	inputBatchAxis, inputChannelsAxis int, inputSpatialAxes []int,
 	kernelInputChannelsAxis, kernelOutputChannelsAxis int, kernelSpatialAxes []int,
 	outputBatchAxis, outputChannelsAxis int, outputSpatialAxes []int,
 	channelGroupCount, batchGroupCount int,
-	inputPrecision, kernelPrecision types.DotGeneralPrecisionType) (*Value, error) {
+	inputPrecision, kernelPrecision types.DotGeneralPrecisionType) (output *Value, err error) {
+	return convolution(input, kernel,
+		strides, paddings, inputDilations, kernelDilations,
+		inputBatchAxis, inputChannelsAxis, inputSpatialAxes,
+		kernelInputChannelsAxis, kernelOutputChannelsAxis, kernelSpatialAxes,
+		outputBatchAxis, outputChannelsAxis, outputSpatialAxes,
+		channelGroupCount, batchGroupCount,
+		inputPrecision, kernelPrecision, dtypes.InvalidDType)
+}
+
+// ConvolutionWithAccumulation is like Convolution, but the convolution is accumulated (and its result
+// generated) in accumulationDType, and then converted back to the input dtype.
+//
+// This is useful for reduced precision inputs (e.g.: BFloat16 or Float16), where one wants to accumulate the
+// products in a higher precision (e.g.: Float32). It's the equivalent of DotGeneralBuilder.OutputDType for
+// convolutions (sometimes called "preferred element type"), since StableHLO convolution has no algorithm attribute.
+//
+// The accumulationDType must be a float dtype that can represent all the values of the input and kernel dtypes (e.g.
+// Float32 for BFloat16 operands, but not Float16, whose range is smaller; or Float64 for Int32 operands, but not
+// Float32, with a 24 bits significand), otherwise an error is returned. Complex operands are not supported.
+// If it is the same as the input dtype, it is the same as Convolution.
+func ConvolutionWithAccumulation(accumulationDType dtypes.DType, input, kernel *Value,
+	strides []int, paddings [][2]int, inputDilations, kernelDilations []int,
+	inputBatchAxis, inputChannelsAxis int, inputSpatialAxes []int,
+	kernelInputChannelsAxis, kernelOutputChannelsAxis int, kernelSpatialAxes []int,
+	outputBatchAxis, outputChannelsAxis int, outputSpatialAxes []int,
+	channelGroupCount, batchGroupCount int,
+	inputPrecision, kernelPrecision types.DotGeneralPrecisionType) (output *Value, err error) {
+	fn := input.fn
+	defer fn.opErrorHandler(&err, &output)()
+	accumulationFormat, found := floatFormats[accumulationDType]
+	if !found {
+		return nil, errors.Errorf("invalid accumulation dtype %s for %s: it must be a float dtype",
+			accumulationDType, optypes.Convolution)
+	}
+	for _, operand := range []*Value{input, kernel} {
+		if operand.shape.DType.IsComplex() {
+			return nil, errors.Errorf("accumulation dtype %s for %s can't hold the imaginary part of the complex "+
+				"operand dtype %s", accumulationDType, optypes.Convolution, operand.shape.DType)
+		}
+		operandFormat, found := floatFormats[operand.shape.DType]
+		if (found && !accumulationFormat.contains(operandFormat)) ||
+			(!found && !accumulationFormat.containsIntegers(operand.shape.DType)) {
+			return nil, errors.Errorf("accumulation dtype %s for %s can't represent all the values of the operand dtype %s",
+				accumulationDType, optypes.Convolution, operand.shape.DType)
+		}
+	}
+	output, err = convolution(input, kernel,
+		strides, paddings, inputDilations, kernelDilations,
+		inputBatchAxis, inputChannelsAxis, inputSpatialAxes,
+		kernelInputChannelsAxis, kernelOutputChannelsAxis, kernelSpatialAxes,
+		outputBatchAxis, outputChannelsAxis, outputSpatialAxes,
+		channelGroupCount, batchGroupCount,
+		inputPrecision, kernelPrecision, accumulationDType)
+	if err != nil || accumulationDType == input.shape.DType {
+		return output, err
+	}
+	return Convert(output, input.shape.DType)
+}
+
+// convolution implements Convolution and ConvolutionWithAccumulation: if outputDType is not
+// dtypes.InvalidDType, it is used as the dtype of the result of the convolution.
+func convolution(input, kernel *Value,
+	strides []int, paddings [][2]int, inputDilations, kernelDilations []int,
+	inputBatchAxis, inputChannelsAxis int, inputSpatialAxes []int,
+	kernelInputChannelsAxis, kernelOutputChannelsAxis int, kernelSpatialAxes []int,
+	outputBatchAxis, outputChannelsAxis int, outputSpatialAxes []int,
+	channelGroupCount, batchGroupCount int,
+	inputPrecision, kernelPrecision types.DotGeneralPrecisionType,
+	outputDType dtypes.DType) (output *Value, err error) {
 	op := optypes.Convolution
 	fn := input.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -1041,6 +1583,9 @@ func Convolution(input, kernel *Value,
 	if err != nil {
 		return nil, err
 	}
+	if outputDType != dtypes.InvalidDType {
+		outputShape.DType = outputDType
+	}
 
 	// Build convolution statement.
 	stmt := fn.addOp(op, outputShape, input, kernel)
@@ -1109,9 +1654,10 @@ func getConvAxesConfig(
 // Reverse axes of x.
 //
 // E.g.: Reverse([1, 2, 3], axes=0) -> [3, 2, 1]
-func Reverse(x *Value, axes ...int) (*Value, error) {
+func Reverse(x *Value, axes ...int) (output *Value, err error) {
 	op := optypes.Reverse
 	fn := x.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -1143,9 +1689,10 @@ func Reverse(x *Value, axes ...int) (*Value, error) {
 // and types.FFTForwardReal. And (last_dim-1)*2 for FFTInverseReal.
 //
 // The underlying Gopjrt implementation for CPU FFT is backed by Eigen's TensorFFT, and for GPU FFT it uses cuFFT.
-func FFT(x *Value, fftType types.FFTType, fftLength ...int) (*Value, error) {
+func FFT(x *Value, fftType types.FFTType, fftLength ...int) (output *Value, err error) {
 	op := optypes.Fft
 	fn := x.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -1215,15 +1762,23 @@ func ReduceWindow(input, initialValue *Value, reductionFn *Function,
 //
 // If strides is not set, it defaults to the value of windowDimensions -- the stride matches the window size.
 //
+// The paddings (low and high, for each axis) can be negative, in which case they trim the (base dilated) inputs:
+// the output dimension of each axis is
+//
+//	floor((dilatedInputDim + paddingLow + paddingHigh - dilatedWindowDim) / stride) + 1
+//
+// and the padded input must still fit at least one window.
+//
 // TODO: promotion of types doesn't seem to be working according to the spec in
 func MultiReduceWindow(inputs, initialValues []*Value, reductionFn *Function,
 	windowDimensions, strides, inputDilations, windowDilations []int,
-	paddings [][2]int) ([]*Value, error) {
+	paddings [][2]int) (outputs []*Value, err error) {
 	op := optypes.ReduceWindow
 	if len(inputs) == 0 {
 		return nil, errors.New("MultiReduce requires at least one input")
 	}
 	fn := inputs[0].fn
+	defer fn.multiOpErrorHandler(&err, &outputs, len(inputs))()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -1266,7 +1821,7 @@ func MultiReduceWindow(inputs, initialValues []*Value, reductionFn *Function,
 
 	outputsShapes, err := shapeinference.ReduceWindow(
 		valuesToShapes(inputs), valuesToShapes(initialValues),
-		valuesToShapes(reductionFn.Inputs), valuesToShapes(reductionFn.Outputs),
+		valuesToShapes(reductionFn.Inputs), reductionFn.OutputShapes(),
 		windowDimensions, strides, inputDilations, windowDilations,
 		paddings)
 	if err != nil {
@@ -1278,7 +1833,7 @@ func MultiReduceWindow(inputs, initialValues []*Value, reductionFn *Function,
 		"window_dimensions": intSliceToArrayI64StableHLO(windowDimensions),
 		"window_strides":    intSliceToArrayI64StableHLO(strides),
 		"window_dilations":  intSliceToArrayI64StableHLO(windowDilations),
-		"base_dilations":    intSliceToArrayI64StableHLO(windowDilations),
+		"base_dilations":    intSliceToArrayI64StableHLO(inputDilations),
 	}
 	stmt.AddFunctionParameter("reductionFn", reductionFn)
 
@@ -1302,9 +1857,10 @@ func MultiReduceWindow(inputs, initialValues []*Value, reductionFn *Function,
 // The return result has the same shape as the input, and it is populated with the initialValue.
 func SelectAndScatter(input, scatterSource, initialValue *Value,
 	selectFn, scatterFn *Function,
-	windowDimensions, strides []int, paddings [][2]int) (*Value, error) {
+	windowDimensions, strides []int, paddings [][2]int) (output *Value, err error) {
 	op := optypes.SelectAndScatter
 	fn := input.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -1375,9 +1931,10 @@ func SelectAndScatter(input, scatterSource, initialValue *Value,
 // The startIndices are adjusted as follows:
 //
 //	adjustedStartIndices[i] = clamp(0, StartIndices[i], operand.Dimensions[i] - sliceSizes[i])
-func DynamicSlice(operand *Value, startIndices []*Value, sliceSizes []int) (*Value, error) {
+func DynamicSlice(operand *Value, startIndices []*Value, sliceSizes []int) (output *Value, err error) {
 	op := optypes.DynamicSlice
 	fn := operand.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -1391,6 +1948,13 @@ func DynamicSlice(operand *Value, startIndices []*Value, sliceSizes []int) (*Val
 	outputShape := operand.shape.Clone()
 	for axis, size := range sliceSizes {
 		outputShape.Dimensions[axis] = size
+		if outputShape.HasBounds() {
+			// The sliced axes are static.
+			outputShape.Bounds[axis] = shapes.DynamicDim
+		}
+	}
+	if !outputShape.HasBounds() {
+		outputShape.Bounds = nil
 	}
 	stmt := fn.addOp(op, outputShape, append([]*Value{operand}, startIndices...)...)
 	stmt.Attributes = map[string]any{"slice_sizes": intSliceToArrayI64StableHLO(sliceSizes)}
@@ -1409,9 +1973,10 @@ func DynamicSlice(operand *Value, startIndices []*Value, sliceSizes []int) (*Val
 // The startIndices are adjusted as follows:
 //
 //	adjustedStartIndices[i] = clamp(0, StartIndices[i], operand.Dimensions[i] - update.Dimensions[i])
-func DynamicUpdateSlice(operand, update *Value, startIndices []*Value) (*Value, error) {
+func DynamicUpdateSlice(operand, update *Value, startIndices []*Value) (output *Value, err error) {
 	op := optypes.DynamicUpdateSlice
 	fn := operand.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -1431,14 +1996,76 @@ func DynamicUpdateSlice(operand, update *Value, startIndices []*Value) (*Value,
 	return stmt.Outputs[0], nil
 }
 
+// SetDimensionSize sets the runtime size of the given axis of the operand, making it a dynamic axis
+// bounded by the operand's (static or bounded) dimension on that axis.
+//
+// - operand: value whose axis size is set. The axis must be static or have an upper-bound.
+// - size: scalar Int32 value with the new size of the axis, it must be <= the bound.
+// - axis: axis of the operand to set the size. Negative values are counted from the end.
+//
+// The output has the same shape as the operand, except the axis is dynamic (shapes.DynamicDim) with a bound,
+// rendered as, e.g., "tensor<?x3xf32, #stablehlo.bounds<8, ?>>".
+func SetDimensionSize(operand, size *Value, axis int) (output *Value, err error) {
+	op := optypes.SetDimensionSize
+	fn := operand.fn
+	defer fn.opErrorHandler(&err, &output)()
+	if fn.Returned {
+		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
+			op, fn.Name)
+	}
+	if size.fn != fn {
+		return nil, errors.Errorf("cannot add operation %s to function %q, because operand and size are from different functions (%q and %q)",
+			op, fn.Name, fn.Name, size.fn.Name)
+	}
+	adjustedAxis, err := shapeinference.AdjustAxisToRank(axis, operand.shape.Rank())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid axis for %s of operand %s", op, operand.shape)
+	}
+	axis = adjustedAxis
+	outputShape, err := shapeinference.SetDimensionSize(operand.shape, size.shape, axis)
+	if err != nil {
+		return nil, err
+	}
+	stmt := fn.addOp(op, outputShape, operand, size)
+	stmt.Attributes = map[string]any{"dimension": int64(axis)}
+	return stmt.Outputs[0], nil
+}
+
+// GetDimensionSize returns the runtime size of the given axis of the operand, as a scalar Int32.
+//
+// It's mostly useful with dynamic axes (see SetDimensionSize), for static axes it's simply the dimension.
+// Negative axes are counted from the end.
+func GetDimensionSize(operand *Value, axis int) (output *Value, err error) {
+	op := optypes.GetDimensionSize
+	fn := operand.fn
+	defer fn.opErrorHandler(&err, &output)()
+	if fn.Returned {
+		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
+			op, fn.Name)
+	}
+	adjustedAxis, err := shapeinference.AdjustAxisToRank(axis, operand.shape.Rank())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "invalid axis for %s of operand %s", op, operand.shape)
+	}
+	axis = adjustedAxis
+	outputShape, err := shapeinference.GetDimensionSize(operand.shape, axis)
+	if err != nil {
+		return nil, err
+	}
+	stmt := fn.addOp(op, outputShape, operand)
+	stmt.Attributes = map[string]any{"dimension": int64(axis)}
+	return stmt.Outputs[0], nil
+}
+
 // BatchNormInference implements batch normalization for inference. See details in
 // https://www.tensorflow.org/xla/operation_semantics#batchnorminference.
 //
 // Based on the paper "Batch Normalization: Accelerating Deep Network Training by Reducing
 // Internal Covariate Shift" (Sergey Ioffe, Christian Szegedy), https://arxiv.org/abs/1502.03167.
-func BatchNormInference(operand, scale, offset, mean, variance *Value, epsilon float32, featureAxis int) (*Value, error) {
+func BatchNormInference(operand, scale, offset, mean, variance *Value, epsilon float32, featureAxis int) (output *Value, err error) {
 	op := optypes.BatchNormInference
 	fn := operand.fn
+	defer fn.opErrorHandler(&err, &output)()
 	if fn.Returned {
 		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -1477,6 +2104,7 @@ func BatchNormInference(operand, scale, offset, mean, variance *Value, epsilon f
 func BatchNormTraining(operand, scale, offset *Value, epsilon float32, featureAxis int) (normalized *Value, batchMean *Value, batchVariance *Value, err error) {
 	op := optypes.BatchNormTraining
 	fn := operand.fn
+	defer fn.opErrorHandler(&err, &normalized, &batchMean, &batchVariance)()
 	if fn.Returned {
 		return nil, nil, nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
@@ -1522,6 +2150,7 @@ func BatchNormTraining(operand, scale, offset *Value, epsilon float32, featureAx
 func BatchNormGradient(operand, scale, mean, variance, gradOutput *Value, epsilon float32, featureAxis int) (gradOperand *Value, gradScale *Value, gradOffset *Value, err error) {
 	op := optypes.BatchNormGrad
 	fn := operand.fn
+	defer fn.opErrorHandler(&err, &gradOperand, &gradScale, &gradOffset)()
 	if fn.Returned {
 		return nil, nil, nil, errors.Errorf("cannot add operation %s after returning, in function %q",
 			op, fn.Name)
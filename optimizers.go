@@ -0,0 +1,186 @@
+package stablehlo
+
+import (
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// OptimizerRule defines how an optimizer updates one parameter, given its gradient and its optimizer state.
+//
+// See SGD and Adam for the provided rules, and Function.ApplyOptimizer for how to use them.
+type OptimizerRule struct {
+	// Name of the optimizer, used in error messages.
+	Name string
+
+	// NumStates is the number of state values per parameter used by the rule: e.g. 0 for SGD and 2 for
+	// Adam (the first and second moments). The states have the same shape as the parameter.
+	NumStates int
+
+	// Update returns the updated parameter and states (with the same shapes), given the current ones and
+	// the gradient.
+	Update func(param, grad *Value, states []*Value) (newParam *Value, newStates []*Value, err error)
+}
+
+// SGD returns the rule for plain stochastic gradient descent: param = param - learningRate * grad.
+func SGD(learningRate float64) OptimizerRule {
+	return OptimizerRule{
+		Name:      "SGD",
+		NumStates: 0,
+		Update: func(param, grad *Value, _ []*Value) (newParam *Value, newStates []*Value, err error) {
+			lr, err := param.fn.broadcastScalar(learningRate, param.shape)
+			if err != nil {
+				return nil, nil, err
+			}
+			newParam, err = param.fn.Expr(grad).Mul(lr).Apply(func(delta *Value) (*Value, error) {
+				return Subtract(param, delta)
+			}).Value()
+			return newParam, nil, err
+		},
+	}
+}
+
+// Adam returns the rule for the Adam optimizer (https://arxiv.org/abs/1412.6980), with the states
+// being the first (m) and second (v) moments of the gradients.
+//
+// The step is a scalar value (of any numeric dtype) with the current training step, starting at 1,
+// used for the bias correction of the moments.
+func Adam(learningRate, beta1, beta2, epsilon float64, step *Value) OptimizerRule {
+	return OptimizerRule{
+		Name:      "Adam",
+		NumStates: 2,
+		Update: func(param, grad *Value, states []*Value) (newParam *Value, newStates []*Value, err error) {
+			fn := param.fn
+			if step == nil || !step.shape.IsScalar() {
+				return nil, nil, errors.Errorf("Adam: step must be a scalar value, got %v", step)
+			}
+			shape := param.shape
+			constants := make(map[float64]*Value)
+			constant := func(value float64) *Value {
+				if err != nil {
+					return nil
+				}
+				if c, found := constants[value]; found {
+					return c
+				}
+				var c *Value
+				c, err = fn.broadcastScalar(value, shape)
+				constants[value] = c
+				return c
+			}
+			var stepV *Value
+			stepV, err = fn.Expr(step).Convert(shape.DType).Apply(func(s *Value) (*Value, error) {
+				return BroadcastInDim(s, shape, nil)
+			}).Value()
+			if err != nil {
+				return nil, nil, err
+			}
+			m, v := states[0], states[1]
+			beta1V, beta2V := constant(beta1), constant(beta2)
+			oneMinusBeta1, oneMinusBeta2 := constant(1-beta1), constant(1-beta2)
+			one, lr, eps := constant(1), constant(learningRate), constant(epsilon)
+			if err != nil {
+				return nil, nil, err
+			}
+			newM, err := fn.Expr(m).Mul(beta1V).Apply(func(x *Value) (*Value, error) {
+				return fn.Expr(grad).Mul(oneMinusBeta1).Add(x).Value()
+			}).Value()
+			if err != nil {
+				return nil, nil, err
+			}
+			newV, err := fn.Expr(v).Mul(beta2V).Apply(func(x *Value) (*Value, error) {
+				return fn.Expr(grad).Mul(grad).Mul(oneMinusBeta2).Add(x).Value()
+			}).Value()
+			if err != nil {
+				return nil, nil, err
+			}
+			// Bias corrections: 1 - beta^step.
+			correction1, err := fn.Expr(beta1V).Power(stepV).Apply(func(x *Value) (*Value, error) { return Subtract(one, x) }).Value()
+			if err != nil {
+				return nil, nil, err
+			}
+			correction2, err := fn.Expr(beta2V).Power(stepV).Apply(func(x *Value) (*Value, error) { return Subtract(one, x) }).Value()
+			if err != nil {
+				return nil, nil, err
+			}
+			denominator, err := fn.Expr(newV).Div(correction2).Sqrt().Add(eps).Value()
+			if err != nil {
+				return nil, nil, err
+			}
+			newParam, err = fn.Expr(newM).Div(correction1).Mul(lr).Div(denominator).Apply(func(delta *Value) (*Value, error) {
+				return Subtract(param, delta)
+			}).Value()
+			if err != nil {
+				return nil, nil, err
+			}
+			return newParam, []*Value{newM, newV}, nil
+		},
+	}
+}
+
+// ApplyOptimizer emits the update of the parameters given their gradients and the optimizer rule, and
+// marks the parameters and states inputs as aliased (see AliasInputToOutput) to their updated values, so their
+// buffers can be reused (donated) during execution.
+//
+// The params and states must be inputs of fn, and states must have rule.NumStates values per parameter
+// (it can be nil if rule.NumStates == 0).
+//
+// The updated parameters and states must be returned by the function (with Function.Return), in any order.
+func (fn *Function) ApplyOptimizer(rule OptimizerRule, params, grads []*Value, states [][]*Value) (
+	newParams []*Value, newStates [][]*Value, err error) {
+	if len(params) != len(grads) {
+		return nil, nil, errors.Errorf("ApplyOptimizer(%s): got %d params but %d gradients", rule.Name, len(params), len(grads))
+	}
+	if rule.NumStates > 0 && len(states) != len(params) {
+		return nil, nil, errors.Errorf("ApplyOptimizer(%s): got %d params but %d states", rule.Name, len(params), len(states))
+	}
+	newParams = make([]*Value, len(params))
+	newStates = make([][]*Value, len(params))
+	for i, param := range params {
+		grad := grads[i]
+		if param.fn != fn || grad.fn != fn {
+			return nil, nil, errors.Errorf("ApplyOptimizer(%s): params[%d] and grads[%d] must be from function %q",
+				rule.Name, i, i, fn.Name)
+		}
+		if !param.shape.Equal(grad.shape) {
+			return nil, nil, errors.Errorf("ApplyOptimizer(%s): params[%d] and grads[%d] must have the same shape, got %s and %s",
+				rule.Name, i, i, param.shape, grad.shape)
+		}
+		var paramStates []*Value
+		if rule.NumStates > 0 {
+			paramStates = states[i]
+			if len(paramStates) != rule.NumStates {
+				return nil, nil, errors.Errorf("ApplyOptimizer(%s): requires %d states per parameter, got %d for params[%d]",
+					rule.Name, rule.NumStates, len(paramStates), i)
+			}
+		}
+		newParams[i], newStates[i], err = rule.Update(param, grad, paramStates)
+		if err != nil {
+			return nil, nil, errors.WithMessagef(err, "ApplyOptimizer(%s): updating params[%d]", rule.Name, i)
+		}
+		if err = fn.AliasInputToOutput(param, newParams[i]); err != nil {
+			return nil, nil, err
+		}
+		for j, state := range paramStates {
+			if err = fn.AliasInputToOutput(state, newStates[i][j]); err != nil {
+				return nil, nil, err
+			}
+		}
+	}
+	return newParams, newStates, nil
+}
+
+// broadcastScalar creates a constant with the value converted to the shape's dtype, broadcast to the shape.
+func (fn *Function) broadcastScalar(value float64, shape shapes.Shape) (*Value, error) {
+	if shape.DType == dtypes.InvalidDType {
+		return nil, errors.Errorf("invalid shape %s to broadcast constant %g", shape, value)
+	}
+	c, err := fn.ConstantFromScalar(shapes.CastAsDType(value, shape.DType))
+	if err != nil {
+		return nil, err
+	}
+	if shape.IsScalar() {
+		return c, nil
+	}
+	return BroadcastInDim(c, shape, nil)
+}
@@ -0,0 +1,88 @@
+package stablehlo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestApplyOptimizer(t *testing.T) {
+	t.Run("SGD", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		shape := shapes.Make(dtypes.Float32, 2)
+		param := must(fn.NamedInput("param", shape))
+		grad := must(fn.NamedInput("grad", shape))
+		newParams, _, err := fn.ApplyOptimizer(SGD(0.5), []*Value{param}, []*Value{grad}, nil)
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if err := fn.Return(newParams[0]); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestApplyOptimizer_SGD {
+  func.func @main(%param: tensor<2xf32> { tf.aliasing_output = 0 : i32 }, %grad: tensor<2xf32>) -> tensor<2xf32> {
+    %0 = "stablehlo.constant"() { value = dense<0.5> : tensor<f32> } : () -> tensor<f32>
+    %1 = "stablehlo.broadcast_in_dim"(%0) { broadcast_dimensions = array<i64> } : (tensor<f32>) -> tensor<2xf32>
+    %2 = "stablehlo.multiply"(%grad, %1) : (tensor<2xf32>, tensor<2xf32>) -> tensor<2xf32>
+    %3 = "stablehlo.subtract"(%param, %2) : (tensor<2xf32>, tensor<2xf32>) -> tensor<2xf32>
+    "stablehlo.return"(%3) : (tensor<2xf32>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+	})
+
+	t.Run("Adam", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		shape := shapes.Make(dtypes.Float32, 2)
+		step := must(fn.NamedInput("step", shapes.Make(dtypes.Int32)))
+		param := must(fn.NamedInput("param", shape))
+		m := must(fn.NamedInput("m", shape))
+		v := must(fn.NamedInput("v", shape))
+		grad := must(fn.NamedInput("grad", shape))
+		newParams, newStates, err := fn.ApplyOptimizer(Adam(0.001, 0.9, 0.999, 1e-7, step),
+			[]*Value{param}, []*Value{grad}, [][]*Value{{m, v}})
+		if err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		// Return in a different order: aliases are resolved at Return.
+		if err := fn.Return(newStates[0][1], newStates[0][0], newParams[0]); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		program := string(must(builder.Build()))
+		for _, want := range []string{
+			"%param: tensor<2xf32> { tf.aliasing_output = 2 : i32 }",
+			"%m: tensor<2xf32> { tf.aliasing_output = 1 : i32 }",
+			"%v: tensor<2xf32> { tf.aliasing_output = 0 : i32 }",
+			"%grad: tensor<2xf32>)",
+		} {
+			if !strings.Contains(program, want) {
+				t.Errorf("program missing %q:\n%s", want, program)
+			}
+		}
+	})
+
+	t.Run("aliased value not returned", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		shape := shapes.Make(dtypes.Float32, 2)
+		param := must(fn.NamedInput("param", shape))
+		grad := must(fn.NamedInput("grad", shape))
+		if _, _, err := fn.ApplyOptimizer(SGD(0.1), []*Value{param}, []*Value{grad}, nil); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		if err := fn.Return(grad); err == nil {
+			t.Error("expected error when the updated parameter is not returned, got nil")
+		}
+	})
+}
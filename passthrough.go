@@ -0,0 +1,143 @@
+package stablehlo
+
+import (
+	"fmt"
+	"maps"
+	"regexp"
+	"slices"
+
+	"github.com/pkg/errors"
+)
+
+// SetPassthroughAttribute sets a module attribute rendered verbatim in the program, as `name = value`.
+//
+// It's an escape hatch to use experimental XLA attributes without typed support in this package, e.g.
+// `b.SetPassthroughAttribute("mhlo.cross_program_prefetches", "[]")`. The value is only checked to be safe to
+// embed in the program (balanced brackets and quotes, a single line, no comments) -- not for its meaning.
+// An empty value removes the attribute.
+//
+// Attributes set by the Builder itself (e.g. "stablehlo.num_replicas") are reserved.
+func (b *Builder) SetPassthroughAttribute(name, value string) error {
+	if err := validatePassthroughAttribute(name, value, reservedModuleAttributes); err != nil {
+		return errors.WithMessagef(err, "module %q", b.name)
+	}
+	if value == "" {
+		delete(b.passthroughAttributes, name)
+		return nil
+	}
+	if b.passthroughAttributes == nil {
+		b.passthroughAttributes = make(map[string]string)
+	}
+	b.passthroughAttributes[name] = value
+	return nil
+}
+
+// SetPassthroughAttribute sets a function attribute rendered verbatim in the program, in the
+// `attributes {name = value}` of the function. See Builder.SetPassthroughAttribute.
+//
+// Unit attributes, like the "no_inline" of the functions that MLIR pipelines must not inline, take the value
+// "unit". E.g.: `fn.SetPassthroughAttribute("no_inline", "unit")` or
+// `fn.SetPassthroughAttribute("jax.uses_shape_polymorphism", "true")`.
+//
+// Closures are rendered as regions of their operations and can't have attributes. See also Function.SetPrivate.
+func (fn *Function) SetPassthroughAttribute(name, value string) error {
+	if fn.Parent != nil {
+		return errors.Errorf("closure %q can't have attributes", fn.Name)
+	}
+	if err := validatePassthroughAttribute(name, value, reservedFunctionAttributes); err != nil {
+		return errors.WithMessagef(err, "function %q", fn.Name)
+	}
+	if value == "" {
+		delete(fn.passthroughAttributes, name)
+		return nil
+	}
+	if fn.passthroughAttributes == nil {
+		fn.passthroughAttributes = make(map[string]string)
+	}
+	fn.passthroughAttributes[name] = value
+	return nil
+}
+
+var (
+	// reservedModuleAttributes are set by the Builder and can't be passed through.
+	reservedModuleAttributes = []string{"sym_name", "stablehlo.num_replicas", "stablehlo.num_partitions"}
+
+	// reservedFunctionAttributes are set by the Function and can't be passed through.
+	reservedFunctionAttributes = []string{"sym_name", "sym_visibility", "function_type", "arg_attrs", "res_attrs"}
+
+	// passthroughNameRegex matches MLIR bare identifiers, used for attribute names.
+	passthroughNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_$.]*$`)
+)
+
+// validatePassthroughAttribute checks that the attribute name is a valid non-reserved identifier, and that the
+// value is safe to be rendered verbatim in the program.
+func validatePassthroughAttribute(name, value string, reserved []string) error {
+	if !passthroughNameRegex.MatchString(name) {
+		return errors.Errorf("invalid attribute name %q", name)
+	}
+	if slices.Contains(reserved, name) {
+		return errors.Errorf("attribute %q is reserved", name)
+	}
+	if err := checkMLIRTokens(value); err != nil {
+		return errors.WithMessagef(err, "attribute %q value %q", name, value)
+	}
+	return nil
+}
+
+// checkMLIRTokens checks that the raw value can be embedded in the program without breaking its structure:
+// it must be a single line without comments, its string literals must be terminated and its brackets balanced.
+func checkMLIRTokens(value string) error {
+	closing := map[byte]byte{'(': ')', '[': ']', '{': '}', '<': '>'}
+	var stack []byte
+	inString := false
+	for i := 0; i < len(value); i++ {
+		c := value[i]
+		if c == '\n' || c == '\r' {
+			return errors.New("newlines are not allowed")
+		}
+		if inString {
+			if c == '\\' {
+				i++
+			} else if c == '"' {
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '/':
+			if i+1 < len(value) && value[i+1] == '/' {
+				return errors.New("comments are not allowed")
+			}
+		case '-':
+			if i+1 < len(value) && value[i+1] == '>' {
+				// Arrow of function types, e.g. "(i32) -> i32".
+				i++
+			}
+		case '(', '[', '{', '<':
+			stack = append(stack, closing[c])
+		case ')', ']', '}', '>':
+			if len(stack) == 0 || stack[len(stack)-1] != c {
+				return errors.Errorf("unbalanced %q at position %d", c, i)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if inString {
+		return errors.New("unterminated string literal")
+	}
+	if len(stack) > 0 {
+		return errors.Errorf("missing closing %q", stack[len(stack)-1])
+	}
+	return nil
+}
+
+// passthroughAttributesList renders the passthrough attributes as `name = value`, sorted by name.
+func passthroughAttributesList(attributes map[string]string) []string {
+	list := make([]string, 0, len(attributes))
+	for _, name := range slices.Sorted(maps.Keys(attributes)) {
+		list = append(list, fmt.Sprintf("%s = %s", name, attributes[name]))
+	}
+	return list
+}
@@ -0,0 +1,86 @@
+package stablehlo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestPassthroughAttributes(t *testing.T) {
+	newProgram := func(t *testing.T, generic bool) *Builder {
+		builder := New(t.Name()).WithNumReplicas(1).WithGenericForm(generic)
+		must0(builder.SetPassthroughAttribute("mhlo.cross_program_prefetches", "[]"))
+		must0(builder.SetPassthroughAttribute("mhlo.frontend_attributes", `{xla.sdy.meshes = "{}"}`))
+		must0(builder.SetPassthroughAttribute("removed", "1 : i32"))
+		must0(builder.SetPassthroughAttribute("removed", ""))
+		fn := builder.Main()
+		must0(fn.SetPassthroughAttribute("execution_thread", `"main"`))
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		must0(fn.Return(must(Negate(x))))
+		return builder
+	}
+
+	t.Run("pretty", func(t *testing.T) {
+		program := string(must(newProgram(t, false).Build()))
+		want := `module @TestPassthroughAttributes_pretty attributes {stablehlo.num_replicas = 1, mhlo.cross_program_prefetches = [], mhlo.frontend_attributes = {xla.sdy.meshes = "{}"}} {
+  func.func @main(%x: tensor<3xf32>) -> tensor<3xf32> attributes {execution_thread = "main"} {
+    %0 = "stablehlo.negate"(%x) : (tensor<3xf32>) -> tensor<3xf32>
+    "stablehlo.return"(%0) : (tensor<3xf32>) -> ()
+  }
+}
+`
+		if program != want {
+			t.Errorf("expected program:\n%s\ngot:\n%s", want, program)
+		}
+	})
+
+	t.Run("generic", func(t *testing.T) {
+		program := string(must(newProgram(t, true).Build()))
+		want := `"builtin.module"() <{sym_name = "TestPassthroughAttributes_generic"}> ({
+  "func.func"() <{function_type = (tensor<3xf32>) -> tensor<3xf32>, sym_name = "main"}> ({
+  ^bb0(%x: tensor<3xf32>):
+    %0 = "stablehlo.negate"(%x) : (tensor<3xf32>) -> tensor<3xf32>
+    "stablehlo.return"(%0) : (tensor<3xf32>) -> ()
+  }) {execution_thread = "main"} : () -> ()
+}) {stablehlo.num_replicas = 1, mhlo.cross_program_prefetches = [], mhlo.frontend_attributes = {xla.sdy.meshes = "{}"}} : () -> ()
+`
+		if program != want {
+			t.Errorf("expected program:\n%s\ngot:\n%s", want, program)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		for _, tc := range []struct {
+			name, value, want string
+		}{
+			{"bad name", "1", "invalid attribute name"},
+			{"stablehlo.num_replicas", "2", "reserved"},
+			{"a", "[1, 2", "missing closing"},
+			{"a", "[1, 2}", "unbalanced"},
+			{"a", `"abc`, "unterminated string"},
+			{"a", "1 // comment", "comments are not allowed"},
+			{"a", "1\n}", "newlines are not allowed"},
+		} {
+			err := builder.SetPassthroughAttribute(tc.name, tc.value)
+			if err == nil || !strings.Contains(err.Error(), tc.want) {
+				t.Errorf("SetPassthroughAttribute(%q, %q): expected error containing %q, got %v", tc.name, tc.value, tc.want, err)
+			}
+		}
+		// Valid tricky values.
+		for _, value := range []string{`"a > b // c \" ]"`, "(tensor<2xf32>) -> tensor<2xf32>", "#foo.bar<[1, 2]>"} {
+			if err := fn.SetPassthroughAttribute("a", value); err != nil {
+				t.Errorf("SetPassthroughAttribute(%q): unexpected error %v", value, err)
+			}
+		}
+		if err := fn.SetPassthroughAttribute("function_type", "() -> ()"); err == nil {
+			t.Error("expected error for reserved function attribute")
+		}
+		if err := fn.Closure().SetPassthroughAttribute("a", "1"); err == nil {
+			t.Error("expected error setting an attribute in a closure")
+		}
+	})
+}
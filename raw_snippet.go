@@ -0,0 +1,163 @@
+package stablehlo
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// rawSnippetPlaceholder is an operand placeholder ($0, $1, ...) found at snippet[start:end] of a raw snippet.
+// The operand is -1 if the index can't be parsed (e.g. it overflows).
+type rawSnippetPlaceholder struct {
+	start, end, operand int
+}
+
+// findRawSnippetPlaceholders returns the operands placeholders of a raw snippet, skipping the ones inside
+// quoted strings (e.g. an attribute `name = "$0"`).
+func findRawSnippetPlaceholders(snippet string) []rawSnippetPlaceholder {
+	var placeholders []rawSnippetPlaceholder
+	inString, escaped := false, false
+	for i := 0; i < len(snippet); i++ {
+		c := snippet[i]
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		if c == '"' {
+			inString = true
+			continue
+		}
+		if c != '$' {
+			continue
+		}
+		end := i + 1
+		for end < len(snippet) && snippet[end] >= '0' && snippet[end] <= '9' {
+			end++
+		}
+		if end == i+1 {
+			continue
+		}
+		operand, err := strconv.Atoi(snippet[i+1 : end])
+		if err != nil {
+			operand = -1
+		}
+		placeholders = append(placeholders, rawSnippetPlaceholder{start: i, end: end, operand: operand})
+		i = end - 1
+	}
+	return placeholders
+}
+
+// RawStatement inserts a raw StableHLO text snippet as a statement of the function, with the given operands and
+// declared output shapes. It's an escape hatch for operations not yet supported by the package.
+//
+// The snippet is the operation itself (name, operands, regions and attributes) in the generic MLIR format, without
+// the results and without the type signature: both are generated from the operands and outputShapes.
+// Operands are referred to in the snippet as $0, $1, etc. -- placeholders inside quoted strings are left as is.
+//
+// Example:
+//
+//	outputs, err := fn.RawStatement(`"stablehlo.cholesky"($0) { lower = true }`, []*Value{x}, x.Shape())
+//
+// It is rendered as:
+//
+//	%1 = "stablehlo.cholesky"(%x) { lower = true } : (tensor<4x4xf32>) -> tensor<4x4xf32>
+//
+// The validation is minimal: the placeholders must refer to valid operands, and quotes, parenthesis, brackets and
+// braces must be balanced. No shape inference is done, the outputShapes are taken as given.
+func (fn *Function) RawStatement(snippet string, operands []*Value, outputShapes ...shapes.Shape) (outputs []*Value, err error) {
+	op := optypes.RawSnippet
+	defer fn.multiOpErrorHandler(&err, &outputs, len(outputShapes))()
+	if fn.Returned {
+		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
+			op, fn.Name)
+	}
+	for i, operand := range operands {
+		if operand.fn != fn {
+			return nil, errors.Errorf("cannot add operation %s to function %q, because operands[%d] is from different function (%q and %q)",
+				op, fn.Name, i, operand.fn.Name, fn.Name)
+		}
+	}
+	for i, shape := range outputShapes {
+		if !shape.Ok() {
+			return nil, errors.Errorf("RawStatement: invalid output shape #%d: %s", i, shape)
+		}
+	}
+	if err = validateRawSnippet(snippet, len(operands)); err != nil {
+		return nil, err
+	}
+	stmt := fn.addMultiOp(op, outputShapes, operands)
+	stmt.rawSnippet = strings.TrimSpace(snippet)
+	return stmt.Outputs, nil
+}
+
+// validateRawSnippet does a minimal validation of a raw snippet: non-empty, valid operands placeholders and
+// balanced quotes and delimiters.
+func validateRawSnippet(snippet string, numOperands int) error {
+	if strings.TrimSpace(snippet) == "" {
+		return errors.New("RawStatement: empty snippet")
+	}
+	for _, placeholder := range findRawSnippetPlaceholders(snippet) {
+		if placeholder.operand < 0 || placeholder.operand >= numOperands {
+			return errors.Errorf("RawStatement: snippet refers to operand %s, but only %d operands were given",
+				snippet[placeholder.start:placeholder.end], numOperands)
+		}
+	}
+	closing := map[rune]rune{')': '(', ']': '[', '}': '{'}
+	var stack []rune
+	inString, escaped := false, false
+	for _, c := range snippet {
+		if inString {
+			switch {
+			case escaped:
+				escaped = false
+			case c == '\\':
+				escaped = true
+			case c == '"':
+				inString = false
+			}
+			continue
+		}
+		switch c {
+		case '"':
+			inString = true
+		case '(', '[', '{':
+			stack = append(stack, c)
+		case ')', ']', '}':
+			if len(stack) == 0 || stack[len(stack)-1] != closing[c] {
+				return errors.Errorf("RawStatement: unbalanced %q in snippet %q", c, snippet)
+			}
+			stack = stack[:len(stack)-1]
+		}
+	}
+	if inString {
+		return errors.Errorf("RawStatement: unterminated string in snippet %q", snippet)
+	}
+	if len(stack) > 0 {
+		return errors.Errorf("RawStatement: unclosed %q in snippet %q", stack[len(stack)-1], snippet)
+	}
+	return nil
+}
+
+// renderRawSnippet replaces the operands placeholders in the snippet with the operands names, and indents
+// any new lines with the given indentation.
+func renderRawSnippet(snippet string, operands []*Value, indentation string) string {
+	var rendered strings.Builder
+	var last int
+	for _, placeholder := range findRawSnippetPlaceholders(snippet) {
+		rendered.WriteString(snippet[last:placeholder.start])
+		rendered.WriteString(operands[placeholder.operand].String())
+		last = placeholder.end
+	}
+	rendered.WriteString(snippet[last:])
+	return strings.ReplaceAll(rendered.String(), "\n", "\n"+indentation)
+}
@@ -0,0 +1,70 @@
+package stablehlo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestRawStatement(t *testing.T) {
+	t.Run("cholesky", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 4, 4)))
+		outputs := must(fn.RawStatement(`"stablehlo.cholesky"($0) { lower = true }`, []*Value{x}, x.Shape()))
+		if err := fn.Return(outputs[0]); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestRawStatement_cholesky {
+  func.func @main(%x: tensor<4x4xf32>) -> tensor<4x4xf32> {
+    %0 = "stablehlo.cholesky"(%x) { lower = true } : (tensor<4x4xf32>) -> tensor<4x4xf32>
+    "stablehlo.return"(%0) : (tensor<4x4xf32>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+	})
+
+	t.Run("placeholders in strings", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 4, 4)))
+		snippet := `"stablehlo.custom_call"($0) { call_target_name = "foo", weird = "$0 \"$1\"" }`
+		outputs := must(fn.RawStatement(snippet, []*Value{x}, x.Shape()))
+		if err := fn.Return(outputs[0]); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `%0 = "stablehlo.custom_call"(%x) { call_target_name = "foo", weird = "$0 \"$1\"" } : (tensor<4x4xf32>) -> tensor<4x4xf32>`
+		if !strings.Contains(program, want) {
+			t.Errorf("expected program to contain %q", want)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 4, 4)))
+		for _, snippet := range []string{
+			"",
+			`"stablehlo.cholesky"($1)`,
+			`"stablehlo.cholesky"($99999999999999999999)`,
+			`"stablehlo.cholesky"($0`,
+			`"stablehlo.cholesky($0)`,
+			`"stablehlo.cholesky"($0) { lower = true ]`,
+		} {
+			if _, err := fn.RawStatement(snippet, []*Value{x}, x.Shape()); err == nil {
+				t.Errorf("expected error for snippet %q, got nil", snippet)
+			}
+		}
+	})
+}
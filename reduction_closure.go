@@ -0,0 +1,116 @@
+package stablehlo
+
+import (
+	"fmt"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// ReductionType selects the operation of the standard reduction closures, see Function.NewReductionClosure.
+type ReductionType int
+
+const (
+	// ReductionAdd sums the values.
+	ReductionAdd ReductionType = iota
+
+	// ReductionMultiply multiplies the values.
+	ReductionMultiply
+
+	// ReductionMaximum takes the maximum of the values.
+	ReductionMaximum
+
+	// ReductionMinimum takes the minimum of the values.
+	ReductionMinimum
+
+	// ReductionAnd takes the logical (for booleans) or bitwise (for integers) "and" of the values.
+	ReductionAnd
+
+	// ReductionOr takes the logical (for booleans) or bitwise (for integers) "or" of the values.
+	ReductionOr
+)
+
+// reductionOpTypes maps the reduction types to the operations of their closures.
+var reductionOpTypes = []optypes.OpType{
+	ReductionAdd:      optypes.Add,
+	ReductionMultiply: optypes.Multiply,
+	ReductionMaximum:  optypes.Maximum,
+	ReductionMinimum:  optypes.Minimum,
+	ReductionAnd:      optypes.And,
+	ReductionOr:       optypes.Or,
+}
+
+// String implements fmt.Stringer.
+func (r ReductionType) String() string {
+	if r < 0 || int(r) >= len(reductionOpTypes) {
+		return "InvalidReductionType"
+	}
+	return "Reduction" + reductionOpTypes[r].String()
+}
+
+// NewReductionClosure returns the standard scalar reduction closure of fn for the given reduction type and dtypes,
+// to be used with Reduce, MultiReduce, ReduceWindow, etc.
+//
+// For N dtypes, the closure takes (lhs_1, ..., lhs_N, rhs_1, ..., rhs_N) scalars and returns
+// (op(lhs_1, rhs_1), ..., op(lhs_N, rhs_N)), the signature expected by MultiReduce.
+//
+// The closures are cached: asking again for the same reduction type and dtypes returns the same closure, since a
+// closure can be used multiple times within its parent function.
+func (fn *Function) NewReductionClosure(reduction ReductionType, reductionDTypes ...dtypes.DType) (*Function, error) {
+	if reduction < 0 || int(reduction) >= len(reductionOpTypes) {
+		return nil, errors.Errorf("NewReductionClosure: invalid reduction type %d", int(reduction))
+	}
+	op := reductionOpTypes[reduction]
+	if len(reductionDTypes) == 0 {
+		return nil, errors.Errorf("NewReductionClosure(%s) requires at least one dtype", reduction)
+	}
+	key := fmt.Sprintf("%s%v", op, reductionDTypes)
+	if closure, found := fn.reductionClosures[key]; found {
+		return closure, nil
+	}
+
+	closure := fn.Closure()
+	lhs := make([]*Value, len(reductionDTypes))
+	rhs := make([]*Value, len(reductionDTypes))
+	for _, side := range []struct {
+		prefix string
+		values []*Value
+	}{{"lhs", lhs}, {"rhs", rhs}} {
+		for i, dtype := range reductionDTypes {
+			name := side.prefix
+			if len(reductionDTypes) > 1 {
+				name = fmt.Sprintf("%s%d", side.prefix, i)
+			}
+			input, err := closure.NamedInput(name, shapes.Make(dtype))
+			if err != nil {
+				return nil, err
+			}
+			side.values[i] = input
+		}
+	}
+	outputShapes := make([]shapes.Shape, len(reductionDTypes))
+	for i, dtype := range reductionDTypes {
+		outputShapes[i] = shapes.Make(dtype)
+	}
+	if err := closure.DeclareOutputs(outputShapes...); err != nil {
+		return nil, err
+	}
+	outputs := make([]*Value, len(reductionDTypes))
+	for i := range reductionDTypes {
+		output, err := closure.binaryOp(op, lhs[i], rhs[i])
+		if err != nil {
+			return nil, errors.WithMessagef(err, "NewReductionClosure(%s) for dtype #%d (%s)", reduction, i, reductionDTypes[i])
+		}
+		outputs[i] = output
+	}
+	if err := closure.Return(outputs...); err != nil {
+		return nil, err
+	}
+	if fn.reductionClosures == nil {
+		fn.reductionClosures = make(map[string]*Function)
+	}
+	fn.reductionClosures[key] = closure
+	return closure, nil
+}
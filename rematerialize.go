@@ -0,0 +1,177 @@
+package stablehlo
+
+import (
+	"cmp"
+	"math"
+	"slices"
+
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/shapeinference"
+	"github.com/pkg/errors"
+)
+
+// rematerializableOps are the operations cheap enough to be recomputed by Function.Rematerialize,
+// besides the standard unary and binary (elementwise) operations.
+var rematerializableOps = []optypes.OpType{optypes.Constant, optypes.Iota, optypes.BroadcastInDim,
+	optypes.Reshape, optypes.Convert, optypes.Compare, optypes.Select}
+
+// isRematerializable returns whether the statement is a cheap operation that Function.Rematerialize can recompute.
+func isRematerializable(stmt *Statement) bool {
+	return len(stmt.Outputs) == 1 && len(stmt.FunctionParameters) == 0 &&
+		(slices.Contains(rematerializableOps, stmt.OpType) ||
+			shapeinference.StandardUnaryOperations.Has(stmt.OpType) ||
+			shapeinference.StandardBinaryOperations.Has(stmt.OpType))
+}
+
+// Rematerialize lowers the peak memory of the function by recomputing cheap values close to their later uses,
+// instead of keeping them alive since they were first computed -- e.g. a large Iota or a broadcast constant used at
+// the beginning and the end of a program.
+//
+// A value is rematerialized if it is computed only from cheap operations (constants, Iota, broadcasts, reshapes,
+// conversions and elementwise operations), with no inputs of the function, and the number of statements needed to
+// compute it (its cost) is at most maxCost. Scalar values are not rematerialized, nor values whose memory is smaller
+// than the memory of the other values computed by their copies (the copy would allocate more than it frees).
+// Each use that comes after statements unrelated to the value gets its own copy of the statements computing it,
+// inserted right before the use.
+//
+// It returns the number of copies inserted. Only the statements of fn are considered, not those of its closures.
+//
+// See package analysis for the Liveness analysis to measure the peak memory of a function.
+func (fn *Function) Rematerialize(maxCost int) (numCopies int, err error) {
+	if err := fn.Err(); err != nil {
+		return 0, err
+	}
+	if maxCost < 1 {
+		return 0, errors.Errorf("Rematerialize requires maxCost >= 1, got %d", maxCost)
+	}
+
+	// The copies are only inserted in fn.Statements at the end, so the positions and producers can be
+	// computed once. Copies are placed right before a statement of fn: their position is the index of that statement,
+	// and they are ordered among themselves by the order of creation.
+	positions := make(map[*Statement]rematerializationPosition, len(fn.Statements))
+	producers := make(map[*Value]*Statement, len(fn.Statements))
+	for idx, stmt := range fn.Statements {
+		positions[stmt] = rematerializationPosition{index: idx, order: math.MaxInt}
+		for _, output := range stmt.Outputs {
+			producers[output] = stmt
+		}
+	}
+	insertBefore := make(map[*Statement][]*Statement)
+	var numCreated int
+
+	for _, stmt := range fn.Statements {
+		if !isRematerializable(stmt) || stmt.Outputs[0].shape.IsScalar() || stmt.Outputs[0].NumUses() < 2 {
+			continue
+		}
+		chain := rematerializationChain(stmt, producers, positions, maxCost)
+		if chain == nil {
+			continue
+		}
+		value := stmt.Outputs[0]
+		var copyMemory uintptr
+		for _, chainStmt := range chain[:len(chain)-1] {
+			copyMemory += chainStmt.Outputs[0].shape.Memory()
+		}
+		if copyMemory > value.shape.Memory() {
+			continue
+		}
+		uses := value.Uses()
+		slices.SortFunc(uses, func(a, b *Statement) int {
+			return positions[a].index - positions[b].index
+		})
+		current := value
+		kept := uses[:1] // Uses of value that are not moved to a copy, reusing the storage of uses.
+		lastUseIdx := positions[uses[0]].index
+		for _, use := range uses[1:] {
+			useIdx := positions[use].index
+			if useIdx-lastUseIdx > 1 || len(insertBefore[use]) > 0 {
+				// There are unrelated statements between the uses: recompute the value right before this use.
+				mapping := make(map[*Value]*Value)
+				for _, chainStmt := range chain {
+					clone := fn.cloneStatement(chainStmt, mapping)
+					numCreated++
+					positions[clone] = rematerializationPosition{index: useIdx, order: numCreated}
+					producers[clone.Outputs[0]] = clone
+					insertBefore[use] = append(insertBefore[use], clone)
+				}
+				current = mapping[value]
+				numCopies++
+			}
+			if current == value {
+				kept = append(kept, use)
+			} else {
+				for i, input := range use.Inputs {
+					if input == value {
+						use.Inputs[i] = current
+						if use.OpType == optypes.FuncReturn && i < len(fn.Outputs) {
+							fn.Outputs[i].name = current.name
+						}
+					}
+				}
+				current.uses = append(current.uses, use)
+			}
+			lastUseIdx = useIdx
+		}
+		value.uses = kept
+	}
+	if numCopies == 0 {
+		return 0, nil
+	}
+
+	statements := make([]*Statement, 0, len(fn.Statements)+numCreated)
+	for _, stmt := range fn.Statements {
+		statements = append(statements, insertBefore[stmt]...)
+		statements = append(statements, stmt)
+	}
+	fn.Statements = statements
+	return numCopies, nil
+}
+
+// rematerializationPosition is the position of a statement in the function after Function.Rematerialize inserts
+// its copies: index is the index in the original statements, and order sorts the copies inserted before the same
+// statement (math.MaxInt for the original statement itself).
+type rematerializationPosition struct {
+	index, order int
+}
+
+// compare returns the order of the positions p and other, as in cmp.Compare.
+func (p rematerializationPosition) compare(other rematerializationPosition) int {
+	return cmp.Or(cmp.Compare(p.index, other.index), cmp.Compare(p.order, other.order))
+}
+
+// rematerializationChain returns the statements (in their order in the function) needed to recompute the output of
+// stmt, or nil if they are not all rematerializable, they depend on inputs of the function, or there are more than
+// maxCost of them.
+func rematerializationChain(stmt *Statement, producers map[*Value]*Statement,
+	positions map[*Statement]rematerializationPosition, maxCost int) []*Statement {
+	var chain []*Statement
+	needed := make(map[*Statement]bool)
+	toVisit := []*Statement{stmt}
+	for len(toVisit) > 0 {
+		current := toVisit[len(toVisit)-1]
+		toVisit = toVisit[:len(toVisit)-1]
+		if needed[current] {
+			continue
+		}
+		if !isRematerializable(current) {
+			return nil
+		}
+		needed[current] = true
+		chain = append(chain, current)
+		if len(chain) > maxCost {
+			return nil
+		}
+		for _, input := range current.Inputs {
+			producer, found := producers[input]
+			if !found {
+				// Input of the function.
+				return nil
+			}
+			toVisit = append(toVisit, producer)
+		}
+	}
+	slices.SortFunc(chain, func(a, b *Statement) int {
+		return positions[a].compare(positions[b])
+	})
+	return chain
+}
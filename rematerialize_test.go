@@ -0,0 +1,105 @@
+package stablehlo
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestRematerialize(t *testing.T) {
+	builder := New(t.Name())
+	fn := builder.Main()
+	shape := shapes.Make(dtypes.Float32, 8)
+	x := must(fn.NamedInput("x", shape))
+	positions := must(Convert(must(fn.Iota(shapes.Make(dtypes.Int32, 8), 0)), dtypes.Float32))
+	y := must(Add(x, positions))
+	y = must(Tanh(must(Multiply(y, y))))
+	z := must(Subtract(y, positions))
+	must0(fn.Return(z))
+
+	if _, err := fn.Rematerialize(0); err == nil {
+		t.Error("expected error for maxCost < 1")
+	}
+	if numCopies := must(fn.Rematerialize(1)); numCopies != 0 {
+		t.Errorf("positions costs 2 statements, it should not be rematerialized with maxCost=1, got %d copies", numCopies)
+	}
+	if numCopies := must(fn.Rematerialize(2)); numCopies != 1 {
+		t.Errorf("expected 1 copy of positions, got %d", numCopies)
+	}
+	program := string(must(builder.Build()))
+	fmt.Printf("%s program:\n%s", t.Name(), program)
+	want := `module @TestRematerialize {
+  func.func @main(%x: tensor<8xf32>) -> tensor<8xf32> {
+    %0 = "stablehlo.iota"() { iota_dimension = 0 : i64 } : () -> tensor<8xi32>
+    %1 = "stablehlo.convert"(%0) : (tensor<8xi32>) -> tensor<8xf32>
+    %2 = "stablehlo.add"(%x, %1) : (tensor<8xf32>, tensor<8xf32>) -> tensor<8xf32>
+    %3 = "stablehlo.multiply"(%2, %2) : (tensor<8xf32>, tensor<8xf32>) -> tensor<8xf32>
+    %4 = "stablehlo.tanh"(%3) : (tensor<8xf32>) -> tensor<8xf32>
+    %6 = "stablehlo.iota"() { iota_dimension = 0 : i64 } : () -> tensor<8xi32>
+    %7 = "stablehlo.convert"(%6) : (tensor<8xi32>) -> tensor<8xf32>
+    %5 = "stablehlo.subtract"(%4, %7) : (tensor<8xf32>, tensor<8xf32>) -> tensor<8xf32>
+    "stablehlo.return"(%5) : (tensor<8xf32>) -> ()
+  }
+}
+`
+	if program != want {
+		fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+		t.Fatal("programs don't match")
+	}
+	requireConsistentUses(t, fn)
+}
+
+func TestRematerializeMemoryGate(t *testing.T) {
+	builder := New(t.Name())
+	fn := builder.Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float16, 8)))
+	// The copy of positions would compute a f32 iota (32 bytes) to free a f16 value (16 bytes).
+	positions := must(Convert(must(fn.Iota(shapes.Make(dtypes.Float32, 8), 0)), dtypes.Float16))
+	y := must(Add(x, positions))
+	y = must(Tanh(must(Multiply(y, y))))
+	must0(fn.Return(must(Subtract(y, positions))))
+	if numCopies := must(fn.Rematerialize(2)); numCopies != 0 {
+		t.Errorf("positions copies would allocate more than they free, expected no copies, got %d", numCopies)
+	}
+}
+
+// BenchmarkRematerializeScaling checks that Rematerialize scales linearly with the number of statements: the time per
+// statement of a 10x larger function, with a rematerializable value used by every other statement, must not grow by
+// more than a small factor.
+func BenchmarkRematerializeScaling(b *testing.B) {
+	build := func(numOps int) *Function {
+		fn := New("benchmark").Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 16)))
+		positions := must(fn.Iota(shapes.Make(dtypes.Float32, 16), 0))
+		y := x
+		for i := range numOps {
+			if i%2 == 0 {
+				y = must(Add(y, positions))
+			} else {
+				y = must(Tanh(y))
+			}
+		}
+		must0(fn.Return(y))
+		return fn
+	}
+	const smallNumOps, largeNumOps = 2_000, 20_000
+	var small, large time.Duration
+	for range b.N {
+		smallFn, largeFn := build(smallNumOps), build(largeNumOps)
+		start := time.Now()
+		_ = must(smallFn.Rematerialize(1))
+		small += time.Since(start)
+		start = time.Now()
+		_ = must(largeFn.Rematerialize(1))
+		large += time.Since(start)
+	}
+	ratio := (float64(large) / largeNumOps) / (float64(small) / smallNumOps)
+	b.ReportMetric(ratio, "scaling")
+	if ratio > 3 {
+		b.Fatalf("Rematerialize is super-linear: the time per statement grew %.1fx from %d to %d statements",
+			ratio, smallNumOps, largeNumOps)
+	}
+}
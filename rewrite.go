@@ -0,0 +1,227 @@
+package stablehlo
+
+import (
+	"slices"
+
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/pkg/errors"
+)
+
+// RewriteRule matches statements of a function and replaces their outputs with a new subgraph.
+// See Function.Rewrite.
+//
+// Example: fuse a multiplication followed by an addition into a custom call.
+//
+//	rule := &RewriteRule{
+//		Name: "fused_multiply_add",
+//		Op:   "stablehlo.add",
+//		Predicate: func(add *Statement) bool {
+//			mul := add.Inputs[0].Producer()
+//			return mul != nil && mul.OpName() == "stablehlo.multiply"
+//		},
+//		Replace: func(fn *Function, add *Statement) ([]*Value, error) {
+//			mul := add.Inputs[0].Producer()
+//			return fn.RawStatement(`"stablehlo.custom_call"($0, $1, $2) { call_target_name = "fma" }`,
+//				[]*Value{mul.Inputs[0], mul.Inputs[1], add.Inputs[1]}, add.Outputs[0].Shape())
+//		},
+//	}
+type RewriteRule struct {
+	// Name of the rule, used in error messages.
+	Name string
+
+	// Op is the StableHLO name of the operations matched, e.g. "stablehlo.add" (see Statement.OpName).
+	Op string
+
+	// Predicate is an optional further condition on the matched statement: on its attributes, operands, etc.
+	Predicate func(match *Statement) bool
+
+	// Replace builds the replacement of the outputs of the matched statement, creating new operations in fn.
+	// It must return one value per output of the matched statement, with the same shapes.
+	//
+	// It may only use values defined before the matched statement (its inputs, for instance), or values it creates,
+	// and it can't return the outputs of the matched statement, which is removed: Rewrite returns an error otherwise.
+	Replace func(fn *Function, match *Statement) ([]*Value, error)
+}
+
+// OpName returns the StableHLO name of the statement's operation, e.g. "stablehlo.add".
+func (s *Statement) OpName() string {
+	return s.OpType.ToStableHLO()
+}
+
+// Producer returns the statement that outputs the value, or nil if it is an input of its function.
+func (v *Value) Producer() *Statement {
+	for _, stmt := range v.fn.Statements {
+		if slices.Contains(stmt.Outputs, v) {
+			return stmt
+		}
+	}
+	return nil
+}
+
+// Rewrite applies the rules to the statements of the function, in order, and returns the number of statements rewritten.
+//
+// For each statement, the first rule that matches (the op name and the optional predicate) is applied:
+// its replacement statements are inserted in place of the matched statement, and the uses of the matched outputs are
+// replaced by the values returned by the rule. Statements created by a rule are not matched again.
+//
+// Statements that become unused after rewriting (e.g. the producers of the matched statement's operands) are removed,
+// except for raw snippets, calls, custom calls and collective operations, that may have side effects.
+//
+// Only the statements of fn are rewritten, not those of its closures. It can be used after the function is returned.
+func (fn *Function) Rewrite(rules ...*RewriteRule) (numRewrites int, err error) {
+	if err := fn.Err(); err != nil {
+		return 0, err
+	}
+	for i, rule := range rules {
+		if rule.Op == "" || rule.Replace == nil {
+			return 0, errors.Errorf("Rewrite: rule #%d (%q) must define Op and Replace", i, rule.Name)
+		}
+	}
+	usedBefore := fn.usedValues()
+	for idx := 0; idx < len(fn.Statements); idx++ {
+		stmt := fn.Statements[idx]
+		if stmt.OpType == optypes.FuncReturn {
+			continue
+		}
+		rule := findRewriteRule(rules, stmt)
+		if rule == nil {
+			continue
+		}
+		numNew, err := fn.applyRewriteRule(rule, idx)
+		if err != nil {
+			return numRewrites, err
+		}
+		numRewrites++
+		// Skip the new statements, and account for the removed matched statement.
+		idx += numNew - 1
+	}
+	if numRewrites > 0 {
+		fn.removeDeadStatements(usedBefore)
+	}
+	return numRewrites, nil
+}
+
+// findRewriteRule returns the first rule matching the statement, or nil.
+func findRewriteRule(rules []*RewriteRule, stmt *Statement) *RewriteRule {
+	opName := stmt.OpName()
+	for _, rule := range rules {
+		if rule.Op == opName && (rule.Predicate == nil || rule.Predicate(stmt)) {
+			return rule
+		}
+	}
+	return nil
+}
+
+// applyRewriteRule replaces the statement at stmtIdx by the statements created by the rule.
+// It returns the number of statements inserted.
+func (fn *Function) applyRewriteRule(rule *RewriteRule, stmtIdx int) (numNew int, err error) {
+	match := fn.Statements[stmtIdx]
+	numStatements := len(fn.Statements)
+	defer func() {
+		if err != nil {
+			// Drop the statements created by the rule.
+			fn.truncateStatements(numStatements)
+		}
+	}()
+	returned := fn.Returned
+	fn.Returned = false
+	values, err := rule.Replace(fn, match)
+	fn.Returned = returned
+	if err == nil {
+		err = fn.Err()
+	}
+	if err != nil {
+		return 0, errors.WithMessagef(err, "Rewrite: rule %q failed for %s", rule.Name, match.OpName())
+	}
+	if len(values) != len(match.Outputs) {
+		return 0, errors.Errorf("Rewrite: rule %q returned %d values to replace %s, which has %d outputs",
+			rule.Name, len(values), match.OpName(), len(match.Outputs))
+	}
+	replacements := make(map[*Value]*Value, len(values))
+	for i, value := range values {
+		if value == nil || value.fn != fn {
+			return 0, errors.Errorf("Rewrite: rule %q returned value #%d that is not part of the function %q",
+				rule.Name, i, fn.Name)
+		}
+		if !value.shape.Equal(match.Outputs[i].shape) {
+			return 0, errors.Errorf("Rewrite: rule %q returned value #%d with shape %s, but %s output has shape %s",
+				rule.Name, i, value.shape, match.OpName(), match.Outputs[i].shape)
+		}
+		if slices.Contains(match.Outputs, value) {
+			return 0, errors.Errorf("Rewrite: rule %q returned value #%d that is an output of the matched %s, "+
+				"which is removed", rule.Name, i, match.OpName())
+		}
+		replacements[match.Outputs[i]] = value
+	}
+	newStatements := fn.Statements[numStatements:]
+	// The new statements, and the values replacing the outputs, must only use the values defined before the matched
+	// statement.
+	replaced := slices.Concat(fn.Statements[:stmtIdx], newStatements)
+	if err = fn.checkStatementsOrder(replaced); err != nil {
+		return 0, errors.WithMessagef(err, "Rewrite: rule %q for %s", rule.Name, match.OpName())
+	}
+	for i, value := range values {
+		isDefined := func(stmt *Statement) bool { return slices.Contains(stmt.Outputs, value) }
+		if !slices.Contains(fn.Inputs, value) && !slices.ContainsFunc(replaced, isDefined) {
+			return 0, errors.Errorf("Rewrite: rule %q returned value #%d (%s) that is defined after the matched %s",
+				rule.Name, i, value, match.OpName())
+		}
+	}
+	match.unregisterUses()
+	fn.Statements = slices.Concat(fn.Statements[:stmtIdx], newStatements, fn.Statements[stmtIdx+1:numStatements])
+	fn.replaceUses(replacements)
+	return len(newStatements), nil
+}
+
+// rewriteKeptOps are the operations that are not removed by Rewrite when they become unused, since they may have
+// side effects.
+var rewriteKeptOps = []optypes.OpType{optypes.RawSnippet, optypes.FuncCall, optypes.CustomCall, optypes.AllReduce,
+	optypes.AllGather, optypes.AllToAll, optypes.CollectiveBroadcast, optypes.CollectivePermute}
+
+// mayHaveSideEffects returns whether the statement is one of the rewriteKeptOps, or any statement of its closures
+// (e.g. the body of a While or the branches of a Case) is, recursively.
+func (s *Statement) mayHaveSideEffects() bool {
+	if slices.Contains(rewriteKeptOps, s.OpType) {
+		return true
+	}
+	for _, closure := range s.FunctionParameters {
+		if slices.ContainsFunc(closure.Statements, (*Statement).mayHaveSideEffects) {
+			return true
+		}
+	}
+	return false
+}
+
+// usedValues returns the set of values used as inputs by the statements of fn.
+func (fn *Function) usedValues() map[*Value]bool {
+	used := make(map[*Value]bool)
+	for _, stmt := range fn.Statements {
+		for _, input := range stmt.Inputs {
+			used[input] = true
+		}
+	}
+	return used
+}
+
+// removeDeadStatements removes the statements whose outputs were used (according to usedBefore), but are no longer used.
+func (fn *Function) removeDeadStatements(usedBefore map[*Value]bool) {
+	for {
+		used := fn.usedValues()
+		numStatements := len(fn.Statements)
+		fn.Statements = slices.DeleteFunc(fn.Statements, func(stmt *Statement) bool {
+			if len(stmt.Outputs) == 0 || stmt.mayHaveSideEffects() {
+				return false
+			}
+			for _, output := range stmt.Outputs {
+				if used[output] || !usedBefore[output] {
+					return false
+				}
+			}
+			stmt.unregisterUses()
+			return true
+		})
+		if len(fn.Statements) == numStatements {
+			return
+		}
+	}
+}
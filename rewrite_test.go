@@ -0,0 +1,168 @@
+package stablehlo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestRewrite(t *testing.T) {
+	fusedMultiplyAdd := &RewriteRule{
+		Name: "fused_multiply_add",
+		Op:   "stablehlo.add",
+		Predicate: func(add *Statement) bool {
+			mul := add.Inputs[0].Producer()
+			return mul != nil && mul.OpName() == "stablehlo.multiply"
+		},
+		Replace: func(fn *Function, add *Statement) ([]*Value, error) {
+			mul := add.Inputs[0].Producer()
+			return fn.RawStatement(`"stablehlo.custom_call"($0, $1, $2) { call_target_name = "fma" }`,
+				[]*Value{mul.Inputs[0], mul.Inputs[1], add.Inputs[1]}, add.Outputs[0].Shape())
+		},
+	}
+
+	t.Run("fuse", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		y := must(fn.NamedInput("y", shapes.Make(dtypes.Float32, 3)))
+		z := must(fn.NamedInput("z", shapes.Make(dtypes.Float32, 3)))
+		fma := must(Add(must(Multiply(x, y)), z))
+		notFused := must(Add(z, fma)) // First operand is not a multiplication.
+		must0(fn.Return(must(Tanh(notFused))))
+
+		numRewrites := must(fn.Rewrite(fusedMultiplyAdd))
+		if numRewrites != 1 {
+			t.Errorf("expected 1 rewrite, got %d", numRewrites)
+		}
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `%4 = "stablehlo.custom_call"(%x, %y, %z) { call_target_name = "fma" } : ` +
+			`(tensor<3xf32>, tensor<3xf32>, tensor<3xf32>) -> tensor<3xf32>
+    %2 = "stablehlo.add"(%z, %4) : (tensor<3xf32>, tensor<3xf32>) -> tensor<3xf32>`
+		if !strings.Contains(program, want) {
+			t.Errorf("program should contain:\n%s", want)
+		}
+		if strings.Contains(program, "stablehlo.multiply") {
+			t.Error("the multiplication should have been removed")
+		}
+	})
+
+	t.Run("returned value", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		must0(fn.Return(must(Add(must(Multiply(x, x)), x))))
+		must(fn.Rewrite(fusedMultiplyAdd))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		if !strings.Contains(program, `"stablehlo.return"(%2)`) {
+			t.Error("the return statement should use the rewritten value")
+		}
+	})
+
+	t.Run("side effects in closures", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		scalar := shapes.Make(dtypes.Float32)
+		x := must(fn.NamedInput("x", scalar))
+		cond := fn.Closure()
+		state := must(cond.NamedInput("state", scalar))
+		must0(cond.Return(must(Compare(state, must(cond.ConstantFromScalar(float32(10))), types.CompareLT,
+			types.CompareFloat))))
+		body := fn.Closure()
+		state = must(body.NamedInput("state", scalar))
+		must(body.RawStatement(`"stablehlo.custom_call"($0) { call_target_name = "log", has_side_effect = true }`,
+			[]*Value{state}, scalar))
+		must0(body.Return(must(Add(state, must(body.ConstantFromScalar(float32(1)))))))
+		loop := must(While(cond, body, x))
+		must0(fn.Return(must(Multiply(loop[0], x))))
+
+		// The rewrite makes the While unused, but it must be kept since its body has a custom call.
+		dropLoop := &RewriteRule{
+			Name: "drop_loop",
+			Op:   "stablehlo.multiply",
+			Replace: func(fn *Function, mul *Statement) ([]*Value, error) {
+				return []*Value{mul.Inputs[1]}, nil
+			},
+		}
+		if numRewrites := must(fn.Rewrite(dropLoop)); numRewrites != 1 {
+			t.Fatalf("expected 1 rewrite, got %d", numRewrites)
+		}
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		if !strings.Contains(program, "stablehlo.while") {
+			t.Error("the While with a custom call in its body should not have been removed")
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		must0(fn.Return(must(Abs(x))))
+		if _, err := fn.Rewrite(&RewriteRule{Name: "incomplete"}); err == nil {
+			t.Error("expected error for incomplete rule")
+		}
+		wrongShape := &RewriteRule{
+			Name: "wrong_shape",
+			Op:   "stablehlo.abs",
+			Replace: func(fn *Function, match *Statement) ([]*Value, error) {
+				v, err := fn.ConstantFromScalar(float32(0))
+				return []*Value{v}, err
+			},
+		}
+		if _, err := fn.Rewrite(wrongShape); err == nil {
+			t.Error("expected error for replacement with the wrong shape")
+		}
+		identity := &RewriteRule{
+			Name: "identity",
+			Op:   "stablehlo.abs",
+			Replace: func(fn *Function, match *Statement) ([]*Value, error) {
+				return match.Outputs, nil
+			},
+		}
+		if _, err := fn.Rewrite(identity); err == nil {
+			t.Error("expected error for replacement with the matched outputs")
+		}
+	})
+
+	t.Run("values defined later", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		y := must(Abs(x))
+		z := must(Negate(x))
+		must0(fn.Return(must(Add(y, z))))
+		useLater := &RewriteRule{
+			Name: "use_later",
+			Op:   "stablehlo.abs",
+			Replace: func(fn *Function, match *Statement) ([]*Value, error) {
+				return []*Value{z}, nil
+			},
+		}
+		if _, err := fn.Rewrite(useLater); err == nil || !strings.Contains(err.Error(), "defined after") {
+			t.Errorf("expected error for replacement with a value defined later, got %v", err)
+		}
+		createLater := &RewriteRule{
+			Name: "create_later",
+			Op:   "stablehlo.abs",
+			Replace: func(fn *Function, match *Statement) ([]*Value, error) {
+				v, err := Abs(z)
+				return []*Value{v}, err
+			},
+		}
+		if _, err := fn.Rewrite(createLater); err == nil || !strings.Contains(err.Error(), "before it is defined") {
+			t.Errorf("expected error for replacement using a value defined later, got %v", err)
+		}
+		// The function is unchanged.
+		program := string(must(builder.Build()))
+		if !strings.Contains(program, `%0 = "stablehlo.abs"(%x)`) || strings.Count(program, "stablehlo.abs") != 1 {
+			t.Errorf("the function was changed by the failed rewrites:\n%s", program)
+		}
+	})
+}
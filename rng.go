@@ -0,0 +1,205 @@
+package stablehlo
+
+import (
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// Constants of the SplitMix64 mixing function, used to derive independent RNG keys.
+const (
+	splitMixGamma = 0x9E3779B97F4A7C15
+	splitMixMul1  = 0xBF58476D1CE4E5B9
+	splitMixMul2  = 0x94D049BB133111EB
+)
+
+// checkRNGState checks that the state is a Philox RNG state: Uint64 with shape [2] or [3], where the first
+// element is the key and the remaining elements the counter.
+func checkRNGState(op string, state *Value) error {
+	shape := state.shape
+	if shape.DType != dtypes.Uint64 || shape.Rank() != 1 || (shape.Dimensions[0] != 2 && shape.Dimensions[0] != 3) {
+		return errors.Errorf("%s requires a Philox RNG state of shape Uint64[2] or Uint64[3], got %s", op, shape)
+	}
+	return nil
+}
+
+// RNGStateFold derives deterministically an independent RNG state for the given stream index from a Philox
+// RNG state (Uint64[2] or Uint64[3], see RNGBitGenerator with types.RNGPhilox).
+//
+// The key of the state (its first element) is mixed with the stream index using SplitMix64, and the counter is
+// kept. The same state and index always return the same stream, so it can be used for reproducible per-op
+// seeding -- e.g.: one stream per dropout layer --, without advancing the original state.
+func RNGStateFold(state *Value, streamIdx int) (newState *Value, err error) {
+	fn := state.fn
+	defer fn.opErrorHandler(&err, &newState)()
+	if err := checkRNGState("RNGStateFold", state); err != nil {
+		return nil, err
+	}
+	if streamIdx < 0 {
+		return nil, errors.Errorf("RNGStateFold requires a non-negative stream index, got %d", streamIdx)
+	}
+	dim := state.shape.Dimensions[0]
+	key, err := Slice(state, []int{0}, []int{1}, nil)
+	if err != nil {
+		return nil, err
+	}
+	counter, err := Slice(state, []int{1}, []int{dim}, nil)
+	if err != nil {
+		return nil, err
+	}
+	constant := func(v uint64) *Value {
+		c, constErr := fn.ConstantFromFlatAndDimensions([]uint64{v}, 1)
+		if constErr != nil && err == nil {
+			err = constErr
+		}
+		return c
+	}
+	// SplitMix64: z = key + (streamIdx+1) * gamma; z = (z ^ (z >> 30)) * mul1; z = (z ^ (z >> 27)) * mul2; z ^ (z >> 31).
+	offset := constant(uint64(streamIdx+1) * splitMixGamma)
+	shift30, shift27, shift31 := constant(30), constant(27), constant(31)
+	mul1, mul2 := constant(splitMixMul1), constant(splitMixMul2)
+	if err != nil {
+		return nil, err
+	}
+	xorShift := func(shift *Value) func(*Value) (*Value, error) {
+		return func(z *Value) (*Value, error) {
+			shifted, err := ShiftRightLogical(z, shift)
+			if err != nil {
+				return nil, err
+			}
+			return Xor(z, shifted)
+		}
+	}
+	key, err = fn.Expr(key).Add(offset).
+		Apply(xorShift(shift30)).Mul(mul1).
+		Apply(xorShift(shift27)).Mul(mul2).
+		Apply(xorShift(shift31)).Value()
+	if err != nil {
+		return nil, err
+	}
+	return Concatenate(0, key, counter)
+}
+
+// RNGStateSplit derives deterministically numStreams independent RNG states from a Philox RNG state, one for each
+// of numStreams parallel operations. It is equivalent to calling RNGStateFold for stream indices 0 to numStreams-1.
+func RNGStateSplit(state *Value, numStreams int) (newStates []*Value, err error) {
+	fn := state.fn
+	defer fn.multiOpErrorHandler(&err, &newStates, max(numStreams, 0))()
+	if numStreams <= 0 {
+		return nil, errors.Errorf("RNGStateSplit requires a positive number of streams, got %d", numStreams)
+	}
+	newStates = make([]*Value, numStreams)
+	for i := range numStreams {
+		newStates[i], err = RNGStateFold(state, i)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return newStates, nil
+}
+
+// RNGStateAdvance advances the counter of a Philox RNG state by delta, skipping the next delta blocks of
+// random bits (each block of 128 bits) of the stream.
+//
+// Only the lower 64 bits of the counter (the second element of the state) are advanced: there is no carry to
+// the third element of a Uint64[3] state.
+func RNGStateAdvance(state *Value, delta uint64) (newState *Value, err error) {
+	fn := state.fn
+	defer fn.opErrorHandler(&err, &newState)()
+	if err := checkRNGState("RNGStateAdvance", state); err != nil {
+		return nil, err
+	}
+	increment := make([]uint64, state.shape.Dimensions[0])
+	increment[1] = delta
+	incrementV, err := fn.ConstantFromFlatAndDimensions(increment, len(increment))
+	if err != nil {
+		return nil, err
+	}
+	return Add(state, incrementV)
+}
+
+// RNGState is a handle to the state of a random number generator (RNG), threading it through the program: each
+// call to Next generates random bits with RNGBitGenerator and replaces the handle's state by the new one, so the
+// same state is never accidentally used twice.
+//
+// Example:
+//
+//	rng, err := NewRNGState(stateInput, types.RNGPhilox)
+//	noise, err := rng.Next(shapes.Make(dtypes.Uint32, 8, 16))
+//	mask, err := rng.Next(shapes.Make(dtypes.Uint8, 8))
+//	err = fn.Return(noise, mask, rng.Value()) // Return the final state for the next execution.
+type RNGState struct {
+	state     *Value
+	algorithm types.RNGBitGeneratorAlgorithm
+}
+
+// NewRNGState returns a handle to the RNG state, used by the given algorithm.
+//
+// The state shape depends on the algorithm, see RNGBitGenerator: Uint64[2] for types.RNGThreeFry, Uint64[2] or
+// Uint64[3] for types.RNGPhilox, and any shape (implementation defined) for types.RNGDefault.
+func NewRNGState(state *Value, algorithm types.RNGBitGeneratorAlgorithm) (*RNGState, error) {
+	if state == nil {
+		return nil, errors.New("NewRNGState requires a state, got nil")
+	}
+	switch algorithm {
+	case types.RNGPhilox:
+		if err := checkRNGState("NewRNGState", state); err != nil {
+			return nil, err
+		}
+	case types.RNGThreeFry:
+		if !state.shape.Equal(shapes.Make(dtypes.Uint64, 2)) {
+			return nil, errors.Errorf("NewRNGState requires a ThreeFry RNG state of shape Uint64[2], got %s", state.shape)
+		}
+	case types.RNGDefault:
+	default:
+		return nil, errors.Errorf("NewRNGState: unknown algorithm %s", algorithm)
+	}
+	return &RNGState{state: state, algorithm: algorithm}, nil
+}
+
+// Value returns the current state, e.g. to return it from the function, so the next execution continues the stream.
+func (s *RNGState) Value() *Value {
+	return s.state
+}
+
+// Algorithm returns the algorithm of the RNG.
+func (s *RNGState) Algorithm() types.RNGBitGeneratorAlgorithm {
+	return s.algorithm
+}
+
+// Next returns values of the given shape filled with random bits, and advances the state.
+//
+// The shape must have an integer (or float, for the raw bits) dtype, see RNGBitGenerator.
+func (s *RNGState) Next(shape shapes.Shape) (*Value, error) {
+	newState, values, err := RNGBitGenerator(s.state, shape, s.algorithm)
+	if err != nil {
+		return nil, err
+	}
+	s.state = newState
+	return values, nil
+}
+
+// Split returns numStreams new independent RNG states, e.g. one for each of numStreams parallel operations, and
+// advances the state, so splitting it again returns different streams.
+//
+// It requires a Philox or ThreeFry state, since it derives the new states from its key, see RNGStateSplit.
+func (s *RNGState) Split(numStreams int) ([]*RNGState, error) {
+	if s.algorithm == types.RNGDefault {
+		return nil, errors.New("RNGState.Split requires the RNGPhilox or RNGThreeFry algorithm, the default state is implementation defined")
+	}
+	if numStreams <= 0 {
+		return nil, errors.Errorf("RNGState.Split requires a positive number of streams, got %d", numStreams)
+	}
+	// Derive one more stream to replace the current state.
+	states, err := RNGStateSplit(s.state, numStreams+1)
+	if err != nil {
+		return nil, err
+	}
+	splits := make([]*RNGState, numStreams)
+	for i := range splits {
+		splits[i] = &RNGState{state: states[i], algorithm: s.algorithm}
+	}
+	s.state = states[numStreams]
+	return splits, nil
+}
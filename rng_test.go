@@ -0,0 +1,122 @@
+package stablehlo
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestRNGState(t *testing.T) {
+	fn := New(t.Name()).Main()
+	state := must(fn.NamedInput("state", shapes.Make(dtypes.Uint64, 3)))
+	states := must(RNGStateSplit(state, 4))
+	if len(states) != 4 {
+		t.Fatalf("expected 4 states, got %d", len(states))
+	}
+	for _, s := range states {
+		if !s.Shape().Equal(state.Shape()) {
+			t.Errorf("expected split state shape %s, got %s", state.Shape(), s.Shape())
+		}
+	}
+	advanced := must(RNGStateAdvance(state, 10))
+	if !advanced.Shape().Equal(state.Shape()) {
+		t.Errorf("expected advanced state shape %s, got %s", state.Shape(), advanced.Shape())
+	}
+
+	invalid := must(fn.NamedInput("invalid", shapes.Make(dtypes.Int64, 2)))
+	if _, err := RNGStateFold(invalid, 0); err == nil {
+		t.Error("expected error for state with the wrong dtype, got nil")
+	}
+	if _, err := RNGStateSplit(state, 0); err == nil {
+		t.Error("expected error for 0 streams, got nil")
+	}
+}
+
+func TestRNGStateHandle(t *testing.T) {
+	fn := New(t.Name()).Main()
+	state := must(fn.NamedInput("state", shapes.Make(dtypes.Uint64, 2)))
+	rng := must(NewRNGState(state, types.RNGPhilox))
+	bits := must(rng.Next(shapes.Make(dtypes.Uint32, 4)))
+	if !bits.Shape().Equal(shapes.Make(dtypes.Uint32, 4)) {
+		t.Errorf("Next returned shape %s", bits.Shape())
+	}
+	afterFirst := rng.Value()
+	if afterFirst == state {
+		t.Fatal("Next should advance the state")
+	}
+	must(rng.Next(shapes.Make(dtypes.Uint8, 2)))
+	if producer := rng.Value().Producer(); producer.Inputs[0] != afterFirst {
+		t.Error("the second Next should use the state returned by the first one")
+	}
+
+	beforeSplit := rng.Value()
+	splits := must(rng.Split(2))
+	if len(splits) != 2 || splits[0].Value() == splits[1].Value() || rng.Value() == beforeSplit {
+		t.Error("Split should return 2 new states and advance the state")
+	}
+	if splits[0].Algorithm() != types.RNGPhilox {
+		t.Errorf("split state algorithm %s, wanted %s", splits[0].Algorithm(), types.RNGPhilox)
+	}
+
+	if _, err := NewRNGState(state, types.RNGThreeFry); err != nil {
+		t.Errorf("unexpected error for a ThreeFry state: %v", err)
+	}
+	if _, err := NewRNGState(must(fn.NamedInput("state3", shapes.Make(dtypes.Uint64, 3))), types.RNGThreeFry); err == nil {
+		t.Error("expected error for a ThreeFry state with 3 elements, got nil")
+	}
+	if _, err := NewRNGState(must(fn.NamedInput("wrong", shapes.Make(dtypes.Int32, 2))), types.RNGPhilox); err == nil {
+		t.Error("expected error for a Philox state with the wrong dtype, got nil")
+	}
+	defaultRNG := must(NewRNGState(must(fn.NamedInput("any", shapes.Make(dtypes.Uint32, 4))), types.RNGDefault))
+	if _, err := defaultRNG.Split(2); err == nil {
+		t.Error("expected error splitting a default RNG state, got nil")
+	}
+	if _, err := rng.Split(0); err == nil {
+		t.Error("expected error for 0 streams, got nil")
+	}
+}
+
+func TestRNG(t *testing.T) {
+	builder := New(t.Name())
+	fn := builder.Main()
+	zero := must(fn.ConstantFromScalar(float32(0)))
+	one := must(fn.ConstantFromScalar(float32(1)))
+	if _, err := RNG(zero, one, shapes.Make(dtypes.Float64, 2), types.RNGUniform); err == nil {
+		t.Error("expected error for shape with a different dtype")
+	}
+	if _, err := RNG(zero, must(fn.ConstantFromScalar(int32(1))), shapes.Make(dtypes.Float32, 2), types.RNGUniform); err == nil {
+		t.Error("expected error for a and b with different dtypes")
+	}
+	minInt, maxInt := must(fn.ConstantFromScalar(int32(-3))), must(fn.ConstantFromScalar(int32(3)))
+	if _, err := RNG(minInt, maxInt, shapes.Make(dtypes.Int32, 2), types.RNGNormal); err == nil {
+		t.Error("expected error for NORMAL distribution of integers")
+	}
+	uniform := must(RNG(minInt, maxInt, shapes.Make(dtypes.Int32, 2, 3), types.RNGUniform))
+	normal := must(RNG(zero, one, shapes.Make(dtypes.Float32), types.RNGNormal))
+	must0(fn.Return(uniform, normal))
+	program := string(must(builder.Build()))
+	fmt.Printf("%s program:\n%s", t.Name(), program)
+	want := `module @TestRNG {
+  func.func @main() -> (tensor<2x3xi32>, tensor<f32>) {
+    %0 = "stablehlo.constant"() { value = dense<0.0> : tensor<f32> } : () -> tensor<f32>
+    %1 = "stablehlo.constant"() { value = dense<1.0> : tensor<f32> } : () -> tensor<f32>
+    %2 = "stablehlo.constant"() { value = dense<1> : tensor<i32> } : () -> tensor<i32>
+    %3 = "stablehlo.constant"() { value = dense<-3> : tensor<i32> } : () -> tensor<i32>
+    %4 = "stablehlo.constant"() { value = dense<3> : tensor<i32> } : () -> tensor<i32>
+    %5 = "stablehlo.constant"() { value = dense<[2, 3]> : tensor<2xi64> } : () -> tensor<2xi64>
+    %6 = "stablehlo.rng"(%3, %4, %5) { rng_distribution = #stablehlo<rng_distribution UNIFORM> } : (tensor<i32>, tensor<i32>, tensor<2xi64>) -> tensor<2x3xi32>
+    %7 = "stablehlo.constant"() { value = dense<[]> : tensor<0xi64> } : () -> tensor<0xi64>
+    %8 = "stablehlo.rng"(%0, %1, %7) { rng_distribution = #stablehlo<rng_distribution NORMAL> } : (tensor<f32>, tensor<f32>, tensor<0xi64>) -> tensor<f32>
+    "stablehlo.return"(%6, %8) : (tensor<2x3xi32>, tensor<f32>) -> ()
+  }
+}
+`
+	if program != want {
+		fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+		t.Fatal("programs don't match")
+	}
+	requireRoundTrip(t, builder)
+}
@@ -0,0 +1,180 @@
+package stablehlo
+
+import (
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/shapeinference"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+// WithShapeInferenceCache enables (or disables) the memoization of the shape inference of the unary, binary and
+// single input Reduce operations, keyed on the operation and the shapes (and axes) of its inputs.
+//
+// Very large graphs, like those generated by high-level frontends, repeat the same operations on the same shapes
+// millions of times: the cache saves re-validating them and recomputing their output shapes. Only static shapes
+// (no bounds, no tuples) of rank up to 8 are cached, and failed inferences are not cached. It uses memory
+// proportional to the number of distinct signatures, so it's disabled by default.
+//
+// The values created from a cached inference share the same shape, so their shapes (see Value.Shape) must not be
+// modified -- which is never safe, since the shapes are also shared with the statements and other values.
+//
+// See Builder.ShapeInferenceCacheStats to check its effectiveness.
+func (b *Builder) WithShapeInferenceCache(enabled bool) *Builder {
+	if !enabled {
+		b.shapeCache = nil
+	} else if b.shapeCache == nil {
+		b.shapeCache = &shapeInferenceCache{entries: make(map[shapeCacheKey]shapes.Shape)}
+	}
+	return b
+}
+
+// ShapeInferenceCacheStats returns the number of shape inferences answered by the cache (hits) and computed
+// (misses) since it was enabled, see Builder.WithShapeInferenceCache. It returns zeros if the cache is disabled.
+func (b *Builder) ShapeInferenceCacheStats() (hits, misses int) {
+	if b.shapeCache == nil {
+		return 0, 0
+	}
+	return b.shapeCache.hits, b.shapeCache.misses
+}
+
+// maxCachedRank is the maximum rank of the shapes whose inference is cached.
+const maxCachedRank = 8
+
+// shapeCacheKey identifies a shape inference: it is a comparable struct, so looking it up doesn't allocate.
+type shapeCacheKey struct {
+	op    optypes.OpType
+	dtype dtypes.DType
+	rank  int8
+	dims  [maxCachedRank]int
+
+	// initialDType and reductionDType are the dtypes of the initial value and of the reduction function, for Reduce.
+	initialDType, reductionDType dtypes.DType
+
+	// numAxes and axes reduced, for Reduce.
+	numAxes int8
+	axes    [maxCachedRank]int8
+}
+
+// makeShapeCacheKey returns the key for the operation on the given shape, and whether the shape can be cached.
+func makeShapeCacheKey(op optypes.OpType, shape shapes.Shape) (key shapeCacheKey, ok bool) {
+	if shape.IsTuple() || shape.HasBounds() || shape.Rank() > maxCachedRank {
+		return key, false
+	}
+	key.op = op
+	key.dtype = shape.DType
+	key.rank = int8(shape.Rank())
+	copy(key.dims[:], shape.Dimensions)
+	return key, true
+}
+
+// shapeInferenceCache memoizes the output shapes of operations, see Builder.WithShapeInferenceCache.
+type shapeInferenceCache struct {
+	entries      map[shapeCacheKey]shapes.Shape
+	hits, misses int
+}
+
+// lookup returns the cached output shape for the key, if any.
+//
+// The returned shape is shared by all the values created from the cached inference: like the shapes returned
+// by Value.Shape, it must not be modified.
+func (c *shapeInferenceCache) lookup(key shapeCacheKey) (output shapes.Shape, found bool) {
+	output, found = c.entries[key]
+	if !found {
+		c.misses++
+		return output, false
+	}
+	c.hits++
+	return output, true
+}
+
+// store the output shape of a successful inference.
+func (c *shapeInferenceCache) store(key shapeCacheKey, output shapes.Shape) {
+	c.entries[key] = output.Clone()
+}
+
+// inferBinaryOp is shapeinference.BinaryOp, memoized if the Builder has a shape inference cache.
+func (b *Builder) inferBinaryOp(op optypes.OpType, lhs, rhs shapes.Shape) (shapes.Shape, error) {
+	if b.shapeCache == nil || !lhs.Equal(rhs) {
+		// Operands with different shapes are an error, not worth caching.
+		return shapeinference.BinaryOp(op, lhs, rhs)
+	}
+	key, ok := makeShapeCacheKey(op, lhs)
+	if !ok {
+		return shapeinference.BinaryOp(op, lhs, rhs)
+	}
+	if output, found := b.shapeCache.lookup(key); found {
+		return output, nil
+	}
+	output, err := shapeinference.BinaryOp(op, lhs, rhs)
+	if err == nil {
+		b.shapeCache.store(key, output)
+	}
+	return output, err
+}
+
+// inferUnaryOp is shapeinference.UnaryOp, memoized if the Builder has a shape inference cache.
+func (b *Builder) inferUnaryOp(op optypes.OpType, operand shapes.Shape) (shapes.Shape, error) {
+	if b.shapeCache == nil {
+		return shapeinference.UnaryOp(op, operand)
+	}
+	key, ok := makeShapeCacheKey(op, operand)
+	if !ok {
+		return shapeinference.UnaryOp(op, operand)
+	}
+	if output, found := b.shapeCache.lookup(key); found {
+		return output, nil
+	}
+	output, err := shapeinference.UnaryOp(op, operand)
+	if err == nil {
+		b.shapeCache.store(key, output)
+	}
+	return output, err
+}
+
+// inferReduce is shapeinference.Reduce, memoized if the Builder has a shape inference cache.
+//
+// Only the common reductions are cached: one input with a scalar initial value, reduced with a function taking
+// two scalars and returning one scalar.
+func (b *Builder) inferReduce(inputs, initialValues, reductionInputs, reductionOutputs []shapes.Shape, axes []int) (
+	[]shapes.Shape, error) {
+	key, ok := makeReduceCacheKey(inputs, initialValues, reductionInputs, reductionOutputs, axes)
+	if b.shapeCache == nil || !ok {
+		return shapeinference.Reduce(inputs, initialValues, reductionInputs, reductionOutputs, axes)
+	}
+	if output, found := b.shapeCache.lookup(key); found {
+		return []shapes.Shape{output}, nil
+	}
+	outputs, err := shapeinference.Reduce(inputs, initialValues, reductionInputs, reductionOutputs, axes)
+	if err == nil {
+		b.shapeCache.store(key, outputs[0])
+	}
+	return outputs, err
+}
+
+// makeReduceCacheKey returns the key for the Reduce operation, and whether it can be cached.
+func makeReduceCacheKey(inputs, initialValues, reductionInputs, reductionOutputs []shapes.Shape, axes []int) (
+	key shapeCacheKey, ok bool) {
+	if len(inputs) != 1 || len(initialValues) != 1 || len(reductionInputs) != 2 || len(reductionOutputs) != 1 ||
+		len(axes) > maxCachedRank {
+		return key, false
+	}
+	if !initialValues[0].IsScalar() || !reductionOutputs[0].IsScalar() ||
+		!reductionInputs[0].Equal(reductionOutputs[0]) || !reductionInputs[1].Equal(reductionOutputs[0]) {
+		return key, false
+	}
+	key, ok = makeShapeCacheKey(optypes.Reduce, inputs[0])
+	if !ok {
+		return key, false
+	}
+	key.initialDType = initialValues[0].DType
+	key.reductionDType = reductionOutputs[0].DType
+	key.numAxes = int8(len(axes))
+	for i, axis := range axes {
+		if axis < 0 || axis >= maxCachedRank {
+			// Invalid axes are an error, not worth caching.
+			return key, false
+		}
+		key.axes[i] = int8(axis)
+	}
+	return key, true
+}
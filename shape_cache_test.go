@@ -0,0 +1,59 @@
+package stablehlo
+
+import (
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestShapeInferenceCache(t *testing.T) {
+	buildProgram := func(b *Builder) []byte {
+		fn := b.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2, 3)))
+		y := x
+		for range 3 {
+			y = must(Tanh(must(Add(y, x))))
+		}
+		zero := must(fn.ConstantFromScalar(float32(0)))
+		sumFn := must(fn.NewReductionClosure(ReductionAdd, dtypes.Float32))
+		sum0 := must(Reduce(y, zero, sumFn, 1))
+		sum1 := must(Reduce(x, zero, sumFn, 1))
+		must0(fn.Return(must(Add(sum0, sum1))))
+		return must(b.Build())
+	}
+	want := string(buildProgram(New(t.Name())))
+	b := New(t.Name()).WithShapeInferenceCache(true)
+	if got := string(buildProgram(b)); got != want {
+		t.Fatalf("program built with the shape inference cache differs:\n%s\nwanted:\n%s", got, want)
+	}
+	// Misses: Add, Tanh and Reduce on [2, 3], Add on [2], and the Add of the reduction closure.
+	// Hits: 2 Add and 2 Tanh in the loop, and the second Reduce.
+	hits, misses := b.ShapeInferenceCacheStats()
+	if hits != 5 || misses != 5 {
+		t.Errorf("ShapeInferenceCacheStats() = (%d, %d), wanted (5, 5)", hits, misses)
+	}
+
+	// Failed inferences are not cached.
+	fn := b.NewFunction("f")
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2, 3)))
+	s := must(fn.NamedInput("s", shapes.Make(dtypes.Int32, 2, 3)))
+	for range 2 {
+		if _, err := Add(x, s); err == nil {
+			t.Fatal("expected error adding float and int values")
+		}
+	}
+	for range 2 {
+		if y := must(Add(x, x)); !y.shape.Equal(x.shape) {
+			t.Errorf("Add(x, x) has shape %s, wanted %s", y.shape, x.shape)
+		}
+	}
+	if hits, misses = b.ShapeInferenceCacheStats(); hits != 7 || misses != 5 {
+		t.Errorf("ShapeInferenceCacheStats() = (%d, %d), wanted (7, 5)", hits, misses)
+	}
+
+	b.WithShapeInferenceCache(false)
+	if hits, misses = b.ShapeInferenceCacheStats(); hits != 0 || misses != 0 {
+		t.Errorf("ShapeInferenceCacheStats() with the cache disabled = (%d, %d), wanted (0, 0)", hits, misses)
+	}
+}
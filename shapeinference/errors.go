@@ -0,0 +1,67 @@
+package shapeinference
+
+import (
+	"fmt"
+
+	"github.com/pkg/errors"
+)
+
+// Kinds of errors returned by the shape inference functions.
+//
+// Errors returned by the package wrap one of these kinds, so they can be tested with errors.Is,
+// while the message (see Error) keeps the details of the failure. E.g.:
+//
+//	if errors.Is(err, shapeinference.ErrInvalidAxis) { ... }
+var (
+	// ErrInvalidShape is returned when an operand shape is invalid (e.g. shapes.Invalid()).
+	ErrInvalidShape = errors.New("invalid shape")
+
+	// ErrShapeMismatch is returned when the shapes (dimensions or ranks) of the operands are not compatible.
+	ErrShapeMismatch = errors.New("shape mismatch")
+
+	// ErrInvalidAxis is returned when an axis is out of range, repeated or otherwise invalid for the operation.
+	ErrInvalidAxis = errors.New("invalid axis")
+
+	// ErrWrongDType is returned when the operands' data types are not supported or don't match.
+	ErrWrongDType = errors.New("wrong dtype")
+
+	// ErrInvalidArgument is returned for other invalid parameters of the operations.
+	ErrInvalidArgument = errors.New("invalid argument")
+)
+
+// Error is the error returned by the shape inference functions: it has one of the error kinds
+// (ErrShapeMismatch, ErrInvalidAxis, ErrWrongDType, etc.) and a detailed message.
+//
+// Use errors.Is to test for the kind, or errors.As to access the Error.
+type Error struct {
+	// Kind is one of the error kinds, e.g.: ErrShapeMismatch.
+	Kind error
+
+	// Message with the details of the error.
+	Message string
+}
+
+// Error implements the error interface: it returns only the message, the kind is implicit.
+func (e *Error) Error() string {
+	return e.Message
+}
+
+// Unwrap returns the kind of the error, so errors.Is(err, ErrShapeMismatch) works.
+func (e *Error) Unwrap() error {
+	return e.Kind
+}
+
+// errorf creates a new Error of the given kind, with a stack trace.
+func errorf(kind error, format string, args ...any) error {
+	return errors.WithStack(&Error{Kind: kind, Message: fmt.Sprintf(format, args...)})
+}
+
+// withHint appends a hint to the error message, preserving its kind.
+func withHint(err error, hint string) error {
+	kind := ErrInvalidArgument
+	var e *Error
+	if errors.As(err, &e) {
+		kind = e.Kind
+	}
+	return errors.WithStack(&Error{Kind: kind, Message: err.Error() + "\n" + hint})
+}
@@ -0,0 +1,49 @@
+package shapeinference
+
+import (
+	"testing"
+
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/types"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+func TestErrorKinds(t *testing.T) {
+	testCases := []struct {
+		name string
+		err  error
+		kind error
+	}{
+		{"BinaryOp shapes", second(BinaryOp(optypes.Add, S(F32, 2), S(F32, 3))), ErrShapeMismatch},
+		{"BinaryOp dtype", second(BinaryOp(optypes.And, S(F32, 2), S(F32, 2))), ErrWrongDType},
+		{"Transpose axis", second(Transpose(S(F32, 2, 3), []int{0, 2})), ErrInvalidAxis},
+		{"Concatenate invalid", second(Concatenate([]shapes.Shape{shapes.Invalid()}, 0)), ErrInvalidShape},
+		{"Compare direction", second(Compare(S(F32), S(F32), types.ComparisonDirection(100), types.CompareFloat)), ErrInvalidArgument},
+		{"Gather with hint", second(Gather(S(F32, 4, 3), S(I32, 2, 1), 1, []int{1}, []int{7}, nil, nil, []int{0}, []int{1, 3}, false)), ErrInvalidAxis},
+	}
+	for _, tc := range testCases {
+		if tc.err == nil {
+			t.Errorf("%s: expected an error", tc.name)
+			continue
+		}
+		if !errors.Is(tc.err, tc.kind) {
+			t.Errorf("%s: expected error kind %v, got %v", tc.name, tc.kind, tc.err)
+		}
+		var e *Error
+		if !errors.As(tc.err, &e) || e.Message == "" {
+			t.Errorf("%s: expected a *shapeinference.Error with a message, got %T", tc.name, tc.err)
+		}
+	}
+
+	// Wrapped errors keep their kind.
+	err := errors.WithMessage(second(Transpose(S(F32, 2, 3), []int{0, 0})), "while building the graph")
+	if !errors.Is(err, ErrInvalidAxis) {
+		t.Errorf("expected wrapped error to be ErrInvalidAxis, got %v", err)
+	}
+}
+
+// second returns the second of two values: the error.
+func second[T any](_ T, err error) error {
+	return err
+}
@@ -0,0 +1,458 @@
+package shapeinference
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+// Fuzzers for the shape inference functions.
+//
+// Each fuzzer decodes the random bytes into shapes and axes configurations (see fuzzReader), calls the
+// shape inference function and cross-checks invariants:
+//
+//   - It should never panic (the fuzzing engine reports those).
+//   - If it succeeds, the output shape follows the expected rank and dimensions math.
+//   - Configurations that are known to be valid must succeed, and known invalid ones must fail.
+//
+// With a plain "go test" only the seed corpus is run. To actually fuzz, run for instance:
+//
+//	go test ./shapeinference -run='^$' -fuzz=FuzzSlice -fuzztime=30s
+
+// fuzzMaxRank and fuzzMaxDim limit the shapes generated, to keep them small.
+const (
+	fuzzMaxRank = 5
+	fuzzMaxDim  = 6
+)
+
+// fuzzDTypes used when generating random shapes.
+var fuzzDTypes = []dtypes.DType{dtypes.Float32, dtypes.Int32, dtypes.Bool, dtypes.Uint8, dtypes.Complex64}
+
+// fuzzReader decodes a byte stream into random values. Once the data is exhausted it returns zeros.
+type fuzzReader struct {
+	data []byte
+}
+
+// intn returns a value in [0, n).
+func (r *fuzzReader) intn(n int) int {
+	if n <= 0 || len(r.data) == 0 {
+		return 0
+	}
+	v := int(r.data[0])
+	r.data = r.data[1:]
+	return v % n
+}
+
+// intRange returns a value in [from, to].
+func (r *fuzzReader) intRange(from, to int) int {
+	return from + r.intn(to-from+1)
+}
+
+// bool returns a random boolean.
+func (r *fuzzReader) bool() bool {
+	return r.intn(2) == 1
+}
+
+// dtype returns one of the fuzzDTypes.
+func (r *fuzzReader) dtype() dtypes.DType {
+	return fuzzDTypes[r.intn(len(fuzzDTypes))]
+}
+
+// dims returns dimensions for the given rank, each dimension in [0, fuzzMaxDim].
+func (r *fuzzReader) dims(rank int) []int {
+	dims := make([]int, rank)
+	for i := range dims {
+		dims[i] = r.intn(fuzzMaxDim + 1)
+	}
+	return dims
+}
+
+// shape returns a random shape with the given dtype, and rank in [0, fuzzMaxRank].
+func (r *fuzzReader) shape(dtype dtypes.DType) shapes.Shape {
+	return shapes.Make(dtype, r.dims(r.intn(fuzzMaxRank+1))...)
+}
+
+// axes returns a list of up to maxLen axes in [-1, rank]: so it includes some out-of-range values.
+func (r *fuzzReader) axes(rank, maxLen int) []int {
+	axes := make([]int, r.intn(maxLen+1))
+	for i := range axes {
+		axes[i] = r.intRange(-1, rank)
+	}
+	return axes
+}
+
+// ints returns n values in [from, to].
+func (r *fuzzReader) ints(n, from, to int) []int {
+	values := make([]int, n)
+	for i := range values {
+		values[i] = r.intRange(from, to)
+	}
+	return values
+}
+
+// isPermutation returns whether axes is a permutation of 0...rank-1.
+func isPermutation(axes []int, rank int) bool {
+	if len(axes) != rank {
+		return false
+	}
+	seen := make([]bool, rank)
+	for _, axis := range axes {
+		if axis < 0 || axis >= rank || seen[axis] {
+			return false
+		}
+		seen[axis] = true
+	}
+	return true
+}
+
+// areUniqueAxes returns whether all axes are in [0, rank) and there are no repeated values.
+func areUniqueAxes(axes []int, rank int) bool {
+	seen := make([]bool, rank)
+	for _, axis := range axes {
+		if axis < 0 || axis >= rank || seen[axis] {
+			return false
+		}
+		seen[axis] = true
+	}
+	return true
+}
+
+// fuzzSeeds adds a few seeds to the corpus of the fuzzer.
+func fuzzSeeds(f *testing.F) {
+	f.Add([]byte{})
+	f.Add([]byte{0, 2, 3, 4, 0, 2, 3, 4})
+	f.Add([]byte{1, 3, 2, 2, 2, 1, 0, 2, 1, 1, 1, 0, 0, 1})
+	f.Add([]byte{2, 4, 1, 5, 0, 3, 3, 2, 1, 0, 5, 5, 5, 5, 5, 5, 5, 5})
+	f.Add([]byte{255, 128, 7, 64, 33, 9, 17, 200, 100, 50, 25, 12, 6, 3})
+}
+
+var fuzzBinaryOps = []optypes.OpType{optypes.Add, optypes.Multiply, optypes.Maximum, optypes.And, optypes.Power,
+	optypes.Atan2, optypes.ShiftLeft, optypes.Remainder, optypes.Compare}
+
+func FuzzBinaryOp(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := &fuzzReader{data: data}
+		opType := fuzzBinaryOps[r.intn(len(fuzzBinaryOps))]
+		lhs := r.shape(r.dtype())
+		rhs := lhs.Clone()
+		if r.bool() {
+			rhs = r.shape(r.dtype())
+		}
+		output, err := BinaryOp(opType, lhs, rhs)
+		if err != nil {
+			return
+		}
+		if !lhs.Equal(rhs) {
+			t.Fatalf("BinaryOp(%s, %s, %s) succeeded with mismatched shapes", opType, lhs, rhs)
+		}
+		if !slices.Equal(output.Dimensions, lhs.Dimensions) {
+			t.Fatalf("BinaryOp(%s, %s, %s) returned %s, dimensions should be the same", opType, lhs, rhs, output)
+		}
+	})
+}
+
+func FuzzTranspose(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := &fuzzReader{data: data}
+		operand := r.shape(r.dtype())
+		rank := operand.Rank()
+		var permutation []int
+		if r.bool() {
+			// Valid permutation.
+			permutation = make([]int, rank)
+			for i := range permutation {
+				permutation[i] = i
+			}
+			for i := rank - 1; i > 0; i-- {
+				j := r.intn(i + 1)
+				permutation[i], permutation[j] = permutation[j], permutation[i]
+			}
+		} else {
+			permutation = r.axes(rank, rank+1)
+		}
+		output, err := Transpose(operand, permutation)
+		valid := isPermutation(permutation, rank)
+		if valid != (err == nil) {
+			t.Fatalf("Transpose(%s, %v): valid=%v, but got err=%v", operand, permutation, valid, err)
+		}
+		if err != nil {
+			return
+		}
+		if output.DType != operand.DType || output.Rank() != rank {
+			t.Fatalf("Transpose(%s, %v) returned %s", operand, permutation, output)
+		}
+		for i, axis := range permutation {
+			if output.Dimensions[i] != operand.Dimensions[axis] {
+				t.Fatalf("Transpose(%s, %v) returned %s", operand, permutation, output)
+			}
+		}
+	})
+}
+
+func FuzzSlice(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := &fuzzReader{data: data}
+		operand := r.shape(r.dtype())
+		rank := operand.Rank()
+		numAxes := rank
+		if r.intn(8) == 0 {
+			numAxes = r.intn(fuzzMaxRank + 1)
+		}
+		starts := r.ints(numAxes, -1, fuzzMaxDim+1)
+		limits := r.ints(numAxes, -1, fuzzMaxDim+1)
+		strides := r.ints(numAxes, -1, 3)
+		output, err := Slice(operand, starts, limits, strides)
+
+		valid := numAxes == rank
+		for axis := 0; valid && axis < rank; axis++ {
+			valid = starts[axis] >= 0 && starts[axis] <= limits[axis] &&
+				limits[axis] <= operand.Dimensions[axis] && strides[axis] >= 1
+		}
+		if valid && err != nil {
+			t.Fatalf("Slice(%s, starts=%v, limits=%v, strides=%v) failed for valid configuration: %v",
+				operand, starts, limits, strides, err)
+		}
+		if err != nil {
+			return
+		}
+		if output.DType != operand.DType || output.Rank() != rank {
+			t.Fatalf("Slice(%s, starts=%v, limits=%v, strides=%v) returned %s", operand, starts, limits, strides, output)
+		}
+		for axis, dim := range output.Dimensions {
+			want := (limits[axis] - starts[axis] + strides[axis] - 1) / strides[axis]
+			if dim != want {
+				t.Fatalf("Slice(%s, starts=%v, limits=%v, strides=%v) returned %s, wanted dimension %d for axis %d",
+					operand, starts, limits, strides, output, want, axis)
+			}
+		}
+	})
+}
+
+func FuzzConcatenate(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := &fuzzReader{data: data}
+		dtype := r.dtype()
+		first := r.shape(dtype)
+		inputs := []shapes.Shape{first}
+		numInputs := r.intRange(1, 4)
+		axis := r.intRange(-1, first.Rank())
+		for range numInputs - 1 {
+			input := first.Clone()
+			switch r.intn(4) {
+			case 0:
+				// Different shape.
+				input = r.shape(r.dtype())
+			default:
+				// Only the concatenation axis dimension changes.
+				if axis >= 0 && axis < input.Rank() {
+					input.Dimensions[axis] = r.intn(fuzzMaxDim + 1)
+				}
+			}
+			inputs = append(inputs, input)
+		}
+		output, err := Concatenate(inputs, axis)
+		if err != nil {
+			return
+		}
+		if axis < 0 || axis >= first.Rank() {
+			t.Fatalf("Concatenate(%v, axis=%d) succeeded with invalid axis", inputs, axis)
+		}
+		if output.DType != dtype || output.Rank() != first.Rank() {
+			t.Fatalf("Concatenate(%v, axis=%d) returned %s", inputs, axis, output)
+		}
+		var sum int
+		for _, input := range inputs {
+			if input.DType != dtype || input.Rank() != first.Rank() {
+				t.Fatalf("Concatenate(%v, axis=%d) succeeded with mismatched inputs", inputs, axis)
+			}
+			sum += input.Dimensions[axis]
+		}
+		for i, dim := range output.Dimensions {
+			want := first.Dimensions[i]
+			if i == axis {
+				want = sum
+			}
+			if dim != want {
+				t.Fatalf("Concatenate(%v, axis=%d) returned %s", inputs, axis, output)
+			}
+		}
+	})
+}
+
+func FuzzPad(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := &fuzzReader{data: data}
+		operand := r.shape(r.dtype())
+		fill := shapes.Make(operand.DType)
+		if r.intn(8) == 0 {
+			fill = r.shape(r.dtype())
+		}
+		rank := operand.Rank()
+		paddingStart := r.ints(rank, -2, 3)
+		paddingEnd := r.ints(rank, -2, 3)
+		paddingInterior := r.ints(rank, -1, 2)
+		output, err := Pad(operand, fill, paddingStart, paddingEnd, paddingInterior)
+		if err != nil {
+			return
+		}
+		if !fill.IsScalar() || fill.DType != operand.DType {
+			t.Fatalf("Pad(%s, fill=%s) succeeded with invalid fill value", operand, fill)
+		}
+		if output.DType != operand.DType || output.Rank() != rank {
+			t.Fatalf("Pad(%s, %v, %v, %v) returned %s", operand, paddingStart, paddingEnd, paddingInterior, output)
+		}
+		for axis, dim := range operand.Dimensions {
+			if paddingInterior[axis] < 0 {
+				t.Fatalf("Pad(%s, %v, %v, %v) succeeded with negative interior padding",
+					operand, paddingStart, paddingEnd, paddingInterior)
+			}
+			want := paddingStart[axis] + dim + paddingEnd[axis]
+			if dim > 0 {
+				want += (dim - 1) * paddingInterior[axis]
+			}
+			if output.Dimensions[axis] != want || want < 0 {
+				t.Fatalf("Pad(%s, %v, %v, %v) returned %s, wanted dimension %d for axis %d",
+					operand, paddingStart, paddingEnd, paddingInterior, output, want, axis)
+			}
+		}
+	})
+}
+
+func FuzzReduce(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := &fuzzReader{data: data}
+		dtype := r.dtype()
+		operand := r.shape(dtype)
+		rank := operand.Rank()
+		initialValue := shapes.Make(dtype)
+		if r.intn(8) == 0 {
+			initialValue = r.shape(r.dtype())
+		}
+		scalar := shapes.Make(dtype)
+		axes := r.axes(rank, rank)
+		outputs, err := Reduce([]shapes.Shape{operand}, []shapes.Shape{initialValue},
+			[]shapes.Shape{scalar, scalar}, []shapes.Shape{scalar}, axes)
+		if err != nil {
+			return
+		}
+		if !areUniqueAxes(axes, rank) {
+			t.Fatalf("Reduce(%s, axes=%v) succeeded with invalid axes", operand, axes)
+		}
+		if len(outputs) != 1 || outputs[0].DType != dtype || outputs[0].Rank() != rank-len(axes) {
+			t.Fatalf("Reduce(%s, axes=%v) returned %v", operand, axes, outputs)
+		}
+		outputAxis := 0
+		for axis, dim := range operand.Dimensions {
+			if slices.Contains(axes, axis) {
+				continue
+			}
+			if outputs[0].Dimensions[outputAxis] != dim {
+				t.Fatalf("Reduce(%s, axes=%v) returned %v", operand, axes, outputs)
+			}
+			outputAxis++
+		}
+	})
+}
+
+func FuzzDotGeneral(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := &fuzzReader{data: data}
+		dtype := r.dtype()
+		lhs := r.shape(dtype)
+		rhs := r.shape(dtype)
+		lhsContracting := r.axes(lhs.Rank(), 2)
+		rhsContracting := r.axes(rhs.Rank(), 2)
+		lhsBatch := r.axes(lhs.Rank(), 2)
+		rhsBatch := r.axes(rhs.Rank(), 2)
+		output, err := DotGeneral(lhs, lhsContracting, lhsBatch, rhs, rhsContracting, rhsBatch, dtype)
+		if err != nil {
+			return
+		}
+		if len(lhsContracting) != len(rhsContracting) || len(lhsBatch) != len(rhsBatch) {
+			t.Fatalf("DotGeneral(%s, %s) succeeded with mismatching number of axes", lhs, rhs)
+		}
+		allLhs := append(slices.Clone(lhsContracting), lhsBatch...)
+		allRhs := append(slices.Clone(rhsContracting), rhsBatch...)
+		if !areUniqueAxes(allLhs, lhs.Rank()) || !areUniqueAxes(allRhs, rhs.Rank()) {
+			t.Fatalf("DotGeneral(%s, contracting=%v, batch=%v, %s, contracting=%v, batch=%v) succeeded with invalid axes",
+				lhs, lhsContracting, lhsBatch, rhs, rhsContracting, rhsBatch)
+		}
+		for i := range lhsContracting {
+			if lhs.Dimensions[lhsContracting[i]] != rhs.Dimensions[rhsContracting[i]] {
+				t.Fatalf("DotGeneral(%s, %s) succeeded with mismatching contracting dimensions", lhs, rhs)
+			}
+		}
+		for i := range lhsBatch {
+			if lhs.Dimensions[lhsBatch[i]] != rhs.Dimensions[rhsBatch[i]] {
+				t.Fatalf("DotGeneral(%s, %s) succeeded with mismatching batch dimensions", lhs, rhs)
+			}
+		}
+		wantRank := len(lhsBatch) + (lhs.Rank() - len(allLhs)) + (rhs.Rank() - len(allRhs))
+		if output.Rank() != wantRank {
+			t.Fatalf("DotGeneral(%s, contracting=%v, batch=%v, %s, contracting=%v, batch=%v) returned %s, wanted rank %d",
+				lhs, lhsContracting, lhsBatch, rhs, rhsContracting, rhsBatch, output, wantRank)
+		}
+	})
+}
+
+func FuzzBroadcastInDim(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		r := &fuzzReader{data: data}
+		dtype := r.dtype()
+		operand := r.shape(dtype)
+		target := r.shape(dtype)
+		axesMapping := r.axes(target.Rank(), operand.Rank()+1)
+		err := BroadcastInDim(operand, target, axesMapping)
+		if err != nil {
+			return
+		}
+		if len(axesMapping) != operand.Rank() || !areUniqueAxes(axesMapping, target.Rank()) {
+			t.Fatalf("BroadcastInDim(%s, %s, %v) succeeded with invalid axes mapping", operand, target, axesMapping)
+		}
+		for i, axis := range axesMapping {
+			dim := operand.Dimensions[i]
+			if dim != 1 && dim != target.Dimensions[axis] {
+				t.Fatalf("BroadcastInDim(%s, %s, %v) succeeded with incompatible dimensions", operand, target, axesMapping)
+			}
+		}
+	})
+}
+
+func FuzzGather(f *testing.F) {
+	fuzzSeeds(f)
+	f.Fuzz(func(t *testing.T, data []byte) {
+		// Gather has too many parameters to enumerate the valid configurations: we only check it doesn't panic
+		// and that a success yields a valid shape of the operand dtype.
+		r := &fuzzReader{data: data}
+		operand := r.shape(r.dtype())
+		indices := r.shape(dtypes.Int32)
+		operandRank, indicesRank := operand.Rank(), indices.Rank()
+		outputRankGuess := operandRank + indicesRank
+		indexVectorAxis := r.intRange(-1, indicesRank)
+		offsetOutputAxes := r.axes(outputRankGuess, 2)
+		collapsedSliceAxes := r.axes(operandRank, 2)
+		operandBatchingAxes := r.axes(operandRank, 1)
+		startIndicesBatchingAxes := r.axes(indicesRank, 1)
+		startIndexMap := r.axes(operandRank, 2)
+		sliceSizes := r.ints(operandRank, 0, fuzzMaxDim)
+		output, err := Gather(operand, indices, indexVectorAxis, offsetOutputAxes, collapsedSliceAxes,
+			operandBatchingAxes, startIndicesBatchingAxes, startIndexMap, sliceSizes, r.bool())
+		if err != nil {
+			return
+		}
+		if !output.Ok() || output.DType != operand.DType {
+			t.Fatalf("Gather(%s, %s) returned invalid shape %s", operand, indices, output)
+		}
+	})
+}
@@ -0,0 +1,107 @@
+package shapeinference
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+// indicesBatchRank returns the number of batch axes of the Gather/Scatter indices: all axes except
+// indexVectorAxis (which may be equal to the rank, in which case it is implicit).
+func indicesBatchRank(indices shapes.Shape, indexVectorAxis int) int {
+	if indexVectorAxis >= 0 && indexVectorAxis < indices.Rank() {
+		return indices.Rank() - 1
+	}
+	return indices.Rank()
+}
+
+// numIndexedAxes returns the number of operand axes indexed by each index (the dimension of indexVectorAxis).
+func numIndexedAxes(indices shapes.Shape, indexVectorAxis int) int {
+	if indexVectorAxis >= 0 && indexVectorAxis < indices.Rank() {
+		return indices.Dimensions[indexVectorAxis]
+	}
+	return 1
+}
+
+// windowAxes returns the axes of the operand not listed in excluded -- the "offset" axes of Gather,
+// or the "window" axes of Scatter.
+func windowAxes(operand shapes.Shape, excluded ...[]int) []int {
+	var axes []int
+	for axis := range operand.Rank() {
+		if !slices.ContainsFunc(excluded, func(list []int) bool { return slices.Contains(list, axis) }) {
+			axes = append(axes, axis)
+		}
+	}
+	return axes
+}
+
+// trailingAxes returns the last n axes of a shape of the given rank.
+func trailingAxes(rank, n int) []int {
+	axes := make([]int, 0, n)
+	for axis := rank - n; axis < rank; axis++ {
+		axes = append(axes, axis)
+	}
+	return axes
+}
+
+// gatherHint explains the expected decomposition of the Gather operand and output axes, and suggests the
+// closest valid configuration of offsetOutputAxes and sliceSizes.
+func gatherHint(operand, startIndices shapes.Shape, indexVectorAxis int,
+	offsetOutputAxes, collapsedSliceAxes, operandBatchingAxes []int) string {
+	var sb strings.Builder
+	batchRank := indicesBatchRank(startIndices, indexVectorAxis)
+	offsetAxes := windowAxes(operand, collapsedSliceAxes, operandBatchingAxes)
+	_, _ = fmt.Fprintf(&sb, "hint: for Gather(operand=%s, startIndices=%s, indexVectorAxis=%d):\n",
+		operand, startIndices, indexVectorAxis)
+	_, _ = fmt.Fprintf(&sb, "  - startIndices has %d batch axes (all but indexVectorAxis), each index points to %d operand "+
+		"axes, so startIndexMap must have length %d;\n", batchRank, numIndexedAxes(startIndices, indexVectorAxis),
+		numIndexedAxes(startIndices, indexVectorAxis))
+	_, _ = fmt.Fprintf(&sb, "  - operand axes %v are collapsed, %v are batching, and %v are offset axes (kept in the output), "+
+		"so offsetOutputAxes must have %d axes (got %d);\n",
+		collapsedSliceAxes, operandBatchingAxes, offsetAxes, len(offsetAxes), len(offsetOutputAxes))
+	outputRank := batchRank + len(offsetAxes)
+	sliceSizes := slices.Clone(operand.Dimensions)
+	for _, axis := range slices.Concat(collapsedSliceAxes, operandBatchingAxes) {
+		if axis >= 0 && axis < len(sliceSizes) {
+			sliceSizes[axis] = 1
+		}
+	}
+	_, _ = fmt.Fprintf(&sb, "  - the output has rank %d (%d batch axes + %d offset axes): a valid choice is "+
+		"offsetOutputAxes=%v (offset axes after the batch axes), with sliceSizes=%v (or smaller on the offset axes).",
+		outputRank, batchRank, len(offsetAxes), trailingAxes(outputRank, len(offsetAxes)), sliceSizes)
+	return sb.String()
+}
+
+// scatterHint explains the expected decomposition of the Scatter input and updates axes, and suggests the
+// closest valid configuration of updateWindowAxes and of the updates shape.
+func scatterHint(input, scatterIndices, updates shapes.Shape, indexVectorAxis int,
+	updateWindowAxes, insertedWindowAxes, inputBatchingAxes []int) string {
+	var sb strings.Builder
+	batchRank := indicesBatchRank(scatterIndices, indexVectorAxis)
+	windowAxesOfInput := windowAxes(input, insertedWindowAxes, inputBatchingAxes)
+	_, _ = fmt.Fprintf(&sb, "hint: for Scatter(input=%s, scatterIndices=%s, updates=%s, indexVectorAxis=%d):\n",
+		input, scatterIndices, updates, indexVectorAxis)
+	_, _ = fmt.Fprintf(&sb, "  - scatterIndices has %d batch axes (all but indexVectorAxis), each index points to %d input "+
+		"axes, so indexedInputAxes must have length %d;\n", batchRank, numIndexedAxes(scatterIndices, indexVectorAxis),
+		numIndexedAxes(scatterIndices, indexVectorAxis))
+	_, _ = fmt.Fprintf(&sb, "  - input axes %v are inserted, %v are batching, and %v are window axes (present in the updates), "+
+		"so updateWindowAxes must have %d axes (got %d);\n",
+		insertedWindowAxes, inputBatchingAxes, windowAxesOfInput, len(windowAxesOfInput), len(updateWindowAxes))
+	updatesRank := batchRank + len(windowAxesOfInput)
+	updatesDims := make([]int, 0, updatesRank)
+	for axis := range scatterIndices.Rank() {
+		if axis != indexVectorAxis {
+			updatesDims = append(updatesDims, scatterIndices.Dimensions[axis])
+		}
+	}
+	for _, axis := range windowAxesOfInput {
+		updatesDims = append(updatesDims, input.Dimensions[axis])
+	}
+	_, _ = fmt.Fprintf(&sb, "  - the updates must have rank %d (%d batch axes + %d window axes, got rank %d): a valid choice is "+
+		"updateWindowAxes=%v (window axes after the batch axes), with updates dimensions %v (or smaller on the window axes).",
+		updatesRank, batchRank, len(windowAxesOfInput), updates.Rank(), trailingAxes(updatesRank, len(windowAxesOfInput)),
+		updatesDims)
+	return sb.String()
+}
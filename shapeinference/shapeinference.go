@@ -67,6 +67,7 @@ var (
 		optypes.Cosine,
 		optypes.Sine,
 		optypes.Tanh,
+		optypes.IsFinite,
 	)
 
 	// FloatOrComplexOperations operates only on float or complex numbers and won't work on integer or boolean values.
@@ -80,7 +81,6 @@ var (
 		optypes.RoundNearestEven,
 		optypes.Rsqrt,
 		optypes.Sqrt,
-		optypes.IsFinite,
 	)
 
 	// ComplexOperations operates only on complex numbers.
@@ -141,6 +141,23 @@ var (
 		optypes.Negate,
 		optypes.Sign,
 	)
+
+	// ResultAccuracyOperations include the unary operations that accept the "result_accuracy" attribute,
+	// see types.ResultAccuracy.
+	ResultAccuracyOperations = utils.SetWith(
+		optypes.Cbrt,
+		optypes.Cosine,
+		optypes.Exponential,
+		optypes.ExponentialMinusOne,
+		optypes.Log,
+		optypes.LogPlusOne,
+		optypes.Logistic,
+		optypes.Rsqrt,
+		optypes.Sine,
+		optypes.Sqrt,
+		optypes.Tan,
+		optypes.Tanh,
+	)
 )
 
 // BinaryOp returns the expected output shape for ops in the StandardBinaryOperations set -- those include all
@@ -151,41 +168,41 @@ var (
 // dtypes, or LogicalAnd not having booleans (dtype.Bool) as input.
 func BinaryOp(opType optypes.OpType, lhsShape, rhsShape shapes.Shape) (output shapes.Shape, err error) {
 	if !StandardBinaryOperations.Has(opType) && !ComparisonOperations.Has(opType) {
-		err = errors.Errorf("operations %s is not in the StandardBinaryOperations set, cannot process it with BinaryOp", opType)
+		err = errorf(ErrInvalidArgument, "operations %s is not in the StandardBinaryOperations set, cannot process it with BinaryOp", opType)
 		return
 	}
 	if lhsShape.DType == dtypes.InvalidDType || rhsShape.DType == dtypes.InvalidDType {
-		err = errors.Errorf("invalid shape for %s or %s for %q", lhsShape, rhsShape, opType)
+		err = errorf(ErrInvalidShape, "invalid shape for %s or %s for %q", lhsShape, rhsShape, opType)
 		return
 	}
 	if !lhsShape.Equal(rhsShape) {
-		err = errors.Errorf("shapes for %q must match, got %s and %s", opType, lhsShape, rhsShape)
+		err = errorf(ErrShapeMismatch, "shapes for %q must match, got %s and %s", opType, lhsShape, rhsShape)
 		return
 	}
 	if BooleanOrBitwiseOperations.Has(opType) && lhsShape.DType != dtypes.Bool && !lhsShape.DType.IsInt() {
-		err = errors.Errorf("Logical/Bitwise %q must have boolean (dtype.Bool) data types as input, got %s", opType, lhsShape)
+		err = errorf(ErrWrongDType, "Logical/Bitwise %q must have boolean (dtype.Bool) data types as input, got %s", opType, lhsShape)
 		return
 	}
 	if BitwiseOperations.Has(opType) && !lhsShape.DType.IsInt() {
-		err = errors.Errorf("bitwise BinaryOp %s must have an integer (Int8, UInt8, Int32, ...) data type as input, got %s", opType, lhsShape)
+		err = errorf(ErrWrongDType, "bitwise BinaryOp %s must have an integer (Int8, UInt8, Int32, ...) data type as input, got %s", opType, lhsShape)
 		return
 	}
 
 	if NumberOperations.Has(opType) && !ComparisonOperations.Has(opType) && !(lhsShape.DType.IsInt() || lhsShape.DType.IsFloat() || lhsShape.DType.IsComplex()) {
-		err = errors.Errorf("numeric BinaryOp %s must have a number (Int32, Float32, Complex64, ...) data type as input, got %s", opType, lhsShape)
+		err = errorf(ErrWrongDType, "numeric BinaryOp %s must have a number (Int32, Float32, Complex64, ...) data type as input, got %s", opType, lhsShape)
 		return
 	}
 
 	if FloatOperations.Has(opType) && !lhsShape.DType.IsFloat() {
-		err = errors.Errorf("float BinaryOp %s must have a float (Float32, Float64, ...) data type as input, got %s", opType, lhsShape)
+		err = errorf(ErrWrongDType, "float BinaryOp %s must have a float (Float32, Float64, ...) data type as input, got %s", opType, lhsShape)
 		return
 	}
 	if FloatOrComplexOperations.Has(opType) && !(lhsShape.DType.IsFloat() || lhsShape.DType.IsComplex()) {
-		err = errors.Errorf("float/complex BinaryOp %s must have a float or complex (Float32, Complex64, ...) data type as input, got %s", opType, lhsShape)
+		err = errorf(ErrWrongDType, "float/complex BinaryOp %s must have a float or complex (Float32, Complex64, ...) data type as input, got %s", opType, lhsShape)
 		return
 	}
 	if ComplexOperations.Has(opType) && !lhsShape.DType.IsComplex() {
-		err = errors.Errorf("complex BinaryOp %s must have a complex (Complex64, Complex128) data type as input, got %s", opType, lhsShape)
+		err = errorf(ErrWrongDType, "complex BinaryOp %s must have a complex (Complex64, Complex128) data type as input, got %s", opType, lhsShape)
 		return
 	}
 
@@ -203,7 +220,7 @@ func binaryOpImpl(opType optypes.OpType, lhsShape, rhsShape shapes.Shape) (outpu
 
 	// Other cases, either the dimensions match or one of them is 1.
 	if lhsShape.Rank() != rhsShape.Rank() {
-		err = errors.Errorf("if operands are not scalars, their rank must match for BinaryOp (%s), got shapes %s and %s",
+		err = errorf(ErrShapeMismatch, "if operands are not scalars, their rank must match for BinaryOp (%s), got shapes %s and %s",
 			opType, lhsShape, rhsShape)
 	}
 	output = lhsShape.Clone()
@@ -211,7 +228,7 @@ func binaryOpImpl(opType optypes.OpType, lhsShape, rhsShape shapes.Shape) (outpu
 		lhsDim := lhsShape.Dimensions[axis]
 		rhsDim := rhsShape.Dimensions[axis]
 		if lhsDim != 1 && rhsDim != 1 && lhsDim != rhsDim {
-			err = errors.Errorf("dimension of axis #%d doesn't match and cannot be broadcast for BinaryOp (%s), got shapes %s and %s",
+			err = errorf(ErrShapeMismatch, "dimension of axis #%d doesn't match and cannot be broadcast for BinaryOp (%s), got shapes %s and %s",
 				axis, opType, lhsShape, rhsShape)
 			return
 		}
@@ -221,43 +238,49 @@ func binaryOpImpl(opType optypes.OpType, lhsShape, rhsShape shapes.Shape) (outpu
 }
 
 // Compare returns the broadcast shape with dtype set to Bool, for comparison operations (Equal, LessThan, GreaterOrEqual, etc.)
+//
+// The compareType can be types.CompareAuto, see ResolveComparisonType.
 func Compare(lhsShape, rhsShape shapes.Shape, direction types.ComparisonDirection, compareType types.ComparisonType) (output shapes.Shape, err error) {
 	if lhsShape.DType == dtypes.InvalidDType || rhsShape.DType == dtypes.InvalidDType {
-		err = errors.Errorf("invalid shape for %s or %s for Compare", lhsShape, rhsShape)
+		err = errorf(ErrInvalidShape, "invalid shape for %s or %s for Compare", lhsShape, rhsShape)
 		return
 	}
 	if lhsShape.DType != rhsShape.DType {
-		err = errors.Errorf("data types (DType) for Compare must match, got %s and %s", lhsShape, rhsShape)
+		err = errorf(ErrWrongDType, "data types (DType) for Compare must match, got %s and %s", lhsShape, rhsShape)
 		return
 	}
 	dtype := lhsShape.DType
+	compareType, err = ResolveComparisonType(dtype, compareType)
+	if err != nil {
+		return
+	}
 	switch compareType {
 	case types.CompareFloat:
-		if !dtype.IsFloat() && !dtype.IsComplex() {
-			err = errors.Errorf("data type %s is not a float or complex, cannot process it with Compare(direction=%s, type=FLOAT)", dtype, direction)
+		if !utils.DTypeIsFloat(dtype) && !dtype.IsComplex() {
+			err = errorf(ErrWrongDType, "data type %s is not a float or complex, cannot process it with Compare(direction=%s, type=FLOAT)", dtype, direction)
 			return
 		}
 	case types.CompareTotalOrder:
-		if !dtype.IsFloat() {
-			err = errors.Errorf("data type %s is not a float, cannot process it with Compare(direction=%s, type=TOTAL_ORDER)", dtype, direction)
+		if !utils.DTypeIsFloat(dtype) {
+			err = errorf(ErrWrongDType, "data type %s is not a float, cannot process it with Compare(direction=%s, type=TOTAL_ORDER)", dtype, direction)
 			return
 		}
 	case types.CompareSigned:
 		if !dtype.IsInt() || dtype.IsUnsigned() {
-			err = errors.Errorf("data type %s is not a signed integer, cannot process it with Compare(direction=%s, type=SIGNED)", dtype, direction)
+			err = errorf(ErrWrongDType, "data type %s is not a signed integer, cannot process it with Compare(direction=%s, type=SIGNED)", dtype, direction)
 			return
 		}
 	case types.CompareUnsigned:
 		if !dtype.IsUnsigned() && dtype != dtypes.Bool {
-			err = errors.Errorf("data type %s is not an unsigned integer, cannot process it with Compare(direction=%s, type=UNSIGNED)", dtype, direction)
+			err = errorf(ErrWrongDType, "data type %s is not an unsigned integer, cannot process it with Compare(direction=%s, type=UNSIGNED)", dtype, direction)
 			return
 		}
 	default:
-		err = errors.Errorf("invalid comparison type %d for Compare", compareType)
+		err = errorf(ErrInvalidArgument, "invalid comparison type %d for Compare", compareType)
 		return
 	}
 	if direction < types.CompareEQ || direction > types.CompareNE {
-		err = errors.Errorf("invalid comparison direction %d for Compare", direction)
+		err = errorf(ErrInvalidArgument, "invalid comparison direction %d for Compare", direction)
 		return
 	}
 	output, err = BinaryOp(optypes.Compare, lhsShape, rhsShape)
@@ -268,44 +291,57 @@ func Compare(lhsShape, rhsShape shapes.Shape, direction types.ComparisonDirectio
 	return
 }
 
+// ResolveComparisonType returns the comparison type matching the dtype of the operands (see
+// types.ComparisonTypeForDType) if compareType is types.CompareAuto, or compareType itself otherwise.
+func ResolveComparisonType(dtype dtypes.DType, compareType types.ComparisonType) (types.ComparisonType, error) {
+	if compareType != types.CompareAuto {
+		return compareType, nil
+	}
+	resolved, err := types.ComparisonTypeForDType(dtype)
+	if err != nil {
+		return compareType, errorf(ErrWrongDType, "cannot infer the comparison type for Compare: %v", err)
+	}
+	return resolved, nil
+}
+
 // UnaryOp checks the validity of the data type for StandardUnaryOperations and returns either an error or
 // the output shape, which is the same as the operand.
 func UnaryOp(opType optypes.OpType, operand shapes.Shape) (output shapes.Shape, err error) {
 	if !StandardUnaryOperations.Has(opType) {
-		err = errors.Errorf("operation %s is not in the StandardUnaryOperations set, cannot process it with UnaryOp", opType)
+		err = errorf(ErrInvalidArgument, "operation %s is not in the StandardUnaryOperations set, cannot process it with UnaryOp", opType)
 		return
 	}
 	if operand.DType == dtypes.InvalidDType {
-		err = errors.Errorf("invalid shape %s for UnaryOp %s", operand, opType)
+		err = errorf(ErrInvalidShape, "invalid shape %s for UnaryOp %s", operand, opType)
 		return
 	}
 	if BooleanOrBitwiseOperations.Has(opType) && operand.DType != dtypes.Bool && !operand.DType.IsInt() {
-		err = errors.Errorf("logical UnaryOp %q must have boolean (dtype.Bool) data types as input, got %s", opType, operand)
+		err = errorf(ErrWrongDType, "logical UnaryOp %q must have boolean (dtype.Bool) data types as input, got %s", opType, operand)
 		return
 	}
 	if BitwiseOperations.Has(opType) && !operand.DType.IsInt() {
-		err = errors.Errorf("bitwise UnaryOp %s must have an integer (Int8, UInt8, Int32, ...) data type as input, got %s", opType, operand)
+		err = errorf(ErrWrongDType, "bitwise UnaryOp %s must have an integer (Int8, UInt8, Int32, ...) data type as input, got %s", opType, operand)
 		return
 	}
 	if SignedNumberOperations.Has(opType) && (operand.DType.IsUnsigned() ||
 		!(operand.DType.IsInt() || operand.DType.IsFloat() || operand.DType.IsComplex())) {
-		err = errors.Errorf("signed UnaryOp %s must have a signed data type as input, got %s", opType, operand)
+		err = errorf(ErrWrongDType, "signed UnaryOp %s must have a signed data type as input, got %s", opType, operand)
 		return
 	}
 	if NumberOperations.Has(opType) && !(operand.DType.IsInt() || operand.DType.IsFloat() || operand.DType.IsComplex()) {
-		err = errors.Errorf("numeric UnaryOp %s must have a number (Int32, Float32, Complex64, ...) data type as input, got %s", opType, operand)
+		err = errorf(ErrWrongDType, "numeric UnaryOp %s must have a number (Int32, Float32, Complex64, ...) data type as input, got %s", opType, operand)
 		return
 	}
 	if FloatOperations.Has(opType) && !operand.DType.IsFloat() {
-		err = errors.Errorf("float UnaryOp %s must have a float (Float32, Float64, ...) data type as input, got %s", opType, operand)
+		err = errorf(ErrWrongDType, "float UnaryOp %s must have a float (Float32, Float64, ...) data type as input, got %s", opType, operand)
 		return
 	}
 	if FloatOrComplexOperations.Has(opType) && !(operand.DType.IsFloat() || operand.DType.IsComplex()) {
-		err = errors.Errorf("float/complex UnaryOp %s must have a float or complex (Float32, Complex64, ...) data type as input, got %s", opType, operand)
+		err = errorf(ErrWrongDType, "float/complex UnaryOp %s must have a float or complex (Float32, Complex64, ...) data type as input, got %s", opType, operand)
 		return
 	}
 	if ComplexOperations.Has(opType) && !operand.DType.IsComplex() {
-		err = errors.Errorf("complex UnaryOp %s must have a complex (Complex64, Complex128) data type as input, got %s", opType, operand)
+		err = errorf(ErrWrongDType, "complex UnaryOp %s must have a complex (Complex64, Complex128) data type as input, got %s", opType, operand)
 		return
 	}
 
@@ -328,20 +364,20 @@ func UnaryOp(opType optypes.OpType, operand shapes.Shape) (output shapes.Shape,
 // isTrue and isFalse must have the same shape and dtypes.
 func Select(pred, onTrue, onFalse shapes.Shape) (output shapes.Shape, err error) {
 	if pred.DType != dtypes.Bool {
-		err = errors.Errorf("pred for Select() must be a boolean, got %s instead", pred)
+		err = errorf(ErrWrongDType, "pred for Select() must be a boolean, got %s instead", pred)
 		return
 	}
 	if !onTrue.Equal(onFalse) {
-		err = errors.Errorf("onTrue (%s) and onFalse (%s) values for Select() must have the same shape",
+		err = errorf(ErrShapeMismatch, "onTrue (%s) and onFalse (%s) values for Select() must have the same shape",
 			onTrue, onFalse)
 		return
 	}
 	if !pred.IsScalar() && pred.CheckDims(onTrue.Dimensions...) != nil {
-		err = errors.Errorf("pred for Select() must either be a scalar or match onTrue and onFalse shapes, instead got shapes pred=%s, onTrue=%s and onFalse=%s",
+		err = errorf(ErrShapeMismatch, "pred for Select() must either be a scalar or match onTrue and onFalse shapes, instead got shapes pred=%s, onTrue=%s and onFalse=%s",
 			pred, onTrue, onFalse)
 	}
 	if !onTrue.IsScalar() && !onFalse.IsScalar() && !onTrue.Equal(onFalse) {
-		err = errors.Errorf("onTrue (%s) and onFalse (%s) values for Select() must either be scalar or match each other's shape",
+		err = errorf(ErrShapeMismatch, "onTrue (%s) and onFalse (%s) values for Select() must either be scalar or match each other's shape",
 			onTrue, onFalse)
 		return
 	}
@@ -351,12 +387,12 @@ func Select(pred, onTrue, onFalse shapes.Shape) (output shapes.Shape, err error)
 // Complex returns the shape resulting from the Complex operation.
 func Complex(real, imag shapes.Shape) (output shapes.Shape, err error) {
 	if real.DType != imag.DType {
-		err = errors.Errorf("real and imaginary parts for Complex() must have the same data type, got %s and %s",
+		err = errorf(ErrWrongDType, "real and imaginary parts for Complex() must have the same data type, got %s and %s",
 			real, imag)
 		return
 	}
 	if real.DType != dtypes.Float32 && real.DType != dtypes.Float64 {
-		err = errors.Errorf("real and imaginary parts for Complex() must have a float data type, got %s",
+		err = errorf(ErrWrongDType, "real and imaginary parts for Complex() must have a float data type, got %s",
 			real)
 	}
 	output = real.Clone()
@@ -371,7 +407,8 @@ func Complex(real, imag shapes.Shape) (output shapes.Shape, err error) {
 // RealOrImag returns the shape resulting from the corresponding operations.
 func RealOrImag(complexOperand shapes.Shape) (output shapes.Shape, err error) {
 	if !complexOperand.DType.IsComplex() {
-		err = errors.Errorf("Real() and Imag() require a complex data type, got %s", complexOperand)
+		err = errorf(ErrWrongDType, "Real() and Imag() require a complex data type, got %s", complexOperand)
+		return
 	}
 	output = complexOperand.Clone()
 	if complexOperand.DType == dtypes.Complex64 {
@@ -385,21 +422,21 @@ func RealOrImag(complexOperand shapes.Shape) (output shapes.Shape, err error) {
 // Clamp returns the shape resulting from the corresponding operation.
 func Clamp(min, operand, max shapes.Shape) (output shapes.Shape, err error) {
 	if operand.DType != min.DType || operand.DType != max.DType {
-		err = errors.Errorf("operand, min and max for Clamp() must have the same data type, got %s, %s and %s",
+		err = errorf(ErrWrongDType, "operand, min and max for Clamp() must have the same data type, got %s, %s and %s",
 			operand, min, max)
 		return
 	}
 	if operand.DType.IsComplex() || operand.DType == dtypes.Bool {
-		err = errors.Errorf("Clamp() does not support complex or boolean data types, got %s", operand)
+		err = errorf(ErrWrongDType, "Clamp() does not support complex or boolean data types, got %s", operand)
 		return
 	}
 	if !min.IsScalar() && !min.Equal(operand) {
-		err = errors.Errorf("min for Clamp() must either be a scalar or match the operand shape, instead got min=%s and operand=%s",
+		err = errorf(ErrShapeMismatch, "min for Clamp() must either be a scalar or match the operand shape, instead got min=%s and operand=%s",
 			min, operand)
 		return
 	}
 	if !max.IsScalar() && !max.Equal(operand) {
-		err = errors.Errorf("max for Clamp() must either be a scalar or match the operand shape, instead got max=%s and operand=%s",
+		err = errorf(ErrShapeMismatch, "max for Clamp() must either be a scalar or match the operand shape, instead got max=%s and operand=%s",
 			max, operand)
 		return
 	}
@@ -413,7 +450,7 @@ func Clamp(min, operand, max shapes.Shape) (output shapes.Shape, err error) {
 func Transpose(operand shapes.Shape, permutation []int) (output shapes.Shape, err error) {
 	rank := operand.Rank()
 	if len(permutation) != rank {
-		err = errors.Errorf("Transpose() requires all axes permutation to be defined, operand has shape %s, but %d permutation were given",
+		err = errorf(ErrInvalidAxis, "Transpose() requires all axes permutation to be defined, operand has shape %s, but %d permutation were given",
 			operand, len(permutation))
 		return
 	}
@@ -426,12 +463,12 @@ func Transpose(operand shapes.Shape, permutation []int) (output shapes.Shape, er
 	slices.Sort(axesSet)
 	for ii, srcAxis := range axesSet {
 		if srcAxis < 0 || srcAxis >= rank {
-			err = errors.Errorf("invalid permutation axis %d given to Transpose(%s), it must be within the range of its rank",
+			err = errorf(ErrInvalidAxis, "invalid permutation axis %d given to Transpose(%s), it must be within the range of its rank",
 				srcAxis, operand)
 			return
 		}
 		if ii > 0 && srcAxis == axesSet[ii-1] {
-			err = errors.Errorf("invalid permutation given to Transpose(%s, %v), there cannot be any repeated axis, each must appear exactly once",
+			err = errorf(ErrInvalidAxis, "invalid permutation given to Transpose(%s, %v), there cannot be any repeated axis, each must appear exactly once",
 				operand, permutation)
 			return
 		}
@@ -442,6 +479,7 @@ func Transpose(operand shapes.Shape, permutation []int) (output shapes.Shape, er
 		srcAxis := permutation[axis]
 		output.Dimensions[axis] = operand.Dimensions[srcAxis]
 	}
+	output = withBoundsFrom(output, operand, permutation)
 	return
 }
 
@@ -451,16 +489,19 @@ func Transpose(operand shapes.Shape, permutation []int) (output shapes.Shape, er
 // The axesMapping is changed in place, replacing negative axes with their positive equivalent.
 func BroadcastInDim(operand, targetShape shapes.Shape, axesMapping []int) error {
 	if operand.DType != targetShape.DType {
-		return errors.Errorf("BroadcastInDim() requires the operand and the target shape to have the same data type, got operand=%s and targetShape=%s",
+		return errorf(ErrWrongDType, "BroadcastInDim() requires the operand and the target shape to have the same data type, got operand=%s and targetShape=%s",
 			operand, targetShape)
 	}
+	if err := targetShape.CheckSize(); err != nil {
+		return errorf(ErrInvalidShape, "BroadcastInDim(): %v", err)
+	}
 	targetRank := targetShape.Rank()
 	if targetRank < operand.Shape().Rank() {
-		return errors.Errorf("BroadcastInDim() cannot be used to shrink the rank of the operand, got operand=%s and targetShape=%s",
+		return errorf(ErrShapeMismatch, "BroadcastInDim() cannot be used to shrink the rank of the operand, got operand=%s and targetShape=%s",
 			operand, targetShape)
 	}
 	if len(axesMapping) != operand.Shape().Rank() {
-		return errors.Errorf("BroadcastInDim() requires all operand's axes mappings to be defined, operand has targetShape %s, but %d axes were given",
+		return errorf(ErrInvalidAxis, "BroadcastInDim() requires all operand's axes mappings to be defined, operand has targetShape %s, but %d axes were given",
 			operand, len(axesMapping))
 	}
 	usedAxis := utils.MakeSet[int](len(axesMapping))
@@ -470,13 +511,13 @@ func BroadcastInDim(operand, targetShape shapes.Shape, axesMapping []int) error
 			return errors.WithMessagef(err, "invalid axes mapping of operand axis %d to targetShape axis %d, targetShape targetShape is %s", operandAxis, targetAxis, targetShape)
 		}
 		if usedAxis.Has(targetAxis) {
-			return errors.Errorf("BroadcastInDim() requires all targetShape axes to be unique, got duplicate axis %d", targetAxis)
+			return errorf(ErrInvalidAxis, "BroadcastInDim() requires all targetShape axes to be unique, got duplicate axis %d", targetAxis)
 		}
 		usedAxis.Insert(targetAxis)
 		operandDim := operand.Dimensions[operandAxis]
 		targetDim := targetShape.Dimensions[targetAxis]
 		if operandDim != 1 && operandDim != targetDim {
-			return errors.Errorf("BroadcastInDim() requires all operand axes to be broadcast to be of dimension 1, but got operand.Dimensions[%d]=%d and targetShape.Dimension[%d]=%d",
+			return errorf(ErrShapeMismatch, "BroadcastInDim() requires all operand axes to be broadcast to be of dimension 1, but got operand.Dimensions[%d]=%d and targetShape.Dimension[%d]=%d",
 				operandAxis, operandDim, targetAxis, targetDim)
 		}
 		axesMapping[operandAxis] = targetAxis
@@ -485,7 +526,88 @@ func BroadcastInDim(operand, targetShape shapes.Shape, axesMapping []int) error
 }
 
 // Gather returns the output shape of a Gather operation.
+//
+// If the configuration is invalid, the error includes a hint with the expected decomposition of the operand
+// and output axes, and a suggestion of a valid configuration.
 func Gather(operand, startIndices shapes.Shape, indexVectorAxis int,
+	offsetOutputAxes, collapsedSliceAxes, operandBatchingAxes,
+	startIndicesBatchingAxes, startIndexMap,
+	sliceSizes []int, indicesAreSorted bool) (output shapes.Shape, err error) {
+	output, err = gather(operand, startIndices, indexVectorAxis,
+		offsetOutputAxes, collapsedSliceAxes, operandBatchingAxes,
+		startIndicesBatchingAxes, startIndexMap,
+		sliceSizes, indicesAreSorted)
+	if err != nil {
+		return shapes.Invalid(), withHint(err,
+			gatherHint(operand, startIndices, indexVectorAxis, offsetOutputAxes, collapsedSliceAxes, operandBatchingAxes))
+	}
+	return output, nil
+}
+
+// DynamicGather returns the output shape of a dynamic_gather operation: like Gather, but the slice sizes are given
+// by the 1D integer tensor sliceSizes (one value per operand axis), only known at runtime.
+//
+// The offset axes of the output (offsetOutputAxes) are dynamic (shapes.DynamicDim), bounded by the (static or
+// bounded) dimension of the corresponding operand axis, since a slice can't be larger than the operand.
+// The startIndices must be static.
+func DynamicGather(operand, startIndices, sliceSizes shapes.Shape, indexVectorAxis int,
+	offsetOutputAxes, collapsedSliceAxes, operandBatchingAxes,
+	startIndicesBatchingAxes, startIndexMap []int, indicesAreSorted bool) (output shapes.Shape, err error) {
+	if !operand.Ok() || operand.IsTuple() || !startIndices.Ok() {
+		return shapes.Invalid(), errorf(ErrInvalidShape, "DynamicGather: invalid operand %s or startIndices %s", operand, startIndices)
+	}
+	if startIndices.IsDynamic() {
+		return shapes.Invalid(), errorf(ErrInvalidShape, "DynamicGather: startIndices %s must be static", startIndices)
+	}
+	if err := checkIndicesVector("DynamicGather", "sliceSizes", sliceSizes, operand.Rank()); err != nil {
+		return shapes.Invalid(), err
+	}
+
+	// Infer the shape with the largest slices possible: the bounds of the operand.
+	boundedOperand := shapes.Make(operand.DType)
+	boundedOperand.Dimensions = make([]int, operand.Rank())
+	maxSliceSizes := make([]int, operand.Rank())
+	for axis := range operand.Rank() {
+		boundedOperand.Dimensions[axis] = max(operand.Bound(axis), 1)
+		maxSliceSizes[axis] = boundedOperand.Dimensions[axis]
+	}
+	var offsetOperandAxes []int
+	for axis := range operand.Rank() {
+		if slices.Contains(collapsedSliceAxes, axis) || slices.Contains(operandBatchingAxes, axis) {
+			maxSliceSizes[axis] = 1
+		} else {
+			offsetOperandAxes = append(offsetOperandAxes, axis)
+		}
+	}
+	output, err = Gather(boundedOperand, startIndices, indexVectorAxis,
+		offsetOutputAxes, collapsedSliceAxes, operandBatchingAxes,
+		startIndicesBatchingAxes, startIndexMap,
+		maxSliceSizes, indicesAreSorted)
+	if err != nil {
+		return shapes.Invalid(), errors.WithMessage(err, "DynamicGather")
+	}
+
+	// Offset axes become dynamic, bounded by the operand.
+	if len(offsetOutputAxes) != len(offsetOperandAxes) {
+		return shapes.Invalid(), errorf(ErrShapeMismatch, "DynamicGather: %d offsetOutputAxes given, but the operand has %d axes not collapsed or batched",
+			len(offsetOutputAxes), len(offsetOperandAxes))
+	}
+	output.Bounds = make([]int, output.Rank())
+	for axis := range output.Bounds {
+		output.Bounds[axis] = shapes.DynamicDim
+	}
+	for i, outputAxis := range offsetOutputAxes {
+		output.Dimensions[outputAxis] = shapes.DynamicDim
+		output.Bounds[outputAxis] = operand.Bound(offsetOperandAxes[i])
+	}
+	if !output.HasBounds() {
+		output.Bounds = nil
+	}
+	return output, nil
+}
+
+// gather implements Gather, without the hints.
+func gather(operand, startIndices shapes.Shape, indexVectorAxis int,
 	offsetOutputAxes, collapsedSliceAxes, operandBatchingAxes,
 	startIndicesBatchingAxes, startIndexMap,
 	sliceSizes []int, indicesAreSorted bool) (output shapes.Shape, err error) {
@@ -503,17 +625,17 @@ func Gather(operand, startIndices shapes.Shape, indexVectorAxis int,
 	_ = indicesAreSorted // Not used for shape inference.
 
 	if operand.IsScalar() {
-		return output, errors.Errorf("Gather() requires a non-scalar operand, got %s", operand)
+		return output, errorf(ErrInvalidArgument, "Gather() requires a non-scalar operand, got %s", operand)
 	}
 
 	// Check collapsedSliceAxes are all valid.
 	setCollapsedAxes := utils.MakeSet[int]()
 	for _, collapsedSliceAxis := range collapsedSliceAxes {
 		if collapsedSliceAxis < 0 || collapsedSliceAxis >= operand.Rank() {
-			return output, errors.Errorf("collapsed slice axis %d is out of range for operand %s", collapsedSliceAxis, operand)
+			return output, errorf(ErrInvalidAxis, "collapsed slice axis %d is out of range for operand %s", collapsedSliceAxis, operand)
 		}
 		if setCollapsedAxes.Has(collapsedSliceAxis) {
-			return output, errors.Errorf("collapsed slice axis %d is defined more than once for operand %s", collapsedSliceAxis, operand)
+			return output, errorf(ErrInvalidAxis, "collapsed slice axis %d is defined more than once for operand %s", collapsedSliceAxis, operand)
 		}
 		setCollapsedAxes.Insert(collapsedSliceAxis)
 	}
@@ -522,69 +644,69 @@ func Gather(operand, startIndices shapes.Shape, indexVectorAxis int,
 	setOperandBatchingAxes := utils.MakeSet[int]()
 	for _, batchAxis := range operandBatchingAxes {
 		if batchAxis < 0 || batchAxis >= operand.Rank() {
-			return output, errors.Errorf("operand batch axis %d is out of range for operand %s", batchAxis, operand)
+			return output, errorf(ErrInvalidAxis, "operand batch axis %d is out of range for operand %s", batchAxis, operand)
 		}
 		if setOperandBatchingAxes.Has(batchAxis) {
-			return output, errors.Errorf("operand batch axis %d is defined more than once for operand %s", batchAxis, operand)
+			return output, errorf(ErrInvalidAxis, "operand batch axis %d is defined more than once for operand %s", batchAxis, operand)
 		}
 		setCollapsedAxes.Insert(batchAxis)
 	}
 	setStartIndicesBatchingAxes := utils.MakeSet[int]()
 	for _, batchAxis := range startIndicesBatchingAxes {
 		if batchAxis < 0 || batchAxis >= startIndices.Rank() {
-			return output, errors.Errorf("startIndices batch axis %d is out of range for startIndices %s", batchAxis, startIndices)
+			return output, errorf(ErrInvalidAxis, "startIndices batch axis %d is out of range for startIndices %s", batchAxis, startIndices)
 		}
 		if setStartIndicesBatchingAxes.Has(batchAxis) {
-			return output, errors.Errorf("startIndices batch axis %d is defined more than once for startIndices %s", batchAxis, startIndices)
+			return output, errorf(ErrInvalidAxis, "startIndices batch axis %d is defined more than once for startIndices %s", batchAxis, startIndices)
 		}
 		if batchAxis == indexVectorAxis {
-			return output, errors.Errorf("startIndices batch axis %d is the same as indexVectorAxis %d -- the same axis cannot be both", batchAxis, indexVectorAxis)
+			return output, errorf(ErrInvalidAxis, "startIndices batch axis %d is the same as indexVectorAxis %d -- the same axis cannot be both", batchAxis, indexVectorAxis)
 		}
 		setStartIndicesBatchingAxes.Insert(batchAxis)
 	}
 	if len(operandBatchingAxes) != len(startIndicesBatchingAxes) {
-		return output, errors.Errorf("operandBatchingAxes and startIndicesBatchingAxes must have the same number of axes (length), got %d and %d", len(operandBatchingAxes), len(startIndicesBatchingAxes))
+		return output, errorf(ErrShapeMismatch, "operandBatchingAxes and startIndicesBatchingAxes must have the same number of axes (length), got %d and %d", len(operandBatchingAxes), len(startIndicesBatchingAxes))
 	}
 	for ii, operandBatchAxis := range operandBatchingAxes {
 		startIndicesBatchAxis := startIndicesBatchingAxes[ii]
 		if operand.Dim(operandBatchAxis) != startIndices.Dim(startIndicesBatchAxis) {
-			return output, errors.Errorf("operand batch axis %d has dimension %d, but startIndices batch axis %d has dimension %d -- they must match",
+			return output, errorf(ErrShapeMismatch, "operand batch axis %d has dimension %d, but startIndices batch axis %d has dimension %d -- they must match",
 				operandBatchAxis, operand.Dim(operandBatchAxis), startIndicesBatchAxis, startIndices.Dim(startIndicesBatchAxis))
 		}
 	}
 
 	// Check slice sizes.
 	if len(sliceSizes) != operand.Rank() {
-		return output, errors.Errorf("sliceSizes must have one value per operand axes, so it length (%d) must match operand rank (%d)", len(sliceSizes), operand.Rank())
+		return output, errorf(ErrShapeMismatch, "sliceSizes must have one value per operand axes, so it length (%d) must match operand rank (%d)", len(sliceSizes), operand.Rank())
 	}
 	for axis, sliceSize := range sliceSizes {
 		if sliceSize < 0 {
-			return output, errors.Errorf("sliceSize %d for axis %d is negative, it must be non-negative", sliceSize, axis)
+			return output, errorf(ErrInvalidArgument, "sliceSize %d for axis %d is negative, it must be non-negative", sliceSize, axis)
 		}
 		if operand.Dimensions[axis] < sliceSize {
-			return output, errors.Errorf("sliceSize %d for axis %d is larger than the corresponding operand dimension %d", sliceSize, axis, operand.Dimensions[axis])
+			return output, errorf(ErrInvalidArgument, "sliceSize %d for axis %d is larger than the corresponding operand dimension %d", sliceSize, axis, operand.Dimensions[axis])
 		}
 	}
 	for collapseAxis := range setCollapsedAxes {
 		if sliceSizes[collapseAxis] != 1 {
-			return output, errors.Errorf("collapsed slice axis %d must have sliceSize 1, but got %d", collapseAxis, sliceSizes[collapseAxis])
+			return output, errorf(ErrInvalidArgument, "collapsed slice axis %d must have sliceSize 1, but got %d", collapseAxis, sliceSizes[collapseAxis])
 		}
 	}
 	for batchAxis := range operandBatchingAxes {
 		if sliceSizes[batchAxis] != 1 {
-			return output, errors.Errorf("operand's batching axis %d must have sliceSize 1, but got %d", batchAxis, sliceSizes[batchAxis])
+			return output, errorf(ErrInvalidArgument, "operand's batching axis %d must have sliceSize 1, but got %d", batchAxis, sliceSizes[batchAxis])
 		}
 	}
 
 	// Check that the operand's axes are all used.
 	if operand.Rank() != len(offsetOutputAxes)+len(collapsedSliceAxes)+len(operandBatchingAxes) {
-		return output, errors.Errorf("the number of collapsedSliceAxes (%d) + the number of offsetOutputAxes (%d) + the number of operandsBatchingAxes (%d) must be equal to the number of axes in the operand (operand.Rank()=%d)",
+		return output, errorf(ErrShapeMismatch, "the number of collapsedSliceAxes (%d) + the number of offsetOutputAxes (%d) + the number of operandsBatchingAxes (%d) must be equal to the number of axes in the operand (operand.Rank()=%d)",
 			len(collapsedSliceAxes), len(offsetOutputAxes), len(operandBatchingAxes), operand.Rank())
 	}
 
 	// Check indexVectorAxis: it is ok if it is equal to startIndices.rank, in which case we assume an implicit extra axis of dimension 1.
 	if indexVectorAxis < 0 || indexVectorAxis > startIndices.Rank() {
-		return output, errors.Errorf("indexVectorAxis=%d is out of range for startIndices %s", indexVectorAxis, startIndices)
+		return output, errorf(ErrInvalidAxis, "indexVectorAxis=%d is out of range for startIndices %s", indexVectorAxis, startIndices)
 	}
 
 	// Check startIndexMap is set for the dimensions of indexVectorAxis in startIndices.
@@ -594,15 +716,15 @@ func Gather(operand, startIndices shapes.Shape, indexVectorAxis int,
 	}
 	if len(startIndexMap) != numIndexedAxes {
 		if indexVectorAxis == startIndices.Rank() {
-			return output, errors.Errorf("when indexVectorAxis==startIndices.Rank() we assume only one axis is being indexed, so startIndexMap be of length 1, got %d instead",
+			return output, errorf(ErrShapeMismatch, "when indexVectorAxis==startIndices.Rank() we assume only one axis is being indexed, so startIndexMap be of length 1, got %d instead",
 				len(startIndexMap))
 		}
-		return output, errors.Errorf("startIndexMap must have one value per dimension of indexVectorAxis, so its length (%d) must match startIndices.Dimensions[%d] (==%d)",
+		return output, errorf(ErrShapeMismatch, "startIndexMap must have one value per dimension of indexVectorAxis, so its length (%d) must match startIndices.Dimensions[%d] (==%d)",
 			len(startIndexMap), indexVectorAxis, numIndexedAxes)
 	}
 	for idx, operandAxis := range startIndexMap {
 		if operandAxis < 0 || operandAxis >= operand.Rank() {
-			return output, errors.Errorf("startIndexMap[%d]=%d is out of range for operand %s", idx, operandAxis, operand)
+			return output, errorf(ErrInvalidAxis, "startIndexMap[%d]=%d is out of range for operand %s", idx, operandAxis, operand)
 		}
 	}
 
@@ -623,10 +745,10 @@ func Gather(operand, startIndices shapes.Shape, indexVectorAxis int,
 	setOffsetOutputAxes := utils.MakeSet[int]()
 	for _, offsetOutputAxis := range offsetOutputAxes {
 		if offsetOutputAxis < 0 || offsetOutputAxis >= output.Rank() {
-			return shapes.Invalid(), errors.Errorf("offset output axis %d is out of range for output of rank %d", offsetOutputAxis, output.Rank())
+			return shapes.Invalid(), errorf(ErrInvalidAxis, "offset output axis %d is out of range for output of rank %d", offsetOutputAxis, output.Rank())
 		}
 		if setOffsetOutputAxes.Has(offsetOutputAxis) {
-			return shapes.Invalid(), errors.Errorf("offset output axis %d is defined more than once: offsetOutputAxes=%v", offsetOutputAxis, offsetOutputAxes)
+			return shapes.Invalid(), errorf(ErrInvalidAxis, "offset output axis %d is defined more than once: offsetOutputAxes=%v", offsetOutputAxis, offsetOutputAxes)
 		}
 		setOffsetOutputAxes.Insert(offsetOutputAxis)
 	}
@@ -668,7 +790,7 @@ func Gather(operand, startIndices shapes.Shape, indexVectorAxis int,
 // It takes a slice of input shapes and the dimension along which to concatenate.
 func Concatenate(inputs []shapes.Shape, axis int) (output shapes.Shape, err error) {
 	if len(inputs) == 0 {
-		return shapes.Invalid(), errors.Errorf("Concatenate requires at least one input shape")
+		return shapes.Invalid(), errorf(ErrInvalidArgument, "Concatenate requires at least one input shape")
 	}
 
 	// Initialize output dimensions with the first shape.
@@ -677,38 +799,41 @@ func Concatenate(inputs []shapes.Shape, axis int) (output shapes.Shape, err erro
 	rank := firstShape.Rank()
 	output = firstShape.Clone()
 	if dtype == dtypes.InvalidDType {
-		return shapes.Invalid(), errors.Errorf("invalid shape %s for first input of Concatenate", firstShape)
+		return shapes.Invalid(), errorf(ErrInvalidShape, "invalid shape %s for first input of Concatenate", firstShape)
+	}
+	if axis < 0 || axis >= rank {
+		return shapes.Invalid(), errorf(ErrInvalidAxis, "invalid concatenation axis %d for shapes with rank %d", axis, rank)
 	}
 	if len(inputs) == 1 {
 		return firstShape, nil
 	}
 
-	if axis < 0 || axis >= rank {
-		return shapes.Invalid(), errors.Errorf("invalid concatenation axis %d for shapes with rank %d", axis, rank)
-	}
-
 	// Validate further inputs and accumulate the concatenation axis size.
 	for i := 1; i < len(inputs); i++ {
 		currentShape := inputs[i]
 		if currentShape.DType == dtypes.InvalidDType {
-			return shapes.Invalid(), errors.Errorf("invalid shape %s for input #%d of Concatenate", currentShape, i)
+			return shapes.Invalid(), errorf(ErrInvalidShape, "invalid shape %s for input #%d of Concatenate", currentShape, i)
 		}
 		if currentShape.DType != dtype {
-			return shapes.Invalid(), errors.Errorf("mismatched DTypes for Concatenate: input #0 has %s, input #%d has %s",
+			return shapes.Invalid(), errorf(ErrWrongDType, "mismatched DTypes for Concatenate: input #0 has %s, input #%d has %s",
 				dtype, i, currentShape.DType)
 		}
 		if currentShape.Rank() != rank {
-			return shapes.Invalid(), errors.Errorf("mismatched ranks for Concatenate: input #0 has rank %d, input #%d has rank %d",
+			return shapes.Invalid(), errorf(ErrShapeMismatch, "mismatched ranks for Concatenate: input #0 has rank %d, input #%d has rank %d",
 				rank, i, currentShape.Rank())
 		}
 
 		for d := 0; d < rank; d++ {
 			if d == axis {
+				if currentShape.Dimensions[d] == shapes.DynamicDim || output.Dimensions[d] == shapes.DynamicDim {
+					return shapes.Invalid(), errorf(ErrInvalidShape, "Concatenate doesn't support a dynamic concatenation axis %d, got input #0 %s and input #%d %s",
+						axis, firstShape, i, currentShape)
+				}
 				output.Dimensions[d] += currentShape.Dimensions[d]
 			} else {
-				if currentShape.Dimensions[d] != output.Dimensions[d] {
-					return shapes.Invalid(), errors.Errorf("mismatched dimensions for Concatenate at axis %d (non-concatenation axis): input #0 has %d, input #%d has %d",
-						d, output.Dimensions[d], i, currentShape.Dimensions[d])
+				if currentShape.Dimensions[d] != output.Dimensions[d] || currentShape.Bound(d) != firstShape.Bound(d) {
+					return shapes.Invalid(), errorf(ErrShapeMismatch, "mismatched dimensions for Concatenate at axis %d (non-concatenation axis): input #0 is %s, input #%d is %s",
+						d, firstShape, i, currentShape)
 				}
 			}
 		}
@@ -720,51 +845,73 @@ func Concatenate(inputs []shapes.Shape, axis int) (output shapes.Shape, err erro
 // updates are applied to the inputs, but their shapes are unchanged.
 //
 // The Scatter operations indicesAreSorted and uniqueIndices don't play a role in this.
+//
+// If the configuration is invalid, the error includes a hint with the expected decomposition of the input and
+// updates axes, and a suggestion of a valid configuration.
 func Scatter(inputs []shapes.Shape, scatterIndices shapes.Shape, updates []shapes.Shape,
+	updateWindowAxes, insertedWindowAxes []int,
+	inputBatchingAxes, scatterIndicesBatchingAxes []int,
+	indexedInputAxes []int, indexVectorAxis int,
+	updateComputationInputs, updateComputationOutputs []shapes.Shape) (outputs []shapes.Shape, err error) {
+	outputs, err = scatter(inputs, scatterIndices, updates,
+		updateWindowAxes, insertedWindowAxes,
+		inputBatchingAxes, scatterIndicesBatchingAxes,
+		indexedInputAxes, indexVectorAxis,
+		updateComputationInputs, updateComputationOutputs)
+	if err != nil && len(inputs) > 0 && len(updates) > 0 {
+		return nil, withHint(err,
+			scatterHint(inputs[0], scatterIndices, updates[0], indexVectorAxis,
+				updateWindowAxes, insertedWindowAxes, inputBatchingAxes))
+	}
+	return outputs, err
+}
+
+// scatter implements Scatter, without the hints.
+func scatter(inputs []shapes.Shape, scatterIndices shapes.Shape, updates []shapes.Shape,
 	updateWindowAxes, insertedWindowAxes []int,
 	inputBatchingAxes, scatterIndicesBatchingAxes []int,
 	indexedInputAxes []int, indexVectorAxis int,
 	updateComputationInputs, updateComputationOutputs []shapes.Shape) (outputs []shapes.Shape, err error) {
 	// Check the number of inputs and updates.
 	if len(inputs) == 0 {
-		return nil, errors.Errorf("Scatter() requires at least one input")
+		return nil, errorf(ErrInvalidArgument, "Scatter() requires at least one input")
 	}
 	if len(inputs) != len(updates) {
-		return nil, errors.Errorf("Scatter() requires the same number of inputs and updates, got %d inputs and %d updates", len(inputs), len(updates))
+		return nil, errorf(ErrShapeMismatch, "Scatter() requires the same number of inputs and updates, got %d inputs and %d updates", len(inputs), len(updates))
 	}
 
 	// Check the dtypes match.
 	if scatterIndices.DType == dtypes.InvalidDType {
-		return nil, errors.Errorf("invalid shape for scatterIndices (%s)", scatterIndices)
+		return nil, errorf(ErrInvalidShape, "invalid shape for scatterIndices (%s)", scatterIndices)
 	}
 	input0 := inputs[0] // Shortcut, it will be used for the other checks.
 	for i, input := range inputs {
 		if input.DType == dtypes.InvalidDType {
-			return nil, errors.Errorf("invalid shape for inputs[%d]=%s", i, input)
+			return nil, errorf(ErrInvalidShape, "invalid shape for inputs[%d]=%s", i, input)
 		}
 		if slices.Compare(input0.Dimensions, input.Dimensions) != 0 {
-			return nil, errors.Errorf("all inputs must have the same shape (even if different dtypes), "+
+			return nil, errorf(ErrShapeMismatch, "all inputs must have the same shape (even if different dtypes), "+
 				"but inputs[0]=%s and inputs[%d]=%s", input0, i, input)
 		}
 	}
 	updates0 := updates[0] // Shortcut, it will be used for the other checks.
 	for i, update := range updates {
 		if update.DType == dtypes.InvalidDType {
-			return nil, errors.Errorf("invalid shape for updates[%d]=%s", i, update)
+			return nil, errorf(ErrInvalidShape, "invalid shape for updates[%d]=%s", i, update)
 		}
 		if update.DType != inputs[i].DType {
-			return nil, errors.Errorf("data types (DType) for inputs[%d]=%s and corresponding updates[%d]=%s must match",
+			return nil, errorf(ErrWrongDType, "data types (DType) for inputs[%d]=%s and corresponding updates[%d]=%s must match",
 				i, inputs[i], i, update)
 		}
 		if slices.Compare(updates0.Dimensions, update.Dimensions) != 0 {
-			return nil, errors.Errorf("all updates must have the same shape (even if different dtypes), "+
+			return nil, errorf(ErrShapeMismatch, "all updates must have the same shape (even if different dtypes), "+
 				"but updates[0]=%s and updates[%d]=%s", updates0, i, update)
 		}
 	}
 
 	// Inputs rank:
 	if input0.Rank() != len(updateWindowAxes)+len(inputBatchingAxes)+len(insertedWindowAxes) {
-		return nil, errors.Errorf("the number of updateWindowAxes (%d) + the number of inputBatchingAxes (%d) "+
+		return nil, errorf(ErrShapeMismatch, "the number of updateWindowAxes (%d) + the number of inputBatchingAxes (%d) "+
 			"+ the number of insertedWindowAxes (%d) must be equal to the number of axes in the inputs (inputs rank is =%d)",
 			len(updateWindowAxes), len(inputBatchingAxes), len(insertedWindowAxes), input0.Rank())
 	}
@@ -777,11 +924,11 @@ func Scatter(inputs []shapes.Shape, scatterIndices shapes.Shape, updates []shape
 
 	// Check updateComputation inputs and outputs.
 	if len(updateComputationOutputs) != len(inputs) {
-		return nil, errors.Errorf("updateComputation must have as many outputs (%d) as there are inputs (%d) to the Scatter operation",
+		return nil, errorf(ErrInvalidArgument, "updateComputation must have as many outputs (%d) as there are inputs (%d) to the Scatter operation",
 			len(updateComputationOutputs), len(inputs))
 	}
 	if len(updateComputationInputs) != 2*len(inputs) {
-		return nil, errors.Errorf(
+		return nil, errorf(ErrInvalidArgument,
 			"updateComputation must have as many inputs (%d) as there are 2 * inputs (%d) = %d to the Scatter operation, "+
 				"one value coming from the input, the other from the update",
 			len(updateComputationInputs), len(inputs), 2*len(inputs))
@@ -789,17 +936,17 @@ func Scatter(inputs []shapes.Shape, scatterIndices shapes.Shape, updates []shape
 	for i := range len(inputs) {
 		dtype := updateComputationInputs[i].DType
 		if !inputs[i].DType.IsPromotableTo(dtype) {
-			return nil, errors.Errorf(
+			return nil, errorf(ErrWrongDType,
 				"inputs[%d].DType=%s is not promotable to updateComputationFn input parameter #%d's dtype (%s)",
 				i, inputs[i].DType, i, dtype)
 		}
 		if dtype != updateComputationInputs[i+len(inputs)].DType {
-			return nil, errors.Errorf(
+			return nil, errorf(ErrWrongDType,
 				"updateComputation input #%d (%s) must match the dtype of the corresponding input #(%d + %d) (%s)",
 				i, dtype, i, len(inputs), updateComputationInputs[i+len(inputs)].DType)
 		}
 		if dtype != updateComputationOutputs[i].DType {
-			return nil, errors.Errorf(
+			return nil, errorf(ErrWrongDType,
 				"updateComputation input #%d (%s) must match the dtype of the corresponding output #%d (%s)",
 				i, dtype, i, updateComputationOutputs[i].DType)
 		}
@@ -822,16 +969,19 @@ func Slice(operand shapes.Shape, starts, limits, strides []int) (output shapes.S
 	rank := operand.Rank()
 	opName := "Slice"
 	if operand.DType == dtypes.InvalidDType {
-		return shapes.Invalid(), errors.Errorf("%s: invalid operand shape %s", opName, operand)
+		return shapes.Invalid(), errorf(ErrInvalidShape, "%s: invalid operand shape %s", opName, operand)
 	}
 	if len(starts) != rank {
-		return shapes.Invalid(), errors.Errorf("%s: len(starts)=%d, but operand rank is %d", opName, len(starts), rank)
+		return shapes.Invalid(), errorf(ErrShapeMismatch, "%s: len(starts)=%d, but operand rank is %d", opName, len(starts), rank)
 	}
 	if len(limits) != rank {
-		return shapes.Invalid(), errors.Errorf("%s: len(limits)=%d, but operand rank is %d", opName, len(limits), rank)
+		return shapes.Invalid(), errorf(ErrShapeMismatch, "%s: len(limits)=%d, but operand rank is %d", opName, len(limits), rank)
 	}
 	if len(strides) != rank {
-		return shapes.Invalid(), errors.Errorf("%s: len(strides)=%d, but operand rank is %d", opName, len(strides), rank)
+		return shapes.Invalid(), errorf(ErrShapeMismatch, "%s: len(strides)=%d, but operand rank is %d", opName, len(strides), rank)
+	}
+	if err := checkNoBounds(opName, "operand", operand); err != nil {
+		return shapes.Invalid(), err
 	}
 
 	output = shapes.Shape{
@@ -844,16 +994,17 @@ func Slice(operand shapes.Shape, starts, limits, strides []int) (output shapes.S
 		dimSize := operand.Dimensions[axis]
 
 		if stride <= 0 {
-			return shapes.Invalid(), errors.Errorf("%s: stride must be positive, but got stride[%d]=%d for operand shape %s",
+			return shapes.Invalid(), errorf(ErrInvalidArgument, "%s: stride must be positive, but got stride[%d]=%d for operand shape %s",
 				opName, axis, stride, operand)
 		}
-		if start < 0 || start >= dimSize {
-			return shapes.Invalid(), errors.Errorf("%s: start index %d is out of bounds for axis %d with size %d (operand shape %s)",
+		// Start can be equal to dimSize, if the slice is empty (limit == start).
+		if start < 0 || start > dimSize {
+			return shapes.Invalid(), errorf(ErrInvalidArgument, "%s: start index %d is out of bounds for axis %d with size %d (operand shape %s)",
 				opName, start, axis, dimSize, operand)
 		}
 		// Limit can be equal to dimSize.
 		if limit < start || limit > dimSize {
-			return shapes.Invalid(), errors.Errorf("%s: limit index %d is out of bounds for axis %d (start=%d, size=%d, operand shape %s)",
+			return shapes.Invalid(), errorf(ErrInvalidArgument, "%s: limit index %d is out of bounds for axis %d (start=%d, size=%d, operand shape %s)",
 				opName, limit, axis, start, dimSize, operand)
 		}
 
@@ -869,24 +1020,31 @@ func Slice(operand shapes.Shape, starts, limits, strides []int) (output shapes.S
 // It will be the shape of the operand minus the "reduce" axis.
 func ArgMinMax(operand shapes.Shape, axis int, outputDType dtypes.DType) (output shapes.Shape, err error) {
 	if !outputDType.IsInt() {
-		err = errors.Errorf("ArgMinMax outputDType must be an integer type, got %s", outputDType)
+		err = errorf(ErrWrongDType, "ArgMinMax outputDType must be an integer type, got %s", outputDType)
 		return
 	}
 	if !operand.DType.IsFloat() && !operand.DType.IsInt() {
-		err = errors.Errorf("ArgMinMax operand DType must be a floating point or integer type, got %s", operand)
+		err = errorf(ErrWrongDType, "ArgMinMax operand DType must be a floating point or integer type, got %s", operand)
 		return
 	}
 	if operand.IsScalar() {
-		err = errors.Errorf("ArgMinMax requires a non-scalar operand, got %s", operand)
+		err = errorf(ErrInvalidArgument, "ArgMinMax requires a non-scalar operand, got %s", operand)
 		return
 	}
 	if axis < 0 || axis >= operand.Rank() {
-		err = errors.Errorf("ArgMinMax axis %d is out of range for operand %s", axis, operand)
+		err = errorf(ErrInvalidAxis, "ArgMinMax axis %d is out of range for operand %s", axis, operand)
 		return
 	}
 	newDims := slices.Clone(operand.Dimensions)
 	newDims = slices.Delete(newDims, axis, axis+1)
 	output = shapes.Make(outputDType, newDims...)
+	operandAxes := make([]int, 0, len(newDims))
+	for operandAxis := range operand.Rank() {
+		if operandAxis != axis {
+			operandAxes = append(operandAxes, operandAxis)
+		}
+	}
+	output = withBoundsFrom(output, operand, operandAxes)
 	return
 }
 
@@ -897,12 +1055,15 @@ func ReduceWindow(inputs, initialValues []shapes.Shape, reductionInputs, reducti
 	windowDimensions, strides, baseDilations, windowDilations []int, paddings [][2]int) (outputs []shapes.Shape, err error) {
 	numReductions := len(inputs)
 	if numReductions < 0 {
-		return nil, errors.New("ReduceWindow requires at least one input")
+		return nil, errorf(ErrInvalidArgument, "ReduceWindow requires at least one input")
 	}
 	baseShape := inputs[0]
 	for i, input := range inputs {
 		if !input.Ok() {
-			return nil, errors.Errorf("ReduceWindow: invalid input[%d] shape %s", i, input)
+			return nil, errorf(ErrInvalidShape, "ReduceWindow: invalid input[%d] shape %s", i, input)
+		}
+		if err = checkNoBounds("ReduceWindow", "input", input); err != nil {
+			return
 		}
 		err = input.CheckDims(baseShape.Dimensions...)
 		if err != nil {
@@ -914,31 +1075,31 @@ func ReduceWindow(inputs, initialValues []shapes.Shape, reductionInputs, reducti
 	rank := baseShape.Rank()
 	for i, initialValue := range initialValues {
 		if initialValue.DType != inputs[i].DType {
-			return nil, errors.Errorf("ReduceWindow: initialValue[%d] has DType %s, but inputs[%d] has DType %s",
+			return nil, errorf(ErrWrongDType, "ReduceWindow: initialValue[%d] has DType %s, but inputs[%d] has DType %s",
 				i, initialValue.DType, i, inputs[i].DType)
 		}
 		if !initialValue.IsScalar() {
-			return nil, errors.Errorf("ReduceWindow: initialValue[%d] must be a scalar, but got shape %s", i, initialValue)
+			return nil, errorf(ErrInvalidArgument, "ReduceWindow: initialValue[%d] must be a scalar, but got shape %s", i, initialValue)
 		}
 	}
 
 	// Check that all reduction inputs and outputs are valid.
 	if len(reductionInputs) != 2*numReductions {
-		return nil, errors.Errorf("The reduction function for the ReduceWindow operation must have 2 inputs for each initialValue, but reduction has %d inputs for 2*%d=%d initial values",
+		return nil, errorf(ErrInvalidArgument, "The reduction function for the ReduceWindow operation must have 2 inputs for each initialValue, but reduction has %d inputs for 2*%d=%d initial values",
 			len(reductionInputs), len(initialValues), 2*len(initialValues))
 	}
 	if len(reductionOutputs) != numReductions {
-		return nil, errors.Errorf("The reduction function for the ReduceWindow operation must have 1 output for each initialValue, but reduction has %d outputs for %d initial values",
+		return nil, errorf(ErrInvalidArgument, "The reduction function for the ReduceWindow operation must have 1 output for each initialValue, but reduction has %d outputs for %d initial values",
 			len(reductionOutputs), len(initialValues))
 	}
 	for i := range numReductions {
 		dtype := reductionInputs[i].DType
 		if dtype != reductionInputs[i+numReductions].DType || dtype != reductionOutputs[i].DType {
-			return nil, errors.Errorf("ReduceWindow requires the same dtype for lhs[i], rhs[i] inputs and output[i], got lhs[%d]=%s and rhs[%d+%d]=%s and output[%d]=%s",
+			return nil, errorf(ErrWrongDType, "ReduceWindow requires the same dtype for lhs[i], rhs[i] inputs and output[i], got lhs[%d]=%s and rhs[%d+%d]=%s and output[%d]=%s",
 				i, reductionInputs[i], i, numReductions, reductionInputs[i+numReductions], i, reductionOutputs[i])
 		}
 		if !inputs[i].DType.IsPromotableTo(dtype) {
-			return nil, errors.Errorf(
+			return nil, errorf(ErrWrongDType,
 				"inputs[%d].DType=%s is not promotable to reductionFn input parameter #%d's dtype (%s)",
 				i, inputs[i].DType, i, dtype)
 		}
@@ -946,19 +1107,19 @@ func ReduceWindow(inputs, initialValues []shapes.Shape, reductionInputs, reducti
 
 	// Validate lengths of slice parameters against rank.
 	if len(windowDimensions) != rank {
-		return nil, errors.Errorf("ReduceWindow: len(windowDimensions)=%d, but inputs rank is %d", len(windowDimensions), rank)
+		return nil, errorf(ErrShapeMismatch, "ReduceWindow: len(windowDimensions)=%d, but inputs rank is %d", len(windowDimensions), rank)
 	}
 	if len(strides) != rank {
-		return nil, errors.Errorf("ReduceWindow: len(strides)=%d, but inputs rank is %d", len(strides), rank)
+		return nil, errorf(ErrShapeMismatch, "ReduceWindow: len(strides)=%d, but inputs rank is %d", len(strides), rank)
 	}
 	if len(paddings) != rank {
-		return nil, errors.Errorf("ReduceWindow: len(paddings)=%d, but inputs rank is %d", len(paddings), rank)
+		return nil, errorf(ErrShapeMismatch, "ReduceWindow: len(paddings)=%d, but inputs rank is %d", len(paddings), rank)
 	}
 	if len(baseDilations) != rank {
-		return nil, errors.Errorf("ReduceWindow: baseDilations is not nil and len(baseDilations)=%d, but inputs rank is %d", len(baseDilations), rank)
+		return nil, errorf(ErrShapeMismatch, "ReduceWindow: baseDilations is not nil and len(baseDilations)=%d, but inputs rank is %d", len(baseDilations), rank)
 	}
 	if len(windowDilations) != rank {
-		return nil, errors.Errorf("ReduceWindow: windowDilations is not nil and len(windowDilations)=%d, but inputs rank is %d", len(windowDilations), rank)
+		return nil, errorf(ErrShapeMismatch, "ReduceWindow: windowDilations is not nil and len(windowDilations)=%d, but inputs rank is %d", len(windowDilations), rank)
 	}
 
 	// If operand is a scalar (rank 0), the output is also a scalar of the same type.
@@ -976,24 +1137,22 @@ func ReduceWindow(inputs, initialValues []shapes.Shape, reductionInputs, reducti
 		inputDim := operand.Dimensions[i] // Already validated to be > 0 by shapes.Make
 		windowDim := windowDimensions[i]
 		if windowDim < 1 {
-			return nil, errors.Errorf("ReduceWindow: windowDimensions[%d]=%d must be >= 1 for operand shape %s", i, windowDim, operand)
+			return nil, errorf(ErrInvalidArgument, "ReduceWindow: windowDimensions[%d]=%d must be >= 1 for operand shape %s", i, windowDim, operand)
 		}
 		stride := strides[i]
 		if stride < 1 {
-			return nil, errors.Errorf("ReduceWindow: strides[%d]=%d must be >= 1 for operand shape %s", i, stride, operand)
+			return nil, errorf(ErrInvalidArgument, "ReduceWindow: strides[%d]=%d must be >= 1 for operand shape %s", i, stride, operand)
 		}
+		// Negative paddings are allowed, they trim the (base dilated) input.
 		paddingLow := paddings[i][0]
 		paddingHigh := paddings[i][1]
-		if paddingLow < 0 || paddingHigh < 0 {
-			return nil, errors.Errorf("ReduceWindow: paddings[%d]=[%d, %d] must be non-negative for operand shape %s", i, paddingLow, paddingHigh, operand)
-		}
 		baseDilation := baseDilations[i]
 		if baseDilation < 1 {
-			return nil, errors.Errorf("ReduceWindow: baseDilations[%d]=%d must be >= 1 for operand shape %s", i, baseDilation, operand)
+			return nil, errorf(ErrInvalidArgument, "ReduceWindow: baseDilations[%d]=%d must be >= 1 for operand shape %s", i, baseDilation, operand)
 		}
 		windowDilation := windowDilations[i]
 		if windowDilation < 1 {
-			return nil, errors.Errorf("ReduceWindow: windowDilations[%d]=%d must be >= 1 for operand shape %s", i, windowDilation, operand)
+			return nil, errorf(ErrInvalidArgument, "ReduceWindow: windowDilations[%d]=%d must be >= 1 for operand shape %s", i, windowDilation, operand)
 		}
 
 		// Effective input dimension after base dilation.
@@ -1003,14 +1162,15 @@ func ReduceWindow(inputs, initialValues []shapes.Shape, reductionInputs, reducti
 		// Effective window dimension after window dilation.
 		effectiveWindowDim := (windowDim-1)*windowDilation + 1
 
-		// Padded effective input size for this dimension.
+		// Padded effective input size for this dimension, smaller than the effective input dimension if the
+		// paddings are negative.
 		paddedEffectiveInputDim := effectiveInputDim + paddingLow + paddingHigh
 
 		// Numerator for the output dimension formula.
 		// output_dim = floor((padded_input_size - effective_window_size) / stride) + 1
 		// The numerator must be non-negative for the output dimension to be at least 1.
 		if effectiveWindowDim > paddedEffectiveInputDim {
-			return nil, errors.Errorf(
+			return nil, errorf(ErrInvalidArgument,
 				"ReduceWindow: effective window dimension %d for axis %d is larger than padded effective input dimension %d. (input_dim: %d, base_dilation: %d, window_dim: %d, window_dilation: %d, padding: [%d,%d]) for operand shape %s",
 				effectiveWindowDim, i, paddedEffectiveInputDim, inputDim, baseDilation, windowDim, windowDilation, paddingLow, paddingHigh, operand)
 		}
@@ -1034,136 +1194,139 @@ func Convolve(input, kernel shapes.Shape,
 	outputBatchAxis, outputChannelsAxis int, outputSpatialAxes []int,
 	channelGroupCount, batchGroupCount int) (shapes.Shape, error) {
 	// Convenient error returns.
-	errorf := func(format string, args ...any) (shapes.Shape, error) {
-		return shapes.Invalid(), errors.Errorf("Convolve:  "+format, args...)
+	errorf := func(kind error, format string, args ...any) (shapes.Shape, error) {
+		return shapes.Invalid(), errorf(kind, "Convolve:  "+format, args...)
 	}
 
 	if !input.Ok() {
-		return errorf("invalid input (operand) shape %s", input)
+		return errorf(ErrInvalidShape, "invalid input (operand) shape %s", input)
 	}
 	if !kernel.Ok() {
-		return errorf("invalid kernel shape %s", kernel)
+		return errorf(ErrInvalidShape, "invalid kernel shape %s", kernel)
+	}
+	if input.HasBounds() || kernel.HasBounds() {
+		return errorf(ErrInvalidShape, "bounded dynamic shapes are not supported, got input %s and kernel %s", input, kernel)
 	}
 
 	// Check ranks.
 	rank := input.Rank()
 	spatialRank := rank - 2
 	if rank < 3 {
-		return errorf("input (operand) needs to be at least rank-3 with axes (in any order) batch, channels and spatial -- input shape is %s", input)
+		return errorf(ErrShapeMismatch, "input (operand) needs to be at least rank-3 with axes (in any order) batch, channels and spatial -- input shape is %s", input)
 	}
 	if kernel.Rank() != rank {
-		return errorf("input (operand) and kernel have different rank!? -- input shape is %s and kernel shape is %s", input, kernel)
+		return errorf(ErrShapeMismatch, "input (operand) and kernel have different rank!? -- input shape is %s and kernel shape is %s", input, kernel)
 	}
 
 	// Check axes configuration:
 	if len(inputSpatialAxes) != spatialRank {
-		return errorf("inputSpatialAxes (%v) must provide one value for each spatial axis (%d), input shape is %s",
+		return errorf(ErrShapeMismatch, "inputSpatialAxes (%v) must provide one value for each spatial axis (%d), input shape is %s",
 			inputSpatialAxes, spatialRank, input)
 	}
 	inputAxes := utils.SetWith(inputBatchAxis, inputChannelsAxis)
 	for _, inputAxis := range inputSpatialAxes {
 		if inputAxis < 0 || inputAxis >= rank {
-			return errorf("invalid input axes configuration (axis %d is out-of-bounds): batch=%d, channel=%d, spatial=%v", inputAxis, inputBatchAxis, inputChannelsAxis, inputSpatialAxes)
+			return errorf(ErrInvalidAxis, "invalid input axes configuration (axis %d is out-of-bounds): batch=%d, channel=%d, spatial=%v", inputAxis, inputBatchAxis, inputChannelsAxis, inputSpatialAxes)
 		}
 		inputAxes.Insert(inputAxis)
 	}
 	if len(inputAxes) != rank {
-		return errorf("duplicate input axes configuration: batch=%d, channel=%d, spatial=%v", inputBatchAxis, inputChannelsAxis, inputSpatialAxes)
+		return errorf(ErrInvalidAxis, "duplicate input axes configuration: batch=%d, channel=%d, spatial=%v", inputBatchAxis, inputChannelsAxis, inputSpatialAxes)
 	}
 
 	if len(kernelSpatialAxes) != spatialRank {
-		return shapes.Invalid(), errors.Errorf("Convolve: kernelSpatialAxes (%v) must provide one value for each spatial axis (%d), kernel shape is %s",
-			inputSpatialAxes, spatialRank, kernel)
+		return errorf(ErrShapeMismatch, "kernelSpatialAxes (%v) must provide one value for each spatial axis (%d), kernel shape is %s",
+			kernelSpatialAxes, spatialRank, kernel)
 	}
 	kernelAxes := utils.SetWith(kernelInputChannelsAxis, kernelOutputChannelsAxis)
 	for _, kernelAxis := range kernelSpatialAxes {
 		if kernelAxis < 0 || kernelAxis >= rank {
-			return errorf("invalid kernel axes configuration (axis %d is out-of-bounds): input channel=%d, output channel=%d, spatial=%v",
+			return errorf(ErrInvalidAxis, "invalid kernel axes configuration (axis %d is out-of-bounds): input channel=%d, output channel=%d, spatial=%v",
 				kernelAxis, kernelInputChannelsAxis, kernelOutputChannelsAxis, kernelSpatialAxes)
 		}
 		kernelAxes.Insert(kernelAxis)
 	}
 	if len(kernelAxes) != rank {
-		return errorf("duplicate kernel axes configuration: input channel=%d, output channel=%d, spatial=%v",
+		return errorf(ErrInvalidAxis, "duplicate kernel axes configuration: input channel=%d, output channel=%d, spatial=%v",
 			kernelInputChannelsAxis, kernelOutputChannelsAxis, kernelSpatialAxes)
 	}
 
 	if len(outputSpatialAxes) != spatialRank {
-		return errorf("outputSpatialAxes (%v) must have one value for each spatial axis (%d), input shape is %s",
+		return errorf(ErrShapeMismatch, "outputSpatialAxes (%v) must have one value for each spatial axis (%d), input shape is %s",
 			outputSpatialAxes, spatialRank, input)
 	}
 	outputAxes := utils.SetWith(outputBatchAxis, outputChannelsAxis)
 	for _, outputAxis := range outputSpatialAxes {
 		if outputAxis < 0 || outputAxis >= rank {
-			return errorf("invalid output axes configuration (axis %d is out-of-bounds): batch=%d, channels=%d, spatial=%v", outputAxis, outputBatchAxis, outputChannelsAxis, outputSpatialAxes)
+			return errorf(ErrInvalidAxis, "invalid output axes configuration (axis %d is out-of-bounds): batch=%d, channels=%d, spatial=%v", outputAxis, outputBatchAxis, outputChannelsAxis, outputSpatialAxes)
 		}
 		outputAxes.Insert(outputAxis)
 	}
 	if len(outputAxes) != rank {
-		return errorf("duplicate output axes configuration: batch=%d, channel=%d, spatial=%v",
+		return errorf(ErrInvalidAxis, "duplicate output axes configuration: batch=%d, channel=%d, spatial=%v",
 			outputBatchAxis, outputChannelsAxis, outputSpatialAxes)
 	}
 
 	// Check strides, paddings, inputDilations and kernelDilations.
 	if len(strides) != 0 && len(strides) != spatialRank {
-		return errorf("strides (%v) must either be nil or provide one value for each spatial axis (%d), input shape is %s",
+		return errorf(ErrShapeMismatch, "strides (%v) must either be nil or provide one value for each spatial axis (%d), input shape is %s",
 			strides, spatialRank, input.Shape())
 	}
 	if len(paddings) != 0 && len(paddings) != spatialRank {
-		return errorf("paddings (%v) must either be nil or provide one value for each spatial axis (%d), input shape is %s",
+		return errorf(ErrShapeMismatch, "paddings (%v) must either be nil or provide one value for each spatial axis (%d), input shape is %s",
 			paddings, spatialRank, input.Shape())
 	}
 	if len(inputDilations) != 0 && len(inputDilations) != spatialRank {
-		return errorf("inputDilations (%v) must either be nil or provide one value for each spatial axis (%d), input shape is %s",
+		return errorf(ErrShapeMismatch, "inputDilations (%v) must either be nil or provide one value for each spatial axis (%d), input shape is %s",
 			inputDilations, spatialRank, input.Shape())
 	}
 	for i, dilation := range inputDilations {
 		if dilation < 1 {
-			return errorf("inputDilations[%d]=%d must be >= 1 for input shape %s", i, dilation, input)
+			return errorf(ErrInvalidArgument, "inputDilations[%d]=%d must be >= 1 for input shape %s", i, dilation, input)
 		}
 	}
 	if len(kernelDilations) != 0 && len(kernelDilations) != spatialRank {
-		return errorf("kernelDilations (%v) must either be nil or provide one value for each spatial axis (%d), input shape is %s",
+		return errorf(ErrShapeMismatch, "kernelDilations (%v) must either be nil or provide one value for each spatial axis (%d), input shape is %s",
 			kernelDilations, spatialRank, input.Shape())
 	}
 	for i, dilation := range kernelDilations {
 		if dilation < 1 {
-			return errorf("kernelDilations[%d]=%d must be >= 1 for input shape %s", i, dilation, input)
+			return errorf(ErrInvalidArgument, "kernelDilations[%d]=%d must be >= 1 for input shape %s", i, dilation, input)
 		}
 	}
 
 	if channelGroupCount > 1 && batchGroupCount > 1 {
-		return errorf("at most one of channelGroupCount (%d) or batchGroupCount (%d) can be set to > 1", channelGroupCount, batchGroupCount)
+		return errorf(ErrInvalidArgument, "at most one of channelGroupCount (%d) or batchGroupCount (%d) can be set to > 1", channelGroupCount, batchGroupCount)
 	}
 
 	// Check that channels (feature dimensions) are valid.
 	inputChannels := input.Dim(inputChannelsAxis)
 	outputChannels := kernel.Dim(kernelOutputChannelsAxis)
 	if channelGroupCount < 1 {
-		return errorf("channelGroupCount=%d must be >= 1 for input shape %s", channelGroupCount, input)
+		return errorf(ErrInvalidArgument, "channelGroupCount=%d must be >= 1 for input shape %s", channelGroupCount, input)
 	}
 	if inputChannels%channelGroupCount != 0 {
-		return errorf("input channels dimension %d must be divisible by channelGroupCount %d", inputChannels, channelGroupCount)
+		return errorf(ErrShapeMismatch, "input channels dimension %d must be divisible by channelGroupCount %d", inputChannels, channelGroupCount)
 	}
 	if outputChannels%channelGroupCount != 0 {
-		return errorf("kernel output channels dimension %d must be divisible by channelGroupCount %d", outputChannels, channelGroupCount)
+		return errorf(ErrShapeMismatch, "kernel output channels dimension %d must be divisible by channelGroupCount %d", outputChannels, channelGroupCount)
 	}
 	kernelInputChannels := kernel.Dim(kernelInputChannelsAxis)
 	if inputChannels != kernelInputChannels*channelGroupCount {
-		return errorf("we must have inputChannels (=%d) = kernelInputChannels (=%d) * channelGroupCount (=%d) -- input shape is %s, kernel shape is %s",
+		return errorf(ErrInvalidArgument, "we must have inputChannels (=%d) = kernelInputChannels (=%d) * channelGroupCount (=%d) -- input shape is %s, kernel shape is %s",
 			inputChannels, kernelInputChannels, channelGroupCount, input, kernel)
 	}
 
 	// Check batchGroupCount.
 	inputBatch := input.Dim(inputBatchAxis)
 	if batchGroupCount < 1 {
-		return errorf("batchGroupCount=%d must be >= 1 for input shape %s", batchGroupCount, input)
+		return errorf(ErrInvalidArgument, "batchGroupCount=%d must be >= 1 for input shape %s", batchGroupCount, input)
 	}
 	if inputBatch%batchGroupCount != 0 {
-		return errorf("input batch dimension %d must be divisible by batchGroupCount %d", inputBatch, batchGroupCount)
+		return errorf(ErrShapeMismatch, "input batch dimension %d must be divisible by batchGroupCount %d", inputBatch, batchGroupCount)
 	}
 	if outputChannels%batchGroupCount != 0 {
-		return errorf("output channels dimension %d must be divisible by batchGroupCount %d", outputChannels, batchGroupCount)
+		return errorf(ErrShapeMismatch, "output channels dimension %d must be divisible by batchGroupCount %d", outputChannels, batchGroupCount)
 	}
 
 	// Find the output shape.
@@ -1195,7 +1358,7 @@ func Convolve(input, kernel shapes.Shape,
 
 		// Calculate outputDim of the convolution.
 		if stride < 1 {
-			return errorf("stride[%d]=%d must be >= 1 for input shape %s", spatialAxisIdx, stride, input)
+			return errorf(ErrInvalidArgument, "stride[%d]=%d must be >= 1 for input shape %s", spatialAxisIdx, stride, input)
 		}
 
 		// Calculate effective dimensions after dilations
@@ -1207,7 +1370,7 @@ func Convolve(input, kernel shapes.Shape,
 
 		// Calculate output dimension
 		if effectiveKernelDim > paddedEffectiveInputDim {
-			return errorf("effective kernel dimension %d for axis %d is larger than padded effective input dimension %d. "+
+			return errorf(ErrInvalidArgument, "effective kernel dimension %d for axis %d is larger than padded effective input dimension %d. "+
 				"(input_dim: %d, input_dilation: %d, filter_dim: %d, filter_dilation: %d, padding: [%d,%d]) for input shape %s",
 				effectiveKernelDim, inputAxis, paddedEffectiveInputDim, inputDim, inputDilation, kernelDim, kernelDilation,
 				padding[0], padding[1], input)
@@ -1220,10 +1383,40 @@ func Convolve(input, kernel shapes.Shape,
 	return output, nil
 }
 
+// withBoundsFrom returns the output with the bounds of its dynamic axes taken from the operand: operandAxes[axis] is
+// the operand axis the output axis comes from, or -1 if it doesn't come from the operand.
+// Static axes of the output have no bound, even if the operand axis they come from has one.
+func withBoundsFrom(output, operand shapes.Shape, operandAxes []int) shapes.Shape {
+	output.Bounds = nil
+	if !operand.HasBounds() {
+		return output
+	}
+	output.Bounds = make([]int, output.Rank())
+	for axis, operandAxis := range operandAxes {
+		output.Bounds[axis] = shapes.DynamicDim
+		if operandAxis >= 0 && output.Dimensions[axis] == shapes.DynamicDim {
+			output.Bounds[axis] = operand.Bounds[operandAxis]
+		}
+	}
+	if !output.HasBounds() {
+		output.Bounds = nil
+	}
+	return output
+}
+
+// checkNoBounds returns an error if the shape has bounds (see shapes.Shape.WithBounds), for the operations whose
+// shape inference doesn't carry them to the outputs.
+func checkNoBounds(opName, operandName string, shape shapes.Shape) error {
+	if shape.HasBounds() {
+		return errorf(ErrInvalidShape, "%s doesn't support bounded dynamic shapes, got %s %s", opName, operandName, shape)
+	}
+	return nil
+}
+
 // AdjustAxisToRank returns a positive axis, adjusting negative numbers to the correct rank.
 func AdjustAxisToRank(axis, rank int) (int, error) {
 	if axis < -rank || axis >= rank {
-		return -1, errors.Errorf("axis %d is out of range for the rank %d", axis, rank)
+		return -1, errorf(ErrInvalidAxis, "axis %d is out of range for the rank %d", axis, rank)
 	}
 	if axis < 0 {
 		axis += rank
@@ -1231,6 +1424,21 @@ func AdjustAxisToRank(axis, rank int) (int, error) {
 	return axis, nil
 }
 
+// checkUniqueAxes checks that the (already adjusted) contracting and batch axes of a DotGeneral operand don't repeat.
+func checkUniqueAxes(operandName string, rank int, contractingAxes, batchAxes []int) error {
+	used := make([]bool, rank)
+	for _, axes := range [][]int{contractingAxes, batchAxes} {
+		for _, axis := range axes {
+			if used[axis] {
+				return errorf(ErrInvalidAxis, "DotGeneral %s axis %d used more than once (contractingAxes=%v, batchAxes=%v)",
+					operandName, axis, contractingAxes, batchAxes)
+			}
+			used[axis] = true
+		}
+	}
+	return nil
+}
+
 // DotGeneral returns the shape resulting from the corresponding operations.
 //
 // It also has a side effect on the axes' specifications: it converts negative axes to their
@@ -1241,17 +1449,24 @@ func DotGeneral(
 	outputDType dtypes.DType) (output shapes.Shape, err error) {
 	dtype := lhs.DType
 	if dtype != rhs.DType {
-		err = errors.Errorf("DotGeneral lhs (left-hand-side) and rhs operands don't match data types: %s and %s", dtype, rhs.DType)
+		err = errorf(ErrWrongDType, "DotGeneral lhs (left-hand-side) and rhs operands don't match data types: %s and %s", dtype, rhs.DType)
 		return
 	}
 	if len(lhsContractingAxes) != len(rhsContractingAxes) {
-		err = errors.Errorf("DotGeneral number of contracting axes for lhs (%d) doesn't match rhs (%d)",
+		err = errorf(ErrShapeMismatch, "DotGeneral number of contracting axes for lhs (%d) doesn't match rhs (%d)",
 			len(lhsContractingAxes), len(rhsContractingAxes))
 		return
 	}
 	if len(lhsBatchAxes) != len(rhsBatchAxes) {
-		err = errors.Errorf("DotGeneral number of contracting axes for lhs (%d) doesn't match rhs (%d)",
-			len(lhsContractingAxes), len(rhsContractingAxes))
+		err = errorf(ErrShapeMismatch, "DotGeneral number of batch axes for lhs (%d) doesn't match rhs (%d)",
+			len(lhsBatchAxes), len(rhsBatchAxes))
+		return
+	}
+	if err = checkNoBounds("DotGeneral", "lhs", lhs); err != nil {
+		return
+	}
+	if err = checkNoBounds("DotGeneral", "rhs", rhs); err != nil {
+		return
 	}
 	lhsRank := lhs.Rank()
 	rhsRank := rhs.Rank()
@@ -1286,13 +1501,21 @@ func DotGeneral(
 		}
 	}
 
+	// Check that no axis is used more than once, as contracting or batch axis.
+	if err = checkUniqueAxes("lhs", lhsRank, lhsContractingAxes, lhsBatchAxes); err != nil {
+		return
+	}
+	if err = checkUniqueAxes("rhs", rhsRank, rhsContractingAxes, rhsBatchAxes); err != nil {
+		return
+	}
+
 	// Check that batch and contracting dimensions from lhs and rhs match.
 	batchDims := make([]int, len(lhsBatchAxes))
 	contractingDims := make([]int, len(lhsContractingAxes))
 	for ii, lhsAxis := range lhsContractingAxes {
 		rhsAxis := rhsContractingAxes[ii]
 		if lhs.Dimensions[lhsAxis] != rhs.Dimensions[rhsAxis] {
-			err = errors.Errorf("DotGeneral contracting dimensions don't match: lhs[%d]=%d != rhs[%d]=%d",
+			err = errorf(ErrShapeMismatch, "DotGeneral contracting dimensions don't match: lhs[%d]=%d != rhs[%d]=%d",
 				lhsAxis, lhs.Dimensions[lhsAxis], rhsAxis, rhs.Dimensions[rhsAxis])
 			return
 		}
@@ -1301,7 +1524,7 @@ func DotGeneral(
 	for ii, lhsAxis := range lhsBatchAxes {
 		rhsAxis := rhsBatchAxes[ii]
 		if lhs.Dimensions[lhsAxis] != rhs.Dimensions[rhsAxis] {
-			err = errors.Errorf("DotGeneral batch dimensions don't match: lhs[%d]=%d != rhs[%d]=%d",
+			err = errorf(ErrShapeMismatch, "DotGeneral batch dimensions don't match: lhs[%d]=%d != rhs[%d]=%d",
 				lhsAxis, lhs.Dimensions[lhsAxis], rhsAxis, rhs.Dimensions[rhsAxis])
 			return
 		}
@@ -1315,7 +1538,7 @@ func DotGeneral(
 
 	// Check that all sizes are positive
 	if batchSize < 0 || lhsCrossSize < 0 || contractingSize < 0 || rhsCrossSize < 0 {
-		err = errors.Errorf("DotGeneral sizes must be positive: lhs(batch=%d, cross=%d, contracting=%d), rhs(cross=%d)",
+		err = errorf(ErrInvalidArgument, "DotGeneral sizes must be positive: lhs(batch=%d, cross=%d, contracting=%d), rhs(cross=%d)",
 			batchSize, lhsCrossSize, contractingSize, rhsCrossSize)
 		return
 	}
@@ -1359,10 +1582,15 @@ func dotGeneralFindSizes(shape shapes.Shape, contractingAxes, batchAxes []int) (
 	return
 }
 
+// IsFinite returns the output shape of the IsFinite operation: the shape of the operand with Bool dtype.
+//
+// The operand must have a floating-point dtype, including the 8-bit float types (F8E4M3FN, F8E5M2, ...).
+// Complex numbers are not supported.
 func IsFinite(operand shapes.Shape) (output shapes.Shape, err error) {
 	dtype := operand.DType
-	if !dtype.IsFloat() {
-		err = errors.Errorf("IsFinite: operand data type %s is a floating point type", dtype)
+	if !utils.DTypeIsFloat(dtype) {
+		err = errorf(ErrWrongDType, "IsFinite: operand data type %s is not a floating point type (Float32, BFloat16, F8E4M3FN, ...)",
+			dtype)
 		return
 	}
 	output = operand.Clone()
@@ -1370,50 +1598,92 @@ func IsFinite(operand shapes.Shape) (output shapes.Shape, err error) {
 	return
 }
 
+// RNG returns the output shape of the legacy RNG operation, with the dtype of the scalars a and b, and the given
+// (static) dimensions.
+func RNG(a, b shapes.Shape, dimensions []int, distribution types.RNGDistribution) (output shapes.Shape, err error) {
+	if !a.IsScalar() || !b.IsScalar() {
+		err = errorf(ErrShapeMismatch, "RNG requires a and b to be scalars, got %s and %s", a, b)
+		return
+	}
+	if a.DType != b.DType {
+		err = errorf(ErrWrongDType, "RNG requires a and b to have the same dtype, got %s and %s", a, b)
+		return
+	}
+	dtype := a.DType
+	switch distribution {
+	case types.RNGUniform:
+		if !dtype.IsFloat() && !dtype.IsInt() && dtype != dtypes.Bool {
+			err = errorf(ErrWrongDType, "RNG with the UNIFORM distribution requires an integer, bool or float dtype, got %s", dtype)
+			return
+		}
+	case types.RNGNormal:
+		if !dtype.IsFloat() {
+			err = errorf(ErrWrongDType, "RNG with the NORMAL distribution requires a float dtype, got %s", dtype)
+			return
+		}
+	default:
+		err = errorf(ErrInvalidArgument, "RNG: invalid distribution %d", distribution)
+		return
+	}
+	for axis, dim := range dimensions {
+		if dim < 0 {
+			err = errorf(ErrInvalidArgument, "RNG requires non-negative static dimensions, got %v (axis %d)", dimensions, axis)
+			return
+		}
+	}
+	output = shapes.Make(dtype, dimensions...)
+	return
+}
+
 // Reduce returns the operation's output shapes and checks all shapes and dtypes are valid.
 // The axes are also normalized to positive in-place.
 func Reduce(inputs, initialValues, reductionInputs, reductionOutputs []shapes.Shape, axes []int) (outputs []shapes.Shape, err error) {
 	// Check inputs and initialValues.
 	numReductions := len(inputs)
 	if numReductions == 0 {
-		return nil, errors.New("Reduce requires at least one input")
+		return nil, errorf(ErrInvalidArgument, "Reduce requires at least one input")
 	}
 	if len(initialValues) != numReductions {
-		return nil, errors.Errorf("Reduce requires the same number of initial values as inputs, got %d initial values and %d inputs",
+		return nil, errorf(ErrShapeMismatch, "Reduce requires the same number of initial values as inputs, got %d initial values and %d inputs",
 			len(initialValues), len(inputs))
 	}
 	baseDimensions := inputs[0].Dimensions
 	for i, input := range inputs {
 		if input.DType != initialValues[i].DType {
-			return nil, errors.Errorf("Reduce requires the same dtype for initial values and inputs, got %s and %s for input #%d",
+			return nil, errorf(ErrWrongDType, "Reduce requires the same dtype for initial values and inputs, got %s and %s for input #%d",
 				initialValues[i].DType, input.DType, i)
 		}
 		if !slices.Equal(input.Dimensions, baseDimensions) {
-			return nil, errors.Errorf("Reduce requires the same shape (dimensions only) for all inputs, got %s and %s for inputs #0 and #%d",
+			return nil, errorf(ErrShapeMismatch, "Reduce requires the same shape (dimensions only) for all inputs, got %s and %s for inputs #0 and #%d",
 				inputs[0], input, i)
 		}
 	}
 
 	// Check that all reduction inputs and outputs are valid.
 	if len(reductionInputs) != 2*numReductions {
-		return nil, errors.Errorf("The reduction function for the Reduce operation must have 2 inputs for each initialValue, but reduction has %d inputs for 2*%d=%d initial values",
+		return nil, errorf(ErrInvalidArgument, "The reduction function for the Reduce operation must have 2 inputs for each initialValue, but reduction has %d inputs for 2*%d=%d initial values",
 			len(reductionInputs), len(initialValues), 2*len(initialValues))
 	}
 	if len(reductionOutputs) != numReductions {
-		return nil, errors.Errorf("The reduction function for the Reduce operation must have 1 output for each initialValue, but reduction has %d outputs for %d initial values",
+		return nil, errorf(ErrInvalidArgument, "The reduction function for the Reduce operation must have 1 output for each initialValue, but reduction has %d outputs for %d initial values",
 			len(reductionOutputs), len(initialValues))
 	}
 	for i := range numReductions {
 		if reductionInputs[i].DType != reductionInputs[i+numReductions].DType || reductionInputs[i].DType != reductionOutputs[i].DType {
-			return nil, errors.Errorf("Reduce requires the same dtype for lhs[i], rhs[i] inputs and output[i], got lhs[%d]=%s and rhs[%d+%d]=%s and output[%d]=%s",
+			return nil, errorf(ErrWrongDType, "Reduce requires the same dtype for lhs[i], rhs[i] inputs and output[i], got lhs[%d]=%s and rhs[%d+%d]=%s and output[%d]=%s",
 				i, reductionInputs[i], i, numReductions, reductionInputs[i+numReductions], i, reductionOutputs[i])
 		}
+		// The inputs can be promoted to the dtype of the reduction function, which is the dtype of the output.
+		if !inputs[i].DType.IsPromotableTo(reductionOutputs[i].DType) {
+			return nil, errorf(ErrWrongDType, "Reduce input #%d dtype %s is not promotable to the reduction function dtype %s",
+				i, inputs[i].DType, reductionOutputs[i].DType)
+		}
 	}
 
 	// Check the axis are valid.
 	rank := inputs[0].Rank()
 	if len(axes) > rank {
-		return nil, errors.Errorf("input for Reduce has rank=%d, but %d axes for reduction were given", rank, len(axes))
+		return nil, errorf(ErrShapeMismatch, "input for Reduce has rank=%d, but %d axes for reduction were given", rank, len(axes))
 	}
 	axesSet := utils.MakeSet[int]()
 	for i, axis := range axes {
@@ -1423,7 +1693,7 @@ func Reduce(inputs, initialValues, reductionInputs, reductionOutputs []shapes.Sh
 				i, axis, inputs[0])
 		}
 		if axesSet.Has(adjustedAxis) {
-			return nil, errors.Errorf("duplicate value for axes[%d]=%d for Reduce, axes=%v)",
+			return nil, errorf(ErrInvalidAxis, "duplicate value for axes[%d]=%d for Reduce, axes=%v)",
 				i, axis, axes)
 		}
 		axesSet.Insert(adjustedAxis)
@@ -1432,70 +1702,93 @@ func Reduce(inputs, initialValues, reductionInputs, reductionOutputs []shapes.Sh
 
 	// Build the output shapes.
 	reducedDims := slices.Clone(inputs[0].Dimensions)
-	var toAxis int
+	keptAxes := make([]int, 0, rank)
 	for axis, dim := range reducedDims {
 		if axesSet.Has(axis) {
 			// This axis will be reduced, and it disappears from the output shape.
 			continue
 		}
-		reducedDims[toAxis] = dim
-		toAxis++
+		reducedDims[len(keptAxes)] = dim
+		keptAxes = append(keptAxes, axis)
 	}
-	reducedDims = reducedDims[:toAxis]
+	reducedDims = reducedDims[:len(keptAxes)]
 	outputs = make([]shapes.Shape, len(inputs))
 	for ii, outputBase := range reductionOutputs {
-		outputs[ii] = shapes.Make(outputBase.DType, reducedDims...)
+		outputs[ii] = withBoundsFrom(shapes.Make(outputBase.DType, reducedDims...), inputs[ii], keptAxes)
 	}
 	return
 }
 
 func BitcastConvert(operand shapes.Shape, targetDType dtypes.DType) (outputShape shapes.Shape, err error) {
 	if operand.DType == dtypes.INVALID {
-		return shapes.Invalid(), errors.New("BitcastConvert: operand data type is invalid")
+		return shapes.Invalid(), errorf(ErrInvalidShape, "BitcastConvert: operand data type is invalid")
 	}
 	sourceDType := operand.DType
 	outputShape = operand.Clone()
 	outputShape.DType = targetDType
-	if sourceDType.Bits() == targetDType.Bits() {
+	if sourceDType == targetDType {
+		return
+	}
+	// Booleans (i1) are stored in a byte, but have a bit width of 1: bitcasting them is not allowed, since it
+	// would depend on the (platform dependent) storage.
+	if sourceDType == dtypes.Bool || targetDType == dtypes.Bool {
+		return shapes.Invalid(), errorf(ErrWrongDType, "BitcastConvert: cannot bitcast from %s to %s, booleans can only be "+
+			"converted with Convert", sourceDType, targetDType)
+	}
+	if sourceDType.IsComplex() != targetDType.IsComplex() {
+		return shapes.Invalid(), errorf(ErrWrongDType, "BitcastConvert: cannot bitcast between complex and non-complex dtypes "+
+			"(%s to %s)", sourceDType, targetDType)
+	}
+	sourceBits, targetBits := utils.DTypeBitWidth(sourceDType), utils.DTypeBitWidth(targetDType)
+	if sourceBits == targetBits {
 		// No changes in shape.
 		return
 	}
-	if sourceDType.Bits() > targetDType.Bits() {
+	if sourceBits > targetBits {
 		// Convert to a smaller data type, append to a new dimension.
-		newDim := sourceDType.Bits() / targetDType.Bits()
+		newDim := sourceBits / targetBits
 		outputShape.Dimensions = append(outputShape.Dimensions, newDim)
+		if outputShape.HasBounds() {
+			outputShape.Bounds = append(outputShape.Bounds, shapes.DynamicDim)
+		}
 		return
 	}
 
 	// Convert to a larger data type, shrink the last dimension.
-	if outputShape.Dim(-1) != (targetDType.Bits()+sourceDType.Bits()-1)/sourceDType.Bits() {
-		return shapes.Invalid(), errors.Errorf("BitcastConvert: cannot convert from %d x %s (%d bits) to %s (%d bits)",
-			outputShape.Dim(-1), sourceDType, sourceDType.Bits(), targetDType, targetDType.Bits())
+	if operand.Rank() == 0 || outputShape.Dim(-1)*sourceBits != targetBits {
+		return shapes.Invalid(), errorf(ErrShapeMismatch, "BitcastConvert: cannot convert from %s (%d bits) to %s (%d bits), "+
+			"the last axis must have dimension %d", operand, sourceBits, targetDType, targetBits, targetBits/sourceBits)
 	}
 	outputShape.Dimensions = outputShape.Dimensions[:len(outputShape.Dimensions)-1]
+	if outputShape.HasBounds() {
+		outputShape.Bounds = outputShape.Bounds[:len(outputShape.Bounds)-1]
+	}
 	return
 }
 
 func Pad(x, fill shapes.Shape, paddingStart, paddingEnd, paddingInterior []int) (outputShape shapes.Shape, err error) {
 	if !x.Ok() || !fill.Ok() {
-		return shapes.Invalid(), errors.Errorf("Pad: invalid input shapes %s and %s", x, fill)
+		return shapes.Invalid(), errorf(ErrInvalidShape, "Pad: invalid input shapes %s and %s", x, fill)
 	}
 	if x.DType != fill.DType {
-		return shapes.Invalid(), errors.Errorf("Pad: operand (%s) and padding value (%s) must have the same dtype", x, fill)
+		return shapes.Invalid(), errorf(ErrWrongDType, "Pad: operand (%s) and padding value (%s) must have the same dtype", x, fill)
 	}
 	if !fill.IsScalar() {
-		return shapes.Invalid(), errors.Errorf("Pad: padding value (%s) must be a scalar", fill)
+		return shapes.Invalid(), errorf(ErrShapeMismatch, "Pad: padding value (%s) must be a scalar", fill)
+	}
+	if err := checkNoBounds("Pad", "operand", x); err != nil {
+		return shapes.Invalid(), err
 	}
 	rank := x.Rank()
 	if len(paddingStart) != rank || len(paddingEnd) != rank || len(paddingInterior) != rank {
-		return shapes.Invalid(), errors.Errorf("Pad: number of padding values (%d, %d, %d) must match input rank %d",
+		return shapes.Invalid(), errorf(ErrShapeMismatch, "Pad: number of padding values (%d, %d, %d) must match input rank %d",
 			len(paddingStart), len(paddingEnd), len(paddingInterior), rank)
 	}
 
 	// Check that interior padding values are non-negative.
 	for axis := range rank {
 		if paddingInterior[axis] < 0 {
-			return shapes.Invalid(), errors.Errorf("Pad: interior padding values must be non-negative, got start=%d, end=%d, interior=%d for axis %d",
+			return shapes.Invalid(), errorf(ErrInvalidArgument, "Pad: interior padding values must be non-negative, got start=%d, end=%d, interior=%d for axis %d",
 				paddingStart[axis], paddingEnd[axis], paddingInterior[axis], axis)
 		}
 	}
@@ -1509,23 +1802,73 @@ func Pad(x, fill shapes.Shape, paddingStart, paddingEnd, paddingInterior []int)
 		} else {
 			outputDims[axis] = paddingStart[axis] + paddingEnd[axis] + inputDim + (inputDim-1)*paddingInterior[axis]
 		}
+		if outputDims[axis] < 0 {
+			return shapes.Invalid(), errorf(ErrInvalidArgument, "Pad: negative padding (start=%d, end=%d) larger than the padded dimension %d for axis %d",
+				paddingStart[axis], paddingEnd[axis], outputDims[axis]-paddingStart[axis]-paddingEnd[axis], axis)
+		}
 	}
 	return shapes.Make(x.DType, outputDims...), nil
 }
 
+// DynamicPad returns the output shape of a dynamic_pad operation: like Pad, but the paddings are given by 1D
+// integer tensors (one value per axis of x), only known at runtime. So all axes of the output are dynamic
+// (shapes.DynamicDim), without bounds.
+func DynamicPad(x, fill, paddingStart, paddingEnd, paddingInterior shapes.Shape) (outputShape shapes.Shape, err error) {
+	if !x.Ok() || !fill.Ok() || x.IsTuple() {
+		return shapes.Invalid(), errorf(ErrInvalidShape, "DynamicPad: invalid input shapes %s and %s", x, fill)
+	}
+	if x.DType != fill.DType {
+		return shapes.Invalid(), errorf(ErrWrongDType, "DynamicPad: operand (%s) and padding value (%s) must have the same dtype", x, fill)
+	}
+	if !fill.IsScalar() {
+		return shapes.Invalid(), errorf(ErrShapeMismatch, "DynamicPad: padding value (%s) must be a scalar", fill)
+	}
+	for i, padding := range []shapes.Shape{paddingStart, paddingEnd, paddingInterior} {
+		name := []string{"paddingStart", "paddingEnd", "paddingInterior"}[i]
+		if err := checkIndicesVector("DynamicPad", name, padding, x.Rank()); err != nil {
+			return shapes.Invalid(), err
+		}
+		if padding.DType != paddingStart.DType {
+			return shapes.Invalid(), errorf(ErrWrongDType, "DynamicPad: paddings must have the same dtype, got %s and %s",
+				paddingStart, padding)
+		}
+	}
+	outputShape = x.Clone()
+	outputShape.Bounds = nil
+	for axis := range outputShape.Dimensions {
+		outputShape.Dimensions[axis] = shapes.DynamicDim
+	}
+	return outputShape, nil
+}
+
+// checkIndicesVector checks that a runtime parameter of the op is a static 1D integer tensor with size values.
+func checkIndicesVector(op, name string, vector shapes.Shape, size int) error {
+	if !vector.Ok() || vector.IsTuple() {
+		return errorf(ErrInvalidShape, "%s: invalid %s shape %s", op, name, vector)
+	}
+	if !vector.DType.IsInt() {
+		return errorf(ErrWrongDType, "%s: %s must be an integer tensor, got %s", op, name, vector)
+	}
+	if vector.Rank() != 1 || vector.Dimensions[0] != size {
+		return errorf(ErrShapeMismatch, "%s: %s must be a 1D tensor with %d values (one per operand axis), got %s",
+			op, name, size, vector)
+	}
+	return nil
+}
+
 func FFT(x shapes.Shape, fftType types.FFTType, fftLength []int) (output shapes.Shape, err error) {
 	if !x.Ok() {
-		return shapes.Invalid(), errors.Errorf("FFT: invalid input shape %s", x)
+		return shapes.Invalid(), errorf(ErrInvalidShape, "FFT: invalid input shape %s", x)
 	}
 
 	// Check the FFT lengths are valid and match the input rank.
 	rank := x.Rank()
 	if len(fftLength) > rank {
-		return shapes.Invalid(), errors.Errorf("FFT: number of FFT lengths (%d) cannot exceed input rank (%d)", len(fftLength), rank)
+		return shapes.Invalid(), errorf(ErrShapeMismatch, "FFT: number of FFT lengths (%d) cannot exceed input rank (%d)", len(fftLength), rank)
 	}
 	for i, length := range fftLength {
 		if length <= 0 {
-			return shapes.Invalid(), errors.Errorf("FFT: fftLength[%d]=%d must be positive", i, length)
+			return shapes.Invalid(), errorf(ErrInvalidArgument, "FFT: fftLength[%d]=%d must be positive", i, length)
 		}
 	}
 
@@ -1533,18 +1876,18 @@ func FFT(x shapes.Shape, fftType types.FFTType, fftLength []int) (output shapes.
 	switch fftType {
 	case types.FFTForward, types.FFTInverse:
 		if !x.DType.IsComplex() {
-			return shapes.Invalid(), errors.Errorf("FFT: FFTForward and FFTInverse require complex input, got %s", x.DType)
+			return shapes.Invalid(), errorf(ErrWrongDType, "FFT: FFTForward and FFTInverse require complex input, got %s", x.DType)
 		}
 	case types.FFTForwardReal:
 		if !x.DType.IsFloat() {
-			return shapes.Invalid(), errors.Errorf("FFT: FFTForwardReal requires real (float) input, got %s", x.DType)
+			return shapes.Invalid(), errorf(ErrWrongDType, "FFT: FFTForwardReal requires real (float) input, got %s", x.DType)
 		}
 	case types.FFTInverseReal:
 		if !x.DType.IsComplex() {
-			return shapes.Invalid(), errors.Errorf("FFT: FFTInverseReal requires complex input, got %s", x.DType)
+			return shapes.Invalid(), errorf(ErrWrongDType, "FFT: FFTInverseReal requires complex input, got %s", x.DType)
 		}
 	default:
-		return shapes.Invalid(), errors.Errorf("FFT: invalid FFT type %d", fftType)
+		return shapes.Invalid(), errorf(ErrInvalidArgument, "FFT: invalid FFT type %d", fftType)
 	}
 
 	// Calculate output shape:
@@ -1557,7 +1900,7 @@ func FFT(x shapes.Shape, fftType types.FFTType, fftLength []int) (output shapes.
 	case types.FFTForwardReal:
 		// Output is complex, with the last FFT dimension halved and rounded up.
 		if len(fftLength) == 0 {
-			return shapes.Invalid(), errors.New("FFT: FFTForwardReal requires at least one FFT length")
+			return shapes.Invalid(), errorf(ErrInvalidArgument, "FFT: FFTForwardReal requires at least one FFT length")
 		}
 		lastFFTDim := fftLength[len(fftLength)-1]
 		output.Dimensions[output.Rank()-1] = lastFFTDim/2 + 1
@@ -1571,11 +1914,11 @@ func FFT(x shapes.Shape, fftType types.FFTType, fftLength []int) (output shapes.
 	case types.FFTInverseReal:
 		// Input must be complex with the last axis dimension being fftLength/2+1
 		if len(fftLength) == 0 {
-			return shapes.Invalid(), errors.New("FFT: FFTInverseReal requires at least one FFT length")
+			return shapes.Invalid(), errorf(ErrInvalidArgument, "FFT: FFTInverseReal requires at least one FFT length")
 		}
 		lastFFTDim := fftLength[len(fftLength)-1]
 		if x.Dim(-1) != lastFFTDim/2+1 {
-			return shapes.Invalid(), errors.Errorf("FFT: FFTInverseReal input dimension %d must be equal to fftLength/2+1=%d",
+			return shapes.Invalid(), errorf(ErrShapeMismatch, "FFT: FFTInverseReal input dimension %d must be equal to fftLength/2+1=%d",
 				x.Dim(-1), lastFFTDim/2+1)
 		}
 		output.Dimensions[output.Rank()-1] = lastFFTDim
@@ -1585,23 +1928,34 @@ func FFT(x shapes.Shape, fftType types.FFTType, fftLength []int) (output shapes.
 		case dtypes.Complex128:
 			output.DType = dtypes.Float64
 		default:
-			return shapes.Invalid(), errors.Errorf("FFT: FFTInverseReal dtype not supported: %s", output.DType)
+			return shapes.Invalid(), errorf(ErrWrongDType, "FFT: FFTInverseReal dtype not supported: %s", output.DType)
 		}
 
 	default:
-		return shapes.Invalid(), errors.Errorf("FFT: FFTType=%s not supported", fftType)
+		return shapes.Invalid(), errorf(ErrInvalidArgument, "FFT: FFTType=%s not supported", fftType)
 	}
+	// The last axis is now static.
+	output = withBoundsFrom(output, x, identityAxes(rank))
 	return
 }
 
+// identityAxes returns the axes 0 to rank-1.
+func identityAxes(rank int) []int {
+	axes := make([]int, rank)
+	for axis := range axes {
+		axes[axis] = axis
+	}
+	return axes
+}
+
 // CollectiveBroadcast returns the output shape for a collective_broadcast operation.
 // The output shape is identical to the operand shape.
 func CollectiveBroadcast(operand shapes.Shape, replicaGroups [][]int) (output shapes.Shape, err error) {
 	if !operand.Ok() {
-		return shapes.Invalid(), errors.Errorf("CollectiveBroadcast: invalid operand shape %s", operand)
+		return shapes.Invalid(), errorf(ErrInvalidShape, "CollectiveBroadcast: invalid operand shape %s", operand)
 	}
 	if len(replicaGroups) == 0 {
-		return shapes.Invalid(), errors.New("CollectiveBroadcast: replica_groups cannot be empty")
+		return shapes.Invalid(), errorf(ErrInvalidArgument, "CollectiveBroadcast: replica_groups cannot be empty")
 	}
 	// TODO: Add more validation for replicaGroups if needed.
 	return operand.Clone(), nil
@@ -1610,13 +1964,16 @@ func CollectiveBroadcast(operand shapes.Shape, replicaGroups [][]int) (output sh
 // AllGather returns the output shape for an all_gather operation.
 func AllGather(operand shapes.Shape, replicaGroups [][]int, allGatherDim int) (output shapes.Shape, err error) {
 	if !operand.Ok() {
-		return shapes.Invalid(), errors.Errorf("AllGather: invalid operand shape %s", operand)
+		return shapes.Invalid(), errorf(ErrInvalidShape, "AllGather: invalid operand shape %s", operand)
 	}
 	if len(replicaGroups) == 0 {
-		return shapes.Invalid(), errors.New("AllGather: replica_groups cannot be empty")
+		return shapes.Invalid(), errorf(ErrInvalidArgument, "AllGather: replica_groups cannot be empty")
 	}
 	if allGatherDim < 0 || allGatherDim >= operand.Rank() {
-		return shapes.Invalid(), errors.Errorf("AllGather: all_gather_dim %d is out of bounds for operand rank %d", allGatherDim, operand.Rank())
+		return shapes.Invalid(), errorf(ErrInvalidAxis, "AllGather: all_gather_dim %d is out of bounds for operand rank %d", allGatherDim, operand.Rank())
+	}
+	if err := checkNoBounds("AllGather", "operand", operand); err != nil {
+		return shapes.Invalid(), err
 	}
 
 	output = operand.Clone()
@@ -1628,22 +1985,25 @@ func AllGather(operand shapes.Shape, replicaGroups [][]int, allGatherDim int) (o
 // AllToAll returns the output shape for an all_to_all operation.
 func AllToAll(operand shapes.Shape, replicaGroups [][]int, splitDimension, concatDimension, splitCount int) (output shapes.Shape, err error) {
 	if !operand.Ok() {
-		return shapes.Invalid(), errors.Errorf("AllToAll: invalid operand shape %s", operand)
+		return shapes.Invalid(), errorf(ErrInvalidShape, "AllToAll: invalid operand shape %s", operand)
 	}
 	if len(replicaGroups) == 0 {
-		return shapes.Invalid(), errors.New("AllToAll: replica_groups cannot be empty")
+		return shapes.Invalid(), errorf(ErrInvalidArgument, "AllToAll: replica_groups cannot be empty")
 	}
 	if splitDimension < 0 || splitDimension >= operand.Rank() {
-		return shapes.Invalid(), errors.Errorf("AllToAll: split_dimension %d is out of bounds for operand rank %d", splitDimension, operand.Rank())
+		return shapes.Invalid(), errorf(ErrInvalidAxis, "AllToAll: split_dimension %d is out of bounds for operand rank %d", splitDimension, operand.Rank())
 	}
 	if concatDimension < 0 || concatDimension >= operand.Rank() {
-		return shapes.Invalid(), errors.Errorf("AllToAll: concat_dimension %d is out of bounds for operand rank %d", concatDimension, operand.Rank())
+		return shapes.Invalid(), errorf(ErrInvalidAxis, "AllToAll: concat_dimension %d is out of bounds for operand rank %d", concatDimension, operand.Rank())
 	}
 	if splitCount <= 0 {
-		return shapes.Invalid(), errors.Errorf("AllToAll: split_count %d must be positive", splitCount)
+		return shapes.Invalid(), errorf(ErrInvalidArgument, "AllToAll: split_count %d must be positive", splitCount)
+	}
+	if err := checkNoBounds("AllToAll", "operand", operand); err != nil {
+		return shapes.Invalid(), err
 	}
 	if operand.Dimensions[splitDimension]%splitCount != 0 {
-		return shapes.Invalid(), errors.Errorf("AllToAll: split_dimension size %d is not divisible by split_count %d", operand.Dimensions[splitDimension], splitCount)
+		return shapes.Invalid(), errorf(ErrShapeMismatch, "AllToAll: split_dimension size %d is not divisible by split_count %d", operand.Dimensions[splitDimension], splitCount)
 	}
 
 	output = operand.Clone()
@@ -1655,10 +2015,10 @@ func AllToAll(operand shapes.Shape, replicaGroups [][]int, splitDimension, conca
 // CollectivePermute returns the output shape for a collective_permute operation.
 func CollectivePermute(operand shapes.Shape, sourceTargetPairs [][2]int) (output shapes.Shape, err error) {
 	if !operand.Ok() {
-		return shapes.Invalid(), errors.Errorf("CollectivePermute: invalid operand shape %s", operand)
+		return shapes.Invalid(), errorf(ErrInvalidShape, "CollectivePermute: invalid operand shape %s", operand)
 	}
 	if len(sourceTargetPairs) == 0 {
-		return shapes.Invalid(), errors.New("CollectivePermute: source_target_pairs cannot be empty")
+		return shapes.Invalid(), errorf(ErrInvalidArgument, "CollectivePermute: source_target_pairs cannot be empty")
 	}
 	return operand.Clone(), nil
 }
@@ -1670,36 +2030,36 @@ func AllReduce(operands []shapes.Shape, reductionInputs, reductionOutputs []shap
 	outputs []shapes.Shape, err error) {
 	numOperands := len(operands)
 	if numOperands == 0 {
-		return nil, errors.New("requires at least one operand")
+		return nil, errorf(ErrInvalidArgument, "requires at least one operand")
 	}
 	dtype := operands[0].DType
 	for i, operand := range operands {
 		if !operand.Ok() {
-			return nil, errors.Errorf("invalid operand[%d] shape %s",
+			return nil, errorf(ErrInvalidShape, "invalid operand[%d] shape %s",
 				i, operand)
 		}
 		if operand.DType != dtype {
-			return nil, errors.Errorf(
+			return nil, errorf(ErrWrongDType,
 				"operand[%d] dtype %s does not match dtype %s for all operands",
 				i, operand.DType, dtype)
 		}
 	}
 	if len(replicaGroups) == 0 {
-		return nil, errors.New("replica_groups cannot be empty")
+		return nil, errorf(ErrInvalidArgument, "replica_groups cannot be empty")
 	}
 
 	// Check the computation function signature.
 	if len(reductionInputs) != 2 {
-		return nil, errors.Errorf("computation function must have 2 inputs, but got %d",
+		return nil, errorf(ErrInvalidArgument, "computation function must have 2 inputs, but got %d",
 			len(reductionInputs))
 	}
 	if len(reductionOutputs) != 1 {
-		return nil, errors.Errorf("computation function must have 1 output, but got %d",
+		return nil, errorf(ErrInvalidArgument, "computation function must have 1 output, but got %d",
 			len(reductionOutputs))
 	}
 	for _, s := range []shapes.Shape{reductionInputs[0], reductionInputs[1], reductionOutputs[0]} {
 		if !s.IsScalar() || s.DType != dtype {
-			return nil, errors.Errorf(
+			return nil, errorf(ErrWrongDType,
 				"computation function inputs and output must be scalar with the same dtype as operands, "+
 					"got (%s, %s) -> %s -- operands dtypes is %s",
 				reductionInputs[0], reductionInputs[1], reductionOutputs[0], dtype)
@@ -1712,3 +2072,100 @@ func AllReduce(operands []shapes.Shape, reductionInputs, reductionOutputs []shap
 	}
 	return outputs, nil
 }
+
+// While returns the output shapes of a while operation, the same as the operands (the loop state).
+//
+// The cond function must take the loop state and return a scalar boolean, and the body function must take the loop
+// state and return the updated loop state, with the same shapes.
+func While(operands, condInputs, condOutputs, bodyInputs, bodyOutputs []shapes.Shape) (outputs []shapes.Shape, err error) {
+	for i, operand := range operands {
+		if !operand.Ok() {
+			return nil, errorf(ErrInvalidShape, "While: invalid operand[%d] shape %s", i, operand)
+		}
+	}
+	if len(condOutputs) != 1 || !condOutputs[0].IsScalar() || condOutputs[0].DType != dtypes.Bool {
+		return nil, errorf(ErrInvalidArgument, "While: cond function must return one scalar boolean, got %v", condOutputs)
+	}
+	names := []string{"cond inputs", "body inputs", "body outputs"}
+	for idx, signature := range [][]shapes.Shape{condInputs, bodyInputs, bodyOutputs} {
+		name := names[idx]
+		if len(signature) != len(operands) {
+			return nil, errorf(ErrInvalidArgument, "While: %s must match the %d operands (the loop state), got %d",
+				name, len(operands), len(signature))
+		}
+		for i, shape := range signature {
+			if !shape.Equal(operands[i]) {
+				return nil, errorf(ErrShapeMismatch, "While: %s #%d has shape %s, but operand #%d has shape %s",
+					name, i, shape, i, operands[i])
+			}
+		}
+	}
+	outputs = make([]shapes.Shape, len(operands))
+	for i, operand := range operands {
+		outputs[i] = operand.Clone()
+	}
+	return outputs, nil
+}
+
+// SetDimensionSize returns the output shape of a set_dimension_size operation: the operand axis becomes
+// dynamic (shapes.DynamicDim), bounded by the original operand dimension.
+//
+// The size must be a scalar int32.
+func SetDimensionSize(operand, size shapes.Shape, axis int) (output shapes.Shape, err error) {
+	if !operand.Ok() || operand.IsTuple() {
+		return shapes.Invalid(), errorf(ErrInvalidShape, "SetDimensionSize: invalid operand shape %s", operand)
+	}
+	if !size.IsScalar() || size.DType != dtypes.Int32 {
+		return shapes.Invalid(), errorf(ErrInvalidArgument, "SetDimensionSize: size must be a scalar Int32, got %s", size)
+	}
+	if axis < 0 || axis >= operand.Rank() {
+		return shapes.Invalid(), errorf(ErrInvalidAxis, "SetDimensionSize: axis %d is out of bounds for operand rank %d", axis, operand.Rank())
+	}
+	bound := operand.Bound(axis)
+	if bound == shapes.DynamicDim {
+		return shapes.Invalid(), errorf(ErrInvalidArgument, "SetDimensionSize: axis %d of operand %s is unbounded", axis, operand)
+	}
+	output = operand.Clone()
+	output.Dimensions[axis] = shapes.DynamicDim
+	if len(output.Bounds) == 0 {
+		output.Bounds = make([]int, operand.Rank())
+		for ii := range output.Bounds {
+			output.Bounds[ii] = shapes.DynamicDim
+		}
+	}
+	output.Bounds[axis] = bound
+	return output, nil
+}
+
+// DynamicIota returns the output shape of a dynamic_iota operation, which is the given shape, after checking it
+// against the outputShape operand -- a 1D integer tensor with one dimension per axis of the shape -- and the axis.
+//
+// The dynamic axes of the shape must have an upper-bound (see shapes.Shape.WithBounds).
+func DynamicIota(outputShape, shape shapes.Shape, axis int) (output shapes.Shape, err error) {
+	if !shape.Ok() || shape.IsTuple() {
+		return shapes.Invalid(), errorf(ErrInvalidShape, "DynamicIota: invalid shape %s", shape)
+	}
+	if err := checkIndicesVector("DynamicIota", "outputShape", outputShape, shape.Rank()); err != nil {
+		return shapes.Invalid(), err
+	}
+	if axis < 0 || axis >= shape.Rank() {
+		return shapes.Invalid(), errorf(ErrInvalidAxis, "DynamicIota: axis %d is out of bounds for shape rank %d", axis, shape.Rank())
+	}
+	for ii := range shape.Rank() {
+		if shape.Bound(ii) == shapes.DynamicDim {
+			return shapes.Invalid(), errorf(ErrInvalidArgument, "DynamicIota: axis %d of shape %s is unbounded", ii, shape)
+		}
+	}
+	return shape.Clone(), nil
+}
+
+// GetDimensionSize returns the output shape of a get_dimension_size operation, always a scalar int32.
+func GetDimensionSize(operand shapes.Shape, axis int) (output shapes.Shape, err error) {
+	if !operand.Ok() || operand.IsTuple() {
+		return shapes.Invalid(), errorf(ErrInvalidShape, "GetDimensionSize: invalid operand shape %s", operand)
+	}
+	if axis < 0 || axis >= operand.Rank() {
+		return shapes.Invalid(), errorf(ErrInvalidAxis, "GetDimensionSize: axis %d is out of bounds for operand rank %d", axis, operand.Rank())
+	}
+	return shapes.Make(dtypes.Int32), nil
+}
@@ -7,6 +7,7 @@ import (
 
 	"github.com/gomlx/gopjrt/dtypes"
 	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/types"
 	"github.com/gomlx/stablehlo/types/shapes"
 )
 
@@ -237,6 +238,21 @@ func TestGather(t *testing.T) {
 			t.Errorf("output check failed: %v", err)
 		}
 	})
+
+	t.Run("hint", func(t *testing.T) {
+		// Gather rows of a [5, 3] matrix, forgetting to set the offset output axis.
+		_, err := Gather(S(F32, 5, 3), S(I32, 2, 1), 1,
+			nil, []int{0}, nil, nil, []int{0}, []int{1, 3}, false)
+		if err == nil {
+			t.Fatal("expected error for missing offsetOutputAxes, got nil")
+		}
+		fmt.Printf("\tHint: %v\n", err)
+		for _, want := range []string{"so offsetOutputAxes must have 1 axes (got 0)", "offsetOutputAxes=[1]", "sliceSizes=[1 3]"} {
+			if !strings.Contains(err.Error(), want) {
+				t.Errorf("expected error to contain %q, got %v", want, err)
+			}
+		}
+	})
 }
 
 func TestScatter(t *testing.T) {
@@ -379,6 +395,22 @@ func TestScatter(t *testing.T) {
 	if !operand5.Equal(outputs5[0]) {
 		t.Errorf("Valid Case 5 Failed (No Window): Expected %s, got %s", operand5, outputs5[0])
 	}
+
+	// --- Hints ---
+	// Scatter rows of updates into a [4, 5] operand, with the updates window axis missing.
+	scalarComputation := []shapes.Shape{S(F32), S(F32)}
+	_, err = Scatter([]shapes.Shape{S(F32, 4, 5)}, S(I32, 2, 1), []shapes.Shape{S(F32, 2, 5)},
+		nil, []int{0}, nil, nil, []int{0}, 1,
+		scalarComputation, scalarComputation[:1])
+	if err == nil {
+		t.Fatal("expected error for missing updateWindowAxes, got nil")
+	}
+	fmt.Printf("\tHint: %v\n", err)
+	for _, want := range []string{"so updateWindowAxes must have 1 axes (got 0)", "updateWindowAxes=[1]", "updates dimensions [2 5]"} {
+		if !strings.Contains(err.Error(), want) {
+			t.Errorf("expected error to contain %q, got %v", want, err)
+		}
+	}
 }
 
 func TestSlice(t *testing.T) {
@@ -571,26 +603,162 @@ func TestArgMinMax(t *testing.T) {
 	})
 }
 
-func TestIsFinite(t *testing.T) {
-	// Positive case: float64 tensor.
-	f64Shape := S(dtypes.Float64, 2, 3)
-	output, err := IsFinite(f64Shape)
+func TestCompare(t *testing.T) {
+	bf16 := dtypes.BFloat16
+	for _, compareType := range []types.ComparisonType{types.CompareAuto, types.CompareFloat, types.CompareTotalOrder} {
+		output, err := Compare(S(bf16, 2, 3), S(bf16, 2, 3), types.CompareLT, compareType)
+		if err != nil {
+			t.Fatalf("Compare of bfloat16 with %s: expected no error, got %v", compareType, err)
+		}
+		if expected := S(Bool, 2, 3); !expected.Equal(output) {
+			t.Errorf("expected %s, got %s", expected, output)
+		}
+	}
+	if _, err := Compare(S(I32), S(I32), types.CompareEQ, types.CompareTotalOrder); err == nil ||
+		!strings.Contains(err.Error(), "type=TOTAL_ORDER") {
+		t.Errorf("expected error for TOTAL_ORDER comparison of integers, got %v", err)
+	}
+	if _, err := Compare(S(dtypes.F8E4M3FN), S(dtypes.F8E4M3FN), types.CompareEQ, types.CompareAuto); err != nil {
+		t.Errorf("Compare of F8E4M3FN with CompareAuto: expected no error, got %v", err)
+	}
+
+	for dtype, want := range map[dtypes.DType]types.ComparisonType{
+		bf16: types.CompareFloat, dtypes.F8E4M3FN: types.CompareFloat, I8: types.CompareSigned, U64: types.CompareUnsigned, Bool: types.CompareUnsigned,
+	} {
+		if got := must1(ResolveComparisonType(dtype, types.CompareAuto)); got != want {
+			t.Errorf("ResolveComparisonType(%s, CompareAuto) = %s, wanted %s", dtype, got, want)
+		}
+	}
+	if got := must1(ResolveComparisonType(bf16, types.CompareTotalOrder)); got != types.CompareTotalOrder {
+		t.Errorf("ResolveComparisonType(bfloat16, CompareTotalOrder) = %s, wanted CompareTotalOrder", got)
+	}
+}
+
+func TestRNG(t *testing.T) {
+	output, err := RNG(S(F32), S(F32), []int{2, 3}, types.RNGNormal)
 	if err != nil {
 		t.Fatalf("expected no error, got %v", err)
 	}
-	expected := S(Bool, 2, 3)
-	if !expected.Equal(output) {
+	if expected := S(F32, 2, 3); !expected.Equal(output) {
+		t.Errorf("expected %s, got %s", expected, output)
+	}
+	output, err = RNG(S(Bool), S(Bool), nil, types.RNGUniform)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if expected := S(Bool); !expected.Equal(output) {
 		t.Errorf("expected %s, got %s", expected, output)
 	}
 
-	// Check non-float type.
-	_, err = IsFinite(S(Bool))
-	if err == nil {
-		t.Error("expected error for IsFinite(Bool), got nil")
+	for _, tc := range []struct {
+		name         string
+		a, b         shapes.Shape
+		dimensions   []int
+		distribution types.RNGDistribution
+	}{
+		{"non-scalar", S(F32, 1), S(F32), nil, types.RNGUniform},
+		{"dtype mismatch", S(F32), S(I32), nil, types.RNGUniform},
+		{"normal integers", S(I32), S(I32), nil, types.RNGNormal},
+		{"negative dimension", S(F32), S(F32), []int{-1}, types.RNGUniform},
+		{"invalid distribution", S(F32), S(F32), nil, types.RNGDistribution(7)},
+	} {
+		if _, err := RNG(tc.a, tc.b, tc.dimensions, tc.distribution); err == nil {
+			t.Errorf("%s: expected error, got nil", tc.name)
+		}
 	}
-	_, err = IsFinite(S(I32))
-	if err == nil {
-		t.Error("expected error for IsFinite(I32), got nil")
+}
+
+func TestIsFinite(t *testing.T) {
+	for _, dtype := range []dtypes.DType{dtypes.Float64, dtypes.Float32, dtypes.Float16, dtypes.BFloat16,
+		dtypes.F8E4M3FN, dtypes.F8E5M2, dtypes.F8E4M3FNUZ, dtypes.F8E5M2FNUZ, dtypes.F8E4M3B11FNUZ,
+		dtypes.F8E4M3, dtypes.F8E3M4, dtypes.F8E8M0FNU} {
+		output, err := IsFinite(S(dtype, 2, 3))
+		if err != nil {
+			t.Fatalf("IsFinite(%s): expected no error, got %v", dtype, err)
+		}
+		expected := S(Bool, 2, 3)
+		if !expected.Equal(output) {
+			t.Errorf("IsFinite(%s): expected %s, got %s", dtype, expected, output)
+		}
+	}
+
+	// Check non-float types.
+	for _, dtype := range []dtypes.DType{Bool, I32, dtypes.Uint8, dtypes.Complex64, dtypes.Complex128} {
+		_, err := IsFinite(S(dtype))
+		if err == nil {
+			t.Errorf("expected error for IsFinite(%s), got nil", dtype)
+			continue
+		}
+		if want := "is not a floating point type"; !strings.Contains(err.Error(), want) {
+			t.Errorf("IsFinite(%s): expected error containing %q, got %q", dtype, want, err)
+		}
+	}
+}
+
+func TestBitcastConvert(t *testing.T) {
+	for _, tc := range []struct {
+		operand  shapes.Shape
+		dtype    dtypes.DType
+		expected shapes.Shape
+	}{
+		{S(F32, 3), I32, S(I32, 3)},
+		{S(F32, 3), I8, S(I8, 3, 4)},
+		{S(I8, 3, 4), F32, S(F32, 3)},
+		{S(I8, 3), dtypes.S4, S(dtypes.S4, 3, 2)},
+		{S(dtypes.U4, 2), I8, S(I8)},
+		{S(Bool, 3), Bool, S(Bool, 3)},
+	} {
+		output, err := BitcastConvert(tc.operand, tc.dtype)
+		if err != nil {
+			t.Errorf("BitcastConvert(%s, %s): expected no error, got %v", tc.operand, tc.dtype, err)
+			continue
+		}
+		if !tc.expected.Equal(output) {
+			t.Errorf("BitcastConvert(%s, %s): expected %s, got %s", tc.operand, tc.dtype, tc.expected, output)
+		}
+	}
+
+	// Booleans (i1), complex numbers and mismatched dimensions.
+	for _, tc := range []struct {
+		operand shapes.Shape
+		dtype   dtypes.DType
+	}{
+		{S(Bool, 8), I8},
+		{S(I8), Bool},
+		{S(dtypes.Complex64), U64},
+		{S(I8, 3), F32},
+		{S(I8), F32},
+	} {
+		if _, err := BitcastConvert(tc.operand, tc.dtype); err == nil {
+			t.Errorf("expected error for BitcastConvert(%s, %s), got nil", tc.operand, tc.dtype)
+		}
+	}
+}
+
+func TestReducePromotion(t *testing.T) {
+	F16 := dtypes.Float16
+	// Inputs promoted to the dtype of the reduction function, which is the output dtype.
+	outputs, err := Reduce([]shapes.Shape{S(F16, 2, 3)}, []shapes.Shape{S(F16)},
+		[]shapes.Shape{S(F32), S(F32)}, []shapes.Shape{S(F32)}, []int{1})
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(outputs) != 1 || !outputs[0].Equal(S(F32, 2)) {
+		t.Errorf("expected output shape %s, got %v", S(F32, 2), outputs)
+	}
+
+	// Not promotable: narrower or of a different kind.
+	for _, tc := range []struct {
+		input, reduction dtypes.DType
+	}{
+		{F32, F16},
+		{I32, F32},
+	} {
+		_, err = Reduce([]shapes.Shape{S(tc.input, 2, 3)}, []shapes.Shape{S(tc.input)},
+			[]shapes.Shape{S(tc.reduction), S(tc.reduction)}, []shapes.Shape{S(tc.reduction)}, []int{1})
+		if err == nil || !strings.Contains(err.Error(), "not promotable") {
+			t.Errorf("Reduce of %s with a %s reduction function: expected promotion error, got %v", tc.input, tc.reduction, err)
+		}
 	}
 }
 
@@ -754,15 +922,40 @@ func TestReduceWindow(t *testing.T) {
 			errorMessageContains: "windowDimensions[0]=0 must be >= 1",
 		},
 		{
-			name:                 "Error_NegativePadding_FromNonNil",
+			name:             "1D_NegativePaddings",
+			operandShape:     shapes.Make(dtypes.Float32, 5),
+			windowDimensions: []int{2},
+			strides:          []int{1},
+			baseDilations:    []int{1},
+			windowDilations:  []int{1},
+			paddings:         [][2]int{{-1, 0}},
+			// Calculation: EffIn=5, EffWin=2. PaddedEffIn=5-1+0=4. Num=4-2=2. Out=(2/1)+1=3.
+			expectedShape: shapes.Make(dtypes.Float32, 3),
+			expectError:   false,
+		},
+		{
+			name:             "2D_MixedNegativePaddings_WithBaseDilation",
+			operandShape:     shapes.Make(dtypes.Float32, 5, 4),
+			windowDimensions: []int{2, 1},
+			strides:          []int{2, 1},
+			baseDilations:    []int{2, 1},
+			windowDilations:  []int{1, 1},
+			paddings:         [][2]int{{-2, 1}, {-1, -1}},
+			// Axis 0: EffIn=(5-1)*2+1=9, EffWin=2. PaddedEffIn=9-2+1=8. Num=8-2=6. Out=(6/2)+1=4.
+			// Axis 1: EffIn=4, EffWin=1. PaddedEffIn=4-1-1=2. Num=2-1=1. Out=(1/1)+1=2.
+			expectedShape: shapes.Make(dtypes.Float32, 4, 2),
+			expectError:   false,
+		},
+		{
+			name:                 "Error_NegativePadding_WindowLargerThanTrimmedInput",
 			operandShape:         shapes.Make(dtypes.Float32, 5),
 			windowDimensions:     []int{2},
 			strides:              []int{1},
-			baseDilations:        []int{1}, // Added explicit base dilation
-			windowDilations:      []int{1}, // Added explicit window dilation
-			paddings:             [][2]int{{-1, 0}},
+			baseDilations:        []int{1},
+			windowDilations:      []int{1},
+			paddings:             [][2]int{{-2, -2}},
 			expectError:          true,
-			errorMessageContains: "paddings[0]=[-1, 0] must be non-negative",
+			errorMessageContains: "effective window dimension 2 for axis 0 is larger than padded effective input dimension 1",
 		},
 		{
 			name:                 "Error_InvalidBaseDilationZero",
@@ -1005,3 +1198,206 @@ func TestCollectiveOps(t *testing.T) {
 		}
 	})
 }
+
+func TestSetDimensionSize(t *testing.T) {
+	output, err := SetDimensionSize(S(F32, 8, 3), S(I32), 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := S(F32, shapes.DynamicDim, 3).WithBounds(8, shapes.DynamicDim)
+	if !expected.Equal(output) {
+		t.Errorf("Expected %s, got %s", expected, output)
+	}
+
+	// Setting the size of an already bounded axis keeps the bound.
+	output, err = SetDimensionSize(output, S(I32), 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !expected.Equal(output) {
+		t.Errorf("Expected %s, got %s", expected, output)
+	}
+
+	_, err = SetDimensionSize(S(F32, 8, 3), S(I8), 0)
+	if err == nil {
+		t.Error("expected error for non-Int32 size, got nil")
+	}
+	_, err = SetDimensionSize(S(F32, 8, 3), S(I32), 2)
+	if err == nil {
+		t.Error("expected error for axis out of bounds, got nil")
+	}
+	_, err = SetDimensionSize(S(F32, shapes.DynamicDim, 3), S(I32), 0)
+	if err == nil {
+		t.Error("expected error for unbounded axis, got nil")
+	}
+}
+
+func TestDynamicIota(t *testing.T) {
+	shape := S(F32, shapes.DynamicDim, 3).WithBounds(8, shapes.DynamicDim)
+	output, err := DynamicIota(S(dtypes.Int64, 2), shape, 0)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if !shape.Equal(output) {
+		t.Errorf("Expected %s, got %s", shape, output)
+	}
+
+	_, err = DynamicIota(S(F32, 2), shape, 0)
+	if err == nil {
+		t.Error("expected error for non-integer outputShape, got nil")
+	}
+	_, err = DynamicIota(S(I32, 3), shape, 0)
+	if err == nil {
+		t.Error("expected error for outputShape with the wrong number of axes, got nil")
+	}
+	_, err = DynamicIota(S(I32, 2), shape, 2)
+	if err == nil {
+		t.Error("expected error for axis out of bounds, got nil")
+	}
+	_, err = DynamicIota(S(I32, 2), S(F32, shapes.DynamicDim, 3), 0)
+	if err == nil {
+		t.Error("expected error for unbounded axis, got nil")
+	}
+}
+
+func TestDynamicPad(t *testing.T) {
+	output, err := DynamicPad(S(F32, 4, 3), S(F32), S(I32, 2), S(I32, 2), S(I32, 2))
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := S(F32, shapes.DynamicDim, shapes.DynamicDim)
+	if !expected.Equal(output) {
+		t.Errorf("Expected %s, got %s", expected, output)
+	}
+
+	_, err = DynamicPad(S(F32, 4, 3), S(F32), S(I32, 3), S(I32, 2), S(I32, 2))
+	if err == nil {
+		t.Error("expected error for paddings with the wrong size, got nil")
+	}
+	_, err = DynamicPad(S(F32, 4, 3), S(F32), S(F32, 2), S(F32, 2), S(F32, 2))
+	if err == nil {
+		t.Error("expected error for non-integer paddings, got nil")
+	}
+	_, err = DynamicPad(S(F32, 4, 3), S(F32), S(I32, 2), S(dtypes.Int64, 2), S(I32, 2))
+	if err == nil {
+		t.Error("expected error for paddings of different dtypes, got nil")
+	}
+	_, err = DynamicPad(S(F32, 4, 3), S(I32), S(I32, 2), S(I32, 2), S(I32, 2))
+	if err == nil {
+		t.Error("expected error for padding value with a different dtype, got nil")
+	}
+}
+
+func TestDynamicGather(t *testing.T) {
+	// Gather rows of a bounded operand: the offset axis is bounded by the operand.
+	operand := S(F32, shapes.DynamicDim, 16).WithBounds(8, shapes.DynamicDim)
+	output, err := DynamicGather(operand, S(I32, 5, 1), S(I32, 2), 1,
+		[]int{1}, []int{0}, nil, nil, []int{0}, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected := S(F32, 5, shapes.DynamicDim).WithBounds(shapes.DynamicDim, 16)
+	if !expected.Equal(output) {
+		t.Errorf("Expected %s, got %s", expected, output)
+	}
+
+	// Offset axis from an unbounded operand axis.
+	output, err = DynamicGather(S(F32, shapes.DynamicDim, 16), S(I32, 5, 1), S(I32, 2), 1,
+		[]int{1}, []int{1}, nil, nil, []int{1}, false)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	expected = S(F32, 5, shapes.DynamicDim)
+	if !expected.Equal(output) {
+		t.Errorf("Expected %s, got %s", expected, output)
+	}
+
+	_, err = DynamicGather(S(F32, 8, 16), S(I32, 5, 1), S(I32, 3), 1,
+		[]int{1}, []int{0}, nil, nil, []int{0}, false)
+	if err == nil {
+		t.Error("expected error for sliceSizes with the wrong size, got nil")
+	}
+	_, err = DynamicGather(S(F32, 8, 16), S(I32, shapes.DynamicDim, 1), S(I32, 2), 1,
+		[]int{1}, []int{0}, nil, nil, []int{0}, false)
+	if err == nil {
+		t.Error("expected error for dynamic startIndices, got nil")
+	}
+	_, err = DynamicGather(S(F32, 8, 16), S(I32, 5, 1), S(I32, 2), 1,
+		[]int{1}, []int{0}, nil, nil, []int{2}, false)
+	if err == nil {
+		t.Error("expected error for invalid startIndexMap, got nil")
+	}
+}
+
+func TestWhile(t *testing.T) {
+	state := []shapes.Shape{S(I32), S(F32, 3)}
+	outputs, err := While(state, state, []shapes.Shape{S(Bool)}, state, state)
+	if err != nil {
+		t.Fatalf("expected no error, got %v", err)
+	}
+	if len(outputs) != 2 || !outputs[0].Equal(state[0]) || !outputs[1].Equal(state[1]) {
+		t.Errorf("expected outputs %v, got %v", state, outputs)
+	}
+	if _, err = While(state, state, []shapes.Shape{S(I32)}, state, state); err == nil {
+		t.Error("expected error for cond not returning a boolean, got nil")
+	}
+	if _, err = While(state, state, []shapes.Shape{S(Bool)}, state, state[:1]); err == nil {
+		t.Error("expected error for body returning fewer values than the state, got nil")
+	}
+	if _, err = While(state, state, []shapes.Shape{S(Bool)}, []shapes.Shape{S(I32), S(F32, 4)}, state); err == nil {
+		t.Error("expected error for body inputs not matching the state, got nil")
+	}
+}
+
+func TestBoundedOperands(t *testing.T) {
+	D := shapes.DynamicDim
+	operand := S(F32, D, 3).WithBounds(8, D)
+	check := func(name string, output shapes.Shape, err error, expected shapes.Shape) {
+		t.Helper()
+		if err != nil {
+			t.Fatalf("%s: expected no error, got %v", name, err)
+		}
+		if !expected.Equal(output) {
+			t.Errorf("%s: expected %s, got %s", name, expected, output)
+		}
+	}
+
+	output, err := Transpose(operand, []int{1, 0})
+	check("Transpose", output, err, S(F32, 3, D).WithBounds(D, 8))
+
+	outputs, err := Reduce([]shapes.Shape{operand}, []shapes.Shape{S(F32)},
+		[]shapes.Shape{S(F32), S(F32)}, []shapes.Shape{S(F32)}, []int{1})
+	check("Reduce", outputs[0], err, S(F32, D).WithBounds(8))
+	outputs, err = Reduce([]shapes.Shape{operand}, []shapes.Shape{S(F32)},
+		[]shapes.Shape{S(F32), S(F32)}, []shapes.Shape{S(F32)}, []int{0})
+	check("Reduce of the bounded axis", outputs[0], err, S(F32, 3))
+
+	output, err = ArgMinMax(operand, 1, I32)
+	check("ArgMinMax", output, err, S(I32, D).WithBounds(8))
+
+	output, err = Concatenate([]shapes.Shape{operand, operand}, 1)
+	check("Concatenate", output, err, S(F32, D, 6).WithBounds(8, D))
+
+	output, err = BitcastConvert(operand, dtypes.Uint8)
+	check("BitcastConvert", output, err, S(dtypes.Uint8, D, 3, 4).WithBounds(8, D, D))
+
+	// Operations that don't carry the bounds reject bounded operands.
+	if _, err = Concatenate([]shapes.Shape{operand, operand}, 0); err == nil {
+		t.Error("Concatenate: expected error for a dynamic concatenation axis, got nil")
+	}
+	if _, err = Concatenate([]shapes.Shape{operand, S(F32, D, 3)}, 1); err == nil {
+		t.Error("Concatenate: expected error for mismatched bounds, got nil")
+	}
+	if _, err = Slice(operand, []int{0, 0}, []int{1, 3}, []int{1, 1}); err == nil {
+		t.Error("Slice: expected error for a bounded operand, got nil")
+	}
+	if _, err = Pad(operand, S(F32), []int{0, 0}, []int{1, 1}, []int{0, 0}); err == nil {
+		t.Error("Pad: expected error for a bounded operand, got nil")
+	}
+	if _, err = DotGeneral(operand, []int{1}, nil, S(F32, 3, 2), []int{0}, nil, F32); err == nil {
+		t.Error("DotGeneral: expected error for a bounded operand, got nil")
+	}
+	if _, err = AllGather(operand, [][]int{{0, 1}}, 1); err == nil {
+		t.Error("AllGather: expected error for a bounded operand, got nil")
+	}
+}
@@ -0,0 +1,57 @@
+package stablehlo
+
+import (
+	"slices"
+	"strings"
+
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/types/shardy"
+	"github.com/pkg/errors"
+)
+
+// checkShardingSpecMesh returns an error if the shardingSpec mesh is not one of the meshes configured
+// with Builder.WithShardy.
+func (b *Builder) checkShardingSpecMesh(shardingSpec *shardy.ShardingSpec) error {
+	if slices.Index(b.meshes, shardingSpec.Mesh) != -1 {
+		return nil
+	}
+	meshesNames := make([]string, 0, len(b.meshes))
+	for _, mesh := range b.meshes {
+		meshesNames = append(meshesNames, mesh.Name())
+	}
+	return errors.Errorf("sharding spec mesh %q doesn't match any of the stablehlo.Builder meshes (%s)",
+		shardingSpec.Mesh.Name(), strings.Join(meshesNames, ", "))
+}
+
+// ShardingConstraint returns x annotated with a sharding constraint (a "sdy.sharding_constraint" op), a hint
+// to the Shardy partitioner of how x should be sharded at this point of the computation.
+//
+// Shardy propagates the constraint to the neighbouring ops, so it gives fine control over the partitioning
+// of intermediary values, beyond the sharding of the inputs and outputs. Use ShardingSpec "open" axes
+// (see shardy.ShardingSpec) to leave the partitioner free to further shard an axis.
+//
+// The output has the same shape as x, and the builder must have been configured with Builder.WithShardy,
+// with the mesh used by shardingSpec.
+func ShardingConstraint(x *Value, shardingSpec *shardy.ShardingSpec) (output *Value, err error) {
+	op := optypes.ShardingConstraint
+	fn := x.fn
+	defer fn.opErrorHandler(&err, &output)()
+	if fn.Returned {
+		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
+			op, fn.Name)
+	}
+	if shardingSpec == nil {
+		return nil, errors.Errorf("%s requires a non-nil sharding spec", op)
+	}
+	if err = fn.Builder.checkShardingSpecMesh(shardingSpec); err != nil {
+		return nil, errors.WithMessagef(err, "in %s", op)
+	}
+	if err = shardingSpec.ValidateShape(x.shape); err != nil {
+		return nil, errors.WithMessagef(err, "in %s of value with shape %s", op, x.shape)
+	}
+	stmt := fn.addOp(op, x.shape, x)
+	stmt.Attributes = map[string]any{
+		"sharding": literalStr(shardingSpec.ToValueAttribute(x.shape)),
+	}
+	return stmt.Outputs[0], nil
+}
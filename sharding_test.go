@@ -0,0 +1,50 @@
+package stablehlo
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/gomlx/stablehlo/types/shardy"
+)
+
+func TestShardingConstraint(t *testing.T) {
+	mesh := must(shardy.NewDeviceMesh("mesh", []int{2, 2}, []string{"data", "model"}))
+
+	t.Run("Emission", func(t *testing.T) {
+		builder := New(t.Name()).WithShardy(mesh)
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 4, 8)))
+		y := must(Add(x, x))
+		y = must(ShardingConstraint(y, builder.NewShardingSpec().AddShardedAxis("data").AddShardedAxis("model")))
+		if !y.Shape().Equal(x.Shape()) {
+			t.Fatalf("ShardingConstraint changed the shape to %s", y.Shape())
+		}
+		must0(fn.Return(y))
+		program := string(must(builder.Build()))
+		want := `%1 = "sdy.sharding_constraint"(%0) { sharding = #sdy.sharding<@mesh, [{"data"}, {"model"}]> } : (tensor<4x8xf32>) -> tensor<4x8xf32>`
+		if !strings.Contains(program, want) {
+			t.Fatalf("program missing %q:\n%s", want, program)
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		builder := New(t.Name()).WithShardy(mesh)
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 4)))
+		if _, err := ShardingConstraint(x, nil); err == nil {
+			t.Error("expected error for nil sharding spec")
+		}
+		otherMesh := must(shardy.NewDeviceMesh("other", []int{2}, []string{"data"}))
+		if _, err := ShardingConstraint(x, shardy.NewShardingSpec(otherMesh).AddShardedAxis("data")); err == nil {
+			t.Error("expected error for sharding spec with a mesh not configured in the builder")
+		}
+		if _, err := ShardingConstraint(x, builder.NewShardingSpec().AddShardedAxis("data").AddShardedAxis("model")); err == nil {
+			t.Error("expected error for sharding spec with rank larger than the value's")
+		}
+		if _, err := ShardingConstraint(x, builder.NewShardingSpec().AddShardedAxis("unknown")); err == nil {
+			t.Error("expected error for sharding spec with unknown mesh axis")
+		}
+	})
+}
@@ -0,0 +1,239 @@
+package stablehlo
+
+import (
+	"slices"
+
+	"github.com/gomlx/stablehlo/shapeinference"
+	"github.com/pkg/errors"
+)
+
+// SliceEx extracts a subarray from x with Python-style slicing semantics (x[start:limit:stride] on each axis):
+//
+//   - Negative starts and limits are relative to the end of the axis: -1 is the last element.
+//   - Out-of-range starts and limits are clamped, so math.MinInt and math.MaxInt can be used for unbounded
+//     starts/limits.
+//   - Negative strides traverse the axis in reverse order: it is lowered to a Reverse followed by a Slice
+//     with a positive stride.
+//
+// Each of starts, limits and strides must either be nil or have one value per axis of x.
+// If starts (or limits) is nil, the slice starts (or ends) at the beginning (or the end) of each axis, in the
+// direction of the stride -- so SliceEx(x, nil, nil, []int{-1}) reverses a vector.
+// If strides is nil, it defaults to 1 for every axis. A stride of 0 is an error.
+//
+// Examples:
+//
+//	SliceEx(x={0, 1, 2, 3, 4}, starts={-2}, limits=nil, strides=nil) -> {3, 4}
+//	SliceEx(x={0, 1, 2, 3, 4}, starts={3}, limits={0}, strides={-1}) -> {3, 2, 1}
+//	SliceEx(x={0, 1, 2, 3, 4}, starts=nil, limits=nil, strides={-2}) -> {4, 2, 0}
+func SliceEx(x *Value, starts, limits, strides []int) (output *Value, err error) {
+	fn := x.fn
+	defer fn.opErrorHandler(&err, &output)()
+	rank := x.shape.Rank()
+	for _, param := range []struct {
+		name   string
+		values []int
+	}{{"starts", starts}, {"limits", limits}, {"strides", strides}} {
+		if param.values != nil && len(param.values) != rank {
+			return nil, errors.Errorf("SliceEx: %s has %d values, but x has rank %d", param.name, len(param.values), rank)
+		}
+	}
+
+	sliceStarts, sliceLimits, sliceStrides := make([]int, rank), make([]int, rank), make([]int, rank)
+	var reversedAxes []int
+	isIdentity := true
+	for axis, dim := range x.shape.Dimensions {
+		stride := 1
+		if strides != nil {
+			stride = strides[axis]
+		}
+		if stride == 0 {
+			return nil, errors.Errorf("SliceEx: stride for axis %d cannot be 0", axis)
+		}
+
+		// Normalize start and limit as in Python: relative to the end if negative, clamped to the axis.
+		normalize := func(value, lower, upper int) int {
+			if value < 0 {
+				value += dim
+			}
+			return min(max(value, lower), upper)
+		}
+		var start, limit, count int
+		if stride > 0 {
+			start, limit = 0, dim
+			if starts != nil {
+				start = normalize(starts[axis], 0, dim)
+			}
+			if limits != nil {
+				limit = normalize(limits[axis], 0, dim)
+			}
+			count = max(0, (limit-start+stride-1)/stride)
+		} else {
+			// With negative strides the range is (limit, start], with -1 meaning "before the first element".
+			start, limit = dim-1, -1
+			if starts != nil {
+				start = normalize(starts[axis], -1, dim-1)
+			}
+			if limits != nil {
+				limit = normalize(limits[axis], -1, dim-1)
+			}
+			stride = -stride
+			count = max(0, (start-limit+stride-1)/stride)
+			// Reversing the axis moves index i to dim-1-i.
+			start = dim - 1 - start
+			reversedAxes = append(reversedAxes, axis)
+		}
+		if count == 0 {
+			sliceStarts[axis], sliceLimits[axis], sliceStrides[axis] = 0, 0, 1
+		} else {
+			sliceStarts[axis], sliceLimits[axis], sliceStrides[axis] = start, start+(count-1)*stride+1, stride
+		}
+		if sliceStarts[axis] != 0 || sliceLimits[axis] != dim || sliceStrides[axis] != 1 {
+			isIdentity = false
+		}
+	}
+	if len(reversedAxes) > 0 {
+		x, err = Reverse(x, reversedAxes...)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if isIdentity {
+		return x, nil
+	}
+	return Slice(x, sliceStarts, sliceLimits, sliceStrides)
+}
+
+// Split x along the axis into numSplits parts of equal size.
+// The dimension of the axis must be divisible by numSplits.
+//
+// See SplitSizes to split into parts of different sizes, and Concatenate for the inverse operation.
+func Split(x *Value, numSplits, axis int) (outputs []*Value, err error) {
+	fn := x.fn
+	defer fn.multiOpErrorHandler(&err, &outputs, max(numSplits, 0))()
+	adjustedAxis, err := shapeinference.AdjustAxisToRank(axis, x.shape.Rank())
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Split: invalid axis %d for x %s", axis, x.shape)
+	}
+	dim := x.shape.Dimensions[adjustedAxis]
+	if numSplits <= 0 || dim%numSplits != 0 {
+		return nil, errors.Errorf("Split: axis %d of x %s (dimension %d) can't be split in %d equal parts",
+			axis, x.shape, dim, numSplits)
+	}
+	sizes := make([]int, numSplits)
+	for i := range sizes {
+		sizes[i] = dim / numSplits
+	}
+	return SplitSizes(x, sizes, adjustedAxis)
+}
+
+// SplitSizes splits x along the axis into parts with the given sizes, which must add up to the dimension of the axis.
+// It is implemented with one Slice per part.
+//
+// See Split to split into parts of equal size, and Concatenate for the inverse operation.
+func SplitSizes(x *Value, sizes []int, axis int) (outputs []*Value, err error) {
+	fn := x.fn
+	defer fn.multiOpErrorHandler(&err, &outputs, len(sizes))()
+	rank := x.shape.Rank()
+	adjustedAxis, err := shapeinference.AdjustAxisToRank(axis, rank)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "SplitSizes: invalid axis %d for x %s", axis, x.shape)
+	}
+	total := 0
+	for _, size := range sizes {
+		if size < 0 {
+			return nil, errors.Errorf("SplitSizes: sizes %v must be non-negative", sizes)
+		}
+		total += size
+	}
+	if total != x.shape.Dimensions[adjustedAxis] {
+		return nil, errors.Errorf("SplitSizes: sizes %v add up to %d, but axis %d of x %s has dimension %d",
+			sizes, total, axis, x.shape, x.shape.Dimensions[adjustedAxis])
+	}
+	starts, limits := make([]int, rank), slices.Clone(x.shape.Dimensions)
+	outputs = make([]*Value, 0, len(sizes))
+	for _, size := range sizes {
+		limits[adjustedAxis] = starts[adjustedAxis] + size
+		part, err := Slice(x, slices.Clone(starts), slices.Clone(limits), nil)
+		if err != nil {
+			return nil, err
+		}
+		outputs = append(outputs, part)
+		starts[adjustedAxis] = limits[adjustedAxis]
+	}
+	return outputs, nil
+}
+
+// Unstack splits x along the axis into one value per index of the axis, with the axis removed.
+// E.g.: Unstack(x=f32[3, 4], axis=0) returns 3 values of shape f32[4].
+//
+// See Stack for the inverse operation.
+func Unstack(x *Value, axis int) (outputs []*Value, err error) {
+	fn := x.fn
+	rank := x.shape.Rank()
+	adjustedAxis, axisErr := shapeinference.AdjustAxisToRank(axis, rank)
+	numOutputs := 0
+	if axisErr == nil {
+		numOutputs = x.shape.Dimensions[adjustedAxis]
+	}
+	defer fn.multiOpErrorHandler(&err, &outputs, numOutputs)()
+	if axisErr != nil {
+		return nil, errors.WithMessagef(axisErr, "Unstack: invalid axis %d for x %s", axis, x.shape)
+	}
+	sizes := make([]int, numOutputs)
+	for i := range sizes {
+		sizes[i] = 1
+	}
+	parts, err := SplitSizes(x, sizes, adjustedAxis)
+	if err != nil {
+		return nil, err
+	}
+	squeezedShape := x.shape.Clone()
+	squeezedShape.Dimensions = slices.Delete(squeezedShape.Dimensions, adjustedAxis, adjustedAxis+1)
+	outputs = make([]*Value, numOutputs)
+	for i, part := range parts {
+		outputs[i], err = Reshape(part, squeezedShape)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return outputs, nil
+}
+
+// Stack the operands along a new axis, inserted at the given position (negative values are counted from the
+// end of the output rank). All operands must have the same shape, and they can be scalars.
+// E.g.: Stack(axis=0, 3 values of shape f32[4]) returns a value of shape f32[3, 4].
+//
+// It is implemented by reshaping the operands to add the new axis (with dimension 1) and concatenating them.
+// See Unstack for the inverse operation.
+func Stack(axis int, operands ...*Value) (output *Value, err error) {
+	if len(operands) == 0 {
+		return nil, errors.New("Stack requires at least one operand")
+	}
+	fn := operands[0].fn
+	defer fn.opErrorHandler(&err, &output)()
+	shape := operands[0].shape
+	for i, operand := range operands {
+		if operand.fn != fn {
+			return nil, errors.Errorf("Stack: operand #%d is from a different function (%q and %q)",
+				i, operand.fn.Name, fn.Name)
+		}
+		if !operand.shape.Equal(shape) {
+			return nil, errors.Errorf("Stack: all operands must have the same shape, got %s for operand #0 and %s for operand #%d",
+				shape, operand.shape, i)
+		}
+	}
+	adjustedAxis, err := shapeinference.AdjustAxisToRank(axis, shape.Rank()+1)
+	if err != nil {
+		return nil, errors.WithMessagef(err, "Stack: invalid axis %d for operands of shape %s", axis, shape)
+	}
+	expandedShape := shape.Clone()
+	expandedShape.Dimensions = slices.Insert(expandedShape.Dimensions, adjustedAxis, 1)
+	expanded := make([]*Value, len(operands))
+	for i, operand := range operands {
+		expanded[i], err = Reshape(operand, expandedShape)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return Concatenate(adjustedAxis, expanded...)
+}
@@ -0,0 +1,125 @@
+package stablehlo
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestSliceEx(t *testing.T) {
+	for _, tc := range []struct {
+		name                    string
+		starts, limits, strides []int
+		wantDims                []int
+		wantOps                 []string
+	}{
+		{"negative start", []int{-2}, nil, nil, []int{2}, []string{"stablehlo.slice"}},
+		{"reverse", nil, nil, []int{-1}, []int{5}, []string{"stablehlo.reverse"}},
+		{"reverse range", []int{3}, []int{0}, []int{-1}, []int{3}, []string{"stablehlo.reverse", "stablehlo.slice"}},
+		{"reverse with stride", nil, nil, []int{-2}, []int{3}, []string{"stablehlo.reverse", "stablehlo.slice"}},
+		{"clamped", []int{math.MinInt}, []int{math.MaxInt}, nil, []int{5}, nil},
+		{"empty", []int{4}, []int{2}, nil, []int{0}, []string{"stablehlo.slice"}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			builder := New(t.Name())
+			fn := builder.Main()
+			x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 5)))
+			y := must(SliceEx(x, tc.starts, tc.limits, tc.strides))
+			if !slices.Equal(y.Shape().Dimensions, tc.wantDims) {
+				t.Errorf("expected dimensions %v, got %s", tc.wantDims, y.Shape())
+			}
+			var ops []string
+			for _, stmt := range fn.Statements {
+				ops = append(ops, stmt.OpType.ToStableHLO())
+			}
+			if !slices.Equal(ops, tc.wantOps) {
+				t.Errorf("expected ops %v, got %v", tc.wantOps, ops)
+			}
+		})
+	}
+
+	t.Run("reverse range attributes", func(t *testing.T) {
+		// x[3:0:-1] on [0 1 2 3 4] is [3 2 1]: on the reversed [4 3 2 1 0] it is the slice [1:4].
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 5)))
+		if err := fn.Return(must(SliceEx(x, []int{3}, []int{0}, []int{-1}))); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		program := string(must(builder.Build()))
+		if !strings.Contains(program, "start_indices = array<i64: 1>") || !strings.Contains(program, "limit_indices = array<i64: 4>") {
+			t.Errorf("unexpected slice of reversed axis:\n%s", program)
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		fn := New(t.Name()).Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 5)))
+		if _, err := SliceEx(x, nil, nil, []int{0}); err == nil {
+			t.Error("expected error for stride 0, got nil")
+		}
+		if _, err := SliceEx(x, []int{0, 0}, nil, nil); err == nil {
+			t.Error("expected error for starts with the wrong rank, got nil")
+		}
+	})
+}
+
+func TestSplit(t *testing.T) {
+	fn := New(t.Name()).Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 6, 4)))
+	parts := must(Split(x, 3, 0))
+	if len(parts) != 3 {
+		t.Fatalf("expected 3 parts, got %d", len(parts))
+	}
+	for _, part := range parts {
+		if !slices.Equal(part.Shape().Dimensions, []int{2, 4}) {
+			t.Errorf("expected parts of dimensions [2 4], got %s", part.Shape())
+		}
+	}
+	parts = must(SplitSizes(x, []int{1, 3}, -1))
+	if !slices.Equal(parts[0].Shape().Dimensions, []int{6, 1}) || !slices.Equal(parts[1].Shape().Dimensions, []int{6, 3}) {
+		t.Errorf("expected parts of dimensions [6 1] and [6 3], got %s and %s", parts[0].Shape(), parts[1].Shape())
+	}
+	parts = must(Unstack(x, 1))
+	if len(parts) != 4 || !slices.Equal(parts[0].Shape().Dimensions, []int{6}) {
+		t.Errorf("expected 4 parts of dimensions [6], got %d parts of %s", len(parts), parts[0].Shape())
+	}
+	if _, err := Split(x, 4, 0); err == nil {
+		t.Error("expected error splitting dimension 6 in 4 parts, got nil")
+	}
+	if _, err := SplitSizes(x, []int{1, 2}, 0); err == nil {
+		t.Error("expected error for sizes not adding up to the dimension, got nil")
+	}
+	if _, err := Unstack(x, 2); err == nil {
+		t.Error("expected error for invalid axis, got nil")
+	}
+}
+
+func TestStack(t *testing.T) {
+	fn := New(t.Name()).Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2, 3)))
+	y := must(fn.NamedInput("y", shapes.Make(dtypes.Float32, 2, 3)))
+	for _, tc := range []struct {
+		axis     int
+		wantDims []int
+	}{{0, []int{2, 2, 3}}, {1, []int{2, 2, 3}}, {-1, []int{2, 3, 2}}} {
+		stacked := must(Stack(tc.axis, x, y))
+		if !slices.Equal(stacked.Shape().Dimensions, tc.wantDims) {
+			t.Errorf("Stack(axis=%d): expected dimensions %v, got %s", tc.axis, tc.wantDims, stacked.Shape())
+		}
+	}
+	scalar := must(fn.ConstantFromScalar(float32(1)))
+	if stacked := must(Stack(0, scalar, scalar, scalar)); !slices.Equal(stacked.Shape().Dimensions, []int{3}) {
+		t.Errorf("expected stacked scalars to have dimensions [3], got %s", stacked.Shape())
+	}
+	if _, err := Stack(0, x, scalar); err == nil {
+		t.Error("expected error stacking values of different shapes, got nil")
+	}
+	if _, err := Stack(3, x, y); err == nil {
+		t.Error("expected error for invalid axis, got nil")
+	}
+}
@@ -0,0 +1,316 @@
+package stablehlo
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/internal/utils"
+	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/pkg/errors"
+)
+
+// floatEncoding describes how a floating-point format encodes its special values.
+type floatEncoding int
+
+const (
+	// ieeeEncoding is the IEEE-754 like encoding: infinities have the exponent bits all set and mantissa 0,
+	// NaNs have the exponent bits all set and a non-zero mantissa.
+	ieeeEncoding floatEncoding = iota
+
+	// finiteEncoding (the "FN" suffix) has no infinities, and NaN is the value with all exponent and mantissa
+	// bits set. E.g.: f8E4M3FN.
+	finiteEncoding
+
+	// finiteUnsignedZeroEncoding (the "FNUZ" suffix) has no infinities and no negative zero: NaN is encoded as
+	// the negative zero. E.g.: f8E5M2FNUZ.
+	finiteUnsignedZeroEncoding
+
+	// exponentOnlyEncoding is used by the unsigned f8E8M0FNU: only exponent bits, no infinities or zero, and NaN
+	// is the value with all bits set.
+	exponentOnlyEncoding
+)
+
+// floatFormat describes the bit layout of a floating-point dtype.
+type floatFormat struct {
+	bits, exponentBits, mantissaBits, bias int
+	encoding                               floatEncoding
+}
+
+// floatFormats lists the layout of the floating-point dtypes supported by the special constants.
+var floatFormats = map[dtypes.DType]floatFormat{
+	dtypes.Float64:       {bits: 64, exponentBits: 11, mantissaBits: 52, bias: 1023, encoding: ieeeEncoding},
+	dtypes.Float32:       {bits: 32, exponentBits: 8, mantissaBits: 23, bias: 127, encoding: ieeeEncoding},
+	dtypes.Float16:       {bits: 16, exponentBits: 5, mantissaBits: 10, bias: 15, encoding: ieeeEncoding},
+	dtypes.BFloat16:      {bits: 16, exponentBits: 8, mantissaBits: 7, bias: 127, encoding: ieeeEncoding},
+	dtypes.F8E5M2:        {bits: 8, exponentBits: 5, mantissaBits: 2, bias: 15, encoding: ieeeEncoding},
+	dtypes.F8E4M3:        {bits: 8, exponentBits: 4, mantissaBits: 3, bias: 7, encoding: ieeeEncoding},
+	dtypes.F8E3M4:        {bits: 8, exponentBits: 3, mantissaBits: 4, bias: 3, encoding: ieeeEncoding},
+	dtypes.F8E4M3FN:      {bits: 8, exponentBits: 4, mantissaBits: 3, bias: 7, encoding: finiteEncoding},
+	dtypes.F8E4M3FNUZ:    {bits: 8, exponentBits: 4, mantissaBits: 3, bias: 8, encoding: finiteUnsignedZeroEncoding},
+	dtypes.F8E5M2FNUZ:    {bits: 8, exponentBits: 5, mantissaBits: 2, bias: 16, encoding: finiteUnsignedZeroEncoding},
+	dtypes.F8E4M3B11FNUZ: {bits: 8, exponentBits: 4, mantissaBits: 3, bias: 11, encoding: finiteUnsignedZeroEncoding},
+	dtypes.F8E8M0FNU:     {bits: 8, exponentBits: 8, mantissaBits: 0, bias: 127, encoding: exponentOnlyEncoding},
+}
+
+// contains returns whether every finite value of the other format is exactly representable in f: f must have as
+// many mantissa bits, and cover the range of exponents (including the subnormals) of the other format.
+func (f floatFormat) contains(other floatFormat) bool {
+	// minSubnormalExponent is the exponent of the smallest positive value: the exponent only encoding has no
+	// subnormals, and its zero exponent is a normal value.
+	minSubnormalExponent := func(format floatFormat) int {
+		if format.encoding == exponentOnlyEncoding {
+			return -format.bias
+		}
+		return 1 - format.bias - format.mantissaBits
+	}
+	return f.mantissaBits >= other.mantissaBits &&
+		f.maxNormalExponent() >= other.maxNormalExponent() &&
+		minSubnormalExponent(f) <= minSubnormalExponent(other)
+}
+
+// containsIntegers returns whether every value of the integer (or boolean) dtype is exactly representable in f:
+// the significand of f (its mantissa bits plus the implicit leading one) must hold the value bits of the dtype
+// (its bits without the sign bit), and its exponents must cover them.
+func (f floatFormat) containsIntegers(dtype dtypes.DType) bool {
+	if f.encoding == exponentOnlyEncoding {
+		// No zero, and no mantissa.
+		return false
+	}
+	valueBits := utils.DTypeBitWidth(dtype)
+	switch dtype {
+	case dtypes.Bool, dtypes.Uint8, dtypes.Uint16, dtypes.Uint32, dtypes.Uint64, dtypes.U4, dtypes.U2:
+	default:
+		valueBits--
+	}
+	return f.mantissaBits+1 >= valueBits && f.maxNormalExponent() >= valueBits-1
+}
+
+// maxNormalExponent is the unbiased exponent of the largest finite values: the all-ones exponent is reserved
+// for infinities and NaNs in the IEEE encoding, and for NaN in the exponent only encoding.
+func (f floatFormat) maxNormalExponent() int {
+	exponent := int(f.maxExponent()) - f.bias
+	if f.encoding == ieeeEncoding || f.encoding == exponentOnlyEncoding {
+		exponent--
+	}
+	return exponent
+}
+
+func (f floatFormat) signBit() uint64 {
+	return 1 << (f.bits - 1)
+}
+
+func (f floatFormat) mantissaMask() uint64 {
+	return (1 << f.mantissaBits) - 1
+}
+
+func (f floatFormat) maxExponent() uint64 {
+	return (1 << f.exponentBits) - 1
+}
+
+// infinityBits returns the bit pattern of +Inf (or -Inf if negative), if the format supports it.
+func (f floatFormat) infinityBits(negative bool) (uint64, bool) {
+	if f.encoding != ieeeEncoding {
+		return 0, false
+	}
+	bits := f.maxExponent() << f.mantissaBits
+	if negative {
+		bits |= f.signBit()
+	}
+	return bits, true
+}
+
+// nanBits returns the bit pattern of a (quiet) NaN.
+func (f floatFormat) nanBits() uint64 {
+	switch f.encoding {
+	case ieeeEncoding:
+		return f.maxExponent()<<f.mantissaBits | 1<<(f.mantissaBits-1)
+	case finiteUnsignedZeroEncoding:
+		return f.signBit()
+	default: // finiteEncoding, exponentOnlyEncoding
+		return f.maxExponent()<<f.mantissaBits | f.mantissaMask()
+	}
+}
+
+// maxFiniteBits returns the bit pattern of the largest finite value.
+func (f floatFormat) maxFiniteBits() uint64 {
+	switch f.encoding {
+	case ieeeEncoding:
+		return (f.maxExponent()-1)<<f.mantissaBits | f.mantissaMask()
+	case finiteUnsignedZeroEncoding:
+		return f.maxExponent()<<f.mantissaBits | f.mantissaMask()
+	default: // finiteEncoding, exponentOnlyEncoding: the value with all bits set is NaN.
+		return f.nanBits() - 1
+	}
+}
+
+// minFiniteBits returns the bit pattern of the lowest (most negative) finite value.
+// For the unsigned exponentOnlyEncoding, it is the smallest positive value.
+func (f floatFormat) minFiniteBits() uint64 {
+	if f.encoding == exponentOnlyEncoding {
+		return 0
+	}
+	return f.signBit() | f.maxFiniteBits()
+}
+
+// epsilonBits returns the bit pattern of the machine epsilon: the difference between 1.0 and the next
+// representable value, that is 2^-mantissaBits.
+func (f floatFormat) epsilonBits() uint64 {
+	exponent := f.bias - f.mantissaBits
+	if exponent >= 1 {
+		return uint64(exponent) << f.mantissaBits
+	}
+	// Epsilon is a subnormal value: 2^(1-bias) * m / 2^mantissaBits = 2^-mantissaBits => m = 2^(bias-1).
+	return 1 << (f.bias - 1)
+}
+
+// bitsFromFloat64 returns the bit pattern of the value converted to the format, rounding to the nearest
+// representable value (ties to even).
+//
+// Values too large for the format become infinities, or NaN for the formats without infinities. For the unsigned
+// exponentOnlyEncoding, non-positive values become NaN, and values too small become its smallest value.
+func (f floatFormat) bitsFromFloat64(value float64) uint64 {
+	if f.encoding == exponentOnlyEncoding {
+		if math.IsNaN(value) || value <= 0 || math.IsInf(value, 1) {
+			return f.nanBits()
+		}
+		frac, exp := math.Frexp(value) // value = frac * 2^exp, with frac in [0.5, 1).
+		exponent := exp - 1 + f.bias
+		if frac > 0.75 || (frac == 0.75 && exponent%2 != 0) {
+			exponent++
+		}
+		if uint64(max(exponent, 0)) >= f.maxExponent() {
+			return f.nanBits()
+		}
+		return uint64(max(exponent, 0))
+	}
+
+	if math.IsNaN(value) {
+		return f.nanBits()
+	}
+	var sign uint64
+	if math.Signbit(value) {
+		sign = f.signBit()
+	}
+	value = math.Abs(value)
+	var magnitude uint64
+	if math.IsInf(value, 0) {
+		magnitude = f.maxFiniteBits() + 1
+	} else if value != 0 {
+		// Round value to an integer number of units in the last place, with the exponent of the unit limited by
+		// the subnormal values: adding the implicit bit to the biased exponent field yields the bit pattern,
+		// including the carry to the next exponent when the rounding overflows the mantissa.
+		_, exp := math.Frexp(value)
+		minUnitExponent := 1 - f.bias - f.mantissaBits
+		unitExponent := max(exp-1-f.mantissaBits, minUnitExponent)
+		units := uint64(math.RoundToEven(math.Ldexp(value, -unitExponent)))
+		magnitude = uint64(unitExponent-minUnitExponent)<<f.mantissaBits + units
+	}
+	if magnitude > f.maxFiniteBits() {
+		if bits, ok := f.infinityBits(sign != 0); ok {
+			return bits
+		}
+		return f.nanBits()
+	}
+	if magnitude == 0 && f.encoding == finiteUnsignedZeroEncoding {
+		// No negative zero: its bit pattern is NaN.
+		return 0
+	}
+	return sign | magnitude
+}
+
+// ConstantInf creates a scalar constant with +Inf (or -Inf if negative is true) for the given float dtype.
+//
+// It returns an error for dtypes without infinity, like f8E4M3FN and the FNUZ variants.
+func (fn *Function) ConstantInf(dtype dtypes.DType, negative bool) (output *Value, err error) {
+	defer fn.opErrorHandler(&err, &output)()
+	format, err := fn.floatFormatFor(dtype, "ConstantInf")
+	if err != nil {
+		return nil, err
+	}
+	bits, ok := format.infinityBits(negative)
+	if !ok {
+		return nil, errors.Errorf("ConstantInf: dtype %s has no infinity representation", dtype)
+	}
+	return fn.constantFromBits(dtype, format, bits), nil
+}
+
+// ConstantNaN creates a scalar constant with a (quiet) NaN for the given float dtype.
+func (fn *Function) ConstantNaN(dtype dtypes.DType) (output *Value, err error) {
+	defer fn.opErrorHandler(&err, &output)()
+	format, err := fn.floatFormatFor(dtype, "ConstantNaN")
+	if err != nil {
+		return nil, err
+	}
+	return fn.constantFromBits(dtype, format, format.nanBits()), nil
+}
+
+// ConstantMaxFinite creates a scalar constant with the largest finite value of the given float dtype.
+func (fn *Function) ConstantMaxFinite(dtype dtypes.DType) (output *Value, err error) {
+	defer fn.opErrorHandler(&err, &output)()
+	format, err := fn.floatFormatFor(dtype, "ConstantMaxFinite")
+	if err != nil {
+		return nil, err
+	}
+	return fn.constantFromBits(dtype, format, format.maxFiniteBits()), nil
+}
+
+// ConstantMinFinite creates a scalar constant with the lowest (most negative) finite value of the given
+// float dtype, that is -ConstantMaxFinite.
+//
+// For the unsigned f8E8M0FNU it is the smallest positive value (2^-127).
+func (fn *Function) ConstantMinFinite(dtype dtypes.DType) (output *Value, err error) {
+	defer fn.opErrorHandler(&err, &output)()
+	format, err := fn.floatFormatFor(dtype, "ConstantMinFinite")
+	if err != nil {
+		return nil, err
+	}
+	return fn.constantFromBits(dtype, format, format.minFiniteBits()), nil
+}
+
+// ConstantEpsilon creates a scalar constant with the machine epsilon of the given float dtype: the
+// difference between 1.0 and the next representable value.
+func (fn *Function) ConstantEpsilon(dtype dtypes.DType) (output *Value, err error) {
+	defer fn.opErrorHandler(&err, &output)()
+	format, err := fn.floatFormatFor(dtype, "ConstantEpsilon")
+	if err != nil {
+		return nil, err
+	}
+	return fn.constantFromBits(dtype, format, format.epsilonBits()), nil
+}
+
+// floatFormatFor checks the function can still take new statements, and returns the format of the float dtype.
+func (fn *Function) floatFormatFor(dtype dtypes.DType, caller string) (floatFormat, error) {
+	if fn.Returned {
+		return floatFormat{}, errors.Errorf("%s: Function.Return already called for %q", caller, fn.Name)
+	}
+	format, found := floatFormats[dtype]
+	if !found {
+		return floatFormat{}, errors.Errorf("%s: dtype %s is not a supported float dtype", caller, dtype)
+	}
+	return format, nil
+}
+
+// constantFromBits adds a scalar constant statement with the value given by its bit pattern, rendered as
+// a hexadecimal literal -- the only exact representation for special values and for the f8 dtypes.
+func (fn *Function) constantFromBits(dtype dtypes.DType, format floatFormat, bits uint64) *Value {
+	return fn.constantFromLiteral(shapes.Make(dtype), fmt.Sprintf("0x%0*x", format.bits/4, bits))
+}
+
+// constantFromLiteral adds a constant statement with the given shape and its value rendered verbatim in the
+// dense attribute, for values without a Go type.
+func (fn *Function) constantFromLiteral(shape shapes.Shape, literal string) *Value {
+	c := &Statement{
+		Builder:  fn.Builder,
+		id:       fn.Builder.newStatementID(),
+		Function: fn,
+		OpType:   optypes.Constant,
+		Attributes: map[string]any{
+			"value": literalStrF("dense<%s> : %s", literal, shape.ToStableHLO()),
+		},
+		Outputs: []*Value{fn.newValue(shape)},
+	}
+	fn.Statements = append(fn.Statements, c)
+	return c.Outputs[0]
+}
@@ -0,0 +1,155 @@
+package stablehlo
+
+import (
+	"math"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+)
+
+func TestSpecialConstants(t *testing.T) {
+	t.Run("bit patterns", func(t *testing.T) {
+		f32 := floatFormats[dtypes.Float32]
+		f64 := floatFormats[dtypes.Float64]
+		infinityBits := func(f floatFormat, negative bool) uint64 {
+			bits, _ := f.infinityBits(negative)
+			return bits
+		}
+		for _, tc := range []struct {
+			name      string
+			got, want uint64
+		}{
+			{"f32 +inf", infinityBits(f32, false), uint64(math.Float32bits(float32(math.Inf(1))))},
+			{"f32 max", f32.maxFiniteBits(), uint64(math.Float32bits(math.MaxFloat32))},
+			{"f32 min", f32.minFiniteBits(), uint64(math.Float32bits(-math.MaxFloat32))},
+			{"f32 epsilon", f32.epsilonBits(), uint64(math.Float32bits(1.0 / (1 << 23)))},
+			{"f64 -inf", infinityBits(f64, true), math.Float64bits(math.Inf(-1))},
+			{"f64 max", f64.maxFiniteBits(), math.Float64bits(math.MaxFloat64)},
+			{"f64 epsilon", f64.epsilonBits(), math.Float64bits(math.Nextafter(1, 2) - 1)},
+			{"f16 +inf", infinityBits(floatFormats[dtypes.Float16], false), 0x7C00},
+			{"f16 max", floatFormats[dtypes.Float16].maxFiniteBits(), 0x7BFF},
+			{"bf16 nan", floatFormats[dtypes.BFloat16].nanBits(), 0x7FC0},
+			{"f8E5M2 -inf", infinityBits(floatFormats[dtypes.F8E5M2], true), 0xFC},
+			{"f8E4M3FN nan", floatFormats[dtypes.F8E4M3FN].nanBits(), 0x7F},
+			{"f8E4M3FN max", floatFormats[dtypes.F8E4M3FN].maxFiniteBits(), 0x7E},
+			{"f8E4M3FN epsilon", floatFormats[dtypes.F8E4M3FN].epsilonBits(), 0x20},
+			{"f8E4M3FNUZ nan", floatFormats[dtypes.F8E4M3FNUZ].nanBits(), 0x80},
+			{"f8E4M3FNUZ min", floatFormats[dtypes.F8E4M3FNUZ].minFiniteBits(), 0xFF},
+			{"f8E3M4 epsilon", floatFormats[dtypes.F8E3M4].epsilonBits(), 0x04},
+			{"f8E8M0FNU max", floatFormats[dtypes.F8E8M0FNU].maxFiniteBits(), 0xFE},
+			{"f8E8M0FNU epsilon", floatFormats[dtypes.F8E8M0FNU].epsilonBits(), 0x7F},
+		} {
+			if tc.got != tc.want {
+				t.Errorf("%s: got %#x, want %#x", tc.name, tc.got, tc.want)
+			}
+		}
+	})
+
+	t.Run("from float64", func(t *testing.T) {
+		for _, tc := range []struct {
+			dtype dtypes.DType
+			value float64
+			want  uint64
+		}{
+			{dtypes.Float32, 0.1, uint64(math.Float32bits(0.1))},
+			{dtypes.Float32, -1e40, uint64(math.Float32bits(float32(math.Inf(-1))))},
+			{dtypes.Float16, 65504, 0x7BFF},
+			{dtypes.Float16, 65520, 0x7C00}, // Ties to even rounds up to +Inf.
+			{dtypes.BFloat16, 1, 0x3F80},
+			{dtypes.BFloat16, math.NaN(), 0x7FC0},
+			{dtypes.F8E4M3FN, 1, 0x38},
+			{dtypes.F8E4M3FN, 464, 0x7E}, // Ties to even rounds down to the max finite value.
+			{dtypes.F8E4M3FN, 500, 0x7F}, // No infinities: NaN.
+			{dtypes.F8E4M3FN, math.Copysign(0, -1), 0x80},
+			{dtypes.F8E5M2, -1e6, 0xFC},
+			{dtypes.F8E5M2, 0x1p-16, 0x01},                  // Smallest subnormal.
+			{dtypes.F8E5M2, 0x1.8p-16, 0x02},                // Ties to even.
+			{dtypes.F8E5M2, 0x1p-18, 0x00},                  // Underflow.
+			{dtypes.F8E5M2, 0x1.ep-15, 0x04},                // Carries to the smallest normal.
+			{dtypes.F8E4M3FNUZ, 1, 0x40},                    // Bias 8.
+			{dtypes.F8E4M3FNUZ, math.Copysign(0, -1), 0x00}, // No negative zero.
+			{dtypes.F8E8M0FNU, 1, 0x7F},
+			{dtypes.F8E8M0FNU, 3, 0x80}, // Ties to even exponent.
+			{dtypes.F8E8M0FNU, 0, 0xFF}, // No zero: NaN.
+			{dtypes.F8E8M0FNU, 0x1p-200, 0x00},
+		} {
+			if got := floatFormats[tc.dtype].bitsFromFloat64(tc.value); got != tc.want {
+				t.Errorf("%s from %g: got %#x, want %#x", tc.dtype, tc.value, got, tc.want)
+			}
+		}
+	})
+
+	t.Run("rendering", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		inf := must(fn.ConstantInf(dtypes.Float16, true))
+		nan := must(fn.ConstantNaN(dtypes.F8E4M3FN))
+		maxF64 := must(fn.ConstantMaxFinite(dtypes.Float64))
+		if err := fn.Return(inf, nan, maxF64); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		program := string(must(builder.Build()))
+		for _, want := range []string{
+			`{ value = dense<0xfc00> : tensor<f16> }`,
+			`{ value = dense<0x7f> : tensor<f8E4M3FN> }`,
+			`{ value = dense<0x7fefffffffffffff> : tensor<f64> }`,
+		} {
+			if !strings.Contains(program, want) {
+				t.Errorf("program missing %q:\n%s", want, program)
+			}
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		fn := New(t.Name()).Main()
+		if _, err := fn.ConstantInf(dtypes.F8E4M3FN, false); err == nil {
+			t.Error("expected error for ConstantInf(f8E4M3FN), got nil")
+		}
+		if _, err := fn.ConstantNaN(dtypes.Int32); err == nil {
+			t.Error("expected error for ConstantNaN(Int32), got nil")
+		}
+	})
+}
+
+func TestFloatFormatContains(t *testing.T) {
+	for _, tc := range []struct {
+		dtype, other dtypes.DType
+		want         bool
+	}{
+		{dtypes.Float32, dtypes.BFloat16, true},
+		{dtypes.Float32, dtypes.Float16, true},
+		{dtypes.Float64, dtypes.Float32, true},
+		{dtypes.Float16, dtypes.F8E5M2, true},
+		{dtypes.Float32, dtypes.F8E8M0FNU, true},
+		{dtypes.BFloat16, dtypes.BFloat16, true},
+		{dtypes.Float16, dtypes.BFloat16, false}, // Smaller range.
+		{dtypes.BFloat16, dtypes.Float16, false}, // Fewer mantissa bits.
+		{dtypes.F8E4M3, dtypes.F8E4M3FN, false},  // F8E4M3FN uses the all-ones exponent for finite values.
+		{dtypes.Float32, dtypes.Float64, false},
+	} {
+		if got := floatFormats[tc.dtype].contains(floatFormats[tc.other]); got != tc.want {
+			t.Errorf("%s contains %s: got %v, wanted %v", tc.dtype, tc.other, got, tc.want)
+		}
+	}
+
+	for _, tc := range []struct {
+		dtype, integer dtypes.DType
+		want           bool
+	}{
+		{dtypes.Float64, dtypes.Int32, true},
+		{dtypes.Float64, dtypes.Uint32, true},
+		{dtypes.Float32, dtypes.Int16, true},
+		{dtypes.BFloat16, dtypes.Int8, true},
+		{dtypes.BFloat16, dtypes.Bool, true},
+		{dtypes.F8E4M3FN, dtypes.S4, true},
+		{dtypes.Float32, dtypes.Int32, false}, // 24 bits significand.
+		{dtypes.Float64, dtypes.Int64, false}, // 53 bits significand.
+		{dtypes.BFloat16, dtypes.Uint16, false},
+		{dtypes.F8E8M0FNU, dtypes.Bool, false}, // No zero.
+	} {
+		if got := floatFormats[tc.dtype].containsIntegers(tc.integer); got != tc.want {
+			t.Errorf("%s contains the values of %s: got %v, wanted %v", tc.dtype, tc.integer, got, tc.want)
+		}
+	}
+}
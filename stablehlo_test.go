@@ -2,12 +2,18 @@ package stablehlo
 
 import (
 	"fmt"
+	"math"
+	"slices"
 	"strings"
 	"testing"
 
 	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/gopjrt/dtypes/bfloat16"
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/types"
 	"github.com/gomlx/stablehlo/types/shapes"
 	"github.com/gomlx/stablehlo/types/shardy"
+	"github.com/x448/float16"
 )
 
 func must[T any](value T, err error) T {
@@ -129,6 +135,863 @@ func TestBuilder(t *testing.T) {
     "stablehlo.return"(%0) : (tensor<f64>) -> ()
   }
 }
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+	})
+
+	t.Run("multiple inputs", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		weights := must(fn.NamedInputs([]string{"w0", "b0"},
+			[]shapes.Shape{shapes.Make(dtypes.Float32, 2, 3), shapes.Make(dtypes.Float32, 3)}))
+		x := must(fn.MultiInput([]shapes.Shape{shapes.Make(dtypes.Float32, 2)}))[0]
+		if _, err := fn.NamedInputs([]string{"w1", "b0"},
+			[]shapes.Shape{shapes.Make(dtypes.Float32, 3), shapes.Make(dtypes.Float32, 3)}); err == nil {
+			t.Error("expected error for a duplicate input name, got nil")
+		}
+		if _, err := fn.NamedInputs([]string{"w1"}, nil); err == nil {
+			t.Error("expected error for a different number of names and shapes, got nil")
+		}
+		if _, err := fn.MultiInput([]shapes.Shape{shapes.Make(dtypes.Float32), shapes.Make(dtypes.InvalidDType)}); err == nil {
+			t.Error("expected error for an invalid shape, got nil")
+		}
+		y := must(DotGeneral(x, []int{0}, nil, weights[0], []int{0}, nil).Done())
+		must0(fn.Return(must(Add(y, weights[1]))))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := "func.func @main(%w0: tensor<2x3xf32>, %b0: tensor<3xf32>, %arg0: tensor<2xf32>) -> tensor<3xf32>"
+		if !strings.Contains(program, want) {
+			t.Fatalf("failed inputs should not be added, wanted %q in the program", want)
+		}
+	})
+
+	t.Run("bounded dynamism", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 8, 3)))
+		length := must(fn.NamedInput("length", shapes.Make(dtypes.Int32)))
+		y := must(SetDimensionSize(x, length, -2))
+		y = must(Tanh(y))
+		if err := fn.Return(y); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestBuilder_bounded_dynamism {
+  func.func @main(%x: tensor<8x3xf32>, %length: tensor<i32>) -> tensor<?x3xf32, #stablehlo.bounds<8, ?>> {
+    %0 = "stablehlo.set_dimension_size"(%x, %length) { dimension = 0 : i64 } : (tensor<8x3xf32>, tensor<i32>) -> tensor<?x3xf32, #stablehlo.bounds<8, ?>>
+    %1 = "stablehlo.tanh"(%0) : (tensor<?x3xf32, #stablehlo.bounds<8, ?>>) -> tensor<?x3xf32, #stablehlo.bounds<8, ?>>
+    "stablehlo.return"(%1) : (tensor<?x3xf32, #stablehlo.bounds<8, ?>>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+
+		// Negative axes are counted from the end.
+		fn = New(t.Name()).Main()
+		x = must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 8, 3)))
+		must(GetDimensionSize(x, -1))
+		if got := fn.Statements[len(fn.Statements)-1].Attributes["dimension"]; got != int64(1) {
+			t.Errorf("GetDimensionSize(x, -1) got dimension %v, wanted 1", got)
+		}
+		if _, err := GetDimensionSize(x, -3); err == nil {
+			t.Error("expected error for GetDimensionSize with an out of range axis, got nil")
+		}
+		if _, err := SetDimensionSize(x, must(fn.ConstantFromScalar(int32(2))), 2); err == nil {
+			t.Error("expected error for SetDimensionSize with an out of range axis, got nil")
+		}
+	})
+
+	t.Run("dynamic iota", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		length := must(fn.NamedInput("length", shapes.Make(dtypes.Int32, 2)))
+		shape := shapes.Make(dtypes.Float32, shapes.DynamicDim, 3).WithBounds(8, shapes.DynamicDim)
+		y := must(fn.DynamicIota(length, shape, -1))
+		must0(fn.Return(y))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestBuilder_dynamic_iota {
+  func.func @main(%length: tensor<2xi32>) -> tensor<?x3xf32, #stablehlo.bounds<8, ?>> {
+    %0 = "stablehlo.dynamic_iota"(%length) { iota_dimension = 1 : i64 } : (tensor<2xi32>) -> tensor<?x3xf32, #stablehlo.bounds<8, ?>>
+    "stablehlo.return"(%0) : (tensor<?x3xf32, #stablehlo.bounds<8, ?>>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+
+		// Constant outputShape are checked against the shape.
+		fn = New(t.Name()).Main()
+		if _, err := fn.DynamicIota(must(fn.ConstantFromFlatAndDimensions([]int32{5, 3}, 2)), shape, 0); err != nil {
+			t.Errorf("unexpected error for a constant outputShape within the bounds: %v", err)
+		}
+		for _, dims := range [][]int32{{9, 3}, {5, 2}, {-1, 3}} {
+			if _, err := fn.DynamicIota(must(fn.ConstantFromFlatAndDimensions(dims, 2)), shape, 0); err == nil {
+				t.Errorf("expected error for constant outputShape %v incompatible with %s, got nil", dims, shape)
+			}
+		}
+		if _, err := fn.DynamicIota(must(fn.ConstantFromFlatAndDimensions([]int32{5, 3}, 2)),
+			shapes.Make(dtypes.Float32, shapes.DynamicDim, 3), 0); err == nil {
+			t.Error("expected error for an unbounded shape, got nil")
+		}
+	})
+
+	t.Run("convolution accumulation", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.BFloat16, 1, 2, 5)))
+		kernel := must(fn.NamedInput("kernel", shapes.Make(dtypes.BFloat16, 3, 2, 2)))
+		y := must(ConvolutionWithAccumulation(dtypes.Float32, x, kernel,
+			nil, nil, nil, nil,
+			0, 1, []int{2},
+			1, 0, []int{2},
+			0, 1, []int{2},
+			1, 1,
+			types.DotGeneralPrecisionDefault, types.DotGeneralPrecisionDefault))
+		if err := fn.Return(y); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		for _, want := range []string{
+			`: (tensor<1x2x5xbf16>, tensor<3x2x2xbf16>) -> tensor<1x3x4xf32>`,
+			`%1 = "stablehlo.convert"(%0) : (tensor<1x3x4xf32>) -> tensor<1x3x4xbf16>`,
+		} {
+			if !strings.Contains(program, want) {
+				t.Errorf("program missing %q", want)
+			}
+		}
+
+		fn = New(t.Name()).Main()
+		x = must(fn.NamedInput("x", shapes.Make(dtypes.BFloat16, 1, 2, 5)))
+		kernel = must(fn.NamedInput("kernel", shapes.Make(dtypes.BFloat16, 3, 2, 2)))
+		for _, accumulationDType := range []dtypes.DType{dtypes.InvalidDType, dtypes.Int32, dtypes.F8E4M3FN, dtypes.Float16} {
+			_, err := ConvolutionWithAccumulation(accumulationDType, x, kernel,
+				nil, nil, nil, nil,
+				0, 1, []int{2},
+				1, 0, []int{2},
+				0, 1, []int{2},
+				1, 1,
+				types.DotGeneralPrecisionDefault, types.DotGeneralPrecisionDefault)
+			if err == nil {
+				t.Errorf("expected error for accumulation dtype %s of bfloat16 operands, got nil", accumulationDType)
+			}
+		}
+
+		// Integer operands need as many significand bits as their value bits; complex operands are not supported.
+		for _, tc := range []struct{ operandDType, accumulationDType dtypes.DType }{
+			{dtypes.Int32, dtypes.Float32},
+			{dtypes.Int64, dtypes.Float64},
+			{dtypes.Complex64, dtypes.Float64},
+		} {
+			x = must(fn.Input(shapes.Make(tc.operandDType, 1, 2, 5)))
+			kernel = must(fn.Input(shapes.Make(tc.operandDType, 3, 2, 2)))
+			_, err := ConvolutionWithAccumulation(tc.accumulationDType, x, kernel,
+				nil, nil, nil, nil,
+				0, 1, []int{2},
+				1, 0, []int{2},
+				0, 1, []int{2},
+				1, 1,
+				types.DotGeneralPrecisionDefault, types.DotGeneralPrecisionDefault)
+			if err == nil {
+				t.Errorf("expected error for accumulation dtype %s of %s operands, got nil",
+					tc.accumulationDType, tc.operandDType)
+			}
+		}
+	})
+
+	t.Run("is finite", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.BFloat16, 3)))
+		y := must(fn.NamedInput("y", shapes.Make(dtypes.F8E4M3FN, 2)))
+		must0(fn.Return(must(IsFinite(x)), must(IsFinite(y))))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestBuilder_is_finite {
+  func.func @main(%x: tensor<3xbf16>, %y: tensor<2xf8E4M3FN>) -> (tensor<3xi1>, tensor<2xi1>) {
+    %0 = "stablehlo.is_finite"(%x) : (tensor<3xbf16>) -> tensor<3xi1>
+    %1 = "stablehlo.is_finite"(%y) : (tensor<2xf8E4M3FN>) -> tensor<2xi1>
+    "stablehlo.return"(%0, %1) : (tensor<3xi1>, tensor<2xi1>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+
+		fn = New(t.Name()).Main()
+		z := must(fn.NamedInput("z", shapes.Make(dtypes.Complex64, 3)))
+		if _, err := IsFinite(z); err == nil || !strings.Contains(err.Error(), "is not a floating point type") {
+			t.Errorf("expected error for IsFinite of complex numbers, got %v", err)
+		}
+	})
+
+	t.Run("dot algorithm preset", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2, 3)))
+		y := must(fn.NamedInput("y", shapes.Make(dtypes.Float32, 3)))
+		f8x := must(fn.NamedInput("f8x", shapes.Make(dtypes.F8E5M2, 2, 3)))
+		f8y := must(fn.NamedInput("f8y", shapes.Make(dtypes.F8E5M2, 3)))
+		dot := must(DotGeneral(x, []int{1}, nil, y, []int{0}, nil).
+			AlgorithmPreset(types.DotAlgorithmBF16_BF16_F32_X3).Done())
+		f8Dot := must(DotGeneral(f8x, []int{1}, nil, f8y, []int{0}, nil).
+			OutputDType(dtypes.Float32).
+			AlgorithmPreset(types.DotAlgorithmANY_F8_ANY_F8_F32_FAST_ACCUM).Done())
+		must0(fn.Return(dot, f8Dot))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestBuilder_dot_algorithm_preset {
+  func.func @main(%x: tensor<2x3xf32>, %y: tensor<3xf32>, %f8x: tensor<2x3xf8E5M2>, %f8y: tensor<3xf8E5M2>) -> (tensor<2xf32>, tensor<2xf32>) {
+    %0 = "stablehlo.dot_general"(%x, %y) {
+      algorithm = #stablehlo.dot_algorithm<
+  lhs_precision_type = bf16,
+  rhs_precision_type = bf16,
+  accumulation_type = f32,
+  lhs_component_count = 1,
+  rhs_component_count = 1,
+  num_primitive_operations = 3,
+  allow_imprecise_accumulation = false>,
+      dot_dimension_numbers = #stablehlo.dot<
+  lhs_batching_dimensions = [],
+  rhs_batching_dimensions = [],
+  lhs_contracting_dimensions = [1],
+  rhs_contracting_dimensions = [0]
+>,
+      precision_config = [#stablehlo<precision DEFAULT>, #stablehlo<precision DEFAULT>]
+    } : (tensor<2x3xf32>, tensor<3xf32>) -> tensor<2xf32>
+    %1 = "stablehlo.dot_general"(%f8x, %f8y) {
+      algorithm = #stablehlo.dot_algorithm<
+  lhs_precision_type = f8E5M2,
+  rhs_precision_type = f8E5M2,
+  accumulation_type = f32,
+  lhs_component_count = 1,
+  rhs_component_count = 1,
+  num_primitive_operations = 1,
+  allow_imprecise_accumulation = true>,
+      dot_dimension_numbers = #stablehlo.dot<
+  lhs_batching_dimensions = [],
+  rhs_batching_dimensions = [],
+  lhs_contracting_dimensions = [1],
+  rhs_contracting_dimensions = [0]
+>,
+      precision_config = [#stablehlo<precision DEFAULT>, #stablehlo<precision DEFAULT>]
+    } : (tensor<2x3xf8E5M2>, tensor<3xf8E5M2>) -> tensor<2xf32>
+    "stablehlo.return"(%0, %1) : (tensor<2xf32>, tensor<2xf32>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+
+		// Preset names are parsed, in the XLA notation.
+		if preset, err := types.DotGeneralAlgorithmPresetString("TF32_TF32_F32_X3"); err != nil || preset != types.DotAlgorithmTF32_TF32_F32_X3 {
+			t.Errorf("DotGeneralAlgorithmPresetString(TF32_TF32_F32_X3) = %s, %v", preset, err)
+		}
+		for _, preset := range types.DotGeneralAlgorithmPresetValues() {
+			if preset.Algorithm() == nil {
+				t.Errorf("preset %s has no algorithm", preset)
+			}
+		}
+
+		// Errors: ANY_F8 with non-f8 operands, and invalid presets.
+		fn = New(t.Name()).Main()
+		x = must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		for _, preset := range []types.DotGeneralAlgorithmPreset{types.DotAlgorithmANY_F8_ANY_F8_F32, 100} {
+			if _, err := DotGeneral(x, []int{0}, nil, x, []int{0}, nil).AlgorithmPreset(preset).Done(); err == nil {
+				t.Errorf("expected error for preset %s with float32 operands, got nil", preset)
+			}
+		}
+	})
+
+	t.Run("result accuracy", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		y := must(ExponentialWithAccuracy(x, types.ResultAccuracy{Mode: types.ResultAccuracyHighest}))
+		y = must(TanhWithAccuracy(y, types.ResultAccuracy{Mode: types.ResultAccuracyTolerance, Atol: 1e-5, Ulps: 2}))
+		if err := fn.Return(y); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestBuilder_result_accuracy {
+  func.func @main(%x: tensor<3xf32>) -> tensor<3xf32> {
+    %0 = "stablehlo.exponential"(%x) { result_accuracy = #stablehlo.result_accuracy<atol = 0.000000e+00, rtol = 0.000000e+00, ulps = 0, mode = #stablehlo.result_accuracy_mode<HIGHEST>> } : (tensor<3xf32>) -> tensor<3xf32>
+    %1 = "stablehlo.tanh"(%0) { result_accuracy = #stablehlo.result_accuracy<atol = 1.000000e-05, rtol = 0.000000e+00, ulps = 2, mode = #stablehlo.result_accuracy_mode<TOLERANCE>> } : (tensor<3xf32>) -> tensor<3xf32>
+    "stablehlo.return"(%1) : (tensor<3xf32>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+		if _, err := SqrtWithAccuracy(x, types.ResultAccuracy{Mode: types.ResultAccuracyDefault, Rtol: 0.1}); err == nil {
+			t.Error("expected error for tolerance set with the default mode, got nil")
+		}
+	})
+
+	t.Run("booleans", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.ConstantFromFlatAndDimensions([]bool{true, false, false, true}, 2, 2))
+		y := must(Convert(x, dtypes.Int8))
+		if _, err := BitcastConvert(x, dtypes.Int8); err == nil {
+			t.Error("expected error bitcasting booleans, got nil")
+		}
+		if err := fn.Return(x, y); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestBuilder_booleans {
+  func.func @main() -> (tensor<2x2xi1>, tensor<2x2xi8>) {
+    %0 = "stablehlo.constant"() { value = dense<[[true, false], [false, true]]> : tensor<2x2xi1> } : () -> tensor<2x2xi1>
+    %1 = "stablehlo.convert"(%0) : (tensor<2x2xi1>) -> tensor<2x2xi8>
+    "stablehlo.return"(%0, %1) : (tensor<2x2xi1>, tensor<2x2xi8>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+	})
+
+	t.Run("complex", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Complex64, 2)))
+		y := must(fn.NamedInput("y", shapes.Make(dtypes.Float32, 2)))
+		abs := must(Abs(x))
+		if abs.Shape().DType != dtypes.Float32 {
+			t.Errorf("Abs of complex64 should be float32, got %s", abs.Shape())
+		}
+		if conjY := must(Conjugate(y)); conjY != y {
+			t.Error("Conjugate of a float value should return the value itself")
+		}
+		if _, err := Real(y); err == nil {
+			t.Error("expected error for Real of a float value, got nil")
+		}
+		if _, err := Imag(y); err == nil {
+			t.Error("expected error for Imag of a float value, got nil")
+		}
+		if _, err := ComplexFromPolar(must(Convert(y, dtypes.Int32)), y); err == nil {
+			t.Error("expected error for ComplexFromPolar with an integer magnitude, got nil")
+		}
+		if err := fn.Return(abs, must(Conjugate(x)), must(ComplexFromPolar(abs, y))); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestBuilder_complex {
+  func.func @main(%x: tensor<2xcomplex<f32>>, %y: tensor<2xf32>) -> (tensor<2xf32>, tensor<2xcomplex<f32>>, tensor<2xcomplex<f32>>) {
+    %0 = "stablehlo.abs"(%x) : (tensor<2xcomplex<f32>>) -> tensor<2xf32>
+    %1 = "stablehlo.convert"(%y) : (tensor<2xf32>) -> tensor<2xi32>
+    %2 = "stablehlo.real"(%x) : (tensor<2xcomplex<f32>>) -> tensor<2xf32>
+    %3 = "stablehlo.imag"(%x) : (tensor<2xcomplex<f32>>) -> tensor<2xf32>
+    %4 = "stablehlo.negate"(%3) : (tensor<2xf32>) -> tensor<2xf32>
+    %5 = "stablehlo.complex"(%2, %4) : (tensor<2xf32>, tensor<2xf32>) -> tensor<2xcomplex<f32>>
+    %6 = "stablehlo.cosine"(%y) : (tensor<2xf32>) -> tensor<2xf32>
+    %7 = "stablehlo.sine"(%y) : (tensor<2xf32>) -> tensor<2xf32>
+    %8 = "stablehlo.multiply"(%0, %6) : (tensor<2xf32>, tensor<2xf32>) -> tensor<2xf32>
+    %9 = "stablehlo.multiply"(%0, %7) : (tensor<2xf32>, tensor<2xf32>) -> tensor<2xf32>
+    %10 = "stablehlo.complex"(%8, %9) : (tensor<2xf32>, tensor<2xf32>) -> tensor<2xcomplex<f32>>
+    "stablehlo.return"(%0, %5, %10) : (tensor<2xf32>, tensor<2xcomplex<f32>>, tensor<2xcomplex<f32>>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+	})
+
+	t.Run("compare auto", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		for dtype, want := range map[dtypes.DType]types.ComparisonType{
+			dtypes.Float32:   types.CompareFloat,
+			dtypes.BFloat16:  types.CompareFloat,
+			dtypes.Complex64: types.CompareFloat,
+			dtypes.Int8:      types.CompareSigned,
+			dtypes.Int64:     types.CompareSigned,
+			dtypes.Uint16:    types.CompareUnsigned,
+			dtypes.Bool:      types.CompareUnsigned,
+		} {
+			x := must(fn.Input(shapes.Make(dtype, 2)))
+			result := must(CompareAuto(x, x, types.CompareEQ))
+			if result.Shape().DType != dtypes.Bool {
+				t.Errorf("CompareAuto of %s returned %s, want a boolean", dtype, result.Shape())
+			}
+			got := fn.Statements[len(fn.Statements)-1].Attributes["compare_type"]
+			if got != want {
+				t.Errorf("CompareAuto of %s used comparison type %v, want %v", dtype, got, want)
+			}
+		}
+		x := must(fn.Input(shapes.Make(dtypes.BFloat16)))
+		must(Compare(x, x, types.CompareLT, types.CompareAuto))
+		if got := fn.Statements[len(fn.Statements)-1].Attributes["compare_type"]; got != types.CompareFloat {
+			t.Errorf("Compare with CompareAuto of bfloat16 used comparison type %v, want CompareFloat", got)
+		}
+		must(Compare(x, x, types.CompareLT, types.CompareTotalOrder))
+		if got := fn.Statements[len(fn.Statements)-1].Attributes["compare_type"]; got != types.CompareTotalOrder {
+			t.Errorf("Compare with CompareTotalOrder of bfloat16 used comparison type %v", got)
+		}
+		x = must(fn.Input(shapes.Make(dtypes.Int32)))
+		y := must(fn.Input(shapes.Make(dtypes.Uint32)))
+		if _, err := CompareAuto(x, y, types.CompareLT); err == nil {
+			t.Error("expected error for CompareAuto of mismatched data types, got nil")
+		}
+	})
+
+	t.Run("reduce keep dims", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Int32, 2, 3, 4)))
+		y := must(fn.NamedInput("y", shapes.Make(dtypes.Float32, 2, 3, 4)))
+		reductionFn := fn.Closure()
+		lhsX := must(reductionFn.NamedInput("lhs_x", shapes.Make(dtypes.Int32)))
+		lhsY := must(reductionFn.NamedInput("lhs_y", shapes.Make(dtypes.Float32)))
+		rhsX := must(reductionFn.NamedInput("rhs_x", shapes.Make(dtypes.Int32)))
+		rhsY := must(reductionFn.NamedInput("rhs_y", shapes.Make(dtypes.Float32)))
+		must0(reductionFn.Return(must(Add(lhsX, rhsX)), must(Add(lhsY, rhsY))))
+		zeroX := must(fn.ConstantFromScalar(int32(0)))
+		zeroY := must(fn.ConstantFromScalar(float32(0)))
+		axes := []int{-1, 0}
+		outputs := must(MultiReduceKeepDims([]*Value{x, y}, []*Value{zeroX, zeroY}, reductionFn, axes...))
+		if !outputs[0].Shape().Equal(shapes.Make(dtypes.Int32, 1, 3, 1)) ||
+			!outputs[1].Shape().Equal(shapes.Make(dtypes.Float32, 1, 3, 1)) {
+			t.Errorf("unexpected MultiReduceKeepDims shapes %s and %s", outputs[0].Shape(), outputs[1].Shape())
+		}
+		if axes[0] != -1 {
+			t.Errorf("MultiReduceKeepDims changed the axes given by the caller to %v", axes)
+		}
+		if _, err := MultiReduceKeepDims([]*Value{x, y}, []*Value{zeroX, zeroY}, reductionFn, 3); err == nil {
+			t.Error("expected error for invalid axis, got nil")
+		}
+	})
+
+	t.Run("reduction closure", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2, 3)))
+		y := must(fn.NamedInput("y", shapes.Make(dtypes.Int32, 2, 3)))
+		maxFn := must(fn.NewReductionClosure(ReductionMaximum, dtypes.Float32, dtypes.Int32))
+		if again := must(fn.NewReductionClosure(ReductionMaximum, dtypes.Float32, dtypes.Int32)); again != maxFn {
+			t.Error("NewReductionClosure should return the cached closure for the same operation and dtypes")
+		}
+		if other := must(fn.NewReductionClosure(ReductionMinimum, dtypes.Float32, dtypes.Int32)); other == maxFn {
+			t.Error("NewReductionClosure returned the same closure for different operations")
+		}
+		lowestX := must(fn.ConstantFromScalar(float32(-1e9)))
+		lowestY := must(fn.ConstantFromScalar(int32(-1000)))
+		outputs := must(MultiReduce([]*Value{x, y}, []*Value{lowestX, lowestY}, maxFn, 1))
+		must0(fn.Return(outputs...))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s\n", program)
+		want := `module @TestBuilder_reduction_closure {
+  func.func @main(%x: tensor<2x3xf32>, %y: tensor<2x3xi32>) -> (tensor<2xf32>, tensor<2xi32>) {
+    %4 = "stablehlo.constant"() { value = dense<-1.0e+09> : tensor<f32> } : () -> tensor<f32>
+    %5 = "stablehlo.constant"() { value = dense<-1000> : tensor<i32> } : () -> tensor<i32>
+    %6, %7 = "stablehlo.reduce"(%x, %y, %4, %5) ({
+      ^reductionFn(%lhs0: tensor<f32>, %lhs1: tensor<i32>, %rhs0: tensor<f32>, %rhs1: tensor<i32>) :
+          %0 = "stablehlo.maximum"(%lhs0, %rhs0) : (tensor<f32>, tensor<f32>) -> tensor<f32>
+          %1 = "stablehlo.maximum"(%lhs1, %rhs1) : (tensor<i32>, tensor<i32>) -> tensor<i32>
+          "stablehlo.return"(%0, %1) : (tensor<f32>, tensor<i32>) -> ()
+    }) { dimensions = array<i64: 1> } : (tensor<2x3xf32>, tensor<2x3xi32>, tensor<f32>, tensor<i32>) -> (tensor<2xf32>, tensor<2xi32>)
+    "stablehlo.return"(%6, %7) : (tensor<2xf32>, tensor<2xi32>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+
+		if _, err := fn.NewReductionClosure(ReductionType(-1), dtypes.Float32); err == nil {
+			t.Error("expected error for unsupported reduction operation, got nil")
+		}
+		if _, err := fn.NewReductionClosure(ReductionAnd, dtypes.Float32); err == nil {
+			t.Error("expected error for logical reduction of floats, got nil")
+		}
+		if _, err := fn.NewReductionClosure(ReductionAdd); err == nil {
+			t.Error("expected error for reduction closure without dtypes, got nil")
+		}
+	})
+
+	t.Run("dynamic pad and gather", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 8, 4)))
+		paddings := must(fn.NamedInput("paddings", shapes.Make(dtypes.Int32, 2)))
+		sizes := must(fn.NamedInput("sizes", shapes.Make(dtypes.Int32, 2)))
+		indices := must(fn.NamedInput("indices", shapes.Make(dtypes.Int32, 3, 1)))
+		zero := must(fn.ConstantFromScalar(float32(0)))
+		noPadding := must(fn.ConstantFromFlatAndDimensions([]int32{0, 0}, 2))
+		padded := must(DynamicPad(x, zero, paddings, paddings, noPadding))
+		if want := shapes.Make(dtypes.Float32, shapes.DynamicDim, shapes.DynamicDim); !padded.Shape().Equal(want) {
+			t.Errorf("DynamicPad shape = %s, want %s", padded.Shape(), want)
+		}
+		staticPadded := must(DynamicPad(x, zero, noPadding, must(fn.ConstantFromFlatAndDimensions([]int32{1, 2}, 2)), noPadding))
+		if want := shapes.Make(dtypes.Float32, 9, 6); !staticPadded.Shape().Equal(want) {
+			t.Errorf("DynamicPad with constant paddings shape = %s, want %s", staticPadded.Shape(), want)
+		}
+		gathered := must(DynamicGather(x, indices, sizes, 1, []int{1}, []int{0}, nil, nil, []int{0}, true))
+		must0(fn.Return(padded, staticPadded, gathered))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s\n", program)
+		want := `module @TestBuilder_dynamic_pad_and_gather {
+  func.func @main(%x: tensor<8x4xf32>, %paddings: tensor<2xi32>, %sizes: tensor<2xi32>, %indices: tensor<3x1xi32>) -> (tensor<?x?xf32>, tensor<9x6xf32>, tensor<3x?xf32, #stablehlo.bounds<?, 4>>) {
+    %0 = "stablehlo.constant"() { value = dense<0.0> : tensor<f32> } : () -> tensor<f32>
+    %1 = "stablehlo.constant"() { value = dense<[0, 0]> : tensor<2xi32> } : () -> tensor<2xi32>
+    %2 = "stablehlo.dynamic_pad"(%x, %0, %paddings, %paddings, %1) : (tensor<8x4xf32>, tensor<f32>, tensor<2xi32>, tensor<2xi32>, tensor<2xi32>) -> tensor<?x?xf32>
+    %3 = "stablehlo.constant"() { value = dense<[1, 2]> : tensor<2xi32> } : () -> tensor<2xi32>
+    %4 = "stablehlo.dynamic_pad"(%x, %0, %1, %3, %1) : (tensor<8x4xf32>, tensor<f32>, tensor<2xi32>, tensor<2xi32>, tensor<2xi32>) -> tensor<9x6xf32>
+    %5 = "stablehlo.dynamic_gather"(%x, %indices, %sizes) {
+      dimension_numbers = #stablehlo.gather<
+  offset_dims = [1],
+  collapsed_slice_dims = [0],
+  operand_batching_dims = [],
+  start_indices_batching_dims = [],
+  start_index_map = [0],
+  index_vector_dim = 1>,
+      indices_are_sorted = true
+    } : (tensor<8x4xf32>, tensor<3x1xi32>, tensor<2xi32>) -> tensor<3x?xf32, #stablehlo.bounds<?, 4>>
+    "stablehlo.return"(%2, %4, %5) : (tensor<?x?xf32>, tensor<9x6xf32>, tensor<3x?xf32, #stablehlo.bounds<?, 4>>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+		requireRoundTrip(t, builder)
+
+		fn = New(t.Name()).Main()
+		x = must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 8, 4)))
+		indices = must(fn.NamedInput("indices", shapes.Make(dtypes.Int32, 3, 1)))
+		zero = must(fn.ConstantFromScalar(float32(0)))
+		if _, err := DynamicPad(x, zero, indices, indices, indices); err == nil {
+			t.Error("expected error for DynamicPad with a 2D padding, got nil")
+		}
+		if _, err := DynamicGather(x, indices, must(fn.ConstantFromFlatAndDimensions([]int32{1, 9}, 2)), 1,
+			[]int{1}, []int{0}, nil, nil, []int{0}, false); err == nil {
+			t.Error("expected error for DynamicGather with constant slice sizes larger than the operand, got nil")
+		}
+	})
+
+	t.Run("ConstantFromTensor", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		// 3x4 row-major matrix: view the 2x2 sub-matrix starting at [1, 1], and the transposed first 2 columns.
+		data := []float32{0, 1, 2, 3, 10, 11, 12, 13, 20, 21, 22, 23}
+		subMatrix := must(fn.ConstantFromTensor(data[5:], []int{2, 2}, []int{4, 1}))
+		transposed := must(fn.ConstantFromTensor(data, []int{2, 3}, []int{1, 4}))
+		repeated := must(fn.ConstantFromTensor([]int32{7, 8}, []int{3, 2}, []int{0, 1}))
+		contiguous := must(fn.ConstantFromTensor([]int32{1, 2, 3, 4, 5}, []int{2, 2}, nil))
+		for _, tc := range []struct {
+			value *Value
+			want  string
+		}{
+			{subMatrix, "dense<[[11.0, 12.0], [21.0, 22.0]]> : tensor<2x2xf32>"},
+			{transposed, "dense<[[0.0, 10.0, 20.0], [1.0, 11.0, 21.0]]> : tensor<2x3xf32>"},
+			{repeated, "dense<[[7, 8], [7, 8], [7, 8]]> : tensor<3x2xi32>"},
+			{contiguous, "dense<[[1, 2], [3, 4]]> : tensor<2x2xi32>"},
+		} {
+			got, _ := tc.value.Producer().AttributeString("value")
+			if got != tc.want {
+				t.Errorf("got constant %s, wanted %s", got, tc.want)
+			}
+		}
+		empty := must(fn.ConstantFromTensor([]float32{}, []int{0, 3}, []int{5, 1}))
+		if !empty.Shape().Equal(shapes.Make(dtypes.Float32, 0, 3)) {
+			t.Errorf("unexpected shape %s for empty view", empty.Shape())
+		}
+
+		for _, tc := range []struct {
+			name          string
+			data          any
+			dims, strides []int
+		}{
+			{"beyond the end", data, []int{3, 4}, []int{4, 2}},
+			{"sub-slice beyond the end", data[5:], []int{2, 4}, []int{4, 1}},
+			{"stride overflow", data, []int{2, 2}, []int{math.MaxInt, 1}},
+			{"negative stride", data, []int{2}, []int{-1}},
+			{"empty data", []float32{}, []int{1}, []int{2}},
+			{"empty data with zero stride", []float32{}, []int{1}, []int{0}},
+			{"strides rank", data, []int{2, 2}, []int{1}},
+			{"negative dimension", data, []int{-1}, []int{1}},
+			{"not a slice", 1.0, []int{}, []int{}},
+		} {
+			if _, err := fn.ConstantFromTensor(tc.data, tc.dims, tc.strides); err == nil {
+				t.Errorf("%s: expected error, got nil", tc.name)
+			}
+		}
+	})
+
+	t.Run("build into", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		must0(fn.Return(must(Tanh(x))))
+		want := string(must(builder.Build()))
+		if stats := builder.LastBuildStats(); stats.Size != len(want) || stats.Reused {
+			t.Errorf("unexpected stats for Build: %+v", stats)
+		}
+
+		// Too small buffer: a new one is allocated.
+		buf := must(builder.BuildInto(make([]byte, 0, 10)))
+		if string(buf) != want {
+			t.Fatalf("BuildInto returned a different program:\n%s", buf)
+		}
+		if stats := builder.LastBuildStats(); stats.Reused || stats.ProvidedCapacity != 10 || stats.Capacity < len(want) {
+			t.Errorf("unexpected stats for BuildInto with a small buffer: %+v", stats)
+		}
+
+		// The returned buffer (with contents) is reused.
+		copy(buf, "garbage")
+		reused := must(builder.BuildInto(buf))
+		if string(reused) != want {
+			t.Fatalf("BuildInto returned a different program:\n%s", reused)
+		}
+		if &reused[0] != &buf[0] {
+			t.Error("BuildInto didn't reuse the buffer")
+		}
+		if stats := builder.LastBuildStats(); !stats.Reused || stats.Size != len(want) || stats.Capacity != cap(buf) {
+			t.Errorf("unexpected stats for BuildInto with a reused buffer: %+v", stats)
+		}
+
+		// Errors don't change the stats.
+		builder.Main()
+		if _, err := builder.BuildInto(buf); err == nil {
+			t.Error("expected error building a program with a function not returned, got nil")
+		}
+		if stats := builder.LastBuildStats(); !stats.Reused || stats.Size != len(want) {
+			t.Errorf("stats changed after a failed build: %+v", stats)
+		}
+	})
+
+	t.Run("multi reduce mixed dtypes", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2, 3)))
+		idx := must(fn.NamedInput("idx", shapes.Make(dtypes.Int32, 2, 3)))
+		z := must(fn.NamedInput("z", shapes.Make(dtypes.BFloat16, 2, 3)))
+		mask := must(fn.NamedInput("mask", shapes.Make(dtypes.Bool, 2, 3)))
+
+		// Closure with 4 reductions of different dtypes: the maximum of x and its index, the sum of z (accumulated in
+		// float32) and the logical or of the mask.
+		inputShapes := []shapes.Shape{
+			shapes.Make(dtypes.Float32), shapes.Make(dtypes.Int32), shapes.Make(dtypes.Float32), shapes.Make(dtypes.Bool)}
+		reductionFn, args, err := fn.ClosureWithSignature(slices.Concat(inputShapes, inputShapes), inputShapes...)
+		if err != nil {
+			t.Fatalf("failed to create the reduction closure: %+v", err)
+		}
+		lhs, rhs := args[:4], args[4:]
+		isLhsLarger := must(CompareAuto(lhs[0], rhs[0], types.CompareGE))
+		must0(reductionFn.Return(
+			must(Maximum(lhs[0], rhs[0])),
+			must(Select(isLhsLarger, lhs[1], rhs[1])),
+			must(Add(lhs[2], rhs[2])),
+			must(Or(lhs[3], rhs[3]))))
+
+		initialValues := []*Value{
+			must(fn.ConstantFromScalar(float32(math.Inf(-1)))),
+			must(fn.ConstantFromScalar(int32(-1))),
+			must(fn.ConstantFromScalar(bfloat16.FromFloat32(0))),
+			must(fn.ConstantFromScalar(false)),
+		}
+		outputs := must(MultiReduce([]*Value{x, idx, z, mask}, initialValues, reductionFn, 1))
+		for i, dtype := range []dtypes.DType{dtypes.Float32, dtypes.Int32, dtypes.Float32, dtypes.Bool} {
+			if want := shapes.Make(dtype, 2); !outputs[i].Shape().Equal(want) {
+				t.Errorf("output #%d has shape %s, wanted %s", i, outputs[i].Shape(), want)
+			}
+		}
+		keptDims := must(MultiReduceKeepDims([]*Value{x, idx, z, mask}, initialValues, reductionFn, 0))
+		for i, output := range keptDims {
+			if output.Shape().Rank() != 2 || output.Shape().Dimensions[0] != 1 || output.Shape().DType != outputs[i].Shape().DType {
+				t.Errorf("MultiReduceKeepDims output #%d has shape %s", i, output.Shape())
+			}
+		}
+		must0(fn.Return(outputs...))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestBuilder_multi_reduce_mixed_dtypes {
+  func.func @main(%x: tensor<2x3xf32>, %idx: tensor<2x3xi32>, %z: tensor<2x3xbf16>, %mask: tensor<2x3xi1>) -> (tensor<2xf32>, tensor<2xi32>, tensor<2xf32>, tensor<2xi1>) {
+    %5 = "stablehlo.constant"() { value = dense<0xff800000> : tensor<f32> } : () -> tensor<f32>
+    %6 = "stablehlo.constant"() { value = dense<-1> : tensor<i32> } : () -> tensor<i32>
+    %7 = "stablehlo.constant"() { value = dense<0.0> : tensor<bf16> } : () -> tensor<bf16>
+    %8 = "stablehlo.constant"() { value = dense<false> : tensor<i1> } : () -> tensor<i1>
+    %9 = "stablehlo.convert"(%z) : (tensor<2x3xbf16>) -> tensor<2x3xf32>
+    %10 = "stablehlo.convert"(%7) : (tensor<bf16>) -> tensor<f32>
+    %11, %12, %13, %14 = "stablehlo.reduce"(%x, %idx, %9, %mask, %5, %6, %10, %8) ({
+      ^reductionFn(%arg0: tensor<f32>, %arg1: tensor<i32>, %arg2: tensor<f32>, %arg3: tensor<i1>, %arg4: tensor<f32>, %arg5: tensor<i32>, %arg6: tensor<f32>, %arg7: tensor<i1>) :
+          %0 = "stablehlo.compare"(%arg0, %arg4) {
+            compare_type = #stablehlo<comparison_type FLOAT>,
+            comparison_direction = #stablehlo<comparison_direction GE>
+          } : (tensor<f32>, tensor<f32>) -> tensor<i1>
+          %1 = "stablehlo.maximum"(%arg0, %arg4) : (tensor<f32>, tensor<f32>) -> tensor<f32>
+          %2 = "stablehlo.select"(%0, %arg1, %arg5) : (tensor<i1>, tensor<i32>, tensor<i32>) -> tensor<i32>
+          %3 = "stablehlo.add"(%arg2, %arg6) : (tensor<f32>, tensor<f32>) -> tensor<f32>
+          %4 = "stablehlo.or"(%arg3, %arg7) : (tensor<i1>, tensor<i1>) -> tensor<i1>
+          "stablehlo.return"(%1, %2, %3, %4) : (tensor<f32>, tensor<i32>, tensor<f32>, tensor<i1>) -> ()
+    }) { dimensions = array<i64: 1> } : (tensor<2x3xf32>, tensor<2x3xi32>, tensor<2x3xf32>, tensor<2x3xi1>, tensor<f32>, tensor<i32>, tensor<f32>, tensor<i1>) -> (tensor<2xf32>, tensor<2xi32>, tensor<2xf32>, tensor<2xi1>)
+    %15 = "stablehlo.convert"(%z) : (tensor<2x3xbf16>) -> tensor<2x3xf32>
+    %16 = "stablehlo.convert"(%7) : (tensor<bf16>) -> tensor<f32>
+    %17, %18, %19, %20 = "stablehlo.reduce"(%x, %idx, %15, %mask, %5, %6, %16, %8) ({
+      ^reductionFn(%arg0: tensor<f32>, %arg1: tensor<i32>, %arg2: tensor<f32>, %arg3: tensor<i1>, %arg4: tensor<f32>, %arg5: tensor<i32>, %arg6: tensor<f32>, %arg7: tensor<i1>) :
+          %0 = "stablehlo.compare"(%arg0, %arg4) {
+            compare_type = #stablehlo<comparison_type FLOAT>,
+            comparison_direction = #stablehlo<comparison_direction GE>
+          } : (tensor<f32>, tensor<f32>) -> tensor<i1>
+          %1 = "stablehlo.maximum"(%arg0, %arg4) : (tensor<f32>, tensor<f32>) -> tensor<f32>
+          %2 = "stablehlo.select"(%0, %arg1, %arg5) : (tensor<i1>, tensor<i32>, tensor<i32>) -> tensor<i32>
+          %3 = "stablehlo.add"(%arg2, %arg6) : (tensor<f32>, tensor<f32>) -> tensor<f32>
+          %4 = "stablehlo.or"(%arg3, %arg7) : (tensor<i1>, tensor<i1>) -> tensor<i1>
+          "stablehlo.return"(%1, %2, %3, %4) : (tensor<f32>, tensor<i32>, tensor<f32>, tensor<i1>) -> ()
+    }) { dimensions = array<i64: 0> } : (tensor<2x3xf32>, tensor<2x3xi32>, tensor<2x3xf32>, tensor<2x3xi1>, tensor<f32>, tensor<i32>, tensor<f32>, tensor<i1>) -> (tensor<3xf32>, tensor<3xi32>, tensor<3xf32>, tensor<3xi1>)
+    %21 = "stablehlo.reshape"(%17) : (tensor<3xf32>) -> tensor<1x3xf32>
+    %22 = "stablehlo.reshape"(%18) : (tensor<3xi32>) -> tensor<1x3xi32>
+    %23 = "stablehlo.reshape"(%19) : (tensor<3xf32>) -> tensor<1x3xf32>
+    %24 = "stablehlo.reshape"(%20) : (tensor<3xi1>) -> tensor<1x3xi1>
+    "stablehlo.return"(%11, %12, %13, %14) : (tensor<2xf32>, tensor<2xi32>, tensor<2xf32>, tensor<2xi1>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+		requireRoundTrip(t, builder)
+
+		// The reduction function must have one dtype per reduction.
+		fn = New(t.Name()).Main()
+		x = must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2, 3)))
+		idx = must(fn.NamedInput("idx", shapes.Make(dtypes.Int32, 2, 3)))
+		mask = must(fn.NamedInput("mask", shapes.Make(dtypes.Bool, 2, 3)))
+		sumFn := must(fn.NewReductionClosure(ReductionAdd, dtypes.Float32, dtypes.Int32, dtypes.Int32))
+		if _, err := MultiReduce([]*Value{x, idx, mask},
+			[]*Value{must(fn.ConstantFromScalar(float32(0))), must(fn.ConstantFromScalar(int32(0))), must(fn.ConstantFromScalar(false))},
+			sumFn, 1); err == nil || !strings.Contains(err.Error(), "input #2") {
+			t.Errorf("expected error for a bool input reduced by an int32 closure, got %v", err)
+		}
+	})
+
+	t.Run("reduce promotion", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float16, 2, 3)))
+		y := must(fn.NamedInput("y", shapes.Make(dtypes.Int8, 2, 3)))
+		sumF32 := must(fn.NewReductionClosure(ReductionAdd, dtypes.Float32))
+		sumI32 := must(fn.NewReductionClosure(ReductionAdd, dtypes.Int32))
+		// Initial values with the dtype of the input and of the reduction function.
+		xSum := must(Reduce(x, must(fn.ConstantFromScalar(float16.Fromfloat32(0))), sumF32, 1))
+		ySum := must(Reduce(y, must(fn.ConstantFromScalar(int32(0))), sumI32, 0))
+		if xSum.Shape().DType != dtypes.Float32 || ySum.Shape().DType != dtypes.Int32 {
+			t.Fatalf("unexpected output shapes %s and %s", xSum.Shape(), ySum.Shape())
+		}
+		must0(fn.Return(xSum, ySum))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestBuilder_reduce_promotion {
+  func.func @main(%x: tensor<2x3xf16>, %y: tensor<2x3xi8>) -> (tensor<2xf32>, tensor<3xi32>) {
+    %2 = "stablehlo.constant"() { value = dense<0.0> : tensor<f16> } : () -> tensor<f16>
+    %3 = "stablehlo.convert"(%x) : (tensor<2x3xf16>) -> tensor<2x3xf32>
+    %4 = "stablehlo.convert"(%2) : (tensor<f16>) -> tensor<f32>
+    %5 = "stablehlo.reduce"(%3, %4) ({
+      ^reductionFn(%lhs: tensor<f32>, %rhs: tensor<f32>) :
+          %0 = "stablehlo.add"(%lhs, %rhs) : (tensor<f32>, tensor<f32>) -> tensor<f32>
+          "stablehlo.return"(%0) : (tensor<f32>) -> ()
+    }) { dimensions = array<i64: 1> } : (tensor<2x3xf32>, tensor<f32>) -> tensor<2xf32>
+    %6 = "stablehlo.constant"() { value = dense<0> : tensor<i32> } : () -> tensor<i32>
+    %7 = "stablehlo.convert"(%y) : (tensor<2x3xi8>) -> tensor<2x3xi32>
+    %8 = "stablehlo.reduce"(%7, %6) ({
+      ^reductionFn(%lhs: tensor<i32>, %rhs: tensor<i32>) :
+          %1 = "stablehlo.add"(%lhs, %rhs) : (tensor<i32>, tensor<i32>) -> tensor<i32>
+          "stablehlo.return"(%1) : (tensor<i32>) -> ()
+    }) { dimensions = array<i64: 0> } : (tensor<2x3xi32>, tensor<i32>) -> tensor<3xi32>
+    "stablehlo.return"(%5, %8) : (tensor<2xf32>, tensor<3xi32>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+
+		// Initial value of a third dtype, promotable to the dtype of the reduction function.
+		fn = New(t.Name()).Main()
+		x = must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2, 3)))
+		sumF32 = must(fn.NewReductionClosure(ReductionAdd, dtypes.Float32))
+		xSum = must(Reduce(x, must(fn.ConstantFromScalar(float16.Fromfloat32(0))), sumF32, 1))
+		if xSum.Shape().DType != dtypes.Float32 {
+			t.Errorf("unexpected output shape %s", xSum.Shape())
+		}
+		if producer := xSum.Producer(); producer.Inputs[1].Shape().DType != dtypes.Float32 ||
+			producer.Inputs[1].Producer().OpType != optypes.Convert {
+			t.Errorf("expected the initial value to be converted to float32, got %s", producer.Inputs[1].Shape())
+		}
+
+		// Errors: inputs or initial value not promotable.
+		fn = New(t.Name()).Main()
+		x = must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2, 3)))
+		sumF16 := must(fn.NewReductionClosure(ReductionAdd, dtypes.Float16))
+		if _, err := Reduce(x, must(fn.ConstantFromScalar(float32(0))), sumF16, 1); err == nil ||
+			!strings.Contains(err.Error(), "not promotable") {
+			t.Errorf("expected error reducing float32 with a float16 closure, got %v", err)
+		}
+		y = must(fn.NamedInput("y", shapes.Make(dtypes.Float16, 2, 3)))
+		sumF64 := must(fn.NewReductionClosure(ReductionAdd, dtypes.Float64))
+		if _, err := Reduce(y, must(fn.ConstantFromScalar(int32(0))), sumF64, 1); err == nil ||
+			!strings.Contains(err.Error(), "initial value #0") {
+			t.Errorf("expected error for initial value dtype, got %v", err)
+		}
+		if _, err := Reduce(x, must(fn.ConstantFromScalar(float64(0))), must(fn.NewReductionClosure(ReductionAdd, dtypes.Float32)), 1); err == nil ||
+			!strings.Contains(err.Error(), "initial value #0 dtype Float64 is not promotable") {
+			t.Errorf("expected error for a float64 initial value with a float32 closure, got %v", err)
+		}
+	})
+
+	t.Run("all reduce tuple", func(t *testing.T) {
+		builder := New(t.Name()).WithNumReplicas(2)
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		y := must(fn.NamedInput("y", shapes.Make(dtypes.Float32, 2, 2)))
+		sumFn := fn.Closure()
+		lhs := must(sumFn.NamedInput("lhs", shapes.Make(dtypes.Float32)))
+		rhs := must(sumFn.NamedInput("rhs", shapes.Make(dtypes.Float32)))
+		must0(sumFn.Return(must(Add(lhs, rhs))))
+		operands := []*Value{x, y}
+		outputs := must(AllReduce(operands, [][]int{{0, 1}}, sumFn))
+		operands[0] = nil // AllReduce must not keep the caller's slice.
+		must0(fn.Return(outputs...))
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestBuilder_all_reduce_tuple attributes {stablehlo.num_replicas = 2} {
+  func.func @main(%x: tensor<3xf32>, %y: tensor<2x2xf32>) -> (tensor<3xf32>, tensor<2x2xf32>) {
+    %1, %2 = "stablehlo.all_reduce"(%x, %y) ({
+      ^computation(%lhs: tensor<f32>, %rhs: tensor<f32>) :
+          %0 = "stablehlo.add"(%lhs, %rhs) : (tensor<f32>, tensor<f32>) -> tensor<f32>
+          "stablehlo.return"(%0) : (tensor<f32>) -> ()
+    }) { replica_groups = dense<[[0, 1]]> : tensor<1x2xi64> } : (tensor<3xf32>, tensor<2x2xf32>) -> (tensor<3xf32>, tensor<2x2xf32>)
+    "stablehlo.return"(%1, %2) : (tensor<3xf32>, tensor<2x2xf32>) -> ()
+  }
+}
 `
 		if program != want {
 			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
@@ -153,6 +1016,154 @@ func TestBuilder_Errors(t *testing.T) {
 			t.Fatalf("error message %q does not contain expected substring", err.Error())
 		}
 	})
+
+	t.Run("reshape dynamic shapes", func(t *testing.T) {
+		fn := New(t.Name()).Main()
+		x := must(fn.Input(shapes.Make(dtypes.Float32, shapes.DynamicDim, 3).WithBounds(2, shapes.DynamicDim)))
+		if _, err := Reshape(x, shapes.Make(dtypes.Float32, 6)); err == nil {
+			t.Error("expected error for Reshape of a dynamic shape, got nil")
+		}
+		y := must(fn.Input(shapes.Make(dtypes.Float32, shapes.DynamicDim)))
+		if _, err := Reshape(y, shapes.Make(dtypes.Float32, shapes.DynamicDim, 1)); err == nil {
+			t.Error("expected error for Reshape of an unbounded dynamic shape, got nil")
+		}
+	})
+
+	t.Run("shapes too large", func(t *testing.T) {
+		// The limits depend on the size of int, so that 32-bit platforms fail explicitly too.
+		huge := math.MaxInt/2 + 1
+		b := New(t.Name())
+		fn := b.Main()
+		if _, err := fn.Input(shapes.Make(dtypes.Uint8, huge, 2)); err == nil {
+			t.Error("expected error for input with too many elements, got nil")
+		}
+		if _, err := fn.Input(shapes.Make(dtypes.Float32, huge/2)); err == nil {
+			t.Error("expected error for input with too many bytes, got nil")
+		}
+		if _, err := fn.Iota(shapes.Make(dtypes.Int64, huge, 4), 0); err == nil {
+			t.Error("expected error for Iota with too many elements, got nil")
+		}
+		x := must(fn.Input(shapes.Make(dtypes.Uint8, huge, 1)))
+		if _, err := BroadcastInDim(x, shapes.Make(dtypes.Uint8, huge, 2), []int{0, 1}); err == nil {
+			t.Error("expected error for BroadcastInDim to too many elements, got nil")
+		}
+		padded := must(Pad(x, must(fn.ConstantFromScalar(uint8(0))), []int{0, 0}, []int{0, 1}, nil))
+		must0(fn.Return(padded))
+		if _, err := b.Build(); err == nil || !strings.Contains(err.Error(), "too large") {
+			t.Errorf("expected Build to fail for a value with too many elements, got %v", err)
+		}
+	})
+
+	t.Run("declared outputs", func(t *testing.T) {
+		b := New(t.Name())
+		fn := b.Main()
+		closure := fn.Closure()
+		must0(closure.DeclareOutputs(shapes.Make(dtypes.Float32)))
+		lhs := must(closure.Input(shapes.Make(dtypes.Float64)))
+		rhs := must(closure.Input(shapes.Make(dtypes.Float64)))
+		sum := must(Add(lhs, rhs))
+		err := closure.Return(sum)
+		if err == nil || !strings.Contains(err.Error(), "declared with output #0 of shape (Float32)") {
+			t.Errorf("expected Return to fail for the wrong output shape, got %v", err)
+		}
+		if err = closure.Return(sum, sum); err == nil || !strings.Contains(err.Error(), "declared with 1 outputs") {
+			t.Errorf("expected Return to fail for the wrong number of outputs, got %v", err)
+		}
+		if closure.Returned {
+			t.Error("closure should not be marked as returned after failing")
+		}
+		must0(closure.DeclareOutputs(shapes.Make(dtypes.Float64)))
+		must0(closure.Return(sum))
+		if err = closure.DeclareOutputs(shapes.Make(dtypes.Float64)); err == nil {
+			t.Error("expected error declaring outputs after returning")
+		}
+		if err = fn.DeclareOutputs(); err == nil {
+			t.Error("expected error declaring no outputs")
+		}
+	})
+
+	t.Run("closure with signature", func(t *testing.T) {
+		// Top-down construction: the closures are used before their bodies are written.
+		b := New(t.Name())
+		fn := b.Main()
+		scalar := shapes.Make(dtypes.Float32)
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		sumFn, sumInputs, err := fn.ClosureWithSignature([]shapes.Shape{scalar, scalar}, scalar)
+		if err != nil {
+			t.Fatalf("ClosureWithSignature failed: %+v", err)
+		}
+		if _, err := sumFn.Input(scalar); err == nil {
+			t.Error("expected error adding an input to a closure with a declared signature")
+		}
+		sum := must(Reduce(x, must(fn.ConstantFromScalar(float32(0))), sumFn, 0))
+		if !sum.Shape().Equal(scalar) {
+			t.Errorf("Reduce with a declared closure: got shape %s, wanted %s", sum.Shape(), scalar)
+		}
+		wrongFn, _, err := fn.ClosureWithSignature([]shapes.Shape{scalar, scalar}, shapes.Make(dtypes.Int32))
+		if err != nil {
+			t.Fatalf("ClosureWithSignature failed: %+v", err)
+		}
+		if _, err := Reduce(x, must(fn.ConstantFromScalar(float32(0))), wrongFn, 0); err == nil {
+			t.Error("expected Reduce to fail for a closure declared with the wrong output dtype")
+		}
+		must0(wrongFn.Return(must(wrongFn.ConstantFromScalar(int32(0)))))
+		must0(fn.Return(sum))
+		if _, err := b.Build(); err == nil || !strings.Contains(err.Error(), "was not returned") {
+			t.Errorf("expected Build to fail while the closure body is not written, got %v", err)
+		}
+		must0(sumFn.Return(must(Add(sumInputs[0], sumInputs[1]))))
+		program := string(must(b.Build()))
+		if !strings.Contains(program, "stablehlo.reduce") || !strings.Contains(program, "stablehlo.add") {
+			t.Errorf("unexpected program:\n%s", program)
+		}
+	})
+
+	t.Run("closure referencing parent value", func(t *testing.T) {
+		b := New(t.Name())
+		fn := b.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		scale := must(fn.NamedInput("scale", shapes.Make(dtypes.Float32)))
+		sumFn := fn.Closure()
+		lhs := must(sumFn.NamedInput("lhs", shapes.Make(dtypes.Float32)))
+		rhs := must(sumFn.NamedInput("rhs", shapes.Make(dtypes.Float32)))
+		if _, err := Multiply(lhs, scale); err == nil {
+			t.Error("expected error using a value of the parent function in a closure, got nil")
+		}
+		sum := must(Add(lhs, rhs))
+		must0(sumFn.Return(sum))
+		must0(fn.Return(must(Reduce(x, must(fn.ConstantFromScalar(float32(0))), sumFn, 0))))
+		must0(b.Verify())
+
+		// Statements changed after their creation are not checked by the operations.
+		sum.Producer().Inputs[1] = scale
+		err := b.Verify()
+		want := `function "main", statement #1 (stablehlo.reduce), region #0 "reductionFn", statement #0 (stablehlo.add): ` +
+			`operand #1 (%scale) is a value of function "main"`
+		if err == nil || !strings.Contains(err.Error(), want) {
+			t.Fatalf("expected Verify to fail with %q, got %v", want, err)
+		}
+		if _, err := b.Build(); err == nil {
+			t.Error("expected Build to fail, got nil")
+		}
+	})
+
+	t.Run("missing mandatory attribute", func(t *testing.T) {
+		b := New(t.Name())
+		fn := b.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 4, 3)))
+		indices := must(fn.ConstantFromFlatAndDimensions([]int32{2, 0}, 2, 1))
+		y := must(Gather(x, indices, 1, []int{1}, []int{0}, nil, nil, []int{0}, []int{1, 3}, false))
+		must0(fn.Return(y))
+		must(b.Build())
+
+		// A transformation (or a buggy helper) dropping an attribute is caught before rendering.
+		gather := y.Producer()
+		must0(gather.SetAttribute("slice_sizes", nil))
+		_, err := b.Build()
+		if err == nil || !strings.Contains(err.Error(), `stablehlo.gather (output %1), in function "main", is missing the mandatory attribute "slice_sizes"`) {
+			t.Errorf("expected Build to fail for the missing slice_sizes, got %v", err)
+		}
+	})
 }
 
 func TestNormalizeIdentifier(t *testing.T) {
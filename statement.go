@@ -24,6 +24,9 @@ type Statement struct {
 	Builder  *Builder
 	Function *Function
 
+	// id is the unique ID of the statement in the Builder, see Statement.ID.
+	id int
+
 	// OpType is the type of the operation.
 	OpType optypes.OpType
 
@@ -39,6 +42,9 @@ type Statement struct {
 
 	// Outputs of the operation. It may be nil for operations like func.return.
 	Outputs []*Value
+
+	// rawSnippet is the StableHLO text of statements created with Function.RawStatement.
+	rawSnippet string
 }
 
 func (s *Statement) AddFunctionParameter(name string, inlineFn *Function) {
@@ -80,6 +86,13 @@ func (s *Statement) Write(writer io.Writer, indentation string) error {
 		w(" = ")
 	}
 
+	// Raw snippets are written as given, followed by the signature:
+	if s.OpType == optypes.RawSnippet {
+		w("%s", renderRawSnippet(s.rawSnippet, s.Inputs, indentation))
+		s.writeSignature(w)
+		return err
+	}
+
 	// Write op name and arguments:
 	w("%q(", s.OpType.ToStableHLO())
 	for i, input := range s.Inputs {
@@ -107,12 +120,19 @@ func (s *Statement) Write(writer io.Writer, indentation string) error {
 	writeAttributes(writer, indentation, s.Attributes, w)
 
 	// Write signature:
+	s.writeSignature(w)
+	return err
+}
+
+// writeSignature writes the " : (inputs types) -> outputs types" signature of the statement.
+// The w function is the one provided by the caller to handle errors.
+func (s *Statement) writeSignature(w func(format string, args ...any)) {
 	w(" : (")
 	for i, input := range s.Inputs {
 		if i > 0 {
 			w(", ")
 		}
-		w(input.shape.ToStableHLO())
+		w("%s", input.shape.ToStableHLO())
 	}
 	w(")")
 	w(" -> ")
@@ -127,14 +147,12 @@ func (s *Statement) Write(writer io.Writer, indentation string) error {
 			if i > 0 {
 				w(", ")
 			}
-			w(output.shape.ToStableHLO())
+			w("%s", output.shape.ToStableHLO())
 		}
 		if len(s.Outputs) > 1 {
 			w(")")
 		}
 	}
-
-	return err
 }
 
 // writeAttributes writes a map of attributes to the writer.
@@ -254,6 +272,36 @@ func boolSliceToArrayI1StableHLO(values []bool) literalStr {
 	return literalStr(sb.String())
 }
 
+// float32SliceToArrayF32StableHLO converts a slice of float32 to a string with comma-separated values, as used
+// by StableHLO for attribute values that are an array of float32 (`array<f32: ...>`).
+func float32SliceToArrayF32StableHLO(values []float32) literalStr {
+	return floatSliceToArrayStableHLO("f32", values)
+}
+
+// float64SliceToArrayF64StableHLO converts a slice of float64 to a string with comma-separated values, as used
+// by StableHLO for attribute values that are an array of float64 (`array<f64: ...>`).
+func float64SliceToArrayF64StableHLO(values []float64) literalStr {
+	return floatSliceToArrayStableHLO("f64", values)
+}
+
+// floatSliceToArrayStableHLO implements float32SliceToArrayF32StableHLO and float64SliceToArrayF64StableHLO.
+// Values are formatted as in the tensor literals: non-finite values use their hexadecimal representation.
+func floatSliceToArrayStableHLO[F float32 | float64](dtype string, values []F) literalStr {
+	var sb strings.Builder
+	sb.WriteString("array<")
+	sb.WriteString(dtype)
+	for i, v := range values {
+		if i == 0 {
+			sb.WriteString(": ")
+		} else {
+			sb.WriteString(", ")
+		}
+		sb.WriteString(floatToStableHLO(any(v)))
+	}
+	sb.WriteString(">")
+	return literalStr(sb.String())
+}
+
 func float32IsFinite(f float32) bool {
 	return !math.IsInf(float64(f), 0) && !math.IsNaN(float64(f))
 }
@@ -344,15 +392,15 @@ func podToStableHLO(pod any) string {
 		}
 		return "false"
 
-	case complex64, complex128:
-		var c complex128
-		if c64, ok := v.(complex64); ok {
-			c = complex128(c64)
-		} else {
-			c = v.(complex128)
-		}
+	case complex64:
+		// The real and imaginary parts must be rendered as float32: non-finite values use the 32-bit hex
+		// representation.
+		return fmt.Sprintf("(%s, %s)",
+			floatToStableHLO(real(v)), floatToStableHLO(imag(v)))
+
+	case complex128:
 		return fmt.Sprintf("(%s, %s)",
-			floatToStableHLO(real(c)), floatToStableHLO(imag(c)))
+			floatToStableHLO(real(v)), floatToStableHLO(imag(v)))
 
 	default:
 		return fmt.Sprintf("*don't know how to present data type*: %t %#v", v, v)
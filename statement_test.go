@@ -0,0 +1,163 @@
+package stablehlo
+
+import (
+	"math"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestTensorLiteralComplex(t *testing.T) {
+	inf32, nan32 := float32(math.Inf(1)), float32(math.NaN())
+	testCases := []struct {
+		name string
+		flat any
+		dims []int
+		want string
+	}{
+		{
+			name: "complex64 scalar",
+			flat: complex(float32(1), -inf32),
+			want: "dense<(1.0, 0xff800000)> : tensor<complex<f32>>",
+		},
+		{
+			name: "complex64 rank-2 with inf/nan",
+			flat: []complex64{complex(inf32, 0), complex(0, nan32), complex(-inf32, inf32), complex(1.5, -2)},
+			dims: []int{2, 2},
+			want: "dense<[[(0x7f800000, 0.0), (0.0, 0x7fc00000)], [(0xff800000, 0x7f800000), (1.5, -2.0)]]> : tensor<2x2xcomplex<f32>>",
+		},
+		{
+			name: "complex128 rank-1 with inf/nan",
+			flat: []complex128{complex(math.Inf(-1), 1e-9), complex(math.NaN(), math.Inf(1)), 3i},
+			dims: []int{3},
+			want: "dense<[(0xfff0000000000000, 1.0e-09), (0x7ff8000000000001, 0x7ff0000000000000), (0.0, 3.0)]> : tensor<3xcomplex<f64>>",
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			literal, err := newTensorLiteralFromFlatAndDimensions(tc.flat, tc.dims...)
+			if err != nil {
+				t.Fatalf("expected no error, got %v", err)
+			}
+			if got := literal.ToStableHLO(); got != tc.want {
+				t.Errorf("ToStableHLO():\n got: %s\nwant: %s", got, tc.want)
+			}
+		})
+	}
+}
+
+func TestFloatArrayAttributes(t *testing.T) {
+	inf32, nan32 := float32(math.Inf(1)), float32(math.NaN())
+	testCases := []struct {
+		name string
+		got  literalStr
+		want string
+	}{
+		{"f32 empty", float32SliceToArrayF32StableHLO(nil), "array<f32>"},
+		{"f32 integral and fractional", float32SliceToArrayF32StableHLO([]float32{1, -2, 0.5}), "array<f32: 1.0, -2.0, 0.5>"},
+		{"f32 negative zero", float32SliceToArrayF32StableHLO([]float32{float32(math.Copysign(0, -1))}), "array<f32: -0.0>"},
+		{"f32 exponents", float32SliceToArrayF32StableHLO([]float32{1e-6, 3e20}), "array<f32: 9.999999974752427e-07, 3.000000060122632e+20>"},
+		{"f32 inf/nan", float32SliceToArrayF32StableHLO([]float32{inf32, -inf32, nan32}), "array<f32: 0x7f800000, 0xff800000, 0x7fc00000>"},
+		{"f64 empty", float64SliceToArrayF64StableHLO([]float64{}), "array<f64>"},
+		{"f64 values", float64SliceToArrayF64StableHLO([]float64{0.1, 1e-6, 2e100, 42}), "array<f64: 0.1, 1.0e-06, 2.0e+100, 42.0>"},
+		{"f64 inf", float64SliceToArrayF64StableHLO([]float64{math.Inf(-1)}), "array<f64: 0xfff0000000000000>"},
+	}
+	for _, tc := range testCases {
+		if string(tc.got) != tc.want {
+			t.Errorf("%s:\n got: %s\nwant: %s", tc.name, tc.got, tc.want)
+		}
+	}
+
+	// Float arrays set with SetAttribute.
+	fn := New(t.Name()).Main()
+	x, err := fn.Input(shapes.Make(dtypes.Float32, 2))
+	if err != nil {
+		t.Fatal(err)
+	}
+	y, err := Negate(x)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stmt := y.Producer()
+	if err = stmt.SetAttributes(map[string]any{"scales": []float32{0.5, 2}, "offsets": []float64{-1.5}}); err != nil {
+		t.Fatal(err)
+	}
+	if got, _ := stmt.AttributeString("scales"); got != "array<f32: 0.5, 2.0>" {
+		t.Errorf("unexpected scales attribute %q", got)
+	}
+	if got, _ := stmt.AttributeString("offsets"); got != "array<f64: -1.5>" {
+		t.Errorf("unexpected offsets attribute %q", got)
+	}
+}
+
+func TestStatementAttributes(t *testing.T) {
+	builder := New(t.Name())
+	fn := builder.Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 4, 6)))
+	sliced := must(Slice(x, []int{0, 0}, []int{2, 6}, []int{1, 2}))
+	zero := must(fn.ConstantFromScalar(float32(0)))
+	padded := must(Pad(x, zero, []int{1, 0}, []int{1, 0}, []int{0, 0}))
+	transposed := must(Transpose(sliced, 1, 0))
+	must0(fn.Return(transposed))
+
+	// Reading attributes.
+	sliceStmt := sliced.Producer()
+	if got := sliceStmt.AttributeNames(); !slices.Equal(got, []string{"limit_indices", "start_indices", "strides"}) {
+		t.Errorf("unexpected attribute names %v", got)
+	}
+	if got := must(sliceStmt.AttributeInts("strides")); !slices.Equal(got, []int{1, 2}) {
+		t.Errorf("strides = %v, want [1 2]", got)
+	}
+	if got, _ := sliceStmt.AttributeString("limit_indices"); got != "array<i64: 2, 6>" {
+		t.Errorf("limit_indices = %q", got)
+	}
+	if _, found := sliceStmt.Attribute("padding"); found {
+		t.Error("Slice should have no padding attribute")
+	}
+
+	// Changing the padding of an unused Pad updates its output shape.
+	padStmt := padded.Producer()
+	must0(padStmt.SetAttribute("edge_padding_high", []int{1, 2}))
+	if want := shapes.Make(dtypes.Float32, 6, 8); !padded.Shape().Equal(want) {
+		t.Errorf("padded shape = %s, want %s", padded.Shape(), want)
+	}
+	if err := padStmt.SetAttribute("interior_padding", []int{0}); err == nil {
+		t.Error("expected error for interior_padding with the wrong rank, got nil")
+	}
+	if got := must(padStmt.AttributeInts("interior_padding")); !slices.Equal(got, []int{0, 0}) {
+		t.Errorf("failed SetAttribute changed interior_padding to %v", got)
+	}
+
+	// The Slice output is used, so its shape can't change, but attributes that keep it can.
+	if err := sliceStmt.SetAttribute("limit_indices", []int{3, 6}); err == nil {
+		t.Error("expected error for changing the shape of a used output, got nil")
+	}
+	must0(sliceStmt.SetAttributes(map[string]any{"start_indices": []int{2, 0}, "limit_indices": []int{4, 6}}))
+	if err := transposed.Producer().SetAttribute("permutation", []int{0, 0}); err == nil {
+		t.Error("expected error for invalid permutation, got nil")
+	}
+	if err := sliceStmt.SetAttribute("strides", map[int]int{}); err == nil {
+		t.Error("expected error for unsupported attribute value type, got nil")
+	}
+	if err := fn.Statements[len(fn.Statements)-1].SetAttribute("foo", true); err == nil {
+		t.Error("expected error setting attributes of the return statement, got nil")
+	}
+	program := string(must(builder.Build()))
+	if !strings.Contains(program, "start_indices = array<i64: 2, 0>") ||
+		!strings.Contains(program, "edge_padding_high = array<i64: 1, 2>") {
+		t.Errorf("changed attributes not in the program:\n%s", program)
+	}
+
+	// Attributes of imported programs.
+	imported := must(Import([]byte(program)))
+	for _, stmt := range imported.functions[0].Statements {
+		if stmt.OpName() == "stablehlo.transpose" {
+			if got := must(stmt.AttributeInts("permutation")); !slices.Equal(got, []int{1, 0}) {
+				t.Errorf("imported permutation = %v, want [1 0]", got)
+			}
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package stablehlo
+
+import (
+	"maps"
+
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/pkg/errors"
+)
+
+// ExtractSubgraph returns a new self-contained "main" Function, in a new Builder, that computes the given outputs of fn.
+// It is useful to debug a numerical issue in a huge graph, by isolating a slice of it into a program that can be built
+// (with the returned function's Builder) and executed on its own.
+//
+// The subgraph includes all the statements needed to compute the outputs. Its inputs are the subgraph's external
+// dependencies: the inputs of fn used by the subgraph, in their original order and with their original names, followed by
+// the cut values used, in the order given. The optional cuts are values of fn that are fed as inputs (named
+// "cut_<value name>") instead of being computed, limiting how far back the subgraph goes.
+//
+// The new Builder keeps the number of replicas, partitions and the Shardy meshes of the original one.
+func (fn *Function) ExtractSubgraph(outputs []*Value, cuts ...*Value) (*Function, error) {
+	if len(outputs) == 0 {
+		return nil, errors.New("ExtractSubgraph requires at least one output")
+	}
+	for i, output := range outputs {
+		if output.fn != fn {
+			return nil, errors.Errorf("ExtractSubgraph: output #%d (%s) is not a value of function %q", i, output, fn.Name)
+		}
+	}
+	isCut := make(map[*Value]bool, len(cuts))
+	for i, cut := range cuts {
+		if cut.fn != fn {
+			return nil, errors.Errorf("ExtractSubgraph: cut #%d (%s) is not a value of function %q", i, cut, fn.Name)
+		}
+		isCut[cut] = true
+	}
+
+	// Find the statements needed, and the external dependencies.
+	producers := make(map[*Value]*Statement)
+	for _, stmt := range fn.Statements {
+		for _, output := range stmt.Outputs {
+			producers[output] = stmt
+		}
+	}
+	neededStmts := make(map[*Statement]bool)
+	usedExternal := make(map[*Value]bool)
+	visited := make(map[*Value]bool)
+	toVisit := append([]*Value(nil), outputs...)
+	for len(toVisit) > 0 {
+		value := toVisit[len(toVisit)-1]
+		toVisit = toVisit[:len(toVisit)-1]
+		if visited[value] {
+			continue
+		}
+		visited[value] = true
+		stmt, found := producers[value]
+		if isCut[value] || !found {
+			usedExternal[value] = true
+			continue
+		}
+		if stmt.OpType == optypes.FuncCall {
+			return nil, errors.Errorf("ExtractSubgraph: value %s is computed by a call to another function, "+
+				"use Builder.InlineCalls first", value)
+		}
+		if !neededStmts[stmt] {
+			neededStmts[stmt] = true
+			toVisit = append(toVisit, stmt.Inputs...)
+		}
+	}
+
+	// Create the new function and its inputs.
+	b := New(fn.Name + "_subgraph")
+	b.numReplicas = fn.Builder.numReplicas
+	b.numPartitions = fn.Builder.numPartitions
+	b.meshes = fn.Builder.meshes
+	sub := b.Main()
+	sub.nextArgID = fn.nextArgID
+	mapping := make(map[*Value]*Value)
+	addInput := func(value *Value, name string) {
+		input := &Value{
+			fn:         sub,
+			name:       name,
+			shape:      value.shape,
+			Attributes: maps.Clone(value.Attributes),
+		}
+		input.mergeTagsFrom(value)
+		sub.Inputs = append(sub.Inputs, input)
+		sub.values = append(sub.values, input)
+		mapping[value] = input
+	}
+	for _, input := range fn.Inputs {
+		if usedExternal[input] && !isCut[input] {
+			addInput(input, input.name)
+		}
+	}
+	for _, cut := range cuts {
+		if usedExternal[cut] && mapping[cut] == nil {
+			addInput(cut, "cut_"+cut.name)
+		}
+	}
+
+	// Clone the statements needed, in their original order.
+	for _, stmt := range fn.Statements {
+		if !neededStmts[stmt] {
+			continue
+		}
+		cutInputs := make(map[*Value]*Value)
+		for _, output := range stmt.Outputs {
+			if isCut[output] {
+				cutInputs[output] = mapping[output]
+			}
+		}
+		sub.Statements = append(sub.Statements, sub.cloneStatement(stmt, mapping))
+		// Uses of the cut values must still refer to the corresponding inputs.
+		for cut, input := range cutInputs {
+			mapping[cut] = input
+		}
+	}
+
+	subOutputs := make([]*Value, len(outputs))
+	for i, output := range outputs {
+		subOutputs[i] = mapping[output]
+	}
+	if err := sub.Return(subOutputs...); err != nil {
+		return nil, errors.WithMessagef(err, "ExtractSubgraph failed to return the outputs of function %q", fn.Name)
+	}
+	return sub, nil
+}
@@ -0,0 +1,63 @@
+package stablehlo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestExtractSubgraph(t *testing.T) {
+	builder := New(t.Name())
+	fn := builder.Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+	y := must(fn.NamedInput("y", shapes.Make(dtypes.Float32, 3)))
+	z := must(fn.NamedInput("z", shapes.Make(dtypes.Float32, 3)))
+	a := must(Exponential(x))
+	bb := must(Add(a, y))
+	c := must(Tanh(bb))
+	d := must(Multiply(c, z))
+	must0(fn.Return(d))
+
+	t.Run("from inputs", func(t *testing.T) {
+		sub := must(fn.ExtractSubgraph([]*Value{c}))
+		program := string(must(sub.Builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		if len(sub.Inputs) != 2 || sub.Inputs[0].name != "x" || sub.Inputs[1].name != "y" {
+			t.Errorf("expected inputs x and y, got %v", sub.Inputs)
+		}
+		for _, want := range []string{"stablehlo.exponential", "stablehlo.add", "stablehlo.tanh"} {
+			if !strings.Contains(program, want) {
+				t.Errorf("subgraph should contain %q", want)
+			}
+		}
+		if strings.Contains(program, "stablehlo.multiply") || strings.Contains(program, "%z") {
+			t.Errorf("subgraph should not contain the multiplication by z")
+		}
+	})
+
+	t.Run("with cut", func(t *testing.T) {
+		sub := must(fn.ExtractSubgraph([]*Value{d}, bb))
+		program := string(must(sub.Builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		if len(sub.Inputs) != 2 || sub.Inputs[0].name != "z" || sub.Inputs[1].name != "cut_"+bb.name {
+			t.Errorf("expected inputs z and cut_%s, got %v", bb.name, sub.Inputs)
+		}
+		if strings.Contains(program, "stablehlo.exponential") || strings.Contains(program, "stablehlo.add") {
+			t.Errorf("subgraph should not contain the statements before the cut")
+		}
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		other := New("other").Main()
+		w := must(other.NamedInput("w", shapes.Make(dtypes.Float32)))
+		if _, err := fn.ExtractSubgraph([]*Value{w}); err == nil {
+			t.Error("expected error for output from another function")
+		}
+		if _, err := fn.ExtractSubgraph(nil); err == nil {
+			t.Error("expected error for no outputs")
+		}
+	})
+}
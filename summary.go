@@ -0,0 +1,109 @@
+package stablehlo
+
+import (
+	"fmt"
+	"io"
+	"maps"
+	"slices"
+	"strings"
+	"text/tabwriter"
+)
+
+// summaryMaxAttributeLength is the length above which the attribute values are truncated in Function.Summary.
+const summaryMaxAttributeLength = 32
+
+// Summary writes a compact human-readable table of the function to w, one line per input and per statement, with
+// the op, the output values and shapes, the number of uses of the outputs and a digest of the attributes -- a quick
+// way to eyeball a generated program without reading the StableHLO text.
+//
+// The closures of the statements (e.g. the reduction function of a Reduce, or the body of a While) are listed
+// indented below the statement, up to maxDepth levels of nesting: 0 lists only the statements of fn itself, and a
+// negative maxDepth lists all the closures.
+func (fn *Function) Summary(w io.Writer, maxDepth int) error {
+	var table strings.Builder
+	tw := tabwriter.NewWriter(&table, 0, 0, 2, ' ', 0)
+	kind := "func"
+	if fn.Parent != nil {
+		kind = "closure"
+	}
+	if _, err := fmt.Fprintf(tw, "%s @%s: %d inputs, %d outputs, %d statements\n",
+		kind, fn.Name, len(fn.Inputs), len(fn.Outputs), len(fn.Statements)); err != nil {
+		return err
+	}
+	if _, err := fmt.Fprintln(tw, "VALUES\tOP\tSHAPES\tUSES\tATTRIBUTES"); err != nil {
+		return err
+	}
+	if err := fn.writeSummaryRows(tw, "", maxDepth); err != nil {
+		return err
+	}
+	if err := tw.Flush(); err != nil {
+		return err
+	}
+	// Remove the padding of the empty trailing cells.
+	for line := range strings.Lines(table.String()) {
+		if _, err := io.WriteString(w, strings.TrimRight(line, " \n")+"\n"); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// writeSummaryRows writes the rows of the inputs and statements of fn, and recursively of its closures, with the
+// given indentation.
+func (fn *Function) writeSummaryRows(w io.Writer, indentation string, maxDepth int) error {
+	for _, input := range fn.Inputs {
+		if _, err := fmt.Fprintf(w, "%s%s\tinput\t%s\t%d\t\n", indentation, input, input.shape.ToStableHLO(),
+			input.NumUses()); err != nil {
+			return err
+		}
+	}
+	for _, stmt := range fn.Statements {
+		names := make([]string, len(stmt.Outputs))
+		outputShapes := make([]string, len(stmt.Outputs))
+		numUses := 0
+		for i, output := range stmt.Outputs {
+			names[i] = output.String()
+			outputShapes[i] = output.shape.ToStableHLO()
+			numUses += output.NumUses()
+		}
+		uses := "-"
+		if len(stmt.Outputs) > 0 {
+			uses = fmt.Sprint(numUses)
+		}
+		if _, err := fmt.Fprintf(w, "%s%s\t%s\t%s\t%s\t%s\n", indentation, strings.Join(names, ", "),
+			stmt.OpType.ToStableHLO(), strings.Join(outputShapes, ", "), uses,
+			attributesDigest(stmt.Attributes)); err != nil {
+			return err
+		}
+		for i, closure := range stmt.FunctionParameters {
+			nested := indentation + IndentationStep
+			if maxDepth == 0 {
+				if _, err := fmt.Fprintf(w, "%s^%s\t(%d statements)\t\t\t\n", nested,
+					stmt.FunctionParametersNames[i], len(closure.Statements)); err != nil {
+					return err
+				}
+				continue
+			}
+			if _, err := fmt.Fprintf(w, "%s^%s\t\t\t\t\n", nested, stmt.FunctionParametersNames[i]); err != nil {
+				return err
+			}
+			if err := closure.writeSummaryRows(w, nested+IndentationStep, maxDepth-1); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// attributesDigest returns the attributes in a single line, sorted by name, with long values truncated.
+func attributesDigest(attributes map[string]any) string {
+	parts := make([]string, 0, len(attributes))
+	for _, key := range slices.Sorted(maps.Keys(attributes)) {
+		value := strings.Join(strings.Fields(literalToStableHLO(attributes[key])), " ")
+		if runes := []rune(value); len(runes) > summaryMaxAttributeLength {
+			value = string(runes[:summaryMaxAttributeLength]) + "..."
+		}
+		parts = append(parts, key+"="+value)
+	}
+	return strings.Join(parts, ", ")
+}
@@ -0,0 +1,65 @@
+package stablehlo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestSummary(t *testing.T) {
+	builder := New(t.Name())
+	fn := builder.Main()
+	x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2, 3)))
+	zero := must(fn.ConstantFromScalar(float32(0)))
+	sum := must(Reduce(x, zero, must(fn.NewReductionClosure(ReductionAdd, dtypes.Float32)), 1))
+	must0(fn.Return(must(Multiply(sum, sum))))
+
+	for _, tc := range []struct {
+		maxDepth int
+		want     string
+	}{
+		{0, `func @main: 1 inputs, 1 outputs, 4 statements
+VALUES          OP                  SHAPES           USES  ATTRIBUTES
+%x              input               tensor<2x3xf32>  1
+%0              stablehlo.constant  tensor<f32>      1     value=dense<0.0> : tensor<f32>
+%2              stablehlo.reduce    tensor<2xf32>    1     dimensions=array<i64: 1>
+  ^reductionFn  (2 statements)
+%3              stablehlo.multiply  tensor<2xf32>    1
+                stablehlo.return                     -
+`},
+		{-1, `func @main: 1 inputs, 1 outputs, 4 statements
+VALUES          OP                  SHAPES           USES  ATTRIBUTES
+%x              input               tensor<2x3xf32>  1
+%0              stablehlo.constant  tensor<f32>      1     value=dense<0.0> : tensor<f32>
+%2              stablehlo.reduce    tensor<2xf32>    1     dimensions=array<i64: 1>
+  ^reductionFn
+    %lhs        input               tensor<f32>      1
+    %rhs        input               tensor<f32>      1
+    %1          stablehlo.add       tensor<f32>      1
+                stablehlo.return                     -
+%3              stablehlo.multiply  tensor<2xf32>    1
+                stablehlo.return                     -
+`},
+	} {
+		var sb strings.Builder
+		must0(fn.Summary(&sb, tc.maxDepth))
+		fmt.Printf("Summary(maxDepth=%d):\n%s", tc.maxDepth, sb.String())
+		if sb.String() != tc.want {
+			t.Errorf("Summary(maxDepth=%d) got:\n%s\nwanted:\n%s", tc.maxDepth, sb.String(), tc.want)
+		}
+	}
+}
+
+func TestAttributesDigest(t *testing.T) {
+	got := attributesDigest(map[string]any{
+		"b": literalStr("array<i64:\n  1, 2>"),
+		"a": literalStr(strings.Repeat("x", 40)),
+	})
+	want := "a=" + strings.Repeat("x", summaryMaxAttributeLength) + "..., b=array<i64: 1, 2>"
+	if got != want {
+		t.Errorf("got %q, wanted %q", got, want)
+	}
+}
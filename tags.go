@@ -0,0 +1,50 @@
+package stablehlo
+
+import (
+	"maps"
+)
+
+// SetTag attaches a user-defined tag to the value, e.g. the name of the layer that created it, or whether it's a
+// model parameter. A nil value removes the tag.
+//
+// Tags are not rendered in the program: they are bookkeeping for the user, kept along with the value by the
+// transformations of this package. Values cloned by a transformation (e.g. by Builder.InlineCalls,
+// Function.Rematerialize or Function.ExtractSubgraph) get a copy of the tags of the original value, and values
+// replacing another one (e.g. by Function.Rewrite or Builder.InlineCalls) get merged the tags of the replaced
+// value, except for the keys they already have.
+func (v *Value) SetTag(key string, value any) {
+	if value == nil {
+		delete(v.tags, key)
+		return
+	}
+	if v.tags == nil {
+		v.tags = make(map[string]any)
+	}
+	v.tags[key] = value
+}
+
+// Tag returns the value of the tag with the given key, and whether it is set. See Value.SetTag.
+func (v *Value) Tag(key string) (value any, found bool) {
+	value, found = v.tags[key]
+	return
+}
+
+// Tags returns a copy of the tags of the value, or nil if it has none. See Value.SetTag.
+func (v *Value) Tags() map[string]any {
+	if len(v.tags) == 0 {
+		return nil
+	}
+	return maps.Clone(v.tags)
+}
+
+// mergeTagsFrom copies the tags of src into v, except for the keys already set in v.
+func (v *Value) mergeTagsFrom(src *Value) {
+	if v == nil || src == nil || v == src {
+		return
+	}
+	for key, value := range src.tags {
+		if _, found := v.tags[key]; !found {
+			v.SetTag(key, value)
+		}
+	}
+}
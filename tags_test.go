@@ -0,0 +1,103 @@
+package stablehlo
+
+import (
+	"maps"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestValueTags(t *testing.T) {
+	t.Run("SetTag", func(t *testing.T) {
+		fn := New(t.Name()).Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		if x.Tags() != nil {
+			t.Errorf("expected no tags, got %v", x.Tags())
+		}
+		x.SetTag("layer", "dense_1")
+		x.SetTag("parameter", true)
+		if value, found := x.Tag("layer"); !found || value != "dense_1" {
+			t.Errorf("Tag(\"layer\") = %v, %v", value, found)
+		}
+		tags := x.Tags()
+		tags["layer"] = "changed"
+		if value, _ := x.Tag("layer"); value != "dense_1" {
+			t.Error("Tags() should return a copy")
+		}
+		x.SetTag("parameter", nil)
+		if _, found := x.Tag("parameter"); found {
+			t.Error("SetTag with nil should remove the tag")
+		}
+	})
+
+	t.Run("Rewrite", func(t *testing.T) {
+		fn := New(t.Name()).Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		y := must(Negate(x))
+		y.SetTag("layer", "output")
+		must0(fn.Return(y))
+		negateToMultiply := &RewriteRule{
+			Name: "negate_to_multiply",
+			Op:   optypes.Negate.ToStableHLO(),
+			Replace: func(fn *Function, match *Statement) ([]*Value, error) {
+				replacement, err := MultiplyScalar(match.Inputs[0], -1)
+				if err != nil {
+					return nil, err
+				}
+				replacement.SetTag("rewritten", true)
+				return []*Value{replacement}, nil
+			},
+		}
+		if numRewrites := must(fn.Rewrite(negateToMultiply)); numRewrites != 1 {
+			t.Fatalf("expected 1 rewrite, got %d", numRewrites)
+		}
+		returned := fn.Statements[len(fn.Statements)-1].Inputs[0]
+		want := map[string]any{"layer": "output", "rewritten": true}
+		if !maps.Equal(returned.Tags(), want) {
+			t.Errorf("tags of the rewritten value: got %v, wanted %v", returned.Tags(), want)
+		}
+	})
+
+	t.Run("InlineCalls", func(t *testing.T) {
+		builder := buildCallsProgram(t)
+		var callee, main *Function
+		for _, fn := range builder.functions {
+			switch fn.Name {
+			case "square_plus_one":
+				callee = fn
+			case MainFunctionName:
+				main = fn
+			}
+		}
+		// Tag the square computed by the callee, and the total returned by main.
+		square := callee.Statements[1]
+		if square.OpType != optypes.Multiply {
+			t.Fatalf("expected the second statement of the callee to be the square, got %s", square.OpName())
+		}
+		square.Outputs[0].SetTag("op", "square")
+		total := main.Statements[len(main.Statements)-1].Inputs[1]
+		total.SetTag("metric", "total")
+		must(builder.InlineCalls(-1))
+
+		var numSquares int
+		for _, stmt := range main.Statements {
+			for _, output := range stmt.Outputs {
+				if value, _ := output.Tag("op"); value == "square" {
+					numSquares++
+				}
+			}
+		}
+		if numSquares != 2 {
+			t.Errorf("expected the 2 inlined copies of the square to keep their tag, got %d", numSquares)
+		}
+		newTotal := main.Statements[len(main.Statements)-1].Inputs[1]
+		if newTotal == total {
+			t.Fatal("expected the total to be replaced by the inlined value")
+		}
+		if value, _ := newTotal.Tag("metric"); value != "total" {
+			t.Errorf("the inlined total lost its tag: %v", newTotal.Tags())
+		}
+	})
+}
@@ -181,21 +181,10 @@ func testCollectiveOps(t *testing.T, client *pjrt.Client) {
 		program := must1(b.Build())
 		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
 
-		input0 := must1(client.BufferFromHost().FromFlatDataWithDimensions(
-			[]float32{1.0, 10.0}, []int{2}).ToDeviceNum(replicaGroups[0][0]).Done())
-		input1 := must1(client.BufferFromHost().FromFlatDataWithDimensions(
-			[]float32{2.0, 20.0}, []int{2}).ToDeviceNum(replicaGroups[0][1]).Done())
-
-		e, err := client.Compile().WithStableHLO(program).WithSPMD(numReplicas).Done()
-		if err != nil {
-			t.Errorf("failed to compile program: \n%s\nError: %v", program, err)
-			return
-		}
-		outputBuffers, err := e.Execute(input0, input1).DonateAll().Done()
-		if err != nil {
-			t.Errorf("failed to execute program: \n%s\nError: %v", program, err)
-			return
-		}
+		outputBuffers := spmdCompileAndExecute(t, client, program, numReplicas, [][]FlatAndDims{
+			{{[]float32{1.0, 10.0}, []int{2}}}, // Replica 0
+			{{[]float32{2.0, 20.0}, []int{2}}}, // Replica 1
+		})
 
 		want := []FlatAndDims{
 			{[]float32{1.0, 10.0, 2.0, 20.0}, []int{4}},
@@ -249,21 +238,10 @@ func testCollectiveOps(t *testing.T, client *pjrt.Client) {
 		program := must1(b.Build())
 		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
 
-		input0 := must1(client.BufferFromHost().FromFlatDataWithDimensions(
-			[]float32{1.0, 10.0}, []int{2}).ToDeviceNum(replicaGroups[0][0]).Done())
-		input1 := must1(client.BufferFromHost().FromFlatDataWithDimensions(
-			[]float32{2.0, 20.0}, []int{2}).ToDeviceNum(replicaGroups[0][1]).Done())
-
-		e, err := client.Compile().WithStableHLO(program).WithSPMD(numReplicas).Done()
-		if err != nil {
-			t.Errorf("failed to compile program: \n%s\nError: %v", program, err)
-			return
-		}
-		outputBuffers, err := e.Execute(input0, input1).DonateAll().Done()
-		if err != nil {
-			t.Errorf("failed to execute program: \n%s\nError: %v", program, err)
-			return
-		}
+		outputBuffers := spmdCompileAndExecute(t, client, program, numReplicas, [][]FlatAndDims{
+			{{[]float32{1.0, 10.0}, []int{2}}}, // Replica 0
+			{{[]float32{2.0, 20.0}, []int{2}}}, // Replica 1
+		})
 
 		want := []FlatAndDims{
 			{[]float32{2.0, 20.0}, []int{2}},
@@ -5,6 +5,7 @@ import (
 	"testing"
 
 	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/gopjrt/dtypes/bfloat16"
 	"github.com/gomlx/gopjrt/pjrt"
 	. "github.com/gomlx/stablehlo"
 	"github.com/gomlx/stablehlo/types"
@@ -41,4 +42,102 @@ func testConvolution(t *testing.T, client *pjrt.Client) {
 		results := compileAndExecute(t, client, program)
 		requireBuffersEqual(t, []FlatAndDims{{[]float32{9.9}, []int{1, 1, 1, 1}}}, results)
 	})
+
+	t.Run("ConvolutionWithAccumulation: BFloat16 accumulated in Float32", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		input := must1(fn.Iota(shapes.Make(dtypes.F32, 1, 1, 3, 3), 2))
+		input = must1(Convert(input, dtypes.BFloat16))
+		kernel := must1(fn.ConstantFromScalar(bfloat16.FromFloat32(1)))
+		kernel = must1(BroadcastInDim(kernel, shapes.Make(dtypes.BFloat16, 1, 1, 3, 3), nil))
+		spatialAxes := []int{2, 3}
+		output := must1(ConvolutionWithAccumulation(dtypes.Float32, input, kernel,
+			nil, nil, nil, nil,
+			0, 1, spatialAxes,
+			1, 0, spatialAxes,
+			0, 1, spatialAxes,
+			1, 1,
+			types.DotGeneralPrecisionDefault, types.DotGeneralPrecisionDefault,
+		))
+		if output.Shape().DType != dtypes.BFloat16 {
+			t.Fatalf("expected output dtype BFloat16, got %s", output.Shape().DType)
+		}
+		must(fn.Return(output))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		results := compileAndExecute(t, client, program)
+		requireBuffersEqual(t, []FlatAndDims{{[]bfloat16.BFloat16{bfloat16.FromFloat32(9)}, []int{1, 1, 1, 1}}}, results)
+	})
+
+	t.Run("Conv1D", func(t *testing.T) {
+		for _, layout := range []ConvLayout{ChannelsLast, ChannelsFirst} {
+			builder := New(t.Name())
+			fn := builder.Main()
+			// Input NWC=[1, 4, 1] or NCW=[1, 1, 4], with values 0, 1, 2, 3 and a kernel of ones of width 2.
+			inputDims, kernelDims, outputDims := []int{1, 4, 1}, []int{2, 1, 1}, []int{1, 3, 1}
+			widthAxis := 1
+			if layout == ChannelsFirst {
+				inputDims, kernelDims, outputDims = []int{1, 1, 4}, []int{1, 1, 2}, []int{1, 1, 3}
+				widthAxis = 2
+			}
+			input := must1(fn.Iota(shapes.Make(dtypes.F32, inputDims...), widthAxis))
+			kernel := must1(fn.ConstantFromScalar(float32(1)))
+			kernel = must1(BroadcastInDim(kernel, shapes.Make(dtypes.F32, kernelDims...), nil))
+			output := must1(Conv1D(input, kernel, layout, Window{}))
+			must(fn.Return(output))
+			program := must1(builder.Build())
+			fmt.Printf("%s program (%s):\n%s", t.Name(), layout, program)
+			results := compileAndExecute(t, client, program)
+			requireBuffersEqual(t, []FlatAndDims{{[]float32{1, 3, 5}, outputDims}}, results)
+		}
+	})
+
+	t.Run("DepthwiseConv2D", func(t *testing.T) {
+		for _, layout := range []ConvLayout{ChannelsLast, ChannelsFirst} {
+			builder := New(t.Name())
+			fn := builder.Main()
+			// Input NHWC=[1, 2, 2, 2] or NCHW=[1, 2, 2, 2], with the values 1 and 2 for the channels 0 and 1, and a
+			// kernel of ones of size 2x2 with a channel multiplier of 2.
+			kernelDims, outputDims := []int{2, 2, 1, 4}, []int{1, 1, 1, 4}
+			channelsAxis := 3
+			if layout == ChannelsFirst {
+				kernelDims, outputDims = []int{4, 1, 2, 2}, []int{1, 4, 1, 1}
+				channelsAxis = 1
+			}
+			input := must1(fn.Iota(shapes.Make(dtypes.F32, 1, 2, 2, 2), channelsAxis))
+			input = must1(AddScalar(input, 1.0))
+			kernel := must1(fn.ConstantFromScalar(float32(1)))
+			kernel = must1(BroadcastInDim(kernel, shapes.Make(dtypes.F32, kernelDims...), nil))
+			output := must1(DepthwiseConv2D(input, kernel, layout, Window{}))
+			must(fn.Return(output))
+			program := must1(builder.Build())
+			fmt.Printf("%s program (%s):\n%s", t.Name(), layout, program)
+			results := compileAndExecute(t, client, program)
+			requireBuffersEqual(t, []FlatAndDims{{[]float32{4, 4, 8, 8}, outputDims}}, results)
+		}
+	})
+
+	t.Run("Conv3D", func(t *testing.T) {
+		for _, layout := range []ConvLayout{ChannelsLast, ChannelsFirst} {
+			builder := New(t.Name())
+			fn := builder.Main()
+			// Input NDHWC=[1, 3, 2, 2, 1] or NCDHW=[1, 1, 3, 2, 2], with the values 0, 1, 2 along the depth axis,
+			// and a kernel of ones of size 2x2x2.
+			inputDims, kernelDims, outputDims := []int{1, 3, 2, 2, 1}, []int{2, 2, 2, 1, 1}, []int{1, 2, 1, 1, 1}
+			depthAxis := 1
+			if layout == ChannelsFirst {
+				inputDims, kernelDims, outputDims = []int{1, 1, 3, 2, 2}, []int{1, 1, 2, 2, 2}, []int{1, 1, 2, 1, 1}
+				depthAxis = 2
+			}
+			input := must1(fn.Iota(shapes.Make(dtypes.F32, inputDims...), depthAxis))
+			kernel := must1(fn.ConstantFromScalar(float32(1)))
+			kernel = must1(BroadcastInDim(kernel, shapes.Make(dtypes.F32, kernelDims...), nil))
+			output := must1(Conv3D(input, kernel, layout, Window{}))
+			must(fn.Return(output))
+			program := must1(builder.Build())
+			fmt.Printf("%s program (%s):\n%s", t.Name(), layout, program)
+			results := compileAndExecute(t, client, program)
+			requireBuffersEqual(t, []FlatAndDims{{[]float32{4, 12}, outputDims}}, results)
+		}
+	})
 }
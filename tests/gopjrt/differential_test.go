@@ -0,0 +1,370 @@
+package gopjrt
+
+import (
+	"flag"
+	"fmt"
+	"math"
+	"math/rand/v2"
+	"slices"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/gopjrt/pjrt"
+	. "github.com/gomlx/stablehlo"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+// Property-based differential testing: random small programs, built from a restricted set of ops, are executed
+// with PJRT and compared with the results of a simple Go reference interpreter (refTensor below).
+//
+// It catches shape-inference and rendering bugs: a wrong shape or a wrongly rendered attribute either fails to
+// compile or produces different results.
+//
+// To run it more extensively:
+//
+//	go test ./tests/gopjrt -run=TestDifferential -differential_programs=10000 -differential_seed=7
+
+var (
+	flagDifferentialPrograms = flag.Int("differential_programs", 50, "Number of random programs to test in TestDifferential.")
+	flagDifferentialSeed     = flag.Uint64("differential_seed", 42, "Random seed used to generate the programs in TestDifferential.")
+)
+
+// refTensor is a float64 tensor used by the reference interpreter.
+type refTensor struct {
+	dims []int
+	flat []float64
+}
+
+func newRefTensor(dims []int) refTensor {
+	size := 1
+	for _, dim := range dims {
+		size *= dim
+	}
+	return refTensor{dims: slices.Clone(dims), flat: make([]float64, size)}
+}
+
+// strides of each axis in the flat representation (row-major).
+func (r refTensor) strides() []int {
+	return shapes.Make(dtypes.Float64, r.dims...).Strides()
+}
+
+// at returns the value at the given multi-dimensional index.
+func (r refTensor) at(idx []int) float64 {
+	pos := 0
+	for axis, stride := range r.strides() {
+		pos += idx[axis] * stride
+	}
+	return r.flat[pos]
+}
+
+// forEachIndex calls fn for each multi-dimensional index of dims, in row-major order, along with the flat position.
+func forEachIndex(dims []int, fn func(idx []int, pos int)) {
+	size := 1
+	for _, dim := range dims {
+		size *= dim
+	}
+	idx := make([]int, len(dims))
+	for pos := range size {
+		fn(idx, pos)
+		for axis := len(dims) - 1; axis >= 0; axis-- {
+			idx[axis]++
+			if idx[axis] < dims[axis] {
+				break
+			}
+			idx[axis] = 0
+		}
+	}
+}
+
+func (r refTensor) mapValues(fn func(float64) float64) refTensor {
+	out := newRefTensor(r.dims)
+	for i, v := range r.flat {
+		out.flat[i] = fn(v)
+	}
+	return out
+}
+
+func (r refTensor) zipValues(other refTensor, fn func(a, b float64) float64) refTensor {
+	out := newRefTensor(r.dims)
+	for i, v := range r.flat {
+		out.flat[i] = fn(v, other.flat[i])
+	}
+	return out
+}
+
+func (r refTensor) transpose(permutation []int) refTensor {
+	dims := make([]int, len(r.dims))
+	for i, axis := range permutation {
+		dims[i] = r.dims[axis]
+	}
+	out := newRefTensor(dims)
+	inIdx := make([]int, len(dims))
+	forEachIndex(dims, func(idx []int, pos int) {
+		for i, axis := range permutation {
+			inIdx[axis] = idx[i]
+		}
+		out.flat[pos] = r.at(inIdx)
+	})
+	return out
+}
+
+func (r refTensor) slice(starts, limits, strides []int) refTensor {
+	dims := make([]int, len(r.dims))
+	for axis := range dims {
+		dims[axis] = (limits[axis] - starts[axis] + strides[axis] - 1) / strides[axis]
+	}
+	out := newRefTensor(dims)
+	inIdx := make([]int, len(dims))
+	forEachIndex(dims, func(idx []int, pos int) {
+		for axis := range idx {
+			inIdx[axis] = starts[axis] + idx[axis]*strides[axis]
+		}
+		out.flat[pos] = r.at(inIdx)
+	})
+	return out
+}
+
+func (r refTensor) concatenate(other refTensor, axis int) refTensor {
+	dims := slices.Clone(r.dims)
+	dims[axis] += other.dims[axis]
+	out := newRefTensor(dims)
+	inIdx := make([]int, len(dims))
+	forEachIndex(dims, func(idx []int, pos int) {
+		copy(inIdx, idx)
+		if idx[axis] < r.dims[axis] {
+			out.flat[pos] = r.at(inIdx)
+		} else {
+			inIdx[axis] -= r.dims[axis]
+			out.flat[pos] = other.at(inIdx)
+		}
+	})
+	return out
+}
+
+func (r refTensor) reduceSum(axis int) refTensor {
+	dims := slices.Delete(slices.Clone(r.dims), axis, axis+1)
+	out := newRefTensor(dims)
+	outIdx := make([]int, len(dims))
+	strides := out.strides()
+	forEachIndex(r.dims, func(idx []int, pos int) {
+		outIdx = append(append(outIdx[:0], idx[:axis]...), idx[axis+1:]...)
+		outPos := 0
+		for i, stride := range strides {
+			outPos += outIdx[i] * stride
+		}
+		out.flat[outPos] += r.flat[pos]
+	})
+	return out
+}
+
+// differentialNode is a value in the random program along with its expected (reference) value.
+type differentialNode struct {
+	value *Value
+	ref   refTensor
+}
+
+// differentialProgram is a randomly generated program, its inputs and expected outputs.
+type differentialProgram struct {
+	program []byte
+	inputs  []refTensor
+	outputs []refTensor
+}
+
+// differentialGenerator builds random programs.
+type differentialGenerator struct {
+	rng   *rand.Rand
+	fn    *Function
+	nodes []differentialNode
+}
+
+// sameDimsNodes returns the nodes with the given dimensions.
+func (g *differentialGenerator) sameDimsNodes(dims []int) []differentialNode {
+	var nodes []differentialNode
+	for _, node := range g.nodes {
+		if slices.Equal(node.ref.dims, dims) {
+			nodes = append(nodes, node)
+		}
+	}
+	return nodes
+}
+
+// sumClosure returns a new scalar float32 sum closure, to be used by Reduce.
+func (g *differentialGenerator) sumClosure() *Function {
+	closure := g.fn.Closure()
+	lhs := must1(closure.NamedInput("lhs", shapes.Make(dtypes.F32)))
+	rhs := must1(closure.NamedInput("rhs", shapes.Make(dtypes.F32)))
+	must(closure.Return(must1(Add(lhs, rhs))))
+	return closure
+}
+
+// randomOp appends a new node to the program, created by a random op applied to random existing nodes.
+func (g *differentialGenerator) randomOp() {
+	rng := g.rng
+	x := g.nodes[rng.IntN(len(g.nodes))]
+	rank := len(x.ref.dims)
+	var node differentialNode
+	switch rng.IntN(8) {
+	case 0:
+		unaryOps := []struct {
+			op  func(*Value) (*Value, error)
+			ref func(float64) float64
+		}{
+			{Abs, math.Abs},
+			{Negate, func(v float64) float64 { return -v }},
+			{Tanh, math.Tanh},
+			{Sine, math.Sin},
+		}
+		unary := unaryOps[rng.IntN(len(unaryOps))]
+		node = differentialNode{must1(unary.op(x.value)), x.ref.mapValues(unary.ref)}
+	case 1, 2:
+		binaryOps := []struct {
+			op  func(*Value, *Value) (*Value, error)
+			ref func(a, b float64) float64
+		}{
+			{Add, func(a, b float64) float64 { return a + b }},
+			{Subtract, func(a, b float64) float64 { return a - b }},
+			{Multiply, func(a, b float64) float64 { return a * b }},
+			{Maximum, math.Max},
+			{Minimum, math.Min},
+		}
+		binary := binaryOps[rng.IntN(len(binaryOps))]
+		candidates := g.sameDimsNodes(x.ref.dims)
+		y := candidates[rng.IntN(len(candidates))]
+		node = differentialNode{must1(binary.op(x.value, y.value)), x.ref.zipValues(y.ref, binary.ref)}
+	case 3:
+		permutation := rng.Perm(rank)
+		node = differentialNode{must1(Transpose(x.value, permutation...)), x.ref.transpose(permutation)}
+	case 4:
+		// Reshape to the reversed dimensions (same size), which keeps the flat values.
+		dims := slices.Clone(x.ref.dims)
+		slices.Reverse(dims)
+		ref := newRefTensor(dims)
+		copy(ref.flat, x.ref.flat)
+		node = differentialNode{must1(Reshape(x.value, shapes.Make(dtypes.F32, dims...))), ref}
+	case 5:
+		if rank == 0 {
+			return
+		}
+		axis := rng.IntN(rank)
+		zero := must1(g.fn.ConstantFromScalar(float32(0)))
+		node = differentialNode{must1(Reduce(x.value, zero, g.sumClosure(), axis)), x.ref.reduceSum(axis)}
+	case 6:
+		starts, limits, strides := make([]int, rank), make([]int, rank), make([]int, rank)
+		for axis, dim := range x.ref.dims {
+			starts[axis] = rng.IntN(dim)
+			limits[axis] = starts[axis] + 1 + rng.IntN(dim-starts[axis])
+			strides[axis] = 1 + rng.IntN(2)
+		}
+		node = differentialNode{must1(Slice(x.value, starts, limits, strides)), x.ref.slice(starts, limits, strides)}
+	case 7:
+		if rank == 0 {
+			return
+		}
+		axis := rng.IntN(rank)
+		candidates := g.sameDimsNodes(x.ref.dims)
+		y := candidates[rng.IntN(len(candidates))]
+		node = differentialNode{must1(Concatenate(axis, x.value, y.value)), x.ref.concatenate(y.ref, axis)}
+	}
+	g.nodes = append(g.nodes, node)
+}
+
+// generateDifferentialProgram builds a random program with the given random number generator.
+func generateDifferentialProgram(name string, rng *rand.Rand) *differentialProgram {
+	b := New(name)
+	g := &differentialGenerator{rng: rng, fn: b.Main()}
+	p := &differentialProgram{}
+	numInputs := 1 + rng.IntN(2)
+	for i := range numInputs {
+		dims := make([]int, rng.IntN(4))
+		for axis := range dims {
+			dims[axis] = 1 + rng.IntN(4)
+		}
+		ref := newRefTensor(dims)
+		for j := range ref.flat {
+			// Values exactly representable in float32.
+			ref.flat[j] = float64(float32(rng.Float64()*2 - 1))
+		}
+		x := must1(g.fn.NamedInput(fmt.Sprintf("x%d", i), shapes.Make(dtypes.F32, dims...)))
+		g.nodes = append(g.nodes, differentialNode{x, ref})
+		p.inputs = append(p.inputs, ref)
+	}
+	numOps := 1 + rng.IntN(8)
+	for len(g.nodes) < numInputs+numOps {
+		g.randomOp()
+	}
+
+	// Return the last one or two nodes created.
+	numOutputs := min(1+rng.IntN(2), numOps)
+	var outputs []*Value
+	for _, node := range g.nodes[len(g.nodes)-numOutputs:] {
+		outputs = append(outputs, node.value)
+		p.outputs = append(p.outputs, node.ref)
+	}
+	must(g.fn.Return(outputs...))
+	p.program = must1(b.Build())
+	return p
+}
+
+func TestDifferential(t *testing.T) {
+	iterateClientsAndTest(t, testDifferential)
+}
+
+func testDifferential(t *testing.T, client *pjrt.Client) {
+	rng := rand.New(rand.NewPCG(*flagDifferentialSeed, 0))
+	for programIdx := range *flagDifferentialPrograms {
+		p := generateDifferentialProgram(fmt.Sprintf("differential_%d", programIdx), rng)
+		var inputs []*pjrt.Buffer
+		for _, input := range p.inputs {
+			flat := make([]float32, len(input.flat))
+			for i, v := range input.flat {
+				flat[i] = float32(v)
+			}
+			inputs = append(inputs, must1(client.BufferFromHost().FromFlatDataWithDimensions(flat, input.dims).Done()))
+		}
+		outputs := compileAndExecute(t, client, p.program, inputs...)
+		for i, output := range outputs {
+			gotFlat, gotDims, err := output.ToFlatDataAndDimensions()
+			must(output.Destroy())
+			if err != nil {
+				t.Fatalf("program #%d (seed %d): failed to get output #%d: %+v", programIdx, *flagDifferentialSeed, i, err)
+			}
+			want := p.outputs[i]
+			if !slices.Equal(gotDims, want.dims) && (len(gotDims) != 0 || len(want.dims) != 0) {
+				t.Fatalf("program #%d (seed %d):\n%s\noutput #%d dimensions don't match: want %v, got %v",
+					programIdx, *flagDifferentialSeed, withLines(p.program), i, want.dims, gotDims)
+			}
+			for j, got := range gotFlat.([]float32) {
+				if math.Abs(float64(got)-want.flat[j]) > 1e-4*(1+math.Abs(want.flat[j])) {
+					t.Fatalf("program #%d (seed %d):\n%s\noutput #%d doesn't match at flat index %d: want %v, got %v",
+						programIdx, *flagDifferentialSeed, withLines(p.program), i, j, want.flat[j], got)
+				}
+			}
+		}
+	}
+}
+
+func TestDifferentialGenerator(t *testing.T) {
+	// The generator and reference interpreter don't require PJRT: check they build valid programs.
+	rng := rand.New(rand.NewPCG(*flagDifferentialSeed, 0))
+	for programIdx := range 100 {
+		p := generateDifferentialProgram(fmt.Sprintf("differential_%d", programIdx), rng)
+		if len(p.program) == 0 || len(p.outputs) == 0 {
+			t.Fatalf("program #%d is empty", programIdx)
+		}
+	}
+
+	// Sanity check of the reference interpreter.
+	x := refTensor{dims: []int{2, 3}, flat: []float64{0, 1, 2, 3, 4, 5}}
+	if got := x.transpose([]int{1, 0}).flat; !slices.Equal(got, []float64{0, 3, 1, 4, 2, 5}) {
+		t.Errorf("transpose: got %v", got)
+	}
+	if got := x.reduceSum(1).flat; !slices.Equal(got, []float64{3, 12}) {
+		t.Errorf("reduceSum: got %v", got)
+	}
+	if got := x.slice([]int{0, 1}, []int{2, 3}, []int{1, 2}).flat; !slices.Equal(got, []float64{1, 4}) {
+		t.Errorf("slice: got %v", got)
+	}
+	if got := x.concatenate(x, 0).dims; !slices.Equal(got, []int{4, 3}) {
+		t.Errorf("concatenate: got dims %v", got)
+	}
+}
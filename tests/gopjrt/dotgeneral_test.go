@@ -82,6 +82,26 @@ func testDotGeneral(t *testing.T, client *pjrt.Client) {
 		requireBuffersEqual(t, wantResult, outputs)
 	})
 
+	t.Run("BatchContractingCross(preset)", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.NewFunction("main")
+		one := must1(fn.ConstantFromScalar(float32(1)))
+		lhs := must1(fn.Iota(S.Make(D.F32, 2*3*1*5), 0))
+		lhs = must1(Add(lhs, must1(BroadcastInDim(one, lhs.Shape(), nil))))
+		lhs = must1(Reshape(lhs, S.Make(D.F32, 2, 3, 1, 5)))
+		rhs := must1(fn.Iota(S.Make(D.F32, 5*3*2*4), 0))
+		rhs = must1(Add(rhs, must1(BroadcastInDim(one, rhs.Shape(), nil))))
+		rhs = must1(Reshape(rhs, S.Make(D.F32, 5, 3, 2, 4)))
+		dg := must1(DotGeneral(lhs, []int{1}, []int{3, 0}, rhs, []int{1}, []int{0, 2}).
+			AlgorithmPreset(types.DotAlgorithmF32_F32_F32).
+			Done())
+		must(fn.Return(dg))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		outputs := compileAndExecute(t, client, program)
+		requireBuffersEqual(t, wantResult, outputs)
+	})
+
 	if strings.Index(strings.ToUpper(client.Plugin().String()), "CUDA") != -1 {
 		t.Run("BatchContractingCross(tf32)", func(t *testing.T) {
 			builder := New(t.Name())
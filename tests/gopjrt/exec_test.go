@@ -0,0 +1,110 @@
+package gopjrt
+
+import (
+	"bytes"
+	"encoding/binary"
+	"math"
+	"os"
+	"slices"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/gopjrt/pjrt"
+	. "github.com/gomlx/stablehlo"
+	"github.com/gomlx/stablehlo/exec"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestExec(t *testing.T) {
+	iterateClientsAndTest(t, testExec)
+}
+
+func testExec(t *testing.T, client *pjrt.Client) {
+	t.Run("constant inputs and weights", func(t *testing.T) {
+		builder := New(t.Name()).WithLargeConstantsAsInputs(3)
+		fn := builder.Main()
+		x := must1(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		weights := must1(fn.ExternalConstant("weights", shapes.Make(dtypes.Float32, 3)))
+		bias := must1(fn.ConstantFromFlatAndDimensions([]float32{10, 20, 30}, 3))
+		y := must1(Multiply(x, weights))
+		y = must1(Add(y, bias))
+		must(fn.Return(y))
+
+		e := must1(exec.Compile(client, fn))
+		defer func() { must(e.Destroy()) }()
+		xBuffer := must1(client.BufferFromHost().FromFlatDataWithDimensions([]float32{1, 2, 3}, []int{3}).Done())
+		if _, err := e.Execute(xBuffer); err == nil {
+			t.Fatal("expected error executing with unbound weights, got nil")
+		}
+		var data []byte
+		for _, v := range []float32{2, 3, 4} {
+			data = binary.LittleEndian.AppendUint32(data, math.Float32bits(v))
+		}
+		must(e.BindWeights(exec.Weights{"weights": {DType: dtypes.Float32, Dimensions: []int{3}, Data: data}}))
+		outputs := must1(e.Execute(xBuffer))
+		requireBuffersEqual(t, []FlatAndDims{{[]float32{12, 26, 42}, []int{3}}}, outputs)
+		must(xBuffer.Destroy())
+	})
+	t.Run("compile options", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must1(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		must(fn.Return(must1(Exponential(must1(Negate(x))))))
+
+		dumpDir := t.TempDir()
+		envBefore, envWasSet := os.LookupEnv(pjrt.EnvXlaDebugOptions)
+		e := must1(exec.CompileWithOptions(client, fn, &exec.CompileOptions{DisableFusion: true, DumpTo: dumpDir}))
+		defer func() { must(e.Destroy()) }()
+		if env, found := os.LookupEnv(pjrt.EnvXlaDebugOptions); found != envWasSet || env != envBefore {
+			t.Errorf("$%s was changed by the compilation to %q", pjrt.EnvXlaDebugOptions, env)
+		}
+		if entries := must1(os.ReadDir(dumpDir)); len(entries) == 0 {
+			t.Errorf("XLA didn't dump the program to %s", dumpDir)
+		}
+		xBuffer := must1(client.BufferFromHost().FromFlatDataWithDimensions([]float32{0, 1, 2}, []int{3}).Done())
+		outputs := must1(e.Execute(xBuffer))
+		requireBuffersEqual(t, []FlatAndDims{{[]float32{1, float32(math.Exp(-1)), float32(math.Exp(-2))}, []int{3}}}, outputs)
+		must(xBuffer.Destroy())
+	})
+	t.Run("DebugPrint", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must1(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		y := must1(DebugPrint(must1(Negate(x)), "-x"))
+		must(fn.Return(must1(Add(y, y))))
+
+		var debugOutput bytes.Buffer
+		e := must1(exec.Compile(client, fn))
+		defer func() { must(e.Destroy()) }()
+		e.WithDebugWriter(&debugOutput)
+		xBuffer := must1(client.BufferFromHost().FromFlatDataWithDimensions([]float32{1, 2, 3}, []int{3}).Done())
+		outputs := must1(e.Execute(xBuffer))
+		requireBuffersEqual(t, []FlatAndDims{{[]float32{-2, -4, -6}, []int{3}}}, outputs)
+		if want := "DebugPrint(-x): dimensions=[3], values=[-1 -2 -3]\n"; debugOutput.String() != want {
+			t.Errorf("expected debug output %q, got %q", want, debugOutput.String())
+		}
+		must(xBuffer.Destroy())
+	})
+	t.Run("host transfers", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must1(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2, 3)))
+		must(fn.Return(must1(Transpose(x, 1, 0))))
+
+		e := must1(exec.Compile(client, fn))
+		defer func() { must(e.Destroy()) }()
+		// float64 data in column-major order fed to the float32 input.
+		xBuffer := must1(e.UploadInput(0, []float64{0, 3, 1, 4, 2, 5}, exec.ColumnMajor))
+		outputs := must1(e.Execute(xBuffer))
+		got := make([]float64, 6)
+		must(exec.Download(outputs[0], got, exec.ColumnMajor))
+		if want := []float64{0, 1, 2, 3, 4, 5}; !slices.Equal(got, want) {
+			t.Errorf("downloaded %v, wanted %v", got, want)
+		}
+		requireBuffersEqual(t, []FlatAndDims{{[]float32{0, 3, 1, 4, 2, 5}, []int{3, 2}}}, outputs)
+		if _, err := e.UploadInput(0, []float64{1, 2}, exec.RowMajor); err == nil {
+			t.Error("expected error uploading data with the wrong size, got nil")
+		}
+		must(xBuffer.Destroy())
+	})
+}
@@ -0,0 +1,53 @@
+package gopjrt
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/gomlx/gopjrt/pjrt"
+	. "github.com/gomlx/stablehlo"
+)
+
+func TestDecompositions(t *testing.T) {
+	iterateClientsAndTest(t, testDecompositions)
+}
+
+func testDecompositions(t *testing.T, client *pjrt.Client) {
+	inputs := []float64{-2.5, -1, -0.5, -0.1, 0, 0.3, 0.5, 1.5, 2.5}
+	for _, tc := range []struct {
+		name      string
+		op        func(*Value) (*Value, error)
+		reference func(float64) float64
+	}{
+		{"chlo.erf", Erf, math.Erf},
+		{"stablehlo.round_nearest_afz", RoundNearestAfz, math.Round},
+		{"stablehlo.cbrt", Cbrt, math.Cbrt},
+		{"stablehlo.logistic", Logistic, func(x float64) float64 { return 1 / (1 + math.Exp(-x)) }},
+		{"stablehlo.tan", Tan, math.Tan},
+		{"stablehlo.exponential_minus_one", ExponentialMinusOne, math.Expm1},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			builder := New(t.Name()).WithTargetFeatures(TargetFeatures{
+				Name:           "test",
+				UnsupportedOps: []string{tc.name},
+			})
+			fn := builder.Main()
+			x := must1(fn.ConstantFromFlatAndDimensions(inputs, len(inputs)))
+			must(fn.Return(must1(tc.op(x))))
+			program := must1(builder.Build())
+			fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+			outputs := compileAndExecute(t, client, program)
+			got, _, err := outputs[0].ToFlatDataAndDimensions()
+			if err != nil {
+				t.Fatalf("failed to get output: %v", err)
+			}
+			for i, x := range inputs {
+				want := tc.reference(x)
+				if diff := math.Abs(got.([]float64)[i] - want); diff > 1e-6 {
+					t.Errorf("%s(%g): got %g, want %g", tc.name, x, got.([]float64)[i], want)
+				}
+			}
+		})
+	}
+}
@@ -11,10 +11,12 @@ import (
 	"testing"
 
 	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/gopjrt/dtypes/bfloat16"
 	"github.com/gomlx/gopjrt/pjrt"
 	. "github.com/gomlx/stablehlo"
 	"github.com/gomlx/stablehlo/types"
 	"github.com/gomlx/stablehlo/types/shapes"
+	"github.com/x448/float16"
 	"k8s.io/klog/v2"
 )
 
@@ -235,6 +237,27 @@ func testRendering(t *testing.T, client *pjrt.Client) {
 			{[]float64{1e6, 1e-6, 0, -1e-8, -1e6}, []int{5, 1}},
 		}, outputs)
 	})
+
+	// GenericForm checks that the module and functions rendered in the MLIR generic form are parsed.
+	t.Run("GenericForm", func(t *testing.T) {
+		builder := New(t.Name()).WithGenericForm(true)
+		fn := builder.Main()
+		x := must1(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		reduceFn := fn.Closure()
+		lhs := must1(reduceFn.NamedInput("lhs", shapes.Make(dtypes.Float32)))
+		rhs := must1(reduceFn.NamedInput("rhs", shapes.Make(dtypes.Float32)))
+		must(reduceFn.Return(must1(Add(lhs, rhs))))
+		sum := must1(Reduce(x, must1(fn.ConstantFromScalar(float32(0))), reduceFn, 0))
+		must(fn.Return(must1(Negate(x)), sum))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		input := must1(client.BufferFromHost().FromFlatDataWithDimensions([]float32{1, 2, 3}, []int{3}).Done())
+		outputs := compileAndExecute(t, client, program, input)
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]float32{-1, -2, -3}, []int{3}},
+			{[]float32{6}, nil},
+		}, outputs)
+	})
 }
 
 func TestOps(t *testing.T) {
@@ -256,6 +279,54 @@ func testOps(t *testing.T, client *pjrt.Client) {
 		requireBuffersEqual(t, []FlatAndDims{{[]complex128{1 - 1i}, nil}}, output)
 	})
 
+	t.Run("Conjugate", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must1(fn.NamedInput("x", shapes.Make(dtypes.Complex128, 2)))
+		must(fn.Return(must1(Conjugate(x))))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		a := must1(client.BufferFromHost().FromFlatDataWithDimensions([]complex128{1 - 2i, -3 + 4i}, []int{2}).Done())
+		output := compileAndExecute(t, client, program, a)
+		requireBuffersEqual(t, []FlatAndDims{{[]complex128{1 + 2i, -3 - 4i}, []int{2}}}, output)
+	})
+
+	t.Run("ComplexFromPolar", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		shape := shapes.Make(dtypes.Float32, 3)
+		magnitude, phase := must1(fn.NamedInput("magnitude", shape)), must1(fn.NamedInput("phase", shape))
+		c := must1(ComplexFromPolar(magnitude, phase))
+		must(fn.Return(must1(Real(c)), must1(Imag(c))))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		a := must1(client.BufferFromHost().FromFlatDataWithDimensions([]float32{2, 1, 3}, []int{3}).Done())
+		b := must1(client.BufferFromHost().FromFlatDataWithDimensions([]float32{0, math.Pi / 2, math.Pi}, []int{3}).Done())
+		outputs := compileAndExecute(t, client, program, a, b)
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]float32{2, 0, -3}, []int{3}},
+			{[]float32{0, 1, 0}, []int{3}},
+		}, outputs)
+	})
+
+	t.Run("AbsComplex", func(t *testing.T) {
+		// Abs of complex values returns the real-typed magnitude.
+		builder := New(t.Name())
+		fn := builder.Main()
+		x64 := must1(fn.NamedInput("x64", shapes.Make(dtypes.Complex64, 2)))
+		x128 := must1(fn.NamedInput("x128", shapes.Make(dtypes.Complex128, 2)))
+		must(fn.Return(must1(Abs(x64)), must1(Abs(x128))))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		a := must1(client.BufferFromHost().FromFlatDataWithDimensions([]complex64{3 + 4i, -1}, []int{2}).Done())
+		b := must1(client.BufferFromHost().FromFlatDataWithDimensions([]complex128{-6 - 8i, 2i}, []int{2}).Done())
+		outputs := compileAndExecute(t, client, program, a, b)
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]float32{5, 1}, []int{2}},
+			{[]float64{10, 2}, []int{2}},
+		}, outputs)
+	})
+
 	t.Run("Clamp", func(t *testing.T) {
 		builder := New(t.Name())
 		fn := builder.Main()
@@ -272,6 +343,22 @@ func testOps(t *testing.T, client *pjrt.Client) {
 		requireBuffersEqual(t, []FlatAndDims{{[]float32{0.1, -1, 1}, []int{3}}}, output)
 	})
 
+	t.Run("OrderingGroup", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must1(fn.NamedInput("x", shapes.Make(dtypes.F32, 3)))
+		outputs := must1(fn.OrderingGroup([]*Value{x}, func(inputs []*Value) ([]*Value, error) {
+			y, err := Multiply(inputs[0], inputs[0])
+			return []*Value{y}, err
+		}))
+		must(fn.Return(must1(Add(outputs[0], x))))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		a := must1(client.BufferFromHost().FromFlatDataWithDimensions([]float32{1, 2, 3}, []int{3}).Done())
+		output := compileAndExecute(t, client, program, a)
+		requireBuffersEqual(t, []FlatAndDims{{[]float32{2, 6, 12}, []int{3}}}, output)
+	})
+
 	t.Run("Iota", func(t *testing.T) {
 		builder := New(t.Name())
 		fn := builder.Main()
@@ -304,6 +391,22 @@ func testOps(t *testing.T, client *pjrt.Client) {
 		}, outputs)
 	})
 
+	t.Run("IsFinite(bf16)", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		input := must1(fn.NamedInput("x", shapes.Make(dtypes.BFloat16, 4)))
+		must(fn.Return(must1(IsFinite(input))))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		flat := []bfloat16.BFloat16{bfloat16.FromFloat32(-2), bfloat16.FromFloat32(float32(math.Inf(1))),
+			bfloat16.FromFloat32(float32(math.NaN())), bfloat16.FromFloat32(0.5)}
+		v := must1(client.BufferFromHost().FromFlatDataWithDimensions(flat, []int{4}).Done())
+		outputs := compileAndExecute(t, client, program, v)
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]bool{true, false, false, true}, []int{4}},
+		}, outputs)
+	})
+
 	t.Run("Reshape", func(t *testing.T) {
 		builder := New(t.Name())
 		fn := builder.Main()
@@ -370,6 +473,31 @@ func testOps(t *testing.T, client *pjrt.Client) {
 		}, outputs)
 	})
 
+	t.Run("GatherToSlices", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must1(fn.Iota(shapes.Make(dtypes.F32, 3*5), 0))
+		x = must1(Reshape(x, shapes.Make(dtypes.F32, 3, 5)))
+		indices := must1(fn.ConstantFromFlatAndDimensions([]int{2, 0, 9}, 3, 1)) // 9 is clamped to 2.
+		var y [2]*Value
+		for i, offsetOutputAxes := range [][]int{{1}, {0}} {
+			y[i] = must1(Gather(x, indices, 1,
+				offsetOutputAxes, []int{0}, nil, nil, []int{0},
+				[]int{1, 5}, false))
+		}
+		must(fn.Return(y[0], y[1]))
+		if numRewrites := must1(fn.Rewrite(GatherToSlices(8))); numRewrites != 2 {
+			t.Fatalf("expected 2 rewrites, got %d", numRewrites)
+		}
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		outputs := compileAndExecute(t, client, program)
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]float32{10, 11, 12, 13, 14, 0, 1, 2, 3, 4, 10, 11, 12, 13, 14}, []int{3, 5}},
+			{[]float32{10, 0, 10, 11, 1, 11, 12, 2, 12, 13, 3, 13, 14, 4, 14}, []int{5, 3}},
+		}, outputs)
+	})
+
 	//	Slice(x={0, 1, 2, 3, 4}, starts={2}, limits={4}, strides=nil) -> {2, 3}
 	//	Slice(x={0, 1, 2, 3, 4}, starts={2}, limits={5}, strides={2}) -> {2, 4}
 	t.Run("Slice", func(t *testing.T) {
@@ -388,6 +516,79 @@ func testOps(t *testing.T, client *pjrt.Client) {
 		}, outputs)
 	})
 
+	t.Run("SliceEx", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must1(fn.Iota(shapes.Make(dtypes.F32, 5), 0))
+		y0 := must1(SliceEx(x, []int{-2}, nil, nil))
+		y1 := must1(SliceEx(x, []int{3}, []int{0}, []int{-1}))
+		y2 := must1(SliceEx(x, nil, nil, []int{-2}))
+		must(fn.Return(y0, y1, y2))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		outputs := compileAndExecute(t, client, program)
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]float32{3, 4}, []int{2}},
+			{[]float32{3, 2, 1}, []int{3}},
+			{[]float32{4, 2, 0}, []int{3}},
+		}, outputs)
+	})
+
+	t.Run("Split and Unstack", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must1(fn.Iota(shapes.Make(dtypes.F32, 6), 0))
+		x = must1(Reshape(x, shapes.Make(dtypes.F32, 2, 3)))
+		parts := must1(SplitSizes(x, []int{1, 2}, 1))
+		rows := must1(Unstack(x, 0))
+		must(fn.Return(append(parts, rows...)...))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		outputs := compileAndExecute(t, client, program)
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]float32{0, 3}, []int{2, 1}},
+			{[]float32{1, 2, 4, 5}, []int{2, 2}},
+			{[]float32{0, 1, 2}, []int{3}},
+			{[]float32{3, 4, 5}, []int{3}},
+		}, outputs)
+	})
+
+	t.Run("Stack", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must1(fn.ConstantFromFlatAndDimensions([]float32{1, 2}, 2))
+		y := must1(fn.ConstantFromFlatAndDimensions([]float32{3, 4}, 2))
+		must(fn.Return(must1(Stack(0, x, y)), must1(Stack(1, x, y))))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		outputs := compileAndExecute(t, client, program)
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]float32{1, 2, 3, 4}, []int{2, 2}},
+			{[]float32{1, 3, 2, 4}, []int{2, 2}},
+		}, outputs)
+	})
+
+	t.Run("Call and InlineCalls", func(t *testing.T) {
+		for _, inline := range []bool{false, true} {
+			builder := New(t.Name())
+			square := builder.NewFunction("square")
+			sx := must1(square.NamedInput("x", shapes.Make(dtypes.F32, 2)))
+			must(square.Return(must1(Multiply(sx, sx))))
+			fn := builder.Main()
+			x := must1(fn.ConstantFromFlatAndDimensions([]float32{2, 3}, 2))
+			y := must1(fn.Call(square, x))[0]
+			y = must1(fn.Call(square, y))[0]
+			must(fn.Return(y))
+			if inline {
+				must1(builder.InlineCalls(-1))
+			}
+			program := must1(builder.Build())
+			fmt.Printf("%s (inline=%v) program:\n%s", t.Name(), inline, withLines(program))
+			outputs := compileAndExecute(t, client, program)
+			requireBuffersEqual(t, []FlatAndDims{{[]float32{16, 81}, []int{2}}}, outputs)
+		}
+	})
+
 	t.Run("Concatenate", func(t *testing.T) {
 		builder := New(t.Name())
 		fn := builder.Main()
@@ -425,6 +626,155 @@ func testOps(t *testing.T, client *pjrt.Client) {
 		}, outputs)
 	})
 
+	t.Run("ReducePromotion", func(t *testing.T) {
+		// Reduce float16 inputs accumulating in float32: 4096 ones sum to 2048 in float16, since 2048+1 is not
+		// representable, but sum to 4096 in float32.
+		builder := New(t.Name())
+		fn := builder.Main()
+		ones := must1(fn.ConstantFromScalar(float16.Fromfloat32(1)))
+		x := must1(BroadcastInDim(ones, shapes.Make(dtypes.Float16, 2, 4096), nil))
+		zero := must1(fn.ConstantFromScalar(float16.Fromfloat32(0)))
+		reductionFn := fn.Closure()
+		lhs := must1(reductionFn.NamedInput("lhs", shapes.Make(dtypes.F32)))
+		rhs := must1(reductionFn.NamedInput("rhs", shapes.Make(dtypes.F32)))
+		must(reductionFn.Return(must1(Add(lhs, rhs))))
+		sum := must1(Reduce(x, zero, reductionFn, 1))
+		if sum.Shape().DType != dtypes.F32 {
+			t.Fatalf("expected float32 output, got %s", sum.Shape())
+		}
+		must(fn.Return(sum))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		outputs := compileAndExecute(t, client, program)
+		requireBuffersEqual(t, []FlatAndDims{{[]float32{4096, 4096}, []int{2}}}, outputs)
+	})
+
+	t.Run("ReduceKeepDims", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must1(fn.Iota(shapes.Make(dtypes.F32, 2*3), 0))
+		x = must1(Reshape(x, shapes.Make(dtypes.F32, 2, 3)))
+		zero := must1(fn.ConstantFromScalar(float32(0)))
+		reductionFn := fn.Closure()
+		lhs := must1(reductionFn.NamedInput("lhs", shapes.Make(dtypes.F32)))
+		rhs := must1(reductionFn.NamedInput("rhs", shapes.Make(dtypes.F32)))
+		must(reductionFn.Return(must1(Add(lhs, rhs))))
+		r0 := must1(ReduceKeepDims(x, zero, reductionFn, -1))
+		r1 := must1(ReduceKeepDims(x, zero, reductionFn, 0))
+		r2 := must1(ReduceAll(x, zero, reductionFn))
+		must(fn.Return(r0, r1, r2))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		outputs := compileAndExecute(t, client, program)
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]float32{3, 12}, []int{2, 1}},
+			{[]float32{3, 5, 7}, []int{1, 3}},
+			{[]float32{15}, nil},
+		}, outputs)
+	})
+
+	t.Run("AccumulateMicrobatches", func(t *testing.T) {
+		builder := New(t.Name())
+		step := builder.NewFunction("step")
+		w := must1(step.NamedInput("w", shapes.Make(dtypes.F32, 3)))
+		x := must1(step.NamedInput("x", shapes.Make(dtypes.F32, 2, 3)))
+		must(step.Return(must1(Multiply(must1(BroadcastInDim(w, x.Shape(), []int{1})), x))))
+		fn := builder.Main()
+		params := must1(fn.ConstantFromFlatAndDimensions([]float32{1, 2, 3}, 3))
+		batch := must1(fn.Iota(shapes.Make(dtypes.F32, 8, 3), 0))
+		sums := must1(fn.AccumulateMicrobatches(step, 4, []*Value{params}, []*Value{batch}))
+		must(fn.Return(sums...))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		outputs := compileAndExecute(t, client, program)
+		// Rows 0, 2, 4, 6 are accumulated in the first row, and rows 1, 3, 5, 7 in the second.
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]float32{12, 24, 36, 16, 32, 48}, []int{2, 3}},
+		}, outputs)
+	})
+
+	t.Run("ForI", func(t *testing.T) {
+		builder := New(t.Name())
+		body := builder.NewFunction("body")
+		i := must1(body.NamedInput("i", shapes.Make(dtypes.Int32)))
+		acc := must1(body.NamedInput("acc", shapes.Make(dtypes.F32)))
+		must(body.Return(must1(Add(acc, must1(Convert(i, dtypes.F32))))))
+		fn := builder.Main()
+		lower := must1(fn.ConstantFromScalar(int32(2)))
+		upper := must1(fn.ConstantFromScalar(int32(5)))
+		init := must1(fn.ConstantFromScalar(float32(10)))
+		must(fn.Return(must1(fn.ForI(lower, upper, body, init))...))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		outputs := compileAndExecute(t, client, program)
+		// 10 + 2 + 3 + 4
+		requireBuffersEqual(t, []FlatAndDims{{[]float32{19}, nil}}, outputs)
+	})
+
+	t.Run("Scan", func(t *testing.T) {
+		builder := New(t.Name())
+		body := builder.NewFunction("body")
+		carry := must1(body.NamedInput("carry", shapes.Make(dtypes.F32, 2)))
+		x := must1(body.NamedInput("x", shapes.Make(dtypes.F32, 2)))
+		sum := must1(Add(carry, x))
+		must(body.Return(sum, sum))
+		fn := builder.Main()
+		xs := must1(fn.Iota(shapes.Make(dtypes.F32, 4*2), 0))
+		xs = must1(Reshape(xs, shapes.Make(dtypes.F32, 4, 2)))
+		init := must1(fn.ConstantFromFlatAndDimensions([]float32{100, 200}, 2))
+		final, ys := must2(fn.Scan([]*Value{xs}, []*Value{init}, body))
+		must(fn.Return(final[0], ys[0]))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		outputs := compileAndExecute(t, client, program)
+		// Cumulative sums of the rows [[0, 1], [2, 3], [4, 5], [6, 7]], starting from [100, 200].
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]float32{112, 216}, []int{2}},
+			{[]float32{100, 201, 102, 204, 106, 209, 112, 216}, []int{4, 2}},
+		}, outputs)
+	})
+
+	t.Run("ArgMinMax", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		nan := float32(math.NaN())
+		x := must1(fn.ConstantFromFlatAndDimensions([]float32{
+			1, 5, 5, 0, 0,
+			3, nan, 2, nan, -1,
+		}, 2, 5))
+		must(fn.Return(
+			must1(ArgMax(x, 1, dtypes.Int32, TiesToLowestIndex)),
+			must1(ArgMax(x, 1, dtypes.Int32, TiesToHighestIndex)),
+			must1(ArgMin(x, 1, dtypes.Int64, TiesToLowestIndex)),
+			must1(ArgMin(x, 1, dtypes.Int64, TiesToHighestIndex))))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		outputs := compileAndExecute(t, client, program)
+		// NaNs win both ArgMax and ArgMin.
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]int32{1, 1}, []int{2}},
+			{[]int32{2, 3}, []int{2}},
+			{[]int64{3, 1}, []int{2}},
+			{[]int64{4, 3}, []int{2}},
+		}, outputs)
+	})
+
+	t.Run("LayerNorm", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must1(fn.ConstantFromFlatAndDimensions([]float32{1, 2, 3, 4, 0, 0, 4, 4}, 2, 4))
+		scale := must1(fn.ConstantFromFlatAndDimensions([]float32{1, 1, 2, 2}, 4))
+		offset := must1(fn.ConstantFromFlatAndDimensions([]float32{0, 0, 0, 1}, 4))
+		y := must1(LayerNorm(x, []int{-1}, scale, offset, 0))
+		must(fn.Return(y))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		outputs := compileAndExecute(t, client, program)
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]float32{-1.3416408, -0.4472136, 0.8944272, 3.6832816, -1, -1, 2, 3}, []int{2, 4}},
+		}, outputs)
+	})
+
 	t.Run("MultiReduce", func(t *testing.T) {
 		builder := New(t.Name())
 		fn := builder.Main()
@@ -455,6 +805,42 @@ func testOps(t *testing.T, client *pjrt.Client) {
 		}, outputs)
 	})
 
+	t.Run("MultiReduce mixed dtypes", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must1(fn.ConstantFromFlatAndDimensions([]float32{1, 5, 2, 7, 0, 3}, 2, 3))
+		idx := must1(fn.Iota(shapes.Make(dtypes.Int32, 2, 3), 1))
+		z := must1(fn.ConstantFromFlatAndDimensions([]float16.Float16{
+			float16.Fromfloat32(1), float16.Fromfloat32(2), float16.Fromfloat32(3),
+			float16.Fromfloat32(4), float16.Fromfloat32(5), float16.Fromfloat32(6)}, 2, 3))
+		// Reductions: maximum of x, the index of the maximum (argmax) and the sum of z accumulated in float32.
+		inputShapes := []shapes.Shape{shapes.Make(dtypes.F32), shapes.Make(dtypes.Int32), shapes.Make(dtypes.F32)}
+		reductionFn, args, err := fn.ClosureWithSignature(append(inputShapes, inputShapes...), inputShapes...)
+		must(err)
+		lhs, rhs := args[:3], args[3:]
+		isLhsLarger := must1(CompareAuto(lhs[0], rhs[0], types.CompareGE))
+		must(reductionFn.Return(
+			must1(Maximum(lhs[0], rhs[0])),
+			must1(Select(isLhsLarger, lhs[1], rhs[1])),
+			must1(Add(lhs[2], rhs[2]))))
+		results := must1(MultiReduce(
+			[]*Value{x, idx, z},
+			[]*Value{
+				must1(fn.ConstantFromScalar(float32(math.Inf(-1)))),
+				must1(fn.ConstantFromScalar(int32(-1))),
+				must1(fn.ConstantFromScalar(float32(0)))},
+			reductionFn, 1))
+		must(fn.Return(results...))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		outputs := compileAndExecute(t, client, program)
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]float32{5, 7}, []int{2}},
+			{[]int32{1, 0}, []int{2}},
+			{[]float32{6, 15}, []int{2}},
+		}, outputs)
+	})
+
 	t.Run("Select", func(t *testing.T) {
 		builder := New(t.Name())
 		fn := builder.Main()
@@ -546,6 +932,77 @@ func testOps(t *testing.T, client *pjrt.Client) {
 		})
 	}
 
+	t.Run("RNG", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		const numSamples = 1000
+		low := must1(fn.ConstantFromScalar(float32(2)))
+		high := must1(fn.ConstantFromScalar(float32(3)))
+		must(fn.Return(must1(RNG(low, high, shapes.Make(dtypes.F32, numSamples), types.RNGUniform))))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		outputs := compileAndExecute(t, client, program)
+		flat, _, err := outputs[0].ToFlatDataAndDimensions()
+		if err != nil {
+			t.Fatalf("ToFlatDataAndDimensions error: %v", err)
+		}
+		var sum float64
+		for _, v := range flat.([]float32) {
+			if v < 2 || v >= 3 {
+				t.Fatalf("sample %g out of the range [2, 3)", v)
+			}
+			sum += float64(v)
+		}
+		if mean := sum / numSamples; mean < 2.4 || mean > 2.6 {
+			t.Errorf("mean of the samples is %g, expected ~2.5", mean)
+		}
+	})
+
+	t.Run("RNGStateSplit", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		state := must1(fn.ConstantFromFlatAndDimensions([]uint64{42, 7}, 2))
+		states := must1(RNGStateSplit(state, 2))
+		must(fn.Return(states[0], states[1], must1(RNGStateAdvance(state, 3))))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		outputs := compileAndExecute(t, client, program)
+		var gamma uint64 = 0x9E3779B97F4A7C15 // Not const, so the multiplication wraps around.
+		splitMix64 := func(z uint64) uint64 {
+			z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+			z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+			return z ^ (z >> 31)
+		}
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]uint64{splitMix64(42 + gamma), 7}, []int{2}},
+			{[]uint64{splitMix64(42 + 2*gamma), 7}, []int{2}},
+			{[]uint64{42, 10}, []int{2}},
+		}, outputs)
+	})
+
+	t.Run("RNGState", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		rng := must1(NewRNGState(must1(fn.ConstantFromFlatAndDimensions([]uint64{42, 7}, 2)), types.RNGPhilox))
+		splits := must1(rng.Split(2))
+		bits := must1(rng.Next(shapes.Make(dtypes.Uint32, 1000)))
+		must(fn.Return(splits[0].Value(), splits[1].Value(), must1(Slice(bits, []int{0}, []int{1}, nil))))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		outputs := compileAndExecute(t, client, program)
+		var gamma uint64 = 0x9E3779B97F4A7C15 // Not const, so the multiplication wraps around.
+		splitMix64 := func(z uint64) uint64 {
+			z = (z ^ (z >> 30)) * 0xBF58476D1CE4E5B9
+			z = (z ^ (z >> 27)) * 0x94D049BB133111EB
+			return z ^ (z >> 31)
+		}
+		// The split states are the streams 0 and 1 of RNGStateSplit, and the state continues as stream 2.
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]uint64{splitMix64(42 + gamma), 7}, []int{2}},
+			{[]uint64{splitMix64(42 + 2*gamma), 7}, []int{2}},
+		}, outputs[:2])
+	})
+
 	t.Run("Scatter", func(t *testing.T) {
 		builder := New(t.Name())
 		fn := builder.Main()
@@ -632,6 +1089,41 @@ func testOps(t *testing.T, client *pjrt.Client) {
 		}, outputs)
 	})
 
+	t.Run("DynamicPad", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must1(fn.Iota(shapes.Make(dtypes.F32, 2, 3), 1))
+		fill := must1(fn.ConstantFromScalar(float32(-1)))
+		start := must1(fn.ConstantFromFlatAndDimensions([]int32{1, 0}, 2))
+		end := must1(fn.ConstantFromFlatAndDimensions([]int32{0, 1}, 2))
+		interior := must1(fn.ConstantFromFlatAndDimensions([]int32{0, 0}, 2))
+		must(fn.Return(must1(DynamicPad(x, fill, start, end, interior))))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		outputs := compileAndExecute(t, client, program)
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]float32{-1, -1, -1, -1, 0, 1, 2, -1, 0, 1, 2, -1}, []int{3, 4}},
+		}, outputs)
+	})
+
+	t.Run("DynamicGather", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must1(fn.Iota(shapes.Make(dtypes.F32, 3*5), 0))
+		x = must1(Reshape(x, shapes.Make(dtypes.F32, 3, 5)))
+		indices := must1(fn.ConstantFromFlatAndDimensions([]int32{2, 0}, 2, 1))
+		sliceSizes := must1(fn.ConstantFromFlatAndDimensions([]int32{1, 2}, 2))
+		y := must1(DynamicGather(x, indices, sliceSizes, 1,
+			[]int{1}, []int{0}, nil, nil, []int{0}, false))
+		must(fn.Return(y))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		outputs := compileAndExecute(t, client, program)
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]float32{10, 11, 0, 1}, []int{2, 2}},
+		}, outputs)
+	})
+
 	t.Run("Reverse", func(t *testing.T) {
 		builder := New(t.Name())
 		fn := builder.Main()
@@ -712,7 +1204,10 @@ func testOps(t *testing.T, client *pjrt.Client) {
 			[]int{2, 2}, []int{1, 1}, nil, nil, nil))
 		r1 := must1(ReduceWindow(x, zero, reductionFn,
 			[]int{2, 2}, []int{1, 1}, nil, nil, [][2]int{{1, 1}, {1, 1}}))
-		must(fn.Return(r0, r1))
+		// Negative padding trims the first column.
+		r2 := must1(ReduceWindow(x, zero, reductionFn,
+			[]int{1, 2}, []int{1, 1}, nil, nil, [][2]int{{0, 0}, {-1, 0}}))
+		must(fn.Return(r0, r1, r2))
 		program := must1(builder.Build())
 		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
 		outputs := compileAndExecute(t, client, program)
@@ -722,6 +1217,30 @@ func testOps(t *testing.T, client *pjrt.Client) {
 				0, 1, 3, 2,
 				3, 8, 12, 7,
 				3, 7, 9, 5}, []int{3, 4}},
+			{[]float32{3, 9}, []int{2, 1}},
+		}, outputs)
+	})
+
+	t.Run("ReduceWindowWith", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must1(fn.Iota(shapes.Make(dtypes.F32, 2*3), 0))
+		x = must1(Reshape(x, shapes.Make(dtypes.F32, 2, 3)))
+		zero := must1(fn.ConstantFromScalar(float32(0)))
+		reductionFn := fn.Closure()
+		lhs := must1(reductionFn.NamedInput("lhs", shapes.Make(dtypes.F32)))
+		rhs := must1(reductionFn.NamedInput("rhs", shapes.Make(dtypes.F32)))
+		must(reductionFn.Return(must1(Add(lhs, rhs))))
+		window := must1(NewWindow(1, 2).Strides(1, 1).InputDilations(1, 2).Done())
+		r0 := must1(ReduceWindowWith(x, zero, reductionFn, window))
+		must(fn.Return(r0))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		outputs := compileAndExecute(t, client, program)
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]float32{
+				0, 1, 1, 2,
+				3, 4, 4, 5}, []int{2, 4}},
 		}, outputs)
 	})
 
@@ -1290,4 +1809,85 @@ func testConstants(t *testing.T, client *pjrt.Client) {
 	t.Run("1D-float32", func(t *testing.T) { testTensor(t, []float32{1, 2, 3, 5, 7}, 5) })
 	t.Run("2D-complex64", func(t *testing.T) { testTensor(t, []complex64{1, 2, 3, 5i, 7i, 11i}, 2, 3) })
 	t.Run("3D-bool", func(t *testing.T) { testTensor(t, []bool{false, true, false, true}, 2, 1, 2) })
+
+	// Complex tensors with special values: NaN != NaN, so they are compared by their bits.
+	testComplexTensor := func(t *testing.T, flat []complex128, dtype dtypes.DType, dimensions ...int) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		var c *Value
+		var err error
+		if dtype == dtypes.Complex64 {
+			flat64 := make([]complex64, len(flat))
+			for i, v := range flat {
+				flat64[i] = complex64(v)
+			}
+			c, err = fn.ConstantFromFlatAndDimensions(flat64, dimensions...)
+		} else {
+			c, err = fn.ConstantFromFlatAndDimensions(flat, dimensions...)
+		}
+		if err != nil {
+			t.Fatalf("ConstantFromFlatAndDimensions error: %v", err)
+		}
+		must(fn.Return(c))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		output := compileAndExecute(t, client, program)[0]
+		gotFlat, _, err := output.ToFlatDataAndDimensions()
+		if err != nil {
+			t.Fatalf("ToFlatDataAndDimensions error: %v", err)
+		}
+		gotV := reflect.ValueOf(gotFlat)
+		if gotV.Len() != len(flat) {
+			t.Fatalf("got %d elements, want %d", gotV.Len(), len(flat))
+		}
+		sameFloat := func(a, b float64) bool { return a == b || (math.IsNaN(a) && math.IsNaN(b)) }
+		for i, want := range flat {
+			got := gotV.Index(i).Complex()
+			if dtype == dtypes.Complex64 {
+				want = complex128(complex64(want))
+			}
+			if !sameFloat(real(got), real(want)) || !sameFloat(imag(got), imag(want)) {
+				t.Errorf("element #%d: got %v, want %v", i, got, want)
+			}
+		}
+	}
+	specialComplex := []complex128{
+		complex(math.Inf(1), 0), complex(0, math.NaN()), complex(math.Inf(-1), math.Inf(1)),
+		complex(1.5, -2), complex(math.NaN(), math.Inf(-1)), complex(-0.25, 1e-3)}
+	t.Run("2D-complex64-special", func(t *testing.T) { testComplexTensor(t, specialComplex, dtypes.Complex64, 2, 3) })
+	t.Run("3D-complex128-special", func(t *testing.T) { testComplexTensor(t, specialComplex, dtypes.Complex128, 3, 1, 2) })
+
+	// Special float constants: the values are converted to float64 to compare, and NaN is checked with math.IsNaN.
+	testSpecial := func(t *testing.T, dtype dtypes.DType, create func(fn *Function) (*Value, error), want float64) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		c, err := create(fn)
+		if err != nil {
+			t.Fatalf("failed to create constant: %v", err)
+		}
+		c = must1(Convert(c, dtypes.Float64))
+		must(fn.Return(c))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		output := compileAndExecute(t, client, program)[0]
+		got := must1(pjrt.BufferToScalar[float64](output))
+		if got != want && !(math.IsNaN(got) && math.IsNaN(want)) {
+			t.Errorf("%s: got %g, want %g", dtype, got, want)
+		}
+	}
+	for _, dtype := range []dtypes.DType{dtypes.Float16, dtypes.BFloat16, dtypes.Float32, dtypes.Float64} {
+		t.Run("special-"+dtype.String(), func(t *testing.T) {
+			testSpecial(t, dtype, func(fn *Function) (*Value, error) { return fn.ConstantInf(dtype, false) }, math.Inf(1))
+			testSpecial(t, dtype, func(fn *Function) (*Value, error) { return fn.ConstantInf(dtype, true) }, math.Inf(-1))
+			testSpecial(t, dtype, func(fn *Function) (*Value, error) { return fn.ConstantNaN(dtype) }, math.NaN())
+		})
+	}
+	t.Run("special-max-epsilon", func(t *testing.T) {
+		testSpecial(t, dtypes.Float32, func(fn *Function) (*Value, error) { return fn.ConstantMaxFinite(dtypes.Float32) }, math.MaxFloat32)
+		testSpecial(t, dtypes.Float32, func(fn *Function) (*Value, error) { return fn.ConstantMinFinite(dtypes.Float32) }, -math.MaxFloat32)
+		testSpecial(t, dtypes.Float16, func(fn *Function) (*Value, error) { return fn.ConstantMaxFinite(dtypes.Float16) }, 65504)
+		testSpecial(t, dtypes.BFloat16, func(fn *Function) (*Value, error) { return fn.ConstantEpsilon(dtypes.BFloat16) }, 1.0/128)
+		testSpecial(t, dtypes.F8E4M3FN, func(fn *Function) (*Value, error) { return fn.ConstantMaxFinite(dtypes.F8E4M3FN) }, 448)
+		testSpecial(t, dtypes.F8E5M2, func(fn *Function) (*Value, error) { return fn.ConstantInf(dtypes.F8E5M2, true) }, math.Inf(-1))
+	})
 }
@@ -0,0 +1,153 @@
+package gopjrt
+
+import (
+	"fmt"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/gopjrt/pjrt"
+	. "github.com/gomlx/stablehlo"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+// requireNumDevices skips the test if the client doesn't have at least numDevices addressable devices.
+//
+// For the CPU plugin, more devices can be configured with XLA_FLAGS=--xla_force_host_platform_device_count=<n>.
+func requireNumDevices(t *testing.T, client *pjrt.Client, numDevices int) {
+	t.Helper()
+	if got := client.NumDevices(); got < numDevices {
+		t.Skipf("Skipping test: not enough devices: %d < %d "+
+			"(for CPU set XLA_FLAGS=--xla_force_host_platform_device_count=%d)", got, numDevices, numDevices)
+	}
+}
+
+// makeReplicaGroups splits the replicas 0...numReplicas-1 into consecutive groups of groupSize replicas each.
+// E.g.: makeReplicaGroups(4, 2) returns [[0, 1], [2, 3]].
+func makeReplicaGroups(numReplicas, groupSize int) [][]int {
+	if groupSize <= 0 || numReplicas%groupSize != 0 {
+		panic(fmt.Sprintf("makeReplicaGroups: numReplicas=%d is not divisible by groupSize=%d", numReplicas, groupSize))
+	}
+	groups := make([][]int, 0, numReplicas/groupSize)
+	for start := 0; start < numReplicas; start += groupSize {
+		group := make([]int, groupSize)
+		for i := range group {
+			group[i] = start + i
+		}
+		groups = append(groups, group)
+	}
+	return groups
+}
+
+// spmdCompileAndExecute compiles the SPMD program for numReplicas devices and executes it.
+//
+// replicaInputs[r] holds the inputs (one per main function argument) for replica r, which is transferred to device r.
+// All inputs are donated.
+//
+// The returned buffers are in replica-major order: all outputs of replica 0, then all outputs of replica 1, etc.
+func spmdCompileAndExecute(t *testing.T, client *pjrt.Client, program []byte, numReplicas int,
+	replicaInputs [][]FlatAndDims) []*pjrt.Buffer {
+	t.Helper()
+	if len(replicaInputs) != numReplicas {
+		t.Fatalf("spmdCompileAndExecute: got inputs for %d replicas, wanted %d", len(replicaInputs), numReplicas)
+	}
+	var inputs []*pjrt.Buffer
+	for replica, replicaInput := range replicaInputs {
+		for _, input := range replicaInput {
+			buf, err := client.BufferFromHost().FromFlatDataWithDimensions(input.Flat, input.Dims).ToDeviceNum(replica).Done()
+			if err != nil {
+				t.Fatalf("failed to transfer input to device #%d: %+v", replica, err)
+			}
+			inputs = append(inputs, buf)
+		}
+	}
+
+	loadedExec, err := client.Compile().WithStableHLO(program).WithSPMD(numReplicas).Done()
+	if err != nil {
+		t.Fatalf("failed to compile program: \n%s\nError: %v", program, err)
+	}
+	defer func() {
+		err := loadedExec.Destroy()
+		if err != nil {
+			t.Errorf("failed to destroy loaded exec: %+v", err)
+		}
+	}()
+	outputBuffers, err := loadedExec.Execute(inputs...).DonateAll().Done()
+	if err != nil {
+		t.Fatalf("failed to execute program: \n%s\nError: %v", program, err)
+	}
+	return outputBuffers
+}
+
+func TestMakeReplicaGroups(t *testing.T) {
+	groups := makeReplicaGroups(4, 2)
+	if fmt.Sprint(groups) != "[[0 1] [2 3]]" {
+		t.Errorf("makeReplicaGroups(4, 2) = %v", groups)
+	}
+	groups = makeReplicaGroups(3, 3)
+	if fmt.Sprint(groups) != "[[0 1 2]]" {
+		t.Errorf("makeReplicaGroups(3, 3) = %v", groups)
+	}
+}
+
+func TestMultiDevice(t *testing.T) {
+	iterateClientsAndTest(t, testMultiDevice)
+}
+
+func testMultiDevice(t *testing.T, client *pjrt.Client) {
+	// 4 replicas split in 2 groups, so we validate that the reductions don't cross group boundaries.
+	const numReplicas = 4
+	requireNumDevices(t, client, numReplicas)
+	replicaGroups := makeReplicaGroups(numReplicas, 2)
+
+	t.Run("AllReduce", func(t *testing.T) {
+		b := New(t.Name()).WithNumReplicas(numReplicas)
+		fn := b.Main()
+		sumComputation := fn.Closure()
+		{
+			lhs := must1(sumComputation.NamedInput("lhs", shapes.Make(dtypes.F32)))
+			rhs := must1(sumComputation.NamedInput("rhs", shapes.Make(dtypes.F32)))
+			must(sumComputation.Return(must1(Add(lhs, rhs))))
+		}
+		x := must1(fn.NamedInput("x", shapes.Make(dtypes.F32, 2)))
+		reduced := must1(AllReduce([]*Value{x}, replicaGroups, sumComputation))
+		must(fn.Return(reduced[0]))
+		program := must1(b.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+
+		outputs := spmdCompileAndExecute(t, client, program, numReplicas, [][]FlatAndDims{
+			{{[]float32{1, 10}, []int{2}}},
+			{{[]float32{2, 20}, []int{2}}},
+			{{[]float32{3, 30}, []int{2}}},
+			{{[]float32{4, 40}, []int{2}}},
+		})
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]float32{3, 30}, []int{2}},
+			{[]float32{3, 30}, []int{2}},
+			{[]float32{7, 70}, []int{2}},
+			{[]float32{7, 70}, []int{2}},
+		}, outputs)
+	})
+
+	t.Run("AllGather", func(t *testing.T) {
+		b := New(t.Name()).WithNumReplicas(numReplicas)
+		fn := b.Main()
+		x := must1(fn.NamedInput("x", shapes.Make(dtypes.F32, 1, 2)))
+		gathered := must1(AllGather(x, replicaGroups, 0))
+		must(fn.Return(gathered))
+		program := must1(b.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+
+		outputs := spmdCompileAndExecute(t, client, program, numReplicas, [][]FlatAndDims{
+			{{[]float32{1, 10}, []int{1, 2}}},
+			{{[]float32{2, 20}, []int{1, 2}}},
+			{{[]float32{3, 30}, []int{1, 2}}},
+			{{[]float32{4, 40}, []int{1, 2}}},
+		})
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]float32{1, 10, 2, 20}, []int{2, 2}},
+			{[]float32{1, 10, 2, 20}, []int{2, 2}},
+			{[]float32{3, 30, 4, 40}, []int{2, 2}},
+			{[]float32{3, 30, 4, 40}, []int{2, 2}},
+		}, outputs)
+	})
+}
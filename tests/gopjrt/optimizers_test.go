@@ -0,0 +1,69 @@
+package gopjrt
+
+import (
+	"fmt"
+	"math"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/gopjrt/pjrt"
+	. "github.com/gomlx/stablehlo"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestOptimizers(t *testing.T) {
+	iterateClientsAndTest(t, testOptimizers)
+}
+
+func testOptimizers(t *testing.T, client *pjrt.Client) {
+	t.Run("SGD", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		shape := shapes.Make(dtypes.F32, 3)
+		param := must1(fn.NamedInput("param", shape))
+		grad := must1(fn.NamedInput("grad", shape))
+		newParams, _ := must2(fn.ApplyOptimizer(SGD(0.5), []*Value{param}, []*Value{grad}, nil))
+		must(fn.Return(newParams[0]))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+		paramBuf := must1(client.BufferFromHost().FromFlatDataWithDimensions([]float32{1, 2, 3}, []int{3}).Done())
+		gradBuf := must1(client.BufferFromHost().FromFlatDataWithDimensions([]float32{2, 0, -2}, []int{3}).Done())
+		outputs := compileAndExecute(t, client, program, paramBuf, gradBuf)
+		requireBuffersEqual(t, []FlatAndDims{{[]float32{0, 2, 4}, []int{3}}}, outputs)
+	})
+
+	t.Run("Adam", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		shape := shapes.Make(dtypes.F64, 2)
+		step := must1(fn.NamedInput("step", shapes.Make(dtypes.Int32)))
+		param := must1(fn.NamedInput("param", shape))
+		m := must1(fn.NamedInput("m", shape))
+		v := must1(fn.NamedInput("v", shape))
+		grad := must1(fn.NamedInput("grad", shape))
+		lr, beta1, beta2, epsilon := 0.1, 0.9, 0.999, 1e-8
+		newParams, newStates := must2(fn.ApplyOptimizer(Adam(lr, beta1, beta2, epsilon, step),
+			[]*Value{param}, []*Value{grad}, [][]*Value{{m, v}}))
+		must(fn.Return(newParams[0], newStates[0][0], newStates[0][1]))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), withLines(program))
+
+		// First step: the bias-corrected update is lr * sign(grad).
+		grads := []float64{1, -2}
+		stepBuf := must1(client.BufferFromHost().FromFlatDataWithDimensions([]int32{1}, nil).Done())
+		paramBuf := must1(client.BufferFromHost().FromFlatDataWithDimensions([]float64{1, 1}, []int{2}).Done())
+		mBuf := must1(client.BufferFromHost().FromFlatDataWithDimensions([]float64{0, 0}, []int{2}).Done())
+		vBuf := must1(client.BufferFromHost().FromFlatDataWithDimensions([]float64{0, 0}, []int{2}).Done())
+		gradBuf := must1(client.BufferFromHost().FromFlatDataWithDimensions(grads, []int{2}).Done())
+		outputs := compileAndExecute(t, client, program, stepBuf, paramBuf, mBuf, vBuf, gradBuf)
+		wantM := []float64{(1 - beta1) * grads[0], (1 - beta1) * grads[1]}
+		wantV := []float64{(1 - beta2) * grads[0] * grads[0], (1 - beta2) * grads[1] * grads[1]}
+		wantParam := make([]float64, 2)
+		for i := range wantParam {
+			mHat := wantM[i] / (1 - beta1)
+			vHat := wantV[i] / (1 - beta2)
+			wantParam[i] = 1 - lr*mHat/(math.Sqrt(vHat)+epsilon)
+		}
+		requireBuffersEqual(t, []FlatAndDims{{wantParam, []int{2}}, {wantM, []int{2}}, {wantV, []int{2}}}, outputs)
+	})
+}
@@ -7,6 +7,7 @@ import (
 	"github.com/gomlx/gopjrt/dtypes"
 	"github.com/gomlx/gopjrt/pjrt"
 	"github.com/gomlx/stablehlo"
+	"github.com/gomlx/stablehlo/exec"
 	"github.com/gomlx/stablehlo/types/shapes"
 	"github.com/gomlx/stablehlo/types/shardy"
 )
@@ -114,4 +115,55 @@ func testShardy(t *testing.T, client *pjrt.Client) {
 		}, outputs)
 	})
 
+	t.Run("sharding-constraint", func(t *testing.T) {
+		mesh := must1(shardy.NewDeviceMesh("data_mesh", []int{2}, []string{"data"}))
+		builder := stablehlo.New(t.Name()).WithShardy(mesh)
+		fn := builder.Main()
+		// Replicated input, whose intermediary value is constrained to be sharded: the output sharding
+		// is propagated from the constraint.
+		x := must1(fn.NamedInput("arg0", shapes.Make(dtypes.F32, 2, 3)))
+		y := must1(stablehlo.Add(x, x))
+		y = must1(stablehlo.ShardingConstraint(y, builder.NewShardingSpec().AddShardedAxis("data")))
+		must(fn.Return(y))
+		program := must1(builder.Build())
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		inputs := make([]*pjrt.Buffer, numReplicas)
+		for i := range inputs {
+			inputs[i] = must1(client.BufferFromHost().
+				ToDeviceNum(deviceAssignment[i]).
+				FromFlatDataWithDimensions([]float32{0, 1, 2, 3, 4, 5}, []int{2, 3}).
+				Done())
+		}
+		outputs := shardyCompileAndExecute(t, client, program, deviceAssignment, inputs...)
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]float32{0, 2, 4}, []int{1, 3}},
+			{[]float32{6, 8, 10}, []int{1, 3}},
+		}, outputs)
+	})
+
+	t.Run("launch-config", func(t *testing.T) {
+		mesh := must1(shardy.NewDeviceMesh("data_mesh", []int{2}, []string{"data"}))
+		builder := stablehlo.New(t.Name()).WithShardy(mesh)
+		fn := builder.Main()
+		x := must1(fn.NamedInputWithSharding("x", shapes.Make(dtypes.F32, 2, 3),
+			builder.NewShardingSpec().AddShardedAxis("data")))
+		must(fn.Return(must1(stablehlo.Add(x, x))))
+		config := must1(exec.NewLaunchConfig(fn, deviceAssignment))
+		config = must1(exec.UnmarshalLaunchConfig(must1(config.Marshal())))
+		e := must1(exec.CompileLaunchConfig(client, config))
+		defer func() { must(e.Destroy()) }()
+		x0 := must1(client.BufferFromHost().
+			ToDeviceNum(deviceAssignment[0]).
+			FromFlatDataWithDimensions([]float32{0, 1, 2}, []int{1, 3}).
+			Done())
+		x1 := must1(client.BufferFromHost().
+			ToDeviceNum(deviceAssignment[1]).
+			FromFlatDataWithDimensions([]float32{3, 4, 5}, []int{1, 3}).
+			Done())
+		outputs := must1(e.Execute(x0, x1))
+		requireBuffersEqual(t, []FlatAndDims{
+			{[]float32{0, 2, 4}, []int{1, 3}},
+			{[]float32{6, 8, 10}, []int{1, 3}},
+		}, outputs)
+	})
 }
@@ -7,11 +7,11 @@ import (
 	"strings"
 )
 
-const _ComparisonTypeName = "CompareFloatCompareTotalOrderCompareSignedCompareUnsigned"
+const _ComparisonTypeName = "CompareFloatCompareTotalOrderCompareSignedCompareUnsignedCompareAuto"
 
-var _ComparisonTypeIndex = [...]uint8{0, 12, 29, 42, 57}
+var _ComparisonTypeIndex = [...]uint8{0, 12, 29, 42, 57, 68}
 
-const _ComparisonTypeLowerName = "comparefloatcomparetotalordercomparesignedcompareunsigned"
+const _ComparisonTypeLowerName = "comparefloatcomparetotalordercomparesignedcompareunsignedcompareauto"
 
 func (i ComparisonType) String() string {
 	if i < 0 || i >= ComparisonType(len(_ComparisonTypeIndex)-1) {
@@ -28,9 +28,10 @@ func _ComparisonTypeNoOp() {
 	_ = x[CompareTotalOrder-(1)]
 	_ = x[CompareSigned-(2)]
 	_ = x[CompareUnsigned-(3)]
+	_ = x[CompareAuto-(4)]
 }
 
-var _ComparisonTypeValues = []ComparisonType{CompareFloat, CompareTotalOrder, CompareSigned, CompareUnsigned}
+var _ComparisonTypeValues = []ComparisonType{CompareFloat, CompareTotalOrder, CompareSigned, CompareUnsigned, CompareAuto}
 
 var _ComparisonTypeNameToValueMap = map[string]ComparisonType{
 	_ComparisonTypeName[0:12]:       CompareFloat,
@@ -41,6 +42,8 @@ var _ComparisonTypeNameToValueMap = map[string]ComparisonType{
 	_ComparisonTypeLowerName[29:42]: CompareSigned,
 	_ComparisonTypeName[42:57]:      CompareUnsigned,
 	_ComparisonTypeLowerName[42:57]: CompareUnsigned,
+	_ComparisonTypeName[57:68]:      CompareAuto,
+	_ComparisonTypeLowerName[57:68]: CompareAuto,
 }
 
 var _ComparisonTypeNames = []string{
@@ -48,6 +51,7 @@ var _ComparisonTypeNames = []string{
 	_ComparisonTypeName[12:29],
 	_ComparisonTypeName[29:42],
 	_ComparisonTypeName[42:57],
+	_ComparisonTypeName[57:68],
 }
 
 // ComparisonTypeString retrieves an enum value from the enum constants string name.
@@ -7,6 +7,7 @@ import (
 
 	"github.com/gomlx/gopjrt/dtypes"
 	"github.com/gomlx/stablehlo/internal/utils"
+	"github.com/pkg/errors"
 )
 
 // ComparisonType enum defined for the Compare op.
@@ -23,6 +24,10 @@ const (
 
 	CompareSigned
 	CompareUnsigned
+
+	// CompareAuto is resolved to the comparison type matching the dtype of the operands (see
+	// ComparisonTypeForDType) by shapeinference.Compare and the Compare op. It is never rendered in the program.
+	CompareAuto
 )
 
 // ToStableHLO returns the StableHLO representation of the comparison type.
@@ -40,6 +45,21 @@ func (c ComparisonType) ToStableHLO() string {
 	return fmt.Sprintf("#stablehlo<comparison_type UNKNOWN %d>", c)
 }
 
+// ComparisonTypeForDType returns the ComparisonType to use for values of the given dtype: CompareFloat for
+// floating point (including the 8-bit floats) and complex dtypes, CompareSigned for signed integers, and
+// CompareUnsigned for unsigned integers and booleans.
+func ComparisonTypeForDType(dtype dtypes.DType) (ComparisonType, error) {
+	switch {
+	case utils.DTypeIsFloat(dtype) || dtype.IsComplex():
+		return CompareFloat, nil
+	case dtype.IsUnsigned() || dtype == dtypes.Bool:
+		return CompareUnsigned, nil
+	case dtype.IsInt():
+		return CompareSigned, nil
+	}
+	return CompareFloat, errors.Errorf("no comparison type for data type %s", dtype)
+}
+
 // ComparisonDirection enum defined for the Compare op.
 type ComparisonDirection int
 
@@ -76,7 +96,7 @@ func (c ComparisonDirection) ToStableHLO() string {
 // There must be the same number of spatial dimensions (axes) for each of the 3 tensors.
 // Input and output have batch and channel axes. Kernel has inputChannel and outputChannel axes.
 //
-// See Builder.ConvGeneral
+// See stablehlo.ConvolutionWith.
 type ConvolveAxesConfig struct {
 	InputBatch, InputChannels int
 	InputSpatial              []int
@@ -158,7 +178,162 @@ type DotGeneralAlgorithm struct {
 	AllowImpreciseAccumulation bool
 }
 
-// RNGBitGeneratorAlgorithm used by the RngBitGenerator operation.
+// DotGeneralAlgorithmPreset names one of the dot algorithms known by XLA (PrecisionConfig.Algorithm in
+// xla_data.proto), so they can be requested without setting each field of DotGeneralAlgorithm.
+// See DotGeneralAlgorithmPreset.Algorithm.
+//
+// The names follow XLA's convention: {LHS}_{RHS}_{ACCUMULATION}[_X{NUM_PRIMITIVE_OPERATIONS}], with the
+// precision types of the operands and of the accumulation. Which ones are supported depends on the backend.
+type DotGeneralAlgorithmPreset int
+
+//go:generate go tool enumer -type=DotGeneralAlgorithmPreset -trimprefix=DotAlgorithm -output=gen_dotgeneralalgorithmpreset_enumer.go ops.go
+
+// The values match XLA's PrecisionConfig.Algorithm enum.
+const (
+	// DotAlgorithmANY_F8_ANY_F8_F32 takes operands of any 8-bit float type, accumulating in float32.
+	DotAlgorithmANY_F8_ANY_F8_F32 DotGeneralAlgorithmPreset = iota + 1
+
+	// DotAlgorithmANY_F8_ANY_F8_F32_FAST_ACCUM is like DotAlgorithmANY_F8_ANY_F8_F32, but the intermediary
+	// results are not periodically promoted to a higher precision (CUBLASLT_MATMUL_DESC_FAST_ACCUM).
+	DotAlgorithmANY_F8_ANY_F8_F32_FAST_ACCUM
+
+	DotAlgorithmF16_F16_F16
+	DotAlgorithmF16_F16_F32
+	DotAlgorithmBF16_BF16_BF16
+	DotAlgorithmBF16_BF16_F32
+
+	// DotAlgorithmBF16_BF16_F32_X3 uses 3 BF16_BF16_F32 dot products to achieve better precision.
+	DotAlgorithmBF16_BF16_F32_X3
+
+	// DotAlgorithmBF16_BF16_F32_X6 uses 6 BF16_BF16_F32 dot products to achieve a precision similar to F32.
+	DotAlgorithmBF16_BF16_F32_X6
+
+	// DotAlgorithmTF32_TF32_F32 rounds the operands to TF32, a precision supported by modern NVidia GPUs.
+	DotAlgorithmTF32_TF32_F32
+
+	// DotAlgorithmTF32_TF32_F32_X3 uses 3 TF32_TF32_F32 dot products to achieve a precision similar to F32.
+	DotAlgorithmTF32_TF32_F32_X3
+
+	DotAlgorithmF32_F32_F32
+	DotAlgorithmF64_F64_F64
+
+	// DotAlgorithmBF16_BF16_F32_X9 uses 9 BF16_BF16_F32 dot products to achieve a precision similar to F32.
+	DotAlgorithmBF16_BF16_F32_X9
+)
+
+// IsAnyF8 returns whether the preset takes operands of any 8-bit float type.
+func (p DotGeneralAlgorithmPreset) IsAnyF8() bool {
+	return p == DotAlgorithmANY_F8_ANY_F8_F32 || p == DotAlgorithmANY_F8_ANY_F8_F32_FAST_ACCUM
+}
+
+// Algorithm returns a new DotGeneralAlgorithm configured for the preset, or nil if it is not a valid preset.
+//
+// For the ANY_F8 presets the precision types of the operands are set to F8E4M3FN: they must be changed to the 8-bit
+// float types of the operands if they are different (DotGeneralBuilder.AlgorithmPreset does it automatically).
+func (p DotGeneralAlgorithmPreset) Algorithm() *DotGeneralAlgorithm {
+	precision := func(dtype dtypes.DType) FloatPrecisionType { return FloatPrecisionType{DType: dtype} }
+	newAlgorithm := func(operands, accumulation FloatPrecisionType, numPrimitiveOperations int) *DotGeneralAlgorithm {
+		return &DotGeneralAlgorithm{
+			LhsPrecisionType:       operands,
+			RhsPrecisionType:       operands,
+			AccumulationType:       accumulation,
+			LhsComponentCount:      1,
+			RhsComponentCount:      1,
+			NumPrimitiveOperations: numPrimitiveOperations,
+		}
+	}
+	switch p {
+	case DotAlgorithmANY_F8_ANY_F8_F32, DotAlgorithmANY_F8_ANY_F8_F32_FAST_ACCUM:
+		algorithm := newAlgorithm(precision(dtypes.F8E4M3FN), precision(dtypes.F32), 1)
+		algorithm.AllowImpreciseAccumulation = p == DotAlgorithmANY_F8_ANY_F8_F32_FAST_ACCUM
+		return algorithm
+	case DotAlgorithmF16_F16_F16:
+		return newAlgorithm(precision(dtypes.F16), precision(dtypes.F16), 1)
+	case DotAlgorithmF16_F16_F32:
+		return newAlgorithm(precision(dtypes.F16), precision(dtypes.F32), 1)
+	case DotAlgorithmBF16_BF16_BF16:
+		return newAlgorithm(precision(dtypes.BFloat16), precision(dtypes.BFloat16), 1)
+	case DotAlgorithmBF16_BF16_F32:
+		return newAlgorithm(precision(dtypes.BFloat16), precision(dtypes.F32), 1)
+	case DotAlgorithmBF16_BF16_F32_X3:
+		return newAlgorithm(precision(dtypes.BFloat16), precision(dtypes.F32), 3)
+	case DotAlgorithmBF16_BF16_F32_X6:
+		return newAlgorithm(precision(dtypes.BFloat16), precision(dtypes.F32), 6)
+	case DotAlgorithmBF16_BF16_F32_X9:
+		return newAlgorithm(precision(dtypes.BFloat16), precision(dtypes.F32), 9)
+	case DotAlgorithmTF32_TF32_F32:
+		return newAlgorithm(FloatPrecisionType{TF32: true}, precision(dtypes.F32), 1)
+	case DotAlgorithmTF32_TF32_F32_X3:
+		return newAlgorithm(FloatPrecisionType{TF32: true}, precision(dtypes.F32), 3)
+	case DotAlgorithmF32_F32_F32:
+		return newAlgorithm(precision(dtypes.F32), precision(dtypes.F32), 1)
+	case DotAlgorithmF64_F64_F64:
+		return newAlgorithm(precision(dtypes.F64), precision(dtypes.F64), 1)
+	default:
+		return nil
+	}
+}
+
+// ResultAccuracyMode defines the accuracy mode requested for the result of some transcendental unary operations
+// (e.g.: Exponential, Log, Tanh), see ResultAccuracy.
+type ResultAccuracyMode int
+
+//go:generate go tool enumer -type=ResultAccuracyMode -trimprefix=ResultAccuracy -output=gen_resultaccuracymode_enumer.go ops.go
+
+const (
+	// ResultAccuracyDefault leaves the accuracy of the implementation to the backend.
+	ResultAccuracyDefault ResultAccuracyMode = iota
+
+	// ResultAccuracyHighest requests the most accurate implementation available.
+	ResultAccuracyHighest
+
+	// ResultAccuracyTolerance requests an implementation within the tolerances given by
+	// ResultAccuracy.Atol, ResultAccuracy.Rtol and ResultAccuracy.Ulps.
+	ResultAccuracyTolerance
+)
+
+// ToStableHLO returns the StableHLO representation of the result accuracy mode.
+func (m ResultAccuracyMode) ToStableHLO() string {
+	return fmt.Sprintf("#stablehlo.result_accuracy_mode<%s>", strings.ToUpper(m.String()))
+}
+
+// ResultAccuracy configures the accuracy of the result of transcendental unary operations, rendered as
+// their "result_accuracy" attribute.
+//
+// For the ResultAccuracyDefault and ResultAccuracyHighest modes, the tolerances must be left as 0.
+type ResultAccuracy struct {
+	Mode ResultAccuracyMode
+
+	// Atol and Rtol are the absolute and relative tolerances, used with ResultAccuracyTolerance.
+	Atol, Rtol float64
+
+	// Ulps is the tolerance in units in the last place, used with ResultAccuracyTolerance.
+	Ulps int
+}
+
+// Validate returns an error if the accuracy configuration is invalid.
+func (a ResultAccuracy) Validate() error {
+	if !a.Mode.IsAResultAccuracyMode() {
+		return errors.Errorf("invalid result accuracy mode %d", a.Mode)
+	}
+	if a.Atol < 0 || a.Rtol < 0 || a.Ulps < 0 {
+		return errors.Errorf("result accuracy tolerances must be non-negative, got atol=%g, rtol=%g, ulps=%d",
+			a.Atol, a.Rtol, a.Ulps)
+	}
+	if a.Mode != ResultAccuracyTolerance && (a.Atol != 0 || a.Rtol != 0 || a.Ulps != 0) {
+		return errors.Errorf("result accuracy tolerances can only be set with mode %s, got mode %s",
+			ResultAccuracyTolerance, a.Mode)
+	}
+	return nil
+}
+
+// ToStableHLO returns the StableHLO representation of the result accuracy attribute.
+func (a ResultAccuracy) ToStableHLO() string {
+	return fmt.Sprintf("#stablehlo.result_accuracy<atol = %e, rtol = %e, ulps = %d, mode = %s>",
+		a.Atol, a.Rtol, a.Ulps, a.Mode.ToStableHLO())
+}
+
+// RNGBitGeneratorAlgorithm used by the RNGBitGenerator operation.
 type RNGBitGeneratorAlgorithm int
 
 const (
@@ -169,6 +344,31 @@ const (
 
 //go:generate go tool enumer -type=RNGBitGeneratorAlgorithm -trimprefix=RNG -output=gen_rngbitgeneratoralgorithm_enumer.go -transform=snake ops.go
 
+// RNGDistribution defines the distribution of the legacy RNG op.
+type RNGDistribution int
+
+const (
+	// RNGUniform samples uniformly from the interval [a, b).
+	RNGUniform RNGDistribution = iota
+
+	// RNGNormal samples from the normal distribution with mean a and standard deviation b.
+	RNGNormal
+)
+
+//go:generate go tool enumer -type=RNGDistribution -trimprefix=RNG -output=gen_rngdistribution_enumer.go ops.go
+
+// ToStableHLO returns the StableHLO representation of the distribution.
+func (d RNGDistribution) ToStableHLO() string {
+	switch d {
+	case RNGUniform:
+		return "#stablehlo<rng_distribution UNIFORM>"
+	case RNGNormal:
+		return "#stablehlo<rng_distribution NORMAL>"
+	default:
+		return fmt.Sprintf("#stablehlo<rng_distribution UNKNOWN %d>", d)
+	}
+}
+
 // FFTType defines the type of the FFT operation, see FFT.
 type FFTType int
 
@@ -226,14 +426,15 @@ type CollectiveConfig struct {
 	// Defaults to CrossReplica (0).
 	ChannelType ChannelType
 
-	// ChannelID, if non-nil, forces a specific channel ID (the 'handle').
-	// If nil, a unique ID will be automatically generated.
+	// ChannelID, if non-nil, forces a specific channel ID (the 'handle'). It can't be negative, and 0 means
+	// no channel, which is not allowed with UseGlobalDeviceIDs.
+	// If nil, a unique positive ID will be automatically generated.
 	// This is **required** for MPMD (multi-program, multi-data) to manually link ops across programs.
 	ChannelID *int
 
 	// UseGlobalDeviceIDs changes the interpretation of replica_groups
 	// from replica IDs to global device IDs.
-	// This only applies to AllReduce, not CollectiveBroadcast.
+	// This only applies to AllReduce and AllGather: other collective operations return an error if it is set.
 	// Defaults to false.
 	UseGlobalDeviceIDs bool
 }
@@ -0,0 +1,181 @@
+package shapes
+
+import (
+	"encoding/json"
+	"strconv"
+	"strings"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/pkg/errors"
+)
+
+// invalidShapeText is the text of the zero (invalid) Shape, see Shape.ToHLOText.
+const invalidShapeText = "invalid"
+
+// ToHLOText returns the shape in the compact notation used by XLA's HLO: the dtype name (see DTypeToHLO) followed
+// by the comma-separated dimensions. Unbounded dynamic axes are rendered as "?", and bounded ones as "<=" followed
+// by the bound. Tuples are rendered as their comma-separated elements in parentheses, and the zero Shape (see
+// Invalid) as "invalid".
+//
+// Examples: "f32[2,3]", "pred[]", "s32[?,<=8]", "(f32[2], u8[])", "()".
+//
+// See Parse for the reverse.
+//
+// Shape doesn't implement encoding.TextMarshaler on purpose: encoding/gob would then use it instead of encoding
+// the fields, and it would no longer decode the shapes (or the structs holding them) encoded by previous versions.
+func (s Shape) ToHLOText() (string, error) {
+	var sb strings.Builder
+	if err := s.writeText(&sb); err != nil {
+		return "", err
+	}
+	return sb.String(), nil
+}
+
+// writeText implements ToHLOText.
+func (s Shape) writeText(sb *strings.Builder) error {
+	if s.DType == dtypes.InvalidDType && s.TupleShapes == nil {
+		sb.WriteString(invalidShapeText)
+		return nil
+	}
+	if s.IsTuple() {
+		sb.WriteString("(")
+		for i, subShape := range s.TupleShapes {
+			if i > 0 {
+				sb.WriteString(", ")
+			}
+			if err := subShape.writeText(sb); err != nil {
+				return err
+			}
+		}
+		sb.WriteString(")")
+		return nil
+	}
+	if _, found := DTypeFromHLO(DTypeToHLO(s.DType)); !found {
+		return errors.Errorf("shape %s has an unknown dtype, it can't be marshaled", s)
+	}
+	sb.WriteString(DTypeToHLO(s.DType))
+	sb.WriteString("[")
+	for axis, dim := range s.Dimensions {
+		if axis > 0 {
+			sb.WriteString(",")
+		}
+		switch {
+		case dim >= 0:
+			sb.WriteString(strconv.Itoa(dim))
+		case dim != DynamicDim:
+			return errors.Errorf("shape %s has an invalid dimension %d for axis %d, it can't be marshaled", s, dim, axis)
+		case s.Bound(axis) != DynamicDim:
+			sb.WriteString("<=")
+			sb.WriteString(strconv.Itoa(s.Bound(axis)))
+		default:
+			sb.WriteString("?")
+		}
+	}
+	sb.WriteString("]")
+	return nil
+}
+
+// Parse parses a shape in the compact HLO notation generated by Shape.ToHLOText, e.g. "f32[2,3]".
+func Parse(text string) (Shape, error) {
+	shape, rest, err := parseText(strings.TrimSpace(text))
+	if err == nil && rest != "" {
+		err = errors.Errorf("unexpected %q after the shape", rest)
+	}
+	if err != nil {
+		return Invalid(), errors.WithMessagef(err, "failed to parse shape %q", text)
+	}
+	return shape, nil
+}
+
+// parseText parses one shape at the start of text, and returns the remaining text, with the leading spaces trimmed.
+func parseText(text string) (Shape, string, error) {
+	if rest, found := strings.CutPrefix(text, invalidShapeText); found {
+		return Invalid(), strings.TrimSpace(rest), nil
+	}
+	if rest, found := strings.CutPrefix(text, "("); found {
+		// Tuple: elements is not nil, even if empty, to distinguish it from the invalid shape.
+		elements := []Shape{}
+		rest = strings.TrimSpace(rest)
+		for !strings.HasPrefix(rest, ")") {
+			if len(elements) > 0 {
+				var found bool
+				rest, found = strings.CutPrefix(rest, ",")
+				if !found {
+					return Invalid(), "", errors.Errorf("missing \",\" or \")\" in tuple at %q", rest)
+				}
+			}
+			element, after, err := parseText(strings.TrimSpace(rest))
+			if err != nil {
+				return Invalid(), "", err
+			}
+			elements = append(elements, element)
+			rest = after
+		}
+		return MakeTuple(elements), strings.TrimSpace(rest[1:]), nil
+	}
+
+	name, dimsAndRest, found := strings.Cut(text, "[")
+	if !found {
+		return Invalid(), "", errors.Errorf("missing \"[\" after the dtype in %q", text)
+	}
+	dtype, found := DTypeFromHLO(name)
+	if !found {
+		return Invalid(), "", errors.Errorf("unknown dtype %q", name)
+	}
+	dimsStr, rest, found := strings.Cut(dimsAndRest, "]")
+	if !found {
+		return Invalid(), "", errors.Errorf("missing \"]\" after the dimensions in %q", text)
+	}
+	if dimsStr == "" {
+		return Make(dtype), strings.TrimSpace(rest), nil
+	}
+	fields := strings.Split(dimsStr, ",")
+	dimensions := make([]int, len(fields))
+	bounds := make([]int, len(fields))
+	for axis, field := range fields {
+		field = strings.TrimSpace(field)
+		bounds[axis] = DynamicDim
+		if field == "?" {
+			dimensions[axis] = DynamicDim
+			continue
+		}
+		boundStr, isBounded := strings.CutPrefix(field, "<=")
+		value, err := strconv.Atoi(boundStr)
+		if err != nil || value < 0 {
+			return Invalid(), "", errors.Errorf("invalid dimension %q for axis %d", field, axis)
+		}
+		if isBounded {
+			dimensions[axis], bounds[axis] = DynamicDim, value
+		} else {
+			dimensions[axis] = value
+		}
+	}
+	return Make(dtype, dimensions...).WithBounds(bounds...), strings.TrimSpace(rest), nil
+}
+
+// MarshalJSON implements json.Marshaler: the shape is encoded as a JSON string with the notation of
+// Shape.ToHLOText, e.g. "f32[2,3]".
+func (s Shape) MarshalJSON() ([]byte, error) {
+	text, err := s.ToHLOText()
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(text)
+}
+
+// UnmarshalJSON implements json.Unmarshaler, the reverse of Shape.MarshalJSON. A JSON null leaves the shape unchanged.
+func (s *Shape) UnmarshalJSON(data []byte) error {
+	if string(data) == "null" {
+		return nil
+	}
+	var text string
+	if err := json.Unmarshal(data, &text); err != nil {
+		return errors.Wrapf(err, "failed to unmarshal shape from JSON, it must be a string like \"f32[2,3]\"")
+	}
+	shape, err := Parse(text)
+	if err != nil {
+		return err
+	}
+	*s = shape
+	return nil
+}
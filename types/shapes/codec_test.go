@@ -0,0 +1,165 @@
+package shapes
+
+import (
+	"bytes"
+	"encoding/json"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+)
+
+// codecTestShapes are the shapes used to test the round trip of the codecs.
+var codecTestShapes = []Shape{
+	Make(dtypes.Float32, 2, 3),
+	Make(dtypes.Int32),
+	Make(dtypes.Bool, 0, 3),
+	Make(dtypes.Complex128, 2),
+	Make(dtypes.BFloat16, 7),
+	Make(dtypes.F8E4M3FN, 1),
+	Make(dtypes.Uint8, DynamicDim, 4),
+	Make(dtypes.Float32, DynamicDim, 3, DynamicDim).WithBounds(8, DynamicDim, DynamicDim),
+	MakeTuple([]Shape{Make(dtypes.Float64, 2), MakeTuple([]Shape{Make(dtypes.Int8)}), Make(dtypes.S4, 16)}),
+}
+
+func TestShapeText(t *testing.T) {
+	for _, tc := range []struct {
+		shape Shape
+		want  string
+	}{
+		{Make(dtypes.Float32, 2, 3), "f32[2,3]"},
+		{Make(dtypes.Bool), "pred[]"},
+		{Make(dtypes.BFloat16, 4), "bf16[4]"},
+		{Make(dtypes.Complex64, 1), "c64[1]"},
+		{Make(dtypes.Int32, DynamicDim, DynamicDim).WithBounds(DynamicDim, 8), "s32[?,<=8]"},
+		{MakeTuple([]Shape{Make(dtypes.Float32, 2), Make(dtypes.Uint8)}), "(f32[2], u8[])"},
+	} {
+		got, err := tc.shape.ToHLOText()
+		if err != nil {
+			t.Fatalf("ToHLOText(%s) failed: %+v", tc.shape, err)
+		}
+		if got != tc.want {
+			t.Errorf("ToHLOText(%s) = %q, want %q", tc.shape, got, tc.want)
+		}
+	}
+
+	for _, shape := range codecTestShapes {
+		text, err := shape.ToHLOText()
+		if err != nil {
+			t.Fatalf("ToHLOText(%s) failed: %+v", shape, err)
+		}
+		parsed, err := Parse(text)
+		if err != nil {
+			t.Fatalf("Parse(%q) failed: %+v", text, err)
+		}
+		if !parsed.Equal(shape) {
+			t.Errorf("Parse(%q) = %s, want %s", text, parsed, shape)
+		}
+	}
+
+	// The zero shape and the empty tuple are distinguished.
+	for _, tc := range []struct {
+		shape Shape
+		want  string
+	}{{Shape{}, "invalid"}, {MakeTuple([]Shape{}), "()"}} {
+		text, err := tc.shape.ToHLOText()
+		if err != nil || text != tc.want {
+			t.Errorf("ToHLOText(%#v) = %q, %v, want %q", tc.shape, text, err, tc.want)
+		}
+		parsed, err := Parse(text)
+		if err != nil || (parsed.TupleShapes == nil) != (tc.shape.TupleShapes == nil) || parsed.DType != tc.shape.DType {
+			t.Errorf("Parse(%q) = %#v, %v, want %#v", text, parsed, err, tc.shape)
+		}
+	}
+
+	// Spaces are accepted.
+	if got, err := Parse(" ( f32[ 2 , <=3 ] , s64[] ) "); err != nil ||
+		!got.Equal(MakeTuple([]Shape{Make(dtypes.Float32, 2, DynamicDim).WithBounds(DynamicDim, 3), Make(dtypes.Int64)})) {
+		t.Errorf("Parse with spaces = %s, %v", got, err)
+	}
+
+	for _, text := range []string{"", "f32", "f32[2", "float32[2]", "f32[-2]", "f32[<=?]", "f32[2]x", "(f32[2]", "(f32[2] s32[])"} {
+		if _, err := Parse(text); err == nil {
+			t.Errorf("Parse(%q) should have failed", text)
+		}
+	}
+	if _, err := Make(dtypes.DType(1000), 2).ToHLOText(); err == nil {
+		t.Errorf("ToHLOText of an unknown dtype should have failed")
+	}
+}
+
+func TestShapeJSON(t *testing.T) {
+	type sidecar struct {
+		Input  Shape   `json:"input"`
+		Output []Shape `json:"outputs"`
+	}
+	metadata := sidecar{Input: Make(dtypes.Float32, DynamicDim, 3), Output: codecTestShapes}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		t.Fatalf("json.Marshal failed: %+v", err)
+	}
+	if !strings.HasPrefix(string(data), `{"input":"f32[?,3]","outputs":["f32[2,3]","s32[]",`) {
+		t.Errorf("unexpected JSON: %s", data)
+	}
+	var restored sidecar
+	if err := json.Unmarshal(data, &restored); err != nil {
+		t.Fatalf("json.Unmarshal failed: %+v", err)
+	}
+	if !restored.Input.Equal(metadata.Input) || len(restored.Output) != len(metadata.Output) {
+		t.Fatalf("json round trip: got %+v, want %+v", restored, metadata)
+	}
+	for i, shape := range restored.Output {
+		if !shape.Equal(metadata.Output[i]) {
+			t.Errorf("json round trip of output #%d: got %s, want %s", i, shape, metadata.Output[i])
+		}
+	}
+
+	for _, data := range []string{`{"input": 3}`, `{"input": "f32[x]"}`} {
+		if err := json.Unmarshal([]byte(data), &restored); err == nil {
+			t.Errorf("json.Unmarshal(%s) should have failed", data)
+		}
+	}
+}
+
+func TestXLAShapeProto(t *testing.T) {
+	// Hand-encoded ShapeProto{element_type: F32, dimensions: [2, 3]}.
+	got, err := Make(dtypes.Float32, 2, 3).ToXLAShapeProto()
+	if err != nil {
+		t.Fatalf("ToXLAShapeProto failed: %+v", err)
+	}
+	if want := []byte{0x10, 11, 0x1a, 2, 2, 3}; !bytes.Equal(got, want) {
+		t.Errorf("ToXLAShapeProto() = %v, want %v", got, want)
+	}
+
+	for _, shape := range codecTestShapes {
+		data, err := shape.ToXLAShapeProto()
+		if err != nil {
+			t.Fatalf("ToXLAShapeProto(%s) failed: %+v", shape, err)
+		}
+		parsed, err := FromXLAShapeProto(data)
+		if err != nil {
+			t.Fatalf("FromXLAShapeProto(%v) for %s failed: %+v", data, shape, err)
+		}
+		if !parsed.Equal(shape) {
+			t.Errorf("FromXLAShapeProto(%v) = %s, want %s", data, parsed, shape)
+		}
+	}
+
+	// Unpacked dimensions and a layout (field 5) are accepted: ShapeProto{element_type: PRED, dimensions: [4],
+	// layout: {minor_to_major: [0]}}.
+	parsed, err := FromXLAShapeProto([]byte{0x10, 1, 0x18, 4, 0x2a, 3, 0x0a, 1, 0})
+	if err != nil || !parsed.Equal(Make(dtypes.Bool, 4)) {
+		t.Errorf("FromXLAShapeProto with layout = %s, %v", parsed, err)
+	}
+
+	for _, data := range [][]byte{
+		{0x10},                                // Truncated.
+		{0x10, 100},                           // Unknown element type.
+		{0x10, 11, 0x1a, 2, 2},                // Truncated dimensions.
+		{0x10, 11, 0x1a, 1, 2, 0x32, 2, 1, 1}, // Rank doesn't match is_dynamic_dimension.
+	} {
+		if shape, err := FromXLAShapeProto(data); err == nil {
+			t.Errorf("FromXLAShapeProto(%v) should have failed, got %s", data, shape)
+		}
+	}
+}
@@ -1 +1,46 @@
 package shapes
+
+import (
+	"strings"
+	"sync"
+
+	"github.com/gomlx/gopjrt/dtypes"
+)
+
+// DTypeToHLO returns the name used by XLA's HLO for the dtype, e.g. "f32", "pred", "bf16" or "c64".
+// It's the XLA PrimitiveType name in lower case.
+func DTypeToHLO(dtype dtypes.DType) string {
+	if dtype == dtypes.InvalidDType {
+		return "invalid"
+	}
+	return strings.ToLower(dtype.PrimitiveType().String())
+}
+
+// DTypeFromHLO returns the dtype for the given HLO name (e.g. "f32", "pred"). It's the reverse of DTypeToHLO,
+// and it returns false if the name is not known.
+func DTypeFromHLO(name string) (dtypes.DType, bool) {
+	dtype, found := hloDTypes()[name]
+	return dtype, found
+}
+
+// hloDTypes maps the HLO names to the dtypes, and it's built on the first use.
+var hloDTypes = sync.OnceValue(func() map[string]dtypes.DType {
+	names := make(map[string]dtypes.DType)
+	for _, dtype := range dtypes.DTypeValues() {
+		if dtype != dtypes.InvalidDType {
+			names[DTypeToHLO(dtype)] = dtype
+		}
+	}
+	return names
+})
+
+// xlaPrimitiveTypes maps the XLA PrimitiveType enum values to the dtypes, and it's built on the first use.
+var xlaPrimitiveTypes = sync.OnceValue(func() map[int32]dtypes.DType {
+	types := make(map[int32]dtypes.DType)
+	for _, dtype := range dtypes.DTypeValues() {
+		if dtype != dtypes.InvalidDType {
+			types[int32(dtype.PrimitiveType())] = dtype
+		}
+	}
+	return types
+})
@@ -70,6 +70,7 @@ package shapes
 import (
 	"encoding/gob"
 	"fmt"
+	"math"
 	"slices"
 	"strings"
 
@@ -85,20 +86,98 @@ type Shape struct {
 	DType       dtypes.DType
 	Dimensions  []int
 	TupleShapes []Shape // Shapes of the tuple, if this is a tuple.
+
+	// Bounds holds the upper-bound for each dynamic axis (bounded dynamism), or DynamicDim for static or
+	// unbounded axes. It is either nil (no bounds) or has one value per axis.
+	//
+	// See Shape.WithBounds.
+	Bounds []int
 }
 
+// DynamicDim is used as the dimension of an axis whose size is only known at runtime.
+// It is rendered as "?" in StableHLO.
+//
+// It's also used in Shape.Bounds to indicate that an axis has no upper-bound.
+const DynamicDim = -1
+
 // Make returns a Shape structure filled with the values given.
 // See MakeTuple for tuple shapes.
+//
+// Dimensions can be set to DynamicDim for axes whose size is only known at runtime.
+// Use Shape.WithBounds to set an upper-bound to those axes.
 func Make(dtype dtypes.DType, dimensions ...int) Shape {
 	s := Shape{Dimensions: slices.Clone(dimensions), DType: dtype}
 	for _, dim := range dimensions {
-		if dim < 0 {
+		if dim < 0 && dim != DynamicDim {
 			panic(errors.Errorf("shapes.Make(%s): cannot create a shape with an axis with dimension < 0", s))
 		}
 	}
 	return s
 }
 
+// WithBounds returns a copy of the shape with the upper-bounds of its dynamic axes set.
+// There must be one bound per axis: static axes (and dynamic axes without a bound) must use DynamicDim.
+//
+// Example: shapes.Make(dtypes.Float32, shapes.DynamicDim, 3).WithBounds(8, shapes.DynamicDim) is rendered
+// as "tensor<?x3xf32, #stablehlo.bounds<8, ?>>".
+//
+// It panics if the number of bounds doesn't match the rank, or if a bound is given to a static axis.
+func (s Shape) WithBounds(bounds ...int) Shape {
+	if len(bounds) != s.Rank() {
+		panic(errors.Errorf("shapes.WithBounds(%v): shape %s has rank %d, but %d bounds were given",
+			bounds, s, s.Rank(), len(bounds)))
+	}
+	s2 := s.Clone()
+	s2.Bounds = slices.Clone(bounds)
+	for axis, bound := range bounds {
+		if bound == DynamicDim {
+			continue
+		}
+		if bound < 0 {
+			panic(errors.Errorf("shapes.WithBounds(%v): invalid bound %d for axis %d", bounds, bound, axis))
+		}
+		if s.Dimensions[axis] != DynamicDim {
+			panic(errors.Errorf("shapes.WithBounds(%v): axis %d of shape %s is static, it can't have a bound",
+				bounds, axis, s))
+		}
+	}
+	if !s2.HasBounds() {
+		s2.Bounds = nil
+	}
+	return s2
+}
+
+// IsDynamic returns whether any of the axes has a dynamic dimension (DynamicDim).
+func (s Shape) IsDynamic() bool {
+	return slices.Contains(s.Dimensions, DynamicDim)
+}
+
+// HasBounds returns whether any of the dynamic axes has an upper-bound set.
+func (s Shape) HasBounds() bool {
+	for _, bound := range s.Bounds {
+		if bound != DynamicDim {
+			return true
+		}
+	}
+	return false
+}
+
+// Bound returns the upper-bound of the given axis: for static axes it is the dimension itself, for dynamic axes it
+// is the bound set with WithBounds, or DynamicDim if there is no bound.
+//
+// Like Dim, axis can take negative values, counting from the end.
+func (s Shape) Bound(axis int) int {
+	adjustedAxis := axis
+	if adjustedAxis < 0 {
+		adjustedAxis += s.Rank()
+	}
+	dim := s.Dim(axis)
+	if dim != DynamicDim || len(s.Bounds) == 0 {
+		return dim
+	}
+	return s.Bounds[adjustedAxis]
+}
+
 // Scalar returns a scalar Shape for the given type.
 func Scalar[T dtypes.Number]() Shape {
 	return Shape{DType: dtypes.FromGenericsType[T]()}
@@ -149,20 +228,91 @@ func (s Shape) String() string {
 	if s.Rank() == 0 {
 		return fmt.Sprintf("(%s)", s.DType)
 	}
-	return fmt.Sprintf("(%s)%v", s.DType, s.Dimensions)
+	if !s.IsDynamic() {
+		return fmt.Sprintf("(%s)%v", s.DType, s.Dimensions)
+	}
+	parts := make([]string, 0, s.Rank())
+	for axis, dim := range s.Dimensions {
+		switch {
+		case dim != DynamicDim:
+			parts = append(parts, fmt.Sprintf("%d", dim))
+		case s.Bound(axis) != DynamicDim:
+			parts = append(parts, fmt.Sprintf("?<=%d", s.Bound(axis)))
+		default:
+			parts = append(parts, "?")
+		}
+	}
+	return fmt.Sprintf("(%s)[%s]", s.DType, strings.Join(parts, " "))
 }
 
 // Size returns the number of elements (not bytes) for this shape. It's the product of all dimensions.
 //
+// For dynamic shapes, the upper-bound of the dynamic axes is used, so it's the maximum size.
+// If any of the dynamic axes is unbounded, it returns DynamicDim.
+//
 // For the number of bytes used to store this shape, see Shape.Memory.
+// It overflows for shapes too large for an int, see Shape.CheckSize.
 func (s Shape) Size() (size int) {
 	size = 1
-	for _, d := range s.Dimensions {
+	for axis := range s.Dimensions {
+		d := s.Bound(axis)
+		if d == DynamicDim {
+			return DynamicDim
+		}
 		size *= d
 	}
 	return
 }
 
+// CheckSize returns an error if the number of elements of the shape, or its size in bytes, doesn't fit an int on
+// the current platform -- e.g. shapes with more than 2^31-1 elements on 32-bit platforms. For such shapes Size,
+// Memory and Strides overflow, so they should be rejected before being used.
+//
+// For dynamic shapes the upper-bounds are used, and unbounded axes are ignored. For tuples, each element is checked.
+func (s Shape) CheckSize() error {
+	if s.IsTuple() {
+		for i, element := range s.TupleShapes {
+			if err := element.CheckSize(); err != nil {
+				return errors.WithMessagef(err, "tuple element #%d", i)
+			}
+		}
+		return nil
+	}
+	if s.IsZeroSize() {
+		return nil
+	}
+	size := 1
+	for axis := range s.Dimensions {
+		dim := s.Bound(axis)
+		if dim == DynamicDim {
+			continue
+		}
+		if size > math.MaxInt/dim {
+			return errors.Errorf("shape %s is too large: its number of elements overflows int (max %d)", s, math.MaxInt)
+		}
+		size *= dim
+	}
+	if elementSize := elementMemory(s.DType); elementSize > 0 && size > math.MaxInt/elementSize {
+		return errors.Errorf("shape %s is too large: its size in bytes overflows int (max %d)", s, math.MaxInt)
+	}
+	return nil
+}
+
+// elementMemory returns the number of bytes used by each element of the dtype, including the f8 dtypes and the
+// sub-byte dtypes (counted as one byte, an upper bound), which have no Go type.
+func elementMemory(dtype dtypes.DType) int {
+	switch dtype {
+	case dtypes.F8E5M2, dtypes.F8E4M3FN, dtypes.F8E4M3B11FNUZ, dtypes.F8E5M2FNUZ, dtypes.F8E4M3FNUZ,
+		dtypes.F8E4M3, dtypes.F8E3M4, dtypes.F8E8M0FNU,
+		dtypes.S4, dtypes.U4, dtypes.S2, dtypes.U2, dtypes.F4E2M1FN:
+		return 1
+	case dtypes.InvalidDType, dtypes.TOKEN:
+		return 0
+	default:
+		return int(dtype.Memory())
+	}
+}
+
 // IsZeroSize returns whether any of the dimensions is zero, in which case
 // it's an empty shape, with no data attached to it.
 //
@@ -179,8 +329,14 @@ func (s Shape) IsZeroSize() bool {
 
 // Memory returns the memory used to store an array of the given shape, the same as the size in bytes.
 // Careful, so far all types in Go and on device seem to use the same sizes, but future type this is not guaranteed.
+//
+// For dynamic shapes it returns the memory of the upper-bound, or 0 if any dynamic axis is unbounded.
 func (s Shape) Memory() uintptr {
-	return s.DType.Memory() * uintptr(s.Size())
+	size := s.Size()
+	if size == DynamicDim {
+		return 0
+	}
+	return s.DType.Memory() * uintptr(size)
 }
 
 // MakeTuple returns a shape representing a tuple of elements with the given shapes.
@@ -220,8 +376,9 @@ func (s Shape) Equal(s2 Shape) bool {
 	if s.IsScalar() {
 		return true
 	}
-	// For normal shapes just compare dimensions.
-	return slices.Equal(s.Dimensions, s2.Dimensions)
+	// For normal shapes just compare dimensions (and bounds).
+	return slices.Equal(s.Dimensions, s2.Dimensions) && (s.HasBounds() == s2.HasBounds()) &&
+		(!s.HasBounds() || slices.Equal(s.Bounds, s2.Bounds))
 }
 
 // EqualDimensions compares two shapes for equality of dimensions. Dtypes can be different.
@@ -254,6 +411,7 @@ func (s Shape) EqualDimensions(s2 Shape) bool {
 func (s Shape) Clone() (s2 Shape) {
 	s2.DType = s.DType
 	s2.Dimensions = slices.Clone(s.Dimensions)
+	s2.Bounds = slices.Clone(s.Bounds)
 	if s.TupleSize() > 0 {
 		s2.TupleShapes = make([]Shape, 0, len(s.TupleShapes))
 		for _, subShape := range s.TupleShapes {
@@ -264,6 +422,10 @@ func (s Shape) Clone() (s2 Shape) {
 }
 
 // GobSerialize shape in binary format.
+//
+// Shapes without bounds (see Shape.WithBounds) are serialized as in the original format (dtype, dimensions and
+// number of tuple elements), so they can be read by older versions. For shapes with bounds, the number of tuple
+// elements n is encoded as -(n+1), followed by the bounds.
 func (s Shape) GobSerialize(encoder *gob.Encoder) (err error) {
 	enc := func(e any) {
 		if err != nil {
@@ -276,7 +438,12 @@ func (s Shape) GobSerialize(encoder *gob.Encoder) (err error) {
 	}
 	enc(s.DType)
 	enc(s.Dimensions)
-	enc(len(s.TupleShapes))
+	if len(s.Bounds) > 0 {
+		enc(-len(s.TupleShapes) - 1)
+		enc(s.Bounds)
+	} else {
+		enc(len(s.TupleShapes))
+	}
 	if err != nil {
 		return
 	}
@@ -290,6 +457,8 @@ func (s Shape) GobSerialize(encoder *gob.Encoder) (err error) {
 }
 
 // GobDeserialize a Shape. Returns new Shape or an error.
+//
+// It reads the format written by Shape.GobSerialize, including the one of the versions before bounds were added.
 func GobDeserialize(decoder *gob.Decoder) (s Shape, err error) {
 	dec := func(data any) {
 		if err != nil {
@@ -304,6 +473,11 @@ func GobDeserialize(decoder *gob.Decoder) (s Shape, err error) {
 	dec(&s.Dimensions)
 	var numTuples int
 	dec(&numTuples)
+	if numTuples < 0 {
+		// Shape with bounds.
+		numTuples = -numTuples - 1
+		dec(&s.Bounds)
+	}
 	if err != nil {
 		return
 	}
@@ -341,3 +515,132 @@ func ConcatenateDimensions(s1, s2 Shape) (shape Shape) {
 	copy(shape.Dimensions[s1.Rank():], s2.Dimensions)
 	return
 }
+
+// Strides returns the row-major (the last axis is the fastest varying) strides of the shape, in number of elements:
+// the distance in a flat buffer between consecutive elements along each axis.
+//
+// For dynamic shapes, the upper-bound of the dynamic axes is used, like in Shape.Size. It returns nil if any of the
+// dynamic axes is unbounded, or for tuples. Scalars have no strides, so it returns an empty slice.
+func (s Shape) Strides() []int {
+	if s.IsTuple() {
+		return nil
+	}
+	strides := make([]int, s.Rank())
+	stride := 1
+	for axis := s.Rank() - 1; axis >= 0; axis-- {
+		dim := s.Bound(axis)
+		if dim == DynamicDim {
+			return nil
+		}
+		strides[axis] = stride
+		stride *= dim
+	}
+	return strides
+}
+
+// FlatIndex returns the position in a row-major flat buffer of the element at the given indices, one per axis.
+//
+// It returns an error if the number of indices doesn't match the rank, or if any index is out of bounds.
+// See Shape.UnflattenIndex for the reverse operation.
+func (s Shape) FlatIndex(indices ...int) (int, error) {
+	if len(indices) != s.Rank() {
+		return 0, errors.Errorf("Shape.FlatIndex(%v) requires one index per axis, but shape %s has rank %d",
+			indices, s, s.Rank())
+	}
+	strides := s.Strides()
+	if strides == nil && s.Rank() > 0 {
+		return 0, errors.Errorf("Shape.FlatIndex() requires a static or bounded shape, got %s", s)
+	}
+	flat := 0
+	for axis, index := range indices {
+		if index < 0 || index >= s.Bound(axis) {
+			return 0, errors.Errorf("Shape.FlatIndex(%v): index %d out-of-bounds for axis %d of shape %s",
+				indices, index, axis, s)
+		}
+		flat += index * strides[axis]
+	}
+	return flat, nil
+}
+
+// UnflattenIndex returns the indices, one per axis, of the element at the given position of a row-major flat buffer.
+// It's the reverse of Shape.FlatIndex.
+//
+// It returns an error if flatIndex is out of bounds.
+func (s Shape) UnflattenIndex(flatIndex int) ([]int, error) {
+	size := s.Size()
+	if s.IsTuple() || size == DynamicDim {
+		return nil, errors.Errorf("Shape.UnflattenIndex() requires a static or bounded shape, got %s", s)
+	}
+	if flatIndex < 0 || flatIndex >= size {
+		return nil, errors.Errorf("Shape.UnflattenIndex(%d) out-of-bounds for shape %s of size %d",
+			flatIndex, s, size)
+	}
+	indices := make([]int, s.Rank())
+	for axis := s.Rank() - 1; axis >= 0; axis-- {
+		dim := s.Bound(axis)
+		indices[axis] = flatIndex % dim
+		flatIndex /= dim
+	}
+	return indices, nil
+}
+
+// BroadcastShapes returns the shape resulting from broadcasting the shapes a and b together, using the NumPy rules:
+// the shape with the lower rank is prefixed with axes of dimension 1, and then for each axis the dimensions must
+// either be equal, or one of them must be 1, in which case it is broadcast to the other.
+//
+// The shapes must have the same dtype, and dynamic axes are only compatible with an equal (dynamic) axis or with
+// a dimension 1. It returns an error if the shapes are not compatible.
+//
+// Notice StableHLO operations don't broadcast implicitly: this is a helper to compute the target shape of
+// explicit BroadcastInDim operations.
+func BroadcastShapes(a, b Shape) (Shape, error) {
+	if a.IsTuple() || b.IsTuple() {
+		return Invalid(), errors.Errorf("BroadcastShapes(%s, %s) doesn't work with tuples", a, b)
+	}
+	if a.DType != b.DType {
+		return Invalid(), errors.Errorf("BroadcastShapes(%s, %s) requires shapes with the same dtype", a, b)
+	}
+	rank := max(a.Rank(), b.Rank())
+	output := Shape{DType: a.DType, Dimensions: make([]int, rank)}
+	var bounds []int
+	if a.HasBounds() || b.HasBounds() {
+		bounds = make([]int, rank)
+	}
+	for axis := range rank {
+		axisA, axisB := axis-(rank-a.Rank()), axis-(rank-b.Rank())
+		dimA, boundA := 1, DynamicDim
+		if axisA >= 0 {
+			dimA, boundA = a.Dimensions[axisA], a.Bound(axisA)
+		}
+		dimB, boundB := 1, DynamicDim
+		if axisB >= 0 {
+			dimB, boundB = b.Dimensions[axisB], b.Bound(axisB)
+		}
+		switch {
+		case dimA == dimB:
+			output.Dimensions[axis] = dimA
+			if boundA == DynamicDim || (boundB != DynamicDim && boundB < boundA) {
+				// Both dynamic axes must have the same size at runtime, so the tighter bound applies.
+				boundA = boundB
+			}
+		case dimB == 1:
+			output.Dimensions[axis] = dimA
+		case dimA == 1:
+			output.Dimensions[axis], boundA = dimB, boundB
+		default:
+			return Invalid(), errors.Errorf("BroadcastShapes(%s, %s): dimensions %d and %d of axis %d are not compatible",
+				a, b, dimA, dimB, axis)
+		}
+		if bounds != nil {
+			if output.Dimensions[axis] == DynamicDim {
+				bounds[axis] = boundA
+			} else {
+				bounds[axis] = DynamicDim
+			}
+		}
+	}
+	if bounds != nil && slices.ContainsFunc(bounds, func(bound int) bool { return bound != DynamicDim }) {
+		output.Bounds = bounds
+	}
+	return output, nil
+}
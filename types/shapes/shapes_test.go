@@ -17,7 +17,11 @@
 package shapes
 
 import (
+	"bytes"
+	"encoding/gob"
+	"math"
 	"reflect"
+	"slices"
 	"testing"
 
 	"github.com/gomlx/gopjrt/dtypes"
@@ -165,3 +169,206 @@ func TestFromAnyValue(t *testing.T) {
 		t.Errorf("irregular shape should have returned an error, instead got shape %s", shape)
 	}
 }
+
+func TestStridesAndFlatIndex(t *testing.T) {
+	s := Make(dtypes.Float32, 2, 3, 4)
+	if got, want := s.Strides(), []int{12, 4, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Strides() = %v, want %v", got, want)
+	}
+	if got := Make(dtypes.Float32).Strides(); len(got) != 0 {
+		t.Errorf("Strides() of scalar = %v, want empty", got)
+	}
+	if got := Make(dtypes.Float32, DynamicDim, 3).Strides(); got != nil {
+		t.Errorf("Strides() of unbounded dynamic shape = %v, want nil", got)
+	}
+	if got, want := Make(dtypes.Float32, DynamicDim, 3).WithBounds(5, DynamicDim).Strides(), []int{3, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("Strides() of bounded dynamic shape = %v, want %v", got, want)
+	}
+
+	for flat := range s.Size() {
+		indices, err := s.UnflattenIndex(flat)
+		if err != nil {
+			t.Fatalf("UnflattenIndex(%d) failed: %+v", flat, err)
+		}
+		got, err := s.FlatIndex(indices...)
+		if err != nil {
+			t.Fatalf("FlatIndex(%v) failed: %+v", indices, err)
+		}
+		if got != flat {
+			t.Errorf("FlatIndex(UnflattenIndex(%d)) = %d", flat, got)
+		}
+	}
+	if got, err := s.FlatIndex(1, 2, 3); err != nil || got != 23 {
+		t.Errorf("FlatIndex(1, 2, 3) = %d, %v, want 23", got, err)
+	}
+	if got, err := Make(dtypes.Int32).FlatIndex(); err != nil || got != 0 {
+		t.Errorf("FlatIndex() of scalar = %d, %v, want 0", got, err)
+	}
+	if _, err := s.FlatIndex(1, 2); err == nil {
+		t.Errorf("FlatIndex(1, 2) should fail for rank 3")
+	}
+	if _, err := s.FlatIndex(1, 3, 0); err == nil {
+		t.Errorf("FlatIndex(1, 3, 0) should fail, index out-of-bounds")
+	}
+	if _, err := s.UnflattenIndex(24); err == nil {
+		t.Errorf("UnflattenIndex(24) should fail, index out-of-bounds")
+	}
+}
+
+func TestCheckSize(t *testing.T) {
+	huge := math.MaxInt/2 + 1
+	for _, shape := range []Shape{
+		Make(dtypes.Float32, 2, 3),
+		Make(dtypes.Uint8, huge),
+		Make(dtypes.Uint8, huge, 0, huge),
+		Make(dtypes.Uint8, DynamicDim, huge).WithBounds(DynamicDim, DynamicDim),
+		Make(dtypes.F8E5M2, huge),
+		Make(dtypes.S4, huge),
+		Make(dtypes.U2, 3),
+	} {
+		if err := shape.CheckSize(); err != nil {
+			t.Errorf("CheckSize(%s) failed: %v", shape, err)
+		}
+	}
+	for _, shape := range []Shape{
+		Make(dtypes.Uint8, huge, 2),
+		Make(dtypes.Int32, huge/2),
+		Make(dtypes.Uint8, DynamicDim, 2).WithBounds(huge, DynamicDim),
+		MakeTuple([]Shape{Make(dtypes.Float32), Make(dtypes.Float64, huge)}),
+	} {
+		if err := shape.CheckSize(); err == nil {
+			t.Errorf("CheckSize(%s) should have failed", shape)
+		}
+	}
+}
+
+func TestBroadcastShapes(t *testing.T) {
+	testCases := []struct {
+		a, b, want Shape
+	}{
+		{Make(dtypes.Float32, 2, 3), Make(dtypes.Float32, 2, 3), Make(dtypes.Float32, 2, 3)},
+		{Make(dtypes.Float32, 2, 1), Make(dtypes.Float32, 1, 3), Make(dtypes.Float32, 2, 3)},
+		{Make(dtypes.Float32, 4, 2, 3), Make(dtypes.Float32, 3), Make(dtypes.Float32, 4, 2, 3)},
+		{Make(dtypes.Float32), Make(dtypes.Float32, 5, 1), Make(dtypes.Float32, 5, 1)},
+		{Make(dtypes.Float32, 0, 1), Make(dtypes.Float32, 1, 7), Make(dtypes.Float32, 0, 7)},
+		{Make(dtypes.Float32, DynamicDim, 1), Make(dtypes.Float32, 1, 3), Make(dtypes.Float32, DynamicDim, 3)},
+		{
+			Make(dtypes.Float32, DynamicDim, 3).WithBounds(8, DynamicDim),
+			Make(dtypes.Float32, DynamicDim, 1).WithBounds(4, DynamicDim),
+			Make(dtypes.Float32, DynamicDim, 3).WithBounds(4, DynamicDim),
+		},
+		{
+			Make(dtypes.Float32, 1, 3),
+			Make(dtypes.Float32, DynamicDim, 1).WithBounds(4, DynamicDim),
+			Make(dtypes.Float32, DynamicDim, 3).WithBounds(4, DynamicDim),
+		},
+	}
+	for _, tc := range testCases {
+		for _, args := range [][2]Shape{{tc.a, tc.b}, {tc.b, tc.a}} {
+			got, err := BroadcastShapes(args[0], args[1])
+			if err != nil {
+				t.Errorf("BroadcastShapes(%s, %s) failed: %+v", args[0], args[1], err)
+				continue
+			}
+			if !got.Equal(tc.want) || !reflect.DeepEqual(got.Bounds, tc.want.Bounds) {
+				t.Errorf("BroadcastShapes(%s, %s) = %s, want %s", args[0], args[1], got, tc.want)
+			}
+		}
+	}
+
+	// Errors.
+	if _, err := BroadcastShapes(Make(dtypes.Float32, 2, 3), Make(dtypes.Float32, 3, 3)); err == nil {
+		t.Errorf("BroadcastShapes should fail for incompatible dimensions")
+	}
+	if _, err := BroadcastShapes(Make(dtypes.Float32, 2), Make(dtypes.Int32, 2)); err == nil {
+		t.Errorf("BroadcastShapes should fail for different dtypes")
+	}
+	if _, err := BroadcastShapes(Make(dtypes.Float32, DynamicDim), Make(dtypes.Float32, 3)); err == nil {
+		t.Errorf("BroadcastShapes should fail for a dynamic axis and a static axis different from 1")
+	}
+}
+
+func TestGobSerialize(t *testing.T) {
+	for _, shape := range []Shape{
+		Make(dtypes.Float32, 2, 3),
+		Make(dtypes.Int32),
+		Make(dtypes.Float32, DynamicDim, 3).WithBounds(8, DynamicDim),
+		MakeTuple([]Shape{Make(dtypes.Float64, 2), Make(dtypes.Uint8, DynamicDim).WithBounds(4)}),
+	} {
+		var buf bytes.Buffer
+		if err := shape.GobSerialize(gob.NewEncoder(&buf)); err != nil {
+			t.Fatalf("GobSerialize(%s) failed: %+v", shape, err)
+		}
+		decoded, err := GobDeserialize(gob.NewDecoder(&buf))
+		if err != nil {
+			t.Fatalf("GobDeserialize of %s failed: %+v", shape, err)
+		}
+		if !decoded.Equal(shape) || !slices.Equal(decoded.Bounds, shape.Bounds) {
+			t.Errorf("GobDeserialize() = %s, want %s", decoded, shape)
+		}
+	}
+
+	// Stream written before the bounds were added: (f32[2,3], s8[]).
+	oldFormat := []byte{0x3, 0x4, 0x0, 0x0, 0xb, 0x7f, 0x2, 0x1, 0x2, 0xff, 0x80, 0x0, 0x1, 0x4, 0x0, 0x0, 0x4, 0xff,
+		0x80, 0x0, 0x0, 0x3, 0x4, 0x0, 0x4, 0x3, 0x4, 0x0, 0x16, 0x6, 0xff, 0x80, 0x0, 0x2, 0x4, 0x6, 0x3, 0x4, 0x0, 0x0,
+		0x3, 0x4, 0x0, 0x4, 0x4, 0xff, 0x80, 0x0, 0x0, 0x3, 0x4, 0x0, 0x0}
+	decoded, err := GobDeserialize(gob.NewDecoder(bytes.NewReader(oldFormat)))
+	want := MakeTuple([]Shape{Make(dtypes.Float32, 2, 3), Make(dtypes.Int8)})
+	if err != nil || !decoded.Equal(want) {
+		t.Errorf("GobDeserialize of the old format = %s, %v, want %s", decoded, err, want)
+	}
+
+	// Shapes without bounds are still written in the old format.
+	var buf bytes.Buffer
+	if err := want.GobSerialize(gob.NewEncoder(&buf)); err != nil || !bytes.Equal(buf.Bytes(), oldFormat) {
+		t.Errorf("GobSerialize(%s) = %#v, %v, want the old format %#v", want, buf.Bytes(), err, oldFormat)
+	}
+}
+
+// gobShapeV1 has the fields of Shape before bounds were added, encoded by encoding/gob field by field.
+type gobShapeV1 struct {
+	DType       dtypes.DType
+	Dimensions  []int
+	TupleShapes []gobShapeV1
+}
+
+// TestShapeGob must run after TestGobSerialize: encoding/gob assigns its type ids globally, in the order the
+// types are first encoded, and TestGobSerialize checks the exact bytes of the old format.
+func TestShapeGob(t *testing.T) {
+	// Structs holding shapes encoded by previous versions can still be decoded.
+	type metadataV1 struct {
+		Name  string
+		Shape gobShapeV1
+	}
+	type metadata struct {
+		Name  string
+		Shape Shape
+	}
+	var buf bytes.Buffer
+	v1 := metadataV1{Name: "x", Shape: gobShapeV1{DType: dtypes.Float32, Dimensions: []int{2, 3}}}
+	if err := gob.NewEncoder(&buf).Encode(v1); err != nil {
+		t.Fatalf("gob encoding failed: %+v", err)
+	}
+	var decoded metadata
+	if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+		t.Fatalf("gob decoding of a previous version failed: %+v", err)
+	}
+	if decoded.Name != "x" || !decoded.Shape.Equal(Make(dtypes.Float32, 2, 3)) {
+		t.Errorf("gob decoding of a previous version: got %+v", decoded)
+	}
+
+	// Round trip, including the zero shape.
+	for _, shape := range append(slices.Clone(codecTestShapes), Shape{}) {
+		buf.Reset()
+		if err := gob.NewEncoder(&buf).Encode(metadata{Name: "y", Shape: shape}); err != nil {
+			t.Fatalf("gob encoding of %s failed: %+v", shape, err)
+		}
+		decoded = metadata{}
+		if err := gob.NewDecoder(&buf).Decode(&decoded); err != nil {
+			t.Fatalf("gob decoding of %s failed: %+v", shape, err)
+		}
+		if !decoded.Shape.Equal(shape) || decoded.Shape.Ok() != shape.Ok() {
+			t.Errorf("gob round trip of %s: got %s", shape, decoded.Shape)
+		}
+	}
+}
@@ -3,9 +3,11 @@ package shapes
 import (
 	"fmt"
 	"io"
+	"strconv"
 	"strings"
 
 	"github.com/gomlx/stablehlo/internal/utils"
+	"github.com/pkg/errors"
 )
 
 // ToStableHLO returns the ToStableHLO representation of the shape's type.
@@ -50,10 +52,141 @@ func (s Shape) WriteStableHLO(writer io.Writer) error {
 			if i > 0 {
 				w("x")
 			}
-			w("%d", dim)
+			if dim == DynamicDim {
+				w("?")
+			} else {
+				w("%d", dim)
+			}
 		}
 		w("x")
 	}
-	w("%s>", utils.DTypeToStableHLO(s.DType))
+	w("%s", utils.DTypeToStableHLO(s.DType))
+	if s.HasBounds() {
+		// Bounded dynamism: static axes and unbounded dynamic axes are rendered as "?".
+		w(", #stablehlo.bounds<")
+		for i, bound := range s.Bounds {
+			if i > 0 {
+				w(", ")
+			}
+			if bound == DynamicDim || s.Dimensions[i] != DynamicDim {
+				w("?")
+			} else {
+				w("%d", bound)
+			}
+		}
+		w(">")
+	}
+	w(">")
 	return err
 }
+
+// FromStableHLO parses a StableHLO tensor (or tuple) type, e.g. "tensor<2x?xf32, #stablehlo.bounds<?, 8>>",
+// into a Shape. It's the reverse of Shape.ToStableHLO.
+func FromStableHLO(typeStr string) (Shape, error) {
+	typeStr = strings.TrimSpace(typeStr)
+	if inner, ok := strings.CutPrefix(typeStr, "tuple<"); ok {
+		inner, ok = strings.CutSuffix(inner, ">")
+		if !ok {
+			return Invalid(), errors.Errorf("invalid StableHLO tuple type %q", typeStr)
+		}
+		var elements []Shape
+		for _, elementStr := range splitTopLevel(inner) {
+			element, err := FromStableHLO(elementStr)
+			if err != nil {
+				return Invalid(), errors.WithMessagef(err, "in tuple type %q", typeStr)
+			}
+			elements = append(elements, element)
+		}
+		return MakeTuple(elements), nil
+	}
+
+	inner, ok := strings.CutPrefix(typeStr, "tensor<")
+	if ok {
+		inner, ok = strings.CutSuffix(inner, ">")
+	}
+	if !ok {
+		return Invalid(), errors.Errorf("invalid StableHLO tensor type %q", typeStr)
+	}
+	parts := splitTopLevel(inner)
+	if len(parts) == 0 || len(parts) > 2 {
+		return Invalid(), errors.Errorf("invalid StableHLO tensor type %q", typeStr)
+	}
+
+	// Dimensions (digits or "?") are each followed by "x", and the element type comes last.
+	var dimensions []int
+	rest := parts[0]
+	for {
+		dimStr, after, found := strings.Cut(rest, "x")
+		if !found || dimStr == "" || strings.Trim(dimStr, "0123456789?") != "" {
+			break
+		}
+		dim := DynamicDim
+		if dimStr != "?" {
+			var err error
+			dim, err = strconv.Atoi(dimStr)
+			if err != nil {
+				return Invalid(), errors.Errorf("invalid dimension %q in StableHLO type %q", dimStr, typeStr)
+			}
+		}
+		dimensions = append(dimensions, dim)
+		rest = after
+	}
+	dtype, found := utils.DTypeFromStableHLO(rest)
+	if !found {
+		return Invalid(), errors.Errorf("unknown element type %q in StableHLO type %q", rest, typeStr)
+	}
+	shape := Make(dtype, dimensions...)
+	if len(parts) == 1 {
+		return shape, nil
+	}
+
+	// Bounded dynamism encoding:
+	boundsStr, ok := strings.CutPrefix(parts[1], "#stablehlo.bounds<")
+	if ok {
+		boundsStr, ok = strings.CutSuffix(boundsStr, ">")
+	}
+	if !ok {
+		return Invalid(), errors.Errorf("unsupported encoding %q in StableHLO type %q", parts[1], typeStr)
+	}
+	boundsFields := strings.Split(boundsStr, ",")
+	if len(boundsFields) != len(dimensions) {
+		return Invalid(), errors.Errorf("StableHLO type %q has %d bounds for rank %d", typeStr, len(boundsFields), len(dimensions))
+	}
+	bounds := make([]int, len(boundsFields))
+	for i, field := range boundsFields {
+		field = strings.TrimSpace(field)
+		if field == "?" {
+			bounds[i] = DynamicDim
+			continue
+		}
+		bound, err := strconv.Atoi(field)
+		if err != nil || bound < 0 || dimensions[i] != DynamicDim {
+			return Invalid(), errors.Errorf("invalid bound %q for axis %d in StableHLO type %q", field, i, typeStr)
+		}
+		bounds[i] = bound
+	}
+	return shape.WithBounds(bounds...), nil
+}
+
+// splitTopLevel splits a comma-separated list, ignoring the commas nested in "<...>".
+func splitTopLevel(str string) []string {
+	var parts []string
+	depth, start := 0, 0
+	for i, c := range str {
+		switch c {
+		case '<':
+			depth++
+		case '>':
+			depth--
+		case ',':
+			if depth == 0 {
+				parts = append(parts, strings.TrimSpace(str[start:i]))
+				start = i + 1
+			}
+		}
+	}
+	if last := strings.TrimSpace(str[start:]); last != "" || len(parts) > 0 {
+		parts = append(parts, last)
+	}
+	return parts
+}
@@ -18,3 +18,63 @@ func TestToStableHLO(t *testing.T) {
 		t.Errorf("ToStableHLO() = %q, want %q", got, "tensor<i32>")
 	}
 }
+
+func TestToStableHLOBounded(t *testing.T) {
+	shape := Make(dtypes.Float32, DynamicDim, 3).WithBounds(8, DynamicDim)
+	want := "tensor<?x3xf32, #stablehlo.bounds<8, ?>>"
+	if got := shape.ToStableHLO(); got != want {
+		t.Errorf("ToStableHLO() = %q, want %q", got, want)
+	}
+	if got := shape.String(); got != "(Float32)[?<=8 3]" {
+		t.Errorf("String() = %q, want %q", got, "(Float32)[?<=8 3]")
+	}
+	if got := shape.Size(); got != 24 {
+		t.Errorf("Size() = %d, want 24", got)
+	}
+
+	// Unbounded dynamic axis.
+	shape = Make(dtypes.Int32, 2, DynamicDim)
+	want = "tensor<2x?xi32>"
+	if got := shape.ToStableHLO(); got != want {
+		t.Errorf("ToStableHLO() = %q, want %q", got, want)
+	}
+	if got := shape.Size(); got != DynamicDim {
+		t.Errorf("Size() = %d, want DynamicDim", got)
+	}
+	if shape.Equal(Make(dtypes.Int32, 2, DynamicDim).WithBounds(DynamicDim, 4)) {
+		t.Errorf("shapes with different bounds should not be equal")
+	}
+	if !shape.Equal(Make(dtypes.Int32, 2, DynamicDim).WithBounds(DynamicDim, DynamicDim)) {
+		t.Errorf("shapes without bounds should be equal")
+	}
+}
+
+func TestFromStableHLO(t *testing.T) {
+	for _, shape := range []Shape{
+		Make(dtypes.Float32, 1, 10),
+		Make(dtypes.Int32),
+		Make(dtypes.Bool, 0, 3),
+		Make(dtypes.Complex128, 2),
+		Make(dtypes.Uint8, DynamicDim, 4),
+		Make(dtypes.Float32, DynamicDim, 3).WithBounds(8, DynamicDim),
+		MakeTuple([]Shape{Make(dtypes.Float64, 2), Make(dtypes.F8E4M3FN)}),
+	} {
+		got, err := FromStableHLO(shape.ToStableHLO())
+		if err != nil {
+			t.Errorf("FromStableHLO(%q) failed: %+v", shape.ToStableHLO(), err)
+			continue
+		}
+		if !got.Equal(shape) || got.ToStableHLO() != shape.ToStableHLO() {
+			t.Errorf("FromStableHLO(%q) = %s, want %s", shape.ToStableHLO(), got, shape)
+		}
+	}
+
+	for _, invalid := range []string{
+		"", "f32", "tensor<>", "tensor<2xfoo>", "tensor<ax3xf32>", "tensor<2x3xf32", "tensor<3xf32, #foo>",
+		"tensor<?xf32, #stablehlo.bounds<1, 2>>", "tensor<3xf32, #stablehlo.bounds<2>>",
+	} {
+		if _, err := FromStableHLO(invalid); err == nil {
+			t.Errorf("FromStableHLO(%q) should have failed", invalid)
+		}
+	}
+}
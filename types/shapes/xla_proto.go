@@ -0,0 +1,204 @@
+package shapes
+
+import (
+	"math"
+
+	"github.com/pkg/errors"
+	"google.golang.org/protobuf/encoding/protowire"
+)
+
+// Field numbers of XLA's ShapeProto (xla_data.proto) -- the layout (5) is not used -- and the PrimitiveType of tuples.
+const (
+	xlaShapeElementTypeField        protowire.Number = 2
+	xlaShapeDimensionsField         protowire.Number = 3
+	xlaShapeTupleShapesField        protowire.Number = 4
+	xlaShapeIsDynamicDimensionField protowire.Number = 6
+	xlaTuplePrimitiveType                            = 13
+)
+
+// xlaUnboundedSize is the dimension used by XLA for dynamic axes without an upper-bound.
+const xlaUnboundedSize = math.MinInt64
+
+// ToXLAShapeProto returns the shape serialized as XLA's ShapeProto (see xla_data.proto), e.g. to be exchanged with
+// tools that use XLA. The bytes can be unmarshalled with proto.Unmarshal into a xla.ShapeProto.
+//
+// Dynamic axes are marked in is_dynamic_dimension, with their bound as the dimension (or XLA's unbounded size,
+// math.MinInt64, if they have no bound). No layout is set.
+//
+// See FromXLAShapeProto for the reverse.
+func (s Shape) ToXLAShapeProto() ([]byte, error) {
+	return s.appendXLAShapeProto(nil)
+}
+
+// appendXLAShapeProto implements ToXLAShapeProto, appending the serialized shape to buf.
+func (s Shape) appendXLAShapeProto(buf []byte) ([]byte, error) {
+	if s.IsTuple() {
+		buf = protowire.AppendTag(buf, xlaShapeElementTypeField, protowire.VarintType)
+		buf = protowire.AppendVarint(buf, xlaTuplePrimitiveType)
+		for _, subShape := range s.TupleShapes {
+			subBuf, err := subShape.appendXLAShapeProto(nil)
+			if err != nil {
+				return nil, err
+			}
+			buf = protowire.AppendTag(buf, xlaShapeTupleShapesField, protowire.BytesType)
+			buf = protowire.AppendBytes(buf, subBuf)
+		}
+		return buf, nil
+	}
+	primitiveType := int32(s.DType.PrimitiveType())
+	if dtype, found := xlaPrimitiveTypes()[primitiveType]; !found || dtype != s.DType {
+		return nil, errors.Errorf("shape %s has a dtype not supported by XLA's ShapeProto", s)
+	}
+	buf = protowire.AppendTag(buf, xlaShapeElementTypeField, protowire.VarintType)
+	buf = protowire.AppendVarint(buf, uint64(primitiveType))
+	if s.Rank() == 0 {
+		return buf, nil
+	}
+
+	var dims []byte
+	for axis, dim := range s.Dimensions {
+		value := int64(dim)
+		switch {
+		case dim >= 0:
+		case dim != DynamicDim:
+			return nil, errors.Errorf("shape %s has an invalid dimension %d for axis %d", s, dim, axis)
+		case s.Bound(axis) != DynamicDim:
+			value = int64(s.Bound(axis))
+		default:
+			value = xlaUnboundedSize
+		}
+		dims = protowire.AppendVarint(dims, uint64(value))
+	}
+	buf = protowire.AppendTag(buf, xlaShapeDimensionsField, protowire.BytesType)
+	buf = protowire.AppendBytes(buf, dims)
+	if s.IsDynamic() {
+		dynamic := make([]byte, 0, s.Rank())
+		for _, dim := range s.Dimensions {
+			dynamic = protowire.AppendVarint(dynamic, protowire.EncodeBool(dim == DynamicDim))
+		}
+		buf = protowire.AppendTag(buf, xlaShapeIsDynamicDimensionField, protowire.BytesType)
+		buf = protowire.AppendBytes(buf, dynamic)
+	}
+	return buf, nil
+}
+
+// FromXLAShapeProto parses the shape from the serialized XLA's ShapeProto (see xla_data.proto), e.g. as returned
+// by proto.Marshal of a xla.ShapeProto. The layout, if present, is ignored.
+//
+// It's the reverse of Shape.ToXLAShapeProto.
+func FromXLAShapeProto(data []byte) (Shape, error) {
+	shape, err := parseXLAShapeProto(data)
+	if err != nil {
+		return Invalid(), errors.WithMessage(err, "failed to parse XLA ShapeProto")
+	}
+	return shape, nil
+}
+
+// parseXLAShapeProto implements FromXLAShapeProto.
+func parseXLAShapeProto(data []byte) (Shape, error) {
+	var (
+		primitiveType int32
+		dimensions    []int64
+		isDynamic     []bool
+		tupleShapes   []Shape
+	)
+	// decodeVarints decodes either a packed or a single (unpacked) repeated varint field.
+	decodeVarints := func(field []byte, typ protowire.Type, values []uint64) ([]uint64, int) {
+		if typ == protowire.VarintType {
+			value, n := protowire.ConsumeVarint(field)
+			return append(values, value), n
+		}
+		packed, n := protowire.ConsumeBytes(field)
+		if n < 0 {
+			return values, n
+		}
+		for len(packed) > 0 {
+			value, m := protowire.ConsumeVarint(packed)
+			if m < 0 {
+				return values, m
+			}
+			values = append(values, value)
+			packed = packed[m:]
+		}
+		return values, n
+	}
+
+	for len(data) > 0 {
+		num, typ, n := protowire.ConsumeTag(data)
+		if n < 0 {
+			return Invalid(), protowire.ParseError(n)
+		}
+		data = data[n:]
+		var values []uint64
+		switch {
+		case num == xlaShapeElementTypeField && typ == protowire.VarintType:
+			var value uint64
+			value, n = protowire.ConsumeVarint(data)
+			primitiveType = int32(value)
+		case num == xlaShapeDimensionsField && (typ == protowire.VarintType || typ == protowire.BytesType):
+			values, n = decodeVarints(data, typ, nil)
+			for _, value := range values {
+				dimensions = append(dimensions, int64(value))
+			}
+		case num == xlaShapeIsDynamicDimensionField && (typ == protowire.VarintType || typ == protowire.BytesType):
+			values, n = decodeVarints(data, typ, nil)
+			for _, value := range values {
+				isDynamic = append(isDynamic, protowire.DecodeBool(value))
+			}
+		case num == xlaShapeTupleShapesField && typ == protowire.BytesType:
+			var subData []byte
+			subData, n = protowire.ConsumeBytes(data)
+			if n >= 0 {
+				subShape, err := parseXLAShapeProto(subData)
+				if err != nil {
+					return Invalid(), errors.WithMessagef(err, "tuple element #%d", len(tupleShapes))
+				}
+				tupleShapes = append(tupleShapes, subShape)
+			}
+		default:
+			// Layout and unknown fields are skipped.
+			n = protowire.ConsumeFieldValue(num, typ, data)
+		}
+		if n < 0 {
+			return Invalid(), errors.WithMessagef(protowire.ParseError(n), "field %d", num)
+		}
+		data = data[n:]
+	}
+
+	if primitiveType == xlaTuplePrimitiveType {
+		return MakeTuple(tupleShapes), nil
+	}
+	dtype, found := xlaPrimitiveTypes()[primitiveType]
+	if !found {
+		return Invalid(), errors.Errorf("element type %d not supported", primitiveType)
+	}
+	if len(tupleShapes) > 0 {
+		return Invalid(), errors.Errorf("array shape of dtype %s has tuple shapes", dtype)
+	}
+	if len(isDynamic) > 0 && len(isDynamic) != len(dimensions) {
+		return Invalid(), errors.Errorf("%d is_dynamic_dimension values given for rank %d", len(isDynamic), len(dimensions))
+	}
+	if len(dimensions) == 0 {
+		return Make(dtype), nil
+	}
+	dims := make([]int, len(dimensions))
+	bounds := make([]int, len(dimensions))
+	for axis, dim := range dimensions {
+		bounds[axis] = DynamicDim
+		switch {
+		case len(isDynamic) > 0 && isDynamic[axis]:
+			dims[axis] = DynamicDim
+			if dim != xlaUnboundedSize {
+				if dim < 0 || dim > math.MaxInt {
+					return Invalid(), errors.Errorf("invalid bound %d for axis %d", dim, axis)
+				}
+				bounds[axis] = int(dim)
+			}
+		case dim < 0 || dim > math.MaxInt:
+			return Invalid(), errors.Errorf("invalid dimension %d for axis %d", dim, axis)
+		default:
+			dims[axis] = int(dim)
+		}
+	}
+	return Make(dtype, dims...).WithBounds(bounds...), nil
+}
@@ -265,11 +265,22 @@ func (m *DeviceMesh) ComputeReplicaGroups(axes []string) ([][]int, error) {
 // ToStableHLO returns the StableHLO representation of the mesh, as it should be used in the module body.
 // E.g.: sdy.mesh @mesh = <["data"=4, "model"=2]>
 func (m *DeviceMesh) ToStableHLO() string {
+	return fmt.Sprintf("sdy.mesh @%s = %s", m.name, m.meshBody())
+}
+
+// ToStableHLOAttribute returns the mesh as an MLIR attribute, as used by the generic form of the "sdy.mesh" operation.
+// E.g.: #sdy.mesh<["data"=4, "model"=2]>
+func (m *DeviceMesh) ToStableHLOAttribute() string {
+	return "#sdy.mesh" + m.meshBody()
+}
+
+// meshBody returns the axes and device ids of the mesh, e.g.: <["data"=4, "model"=2]>
+func (m *DeviceMesh) meshBody() string {
 	var buf strings.Builder
 	w := func(format string, args ...any) {
 		buf.WriteString(fmt.Sprintf(format, args...))
 	}
-	w("sdy.mesh @%s = <[", m.name)
+	w("<[")
 	for i, axisName := range m.axesNames {
 		if i > 0 {
 			w(", ")
@@ -70,6 +70,10 @@ func TestDeviceMesh(t *testing.T) {
 				if got := mesh.ToStableHLO(); got != tt.wantStableHLO {
 					t.Errorf("ToStableHLO() = %q, want %q", got, tt.wantStableHLO)
 				}
+				wantAttribute := "#sdy.mesh" + strings.TrimPrefix(tt.wantStableHLO, "sdy.mesh @mesh = ")
+				if got := mesh.ToStableHLOAttribute(); got != wantAttribute {
+					t.Errorf("ToStableHLOAttribute() = %q, want %q", got, wantAttribute)
+				}
 			})
 		}
 	})
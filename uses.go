@@ -0,0 +1,110 @@
+package stablehlo
+
+import (
+	"slices"
+
+	"github.com/pkg/errors"
+)
+
+// Uses returns the statements of the function that use the value as an operand, in the order they were added --
+// including the function's return statement, if the value is returned.
+//
+// The uses are tracked as the program is built and transformed by this package (e.g. Function.Rewrite and
+// Builder.InlineCalls), but not if Statement.Inputs is changed directly.
+//
+// It's useful to decide, for instance, whether a value is worth rematerializing (recomputing) instead of keeping it
+// alive, or whether a statement is dead (its outputs have no uses).
+func (v *Value) Uses() []*Statement {
+	return slices.Clone(v.uses)
+}
+
+// NumUses returns the number of statements using the value as an operand. See Value.Uses.
+func (v *Value) NumUses() int {
+	return len(v.uses)
+}
+
+// ReplaceAllUsesWith replaces v by newValue as the operand of all the statements using it (see Uses), including the
+// return statement of the function. The tags of v are merged into newValue (see Value.SetTag).
+//
+// It is the basic primitive of transformation passes: create the replacement (e.g. with Function.InsertAfter) and
+// replace the uses of the original value, which then becomes unused.
+//
+// newValue must be a value of the same function, with the same shape, defined before all the uses of v -- so it
+// can't be computed from v: the statement computing it would use itself. Otherwise, an error is returned and
+// nothing is changed.
+func (v *Value) ReplaceAllUsesWith(newValue *Value) error {
+	if newValue == nil {
+		return errors.Errorf("ReplaceAllUsesWith(nil) for value %s", v)
+	}
+	if newValue == v {
+		return nil
+	}
+	fn := v.fn
+	if newValue.fn != fn {
+		return errors.Errorf("ReplaceAllUsesWith: value %s is from function %q, but %s is from function %q",
+			newValue, newValue.fn.Name, v, fn.Name)
+	}
+	if !newValue.shape.Equal(v.shape) {
+		return errors.Errorf("ReplaceAllUsesWith: value %s has shape %s, but %s has shape %s",
+			newValue, newValue.shape, v, v.shape)
+	}
+	definedAt := -1 // The inputs of the function are defined before all statements.
+	if producer := newValue.Producer(); producer != nil {
+		definedAt = slices.Index(fn.Statements, producer)
+	}
+	for _, use := range v.uses {
+		if idx := slices.Index(fn.Statements, use); idx <= definedAt {
+			return errors.Errorf("ReplaceAllUsesWith: %s is used by statement #%d (%s) of function %q, before %s is defined (statement #%d)",
+				v, idx, use.OpName(), fn.Name, newValue, definedAt)
+		}
+	}
+	fn.replaceUses(map[*Value]*Value{v: newValue})
+	return nil
+}
+
+// registerUses adds the new statement to the uses of its inputs.
+//
+// The statement must be the most recent use of its inputs, so an input used more than once by it only needs to be
+// compared with the last use -- checking all the uses would make building graphs with heavily used values quadratic.
+//
+// It panics with poisonedValuePanic if any of the inputs is poisoned (see Function.WithErrorAccumulation).
+func (s *Statement) registerUses() {
+	for _, input := range s.Inputs {
+		input.panicIfPoisoned()
+		if input != nil && (len(input.uses) == 0 || input.uses[len(input.uses)-1] != s) {
+			input.uses = append(input.uses, s)
+		}
+	}
+}
+
+// unregisterUses removes the statement from the uses of its inputs.
+func (s *Statement) unregisterUses() {
+	for _, input := range s.Inputs {
+		input.removeUse(s)
+	}
+}
+
+// addUse adds stmt to the uses of v, if not there yet.
+func (v *Value) addUse(stmt *Statement) {
+	if v != nil && !slices.Contains(v.uses, stmt) {
+		v.uses = append(v.uses, stmt)
+	}
+}
+
+// removeUse removes stmt from the uses of v.
+func (v *Value) removeUse(stmt *Statement) {
+	if v != nil {
+		v.uses = slices.DeleteFunc(v.uses, func(use *Statement) bool { return use == stmt })
+	}
+}
+
+// truncateStatements drops the statements of fn after the first n, removing them from the uses of their inputs.
+func (fn *Function) truncateStatements(n int) {
+	if n >= len(fn.Statements) {
+		return
+	}
+	for _, stmt := range fn.Statements[n:] {
+		stmt.unregisterUses()
+	}
+	fn.Statements = fn.Statements[:n]
+}
@@ -0,0 +1,158 @@
+package stablehlo
+
+import (
+	"slices"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+// requireConsistentUses checks that the uses tracked for the values of fn match its statements.
+func requireConsistentUses(t *testing.T, fn *Function) {
+	t.Helper()
+	want := make(map[*Value][]*Statement)
+	for _, stmt := range fn.Statements {
+		for _, input := range stmt.Inputs {
+			if !slices.Contains(want[input], stmt) {
+				want[input] = append(want[input], stmt)
+			}
+		}
+	}
+	for _, value := range fn.values {
+		uses := value.Uses()
+		if len(uses) != len(want[value]) {
+			t.Errorf("value %s has %d uses tracked, want %d", value, len(uses), len(want[value]))
+			continue
+		}
+		for _, use := range uses {
+			if !slices.Contains(want[value], use) {
+				t.Errorf("value %s has stale use %s", value, use.OpName())
+			}
+		}
+	}
+}
+
+func TestValueUses(t *testing.T) {
+	t.Run("construction", func(t *testing.T) {
+		fn := New(t.Name()).Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		y := must(fn.NamedInput("y", shapes.Make(dtypes.Float32, 3)))
+		sum := must(Add(x, x))
+		product := must(Multiply(sum, y))
+		must0(fn.Return(product, sum))
+		if x.NumUses() != 1 || x.Uses()[0].OpName() != "stablehlo.add" {
+			t.Errorf("x should be used once, by the addition, got %d uses", x.NumUses())
+		}
+		if sum.NumUses() != 2 || sum.Uses()[1].OpType.String() != "FuncReturn" {
+			t.Errorf("sum should be used by the multiplication and the return, got %d uses", sum.NumUses())
+		}
+		requireConsistentUses(t, fn)
+	})
+
+	t.Run("inlining", func(t *testing.T) {
+		builder := New(t.Name())
+		double := builder.NewFunction("double")
+		a := must(double.NamedInput("a", shapes.Make(dtypes.Float32)))
+		must0(double.Return(must(Add(a, a))))
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32)))
+		outputs := must(fn.Call(double, x))
+		must0(fn.Return(must(Negate(outputs[0]))))
+		must(builder.InlineCalls(-1))
+		if x.NumUses() != 1 || x.Uses()[0].OpName() != "stablehlo.add" {
+			t.Errorf("after inlining x should be used by the inlined addition only, got %d uses", x.NumUses())
+		}
+		if outputs[0].NumUses() != 0 {
+			t.Errorf("the output of the inlined call should have no uses, got %d", outputs[0].NumUses())
+		}
+		requireConsistentUses(t, fn)
+	})
+
+	t.Run("rewrite", func(t *testing.T) {
+		fn := New(t.Name()).Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		product := must(Multiply(x, x))
+		must0(fn.Return(must(Tanh(product))))
+		must(fn.Rewrite(&RewriteRule{
+			Name: "square_to_power",
+			Op:   "stablehlo.multiply",
+			Replace: func(fn *Function, mul *Statement) ([]*Value, error) {
+				two := must(fn.ConstantFromScalar(float32(2)))
+				return []*Value{must(Power(mul.Inputs[0], must(BroadcastInDim(two, mul.Inputs[0].Shape(), nil))))}, nil
+			},
+		}))
+		if product.NumUses() != 0 {
+			t.Errorf("the rewritten multiplication should have no uses, got %d", product.NumUses())
+		}
+		requireConsistentUses(t, fn)
+	})
+
+	t.Run("error accumulation", func(t *testing.T) {
+		fn := New(t.Name()).Main().WithErrorAccumulation()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		y := must(fn.NamedInput("y", shapes.Make(dtypes.Float32, 2)))
+		_, _ = Add(x, y) // Fails: the function is invalid from here.
+		_, _ = Negate(x)
+		if fn.Err() == nil {
+			t.Fatal("expected accumulated error")
+		}
+		requireConsistentUses(t, fn)
+	})
+
+	t.Run("replace all uses with", func(t *testing.T) {
+		fn := New(t.Name()).Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		y := must(fn.NamedInput("y", shapes.Make(dtypes.Float32, 2)))
+		th := must(Tanh(x))
+		sum := must(Add(th, th))
+		late := must(Abs(x))
+		must0(fn.Return(sum, th, late, y))
+		tanhStmt := th.Producer()
+		th.SetTag("origin", "tanh")
+
+		// Replacement computed from the value itself.
+		var negated *Value
+		must(fn.InsertAfter(tanhStmt, func() (err error) {
+			negated, err = Negate(th)
+			return err
+		}))
+		if err := th.ReplaceAllUsesWith(negated); err == nil {
+			t.Error("expected error replacing a value by a value computed from it, got nil")
+		}
+		// Replacement defined after the uses.
+		if err := th.ReplaceAllUsesWith(late); err == nil {
+			t.Error("expected error replacing a value by a value defined after its uses, got nil")
+		}
+		if err := th.ReplaceAllUsesWith(y); err == nil {
+			t.Error("expected error replacing a value by a value of a different shape, got nil")
+		}
+		other := New("other").Main()
+		if err := th.ReplaceAllUsesWith(must(other.NamedInput("z", shapes.Make(dtypes.Float32, 3)))); err == nil {
+			t.Error("expected error replacing a value by a value of another function, got nil")
+		}
+		if th.NumUses() != 3 {
+			t.Fatalf("failed replacements changed the uses of the value: %d uses, wanted 3", th.NumUses())
+		}
+
+		// Valid replacement, defined before tanh.
+		var abs *Value
+		must(fn.InsertBefore(tanhStmt, func() (err error) {
+			abs, err = Abs(x)
+			return err
+		}))
+		must0(th.ReplaceAllUsesWith(abs))
+		if th.NumUses() != 0 || abs.NumUses() != 3 {
+			t.Errorf("after the replacement, tanh has %d uses (wanted 0) and abs has %d (wanted 3: add, negate and return)",
+				th.NumUses(), abs.NumUses())
+		}
+		if sum.Producer().Inputs[0] != abs || sum.Producer().Inputs[1] != abs {
+			t.Error("the addition should use the replacement")
+		}
+		if got, _ := abs.Tag("origin"); got != "tanh" {
+			t.Errorf("the replacement should have the tags of the replaced value, got %v", got)
+		}
+		must0(abs.ReplaceAllUsesWith(abs))
+		requireConsistentUses(t, fn)
+	})
+}
@@ -22,6 +22,15 @@ type Value struct {
 	name       string
 	shape      shapes.Shape
 	Attributes map[string]any
+
+	// poisoned is set for values returned by operations in error-accumulating mode after an error.
+	poisoned bool
+
+	// uses are the statements using the value as an operand, see Value.Uses.
+	uses []*Statement
+
+	// tags are the user-defined tags of the value, see Value.SetTag.
+	tags map[string]any
 }
 
 // Shape returns the shape of the value.
@@ -29,6 +38,12 @@ func (v *Value) Shape() shapes.Shape {
 	return v.shape
 }
 
+// IsPoisoned returns whether the value is the result of a failed operation in the error-accumulating mode.
+// See Function.WithErrorAccumulation.
+func (v *Value) IsPoisoned() bool {
+	return v.poisoned
+}
+
 // Write writes the value in ToStableHLO text format to the given writer.
 func (v *Value) Write(w io.Writer, indentation string) error {
 	_ = indentation
@@ -0,0 +1,84 @@
+package stablehlo
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/pkg/errors"
+)
+
+// Version of StableHLO, used to configure the target version of the generated program,
+// see Builder.WithTargetVersion.
+type Version struct {
+	Major, Minor, Patch int
+}
+
+// ParseVersion parses a version in the format "major.minor.patch" (e.g. "1.9.0"), the patch being optional.
+func ParseVersion(version string) (Version, error) {
+	parts := strings.Split(strings.TrimPrefix(version, "v"), ".")
+	if len(parts) < 2 || len(parts) > 3 {
+		return Version{}, errors.Errorf("invalid StableHLO version %q, expected \"major.minor.patch\"", version)
+	}
+	var numbers [3]int
+	for i, part := range parts {
+		n, err := strconv.Atoi(part)
+		if err != nil || n < 0 {
+			return Version{}, errors.Errorf("invalid StableHLO version %q, expected \"major.minor.patch\"", version)
+		}
+		numbers[i] = n
+	}
+	return Version{Major: numbers[0], Minor: numbers[1], Patch: numbers[2]}, nil
+}
+
+// String implements fmt.Stringer.
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// Less returns whether v is an earlier version than other.
+func (v Version) Less(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major < other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor < other.Minor
+	}
+	return v.Patch < other.Patch
+}
+
+// Minimum StableHLO versions of features that are gated by Builder.WithTargetVersion.
+var (
+	// versionBatchingDims introduced the batching axes of Gather and Scatter.
+	versionBatchingDims = Version{1, 1, 0}
+
+	// versionResultAccuracy introduced the "result_accuracy" attribute of transcendental unary operations.
+	versionResultAccuracy = Version{1, 9, 0}
+)
+
+// WithTargetVersion configures the StableHLO version of the backend the program is built for.
+//
+// Features introduced after the target version are either legalized, if they are not actually used
+// (e.g.: empty batching axes in Gather or Scatter, or a default result accuracy are omitted), or
+// the operation returns an error.
+//
+// It must be set before the operations are created. By default, the latest version is targeted.
+func (b *Builder) WithTargetVersion(version Version) *Builder {
+	b.targetVersion = &version
+	return b
+}
+
+// supportsVersion returns whether the target version is at least the given version.
+// It is always true if no target version was configured.
+func (b *Builder) supportsVersion(version Version) bool {
+	return b.targetVersion == nil || !b.targetVersion.Less(version)
+}
+
+// requireVersion returns an error if the target version is earlier than the given version.
+func (b *Builder) requireVersion(feature string, version Version) error {
+	if b.supportsVersion(version) {
+		return nil
+	}
+	return errors.Errorf("%s requires StableHLO version %s, but the target version is %s",
+		feature, version, b.targetVersion)
+}
@@ -0,0 +1,75 @@
+package stablehlo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestTargetVersion(t *testing.T) {
+	t.Run("ParseVersion", func(t *testing.T) {
+		v := must(ParseVersion("v1.9"))
+		if v != (Version{1, 9, 0}) {
+			t.Errorf("expected version 1.9.0, got %s", v)
+		}
+		if !v.Less(Version{1, 10, 0}) || (Version{1, 10, 0}).Less(v) || v.Less(v) {
+			t.Errorf("unexpected ordering of versions for %s", v)
+		}
+		for _, invalid := range []string{"", "1", "1.x.0", "1.2.3.4", "-1.0"} {
+			if _, err := ParseVersion(invalid); err == nil {
+				t.Errorf("expected error parsing version %q", invalid)
+			}
+		}
+	})
+
+	t.Run("Gather legalized", func(t *testing.T) {
+		builder := New(t.Name()).WithTargetVersion(Version{1, 0, 0})
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 5, 3)))
+		indices := must(fn.NamedInput("indices", shapes.Make(dtypes.Int32, 2, 1)))
+		y := must(Gather(x, indices, 1, []int{1}, []int{0}, nil, nil, []int{0}, []int{1, 3}, false))
+		if err := fn.Return(y); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		if strings.Contains(program, "batching_dims") {
+			t.Errorf("program targeting version 1.0.0 should not have batching_dims")
+		}
+	})
+
+	t.Run("Gather with batching axes", func(t *testing.T) {
+		builder := New(t.Name()).WithTargetVersion(Version{1, 0, 0})
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 2, 5, 3)))
+		indices := must(fn.NamedInput("indices", shapes.Make(dtypes.Int32, 2, 1)))
+		_, err := Gather(x, indices, 1, []int{1}, []int{1}, []int{0}, []int{0}, []int{1}, []int{1, 1, 3}, false)
+		if err == nil {
+			t.Fatal("expected error using Gather batching axes when targeting version 1.0.0, got nil")
+		}
+	})
+
+	t.Run("result accuracy", func(t *testing.T) {
+		builder := New(t.Name()).WithTargetVersion(Version{1, 8, 0})
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		y := must(ExponentialWithAccuracy(x, types.ResultAccuracy{}))
+		if err := fn.Return(y); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		program := string(must(builder.Build()))
+		if strings.Contains(program, "#stablehlo.result_accuracy") {
+			t.Errorf("program targeting version 1.8.0 should not have result_accuracy:\n%s", program)
+		}
+
+		fn2 := New(t.Name()).WithTargetVersion(Version{1, 8, 0}).Main()
+		x = must(fn2.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+		if _, err := ExponentialWithAccuracy(x, types.ResultAccuracy{Mode: types.ResultAccuracyHighest}); err == nil {
+			t.Fatal("expected error using result_accuracy when targeting version 1.8.0, got nil")
+		}
+	})
+}
@@ -0,0 +1,28 @@
+package stablehlo
+
+import (
+	"github.com/pkg/errors"
+)
+
+// SetPrivate sets the visibility of the function to private (rendered as `func.func private`), or back to the
+// default public visibility.
+//
+// Private functions can only be called from within the module (see Function.Call): MLIR pipelines can then
+// inline, specialize or drop them freely. The main function must be public.
+//
+// Closures are rendered as regions of their operations, not as functions, and have no visibility.
+func (fn *Function) SetPrivate(private bool) error {
+	if fn.Parent != nil {
+		return errors.Errorf("closure %q has no visibility, it's rendered as a region of its operation", fn.Name)
+	}
+	if private && fn.Name == MainFunctionName {
+		return errors.Errorf("function %q must be public", fn.Name)
+	}
+	fn.private = private
+	return nil
+}
+
+// IsPrivate returns whether the function has private visibility, see SetPrivate.
+func (fn *Function) IsPrivate() bool {
+	return fn.private
+}
@@ -0,0 +1,84 @@
+package stablehlo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+// buildPrivateProgram builds a program where main calls the private function "double", marked as no_inline.
+func buildPrivateProgram(t *testing.T) *Builder {
+	builder := New(t.Name())
+	f := builder.NewFunction("double")
+	must0(f.SetPrivate(true))
+	must0(f.SetPassthroughAttribute("no_inline", "unit"))
+	x := must(f.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+	must0(f.Return(must(Add(x, x))))
+	fn := builder.Main()
+	must0(fn.SetPassthroughAttribute("jax.uses_shape_polymorphism", "true"))
+	x = must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 3)))
+	must0(fn.Return(must(fn.Call(f, x))[0]))
+	return builder
+}
+
+func TestPrivateFunctions(t *testing.T) {
+	t.Run("Rendering", func(t *testing.T) {
+		builder := buildPrivateProgram(t)
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `module @TestPrivateFunctions_Rendering {
+  func.func private @double(%x: tensor<3xf32>) -> tensor<3xf32> attributes {no_inline = unit} {
+    %0 = "stablehlo.add"(%x, %x) : (tensor<3xf32>, tensor<3xf32>) -> tensor<3xf32>
+    "stablehlo.return"(%0) : (tensor<3xf32>) -> ()
+  }
+
+  func.func @main(%x: tensor<3xf32>) -> tensor<3xf32> attributes {jax.uses_shape_polymorphism = true} {
+    %0 = "func.call"(%x) { callee = @double } : (tensor<3xf32>) -> tensor<3xf32>
+    "stablehlo.return"(%0) : (tensor<3xf32>) -> ()
+  }
+}
+`
+		if program != want {
+			fmt.Printf("  Failed. Wanted the following program:\n%s", want)
+			t.Fatal("programs don't match")
+		}
+	})
+
+	t.Run("GenericForm", func(t *testing.T) {
+		builder := buildPrivateProgram(t).WithGenericForm(true)
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		want := `sym_name = "double", sym_visibility = "private"}> ({`
+		if !strings.Contains(program, want) {
+			t.Errorf("program missing %q", want)
+		}
+		if strings.Count(program, "sym_visibility") != 1 {
+			t.Error("only the function double should be private")
+		}
+		requireRoundTrip(t, builder)
+	})
+
+	t.Run("Import", func(t *testing.T) {
+		imported := requireRoundTrip(t, buildPrivateProgram(t))
+		for _, fn := range imported.functions {
+			if fn.IsPrivate() != (fn.Name == "double") {
+				t.Errorf("imported function %q has IsPrivate()=%v", fn.Name, fn.IsPrivate())
+			}
+		}
+	})
+
+	t.Run("Errors", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		if err := fn.SetPrivate(true); err == nil {
+			t.Error("expected error setting the main function private, got nil")
+		}
+		if err := fn.Closure().SetPrivate(true); err == nil {
+			t.Error("expected error setting a closure private, got nil")
+		}
+		must0(fn.SetPrivate(false))
+	})
+}
@@ -0,0 +1,56 @@
+package stablehlo
+
+import (
+	"slices"
+
+	"github.com/gomlx/stablehlo/internal/optypes"
+	"github.com/gomlx/stablehlo/shapeinference"
+	"github.com/pkg/errors"
+)
+
+// While executes the body function while the cond function returns true, and returns the final loop state.
+//
+//   - cond: a closure of the function (see Function.Closure) that takes the loop state and returns a scalar boolean.
+//   - body: a closure of the function that takes the loop state and returns the updated loop state, with the same shapes.
+//   - initialStates: the initial loop state, at least one value.
+//
+// The closures can only use the values of the loop state (and the constants they create): values used by the loop
+// must be part of the state, even if they are not changed by the body.
+func While(cond, body *Function, initialStates ...*Value) (outputs []*Value, err error) {
+	op := optypes.While
+	if len(initialStates) == 0 {
+		return nil, errors.Errorf("%s requires at least one initial state value", op)
+	}
+	fn := initialStates[0].fn
+	defer fn.multiOpErrorHandler(&err, &outputs, len(initialStates))()
+	if fn.Returned {
+		return nil, errors.Errorf("cannot add operation %s after returning, in function %q",
+			op, fn.Name)
+	}
+	for i, state := range initialStates {
+		if state.fn != fn {
+			return nil, errors.Errorf("cannot add operation %s to function %q, because initial state #%d is from different function (%q and %q)",
+				op, fn.Name, i, state.fn.Name, fn.Name)
+		}
+	}
+	for i, closure := range []*Function{cond, body} {
+		name := []string{"cond", "body"}[i]
+		if closure.Parent != fn {
+			return nil, errors.Errorf("cannot add operation %s because %s is not a StableHLO closure of %q",
+				op, name, fn.Name)
+		}
+		if !closure.Returned && closure.declaredOutputs == nil {
+			return nil, errors.Errorf("cannot add operation %s because %s was not returned, nor its outputs declared", op, name)
+		}
+	}
+	outputShapes, err := shapeinference.While(valuesToShapes(initialStates),
+		valuesToShapes(cond.Inputs), cond.OutputShapes(),
+		valuesToShapes(body.Inputs), body.OutputShapes())
+	if err != nil {
+		return nil, err
+	}
+	stmt := fn.addMultiOp(op, outputShapes, slices.Clone(initialStates))
+	stmt.AddFunctionParameter("cond", cond)
+	stmt.AddFunctionParameter("body", body)
+	return stmt.Outputs, nil
+}
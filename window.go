@@ -0,0 +1,210 @@
+package stablehlo
+
+import (
+	"slices"
+
+	"github.com/gomlx/stablehlo/types"
+	"github.com/pkg/errors"
+)
+
+// Window configures the sliding window of the ReduceWindowWith, MultiReduceWindowWith, SelectAndScatterWith and
+// ConvolutionWith operations.
+//
+// Use NewWindow to create it with named setters and validation. Any field left empty uses its default value.
+type Window struct {
+	// Dimensions of the window, one per axis. Defaults to 1 for each axis.
+	// It is not used by ConvolutionWith, where the window is defined by the kernel spatial dimensions.
+	Dimensions []int
+
+	// Strides of the window, one per axis.
+	// Defaults to Dimensions for ReduceWindow and to 1 for SelectAndScatter and Convolution.
+	Strides []int
+
+	// Paddings (start and end) for each axis. Defaults to no padding.
+	// ReduceWindowWith accepts negative paddings, which trim the input.
+	Paddings [][2]int
+
+	// InputDilations (also known as "base dilations", or "lhs_dilation" for convolutions) for each axis,
+	// defaults to 1. Not supported by SelectAndScatterWith.
+	InputDilations []int
+
+	// WindowDilations (or "rhs_dilation" for convolutions, where they dilate the kernel) for each axis,
+	// defaults to 1. Not supported by SelectAndScatterWith.
+	WindowDilations []int
+}
+
+// WindowBuilder builds a Window with named setters, see NewWindow.
+type WindowBuilder struct {
+	window Window
+}
+
+// NewWindow returns a builder of a Window with the given dimensions (it can be empty for convolutions).
+// Call WindowBuilder.Done to validate it and get the Window.
+//
+// Example:
+//
+//	window, err := NewWindow(2, 2).Strides(1, 1).Paddings([2]int{0, 1}, [2]int{0, 1}).Done()
+//	output, err := ReduceWindowWith(x, zero, sumFn, window)
+func NewWindow(dimensions ...int) *WindowBuilder {
+	return &WindowBuilder{window: Window{Dimensions: slices.Clone(dimensions)}}
+}
+
+// Strides sets the strides of the window, one per axis.
+func (b *WindowBuilder) Strides(strides ...int) *WindowBuilder {
+	b.window.Strides = slices.Clone(strides)
+	return b
+}
+
+// Paddings sets the paddings (start and end) of each axis.
+func (b *WindowBuilder) Paddings(paddings ...[2]int) *WindowBuilder {
+	b.window.Paddings = slices.Clone(paddings)
+	return b
+}
+
+// InputDilations sets the dilations of the input (also known as "base dilations"), one per axis.
+func (b *WindowBuilder) InputDilations(dilations ...int) *WindowBuilder {
+	b.window.InputDilations = slices.Clone(dilations)
+	return b
+}
+
+// WindowDilations sets the dilations of the window (the kernel, for convolutions), one per axis.
+func (b *WindowBuilder) WindowDilations(dilations ...int) *WindowBuilder {
+	b.window.WindowDilations = slices.Clone(dilations)
+	return b
+}
+
+// Done validates the configuration and returns the Window.
+//
+// It checks that all values set have the same number of axes, and that dimensions, strides and dilations are
+// positive. The number of axes is only checked against the operands when the window is used.
+func (b *WindowBuilder) Done() (Window, error) {
+	return b.window, b.window.Validate()
+}
+
+// Validate checks that all values set have the same number of axes, and that dimensions, strides and dilations
+// are positive.
+func (w Window) Validate() error {
+	numAxes := -1
+	checkNumAxes := func(name string, length int) error {
+		if length == 0 {
+			return nil
+		}
+		if numAxes == -1 {
+			numAxes = length
+			return nil
+		}
+		if length != numAxes {
+			return errors.Errorf("invalid window: %s has %d axes, but other parameters have %d", name, length, numAxes)
+		}
+		return nil
+	}
+	for _, param := range []struct {
+		name   string
+		values []int
+	}{
+		{"dimensions", w.Dimensions},
+		{"strides", w.Strides},
+		{"input dilations", w.InputDilations},
+		{"window dilations", w.WindowDilations},
+	} {
+		if err := checkNumAxes(param.name, len(param.values)); err != nil {
+			return err
+		}
+		for axis, value := range param.values {
+			if value < 1 {
+				return errors.Errorf("invalid window: %s must be positive, got %d for axis %d", param.name, value, axis)
+			}
+		}
+	}
+	return checkNumAxes("paddings", len(w.Paddings))
+}
+
+// checkRank checks the window is valid and compatible with the given rank (number of axes of the window).
+func (w Window) checkRank(op string, rank int) error {
+	if err := w.Validate(); err != nil {
+		return errors.WithMessagef(err, "in %s", op)
+	}
+	for _, length := range []int{len(w.Dimensions), len(w.Strides), len(w.Paddings), len(w.InputDilations), len(w.WindowDilations)} {
+		if length != 0 && length != rank {
+			return errors.Errorf("%s: window has %d axes, but it requires %d", op, length, rank)
+		}
+	}
+	return nil
+}
+
+// ReduceWindowWith is like ReduceWindow, but the window is configured with a Window, see NewWindow.
+func ReduceWindowWith(input, initialValue *Value, reductionFn *Function, window Window) (output *Value, err error) {
+	fn := input.fn
+	defer fn.opErrorHandler(&err, &output)()
+	results, err := MultiReduceWindowWith([]*Value{input}, []*Value{initialValue}, reductionFn, window)
+	if err != nil {
+		return nil, err
+	}
+	return results[0], nil
+}
+
+// MultiReduceWindowWith is like MultiReduceWindow, but the window is configured with a Window, see NewWindow.
+func MultiReduceWindowWith(inputs, initialValues []*Value, reductionFn *Function, window Window) (outputs []*Value, err error) {
+	if len(inputs) == 0 {
+		return nil, errors.New("MultiReduceWindowWith requires at least one input")
+	}
+	fn := inputs[0].fn
+	defer fn.multiOpErrorHandler(&err, &outputs, len(inputs))()
+	if err := window.checkRank("ReduceWindow", inputs[0].shape.Rank()); err != nil {
+		return nil, err
+	}
+	return MultiReduceWindow(inputs, initialValues, reductionFn,
+		window.Dimensions, window.Strides, window.InputDilations, window.WindowDilations, window.Paddings)
+}
+
+// SelectAndScatterWith is like SelectAndScatter, but the window is configured with a Window, see NewWindow.
+//
+// The window dilations are not supported by SelectAndScatter.
+func SelectAndScatterWith(input, scatterSource, initialValue *Value, selectFn, scatterFn *Function, window Window) (output *Value, err error) {
+	fn := input.fn
+	defer fn.opErrorHandler(&err, &output)()
+	if err := window.checkRank("SelectAndScatter", input.shape.Rank()); err != nil {
+		return nil, err
+	}
+	if len(window.InputDilations) > 0 || len(window.WindowDilations) > 0 {
+		return nil, errors.New("SelectAndScatter doesn't support window or input dilations")
+	}
+	return SelectAndScatter(input, scatterSource, initialValue, selectFn, scatterFn,
+		window.Dimensions, window.Strides, window.Paddings)
+}
+
+// ConvolutionWith is like Convolution, but the window (strides, paddings and dilations) is configured with a
+// Window (see NewWindow), and the axes with a types.ConvolveAxesConfig.
+//
+// The window is defined over the spatial axes, and its Dimensions, if set, must match the kernel spatial dimensions.
+func ConvolutionWith(input, kernel *Value, window Window, axes types.ConvolveAxesConfig,
+	channelGroupCount, batchGroupCount int,
+	inputPrecision, kernelPrecision types.DotGeneralPrecisionType) (output *Value, err error) {
+	fn := input.fn
+	defer fn.opErrorHandler(&err, &output)()
+	if err := window.checkRank("Convolution", len(axes.InputSpatial)); err != nil {
+		return nil, err
+	}
+	if len(window.Dimensions) > 0 {
+		if len(axes.KernelSpatial) != len(window.Dimensions) {
+			return nil, errors.Errorf("Convolution: window dimensions %v don't match the %d kernel spatial axes %v",
+				window.Dimensions, len(axes.KernelSpatial), axes.KernelSpatial)
+		}
+		for i, axis := range axes.KernelSpatial {
+			if axis < 0 || axis >= kernel.shape.Rank() {
+				return nil, errors.Errorf("Convolution: invalid kernel spatial axis %d for kernel shape %s", axis, kernel.shape)
+			}
+			if kernel.shape.Dimensions[axis] != window.Dimensions[i] {
+				return nil, errors.Errorf("Convolution: window dimensions %v don't match the kernel %s spatial axes %v",
+					window.Dimensions, kernel.shape, axes.KernelSpatial)
+			}
+		}
+	}
+	return Convolution(input, kernel,
+		window.Strides, window.Paddings, window.InputDilations, window.WindowDilations,
+		axes.InputBatch, axes.InputChannels, axes.InputSpatial,
+		axes.KernelInputChannels, axes.KernelOutputChannels, axes.KernelSpatial,
+		axes.OutputBatch, axes.OutputChannels, axes.OutputSpatial,
+		channelGroupCount, batchGroupCount,
+		inputPrecision, kernelPrecision)
+}
@@ -0,0 +1,77 @@
+package stablehlo
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+
+	"github.com/gomlx/gopjrt/dtypes"
+	"github.com/gomlx/stablehlo/types"
+	"github.com/gomlx/stablehlo/types/shapes"
+)
+
+func TestWindow(t *testing.T) {
+	t.Run("validation", func(t *testing.T) {
+		for _, builder := range []*WindowBuilder{
+			NewWindow(2, 2).Strides(1),
+			NewWindow(2, 0),
+			NewWindow(2, 2).InputDilations(1, -1),
+			NewWindow(2, 2).Paddings([2]int{0, 1}),
+		} {
+			if _, err := builder.Done(); err == nil {
+				t.Errorf("expected error for window %+v, got nil", builder.window)
+			}
+		}
+		if _, err := NewWindow().Strides(2, 2).WindowDilations(1, 2).Done(); err != nil {
+			t.Errorf("unexpected error: %+v", err)
+		}
+	})
+
+	t.Run("ReduceWindowWith", func(t *testing.T) {
+		builder := New(t.Name())
+		fn := builder.Main()
+		x := must(fn.NamedInput("x", shapes.Make(dtypes.Float32, 4, 6)))
+		zero := must(fn.ConstantFromScalar(float32(0)))
+		reductionFn := fn.Closure()
+		lhs := must(reductionFn.NamedInput("lhs", shapes.Make(dtypes.Float32)))
+		rhs := must(reductionFn.NamedInput("rhs", shapes.Make(dtypes.Float32)))
+		if err := reductionFn.Return(must(Add(lhs, rhs))); err != nil {
+			t.Fatalf("failed to build reduction function: %v", err)
+		}
+
+		if _, err := ReduceWindowWith(x, zero, reductionFn, Window{Dimensions: []int{2}}); err == nil {
+			t.Error("expected error for window with the wrong number of axes, got nil")
+		}
+		window := must(NewWindow(2, 2).InputDilations(1, 2).WindowDilations(2, 1).Done())
+		y := must(ReduceWindowWith(x, zero, reductionFn, window))
+		if err := fn.Return(y); err != nil {
+			t.Fatalf("expected no error, got %v", err)
+		}
+		program := string(must(builder.Build()))
+		fmt.Printf("%s program:\n%s", t.Name(), program)
+		for _, want := range []string{
+			"base_dilations = array<i64: 1, 2>",
+			"window_dilations = array<i64: 2, 1>",
+			"window_strides = array<i64: 2, 2>",
+		} {
+			if !strings.Contains(program, want) {
+				t.Errorf("program missing %q", want)
+			}
+		}
+	})
+	t.Run("ConvolutionWith", func(t *testing.T) {
+		fn := New(t.Name()).Main()
+		input := must(fn.NamedInput("input", shapes.Make(dtypes.Float32, 1, 2, 8)))
+		kernel := must(fn.NamedInput("kernel", shapes.Make(dtypes.Float32, 2, 3, 3, 3)))
+		axes := types.ConvolveAxesConfig{
+			InputBatch: 0, InputChannels: 1, InputSpatial: []int{2},
+			KernelInputChannels: 0, KernelOutputChannels: 1, KernelSpatial: []int{2, 3},
+			OutputBatch: 0, OutputChannels: 1, OutputSpatial: []int{2},
+		}
+		// More kernel spatial axes than window dimensions.
+		if _, err := ConvolutionWith(input, kernel, Window{Dimensions: []int{3}}, axes, 1, 1,
+			types.DotGeneralPrecisionDefault, types.DotGeneralPrecisionDefault); err == nil {
+			t.Error("expected error for kernel spatial axes not matching the window dimensions, got nil")
+		}
+	})
+}